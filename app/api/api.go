@@ -1,7 +1,64 @@
 package api
 
-import "asset-management/app/service"
+import (
+	"asset-management/app/service"
+	"asset-management/config"
+)
 
-func Initial() {
-	service.Initial()
+/*
+All http handlers, wired to their services once at startup
+*/
+type Apis struct {
+	ApiToken   *ApiTokenApi
+	Asset      *AssetApi
+	AssetClass *AssetClassApi
+	Async      *AsyncApi
+	Department *DepartmentApi
+	Entity     *EntityApi
+	Feishu     *FeishuApi
+	Identity   *IdentityApi
+	Invite     *InviteApi
+	Log        *LogApi
+	Oss        *OssApi
+	Permission *PermissionApi
+	Scim       *ScimApi
+	Session    *SessionApi
+	Stat       *StatApi
+	Storage    *StorageApi
+	Task       *TaskApi
+	TwoFactor  *TwoFactorApi
+	Url        *UrlApi
+	User       *UserApi
+}
+
+func NewApis(conf *config.Config, services *service.Services) *Apis {
+	permissionApi := NewPermissionApi(services.Department, services.Entity, services.Permission, services.User)
+	assetClassApi := NewAssetClassApi(services.Asset, services.AssetClass, services.Department, services.Entity, services.User, permissionApi)
+	departmentApi := NewDepartmentApi(services.Asset, services.Department, services.Entity, services.Password, services.User, assetClassApi, permissionApi)
+	entityApi := NewEntityApi(services.Entity, services.LoginLock, services.Password, services.TwoFactor, services.User, permissionApi)
+	twoFactorApi := NewTwoFactorApi(services.LoginLock, services.Token, services.TwoFactor, services.User)
+	identityApi := NewIdentityApi(services.Entity, services.Identity, services.Token, services.User, entityApi, twoFactorApi)
+	userApi := NewUserApi(services.Asset, services.Async, services.Entity, services.Feishu, services.Invite, services.LoginLock, services.Password, services.Task, services.Token, services.User, departmentApi, twoFactorApi, permissionApi)
+	return &Apis{
+		ApiToken:   NewApiTokenApi(services.Entity, services.Token, services.User, entityApi),
+		Asset:      NewAssetApi(services.AssetClass, services.Asset, services.AssetRecycle, services.Department, services.Entity, services.User, assetClassApi, permissionApi),
+		AssetClass: assetClassApi,
+		Async:      NewAsyncApi(services.Async, services.Entity, services.User, permissionApi),
+		Department: departmentApi,
+		Entity:     entityApi,
+		Feishu:     NewFeishuApi(services.Feishu, services.Identity, services.Task, identityApi),
+		Identity:   identityApi,
+		Invite:     NewInviteApi(services.Department, services.Entity, services.Invite, services.Permission, departmentApi, permissionApi),
+		Log:        NewLogApi(services.Entity, services.Log, services.User, permissionApi),
+		Oss:        NewOssApi(conf.STS),
+		Permission: permissionApi,
+		Scim:       NewScimApi(services.Asset, services.Async, services.Department, services.Scim, services.Task, services.Token, services.User),
+		Session:    NewSessionApi(services.Entity, services.Token, services.User, userApi, permissionApi),
+		Stat:       NewStatApi(services.Department, services.Stat, assetClassApi),
+		Storage:    NewStorageApi(permissionApi),
+		Task:       NewTaskApi(services.Asset, services.Department, services.Entity, services.Feishu, services.Task, services.User, permissionApi),
+		TwoFactor:  twoFactorApi,
+		Url:        NewUrlApi(services.Entity, services.Url, services.User, permissionApi),
+		User:       userApi,
+	}
 }
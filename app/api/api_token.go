@@ -0,0 +1,197 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+)
+
+type ApiTokenApi struct {
+	entityService service.EntityServiceInterface
+	tokenService  service.TokenServiceInterface
+	userService   service.UserServiceInterface
+	entityApi     *EntityApi
+}
+
+func NewApiTokenApi(
+	entityService service.EntityServiceInterface,
+	tokenService service.TokenServiceInterface,
+	userService service.UserServiceInterface,
+	entityApi *EntityApi,
+) *ApiTokenApi {
+	return &ApiTokenApi{
+		entityService: entityService,
+		tokenService:  tokenService,
+		userService:   userService,
+		entityApi:     entityApi,
+	}
+}
+
+/*
+A leaked token must not be able to mint more credentials, only a login may create them
+*/
+func checkNotApiToken(ctx *utils.Context) bool {
+	if _, exists := ctx.Get("api_token_id"); exists {
+		ctx.Forbidden(myerror.API_TOKEN_NOT_ALLOWED, myerror.API_TOKEN_NOT_ALLOWED_INFO)
+		return false
+	}
+	return true
+}
+
+func (apiToken *ApiTokenApi) tokenList(ctx *utils.Context, tokenList []*model.ApiToken) {
+	tokenListRes := []define.ApiTokenInfo{}
+	err := copier.Copy(&tokenListRes, tokenList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.ApiTokenListResponse{
+		TokenList: tokenListRes,
+	})
+}
+
+func (apiToken *ApiTokenApi) create(ctx *utils.Context, ownerID uint, kind string, entityID uint) {
+	var req define.CreateApiTokenReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	plain, newToken, err := apiToken.tokenService.CreateApiToken(ownerID, kind, entityID, req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if newToken == nil {
+		ctx.Forbidden(myerror.API_TOKEN_SCOPE_INVALID, myerror.API_TOKEN_SCOPE_INVALID_INFO)
+		return
+	}
+	var info define.ApiTokenInfo
+	err = copier.Copy(&info, newToken)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.CreateApiTokenResponse{
+		Token: plain,
+		Info:  info,
+	})
+}
+
+/*
+Handle func for GET /user/tokens
+*/
+func (apiToken *ApiTokenApi) GetPersonalTokens(ctx *utils.Context) {
+	tokenList, err := apiToken.tokenService.GetUserApiTokens(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	apiToken.tokenList(ctx, tokenList)
+}
+
+/*
+Handle func for POST /user/tokens
+*/
+func (apiToken *ApiTokenApi) CreatePersonalToken(ctx *utils.Context) {
+	if !checkNotApiToken(ctx) || !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser, err := apiToken.userService.GetUserByID(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisUser == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return
+	}
+	apiToken.create(ctx, thisUser.ID, model.API_TOKEN_PERSONAL, thisUser.EntityID)
+}
+
+/*
+Handle func for DELETE /user/tokens/:token_id
+*/
+func (apiToken *ApiTokenApi) RevokePersonalToken(ctx *utils.Context) {
+	tokenID, err := apiToken.entityService.GetParamID(ctx, "token_id")
+	if err != nil {
+		return
+	}
+	thisToken, err := apiToken.tokenService.GetApiTokenByID(tokenID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisToken == nil || thisToken.Kind != model.API_TOKEN_PERSONAL || thisToken.UserID != GetOperatorID(ctx) {
+		ctx.NotFound(myerror.API_TOKEN_NOT_FOUND, myerror.API_TOKEN_NOT_FOUND_INFO)
+		return
+	}
+
+	err = apiToken.tokenService.RevokeApiToken(tokenID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /entity/:entity_id/api-keys
+*/
+func (apiToken *ApiTokenApi) GetEntityKeys(ctx *utils.Context) {
+	hasIdentity, entityID := apiToken.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	tokenList, err := apiToken.tokenService.GetEntityApiKeys(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	apiToken.tokenList(ctx, tokenList)
+}
+
+/*
+Handle func for POST /entity/:entity_id/api-keys
+*/
+func (apiToken *ApiTokenApi) CreateEntityKey(ctx *utils.Context) {
+	if !checkNotApiToken(ctx) || !checkNotImpersonating(ctx) {
+		return
+	}
+	hasIdentity, entityID := apiToken.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	apiToken.create(ctx, GetOperatorID(ctx), model.API_TOKEN_ENTITY, entityID)
+}
+
+/*
+Handle func for DELETE /entity/:entity_id/api-keys/:key_id
+*/
+func (apiToken *ApiTokenApi) RevokeEntityKey(ctx *utils.Context) {
+	hasIdentity, entityID := apiToken.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	keyID, err := apiToken.entityService.GetParamID(ctx, "key_id")
+	if err != nil {
+		return
+	}
+	thisKey, err := apiToken.tokenService.GetApiTokenByID(keyID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisKey == nil || thisKey.Kind != model.API_TOKEN_ENTITY || thisKey.EntityID != entityID {
+		ctx.NotFound(myerror.API_TOKEN_NOT_FOUND, myerror.API_TOKEN_NOT_FOUND_INFO)
+		return
+	}
+
+	err = apiToken.tokenService.RevokeApiToken(keyID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiToken(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("api_key_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("api_key_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("api_key_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reader = GetJsonBody(body)
+		}
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, reader)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	created := func(res *httptest.ResponseRecorder) define.CreateApiTokenResponse {
+		var tokenRes struct {
+			Data define.CreateApiTokenResponse `json:"data"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &tokenRes)
+		return tokenRes.Data
+	}
+
+	res = call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "api_key_manager", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data := map[string]interface{}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	jwtToken := data["data"].(map[string]interface{})["token"].(string)
+
+	keysURL := fmt.Sprintf("/entity/%d/api-keys", entityID)
+	res = call(http.MethodPost, keysURL, jwtToken, define.CreateApiTokenReq{Name: "itsm", Scope: define.API_SCOPE_READ, ExpiresInDays: 30})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	readKey := created(res)
+	assert.Equal(t, define.API_KEY_PREFIX, readKey.Token[:len(define.API_KEY_PREFIX)], "response failed")
+
+	// the key acts as an entity manager but only for reading
+	res = call(http.MethodGet, fmt.Sprintf("/entity/%d/login-locks", entityID), readKey.Token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	required := false
+	res = call(http.MethodPut, fmt.Sprintf("/entity/%d/two-factor", entityID), readKey.Token, define.TwoFactorPolicyReq{Required: &required})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, fmt.Sprintf("/entity/%d/login-locks", otherID), readKey.Token, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	// a token cannot mint more tokens
+	res = call(http.MethodPost, keysURL, readKey.Token, define.CreateApiTokenReq{Name: "more", Scope: define.API_SCOPE_WRITE, ExpiresInDays: 30})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodPost, keysURL, jwtToken, define.CreateApiTokenReq{Name: "too_long", Scope: define.API_SCOPE_READ, ExpiresInDays: 400})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodGet, keysURL, jwtToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	var listRes struct {
+		Data define.ApiTokenListResponse `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &listRes)
+	assert.Equal(t, 1, len(listRes.Data.TokenList), "response failed")
+	assert.NotNil(t, listRes.Data.TokenList[0].LastUsedAt, "response failed")
+
+	res = call(http.MethodDelete, fmt.Sprintf("/entity/%d/api-keys/%d", otherID, readKey.Info.ID), jwtToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", keysURL, readKey.Info.ID), jwtToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, fmt.Sprintf("/entity/%d/login-locks", entityID), readKey.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+
+	// personal tokens
+	res = call(http.MethodPost, "/user/tokens", jwtToken, define.CreateApiTokenReq{Name: "script", Scope: define.API_SCOPE_WRITE, ExpiresInDays: 7})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	personal := created(res)
+	assert.Equal(t, define.PERSONAL_TOKEN_PREFIX, personal.Token[:len(define.PERSONAL_TOKEN_PREFIX)], "response failed")
+
+	res = call(http.MethodGet, "/user/tokens", personal.Token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &listRes)
+	assert.Equal(t, 1, len(listRes.Data.TokenList), "response failed")
+	res = call(http.MethodPost, "/user/tokens", personal.Token, define.CreateApiTokenReq{Name: "more", Scope: define.API_SCOPE_READ, ExpiresInDays: 7})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodDelete, fmt.Sprintf("/user/tokens/%d", personal.Info.ID), personal.Token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/tokens", personal.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+}
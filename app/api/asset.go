@@ -6,6 +6,8 @@ import (
 	"asset-management/app/service"
 	"asset-management/myerror"
 	"asset-management/utils"
+	"encoding/json"
+	"errors"
 	"sort"
 	"strconv"
 	"time"
@@ -15,30 +17,51 @@ import (
 	"github.com/thoas/go-funk"
 )
 
-type assetApi struct {
+type AssetApi struct {
+	assetClassService   service.AssetClassServiceInterface
+	assetService        service.AssetServiceInterface
+	assetRecycleService service.AssetRecycleServiceInterface
+	departmentService   service.DepartmentServiceInterface
+	entityService       service.EntityServiceInterface
+	userService         service.UserServiceInterface
+	assetClassApi       *AssetClassApi
+	permissionApi       *PermissionApi
 }
 
-var AssetApi *assetApi
-
-func newAssetApi() *assetApi {
-	return &assetApi{}
-}
-
-func init() {
-	AssetApi = newAssetApi()
+func NewAssetApi(
+	assetClassService service.AssetClassServiceInterface,
+	assetService service.AssetServiceInterface,
+	assetRecycleService service.AssetRecycleServiceInterface,
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	userService service.UserServiceInterface,
+	assetClassApi *AssetClassApi,
+	permissionApi *PermissionApi,
+) *AssetApi {
+	return &AssetApi{
+		assetClassService:   assetClassService,
+		assetService:        assetService,
+		assetRecycleService: assetRecycleService,
+		departmentService:   departmentService,
+		entityService:       entityService,
+		userService:         userService,
+		assetClassApi:       assetClassApi,
+		permissionApi:       permissionApi,
+	}
 }
 
-func (asset *assetApi) CheckAssetExistsAndValid(ctx *utils.Context, departmentID uint) (uint, *model.Asset, bool) {
-	assetID, err := service.EntityService.GetParamID(ctx, "asset_id")
+func (asset *AssetApi) CheckAssetExistsAndValid(ctx *utils.Context, departmentID uint) (uint, *model.Asset, bool) {
+	assetID, err := asset.entityService.GetParamID(ctx, "asset_id")
 	if err != nil {
 		return 0, nil, false
 	}
 
-	thisAsset, err := service.AssetService.GetAssetByID(assetID)
+	thisAsset, err := asset.assetService.GetAssetByID(assetID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return 0, nil, false
-	} else if thisAsset == nil {
+	} else if thisAsset == nil || thisAsset.State == define.ASSET_DELETED {
+		// assets in the recycle bin are reached through the bin only
 		ctx.BadRequest(myerror.ASSET_NOT_FOUND, myerror.ASSET_NOT_FOUND_INFO)
 		return 0, nil, false
 	} else if thisAsset.DepartmentID != departmentID {
@@ -49,10 +72,10 @@ func (asset *assetApi) CheckAssetExistsAndValid(ctx *utils.Context, departmentID
 	return assetID, thisAsset, true
 }
 
-func (asset *assetApi) CheckAssetsValid(ctx *utils.Context, departmentID uint, assetList []define.ExpireAssetReq) ([]uint, bool) {
+func (asset *AssetApi) CheckAssetsValid(ctx *utils.Context, departmentID uint, assetList []define.ExpireAssetReq) ([]uint, bool) {
 	assetIDs := []uint{}
 	for _, assetID := range assetList {
-		exists, err := service.AssetService.ExistAsset(assetID.AssetID)
+		exists, err := asset.assetService.ExistAsset(assetID.AssetID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return nil, false
@@ -60,7 +83,7 @@ func (asset *assetApi) CheckAssetsValid(ctx *utils.Context, departmentID uint, a
 			ctx.BadRequest(myerror.ASSET_NOT_FOUND, myerror.ASSET_NOT_FOUND_INFO)
 			return nil, false
 		}
-		isInDepartment, err := service.AssetService.CheckAssetInDepartment(assetID.AssetID, departmentID)
+		isInDepartment, err := asset.assetService.CheckAssetInDepartment(assetID.AssetID, departmentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return nil, false
@@ -78,20 +101,20 @@ func (asset *assetApi) CheckAssetsValid(ctx *utils.Context, departmentID uint, a
 /*
 Handle func for GET /department/{department_id}/asset/list
 */
-func (asset *assetApi) GetAssetList(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) GetAssetList(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
-	// departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+	// departmentID, err := asset.entityService.GetParamID(ctx, "department_id")
 	// if err != nil {
 	// 	return
 	// }
 
-	// existsDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	// existsDepartment, err := asset.departmentService.ExistsDepartmentByID(departmentID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -100,7 +123,7 @@ func (asset *assetApi) GetAssetList(ctx *utils.Context) {
 	// 	return
 	// }
 
-	// thisUser := UserApi.GetOperatorInfo(ctx)
+	// thisUser := GetOperatorInfo(ctx)
 	// if thisUser.DepartmentID != departmentID {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
@@ -117,7 +140,7 @@ func (asset *assetApi) GetAssetList(ctx *utils.Context) {
 		return
 	}
 
-	assetTree, count, err := service.AssetService.GetSubAssetPage(0, departmentID, uint(page_size), uint(page_num))
+	assetTree, count, err := asset.assetService.GetSubAssetPage(0, departmentID, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -134,8 +157,8 @@ func (asset *assetApi) GetAssetList(ctx *utils.Context) {
 /*
 Handle func for GET /department/{department_id}/asset/list/basic
 */
-func (asset *assetApi) GetDepartmentAssetBasicList(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetViewIdentity(ctx)
+func (asset *AssetApi) GetDepartmentAssetBasicList(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -143,7 +166,7 @@ func (asset *assetApi) GetDepartmentAssetBasicList(ctx *utils.Context) {
 		return
 	}
 
-	assetList, err := service.AssetService.GetDepartmentAssetBasicList(departmentID)
+	assetList, err := asset.assetService.GetDepartmentAssetBasicList(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -166,8 +189,8 @@ func (asset *assetApi) GetDepartmentAssetBasicList(ctx *utils.Context) {
 /*
 Handle func for PATCH /department/{department_id}/asset/{asset_id}
 */
-func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) ModifyAssetInfo(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -175,12 +198,12 @@ func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
 		return
 	}
 
-	// assetID, err := service.EntityService.GetParamID(ctx, "asset_id")
+	// assetID, err := asset.entityService.GetParamID(ctx, "asset_id")
 	// if err != nil {
 	// 	return
 	// }
 
-	// thisAsset, err := service.AssetService.GetAssetByID(assetID)
+	// thisAsset, err := asset.assetService.GetAssetByID(assetID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -214,7 +237,7 @@ func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
 	}
 
 	if modifyAssetReq.ParentID != nil && *modifyAssetReq.ParentID != 0 {
-		exists, err := service.AssetService.ExistAsset(*modifyAssetReq.ParentID)
+		exists, err := asset.assetService.ExistAsset(*modifyAssetReq.ParentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -223,7 +246,7 @@ func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
 			ctx.BadRequest(myerror.PARENT_ASSET_NOT_FOUND, myerror.PARENT_ASSET_NOT_FOUND_INFO)
 			return
 		}
-		isAncestor, err := service.AssetService.CheckIsAncestor(thisAsset.ID, *modifyAssetReq.ParentID)
+		isAncestor, err := asset.assetService.CheckIsAncestor(thisAsset.ID, *modifyAssetReq.ParentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -234,14 +257,14 @@ func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
 		}
 	}
 
-	err = service.AssetService.ModifyAssetInfo(assetID, modifyAssetReq)
+	err = asset.assetService.ModifyAssetInfo(assetID, modifyAssetReq, GetOperatorInfo(ctx).UserID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
 	if modifyAssetReq.Price != decimal.Zero || modifyAssetReq.Expire != 0 {
-		_ = service.AssetService.UpdateNetWorth(assetID)
+		_ = asset.assetService.UpdateNetWorth(assetID)
 	}
 
 	ctx.Success(nil)
@@ -250,8 +273,8 @@ func (asset *assetApi) ModifyAssetInfo(ctx *utils.Context) {
 /*
 Handle func for POST /department/{department_id}/asset
 */
-func (asset *assetApi) CreateAssets(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) CreateAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -259,7 +282,7 @@ func (asset *assetApi) CreateAssets(ctx *utils.Context) {
 		return
 	}
 
-	userID := UserApi.GetOperatorID(ctx)
+	userID := GetOperatorID(ctx)
 
 	var assetsCreateReq define.CreateAssetListReq
 	err = ctx.MustBindWith(&assetsCreateReq, binding.JSON)
@@ -271,8 +294,8 @@ func (asset *assetApi) CreateAssets(ctx *utils.Context) {
 	minimalPrice := decimal.NewFromFloat(0)
 	maxiumPrice, _ := decimal.NewFromString("99999999.99")
 
-	for _, asset := range assetsCreateReq.AssetList {
-		exists, err := service.AssetClassService.ExistsAssetClass(asset.ClassID)
+	for _, thisAsset := range assetsCreateReq.AssetList {
+		exists, err := asset.assetClassService.ExistsAssetClass(thisAsset.ClassID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -281,13 +304,13 @@ func (asset *assetApi) CreateAssets(ctx *utils.Context) {
 			return
 		}
 
-		if minimalPrice.Cmp(asset.Price) == 1 || maxiumPrice.Cmp(asset.Price) == -1 {
+		if minimalPrice.Cmp(thisAsset.Price) == 1 || maxiumPrice.Cmp(thisAsset.Price) == -1 {
 			ctx.BadRequest(myerror.PRICE_OUT_OF_RANGE, myerror.PRICE_OUT_OF_RANGE_INFO)
 			return
 		}
 
-		if asset.ParentID != 0 {
-			exists, err := service.AssetService.ExistAsset(asset.ParentID)
+		if thisAsset.ParentID != 0 {
+			exists, err := asset.assetService.ExistAsset(thisAsset.ParentID)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
@@ -297,7 +320,7 @@ func (asset *assetApi) CreateAssets(ctx *utils.Context) {
 			}
 		}
 
-		err = service.AssetService.CreateAsset(&asset, departmentID, asset.ParentID, userID)
+		err = asset.assetService.CreateAsset(&thisAsset, departmentID, thisAsset.ParentID, userID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -310,8 +333,8 @@ func (asset *assetApi) CreateAssets(ctx *utils.Context) {
 /*
 Handle func for PATCH /department/{department_id}/asset/expire
 */
-func (asset *assetApi) ExpireAsset(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) ExpireAsset(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -328,7 +351,7 @@ func (asset *assetApi) ExpireAsset(ctx *utils.Context) {
 
 	// assetIDs := []uint{}
 	// for _, assetID := range expireReq.ExpireList {
-	// 	exists, err := service.AssetService.ExistAsset(assetID.AssetID)
+	// 	exists, err := asset.assetService.ExistAsset(assetID.AssetID)
 	// 	if err != nil {
 	// 		ctx.InternalError(err.Error())
 	// 		return
@@ -336,7 +359,7 @@ func (asset *assetApi) ExpireAsset(ctx *utils.Context) {
 	// 		ctx.BadRequest(myerror.ASSET_NOT_FOUND, myerror.ASSET_NOT_FOUND_INFO)
 	// 		return
 	// 	}
-	// 	isInDepartment, err := service.AssetService.CheckAssetInDepartment(assetID.AssetID, departmentID)
+	// 	isInDepartment, err := asset.assetService.CheckAssetInDepartment(assetID.AssetID, departmentID)
 	// 	if err != nil {
 	// 		ctx.InternalError(err.Error())
 	// 		return
@@ -353,9 +376,10 @@ func (asset *assetApi) ExpireAsset(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.ExpireAssets(assetIDs)
+	thisUser := GetOperatorInfo(ctx)
+	err = asset.assetService.ExpireAssets(assetIDs, thisUser.UserID, expireReq.Reason)
 	if err != nil {
-		ctx.InternalError(err.Error())
+		assetStateError(ctx, err)
 		return
 	}
 
@@ -365,8 +389,8 @@ func (asset *assetApi) ExpireAsset(ctx *utils.Context) {
 /*
 Handle func for POST /department/{department_id}/asset/transfer
 */
-func (asset *assetApi) TransferAssets(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) TransferAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -381,8 +405,8 @@ func (asset *assetApi) TransferAssets(ctx *utils.Context) {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
-	targetUser, err := service.UserService.GetUserByID(transferReq.UserID)
+	thisUser := GetOperatorInfo(ctx)
+	targetUser, err := asset.userService.GetUserByID(transferReq.UserID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -392,7 +416,13 @@ func (asset *assetApi) TransferAssets(ctx *utils.Context) {
 	} else if targetUser.EntityID != thisUser.EntityID {
 		ctx.BadRequest(myerror.NOT_IN_SAME_ENTITY, myerror.NOT_IN_SAME_ENTITY_INFO)
 		return
-	} else if !targetUser.DepartmentSuper {
+	}
+	// the assets go to the target's department, which they must manage
+	manager, err := asset.permissionApi.UserAllowed(targetUser, define.PERM_ASSET_MANAGE, targetUser.EntityID, targetUser.DepartmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if !manager {
 		ctx.BadRequest(myerror.TARGET_NOT_DEPARTMENT_SUPER, myerror.TARGET_NOT_DEPARTMENT_SUPER_INFO)
 		return
 	}
@@ -402,28 +432,28 @@ func (asset *assetApi) TransferAssets(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.TransferAssets(assetIDs, targetUser.ID, targetUser.DepartmentID, departmentID)
+	err = asset.assetService.TransferAssets(assetIDs, targetUser.ID, targetUser.DepartmentID, departmentID, thisUser.UserID, transferReq.Reason)
 	if err != nil {
-		ctx.InternalError(err.Error())
+		assetStateError(ctx, err)
 		return
 	}
 
 	ctx.Success(nil)
 }
 
-func (asset *assetApi) userAssetPrevillige(ctx *utils.Context) (*model.User, bool) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (asset *AssetApi) userAssetPrevillige(ctx *utils.Context) (*model.User, bool) {
+	userID, err := asset.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return nil, false
 	}
 
-	operatorUser := UserApi.GetOperatorInfo(ctx)
+	operatorUser := GetOperatorInfo(ctx)
 	if operatorUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return nil, false
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := asset.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return nil, false
@@ -446,19 +476,19 @@ func (asset *assetApi) userAssetPrevillige(ctx *utils.Context) (*model.User, boo
 /*
 Handle func for GET /users/:user_id/assets/maintain
 */
-func (asset *assetApi) GetUserMaintainAssets(ctx *utils.Context) {
-	// userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (asset *AssetApi) GetUserMaintainAssets(ctx *utils.Context) {
+	// userID, err := asset.entityService.GetParamID(ctx, "user_id")
 	// if err != nil {
 	// 	return
 	// }
 
-	// operatorUser := UserApi.GetOperatorInfo(ctx)
+	// operatorUser := GetOperatorInfo(ctx)
 	// if operatorUser.UserID != userID {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
 
-	// thisUser, err := service.UserService.GetUserByID(userID)
+	// thisUser, err := asset.userService.GetUserByID(userID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -482,7 +512,7 @@ func (asset *assetApi) GetUserMaintainAssets(ctx *utils.Context) {
 
 	// var assetListRes []*define.AssetInfo
 
-	assetList, err := service.AssetService.GetUserMaintainAssets(thisUser.ID)
+	assetList, err := asset.assetService.GetUserMaintainAssets(thisUser.ID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -494,7 +524,7 @@ func (asset *assetApi) GetUserMaintainAssets(ctx *utils.Context) {
 	// 	return
 	// }
 
-	assetListRes := service.AssetService.TransformAssetBasicInfo(assetList)
+	assetListRes := asset.assetService.TransformAssetBasicInfo(assetList)
 
 	for _, thisAsset := range assetListRes {
 		thisAsset.Children = nil
@@ -510,25 +540,25 @@ func (asset *assetApi) GetUserMaintainAssets(ctx *utils.Context) {
 /*
 Handle func for POST /users/:user_id/assets/:asset_id/maintain
 */
-func (asset *assetApi) FinishMaintenance(ctx *utils.Context) {
+func (asset *AssetApi) FinishMaintenance(ctx *utils.Context) {
 	thisUser, isOK := asset.userAssetPrevillige(ctx)
 	if !isOK {
 		return
 	}
 
-	assetID, err := service.EntityService.GetParamID(ctx, "asset_id")
+	assetID, err := asset.entityService.GetParamID(ctx, "asset_id")
 	if err != nil {
 		return
 	}
 
-	thisAsset, err := service.AssetService.GetAssetByID(assetID)
+	thisAsset, err := asset.assetService.GetAssetByID(assetID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	} else if thisAsset == nil {
 		ctx.BadRequest(myerror.ASSET_NOT_FOUND, myerror.ASSET_NOT_FOUND_INFO)
 		return
-	} else if thisAsset.State != 2 {
+	} else if thisAsset.State != define.ASSET_IN_MAINTAIN {
 		ctx.BadRequest(myerror.ASSET_NOT_IN_MAINTENCE, myerror.ASSET_NOT_IN_MAINTENCE_INFO)
 		return
 	} else if thisAsset.MaintainerID != thisUser.ID {
@@ -536,9 +566,12 @@ func (asset *assetApi) FinishMaintenance(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.ModifyAssetMaintainerAndState([]uint{assetID}, 0)
+	err = asset.assetService.ChangeAssetState([]uint{assetID}, service.AssetStateChange{
+		Event:   define.ASSET_FINISH_MAINTAIN,
+		ActorID: thisUser.ID,
+	})
 	if err != nil {
-		ctx.InternalError(err.Error())
+		assetStateError(ctx, err)
 		return
 	}
 
@@ -548,8 +581,8 @@ func (asset *assetApi) FinishMaintenance(ctx *utils.Context) {
 /*
 Handle func for POST /department/{department_id}/asset/{asset_id}/property
 */
-func (asset *assetApi) CreateAssetProperty(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) CreateAssetProperty(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -569,7 +602,7 @@ func (asset *assetApi) CreateAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	isExist, err := service.AssetService.ExistsProperty(assetID, createPropertyReq.Key)
+	isExist, err := asset.assetService.ExistsProperty(assetID, createPropertyReq.Key)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -578,7 +611,7 @@ func (asset *assetApi) CreateAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.SetProperty(assetID, createPropertyReq.Key, createPropertyReq.Value)
+	err = asset.assetService.SetProperty(assetID, createPropertyReq.Key, createPropertyReq.Value, GetOperatorInfo(ctx).UserID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -590,8 +623,8 @@ func (asset *assetApi) CreateAssetProperty(ctx *utils.Context) {
 /*
 Handle func for PATCH /department/{department_id}/asset/{asset_id}/property
 */
-func (asset *assetApi) ModifyAssetProperty(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) ModifyAssetProperty(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -611,7 +644,7 @@ func (asset *assetApi) ModifyAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	isExist, err := service.AssetService.ExistsProperty(assetID, modifyPropertyReq.Key)
+	isExist, err := asset.assetService.ExistsProperty(assetID, modifyPropertyReq.Key)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -620,7 +653,7 @@ func (asset *assetApi) ModifyAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.SetProperty(assetID, modifyPropertyReq.Key, modifyPropertyReq.Value)
+	err = asset.assetService.SetProperty(assetID, modifyPropertyReq.Key, modifyPropertyReq.Value, GetOperatorInfo(ctx).UserID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -632,8 +665,8 @@ func (asset *assetApi) ModifyAssetProperty(ctx *utils.Context) {
 /*
 Handle func for DELETE /department/{department_id}/asset/{asset_id}/property
 */
-func (asset *assetApi) DeleteAssetProperty(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) DeleteAssetProperty(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -653,7 +686,7 @@ func (asset *assetApi) DeleteAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	isExist, err := service.AssetService.ExistsProperty(assetID, deletePropertyReq.Key)
+	isExist, err := asset.assetService.ExistsProperty(assetID, deletePropertyReq.Key)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -662,7 +695,7 @@ func (asset *assetApi) DeleteAssetProperty(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetService.DeleteProperty(assetID, deletePropertyReq.Key)
+	err = asset.assetService.DeleteProperty(assetID, deletePropertyReq.Key, GetOperatorInfo(ctx).UserID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -674,8 +707,8 @@ func (asset *assetApi) DeleteAssetProperty(ctx *utils.Context) {
 /*
 Handle func for GET /department/:department_id/asset/:asset_id/history
 */
-func (asset *assetApi) GetAssetHistory(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetViewIdentity(ctx)
+func (asset *AssetApi) GetAssetHistory(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -688,7 +721,7 @@ func (asset *assetApi) GetAssetHistory(ctx *utils.Context) {
 		return
 	}
 
-	approvedTaskList, err := service.AssetService.GetAssetHistory(assetID)
+	approvedTaskList, err := asset.assetService.GetAssetHistory(assetID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -727,11 +760,366 @@ func (asset *assetApi) GetAssetHistory(ctx *utils.Context) {
 	ctx.Success(assetHistoryRes)
 }
 
+/*
+Handle func for GET /department/:department_id/asset/:asset_id/states
+*/
+func (asset *AssetApi) GetAssetStateHistory(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	assetID, _, isOK := asset.CheckAssetExistsAndValid(ctx, departmentID)
+	if !isOK {
+		return
+	}
+
+	historyList, err := asset.assetService.GetAssetStateHistory(assetID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	history := funk.Map(historyList, func(record *model.AssetStateHistory) *define.AssetStateHistory {
+		return &define.AssetStateHistory{
+			ID:        record.ID,
+			Event:     record.Event,
+			FromState: record.FromState,
+			ToState:   record.ToState,
+			ActorID:   record.ActorID,
+			ActorName: record.Actor.UserName,
+			TaskID:    record.TaskID,
+			Reason:    record.Reason,
+			Quantity:  record.Quantity,
+			CreatedAt: record.CreatedAt,
+		}
+	}).([]*define.AssetStateHistory)
+
+	ctx.Success(define.AssetStateHistoryResponse{
+		History: history,
+	})
+}
+
+/*
+Handle func for GET /department/:department_id/asset/:asset_id/versions
+*/
+func (asset *AssetApi) GetAssetVersions(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	assetID, _, isOK := asset.CheckAssetExistsAndValid(ctx, departmentID)
+	if !isOK {
+		return
+	}
+
+	versionList, err := asset.assetService.GetAssetVersions(assetID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	versions := []*define.AssetVersionInfo{}
+	for _, version := range versionList {
+		versionInfo := &define.AssetVersionInfo{
+			Version:   version.Version,
+			ActorID:   version.ActorID,
+			ActorName: version.Actor.UserName,
+			RevertOf:  version.RevertOf,
+			CreatedAt: version.CreatedAt,
+		}
+		err = json.Unmarshal(version.Changes, &versionInfo.Changes)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		}
+		versions = append(versions, versionInfo)
+	}
+
+	ctx.Success(define.AssetVersionListResponse{
+		Versions: versions,
+	})
+}
+
+/*
+Handle func for POST /department/:department_id/asset/:asset_id/versions/:version/revert
+*/
+func (asset *AssetApi) RevertAsset(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	assetID, _, isOK := asset.CheckAssetExistsAndValid(ctx, departmentID)
+	if !isOK {
+		return
+	}
+	version, err := asset.entityService.GetParamID(ctx, "version")
+	if err != nil {
+		return
+	}
+
+	err = asset.assetService.RevertAsset(assetID, version, GetOperatorInfo(ctx).UserID)
+	if errors.Is(err, service.ErrAssetVersionNotFound) {
+		ctx.NotFound(myerror.ASSET_VERSION_NOT_FOUND, myerror.ASSET_VERSION_NOT_FOUND_INFO)
+		return
+	} else if errors.Is(err, service.ErrRevertParent) {
+		ctx.BadRequest(myerror.PARENT_ASSET_NOT_FOUND, myerror.PARENT_ASSET_NOT_FOUND_INFO)
+		return
+	} else if errors.Is(err, service.ErrRevertCycle) {
+		ctx.BadRequest(myerror.PARENT_CANNOOT_BE_SUCCESSOR, myerror.PARENT_CANNOOT_BE_SUCCESSOR_INFO)
+		return
+	} else if errors.Is(err, service.ErrRevertClass) {
+		ctx.BadRequest(myerror.ASSET_CLASS_NOT_FOUND, myerror.ASSET_CLASS_NOT_FOUND_INFO)
+		return
+	} else if err != nil {
+		assetStateError(ctx, err)
+		return
+	}
+
+	// a restored price or expire changes the net worth
+	err = asset.assetService.UpdateNetWorth(assetID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	ctx.Success(nil)
+}
+
+/*
+Handle func for PUT /department/:department_id/asset/:asset_id/depreciation
+*/
+func (asset *AssetApi) ModifyAssetDepreciation(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	assetID, _, isOK := asset.CheckAssetExistsAndValid(ctx, departmentID)
+	if !isOK {
+		return
+	}
+
+	var req define.AssetDepreciationReq
+	err = ctx.MustBindWith(&req, binding.JSON)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	err = service.CheckDepreciation(req.DepreciationMethod, req.ResidualRate.Decimal)
+	if err != nil {
+		ctx.BadRequest(myerror.DEPRECIATION_INVALID, err.Error())
+		return
+	}
+
+	err = asset.assetService.ModifyAssetDepreciation(assetID, req, GetOperatorInfo(ctx).UserID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	err = asset.assetService.UpdateNetWorth(assetID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /department/:department_id/asset/:asset_id/depreciation,
+method and step are optional
+*/
+func (asset *AssetApi) PreviewDepreciation(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	assetID, _, isOK := asset.CheckAssetExistsAndValid(ctx, departmentID)
+	if !isOK {
+		return
+	}
+
+	method := ctx.Query("method")
+	err = service.CheckDepreciation(method, decimal.Zero)
+	if err != nil {
+		ctx.BadRequest(myerror.DEPRECIATION_INVALID, err.Error())
+		return
+	}
+	step := uint64(define.DEPRECIATION_PREVIEW_STEP)
+	if ctx.Query("step") != "" {
+		step, err = strconv.ParseUint(ctx.Query("step"), 10, 31)
+		if err != nil || step == 0 {
+			ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+			return
+		}
+	}
+
+	preview, err := asset.assetService.PreviewDepreciation(assetID, method, int(step))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	ctx.Success(preview)
+}
+
+/*
+Handle func for DELETE /department/{department_id}/asset
+*/
+func (asset *AssetApi) DeleteAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	var deleteReq define.AssetRecycleReq
+	err = ctx.MustBindWith(&deleteReq, binding.JSON)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	assetIDs, isOK := asset.CheckAssetsValid(ctx, departmentID, deleteReq.Assets)
+	if !isOK {
+		return
+	}
+
+	err = asset.assetRecycleService.DeleteAssets(assetIDs, GetOperatorInfo(ctx).UserID, deleteReq.Reason)
+	if err != nil {
+		assetStateError(ctx, err)
+		return
+	}
+
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /department/{department_id}/asset/recycle
+*/
+func (asset *AssetApi) GetRecycleBin(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	page_size, err := strconv.ParseUint(ctx.Query("page_size"), 10, 64)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_PAGE_SIZE, myerror.INVALID_PAGE_SIZE_INFO)
+		return
+	}
+	page_num, err := strconv.ParseUint(ctx.Query("page_num"), 10, 64)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_PAGE_NUM, myerror.INVALID_PAGE_NUM_INFO)
+		return
+	}
+
+	assetList, count, err := asset.assetRecycleService.GetRecycleBin(departmentID, uint(page_size), uint(page_num))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	recycleList := []*define.AssetRecycleInfo{}
+	for _, thisAsset := range assetList {
+		recycleList = append(recycleList, &define.AssetRecycleInfo{
+			AssetID:   thisAsset.ID,
+			AssetName: thisAsset.Name,
+			ParentID:  thisAsset.DeletedParentID,
+			User: define.AssetUserBasicInfo{
+				UserID:   thisAsset.UserID,
+				Username: thisAsset.User.UserName,
+			},
+			Class: define.AssetClassBasicInfo{
+				ClassID:   thisAsset.ClassID,
+				ClassName: thisAsset.Class.Name,
+			},
+			Price:     thisAsset.Price,
+			State:     thisAsset.DeletedState,
+			DeletedAt: thisAsset.DeletedAt,
+			PurgeAt:   model.ModelTime(asset.assetRecycleService.PurgeAt(thisAsset)),
+		})
+	}
+
+	ctx.Success(define.AssetRecycleBinResponse{
+		AssetList: recycleList,
+		AllCount:  uint(count),
+	})
+}
+
+/*
+Handle func for POST /department/{department_id}/asset/recycle/restore
+*/
+func (asset *AssetApi) RestoreAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
+	if err != nil {
+		return
+	} else if !hasIdentity {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return
+	}
+
+	var restoreReq define.AssetRecycleReq
+	err = ctx.MustBindWith(&restoreReq, binding.JSON)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	assetIDs, isOK := asset.CheckAssetsValid(ctx, departmentID, restoreReq.Assets)
+	if !isOK {
+		return
+	}
+
+	err = asset.assetRecycleService.RestoreAssets(assetIDs, GetOperatorInfo(ctx).UserID, restoreReq.Reason)
+	if err != nil {
+		assetStateError(ctx, err)
+		return
+	}
+
+	ctx.Success(nil)
+}
+
+func assetStateError(ctx *utils.Context, err error) {
+	if errors.Is(err, service.ErrAssetState) {
+		ctx.BadRequest(myerror.ASSET_STATE_INVALID, err.Error())
+	} else if errors.Is(err, service.ErrAssetQuantity) {
+		ctx.BadRequest(myerror.ASSET_QUANTITY_INVALID, err.Error())
+	} else {
+		ctx.InternalError(err.Error())
+	}
+}
+
 /*
 Handle func for POST /department/:department_id/asset/search
 */
-func (asset *assetApi) SearchAssets(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (asset *AssetApi) SearchAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -757,7 +1145,7 @@ func (asset *assetApi) SearchAssets(ctx *utils.Context) {
 		return
 	}
 
-	assetList, count, err := service.AssetService.SearchDepartmentAssets(departmentID, &req, uint(page_size), uint(page_num))
+	assetList, count, err := asset.assetService.SearchDepartmentAssets(departmentID, &req, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -769,7 +1157,7 @@ func (asset *assetApi) SearchAssets(ctx *utils.Context) {
 	// 	ctx.InternalError(err.Error())
 	// 	return
 	// }
-	assetBasicInfoList := service.AssetService.TransformAssetBasicInfo(assetList)
+	assetBasicInfoList := asset.assetService.TransformAssetBasicInfo(assetList)
 
 	assetListResp := define.AssetListResponse{
 		AssetList: assetBasicInfoList,
@@ -782,8 +1170,8 @@ func (asset *assetApi) SearchAssets(ctx *utils.Context) {
 /*
 Handle func for POST /department/:department_id/asset/search/spare
 */
-func (asset *assetApi) SearchSpareAssets(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetViewIdentity(ctx)
+func (asset *AssetApi) SearchSpareAssets(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -810,13 +1198,13 @@ func (asset *assetApi) SearchSpareAssets(ctx *utils.Context) {
 		return
 	}
 
-	assetList, count, err := service.AssetService.SearchDepartmentAssets(departmentID, &req, uint(page_size), uint(page_num))
+	assetList, count, err := asset.assetService.SearchDepartmentAssets(departmentID, &req, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
-	assetBasicInfoList := service.AssetService.TransformAssetBasicInfo(assetList)
+	assetBasicInfoList := asset.assetService.TransformAssetBasicInfo(assetList)
 
 	assetListResp := define.AssetListResponse{
 		AssetList: assetBasicInfoList,
@@ -826,7 +1214,7 @@ func (asset *assetApi) SearchSpareAssets(ctx *utils.Context) {
 	ctx.Success(assetListResp)
 }
 
-func (asset *assetApi) getAssetInfoFromAssetModel(thisAsset *model.Asset) *define.AssetInfo {
+func (asset *AssetApi) getAssetInfoFromAssetModel(thisAsset *model.Asset) *define.AssetInfo {
 	assetInfo := define.AssetInfo{
 		AssetID:   thisAsset.ID,
 		AssetName: thisAsset.Name,
@@ -871,8 +1259,8 @@ func (asset *assetApi) getAssetInfoFromAssetModel(thisAsset *model.Asset) *defin
 /*
 Handle func for GET /department/:department_id/asset/:asset_id
 */
-func (asset *assetApi) GetAssetInfo(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetViewIdentity(ctx)
+func (asset *AssetApi) GetAssetInfo(ctx *utils.Context) {
+	hasIdentity, departmentID, err := asset.assetClassApi.CheckAssetViewIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -893,13 +1281,13 @@ func (asset *assetApi) GetAssetInfo(ctx *utils.Context) {
 /*
 Handle func for GET /asset/:asset_id/info
 */
-func (asset *assetApi) GetAssetInfoByScan(ctx *utils.Context) {
-	assetID, err := service.EntityService.GetParamID(ctx, "asset_id")
+func (asset *AssetApi) GetAssetInfoByScan(ctx *utils.Context) {
+	assetID, err := asset.entityService.GetParamID(ctx, "asset_id")
 	if err != nil {
 		return
 	}
 
-	thisAsset, err := service.AssetService.GetAssetByID(assetID)
+	thisAsset, err := asset.assetService.GetAssetByID(assetID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
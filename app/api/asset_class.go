@@ -9,29 +9,44 @@ import (
 	"errors"
 
 	"github.com/gin-gonic/gin/binding"
+	"github.com/shopspring/decimal"
 )
 
-type assetClassApi struct {
+type AssetClassApi struct {
+	assetService      service.AssetServiceInterface
+	assetClassService service.AssetClassServiceInterface
+	departmentService service.DepartmentServiceInterface
+	entityService     service.EntityServiceInterface
+	userService       service.UserServiceInterface
+	permissionApi     *PermissionApi
 }
 
-var AssetClassApi *assetClassApi
-
-func newAssetClassApi() *assetClassApi {
-	return &assetClassApi{}
-}
-
-func init() {
-	AssetClassApi = newAssetClassApi()
+func NewAssetClassApi(
+	assetService service.AssetServiceInterface,
+	assetClassService service.AssetClassServiceInterface,
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *AssetClassApi {
+	return &AssetClassApi{
+		assetService:      assetService,
+		assetClassService: assetClassService,
+		departmentService: departmentService,
+		entityService:     entityService,
+		userService:       userService,
+		permissionApi:     permissionApi,
+	}
 }
 
 /*
  */
-func (assetClass *assetClassApi) CheckAssetIdentity(ctx *utils.Context) (bool, uint, error) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (assetClass *AssetClassApi) CheckAssetIdentity(ctx *utils.Context) (bool, uint, error) {
+	departmentID, err := assetClass.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return false, departmentID, err
 	}
-	existsDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	existsDepartment, err := assetClass.departmentService.ExistsDepartmentByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false, departmentID, err
@@ -39,22 +54,22 @@ func (assetClass *assetClassApi) CheckAssetIdentity(ctx *utils.Context) (bool, u
 		ctx.NotFound(myerror.DEPARTMENT_NOT_FOUND, myerror.DEPARTMENT_NOT_FOUND_INFO)
 		return false, departmentID, errors.New("")
 	}
-	isDepartmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !isDepartmentSuper {
-		return false, departmentID, nil
+	allowed, err := assetClass.permissionApi.Allowed(ctx, define.PERM_ASSET_MANAGE, 0, departmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false, departmentID, err
 	}
-	isInDepartment := service.DepartmentService.CheckIsInDepartment(ctx, departmentID)
-	return isInDepartment, departmentID, nil
+	return allowed, departmentID, nil
 }
 
 /*
  */
-func (assetClass *assetClassApi) CheckAssetIdentityReturnDepartment(ctx *utils.Context) (bool, *model.Department, error) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (assetClass *AssetClassApi) CheckAssetIdentityReturnDepartment(ctx *utils.Context) (bool, *model.Department, error) {
+	departmentID, err := assetClass.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return false, nil, err
 	}
-	thisDepartment, err := service.DepartmentService.GetDepartmentInfoByID(departmentID)
+	thisDepartment, err := assetClass.departmentService.GetDepartmentInfoByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false, nil, err
@@ -62,20 +77,20 @@ func (assetClass *assetClassApi) CheckAssetIdentityReturnDepartment(ctx *utils.C
 		ctx.NotFound(myerror.DEPARTMENT_NOT_FOUND, myerror.DEPARTMENT_NOT_FOUND_INFO)
 		return false, nil, errors.New("")
 	}
-	isDepartmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !isDepartmentSuper {
-		return false, nil, nil
+	allowed, err := assetClass.permissionApi.Allowed(ctx, define.PERM_ASSET_MANAGE, thisDepartment.EntityID, departmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false, nil, err
 	}
-	isInDepartment := service.DepartmentService.CheckIsInDepartment(ctx, departmentID)
-	return isInDepartment, thisDepartment, nil
+	return allowed, thisDepartment, nil
 }
 
-func (assetClass *assetClassApi) CheckAssetViewIdentity(ctx *utils.Context) (bool, uint, error) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (assetClass *AssetClassApi) CheckAssetViewIdentity(ctx *utils.Context) (bool, uint, error) {
+	departmentID, err := assetClass.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return false, departmentID, err
 	}
-	existsDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	existsDepartment, err := assetClass.departmentService.ExistsDepartmentByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false, departmentID, err
@@ -83,14 +98,14 @@ func (assetClass *assetClassApi) CheckAssetViewIdentity(ctx *utils.Context) (boo
 		ctx.NotFound(myerror.DEPARTMENT_NOT_FOUND, myerror.DEPARTMENT_NOT_FOUND_INFO)
 		return false, departmentID, errors.New("")
 	}
-	isInDepartment := service.DepartmentService.CheckIsInDepartment(ctx, departmentID)
+	isInDepartment := assetClass.departmentService.CheckIsInDepartment(ctx, departmentID)
 	return isInDepartment, departmentID, nil
 }
 
 /*
 Handle func for POST /department/{department_id}/asset_class
 */
-func (assetClass *assetClassApi) CreateAssetClass(ctx *utils.Context) {
+func (assetClass *AssetClassApi) CreateAssetClass(ctx *utils.Context) {
 	hasIdentity, departmentID, err := assetClass.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
@@ -109,9 +124,14 @@ func (assetClass *assetClassApi) CreateAssetClass(ctx *utils.Context) {
 		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
 		return
 	}
+	err = service.CheckDepreciation(createAssetClassReq.DepreciationMethod, createAssetClassReq.ResidualRate)
+	if err != nil {
+		ctx.BadRequest(myerror.DEPRECIATION_INVALID, err.Error())
+		return
+	}
 
 	if createAssetClassReq.ParentID != 0 {
-		existsParentClass, err := service.AssetClassService.ExistsAssetClass(createAssetClassReq.ParentID)
+		existsParentClass, err := assetClass.assetClassService.ExistsAssetClass(createAssetClassReq.ParentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -122,7 +142,7 @@ func (assetClass *assetClassApi) CreateAssetClass(ctx *utils.Context) {
 		}
 	}
 
-	err = service.AssetClassService.CreateAssetClass(createAssetClassReq, departmentID)
+	err = assetClass.assetClassService.CreateAssetClass(createAssetClassReq, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -134,7 +154,7 @@ func (assetClass *assetClassApi) CreateAssetClass(ctx *utils.Context) {
 /*
 Handler func for GET /department/{department_id}/asset_class
 */
-func (assetClass *assetClassApi) GetAssetClassTree(ctx *utils.Context) {
+func (assetClass *AssetClassApi) GetAssetClassTree(ctx *utils.Context) {
 	hasIdentity, departmentID, err := assetClass.CheckAssetViewIdentity(ctx)
 	if err != nil {
 		return
@@ -143,7 +163,7 @@ func (assetClass *assetClassApi) GetAssetClassTree(ctx *utils.Context) {
 		return
 	}
 
-	assetClassTree, err := service.AssetClassService.GetSubAssetClass(0, departmentID)
+	assetClassTree, err := assetClass.assetClassService.GetSubAssetClass(0, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -159,7 +179,7 @@ func (assetClass *assetClassApi) GetAssetClassTree(ctx *utils.Context) {
 /*
 Handle func for PATCH /department/{department_id}/asset_class/{class_id}
 */
-func (assetClass *assetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
+func (assetClass *AssetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
 	hasIdentity, _, err := assetClass.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
@@ -178,13 +198,25 @@ func (assetClass *assetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
 		ctx.BadRequest(myerror.INVALID_TYPE_OF_CLASS, myerror.INVALID_TYPE_OF_CLASS_INFO)
 		return
 	}
+	method, residualRate := "", decimal.Zero
+	if modifyAssetClassReq.DepreciationMethod != nil {
+		method = *modifyAssetClassReq.DepreciationMethod
+	}
+	if modifyAssetClassReq.ResidualRate != nil {
+		residualRate = *modifyAssetClassReq.ResidualRate
+	}
+	err = service.CheckDepreciation(method, residualRate)
+	if err != nil {
+		ctx.BadRequest(myerror.DEPRECIATION_INVALID, err.Error())
+		return
+	}
 
-	classID, err := service.EntityService.GetParamID(ctx, "class_id")
+	classID, err := assetClass.entityService.GetParamID(ctx, "class_id")
 	if err != nil {
 		return
 	}
 
-	existAssetClass, err := service.AssetClassService.ExistsAssetClass(classID)
+	existAssetClass, err := assetClass.assetClassService.ExistsAssetClass(classID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -195,7 +227,7 @@ func (assetClass *assetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
 		return
 	}
 	if modifyAssetClassReq.ParentID != nil && *modifyAssetClassReq.ParentID != 0 {
-		existAssetClass, err = service.AssetClassService.ExistsAssetClass(*modifyAssetClassReq.ParentID)
+		existAssetClass, err = assetClass.assetClassService.ExistsAssetClass(*modifyAssetClassReq.ParentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -206,7 +238,7 @@ func (assetClass *assetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
 			return
 		}
 
-		isAncestor, err := service.AssetClassService.CheckIsAncestor(classID, *modifyAssetClassReq.ParentID)
+		isAncestor, err := assetClass.assetClassService.CheckIsAncestor(classID, *modifyAssetClassReq.ParentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -217,19 +249,28 @@ func (assetClass *assetClassApi) ModifyAssetClassInfo(ctx *utils.Context) {
 		}
 	}
 
-	err = service.AssetClassService.ModifyAssetClassInfo(modifyAssetClassReq, classID)
+	err = assetClass.assetClassService.ModifyAssetClassInfo(modifyAssetClassReq, classID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
+	// assets following the class depreciate by its new method from now on
+	if modifyAssetClassReq.DepreciationMethod != nil || modifyAssetClassReq.ResidualRate != nil {
+		err = assetClass.assetService.UpdateClassNetWorth(classID)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		}
+	}
+
 	ctx.Success(nil)
 }
 
 /*
 Handle func for DELETE /department/:department_id/asset_class/:class_id
 */
-func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
+func (assetClass *AssetClassApi) DeleteAssetClass(ctx *utils.Context) {
 	hasIdentity, departmentID, err := assetClass.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
@@ -238,12 +279,12 @@ func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
 		return
 	}
 
-	classID, err := service.EntityService.GetParamID(ctx, "class_id")
+	classID, err := assetClass.entityService.GetParamID(ctx, "class_id")
 	if err != nil {
 		return
 	}
 
-	existAssetClass, err := service.AssetClassService.ExistsAssetClass(classID)
+	existAssetClass, err := assetClass.assetClassService.ExistsAssetClass(classID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -253,7 +294,7 @@ func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
 		return
 	}
 
-	hasAsset, err := service.AssetClassService.ClassHasAsset(classID)
+	hasAsset, err := assetClass.assetClassService.ClassHasAsset(classID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -263,7 +304,7 @@ func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
 		return
 	}
 
-	hasSubClass, err := service.AssetClassService.ClassHasSubClass(classID, departmentID)
+	hasSubClass, err := assetClass.assetClassService.ClassHasSubClass(classID, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 	} else if hasSubClass {
@@ -271,7 +312,7 @@ func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AssetClassService.DeleteAssetClass(classID)
+	err = assetClass.assetClassService.DeleteAssetClass(classID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -283,7 +324,7 @@ func (assetClass *assetClassApi) DeleteAssetClass(ctx *utils.Context) {
 /*
 Handle func for /department/:department_id/asset_class/:class_id
 */
-func (assetClass *assetClassApi) GetSubAssetClass(ctx *utils.Context) {
+func (assetClass *AssetClassApi) GetSubAssetClass(ctx *utils.Context) {
 	hasIdentity, departmentID, err := assetClass.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
@@ -292,13 +333,13 @@ func (assetClass *assetClassApi) GetSubAssetClass(ctx *utils.Context) {
 		return
 	}
 
-	classID, err := service.EntityService.GetParamID(ctx, "class_id")
+	classID, err := assetClass.entityService.GetParamID(ctx, "class_id")
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
-	assetClassTree, err := service.AssetClassService.GetSubAssetClass(classID, departmentID)
+	assetClassTree, err := assetClass.assetClassService.GetSubAssetClass(classID, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/middleware"
@@ -19,11 +18,11 @@ import (
 
 func InitForAssetClass(r *gin.Engine) {
 	group := r.Group("/department")
-	group.Use(utils.Handler(middleware.JWTMiddleware()))
-	group.GET("/:department_id/asset_class/tree", utils.Handler(AssetClassApi.GetAssetClassTree))
-	group.POST("/:department_id/asset_class", utils.Handler(AssetClassApi.CreateAssetClass))
-	group.DELETE("/:department_id/asset_class/:class_id", utils.Handler(AssetClassApi.DeleteAssetClass))
-	group.PATCH("/:department_id/asset_class/:class_id", utils.Handler(AssetClassApi.ModifyAssetClassInfo))
+	group.Use(utils.Handler(middleware.JWTMiddleware(tokenChecker)))
+	group.GET("/:department_id/asset_class/tree", utils.Handler(apis.AssetClass.GetAssetClassTree))
+	group.POST("/:department_id/asset_class", utils.Handler(apis.AssetClass.CreateAssetClass))
+	group.DELETE("/:department_id/asset_class/:class_id", utils.Handler(apis.AssetClass.DeleteAssetClass))
+	group.PATCH("/:department_id/asset_class/:class_id", utils.Handler(apis.AssetClass.ModifyAssetClassInfo))
 }
 
 func TestAssetClass(t *testing.T) {
@@ -39,7 +38,7 @@ func TestAssetClass(t *testing.T) {
 		DepartmentSuper: true,
 		Ban:             false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -85,6 +84,7 @@ func TestAssetClass(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
 	}
+	changeInitialPassword(r, "entity_manager", password)
 	UserLogin2 := define.UserLoginReq{
 		UserName: "entity_manager",
 		Password: "123456",
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetRecycle(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("recycle_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Department.departmentService.CreateDepartment("recycle_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+		UserName:        "recycle_super",
+		Password:        password,
+		DepartmentSuper: true,
+	}, entityID, departmentID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Asset.assetService.CreateAsset(&define.CreateAssetReq{
+		AssetName: "recycle_asset",
+		Price:     decimal.New(100, 0),
+		Number:    1,
+		Type:      1,
+	}, departmentID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	assetList, err := apis.Asset.assetService.GetDepartmentAssetBasicList(departmentID)
+	assert.Equal(t, nil, err, "service error")
+	assetID := assetList[0].ID
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	res = call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "recycle_super", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	login := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &login)
+	token := login.Data.Token
+
+	assets := []define.ExpireAssetReq{{AssetID: assetID}}
+	res = call(http.MethodDelete, fmt.Sprintf("/department/%d/asset", departmentID), token,
+		define.AssetRecycleReq{Assets: assets, Reason: "duplicate"})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodGet, fmt.Sprintf("/department/%d/asset/recycle?page_size=10&page_num=0", departmentID), token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	bin := struct {
+		Data define.AssetRecycleBinResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &bin)
+	assert.Equal(t, uint(1), bin.Data.AllCount, "response failed")
+	assert.Equal(t, assetID, bin.Data.AssetList[0].AssetID, "response failed")
+	assert.Equal(t, define.ASSET_IDLE, bin.Data.AssetList[0].State, "response failed")
+	assert.Equal(t, true, time.Time(bin.Data.AssetList[0].PurgeAt).After(time.Now()), "response failed")
+
+	// a binned asset can be neither read nor edited outside the bin
+	errData := utils.ResponseData{}
+	res = call(http.MethodGet, fmt.Sprintf("/department/%d/asset/%d/versions", departmentID, assetID), token, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_NOT_FOUND, errData.Error.Code, "response failed")
+	res = call(http.MethodPut, fmt.Sprintf("/department/%d/asset/%d/depreciation", departmentID, assetID), token,
+		define.AssetDepreciationReq{DepreciationMethod: define.DEPRECIATION_STRAIGHT_LINE})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_NOT_FOUND, errData.Error.Code, "response failed")
+
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset/recycle/restore", departmentID), token,
+		define.AssetRecycleReq{Assets: assets})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	thisAsset, err := apis.Asset.assetService.GetAssetByID(assetID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.ASSET_IDLE, thisAsset.State, "response failed")
+
+	// the asset is out of the bin, so it cannot be restored twice
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset/recycle/restore", departmentID), token,
+		define.AssetRecycleReq{Assets: assets})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	errData = utils.ResponseData{}
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_STATE_INVALID, errData.Error.Code, "response failed")
+}
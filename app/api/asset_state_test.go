@@ -0,0 +1,98 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetState(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("state_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Department.departmentService.CreateDepartment("state_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	for _, name := range []string{"state_super", "state_target"} {
+		err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+			UserName:        name,
+			Password:        password,
+			DepartmentSuper: true,
+		}, entityID, departmentID)
+		assert.Equal(t, nil, err, "service error")
+	}
+	target, _ := userDao.GetUserByName("state_target")
+	err = apis.Asset.assetService.CreateAsset(&define.CreateAssetReq{
+		AssetName: "state_asset",
+		Price:     decimal.New(100, 0),
+		Number:    1,
+		Type:      1,
+	}, departmentID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	assetList, err := apis.Asset.assetService.GetDepartmentAssetBasicList(departmentID)
+	assert.Equal(t, nil, err, "service error")
+	assetID := assetList[0].ID
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	res = call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "state_super", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	login := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &login)
+	token := login.Data.Token
+
+	assets := []define.ExpireAssetReq{{AssetID: assetID}}
+	res = call(http.MethodPatch, fmt.Sprintf("/department/%d/asset/expire", departmentID), token,
+		define.ExpireAssetListReq{ExpireList: assets, Reason: "water damage"})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	// a retired asset can be neither retired again nor transferred
+	res = call(http.MethodPatch, fmt.Sprintf("/department/%d/asset/expire", departmentID), token,
+		define.ExpireAssetListReq{ExpireList: assets})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset/transfer", departmentID), token,
+		define.AssetTransferReq{UserID: target.ID, Assets: assets})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	errData := utils.ResponseData{}
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_STATE_INVALID, errData.Error.Code, "response failed")
+
+	res = call(http.MethodGet, fmt.Sprintf("/department/%d/asset/%d/states", departmentID, assetID), token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	history := struct {
+		Data define.AssetStateHistoryResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &history)
+	assert.Equal(t, 1, len(history.Data.History), "response failed")
+	assert.Equal(t, define.ASSET_RETIRE, history.Data.History[0].Event, "response failed")
+	assert.Equal(t, define.ASSET_RETIRED, history.Data.History[0].ToState, "response failed")
+	assert.Equal(t, "state_super", history.Data.History[0].ActorName, "response failed")
+	assert.Equal(t, "water damage", history.Data.History[0].Reason, "response failed")
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/middleware"
@@ -20,12 +19,22 @@ import (
 
 func InitForAsset(r *gin.Engine) {
 	group := r.Group("/department")
-	group.Use(utils.Handler(middleware.JWTMiddleware()))
-	group.GET("/:department_id/asset/list", utils.Handler(AssetApi.GetAssetList))
-	group.PATCH("/:department_id/asset/:asset_id", utils.Handler(AssetApi.ModifyAssetInfo))
-	group.POST("/:department_id/asset", utils.Handler(AssetApi.CreateAssets))
-	group.PATCH("/:department_id/asset/expire", utils.Handler(AssetApi.ExpireAsset))
-	group.POST("/:department_id/asset/transfer", utils.Handler(AssetApi.TransferAssets))
+	group.Use(utils.Handler(middleware.JWTMiddleware(tokenChecker)))
+	group.GET("/:department_id/asset/list", utils.Handler(apis.Asset.GetAssetList))
+	group.PATCH("/:department_id/asset/:asset_id", utils.Handler(apis.Asset.ModifyAssetInfo))
+	group.POST("/:department_id/asset", utils.Handler(apis.Asset.CreateAssets))
+	group.PATCH("/:department_id/asset/expire", utils.Handler(apis.Asset.ExpireAsset))
+	group.POST("/:department_id/asset/transfer", utils.Handler(apis.Asset.TransferAssets))
+	group.GET("/:department_id/asset/:asset_id/states", utils.Handler(apis.Asset.GetAssetStateHistory))
+	group.GET("/:department_id/asset/:asset_id/versions", utils.Handler(apis.Asset.GetAssetVersions))
+	group.POST("/:department_id/asset/:asset_id/versions/:version/revert", utils.Handler(apis.Asset.RevertAsset))
+	group.GET("/:department_id/asset/:asset_id/depreciation", utils.Handler(apis.Asset.PreviewDepreciation))
+	group.PUT("/:department_id/asset/:asset_id/depreciation", utils.Handler(apis.Asset.ModifyAssetDepreciation))
+	group.DELETE("/:department_id/asset", utils.Handler(apis.Asset.DeleteAssets))
+	group.GET("/:department_id/asset/recycle", utils.Handler(apis.Asset.GetRecycleBin))
+	group.POST("/:department_id/asset/recycle/restore", utils.Handler(apis.Asset.RestoreAssets))
+	group.PATCH("/:department_id/asset/:asset_id/property", utils.Handler(apis.Asset.ModifyAssetProperty))
+	group.POST("/:department_id/asset/:asset_id/property", utils.Handler(apis.Asset.CreateAssetProperty))
 }
 
 func TestAsset(t *testing.T) {
@@ -41,7 +50,7 @@ func TestAsset(t *testing.T) {
 		DepartmentSuper: true,
 		Ban:             false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -87,6 +96,7 @@ func TestAsset(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
 	}
+	changeInitialPassword(r, "entity_manager", password)
 	UserLogin2 := define.UserLoginReq{
 		UserName: "entity_manager",
 		Password: "123456",
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetVersion(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("version_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Department.departmentService.CreateDepartment("version_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+		UserName:        "version_super",
+		Password:        password,
+		DepartmentSuper: true,
+	}, entityID, departmentID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Asset.assetService.CreateAsset(&define.CreateAssetReq{
+		AssetName: "version_asset",
+		Price:     decimal.New(100, 0),
+		Number:    1,
+		Type:      1,
+	}, departmentID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	assetList, err := apis.Asset.assetService.GetDepartmentAssetBasicList(departmentID)
+	assert.Equal(t, nil, err, "service error")
+	assetID := assetList[0].ID
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	res = call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "version_super", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	login := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &login)
+	token := login.Data.Token
+
+	res = call(http.MethodPatch, fmt.Sprintf("/department/%d/asset/%d", departmentID, assetID), token,
+		define.ModifyAssetInfoReq{AssetName: "version_asset", Price: decimal.New(250, 0), Type: 1, Number: 1})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodGet, fmt.Sprintf("/department/%d/asset/%d/versions", departmentID, assetID), token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	versions := struct {
+		Data define.AssetVersionListResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &versions)
+	assert.Equal(t, 1, len(versions.Data.Versions), "response failed")
+	assert.Equal(t, "version_super", versions.Data.Versions[0].ActorName, "response failed")
+	assert.Contains(t, versions.Data.Versions[0].Changes, "price", "response failed")
+	assert.NotContains(t, versions.Data.Versions[0].Changes, "name", "response failed")
+
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset/%d/versions/0/revert", departmentID, assetID), token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	thisAsset, err := apis.Asset.assetService.GetAssetByID(assetID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, decimal.New(100, 0).Equal(thisAsset.Price), "response failed")
+
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset/%d/versions/99/revert", departmentID, assetID), token, nil)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+	errData := utils.ResponseData{}
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_VERSION_NOT_FOUND, errData.Error.Code, "response failed")
+}
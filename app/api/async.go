@@ -12,35 +12,43 @@ import (
 	"github.com/thoas/go-funk"
 )
 
-type asyncApi struct {
+type AsyncApi struct {
+	asyncService  service.AsyncServiceInterface
+	entityService service.EntityServiceInterface
+	userService   service.UserServiceInterface
+	permissionApi *PermissionApi
 }
 
-var AsyncApi *asyncApi
-
-func newAsyncApi() *asyncApi {
-	return &asyncApi{}
-}
-
-func init() {
-	AsyncApi = newAsyncApi()
+func NewAsyncApi(
+	asyncService service.AsyncServiceInterface,
+	entityService service.EntityServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *AsyncApi {
+	return &AsyncApi{
+		asyncService:  asyncService,
+		entityService: entityService,
+		userService:   userService,
+		permissionApi: permissionApi,
+	}
 }
 
 /*
 Handle func for GET /user/:user_id/async/list
 */
-func (asy *asyncApi) GetUserAsyncTasks(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (asy *AsyncApi) GetUserAsyncTasks(ctx *utils.Context) {
+	userID, err := asy.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	userInfo, err := service.UserService.GetUserByID(userID)
+	userInfo, err := asy.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -49,7 +57,7 @@ func (asy *asyncApi) GetUserAsyncTasks(ctx *utils.Context) {
 		return
 	}
 
-	taskList, err := service.AsyncService.GetUserAsyncTasks(userID)
+	taskList, err := asy.asyncService.GetUserAsyncTasks(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -62,7 +70,7 @@ func (asy *asyncApi) GetUserAsyncTasks(ctx *utils.Context) {
 			UserID:       task.UserID,
 			Username:     task.User.UserName,
 			State:        task.State,
-			DownloadLink: task.DownloadLink,
+			DownloadLink: asy.asyncService.GetDownloadLink(task),
 			Message:      task.Message,
 			LogType:      task.LogType,
 		}
@@ -78,19 +86,19 @@ func (asy *asyncApi) GetUserAsyncTasks(ctx *utils.Context) {
 /*
 Handle func for POST /user/:user_id/async
 */
-func (asy *asyncApi) CreateAsyncTask(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (asy *AsyncApi) CreateAsyncTask(ctx *utils.Context) {
+	userID, err := asy.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	userInfo, err := service.UserService.GetUserByID(userID)
+	userInfo, err := asy.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -104,33 +112,25 @@ func (asy *asyncApi) CreateAsyncTask(ctx *utils.Context) {
 	}
 
 	if req.Type == 0 {
-		if !userInfo.DepartmentSuper {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-			return
-		} else if userInfo.DepartmentID != req.DepartmentID {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		if !asy.permissionApi.Check(ctx, define.PERM_ASSET_IMPORT, userInfo.EntityID, req.DepartmentID) {
 			return
 		}
 
 		req.EntityID = userInfo.EntityID
 
-		err = service.AsyncService.CreateAsyncTask(userID, &req)
+		err = asy.asyncService.CreateAsyncTask(userID, &req)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
 	} else {
-		if !userInfo.EntitySuper {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-			return
-		} else if userInfo.EntityID != req.EntityID {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		if !asy.permissionApi.Check(ctx, define.PERM_LOG_EXPORT, req.EntityID, 0) {
 			return
 		}
 
 		req.DepartmentID = userInfo.DepartmentID
 
-		err = service.AsyncService.CreateAsyncTask(userID, &req)
+		err = asy.asyncService.CreateAsyncTask(userID, &req)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -143,23 +143,23 @@ func (asy *asyncApi) CreateAsyncTask(ctx *utils.Context) {
 /*
 Handle func for PATCH /user/:user_id/async/:task_id
 */
-func (asy *asyncApi) ModifyAsyncState(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (asy *AsyncApi) ModifyAsyncState(ctx *utils.Context) {
+	userID, err := asy.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
-	taskID, err := service.EntityService.GetParamID(ctx, "task_id")
+	taskID, err := asy.entityService.GetParamID(ctx, "task_id")
 	if err != nil {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	taskInfo, err := service.AsyncService.GetAsyncTaskByID(taskID)
+	taskInfo, err := asy.asyncService.GetAsyncTaskByID(taskID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -176,13 +176,11 @@ func (asy *asyncApi) ModifyAsyncState(ctx *utils.Context) {
 	}
 
 	if taskInfo.Type == 0 {
-		if !thisUser.DepartmentSuper || thisUser.DepartmentID != taskInfo.DepartmentID {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		if !asy.permissionApi.Check(ctx, define.PERM_ASSET_IMPORT, taskInfo.EntityID, taskInfo.DepartmentID) {
 			return
 		}
 	} else {
-		if !thisUser.EntitySuper || thisUser.EntityID != taskInfo.EntityID {
-			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		if !asy.permissionApi.Check(ctx, define.PERM_LOG_EXPORT, taskInfo.EntityID, 0) {
 			return
 		}
 	}
@@ -195,7 +193,7 @@ func (asy *asyncApi) ModifyAsyncState(ctx *utils.Context) {
 		return
 	}
 
-	err = service.AsyncService.ModifyAsyncTaskState(taskID, req.State)
+	err = asy.asyncService.ModifyAsyncTaskState(taskID, req.State)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
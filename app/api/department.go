@@ -10,21 +10,38 @@ import (
 	"github.com/jinzhu/copier"
 )
 
-type departmentApi struct {
+type DepartmentApi struct {
+	assetService      service.AssetServiceInterface
+	departmentService service.DepartmentServiceInterface
+	entityService     service.EntityServiceInterface
+	passwordService   service.PasswordServiceInterface
+	userService       service.UserServiceInterface
+	assetClassApi     *AssetClassApi
+	permissionApi     *PermissionApi
 }
 
-var DepartmentApi *departmentApi
-
-func newDepartmentApi() *departmentApi {
-	return &departmentApi{}
-}
-
-func init() {
-	DepartmentApi = newDepartmentApi()
+func NewDepartmentApi(
+	assetService service.AssetServiceInterface,
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	passwordService service.PasswordServiceInterface,
+	userService service.UserServiceInterface,
+	assetClassApi *AssetClassApi,
+	permissionApi *PermissionApi,
+) *DepartmentApi {
+	return &DepartmentApi{
+		assetService:      assetService,
+		departmentService: departmentService,
+		entityService:     entityService,
+		passwordService:   passwordService,
+		userService:       userService,
+		assetClassApi:     assetClassApi,
+		permissionApi:     permissionApi,
+	}
 }
 
-func (department *departmentApi) CheckEntityDepartmentValid(ctx *utils.Context, entityID uint, departmentID uint) bool {
-	existsEntity, err := service.EntityService.ExistsEntityByID(entityID)
+func (department *DepartmentApi) CheckEntityDepartmentValid(ctx *utils.Context, entityID uint, departmentID uint) bool {
+	existsEntity, err := department.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false
@@ -34,7 +51,7 @@ func (department *departmentApi) CheckEntityDepartmentValid(ctx *utils.Context,
 		return false
 	}
 
-	existsDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	existsDepartment, err := department.departmentService.ExistsDepartmentByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false
@@ -44,7 +61,7 @@ func (department *departmentApi) CheckEntityDepartmentValid(ctx *utils.Context,
 		return false
 	}
 
-	flag, err := service.DepartmentService.CheckDepartmentInEntity(entityID, departmentID)
+	flag, err := department.departmentService.CheckDepartmentInEntity(entityID, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false
@@ -56,12 +73,12 @@ func (department *departmentApi) CheckEntityDepartmentValid(ctx *utils.Context,
 	return true
 }
 
-func (department *departmentApi) GetTwoIDs(ctx *utils.Context) (uint, uint, error) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (department *DepartmentApi) GetTwoIDs(ctx *utils.Context) (uint, uint, error) {
+	entityID, err := department.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return 0, 0, err
 	}
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+	departmentID, err := department.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return 0, 0, err
 	}
@@ -71,24 +88,14 @@ func (department *departmentApi) GetTwoIDs(ctx *utils.Context) (uint, uint, erro
 /*
 只有本实体的系统管理员才可以进行修改该实体内的部门相关操作
 */
-func (department *departmentApi) CheckDepartmentModifyIdentity(ctx *utils.Context, entityID uint) bool {
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return false
-	}
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !isInEntity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return false
-	}
-	return true
+func (department *DepartmentApi) CheckDepartmentModifyIdentity(ctx *utils.Context, entityID uint) bool {
+	return department.permissionApi.Check(ctx, define.PERM_DEPARTMENT_MANAGE, entityID, 0)
 }
 
 /*
 本实体内的资产/系统管理员的权限
 */
-func (department *departmentApi) CheckDepartmentSuperIdentity(ctx *utils.Context) (uint, uint, bool) {
+func (department *DepartmentApi) CheckDepartmentSuperIdentity(ctx *utils.Context) (uint, uint, bool) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return 0, 0, false
@@ -99,15 +106,7 @@ func (department *departmentApi) CheckDepartmentSuperIdentity(ctx *utils.Context
 		return 0, 0, false
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !entitySuper && !departmentSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return 0, 0, false
-	}
-	identity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !identity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !department.permissionApi.CheckInEntity(ctx, define.PERM_USER_MANAGE, entityID) {
 		return 0, 0, false
 	}
 
@@ -117,14 +116,12 @@ func (department *departmentApi) CheckDepartmentSuperIdentity(ctx *utils.Context
 /*
 Handle func for POST /entity/{entity_id}/department and /entity/{entity_id}/department/{department_id}/department
 */
-func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
-	isEntitySuper := service.UserService.EntitySuper(ctx)
-	if !isEntitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+func (department *DepartmentApi) CreateDepartment(ctx *utils.Context) {
+	entityID, err := department.entityService.GetParamID(ctx, "entity_id")
+	if err != nil {
 		return
 	}
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
-	if err != nil {
+	if !department.CheckDepartmentModifyIdentity(ctx, entityID) {
 		return
 	}
 
@@ -141,7 +138,7 @@ func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
 
 	param := ctx.Param("department_id")
 	if param == "" {
-		existsEntity, err := service.EntityService.ExistsEntityByID(entityID)
+		existsEntity, err := department.entityService.ExistsEntityByID(entityID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -152,7 +149,7 @@ func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
 		}
 		createDepartmentReq.EntityID = entityID
 	} else {
-		departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+		departmentID, err := department.entityService.GetParamID(ctx, "department_id")
 		if err != nil {
 			return
 		}
@@ -164,13 +161,7 @@ func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
 		createDepartmentReq.DepartmentID = departmentID
 	}
 
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !isInEntity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-
-	existsDepartment, err := service.DepartmentService.ExistsDepartmentSub(createDepartmentReq.DepartmentName, createDepartmentReq.EntityID, createDepartmentReq.DepartmentID)
+	existsDepartment, err := department.departmentService.ExistsDepartmentSub(createDepartmentReq.DepartmentName, createDepartmentReq.EntityID, createDepartmentReq.DepartmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -179,7 +170,7 @@ func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.CreateDepartment(createDepartmentReq.DepartmentName, createDepartmentReq.EntityID, createDepartmentReq.DepartmentID)
+	err = department.departmentService.CreateDepartment(createDepartmentReq.DepartmentName, createDepartmentReq.EntityID, createDepartmentReq.DepartmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -190,7 +181,7 @@ func (department *departmentApi) CreateDepartment(ctx *utils.Context) {
 /*
 Handle func for DELETE /entity/{entity_id}/department/{department_id}
 */
-func (department *departmentApi) DeleteDepartment(ctx *utils.Context) {
+func (department *DepartmentApi) DeleteDepartment(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -205,7 +196,7 @@ func (department *departmentApi) DeleteDepartment(ctx *utils.Context) {
 		return
 	}
 
-	// existDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	// existDepartment, err := department.departmentService.ExistsDepartmentByID(departmentID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -214,7 +205,7 @@ func (department *departmentApi) DeleteDepartment(ctx *utils.Context) {
 	// 	return
 	// }
 
-	hasUsers, err := service.DepartmentService.DepartmentHasUsers(departmentID)
+	hasUsers, err := department.departmentService.DepartmentHasUsers(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -224,7 +215,7 @@ func (department *departmentApi) DeleteDepartment(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.DeleteDepartment(departmentID)
+	err = department.departmentService.DeleteDepartment(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -236,7 +227,7 @@ func (department *departmentApi) DeleteDepartment(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/department/{department_id}
 */
-func (department *departmentApi) GetDepartmentByID(ctx *utils.Context) {
+func (department *DepartmentApi) GetDepartmentByID(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -245,17 +236,13 @@ func (department *departmentApi) GetDepartmentByID(ctx *utils.Context) {
 	if !isValid {
 		return
 	}
-	identity, err := service.DepartmentService.CheckDepartmentIdentity(ctx, entityID, departmentID)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-	if !identity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	// members see their own department, managers also those below theirs
+	if !department.departmentService.CheckIsInDepartment(ctx, departmentID) &&
+		!department.permissionApi.CheckInDepartmentTree(ctx, define.PERM_USER_MANAGE, departmentID) {
 		return
 	}
 
-	thisDepartment, err := service.DepartmentService.GetDepartmentInfoByID(departmentID)
+	thisDepartment, err := department.departmentService.GetDepartmentInfoByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -283,13 +270,13 @@ func (department *departmentApi) GetDepartmentByID(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/department/{department_id}/department/list
 */
-func (department *departmentApi) GetSubDepartments(ctx *utils.Context) {
+func (department *DepartmentApi) GetSubDepartments(ctx *utils.Context) {
 	_, departmentID, isOK := department.CheckDepartmentSuperIdentity(ctx)
 	if !isOK {
 		return
 	}
 
-	departmentList, err := service.DepartmentService.GetSubDepartments(departmentID)
+	departmentList, err := department.departmentService.GetSubDepartments(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -309,7 +296,7 @@ func (department *departmentApi) GetSubDepartments(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/department/{department_id}/user/list
 */
-func (department *departmentApi) GetAllUsersUnderDepartment(ctx *utils.Context) {
+func (department *DepartmentApi) GetAllUsersUnderDepartment(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -320,23 +307,11 @@ func (department *departmentApi) GetAllUsersUnderDepartment(ctx *utils.Context)
 		return
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !entitySuper && !departmentSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-	identity, err := service.DepartmentService.CheckDepartmentIdentity(ctx, entityID, departmentID)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-	if !identity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !department.permissionApi.CheckInDepartmentTree(ctx, define.PERM_USER_MANAGE, departmentID) {
 		return
 	}
 
-	userList, err := service.DepartmentService.GetAllUsers(departmentID)
+	userList, err := department.departmentService.GetAllUsers(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -356,7 +331,7 @@ func (department *departmentApi) GetAllUsersUnderDepartment(ctx *utils.Context)
 /*
 Handle func for GET /entity/:entity_id/department/:department_id/user/sub
 */
-func (department *departmentApi) GetDepartmentSubUsers(ctx *utils.Context) {
+func (department *DepartmentApi) GetDepartmentSubUsers(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -367,13 +342,13 @@ func (department *departmentApi) GetDepartmentSubUsers(ctx *utils.Context) {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.EntityID != entityID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	userList, err := service.DepartmentService.GetSubUsers(departmentID)
+	userList, err := department.departmentService.GetSubUsers(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -393,7 +368,7 @@ func (department *departmentApi) GetDepartmentSubUsers(ctx *utils.Context) {
 /*
 Handle func for POST /entity/{entity_id}/department/{department_id}/user
 */
-func (department *departmentApi) CreateUserInDepartment(ctx *utils.Context) {
+func (department *DepartmentApi) CreateUserInDepartment(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -416,7 +391,7 @@ func (department *departmentApi) CreateUserInDepartment(ctx *utils.Context) {
 		return
 	}
 
-	existsUser, err := service.UserService.ExistsUser(createUserReq.UserName)
+	existsUser, err := department.userService.ExistsUser(createUserReq.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -426,7 +401,17 @@ func (department *departmentApi) CreateUserInDepartment(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.CreateDepartmentUser(createUserReq, entityID, departmentID)
+	err = department.passwordService.Validate(entityID, createUserReq.Password)
+	if err != nil {
+		passwordError(ctx, err)
+		return
+	}
+	err = department.departmentService.CreateDepartmentUser(createUserReq, entityID, departmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	err = department.passwordService.RequireChange(createUserReq.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -438,7 +423,7 @@ func (department *departmentApi) CreateUserInDepartment(ctx *utils.Context) {
 /*
 Handle func for POST /entity/{entity_id}/department/{department_id}/manager
 */
-func (department *departmentApi) SetManager(ctx *utils.Context) {
+func (department *DepartmentApi) SetManager(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -461,7 +446,7 @@ func (department *departmentApi) SetManager(ctx *utils.Context) {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByName(setManagerReq.UserName)
+	thisUser, err := department.userService.GetUserByName(setManagerReq.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -475,7 +460,7 @@ func (department *departmentApi) SetManager(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.SetDepartmentManager(setManagerReq.UserName, departmentID)
+	err = department.departmentService.SetDepartmentManager(setManagerReq.UserName, departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -487,7 +472,7 @@ func (department *departmentApi) SetManager(ctx *utils.Context) {
 /*
 Handle func for DELETE /entity/{entity_id}/department/{department_id}/manager/{user_id}
 */
-func (department *departmentApi) DeleteDepartmentManager(ctx *utils.Context) {
+func (department *DepartmentApi) DeleteDepartmentManager(ctx *utils.Context) {
 	entityID, departmentID, err := department.GetTwoIDs(ctx)
 	if err != nil {
 		return
@@ -503,12 +488,12 @@ func (department *departmentApi) DeleteDepartmentManager(ctx *utils.Context) {
 		return
 	}
 
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+	userID, err := department.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := department.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -526,7 +511,7 @@ func (department *departmentApi) DeleteDepartmentManager(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.DeleteDepartmentManager(userID)
+	err = department.departmentService.DeleteDepartmentManager(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -538,14 +523,14 @@ func (department *departmentApi) DeleteDepartmentManager(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/department/{department_id}/manager
 */
-func (department *departmentApi) GetDepartmentManager(ctx *utils.Context) {
+func (department *DepartmentApi) GetDepartmentManager(ctx *utils.Context) {
 	// abandon later
-	// entitySuper := service.UserService.EntitySuper(ctx)
+	// entitySuper := department.userService.EntitySuper(ctx)
 	// if !entitySuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	// isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
+	// isInEntity := department.entityService.CheckIsInEntity(ctx, entityID)
 	// if !isInEntity {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
@@ -556,7 +541,7 @@ func (department *departmentApi) GetDepartmentManager(ctx *utils.Context) {
 		return
 	}
 
-	managerList, err := service.DepartmentService.GetDepartmentManagerList(departmentID)
+	managerList, err := department.departmentService.GetDepartmentManagerList(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -578,23 +563,23 @@ func (department *departmentApi) GetDepartmentManager(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/department/tree
 */
-func (department *departmentApi) GetDepartmentTree(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (department *DepartmentApi) GetDepartmentTree(ctx *utils.Context) {
+	entityID, err := department.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
-	// entitySuper := service.UserService.EntitySuper(ctx)
-	// departmentSuper := service.UserService.DepartmentSuper(ctx)
+	// entitySuper := department.userService.EntitySuper(ctx)
+	// departmentSuper := department.userService.DepartmentSuper(ctx)
 	// if !entitySuper && !departmentSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	operatorInfo := UserApi.GetOperatorInfo(ctx)
+	operatorInfo := GetOperatorInfo(ctx)
 	if operatorInfo.EntityID != entityID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := department.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -603,7 +588,7 @@ func (department *departmentApi) GetDepartmentTree(ctx *utils.Context) {
 		return
 	}
 
-	departmentSubTree, err := service.DepartmentService.GetSubDepartmentTreeNodes(0, entityID)
+	departmentSubTree, err := department.departmentService.GetSubDepartmentTreeNodes(0, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -619,8 +604,8 @@ func (department *departmentApi) GetDepartmentTree(ctx *utils.Context) {
 /*
 Handle func for POST /department/:department_id/template
 */
-func (department *departmentApi) DefineDepartmentAssetTemplate(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (department *DepartmentApi) DefineDepartmentAssetTemplate(ctx *utils.Context) {
+	hasIdentity, departmentID, err := department.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -635,7 +620,7 @@ func (department *departmentApi) DefineDepartmentAssetTemplate(ctx *utils.Contex
 		return
 	}
 
-	err = service.DepartmentService.ModifyDepartmentTemplate(departmentID, &req)
+	err = department.departmentService.ModifyDepartmentTemplate(departmentID, &req)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -647,12 +632,12 @@ func (department *departmentApi) DefineDepartmentAssetTemplate(ctx *utils.Contex
 /*
 Handle func for GET /department/:department_id/template
 */
-func (department *departmentApi) GetDepartmentTemplate(ctx *utils.Context) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (department *DepartmentApi) GetDepartmentTemplate(ctx *utils.Context) {
+	departmentID, err := department.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return
 	}
-	thisDepartment, err := service.DepartmentService.GetDepartmentInfoByID(departmentID)
+	thisDepartment, err := department.departmentService.GetDepartmentInfoByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -660,12 +645,12 @@ func (department *departmentApi) GetDepartmentTemplate(ctx *utils.Context) {
 		ctx.BadRequest(myerror.DEPARTMENT_NOT_FOUND, myerror.DEPARTMENT_NOT_FOUND_INFO)
 		return
 	}
-	// isDepartmentSuper := service.UserService.DepartmentSuper(ctx)
+	// isDepartmentSuper := department.userService.DepartmentSuper(ctx)
 	// if !isDepartmentSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	isInDepartment := service.DepartmentService.CheckIsInDepartment(ctx, departmentID)
+	isInDepartment := department.departmentService.CheckIsInDepartment(ctx, departmentID)
 	if !isInDepartment {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
@@ -681,8 +666,8 @@ func (department *departmentApi) GetDepartmentTemplate(ctx *utils.Context) {
 /*
 Handle func for POST /department/:department_id/warn
 */
-func (department *departmentApi) SetDepartmentWarnStrategy(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (department *DepartmentApi) SetDepartmentWarnStrategy(ctx *utils.Context) {
+	hasIdentity, departmentID, err := department.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -697,7 +682,7 @@ func (department *departmentApi) SetDepartmentWarnStrategy(ctx *utils.Context) {
 		return
 	}
 
-	err = service.DepartmentService.ModifyDepartmentThreshold(departmentID, req.Threshold)
+	err = department.departmentService.ModifyDepartmentThreshold(departmentID, req.Threshold)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -709,8 +694,8 @@ func (department *departmentApi) SetDepartmentWarnStrategy(ctx *utils.Context) {
 /*
 Handle func for GET /department/:department_id/warn
 */
-func (department *departmentApi) GetDepartmentAssetWarnInfo(ctx *utils.Context) {
-	hasIdentity, thisDepartment, err := AssetClassApi.CheckAssetIdentityReturnDepartment(ctx)
+func (department *DepartmentApi) GetDepartmentAssetWarnInfo(ctx *utils.Context) {
+	hasIdentity, thisDepartment, err := department.assetClassApi.CheckAssetIdentityReturnDepartment(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -718,14 +703,14 @@ func (department *departmentApi) GetDepartmentAssetWarnInfo(ctx *utils.Context)
 		return
 	}
 
-	count, err := service.AssetService.GetDepartmentAssetCount(thisDepartment.ID)
+	count, err := department.assetService.GetDepartmentAssetCount(thisDepartment.ID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
 	isWarn := (count <= int64(thisDepartment.Threshold))
-	assetList, err := service.AssetService.GetDepartmentAssetInWarn(thisDepartment.ID)
+	assetList, err := department.assetService.GetDepartmentAssetInWarn(thisDepartment.ID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -757,7 +742,7 @@ func (department *departmentApi) GetDepartmentAssetWarnInfo(ctx *utils.Context)
 	// 		Warn: warnAsset.Warn,
 	// 	}
 	// }).([]define.AssetBasicInfo)
-	warnAssets := service.AssetService.TransformAssetBasicInfo(assetList)
+	warnAssets := department.assetService.TransformAssetBasicInfo(assetList)
 	warnInfoRes := define.DepartmentWarnInfo{
 		Count:          count,
 		CountThreshold: thisDepartment.Threshold,
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/utils"
@@ -29,7 +28,7 @@ func TestDepartment(t *testing.T) {
 		DepartmentSuper: true,
 		Ban:             false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -87,6 +86,7 @@ func TestDepartment(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
 	}
+	changeInitialPassword(r, "entity_manager", password)
 	{
 		req := GetRequest(http.MethodPost, "/entity/1/manager", headerJson, GetJsonBody(managerReq2))
 		res = httptest.NewRecorder()
@@ -344,7 +344,7 @@ func TestDepartmentNoPermission(t *testing.T) {
 		DepartmentSuper: true,
 		Ban:             false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -369,6 +369,12 @@ func TestDepartmentNoPermission(t *testing.T) {
 	headerJsonToken["Authorization"] = token
 	headerFormToken["Authorization"] = token
 
+	// names that are already taken
+	err = apis.Entity.entityService.CreateEntity("test_entity1")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Department.departmentService.CreateDepartment("test_department", 1, 0)
+	assert.Equal(t, nil, err, "service error")
+
 	CreateEntity := define.CreateEntityReq{
 		EntityName: "test_entity1",
 	}
@@ -390,6 +396,7 @@ func TestDepartmentNoPermission(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
 	}
+	changeInitialPassword(r, "entity_manager", password)
 	UserLogin2 := define.UserLoginReq{
 		UserName: "entity_manager",
 		Password: "123456",
@@ -429,7 +436,7 @@ func TestDepartmentNoPermission(t *testing.T) {
 		Password: utils.CreateMD5("21232f297a57a5a743894a0e4a801fc3"),
 		Ban:      false,
 	}
-	dao.UserDao.Create(no)
+	userDao.Create(no)
 
 	UserLogin = define.UserLoginReq{
 		UserName: "no",
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetDepreciation(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("depreciation_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Department.departmentService.CreateDepartment("depreciation_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+		UserName:        "depreciation_super",
+		Password:        password,
+		DepartmentSuper: true,
+	}, entityID, departmentID)
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	res = call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "depreciation_super", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	login := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &login)
+	token := login.Data.Token
+	errorCode := func(res *httptest.ResponseRecorder) int {
+		errData := utils.ResponseData{}
+		json.Unmarshal(res.Body.Bytes(), &errData)
+		return errData.Error.Code
+	}
+
+	// an unknown method is refused, a known one is kept on the class
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset_class", departmentID), token, define.CreateAssetClassReq{
+		ClassName:          "depreciation_class",
+		Type:               1,
+		DepreciationMethod: "units_of_production",
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Equal(t, myerror.DEPRECIATION_INVALID, errorCode(res), "response failed")
+	res = call(http.MethodPost, fmt.Sprintf("/department/%d/asset_class", departmentID), token, define.CreateAssetClassReq{
+		ClassName:          "depreciation_class",
+		Type:               1,
+		DepreciationMethod: define.DEPRECIATION_DOUBLE_DECLINING,
+		ResidualRate:       decimal.NewFromFloat(0.1),
+	})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	classList, err := apis.AssetClass.assetClassService.GetSubAssetClass(0, departmentID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.DEPRECIATION_DOUBLE_DECLINING, classList[0].DepreciationMethod, "service error")
+
+	err = apis.Asset.assetService.CreateAsset(&define.CreateAssetReq{
+		AssetName: "depreciation_asset",
+		Price:     decimal.New(1000, 0),
+		Number:    1,
+		Type:      1,
+		ClassID:   classList[0].ClassID,
+		Expire:    100,
+	}, departmentID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	assetList, err := apis.Asset.assetService.GetDepartmentAssetBasicList(departmentID)
+	assert.Equal(t, nil, err, "service error")
+	assetID := assetList[0].ID
+
+	url := fmt.Sprintf("/department/%d/asset/%d/depreciation", departmentID, assetID)
+	preview := struct {
+		Data define.AssetDepreciationResponse `json:"data"`
+	}{}
+	res = call(http.MethodGet, url+"?step=50", token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &preview)
+	assert.Equal(t, define.DEPRECIATION_DOUBLE_DECLINING, preview.Data.DepreciationMethod, "response failed")
+	assert.Equal(t, 3, len(preview.Data.Schedule), "response failed")
+	assert.Equal(t, "100", preview.Data.Schedule[2].NetWorth.String(), "response failed")
+
+	// another method can be previewed without changing the asset
+	res = call(http.MethodGet, url+"?step=50&method="+define.DEPRECIATION_STRAIGHT_LINE, token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &preview)
+	assert.Equal(t, "550", preview.Data.Schedule[1].NetWorth.String(), "response failed")
+	res = call(http.MethodGet, url+"?method=teleport", token, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodPut, url, token, define.AssetDepreciationReq{
+		ResidualRate: decimal.NewNullDecimal(decimal.NewFromInt(2)),
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Equal(t, myerror.DEPRECIATION_INVALID, errorCode(res), "response failed")
+	res = call(http.MethodPut, url, token, define.AssetDepreciationReq{
+		ResidualRate: decimal.NewNullDecimal(decimal.RequireFromString("0.12345")),
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPut, url, token, define.AssetDepreciationReq{
+		DepreciationMethod: define.DEPRECIATION_SUM_OF_YEARS,
+	})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, url, token, nil)
+	json.Unmarshal(res.Body.Bytes(), &preview)
+	assert.Equal(t, define.DEPRECIATION_SUM_OF_YEARS, preview.Data.DepreciationMethod, "response failed")
+	assert.Equal(t, "100", preview.Data.ResidualValue.String(), "response failed")
+}
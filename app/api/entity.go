@@ -2,45 +2,58 @@ package api
 
 import (
 	"asset-management/app/define"
+	"asset-management/app/model"
 	"asset-management/app/service"
 	"asset-management/myerror"
 	"asset-management/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin/binding"
 	"github.com/jinzhu/copier"
 )
 
-type entityApi struct {
+type EntityApi struct {
+	entityService    service.EntityServiceInterface
+	loginLockService service.LoginLockServiceInterface
+	passwordService  service.PasswordServiceInterface
+	twoFactorService service.TwoFactorServiceInterface
+	userService      service.UserServiceInterface
+	permissionApi    *PermissionApi
 }
 
-var EntityApi *entityApi
-
-func newEntityApi() *entityApi {
-	return &entityApi{}
-}
-
-func init() {
-	EntityApi = newEntityApi()
+func NewEntityApi(
+	entityService service.EntityServiceInterface,
+	loginLockService service.LoginLockServiceInterface,
+	passwordService service.PasswordServiceInterface,
+	twoFactorService service.TwoFactorServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *EntityApi {
+	return &EntityApi{
+		entityService:    entityService,
+		loginLockService: loginLockService,
+		passwordService:  passwordService,
+		twoFactorService: twoFactorService,
+		userService:      userService,
+		permissionApi:    permissionApi,
+	}
 }
 
 /*
-检查与实体有关的查看信息权限，暂时认为只有超级管理员以及本实体的系统管理员有该权限
+检查与实体有关的查看信息权限，需要在该实体上拥有 entity.manage
 修改实体信息权限与此相同
 */
-func (entity *entityApi) CheckViewIdentity(ctx *utils.Context) (bool, uint) {
-	systemSuper := service.UserService.SystemSuper(ctx)
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !systemSuper && !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+func (entity *EntityApi) CheckViewIdentity(ctx *utils.Context) (bool, uint) {
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
+	if err != nil {
 		return false, 0
 	}
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
-	if err != nil {
+	if !entity.permissionApi.Check(ctx, define.PERM_ENTITY_MANAGE, entityID, 0) {
 		return false, 0
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := entity.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return false, 0
@@ -49,26 +62,14 @@ func (entity *entityApi) CheckViewIdentity(ctx *utils.Context) (bool, uint) {
 		ctx.NotFound(myerror.ENTITY_NOT_FOUND, myerror.ENTITY_NOT_FOUND_INFO)
 		return false, 0
 	}
-
-	if systemSuper {
-		return true, entityID
-	}
-
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !isInEntity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return false, 0
-	}
 	return true, entityID
 }
 
 /*
 Handle func for POST /entity
 */
-func (entity *entityApi) CreateEntity(ctx *utils.Context) {
-	isSystemSuper := service.UserService.SystemSuper(ctx)
-	if !isSystemSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+func (entity *EntityApi) CreateEntity(ctx *utils.Context) {
+	if !entity.permissionApi.Check(ctx, define.PERM_SYSTEM_MANAGE, 0, 0) {
 		return
 	}
 
@@ -82,7 +83,7 @@ func (entity *entityApi) CreateEntity(ctx *utils.Context) {
 		ctx.BadRequest(myerror.ENTITY_NAME_CANNOT_BE_EMPTY, myerror.ENTITY_NAME_CANNOT_BE_EMPTY_INFO)
 		return
 	}
-	isExist, err := service.EntityService.ExistsEntityByName(createReq.EntityName)
+	isExist, err := entity.entityService.ExistsEntityByName(createReq.EntityName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -91,7 +92,7 @@ func (entity *entityApi) CreateEntity(ctx *utils.Context) {
 		return
 	}
 
-	err = service.EntityService.CreateEntity(createReq.EntityName)
+	err = entity.entityService.CreateEntity(createReq.EntityName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -102,18 +103,18 @@ func (entity *entityApi) CreateEntity(ctx *utils.Context) {
 /*
 Handle func for DELETE /entity/{entity_id}
 */
-func (entity *entityApi) DeleteEntity(ctx *utils.Context) {
-	// isSystemSuper := service.UserService.SystemSuper(ctx)
+func (entity *EntityApi) DeleteEntity(ctx *utils.Context) {
+	// isSystemSuper := entity.userService.SystemSuper(ctx)
 	// if !isSystemSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
 
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := entity.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -122,7 +123,7 @@ func (entity *entityApi) DeleteEntity(ctx *utils.Context) {
 		return
 	}
 
-	hasUsers, err := service.EntityService.EntityHasUser(entityID)
+	hasUsers, err := entity.entityService.EntityHasUser(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -132,7 +133,7 @@ func (entity *entityApi) DeleteEntity(ctx *utils.Context) {
 		return
 	}
 
-	err = service.EntityService.DeleteEntity(entityID)
+	err = entity.entityService.DeleteEntity(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -143,13 +144,13 @@ func (entity *entityApi) DeleteEntity(ctx *utils.Context) {
 /*
 Handle func for GET /entity/list
 */
-func (entity *entityApi) GetEntityList(ctx *utils.Context) {
-	// isSystemSuper := service.UserService.SystemSuper(ctx)
+func (entity *EntityApi) GetEntityList(ctx *utils.Context) {
+	// isSystemSuper := entity.userService.SystemSuper(ctx)
 	// if !isSystemSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	entityList, err := service.EntityService.GetAllEntity()
+	entityList, err := entity.entityService.GetAllEntity()
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -170,8 +171,8 @@ func (entity *entityApi) GetEntityList(ctx *utils.Context) {
 /*
 Handle func for GET /entity/:entity_id
 */
-func (entity *entityApi) GetEntityByID(ctx *utils.Context) {
-	// isSystemSuper := service.UserService.SystemSuper(ctx)
+func (entity *EntityApi) GetEntityByID(ctx *utils.Context) {
+	// isSystemSuper := entity.userService.SystemSuper(ctx)
 	// if !isSystemSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
@@ -181,7 +182,7 @@ func (entity *entityApi) GetEntityByID(ctx *utils.Context) {
 		return
 	}
 
-	thisEntity, err := service.EntityService.GetEntityInfoByID(entityID)
+	thisEntity, err := entity.entityService.GetEntityInfoByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -191,7 +192,7 @@ func (entity *entityApi) GetEntityByID(ctx *utils.Context) {
 	// 	return
 	// }
 
-	managerList, err := service.EntityService.GetEntityManagerList(entityID)
+	managerList, err := entity.entityService.GetEntityManagerList(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -217,19 +218,19 @@ func (entity *entityApi) GetEntityByID(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/user/list
 */
-func (entity *entityApi) UsersInEntity(ctx *utils.Context) {
-	// systemSuper := service.UserService.SystemSuper(ctx)
-	// entitySuper := service.UserService.EntitySuper(ctx)
+func (entity *EntityApi) UsersInEntity(ctx *utils.Context) {
+	// systemSuper := entity.userService.SystemSuper(ctx)
+	// entitySuper := entity.userService.EntitySuper(ctx)
 	// if !systemSuper && !entitySuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	// entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+	// entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
 	// if err != nil {
 	// 	return
 	// }
 
-	// exists, err := service.EntityService.ExistsEntityByID(entityID)
+	// exists, err := entity.entityService.ExistsEntityByID(entityID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -255,7 +256,7 @@ func (entity *entityApi) UsersInEntity(ctx *utils.Context) {
 		return
 	}
 
-	userList, count, err := service.EntityService.GetUsersUnderEntity(entityID, uint(page_size), uint(page_num))
+	userList, count, err := entity.entityService.GetUsersUnderEntity(entityID, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -276,19 +277,16 @@ func (entity *entityApi) UsersInEntity(ctx *utils.Context) {
 /*
 Handler func for GET /entity/{entity_id}/department/list
 */
-func (entity *entityApi) DepartmentsInEntity(ctx *utils.Context) {
-	entitySuper := service.UserService.EntitySuper(ctx)
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !entitySuper && !departmentSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+func (entity *EntityApi) DepartmentsInEntity(ctx *utils.Context) {
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
+	if err != nil {
 		return
 	}
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
-	if err != nil {
+	if !entity.permissionApi.CheckInEntity(ctx, define.PERM_USER_MANAGE, entityID) {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := entity.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -298,13 +296,7 @@ func (entity *entityApi) DepartmentsInEntity(ctx *utils.Context) {
 		return
 	}
 
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !isInEntity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-
-	departmentList, err := service.EntityService.GetAllDepartmentsUnderEntity(entityID)
+	departmentList, err := entity.entityService.GetAllDepartmentsUnderEntity(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -324,13 +316,13 @@ func (entity *entityApi) DepartmentsInEntity(ctx *utils.Context) {
 /*
 Handle func for POST /entity/{entity_id}/manager
 */
-func (entity *entityApi) SetManager(ctx *utils.Context) {
-	// isSystemSuper := service.UserService.SystemSuper(ctx)
+func (entity *EntityApi) SetManager(ctx *utils.Context) {
+	// isSystemSuper := entity.userService.SystemSuper(ctx)
 	// if !isSystemSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return-
 	// }
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
@@ -340,7 +332,7 @@ func (entity *entityApi) SetManager(ctx *utils.Context) {
 		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
 		return
 	}
-	thisUser, err := service.UserService.GetUserByName(setManagerReq.Username)
+	thisUser, err := entity.userService.GetUserByName(setManagerReq.Username)
 	if err != nil {
 		ctx.InternalError(err.Error())
 	}
@@ -354,7 +346,7 @@ func (entity *entityApi) SetManager(ctx *utils.Context) {
 			ctx.BadRequest(myerror.USER_NOT_IN_ENTITY, myerror.USER_NOT_IN_ENTITY_INFO)
 			return
 		}
-		err = service.EntityService.SetManager(setManagerReq.Username)
+		err = entity.entityService.SetManager(setManagerReq.Username)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -364,7 +356,17 @@ func (entity *entityApi) SetManager(ctx *utils.Context) {
 			ctx.BadRequest(myerror.USER_HAS_EXISTED, myerror.USER_HAS_EXISTED_INFO)
 			return
 		}
-		err = service.EntityService.CreateManager(setManagerReq.Username, *setManagerReq.Password, entityID)
+		err = entity.passwordService.Validate(entityID, *setManagerReq.Password)
+		if err != nil {
+			passwordError(ctx, err)
+			return
+		}
+		err = entity.entityService.CreateManager(setManagerReq.Username, *setManagerReq.Password, entityID)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		}
+		err = entity.passwordService.RequireChange(setManagerReq.Username)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -376,21 +378,21 @@ func (entity *entityApi) SetManager(ctx *utils.Context) {
 /*
 Handle func for DELETE /entity/{entity_id}/manager/{user_id}
 */
-func (entity *entityApi) DeleteManager(ctx *utils.Context) {
-	// isSystemSuper := service.UserService.SystemSuper(ctx)
+func (entity *EntityApi) DeleteManager(ctx *utils.Context) {
+	// isSystemSuper := entity.userService.SystemSuper(ctx)
 	// if !isSystemSuper {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+	userID, err := entity.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := entity.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -402,7 +404,7 @@ func (entity *entityApi) DeleteManager(ctx *utils.Context) {
 		return
 	}
 
-	err = service.EntityService.DeleteManager(userID)
+	err = entity.entityService.DeleteManager(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -414,7 +416,7 @@ func (entity *entityApi) DeleteManager(ctx *utils.Context) {
 /*
 Handle func for PATCH /entity/{entity_id}
 */
-func (entity *entityApi) ModifyEntityInfo(ctx *utils.Context) {
+func (entity *EntityApi) ModifyEntityInfo(ctx *utils.Context) {
 	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
 	if !hasIdentity {
 		return
@@ -431,7 +433,7 @@ func (entity *entityApi) ModifyEntityInfo(ctx *utils.Context) {
 		return
 	}
 
-	err = service.EntityService.ModifyEntity(entityID, modifyEntityInfoReq)
+	err = entity.entityService.ModifyEntity(entityID, modifyEntityInfoReq)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -443,13 +445,13 @@ func (entity *entityApi) ModifyEntityInfo(ctx *utils.Context) {
 /*
 Handle func for GET /entity/:entity_id/department/sub
 */
-func (entity *entityApi) GetEntitySubDepartments(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (entity *EntityApi) GetEntitySubDepartments(ctx *utils.Context) {
+	entityID, err := entity.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := entity.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -458,20 +460,11 @@ func (entity *entityApi) GetEntitySubDepartments(ctx *utils.Context) {
 		ctx.NotFound(myerror.ENTITY_NOT_FOUND, myerror.ENTITY_NOT_FOUND_INFO)
 		return
 	}
-	entitySuper := service.UserService.EntitySuper(ctx)
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	if !entitySuper && !departmentSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !entity.permissionApi.CheckInEntity(ctx, define.PERM_USER_MANAGE, entityID) {
 		return
 	}
 
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	if !isInEntity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-
-	departmentList, err := service.EntityService.GetEntitySubDepartments(entityID)
+	departmentList, err := entity.entityService.GetEntitySubDepartments(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -487,3 +480,137 @@ func (entity *entityApi) GetEntitySubDepartments(ctx *utils.Context) {
 	}
 	ctx.Success(departmentListResponse)
 }
+
+/*
+Handle func for GET /entity/:entity_id/login-locks
+*/
+func (entity *EntityApi) GetLoginLocks(ctx *utils.Context) {
+	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+
+	lockList, err := entity.loginLockService.GetEntityLocks(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	lockListRes, err := loginLockList(lockList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(lockListRes)
+}
+
+func loginLockList(lockList []*model.LoginLock) (*define.LoginLockListResponse, error) {
+	lockListRes := []define.LoginLockInfo{}
+	if err := copier.Copy(&lockListRes, lockList); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range lockListRes {
+		lockedUntil := lockListRes[i].LockedUntil
+		lockListRes[i].Locked = lockedUntil != nil && now.Before(*lockedUntil)
+	}
+	return &define.LoginLockListResponse{
+		LockList: lockListRes,
+	}, nil
+}
+
+/*
+Handle func for DELETE /entity/:entity_id/login-locks/:lock_id
+*/
+func (entity *EntityApi) ClearLoginLock(ctx *utils.Context) {
+	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	lockID, err := entity.entityService.GetParamID(ctx, "lock_id")
+	if err != nil {
+		return
+	}
+
+	thisLock, err := entity.loginLockService.GetLockByID(lockID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisLock == nil || thisLock.Kind != model.LOCK_BY_USERNAME || thisLock.EntityID != entityID {
+		ctx.NotFound(myerror.LOGIN_LOCK_NOT_FOUND, myerror.LOGIN_LOCK_NOT_FOUND_INFO)
+		return
+	}
+
+	err = entity.loginLockService.ClearLock(lockID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for PUT /entity/:entity_id/two-factor
+*/
+func (entity *EntityApi) SetTwoFactorPolicy(ctx *utils.Context) {
+	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	var req define.TwoFactorPolicyReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	err := entity.twoFactorService.SetEntityRequired(entityID, *req.Required)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /entity/:entity_id/password-policy
+*/
+func (entity *EntityApi) GetPasswordPolicy(ctx *utils.Context) {
+	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+
+	policy, err := entity.passwordService.GetPolicy(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var policyInfo define.PasswordPolicyInfo
+	err = copier.Copy(&policyInfo, policy)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(policyInfo)
+}
+
+/*
+Handle func for PUT /entity/:entity_id/password-policy
+*/
+func (entity *EntityApi) SetPasswordPolicy(ctx *utils.Context) {
+	hasIdentity, entityID := entity.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	var req define.PasswordPolicyReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	err := entity.passwordService.SetPolicy(entityID, req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
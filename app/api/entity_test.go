@@ -1,12 +1,12 @@
 package api
 
 import (
-	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/middleware"
 	"asset-management/utils"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -34,31 +34,54 @@ func print_errormessage(res *httptest.ResponseRecorder) string {
 
 func InitForEntity(r *gin.Engine) {
 	group := r.Group("/entity")
-	group.Use(utils.Handler(middleware.JWTMiddleware()))
-	group.GET("/:entity_id/user/list", utils.Handler(EntityApi.UsersInEntity))             //
-	group.GET("/:entity_id/department/list", utils.Handler(EntityApi.DepartmentsInEntity)) // change later
-	group.PATCH("/:entity_id", utils.Handler(EntityApi.ModifyEntityInfo))                  //
-
-	group.POST("/:entity_id/department", utils.Handler(DepartmentApi.CreateDepartment))                                          //
-	group.POST("/:entity_id/department/:department_id/department", utils.Handler(DepartmentApi.CreateDepartment))                //
-	group.DELETE("/:entity_id/department/:department_id", utils.Handler(DepartmentApi.DeleteDepartment))                         //
-	group.GET("/:entity_id/department/:department_id", utils.Handler(DepartmentApi.GetDepartmentByID))                           //
-	group.GET("/:entity_id/department/:department_id/department/list", utils.Handler(DepartmentApi.GetSubDepartments))           //
-	group.GET("/:entity_id/department/:department_id/user/list", utils.Handler(DepartmentApi.GetAllUsersUnderDepartment))        //
-	group.POST("/:entity_id/department/:department_id/user", utils.Handler(DepartmentApi.CreateUserInDepartment))                //
-	group.POST("/:entity_id/department/:department_id/manager", utils.Handler(DepartmentApi.SetManager))                         //
-	group.DELETE("/:entity_id/department/:department_id/manager/:user_id", utils.Handler(DepartmentApi.DeleteDepartmentManager)) //
-	group.GET("/:entity_id/department/:department_id/manager", utils.Handler(DepartmentApi.GetDepartmentManager))                //
-	group.GET("/:entity_id/department/tree", utils.Handler(DepartmentApi.GetDepartmentTree))
-
-	group.Use(utils.Handler(middleware.CheckSystemSuper()))
-	{
-		group.POST("/", utils.Handler(EntityApi.CreateEntity))                               //
-		group.DELETE("/:entity_id", utils.Handler(EntityApi.DeleteEntity))                   //
-		group.GET("/list", utils.Handler(EntityApi.GetEntityList))                           //
-		group.GET("/:entity_id", utils.Handler(EntityApi.GetEntityByID))                     //
-		group.POST("/:entity_id/manager", utils.Handler(EntityApi.SetManager))               //
-		group.DELETE("/:entity_id/manager/:user_id", utils.Handler(EntityApi.DeleteManager)) //
+	group.Use(utils.Handler(middleware.JWTMiddleware(tokenChecker)))
+	group.GET("/:entity_id/user/list", utils.Handler(apis.Entity.UsersInEntity))             //
+	group.GET("/:entity_id/department/list", utils.Handler(apis.Entity.DepartmentsInEntity)) // change later
+	group.PATCH("/:entity_id", utils.Handler(apis.Entity.ModifyEntityInfo))                  //
+
+	group.POST("/:entity_id/department", utils.Handler(apis.Department.CreateDepartment))                                          //
+	group.POST("/:entity_id/department/:department_id/department", utils.Handler(apis.Department.CreateDepartment))                //
+	group.DELETE("/:entity_id/department/:department_id", utils.Handler(apis.Department.DeleteDepartment))                         //
+	group.GET("/:entity_id/department/:department_id", utils.Handler(apis.Department.GetDepartmentByID))                           //
+	group.GET("/:entity_id/department/:department_id/department/list", utils.Handler(apis.Department.GetSubDepartments))           //
+	group.GET("/:entity_id/department/:department_id/user/list", utils.Handler(apis.Department.GetAllUsersUnderDepartment))        //
+	group.POST("/:entity_id/department/:department_id/user", utils.Handler(apis.Department.CreateUserInDepartment))                //
+	group.POST("/:entity_id/department/:department_id/manager", utils.Handler(apis.Department.SetManager))                         //
+	group.DELETE("/:entity_id/department/:department_id/manager/:user_id", utils.Handler(apis.Department.DeleteDepartmentManager)) //
+	group.GET("/:entity_id/department/:department_id/manager", utils.Handler(apis.Department.GetDepartmentManager))                //
+	group.GET("/:entity_id/department/tree", utils.Handler(apis.Department.GetDepartmentTree))
+	group.GET("/:entity_id/login-locks", utils.Handler(apis.Entity.GetLoginLocks))
+	group.DELETE("/:entity_id/login-locks/:lock_id", utils.Handler(apis.Entity.ClearLoginLock))
+	group.PUT("/:entity_id/two-factor", utils.Handler(apis.Entity.SetTwoFactorPolicy))
+	group.GET("/:entity_id/password-policy", utils.Handler(apis.Entity.GetPasswordPolicy))
+	group.PUT("/:entity_id/password-policy", utils.Handler(apis.Entity.SetPasswordPolicy))
+	group.GET("/:entity_id/api-keys", utils.Handler(apis.ApiToken.GetEntityKeys))
+	group.POST("/:entity_id/api-keys", utils.Handler(apis.ApiToken.CreateEntityKey))
+	group.DELETE("/:entity_id/api-keys/:key_id", utils.Handler(apis.ApiToken.RevokeEntityKey))
+	group.GET("/:entity_id/identity-providers", utils.Handler(apis.Identity.GetProviders))
+	group.POST("/:entity_id/identity-providers", utils.Handler(apis.Identity.CreateProvider))
+	group.PUT("/:entity_id/identity-providers/:provider_id", utils.Handler(apis.Identity.ModifyProvider))
+	group.DELETE("/:entity_id/identity-providers/:provider_id", utils.Handler(apis.Identity.DeleteProvider))
+	group.GET("/:entity_id/roles", utils.Handler(apis.Permission.GetRoles))
+	group.POST("/:entity_id/roles", utils.Handler(apis.Permission.CreateRole))
+	group.PUT("/:entity_id/roles/:role_id", utils.Handler(apis.Permission.ModifyRole))
+	group.DELETE("/:entity_id/roles/:role_id", utils.Handler(apis.Permission.DeleteRole))
+	group.GET("/:entity_id/role-assignments", utils.Handler(apis.Permission.GetAssignments))
+	group.POST("/:entity_id/role-assignments", utils.Handler(apis.Permission.Assign))
+	group.DELETE("/:entity_id/role-assignments/:assignment_id", utils.Handler(apis.Permission.Unassign))
+	group.GET("/:entity_id/login-logs", utils.Handler(apis.Log.GetLoginLog))
+	group.GET("/:entity_id/department/:department_id/invites", utils.Handler(apis.Invite.GetInvites))
+	group.POST("/:entity_id/department/:department_id/invites", utils.Handler(apis.Invite.CreateInvite))
+	group.DELETE("/:entity_id/department/:department_id/invites/:invite_id", utils.Handler(apis.Invite.RevokeInvite))
+
+	group.Use(utils.Handler(apis.Permission.Require(define.PERM_SYSTEM_MANAGE)))
+	{
+		group.POST("/", utils.Handler(apis.Entity.CreateEntity))                               //
+		group.DELETE("/:entity_id", utils.Handler(apis.Entity.DeleteEntity))                   //
+		group.GET("/list", utils.Handler(apis.Entity.GetEntityList))                           //
+		group.GET("/:entity_id", utils.Handler(apis.Entity.GetEntityByID))                     //
+		group.POST("/:entity_id/manager", utils.Handler(apis.Entity.SetManager))               //
+		group.DELETE("/:entity_id/manager/:user_id", utils.Handler(apis.Entity.DeleteManager)) //
 	}
 }
 
@@ -74,7 +97,7 @@ func TestEntity(t *testing.T) {
 		EntitySuper: true,
 		Ban:         false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -292,7 +315,7 @@ func TestEntityNoPermission(t *testing.T) {
 		Password: utils.CreateMD5("21232f297a57a5a743894a0e4a801fc3"),
 		Ban:      false,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "no",
@@ -414,3 +437,140 @@ func TestEntityNoPermission(t *testing.T) {
 		assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
 	}
 }
+
+func TestEntityLoginLock(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("lock_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("other_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("lock_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = userDao.Create(model.User{
+		UserName: "lock_user",
+		Password: utils.CreateMD5(password),
+		EntityID: entityID,
+	})
+	assert.Equal(t, nil, err, "service error")
+
+	login := func(username string, password string, ip string) *httptest.ResponseRecorder {
+		req := GetRequest(http.MethodPost, "/user/login", headerJson, GetJsonBody(define.UserLoginReq{
+			UserName: username,
+			Password: password,
+		}))
+		req.RemoteAddr = ip + ":1234"
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	for i := 0; i < 4; i++ {
+		res = login("lock_user", "wrong", "10.0.0.1")
+		assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	}
+	res = login("lock_user", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = login("lock_user", password, "10.0.0.2")
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	res = login("lock_manager", password, "10.0.0.3")
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data := map[string]interface{}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	headerJsonToken["Authorization"] = data["data"].(map[string]interface{})["token"].(string)
+
+	var lockID uint
+	{
+		req := GetRequest(http.MethodGet, fmt.Sprintf("/entity/%d/login-locks", entityID), headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+		var lockRes struct {
+			Data define.LoginLockListResponse `json:"data"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &lockRes)
+		assert.Equal(t, 1, len(lockRes.Data.LockList), "response failed")
+		assert.Equal(t, "lock_user", lockRes.Data.LockList[0].Key, "response failed")
+		assert.Equal(t, true, lockRes.Data.LockList[0].Locked, "response failed")
+		lockID = lockRes.Data.LockList[0].ID
+	}
+	{
+		req := GetRequest(http.MethodDelete, fmt.Sprintf("/entity/%d/login-locks/%d", otherID, lockID), headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	}
+	{
+		req := GetRequest(http.MethodDelete, fmt.Sprintf("/entity/%d/login-locks/%d", entityID, lockID), headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	}
+
+	// the ip that guessed stays locked
+	res = login("lock_user", password, "10.0.0.1")
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = login("lock_user", password, "10.0.0.2")
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	// ip locks are for system supers only
+	{
+		req := GetRequest(http.MethodGet, "/user/login-locks", headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	}
+	err = userDao.Create(model.User{
+		UserName:    "lock_admin",
+		Password:    utils.CreateMD5(password),
+		SystemSuper: true,
+	})
+	assert.Equal(t, nil, err, "service error")
+	res = login("lock_admin", password, "10.0.0.3")
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data = map[string]interface{}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	headerJsonToken["Authorization"] = data["data"].(map[string]interface{})["token"].(string)
+	{
+		req := GetRequest(http.MethodGet, "/user/login-locks", headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+		var lockRes struct {
+			Data define.LoginLockListResponse `json:"data"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &lockRes)
+		lockID = 0
+		for _, thisLock := range lockRes.Data.LockList {
+			assert.Equal(t, model.LOCK_BY_IP, thisLock.Kind, "response failed")
+			if thisLock.Key == "10.0.0.1" {
+				assert.Equal(t, true, thisLock.Locked, "response failed")
+				lockID = thisLock.ID
+			}
+		}
+		assert.NotEqual(t, uint(0), lockID, "response failed")
+	}
+	{
+		req := GetRequest(http.MethodDelete, fmt.Sprintf("/user/login-locks/%d", lockID), headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	}
+	{
+		req := GetRequest(http.MethodDelete, fmt.Sprintf("/user/login-locks/%d", lockID), headerJsonToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+	}
+	res = login("lock_user", password, "10.0.0.1")
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"asset-management/app/define"
-	"asset-management/app/model"
 	"asset-management/app/service"
 	"asset-management/myerror"
 	"asset-management/utils"
@@ -10,53 +9,59 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin/binding"
-	"github.com/jinzhu/copier"
-	"github.com/thoas/go-funk"
 )
 
-type feishuApi struct {
+type FeishuApi struct {
+	feishuService   service.FeishuServiceInterface
+	identityService service.IdentityServiceInterface
+	taskService     service.TaskServiceInterface
+	identityApi     *IdentityApi
 }
 
-var FeishuApi *feishuApi
-
-func newFeishuApi() *feishuApi {
-	return &feishuApi{}
-}
-
-func init() {
-	FeishuApi = newFeishuApi()
+func NewFeishuApi(
+	feishuService service.FeishuServiceInterface,
+	identityService service.IdentityServiceInterface,
+	taskService service.TaskServiceInterface,
+	identityApi *IdentityApi,
+) *FeishuApi {
+	return &FeishuApi{
+		feishuService:   feishuService,
+		identityService: identityService,
+		taskService:     taskService,
+		identityApi:     identityApi,
+	}
 }
 
 /*
-Handle func for POST /user/feishu/login
+Redeem a code from Feishu's login page, which these endpoints take without a state
 */
-func (feishu *feishuApi) FeishuLogin(ctx *utils.Context) {
+func (feishu *FeishuApi) exchange(ctx *utils.Context) (*define.ExternalIdentity, bool) {
 	var req define.FeishuBindOrLoginRequest
 
 	err := ctx.MustBindWith(&req, binding.JSON)
 	if err != nil {
 		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
-		return
+		return nil, false
 	}
 
-	token_res, err := service.FeishuService.GetAccessToken(req.Code)
+	identity, err := feishu.identityService.FeishuProvider().Exchange(req.Code, "", "", "")
 	if err != nil {
 		ctx.BadRequest(myerror.INVALID_FEISHU_CODE, myerror.INVALID_FEISHU_CODE_INFO)
-		return
+		return nil, false
 	}
+	return identity, true
+}
 
-	access_token := token_res.Data.AccessToken
-	refresh_token := token_res.Data.RefreshToken
-
-	info_res, err := service.FeishuService.GetUserInfo(access_token)
-	if err != nil {
-		ctx.InternalError(err.Error())
+/*
+Handle func for POST /user/feishu/login
+*/
+func (feishu *FeishuApi) FeishuLogin(ctx *utils.Context) {
+	identity, ok := feishu.exchange(ctx)
+	if !ok {
 		return
 	}
 
-	feishu_id := info_res.Data.UserID
-
-	user, err := service.FeishuService.FindUserByFeishuID(feishu_id)
+	user, err := feishu.identityService.FeishuProvider().Login(identity)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -66,100 +71,37 @@ func (feishu *feishuApi) FeishuLogin(ctx *utils.Context) {
 		return
 	}
 
-	err = service.FeishuService.StoreToken(user.ID, access_token, refresh_token)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-
-	userBasicInfo := define.UserBasicInfo{
-		UserID:          user.ID,
-		UserName:        user.UserName,
-		EntitySuper:     user.EntitySuper,
-		DepartmentSuper: user.DepartmentSuper,
-		SystemSuper:     user.SystemSuper,
-		EntityID:        user.EntityID,
-		DepartmentID:    user.DepartmentID,
-	}
-	token, err := utils.CreateToken(userBasicInfo)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-
-	var userInfo define.UserInfo
-	err = copier.Copy(&userInfo, user)
-	if err != nil {
-		ctx.InternalError(err.Error())
+	if !feishu.identityApi.finishLogin(ctx, user) {
 		return
 	}
 
-	data := define.UserLoginResponse{
-		Token:    token,
-		User:     userInfo,
-		FeishuID: user.FeishuID,
-	}
-
 	go func() {
-		err = service.FeishuService.FeishuSync(userInfo.EntityID)
+		err := feishu.feishuService.FeishuSync(user.EntityID)
 		if err != nil {
-			// ctx.InternalError(err.Error())
 			log.Println(err.Error())
 			return
 		}
 	}()
-
-	ctx.Success(data)
 }
 
 /*
 Handle func for POST /user/feishu/bind
 */
-func (feishu *feishuApi) FeishuBind(ctx *utils.Context) {
-	var req define.FeishuBindOrLoginRequest
-
-	err := ctx.MustBindWith(&req, binding.JSON)
-	if err != nil {
-		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+func (feishu *FeishuApi) FeishuBind(ctx *utils.Context) {
+	identity, ok := feishu.exchange(ctx)
+	if !ok {
 		return
 	}
 
-	token_res, err := service.FeishuService.GetAccessToken(req.Code)
-	if err != nil {
-		ctx.BadRequest(myerror.INVALID_FEISHU_CODE, myerror.INVALID_FEISHU_CODE_INFO)
-		return
-	}
-
-	access_token := token_res.Data.AccessToken
-	refresh_token := token_res.Data.RefreshToken
-
-	info_res, err := service.FeishuService.GetUserInfo(access_token)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-
-	current_user_id := UserApi.GetOperatorID(ctx)
-	exist_user, err := service.FeishuService.FindUserByFeishuID(info_res.Data.UserID)
+	bound, err := feishu.identityService.FeishuProvider().Bind(GetOperatorID(ctx), identity)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
-	if exist_user != nil && exist_user.ID != current_user_id {
+	if !bound {
 		ctx.BadRequest(myerror.FEISHU_DUPLICATE_BIND, myerror.FEISHU_DUPLICATE_BIND_INFO)
 		return
 	}
-	err = service.FeishuService.BindFeishu(current_user_id, info_res.Data.UserID)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
-
-	err = service.FeishuService.StoreToken(current_user_id, access_token, refresh_token)
-	if err != nil {
-		ctx.InternalError(err.Error())
-		return
-	}
 
 	ctx.Success(nil)
 }
@@ -167,9 +109,8 @@ func (feishu *feishuApi) FeishuBind(ctx *utils.Context) {
 /*
 Handle func for DELETE /user/feishu/bind
 */
-func (feishu *feishuApi) FeishuUnBind(ctx *utils.Context) {
-	current_user_id := UserApi.GetOperatorID(ctx)
-	err := service.FeishuService.BindFeishu(current_user_id, "")
+func (feishu *FeishuApi) FeishuUnBind(ctx *utils.Context) {
+	err := feishu.identityService.FeishuProvider().Unbind(GetOperatorID(ctx))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -180,7 +121,7 @@ func (feishu *feishuApi) FeishuUnBind(ctx *utils.Context) {
 /*
 Handle func for POST /user/feishu/bind
 */
-func (feishu *feishuApi) FeishuCallBack(ctx *utils.Context) {
+func (feishu *FeishuApi) FeishuCallBack(ctx *utils.Context) {
 	// action_type, exists := ctx.Get("action_type")
 	// if !exists {
 	// 	ctx.BadRequest(myerror.FEISHU_CALLBACK_ERROR, myerror.FEISHU_CALLBACK_ERROR_INFO)
@@ -201,7 +142,7 @@ func (feishu *feishuApi) FeishuCallBack(ctx *utils.Context) {
 		"REJECT":  2,
 	}
 
-	matched_token := "sdjkljkx9lsadf110"
+	matched_token := feishu.feishuService.CallbackToken()
 	// token, exists := ctx.Get("token")
 	// if !exists || token != matched_token {
 	// 	ctx.BadRequest(myerror.FEISHU_CALLBACK_ERROR, myerror.FEISHU_CALLBACK_ERROR_INFO)
@@ -224,7 +165,7 @@ func (feishu *feishuApi) FeishuCallBack(ctx *utils.Context) {
 		ctx.BadRequest(myerror.FEISHU_CALLBACK_ERROR, myerror.FEISHU_CALLBACK_ERROR_INFO)
 		return
 	}
-	thisTask, err := service.TaskService.GetTaskInfoByID(uint(instanceID))
+	thisTask, err := feishu.taskService.GetTaskInfoByID(uint(instanceID))
 	if err != nil {
 		ctx.InternalError("callback_error")
 		return
@@ -237,48 +178,30 @@ func (feishu *feishuApi) FeishuCallBack(ctx *utils.Context) {
 	// 	ctx.InternalError(myerror.TASK_NOT_PENDING_INFO)
 	// 	return
 	// }
-	err = service.TaskService.ModifyTaskState(uint(instanceID), action_map[req.ActionType])
-	thisTask.State = action_map[req.ActionType]
-	if err != nil {
-		ctx.InternalError("callback_error")
-		return
-	}
-
-	thisUser, err := service.FeishuService.FindUserByFeishuID(req.UserID)
+	thisUser, err := feishu.feishuService.FindUserByFeishuID(req.UserID)
 	if err != nil || thisUser == nil {
 		ctx.InternalError("callback_error")
 		return
 	}
 
-	assetIDs := funk.Map(thisTask.AssetList, func(thisAsset *model.Asset) uint {
-		return thisAsset.ID
-	}).([]uint)
-
 	if req.ActionType == "APPROVE" {
-		if thisTask.TaskType == 0 {
-			err = service.AssetService.AcquireAssets(assetIDs, thisTask.UserID)
-		} else if thisTask.TaskType == 1 {
-			err = service.AssetService.CancelAssets(assetIDs, thisUser.ID)
-		} else if thisTask.TaskType == 2 {
-			err = service.AssetService.ModifyAssetMaintainerAndState(assetIDs, thisTask.TargetID)
-		} else {
-			err = service.AssetService.TransferAssets(assetIDs, thisTask.TargetID, thisTask.Target.DepartmentID, thisTask.DepartmentID)
-		}
-
-		if err != nil {
-			ctx.InternalError("callback_error")
-			return
-		}
+		err = feishu.taskService.ApproveTask(thisTask, thisUser.ID)
+	} else {
+		err = feishu.taskService.ModifyTaskState(uint(instanceID), action_map[req.ActionType])
+	}
+	if err != nil {
+		ctx.InternalError("callback_error")
+		return
 	}
 
-	approvalCode, err := service.FeishuService.CreateApprovalDefination()
+	approvalCode, err := feishu.feishuService.CreateApprovalDefination()
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 
 	thisTask.State = action_map[req.ActionType]
-	err = service.FeishuService.PutApproval(*thisTask, thisTask.User.FeishuID, approvalCode)
+	err = feishu.feishuService.PutApproval(*thisTask, thisTask.User.FeishuID, approvalCode)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -0,0 +1,431 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+)
+
+type IdentityApi struct {
+	entityService   service.EntityServiceInterface
+	identityService service.IdentityServiceInterface
+	tokenService    service.TokenServiceInterface
+	userService     service.UserServiceInterface
+	entityApi       *EntityApi
+	twoFactorApi    *TwoFactorApi
+}
+
+func NewIdentityApi(
+	entityService service.EntityServiceInterface,
+	identityService service.IdentityServiceInterface,
+	tokenService service.TokenServiceInterface,
+	userService service.UserServiceInterface,
+	entityApi *EntityApi,
+	twoFactorApi *TwoFactorApi,
+) *IdentityApi {
+	return &IdentityApi{
+		entityService:   entityService,
+		identityService: identityService,
+		tokenService:    tokenService,
+		userService:     userService,
+		entityApi:       entityApi,
+		twoFactorApi:    twoFactorApi,
+	}
+}
+
+/*
+Log in a user an external provider vouched for, false when no tokens were issued
+*/
+func (identity *IdentityApi) finishLogin(ctx *utils.Context, thisUser *model.User) bool {
+	if thisUser.Ban {
+		ctx.BadRequest(3, "User Banned")
+		return false
+	}
+	if identity.twoFactorApi.Challenge(ctx, thisUser) {
+		return false
+	}
+
+	tokens, err := identity.tokenService.IssueTokens(thisUser, sessionClient(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	}
+	var userInfo define.UserInfo
+	err = copier.Copy(&userInfo, thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	}
+	ctx.Success(define.UserLoginResponse{
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		User:                   userInfo,
+		FeishuID:               thisUser.FeishuID,
+		PasswordChangeRequired: tokens.PasswordChangeRequired,
+	})
+	return true
+}
+
+/*
+The provider named by the path, only enabled ones can be used to log in or bind
+*/
+func (identity *IdentityApi) pathProvider(ctx *utils.Context, mustEnabled bool) (*model.IdentityProvider, bool) {
+	providerID, err := identity.entityService.GetParamID(ctx, "provider_id")
+	if err != nil {
+		return nil, false
+	}
+	thisProvider, err := identity.identityService.GetProviderByID(providerID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, false
+	} else if thisProvider == nil || (mustEnabled && !thisProvider.Enabled) {
+		ctx.NotFound(myerror.IDENTITY_PROVIDER_NOT_FOUND, myerror.IDENTITY_PROVIDER_NOT_FOUND_INFO)
+		return nil, false
+	}
+	return thisProvider, true
+}
+
+/*
+The provider named by the path and the operator, who can only bind providers of their own entity.
+Binding is refused to API tokens, a leaked token must not be able to link another login
+*/
+func (identity *IdentityApi) bindProvider(ctx *utils.Context, mustEnabled bool) (*model.IdentityProvider, *model.User, bool) {
+	if !checkNotApiToken(ctx) || !checkNotImpersonating(ctx) {
+		return nil, nil, false
+	}
+	thisProvider, ok := identity.pathProvider(ctx, mustEnabled)
+	if !ok {
+		return nil, nil, false
+	}
+	thisUser, err := identity.userService.GetUserByID(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, nil, false
+	} else if thisUser == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return nil, nil, false
+	} else if thisUser.EntityID != thisProvider.EntityID {
+		ctx.NotFound(myerror.IDENTITY_PROVIDER_NOT_FOUND, myerror.IDENTITY_PROVIDER_NOT_FOUND_INFO)
+		return nil, nil, false
+	}
+	return thisProvider, thisUser, true
+}
+
+/*
+Redeem the code the provider redirected back with, for the authorization the state names
+*/
+func (identity *IdentityApi) exchange(ctx *utils.Context, thisProvider *model.IdentityProvider, purpose string, userID uint) (*define.ExternalIdentity, bool) {
+	var req define.AuthorizationCodeReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return nil, false
+	}
+	thisState, err := identity.identityService.TakeAuthorizationState(thisProvider, purpose, userID, req.State)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, false
+	} else if thisState == nil {
+		ctx.BadRequest(myerror.AUTHORIZATION_STATE_INVALID, myerror.AUTHORIZATION_STATE_INVALID_INFO)
+		return nil, false
+	}
+	externalIdentity, err := identity.identityService.Provider(thisProvider).
+		Exchange(req.Code, thisState.RedirectURI, thisState.Nonce, thisState.CodeVerifier)
+	if err != nil {
+		ctx.BadRequest(myerror.IDENTITY_CODE_INVALID, myerror.IDENTITY_CODE_INVALID_INFO)
+		return nil, false
+	}
+	return externalIdentity, true
+}
+
+func (identity *IdentityApi) authorize(ctx *utils.Context, thisProvider *model.IdentityProvider, purpose string, userID uint) {
+	var req define.AuthorizeReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	res, err := identity.identityService.StartAuthorization(thisProvider, purpose, userID, req.RedirectURI)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(res)
+}
+
+/*
+Handle func for GET /user/oauth/providers, entity_id in the query narrows the list to one entity
+*/
+func (identity *IdentityApi) GetLoginProviders(ctx *utils.Context) {
+	var entityID uint64
+	if query := ctx.Query("entity_id"); query != "" {
+		var err error
+		entityID, err = strconv.ParseUint(query, 10, 0)
+		if err != nil {
+			ctx.BadRequest(myerror.INVALID_PARAM, myerror.INVALID_PARAM_INFO)
+			return
+		}
+	}
+	providerList, err := identity.identityService.GetLoginProviders(uint(entityID))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	providerListRes := []define.LoginProviderInfo{}
+	err = copier.Copy(&providerListRes, providerList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.LoginProviderListResponse{
+		ProviderList: providerListRes,
+	})
+}
+
+/*
+Handle func for POST /user/oauth/:provider_id/authorize
+*/
+func (identity *IdentityApi) Authorize(ctx *utils.Context) {
+	thisProvider, ok := identity.pathProvider(ctx, true)
+	if !ok {
+		return
+	}
+	identity.authorize(ctx, thisProvider, define.AUTH_PURPOSE_LOGIN, 0)
+}
+
+/*
+Handle func for POST /user/oauth/:provider_id/login
+*/
+func (identity *IdentityApi) Login(ctx *utils.Context) {
+	thisProvider, ok := identity.pathProvider(ctx, true)
+	if !ok {
+		return
+	}
+	externalIdentity, ok := identity.exchange(ctx, thisProvider, define.AUTH_PURPOSE_LOGIN, 0)
+	if !ok {
+		return
+	}
+	thisUser, err := identity.identityService.Provider(thisProvider).Login(externalIdentity)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisUser == nil || thisUser.EntityID != thisProvider.EntityID {
+		ctx.BadRequest(myerror.IDENTITY_NOT_BIND, myerror.IDENTITY_NOT_BIND_INFO)
+		return
+	}
+	identity.finishLogin(ctx, thisUser)
+}
+
+/*
+Handle func for GET /user/oauth/bindings
+*/
+func (identity *IdentityApi) GetMyBindings(ctx *utils.Context) {
+	thisUser, err := identity.userService.GetUserByID(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisUser == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return
+	}
+	bindingList, err := identity.identityService.GetUserBindings(thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.IdentityBindingListResponse{
+		BindingList: bindingList,
+	})
+}
+
+/*
+Handle func for POST /user/oauth/:provider_id/bind/authorize
+*/
+func (identity *IdentityApi) BindAuthorize(ctx *utils.Context) {
+	thisProvider, thisUser, ok := identity.bindProvider(ctx, true)
+	if !ok {
+		return
+	}
+	identity.authorize(ctx, thisProvider, define.AUTH_PURPOSE_BIND, thisUser.ID)
+}
+
+/*
+Handle func for POST /user/oauth/:provider_id/bind
+*/
+func (identity *IdentityApi) Bind(ctx *utils.Context) {
+	thisProvider, thisUser, ok := identity.bindProvider(ctx, true)
+	if !ok {
+		return
+	}
+	externalIdentity, ok := identity.exchange(ctx, thisProvider, define.AUTH_PURPOSE_BIND, thisUser.ID)
+	if !ok {
+		return
+	}
+	bound, err := identity.identityService.Provider(thisProvider).Bind(thisUser.ID, externalIdentity)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if !bound {
+		ctx.BadRequest(myerror.IDENTITY_DUPLICATE_BIND, myerror.IDENTITY_DUPLICATE_BIND_INFO)
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for DELETE /user/oauth/:provider_id/bind, also allowed once the provider is disabled
+*/
+func (identity *IdentityApi) Unbind(ctx *utils.Context) {
+	thisProvider, thisUser, ok := identity.bindProvider(ctx, false)
+	if !ok {
+		return
+	}
+	err := identity.identityService.Provider(thisProvider).Unbind(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+func (identity *IdentityApi) bindProviderReq(ctx *utils.Context) (*define.IdentityProviderReq, bool) {
+	var req define.IdentityProviderReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return nil, false
+	}
+	if req.Kind == model.IDENTITY_PROVIDER_OIDC && (req.Issuer == "" || req.ClientID == "") {
+		ctx.BadRequest(myerror.IDENTITY_PROVIDER_INVALID, myerror.IDENTITY_PROVIDER_INVALID_INFO)
+		return nil, false
+	}
+	return &req, true
+}
+
+func (identity *IdentityApi) entityProvider(ctx *utils.Context, entityID uint) (*model.IdentityProvider, bool) {
+	thisProvider, ok := identity.pathProvider(ctx, false)
+	if !ok {
+		return nil, false
+	} else if thisProvider.EntityID != entityID {
+		ctx.NotFound(myerror.IDENTITY_PROVIDER_NOT_FOUND, myerror.IDENTITY_PROVIDER_NOT_FOUND_INFO)
+		return nil, false
+	}
+	return thisProvider, true
+}
+
+/*
+Handle func for GET /entity/:entity_id/identity-providers
+*/
+func (identity *IdentityApi) GetProviders(ctx *utils.Context) {
+	hasIdentity, entityID := identity.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	providerList, err := identity.identityService.GetEntityProviders(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	providerListRes := []define.IdentityProviderInfo{}
+	err = copier.Copy(&providerListRes, providerList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.IdentityProviderListResponse{
+		ProviderList: providerListRes,
+	})
+}
+
+/*
+Handle func for POST /entity/:entity_id/identity-providers
+*/
+func (identity *IdentityApi) CreateProvider(ctx *utils.Context) {
+	hasIdentity, entityID := identity.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	req, ok := identity.bindProviderReq(ctx)
+	if !ok {
+		return
+	}
+	exists, err := identity.identityService.ExistsProviderName(entityID, req.Name)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if exists {
+		ctx.BadRequest(myerror.IDENTITY_PROVIDER_HAS_EXIST, myerror.IDENTITY_PROVIDER_HAS_EXIST_INFO)
+		return
+	}
+
+	newProvider, err := identity.identityService.CreateProvider(entityID, *req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var providerInfo define.IdentityProviderInfo
+	err = copier.Copy(&providerInfo, newProvider)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(providerInfo)
+}
+
+/*
+Handle func for PUT /entity/:entity_id/identity-providers/:provider_id
+*/
+func (identity *IdentityApi) ModifyProvider(ctx *utils.Context) {
+	hasIdentity, entityID := identity.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	thisProvider, ok := identity.entityProvider(ctx, entityID)
+	if !ok {
+		return
+	}
+	req, ok := identity.bindProviderReq(ctx)
+	if !ok {
+		return
+	}
+	if req.Name != thisProvider.Name {
+		exists, err := identity.identityService.ExistsProviderName(entityID, req.Name)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		} else if exists {
+			ctx.BadRequest(myerror.IDENTITY_PROVIDER_HAS_EXIST, myerror.IDENTITY_PROVIDER_HAS_EXIST_INFO)
+			return
+		}
+	}
+
+	err := identity.identityService.ModifyProvider(thisProvider.ID, *req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for DELETE /entity/:entity_id/identity-providers/:provider_id, OIDC bindings go with it
+*/
+func (identity *IdentityApi) DeleteProvider(ctx *utils.Context) {
+	hasIdentity, entityID := identity.entityApi.CheckViewIdentity(ctx)
+	if !hasIdentity {
+		return
+	}
+	thisProvider, ok := identity.entityProvider(ctx, entityID)
+	if !ok {
+		return
+	}
+	err := identity.identityService.DeleteProvider(thisProvider.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/oidc/oidctest"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityProviders(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	InitForTest(r)
+
+	idp := oidctest.NewServer("asset-client", "asset-secret")
+	defer idp.Close()
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("identity_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("identity_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("identity_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	for username, userEntity := range map[string]uint{"identity_user": entityID, "identity_outsider": otherID} {
+		err = userDao.Create(model.User{
+			UserName: username,
+			Password: utils.CreateMD5(password),
+			EntityID: userEntity,
+		})
+		assert.Equal(t, nil, err, "service error")
+	}
+
+	type response struct {
+		Code  int
+		Error utils.ErrorData
+		Data  map[string]interface{}
+	}
+	call := func(method string, url string, token string, body interface{}) response {
+		var reader io.Reader
+		if body != nil {
+			reader = GetJsonBody(body)
+		}
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, reader)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		data := struct {
+			Error utils.ErrorData        `json:"error"`
+			Data  map[string]interface{} `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return response{Code: res.Result().StatusCode, Error: data.Error, Data: data.Data}
+	}
+	login := func(username string) string {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: username, Password: password})
+		assert.Equal(t, http.StatusOK, res.Code, "response failed")
+		return res.Data["token"].(string)
+	}
+	managerToken := login("identity_manager")
+	userToken := login("identity_user")
+	outsiderToken := login("identity_outsider")
+
+	providersURL := fmt.Sprintf("/entity/%d/identity-providers", entityID)
+	mock := define.IdentityProviderReq{
+		Name:         "mock",
+		Kind:         model.IDENTITY_PROVIDER_OIDC,
+		Issuer:       idp.URL,
+		ClientID:     "asset-client",
+		ClientSecret: "asset-secret",
+	}
+	res := call(http.MethodPost, providersURL, userToken, mock)
+	assert.Equal(t, http.StatusForbidden, res.Code, "response failed")
+	res = call(http.MethodPost, providersURL, managerToken, define.IdentityProviderReq{Name: "broken", Kind: model.IDENTITY_PROVIDER_OIDC})
+	assert.Equal(t, myerror.IDENTITY_PROVIDER_INVALID, res.Error.Code, "response failed")
+	res = call(http.MethodPost, providersURL, managerToken, mock)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	providerID := uint(res.Data["id"].(float64))
+	assert.Nil(t, res.Data["client_secret"], "response failed")
+	res = call(http.MethodPost, providersURL, managerToken, mock)
+	assert.Equal(t, myerror.IDENTITY_PROVIDER_HAS_EXIST, res.Error.Code, "response failed")
+
+	res = call(http.MethodGet, fmt.Sprintf("/user/oauth/providers?entity_id=%d", entityID), "", nil)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	assert.Equal(t, 1, len(res.Data["provider_list"].([]interface{})), "response failed")
+
+	redirect := define.AuthorizeReq{RedirectURI: "http://localhost/oauth/callback"}
+	authorize := func(url string, token string) define.AuthorizationCodeReq {
+		res := call(http.MethodPost, url, token, redirect)
+		assert.Equal(t, http.StatusOK, res.Code, "response failed")
+		code, state, err := oidctest.Authorize(res.Data["url"].(string))
+		assert.Equal(t, nil, err, "response failed")
+		assert.Equal(t, res.Data["state"], state, "response failed")
+		return define.AuthorizationCodeReq{State: state, Code: code}
+	}
+	loginURL := fmt.Sprintf("/user/oauth/%d/login", providerID)
+	authorizeURL := fmt.Sprintf("/user/oauth/%d/authorize", providerID)
+	bindURL := fmt.Sprintf("/user/oauth/%d/bind", providerID)
+
+	// nobody is bound to alice yet
+	idp.Login("alice", "alice@example.com")
+	res = call(http.MethodPost, loginURL, "", authorize(authorizeURL, ""))
+	assert.Equal(t, myerror.IDENTITY_NOT_BIND, res.Error.Code, "response failed")
+
+	res = call(http.MethodPost, bindURL+"/authorize", outsiderToken, redirect)
+	assert.Equal(t, http.StatusNotFound, res.Code, "response failed")
+	bindReq := authorize(bindURL+"/authorize", userToken)
+	res = call(http.MethodPost, bindURL, managerToken, bindReq)
+	assert.Equal(t, myerror.AUTHORIZATION_STATE_INVALID, res.Error.Code, "response failed")
+	bindReq = authorize(bindURL+"/authorize", userToken)
+	res = call(http.MethodPost, bindURL, userToken, bindReq)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	res = call(http.MethodPost, bindURL, managerToken, authorize(bindURL+"/authorize", managerToken))
+	assert.Equal(t, myerror.IDENTITY_DUPLICATE_BIND, res.Error.Code, "response failed")
+
+	loginReq := authorize(authorizeURL, "")
+	res = call(http.MethodPost, loginURL, "", define.AuthorizationCodeReq{State: loginReq.State, Code: "forged"})
+	assert.Equal(t, myerror.IDENTITY_CODE_INVALID, res.Error.Code, "response failed")
+	loginReq = authorize(authorizeURL, "")
+	res = call(http.MethodPost, loginURL, "", loginReq)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	oidcToken := res.Data["token"].(string)
+	res = call(http.MethodPost, loginURL, "", loginReq)
+	assert.Equal(t, myerror.AUTHORIZATION_STATE_INVALID, res.Error.Code, "response failed")
+
+	res = call(http.MethodGet, "/user/oauth/bindings", oidcToken, nil)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	bindings := res.Data["binding_list"].([]interface{})
+	assert.Equal(t, 1, len(bindings), "response failed")
+	assert.Equal(t, "alice", bindings[0].(map[string]interface{})["subject"], "response failed")
+
+	// a disabled provider cannot be used to log in
+	disabled := false
+	mock.Enabled = &disabled
+	res = call(http.MethodPut, fmt.Sprintf("%s/%d", providersURL, providerID), managerToken, mock)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	res = call(http.MethodPost, authorizeURL, "", redirect)
+	assert.Equal(t, http.StatusNotFound, res.Code, "response failed")
+
+	res = call(http.MethodDelete, bindURL, userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	res = call(http.MethodGet, "/user/oauth/bindings", userToken, nil)
+	assert.Equal(t, 0, len(res.Data["binding_list"].([]interface{})), "response failed")
+
+	res = call(http.MethodDelete, fmt.Sprintf("/entity/%d/identity-providers/%d", otherID, providerID), outsiderToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Code, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", providersURL, providerID), managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Code, "response failed")
+	res = call(http.MethodGet, providersURL, managerToken, nil)
+	assert.Equal(t, 0, len(res.Data["provider_list"].([]interface{})), "response failed")
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+	"github.com/thoas/go-funk"
+)
+
+type InviteApi struct {
+	departmentService service.DepartmentServiceInterface
+	entityService     service.EntityServiceInterface
+	inviteService     service.InviteServiceInterface
+	permissionService service.PermissionServiceInterface
+	departmentApi     *DepartmentApi
+	permissionApi     *PermissionApi
+}
+
+func NewInviteApi(
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	inviteService service.InviteServiceInterface,
+	permissionService service.PermissionServiceInterface,
+	departmentApi *DepartmentApi,
+	permissionApi *PermissionApi,
+) *InviteApi {
+	return &InviteApi{
+		departmentService: departmentService,
+		entityService:     entityService,
+		inviteService:     inviteService,
+		permissionService: permissionService,
+		departmentApi:     departmentApi,
+		permissionApi:     permissionApi,
+	}
+}
+
+/*
+Entity supers invite into any department of their entity,
+department supers into their own department and those below it
+*/
+func (invite *InviteApi) checkInviteIdentity(ctx *utils.Context) (uint, uint, bool) {
+	entityID, departmentID, ok := invite.departmentApi.CheckDepartmentSuperIdentity(ctx)
+	if !ok || !invite.permissionApi.CheckInDepartmentTree(ctx, define.PERM_USER_MANAGE, departmentID) {
+		return 0, 0, false
+	}
+	return entityID, departmentID, true
+}
+
+/*
+The role must be built in or the entity's own. A department super may only hand
+out a role whose permissions they hold on their own department themselves
+*/
+func (invite *InviteApi) checkInviteRole(ctx *utils.Context, entityID uint, roleID uint) bool {
+	if roleID == 0 {
+		return true
+	}
+	thisRole, err := invite.permissionService.GetRoleByID(roleID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	} else if thisRole == nil || (!thisRole.BuiltIn && thisRole.EntityID != entityID) {
+		ctx.NotFound(myerror.ROLE_NOT_FOUND, myerror.ROLE_NOT_FOUND_INFO)
+		return false
+	}
+	manager, err := invite.permissionApi.Allowed(ctx, define.PERM_ROLE_MANAGE, entityID, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	} else if manager {
+		return true
+	}
+	operator := GetOperatorInfo(ctx)
+	granted, err := invite.permissionService.Permissions(*operator, entityID, operator.DepartmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	}
+	if len(funk.LeftJoinString(thisRole.Permissions, granted)) != 0 {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return false
+	}
+	return true
+}
+
+/*
+Handle func for GET /entity/{entity_id}/department/{department_id}/invites
+*/
+func (invite *InviteApi) GetInvites(ctx *utils.Context) {
+	_, departmentID, ok := invite.checkInviteIdentity(ctx)
+	if !ok {
+		return
+	}
+	inviteList, err := invite.inviteService.GetDepartmentInvites(departmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	inviteListRes := []define.InviteInfo{}
+	err = copier.Copy(&inviteListRes, inviteList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.InviteListResponse{
+		InviteList: inviteListRes,
+	})
+}
+
+/*
+Handle func for POST /entity/{entity_id}/department/{department_id}/invites
+*/
+func (invite *InviteApi) CreateInvite(ctx *utils.Context) {
+	if !checkNotApiToken(ctx) || !checkNotImpersonating(ctx) {
+		return
+	}
+	entityID, departmentID, ok := invite.checkInviteIdentity(ctx)
+	if !ok {
+		return
+	}
+	var req define.CreateInviteReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	if !invite.checkInviteRole(ctx, entityID, req.RoleID) {
+		return
+	}
+
+	plain, newInvite, err := invite.inviteService.CreateInvite(GetOperatorID(ctx), entityID, departmentID, req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var info define.InviteInfo
+	err = copier.Copy(&info, newInvite)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.CreateInviteResponse{
+		Token: plain,
+		Info:  info,
+	})
+}
+
+/*
+Handle func for DELETE /entity/{entity_id}/department/{department_id}/invites/{invite_id}
+*/
+func (invite *InviteApi) RevokeInvite(ctx *utils.Context) {
+	_, departmentID, ok := invite.checkInviteIdentity(ctx)
+	if !ok {
+		return
+	}
+	inviteID, err := invite.entityService.GetParamID(ctx, "invite_id")
+	if err != nil {
+		return
+	}
+	thisInvite, err := invite.inviteService.GetInviteByID(inviteID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisInvite == nil || thisInvite.DepartmentID != departmentID {
+		ctx.NotFound(myerror.INVITE_NOT_FOUND, myerror.INVITE_NOT_FOUND_INFO)
+		return
+	}
+
+	err = invite.inviteService.RevokeInvite(inviteID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvite(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("invite_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("invite_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Department.departmentService.CreateDepartment("invite_parent", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	parentID := departmentList[0].ID
+	err = apis.Department.departmentService.CreateDepartment("invite_child", entityID, parentID)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err = apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	childID := departmentList[1].ID
+	for _, req := range []define.CreateDepartmentUserReq{
+		{UserName: "invite_super", Password: password, DepartmentSuper: true},
+		{UserName: "invite_employee", Password: password},
+	} {
+		err = apis.Department.departmentService.CreateDepartmentUser(req, entityID, parentID)
+		assert.Equal(t, nil, err, "service error")
+	}
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(username string) string {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: username, Password: password})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data["data"].(map[string]interface{})["token"].(string)
+	}
+	invitesURL := func(departmentID uint) string {
+		return fmt.Sprintf("/entity/%d/department/%d/invites", entityID, departmentID)
+	}
+	createInvite := func(departmentID uint, token string, req define.CreateInviteReq) define.CreateInviteResponse {
+		res := call(http.MethodPost, invitesURL(departmentID), token, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := struct {
+			Data define.CreateInviteResponse `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data.Data
+	}
+	managerToken := login("invite_manager")
+	superToken := login("invite_super")
+	employeeToken := login("invite_employee")
+	createRole := func(name string, permission string) uint {
+		res := call(http.MethodPost, fmt.Sprintf("/entity/%d/roles", entityID), managerToken, define.RoleReq{Name: name, Permissions: []string{permission}})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := struct {
+			Data define.RoleInfo `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data.Data.ID
+	}
+	register := func(username string, inviteToken string) *httptest.ResponseRecorder {
+		return call(http.MethodPost, "/user/register", "", define.UserRegisterReq{UserName: username, Password: password, InviteToken: inviteToken})
+	}
+	approverID := createRole("invite_approver", define.PERM_ASSET_APPROVE)
+	roleManagerID := createRole("invite_role_manager", define.PERM_ROLE_MANAGE)
+
+	// department supers invite below themselves with roles they hold
+	res = call(http.MethodPost, invitesURL(parentID), employeeToken, define.CreateInviteReq{MaxUses: 1, ExpiresInDays: 1})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, invitesURL(parentID), superToken, define.CreateInviteReq{RoleID: roleManagerID, MaxUses: 1, ExpiresInDays: 1})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, invitesURL(parentID), superToken, define.CreateInviteReq{MaxUses: 1, ExpiresInDays: 31})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	approverInvite := createInvite(parentID, superToken, define.CreateInviteReq{RoleID: approverID, MaxUses: 1, ExpiresInDays: 1})
+	childInvite := createInvite(childID, superToken, define.CreateInviteReq{MaxUses: 5, ExpiresInDays: 7})
+	managerInvite := createInvite(childID, managerToken, define.CreateInviteReq{RoleID: roleManagerID, MaxUses: 1, ExpiresInDays: 7})
+	assert.Equal(t, childID, managerInvite.Info.DepartmentID, "response failed")
+
+	// registering through the link places the user and grants the role
+	res = register("invite_approver_user", approverInvite.Token)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	thisUser, err := userDao.GetUserByName("invite_approver_user")
+	assert.Equal(t, nil, err, "dao error")
+	assert.Equal(t, entityID, thisUser.EntityID, "response failed")
+	assert.Equal(t, parentID, thisUser.DepartmentID, "response failed")
+	res = call(http.MethodGet, fmt.Sprintf("/user/permissions?department_id=%d", parentID), login("invite_approver_user"), nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.Contains(t, res.Body.String(), define.PERM_ASSET_APPROVE, "response failed")
+	res = register("invite_late_user", approverInvite.Token)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = register("invite_child_user", childInvite.Token)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = register("invite_orphan", "")
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	data := struct {
+		Data define.InviteListResponse `json:"data"`
+	}{}
+	res = call(http.MethodGet, invitesURL(childID), superToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &data)
+	assert.Equal(t, 2, len(data.Data.InviteList), "response failed")
+	assert.Equal(t, uint(1), data.Data.InviteList[1].Uses, "response failed")
+	res = call(http.MethodGet, invitesURL(childID), employeeToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	// a revoked link stops working, invites are only found through their own department
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", invitesURL(parentID), childInvite.Info.ID), superToken, nil)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", invitesURL(childID), childInvite.Info.ID), superToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = register("invite_revoked_user", childInvite.Token)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+}
@@ -10,36 +10,36 @@ import (
 	"github.com/jinzhu/copier"
 )
 
-type logApi struct {
+type LogApi struct {
+	entityService service.EntityServiceInterface
+	logService    service.LogServiceInterface
+	userService   service.UserServiceInterface
+	permissionApi *PermissionApi
 }
 
-var LogApi *logApi
-
-func newLogApi() *logApi {
-	return &logApi{}
-}
-
-func init() {
-	LogApi = newLogApi()
+func NewLogApi(
+	entityService service.EntityServiceInterface,
+	logService service.LogServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *LogApi {
+	return &LogApi{
+		entityService: entityService,
+		logService:    logService,
+		userService:   userService,
+		permissionApi: permissionApi,
+	}
 }
 
 /*
 Handle func for GET /entity/:entity_id/login-logs
 */
-func (mylog *logApi) GetLoginLog(ctx *utils.Context) {
-	isSysyemSuper := service.UserService.SystemSuper(ctx)
-	isEntitySuper := service.UserService.EntitySuper(ctx)
-	if !isEntitySuper && !isSysyemSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (mylog *LogApi) GetLoginLog(ctx *utils.Context) {
+	entityID, err := mylog.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
-	userInfo := UserApi.GetOperatorInfo(ctx)
-	if !isSysyemSuper && userInfo.EntityID != entityID {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !mylog.permissionApi.Check(ctx, define.PERM_LOG_VIEW, entityID, 0) {
 		return
 	}
 
@@ -54,7 +54,7 @@ func (mylog *logApi) GetLoginLog(ctx *utils.Context) {
 		return
 	}
 
-	logList, count, err := service.LogService.GetLoginLog(entityID, uint(page_size), uint(page_num))
+	logList, count, err := mylog.logService.GetLoginLog(entityID, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -79,20 +79,12 @@ func (mylog *logApi) GetLoginLog(ctx *utils.Context) {
 /*
 Handle func for GET /entity/:entity_id/data-logs
 */
-func (mylog *logApi) GetDataLog(ctx *utils.Context) {
-	isSysyemSuper := service.UserService.SystemSuper(ctx)
-	isEntitySuper := service.UserService.EntitySuper(ctx)
-	if !isEntitySuper && !isSysyemSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
-		return
-	}
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (mylog *LogApi) GetDataLog(ctx *utils.Context) {
+	entityID, err := mylog.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
-	userInfo := UserApi.GetOperatorInfo(ctx)
-	if !isSysyemSuper && userInfo.EntityID != entityID {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !mylog.permissionApi.Check(ctx, define.PERM_LOG_VIEW, entityID, 0) {
 		return
 	}
 
@@ -107,7 +99,7 @@ func (mylog *logApi) GetDataLog(ctx *utils.Context) {
 		return
 	}
 
-	logList, count, err := service.LogService.GetDataLog(entityID, uint(page_size), uint(page_num))
+	logList, count, err := mylog.logService.GetDataLog(entityID, uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
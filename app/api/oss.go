@@ -1,6 +1,7 @@
 package api
 
 import (
+	"asset-management/config"
 	"asset-management/myerror"
 	"asset-management/utils"
 
@@ -10,28 +11,14 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 )
 
-type ossApi struct {
+type OssApi struct {
+	conf config.STSConfig
 }
 
-var OssApi *ossApi
-
-func newOssApi() *ossApi {
-	return &ossApi{}
-}
-
-func init() {
-	OssApi = newOssApi()
+func NewOssApi(conf config.STSConfig) *OssApi {
+	return &OssApi{conf: conf}
 }
 
-const (
-	roleArn           = "acs:ram::1237783510428187:role/xiongjc21"
-	roleSessionName   = "xiongjc21"
-	durationSeconds   = 900
-	myAccessKeyId     = "LTAI5tCpT5SSksUNe355TY8V"
-	myAccessKeySecret = "WgkwIjagXCfiu0ykLmrZu1bcXQswV5"
-	endpoint          = "sts.cn-beijing.aliyuncs.com"
-)
-
 /**
  * 使用AK&SK初始化账号Client
  * @param accessKeyId
@@ -39,25 +26,25 @@ const (
  * @return Client
  * @throws Exception
  */
-func (oss *ossApi) createClient(accessKeyId *string, accessKeySecret *string) (_result *sts20150401.Client, _err error) {
-	config := &openapi.Config{
+func (oss *OssApi) createClient(accessKeyId *string, accessKeySecret *string) (_result *sts20150401.Client, _err error) {
+	conf := &openapi.Config{
 		// 必填，您的 AccessKey ID
 		AccessKeyId: accessKeyId,
 		// 必填，您的 AccessKey Secret
 		AccessKeySecret: accessKeySecret,
 	}
 	// 访问的域名
-	config.Endpoint = tea.String(endpoint)
+	conf.Endpoint = tea.String(oss.conf.Endpoint)
 	_result = &sts20150401.Client{}
-	_result, _err = sts20150401.NewClient(config)
+	_result, _err = sts20150401.NewClient(conf)
 	return _result, _err
 }
 
 /*
 Handle func for GET /oss/key
 */
-func (oss *ossApi) GetTempKey(ctx *utils.Context) {
-	client, err := oss.createClient(tea.String(myAccessKeyId), tea.String(myAccessKeySecret))
+func (oss *OssApi) GetTempKey(ctx *utils.Context) {
+	client, err := oss.createClient(tea.String(oss.conf.AccessKeyID), tea.String(oss.conf.AccessKeySecret))
 
 	if err != nil {
 		ctx.BadRequest(myerror.OSS_REQUEST_FAILED, myerror.OSS_REQUEST_FAILED_INFO)
@@ -65,9 +52,9 @@ func (oss *ossApi) GetTempKey(ctx *utils.Context) {
 	}
 
 	assumeRoleRequest := &sts20150401.AssumeRoleRequest{
-		DurationSeconds: tea.Int64(durationSeconds),
-		RoleArn:         tea.String(roleArn),
-		RoleSessionName: tea.String(roleSessionName),
+		DurationSeconds: tea.Int64(oss.conf.DurationSeconds),
+		RoleArn:         tea.String(oss.conf.RoleArn),
+		RoleSessionName: tea.String(oss.conf.RoleSessionName),
 	}
 	runtime := &util.RuntimeOptions{}
 
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/mail/mailtest"
+	"asset-management/config"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset(t *testing.T) {
+	catcher := mailtest.NewServer()
+	defer catcher.Close()
+	conf := config.ForTest()
+	conf.Mail = catcher.Config()
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTestWithConfig(r, conf)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.User.userService.CreateUser("reset_user", password)
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := userDao.GetUserByName("reset_user")
+	assert.Equal(t, nil, err, "dao error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		return call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "reset_user", Password: password})
+	}
+	res = login(password)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	token := data.Data.Token
+	emailURL := fmt.Sprintf("/user/info/%d/email", thisUser.ID)
+
+	res = call(http.MethodPut, emailURL, token, define.ChangeEmailReq{Email: "not an address"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPut, emailURL, token, define.ChangeEmailReq{Email: "reset@example.com"})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	// unknown users get the same answer and no mail
+	res = call(http.MethodPost, "/user/password/forgot", "", define.ForgotPasswordReq{UserName: "reset_nobody", Channel: define.RESET_BY_EMAIL})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.Equal(t, 0, len(catcher.Messages()), "response failed")
+	res = call(http.MethodPost, "/user/password/forgot", "", define.ForgotPasswordReq{UserName: "reset_user", Channel: "sms"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, "/user/password/forgot", "", define.ForgotPasswordReq{UserName: "reset_user", Channel: define.RESET_BY_EMAIL})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	messages := catcher.Messages()
+	assert.Equal(t, 1, len(messages), "response failed")
+	assert.Equal(t, []string{"reset@example.com"}, messages[0].To, "response failed")
+	resetToken := regexp.MustCompile(define.PASSWORD_RESET_PREFIX + "[0-9a-f]+").FindString(messages[0].Data)
+
+	// the reset ends every session and works once
+	newPassword := "e10adc3949ba59abbe56e057f20f883e"
+	res = call(http.MethodPost, "/user/password/reset", "", define.ResetPasswordReq{Token: resetToken, Password: "123"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, "/user/password/reset", "", define.ResetPasswordReq{Token: resetToken, Password: newPassword})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, "/user/password/reset", "", define.ResetPasswordReq{Token: resetToken, Password: newPassword})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	errData := utils.ResponseData{}
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.PASSWORD_RESET_INVALID, errData.Error.Code, "response failed")
+
+	res = call(http.MethodGet, "/user/sessions", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	res = login(password)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = login(newPassword)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("password_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("password_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Department.departmentService.CreateDepartment("password_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{UserName: "password_employee", Password: password}, entityID, departmentID)
+	assert.Equal(t, nil, err, "service error")
+	employee, err := userDao.GetUserByName("password_employee")
+	assert.Equal(t, nil, err, "dao error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(username string, password string) define.UserLoginResponse {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: username, Password: password})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := struct {
+			Data define.UserLoginResponse `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data.Data
+	}
+	errorCode := func(res *httptest.ResponseRecorder) int {
+		data := utils.ResponseData{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data.Error.Code
+	}
+	policyURL := fmt.Sprintf("/entity/%d/password-policy", entityID)
+	passwordURL := fmt.Sprintf("/user/info/%d/password", employee.ID)
+	managerToken := login("password_manager", password).Token
+	employeeToken := login("password_employee", password).Token
+
+	// the entity starts from the configured minimum length
+	res = call(http.MethodGet, policyURL, employeeToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, policyURL, managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	policy := struct {
+		Data define.PasswordPolicyInfo `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &policy)
+	assert.Equal(t, uint(6), policy.Data.MinLength, "response failed")
+
+	res = call(http.MethodPut, policyURL, managerToken, define.PasswordPolicyReq{MinLength: 10, HistorySize: 13})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPut, policyURL, managerToken, define.PasswordPolicyReq{MinLength: 10, RequireDigit: true, HistorySize: 2})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, policyURL, managerToken, nil)
+	json.Unmarshal(res.Body.Bytes(), &policy)
+	assert.Equal(t, uint(10), policy.Data.MinLength, "response failed")
+	assert.Equal(t, true, policy.Data.RequireDigit, "response failed")
+
+	// weak passwords are turned away, also for new accounts
+	res = call(http.MethodPost, passwordURL, managerToken, define.ChangePasswordReq{Password: "weakpassword"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Equal(t, myerror.PASSWORD_POLICY_VIOLATED, errorCode(res), "response failed")
+	res = call(http.MethodPost, fmt.Sprintf("/entity/%d/department/%d/user", entityID, departmentID), managerToken, define.CreateDepartmentUserReq{UserName: "password_weak", Password: "short1"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	// a password set by a super has to be changed before anything else
+	res = call(http.MethodPost, passwordURL, managerToken, define.ChangePasswordReq{Password: "temporary123"})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/sessions", employeeToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	employeeLogin := login("password_employee", "temporary123")
+	assert.Equal(t, true, employeeLogin.PasswordChangeRequired, "response failed")
+	res = call(http.MethodGet, "/user/sessions", employeeLogin.Token, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	assert.Equal(t, myerror.PASSWORD_CHANGE_REQUIRED, errorCode(res), "response failed")
+
+	// the current password counts towards the history
+	res = call(http.MethodPost, passwordURL, employeeLogin.Token, define.ChangePasswordReq{Password: "temporary123"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Equal(t, myerror.PASSWORD_REUSED, errorCode(res), "response failed")
+	res = call(http.MethodPost, passwordURL, employeeLogin.Token, define.ChangePasswordReq{Password: "mypassword123"})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, passwordURL, login("password_employee", "mypassword123").Token, define.ChangePasswordReq{Password: "temporary123"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	employeeLogin = login("password_employee", "mypassword123")
+	assert.Equal(t, false, employeeLogin.PasswordChangeRequired, "response failed")
+	res = call(http.MethodGet, "/user/sessions", employeeLogin.Token, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+}
@@ -0,0 +1,478 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+	"github.com/thoas/go-funk"
+)
+
+type PermissionApi struct {
+	departmentService service.DepartmentServiceInterface
+	entityService     service.EntityServiceInterface
+	permissionService service.PermissionServiceInterface
+	userService       service.UserServiceInterface
+}
+
+func NewPermissionApi(
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	permissionService service.PermissionServiceInterface,
+	userService service.UserServiceInterface,
+) *PermissionApi {
+	return &PermissionApi{
+		departmentService: departmentService,
+		entityService:     entityService,
+		permissionService: permissionService,
+		userService:       userService,
+	}
+}
+
+/*
+Who the permissions are looked up for, requests made with an API token get only
+what the token carries
+*/
+func permissionSubject(ctx *utils.Context) (define.UserBasicInfo, bool) {
+	operator := GetOperatorInfo(ctx)
+	if operator == nil {
+		return define.UserBasicInfo{}, false
+	}
+	subject := *operator
+	if _, exists := ctx.Get("api_token_id"); exists {
+		subject.UserID = 0
+	}
+	return subject, true
+}
+
+/*
+Write the response for a permission lookup, true only when it was allowed
+*/
+func permissionResult(ctx *utils.Context, allowed bool, err error) bool {
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	} else if !allowed {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return false
+	}
+	return true
+}
+
+/*
+Whether the operator holds the permission on the entity, or on the department when
+departmentID is set
+*/
+func (permission *PermissionApi) Allowed(ctx *utils.Context, name string, entityID uint, departmentID uint) (bool, error) {
+	subject, ok := permissionSubject(ctx)
+	if !ok {
+		return false, nil
+	}
+	return permission.permissionService.Can(subject, name, entityID, departmentID)
+}
+
+/*
+Same as Allowed, the response is written when false
+*/
+func (permission *PermissionApi) Check(ctx *utils.Context, name string, entityID uint, departmentID uint) bool {
+	allowed, err := permission.Allowed(ctx, name, entityID, departmentID)
+	return permissionResult(ctx, allowed, err)
+}
+
+/*
+Whether another user holds the permission, with every role assigned to them
+*/
+func (permission *PermissionApi) UserAllowed(thisUser *model.User, name string, entityID uint, departmentID uint) (bool, error) {
+	return permission.permissionService.Can(service.UserBasicInfo(thisUser), name, entityID, departmentID)
+}
+
+/*
+Whether the operator holds the permission on the entity or on any one of its departments
+*/
+func (permission *PermissionApi) AllowedInEntity(ctx *utils.Context, name string, entityID uint) (bool, error) {
+	subject, ok := permissionSubject(ctx)
+	if !ok {
+		return false, nil
+	}
+	return permission.permissionService.CanInEntity(subject, name, entityID)
+}
+
+/*
+Same as AllowedInEntity, the response is written when false
+*/
+func (permission *PermissionApi) CheckInEntity(ctx *utils.Context, name string, entityID uint) bool {
+	allowed, err := permission.AllowedInEntity(ctx, name, entityID)
+	return permissionResult(ctx, allowed, err)
+}
+
+/*
+Whether the operator holds the permission on the department or on any department above it
+*/
+func (permission *PermissionApi) AllowedInDepartmentTree(ctx *utils.Context, name string, departmentID uint) (bool, error) {
+	subject, ok := permissionSubject(ctx)
+	if !ok {
+		return false, nil
+	}
+	return permission.permissionService.CanInDepartmentTree(subject, name, departmentID)
+}
+
+/*
+Same as AllowedInDepartmentTree, the response is written when false
+*/
+func (permission *PermissionApi) CheckInDepartmentTree(ctx *utils.Context, name string, departmentID uint) bool {
+	allowed, err := permission.AllowedInDepartmentTree(ctx, name, departmentID)
+	return permissionResult(ctx, allowed, err)
+}
+
+func (permission *PermissionApi) checkManageRoles(ctx *utils.Context) (uint, bool) {
+	entityID, err := permission.entityService.GetParamID(ctx, "entity_id")
+	if err != nil {
+		return 0, false
+	}
+	exists, err := permission.entityService.ExistsEntityByID(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return 0, false
+	} else if !exists {
+		ctx.NotFound(myerror.ENTITY_NOT_FOUND, myerror.ENTITY_NOT_FOUND_INFO)
+		return 0, false
+	}
+	return entityID, permission.Check(ctx, define.PERM_ROLE_MANAGE, entityID, 0)
+}
+
+func (permission *PermissionApi) bindRoleReq(ctx *utils.Context) (*define.RoleReq, bool) {
+	var req define.RoleReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return nil, false
+	}
+	for _, name := range req.Permissions {
+		if !define.IsPermission(name) {
+			ctx.BadRequest(myerror.PERMISSION_UNKNOWN, myerror.PERMISSION_UNKNOWN_INFO)
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+/*
+The entity's own role behind role_id, the response is written when false
+*/
+func (permission *PermissionApi) entityRole(ctx *utils.Context, entityID uint) (*model.Role, bool) {
+	roleID, err := permission.entityService.GetParamID(ctx, "role_id")
+	if err != nil {
+		return nil, false
+	}
+	thisRole, err := permission.permissionService.GetRoleByID(roleID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, false
+	} else if thisRole != nil && thisRole.BuiltIn {
+		ctx.Forbidden(myerror.ROLE_BUILT_IN, myerror.ROLE_BUILT_IN_INFO)
+		return nil, false
+	} else if thisRole == nil || thisRole.EntityID != entityID {
+		ctx.NotFound(myerror.ROLE_NOT_FOUND, myerror.ROLE_NOT_FOUND_INFO)
+		return nil, false
+	}
+	return thisRole, true
+}
+
+/*
+Handle func for GET /user/permissions, the operator's permissions on their entity,
+or on the department given by the department_id query
+*/
+func (permission *PermissionApi) GetMyPermissions(ctx *utils.Context) {
+	operator := GetOperatorInfo(ctx)
+	var departmentID uint
+	if query := ctx.Query("department_id"); query != "" {
+		id, err := strconv.ParseUint(query, 10, 0)
+		if err != nil {
+			ctx.BadRequest(myerror.INVALID_PARAM, myerror.INVALID_PARAM_INFO)
+			return
+		}
+		departmentID = uint(id)
+	}
+	subject := *operator
+	if _, exists := ctx.Get("api_token_id"); exists {
+		subject.UserID = 0
+	}
+	granted, err := permission.permissionService.Permissions(subject, operator.EntityID, departmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	if granted == nil {
+		granted = []string{}
+	}
+	ctx.Success(define.PermissionListResponse{
+		Permissions: granted,
+	})
+}
+
+/*
+Handle func for GET /entity/:entity_id/roles
+*/
+func (permission *PermissionApi) GetRoles(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	roleList, err := permission.permissionService.GetEntityRoles(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	roleListRes := []define.RoleInfo{}
+	err = copier.Copy(&roleListRes, roleList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.RoleListResponse{
+		RoleList: roleListRes,
+	})
+}
+
+/*
+Handle func for POST /entity/:entity_id/roles
+*/
+func (permission *PermissionApi) CreateRole(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	req, ok := permission.bindRoleReq(ctx)
+	if !ok {
+		return
+	}
+	exists, err := permission.permissionService.ExistsRoleName(entityID, req.Name)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if exists {
+		ctx.BadRequest(myerror.ROLE_HAS_EXIST, myerror.ROLE_HAS_EXIST_INFO)
+		return
+	}
+
+	newRole, err := permission.permissionService.CreateRole(entityID, *req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var roleInfo define.RoleInfo
+	err = copier.Copy(&roleInfo, newRole)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(roleInfo)
+}
+
+/*
+Handle func for PUT /entity/:entity_id/roles/:role_id
+*/
+func (permission *PermissionApi) ModifyRole(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	thisRole, ok := permission.entityRole(ctx, entityID)
+	if !ok {
+		return
+	}
+	req, ok := permission.bindRoleReq(ctx)
+	if !ok {
+		return
+	}
+	if req.Name != thisRole.Name {
+		exists, err := permission.permissionService.ExistsRoleName(entityID, req.Name)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		} else if exists {
+			ctx.BadRequest(myerror.ROLE_HAS_EXIST, myerror.ROLE_HAS_EXIST_INFO)
+			return
+		}
+	}
+
+	err := permission.permissionService.ModifyRole(thisRole.ID, *req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for DELETE /entity/:entity_id/roles/:role_id, its assignments go with it
+*/
+func (permission *PermissionApi) DeleteRole(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	thisRole, ok := permission.entityRole(ctx, entityID)
+	if !ok {
+		return
+	}
+
+	err := permission.permissionService.DeleteRole(thisRole.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /entity/:entity_id/role-assignments
+*/
+func (permission *PermissionApi) GetAssignments(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	assignmentList, err := permission.permissionService.GetEntityAssignments(entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	assignmentListRes := []define.RoleAssignmentInfo{}
+	for _, assignment := range assignmentList {
+		assignmentListRes = append(assignmentListRes, define.RoleAssignmentInfo{
+			ID:           assignment.ID,
+			UserID:       assignment.UserID,
+			RoleID:       assignment.RoleID,
+			RoleName:     assignment.Role.Name,
+			EntityID:     assignment.EntityID,
+			DepartmentID: assignment.DepartmentID,
+		})
+	}
+	ctx.Success(define.RoleAssignmentListResponse{
+		AssignmentList: assignmentListRes,
+	})
+}
+
+/*
+Handle func for POST /entity/:entity_id/role-assignments
+*/
+func (permission *PermissionApi) Assign(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	var req define.RoleAssignmentReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	thisRole, err := permission.permissionService.GetRoleByID(req.RoleID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisRole == nil || (!thisRole.BuiltIn && thisRole.EntityID != entityID) {
+		ctx.NotFound(myerror.ROLE_NOT_FOUND, myerror.ROLE_NOT_FOUND_INFO)
+		return
+	}
+	thisUser, err := permission.userService.GetUserByID(req.UserID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisUser == nil {
+		ctx.NotFound(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return
+	} else if thisUser.EntityID != entityID {
+		ctx.BadRequest(myerror.USER_NOT_IN_ENTITY, myerror.USER_NOT_IN_ENTITY_INFO)
+		return
+	}
+	if req.DepartmentID != 0 {
+		thisDepartment, err := permission.departmentService.GetDepartmentInfoByID(req.DepartmentID)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		} else if thisDepartment == nil || thisDepartment.EntityID != entityID {
+			ctx.BadRequest(myerror.DEPARTMENT_NOT_IN_ENTITY, myerror.DEPARTMENT_NOT_IN_ENTITY_INFO)
+			return
+		}
+	}
+	// built-in roles are shared by every entity, none may hand out more than the operator holds
+	if thisRole.BuiltIn {
+		subject, _ := permissionSubject(ctx)
+		granted, err := permission.permissionService.Permissions(subject, entityID, req.DepartmentID)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return
+		} else if len(funk.SubtractString([]string(thisRole.Permissions), granted)) != 0 {
+			ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+			return
+		}
+	}
+
+	newAssignment, err := permission.permissionService.Assign(entityID, req)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.RoleAssignmentInfo{
+		ID:           newAssignment.ID,
+		UserID:       newAssignment.UserID,
+		RoleID:       newAssignment.RoleID,
+		RoleName:     thisRole.Name,
+		EntityID:     newAssignment.EntityID,
+		DepartmentID: newAssignment.DepartmentID,
+	})
+}
+
+/*
+Handle func for DELETE /entity/:entity_id/role-assignments/:assignment_id
+*/
+func (permission *PermissionApi) Unassign(ctx *utils.Context) {
+	entityID, ok := permission.checkManageRoles(ctx)
+	if !ok {
+		return
+	}
+	assignmentID, err := permission.entityService.GetParamID(ctx, "assignment_id")
+	if err != nil {
+		return
+	}
+	thisAssignment, err := permission.permissionService.GetAssignmentByID(assignmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisAssignment == nil || thisAssignment.EntityID != entityID {
+		ctx.NotFound(myerror.ROLE_ASSIGNMENT_NOT_FOUND, myerror.ROLE_ASSIGNMENT_NOT_FOUND_INFO)
+		return
+	}
+
+	err = permission.permissionService.Unassign(assignmentID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Middleware that lets the request through only when the operator holds the permission
+outside of any entity, such as system.manage
+*/
+func (permission *PermissionApi) Require(name string) utils.HandlerFunc {
+	return func(ctx *utils.Context) {
+		if GetOperatorInfo(ctx) == nil {
+			ctx.Unauthorized(myerror.TOKEN_INVALID, myerror.TOKEN_INVALID_INFO)
+			ctx.Abort()
+			return
+		}
+		if !permission.Check(ctx, name, 0, 0) {
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
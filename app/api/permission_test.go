@@ -0,0 +1,159 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoles(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("role_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("role_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("role_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Permission.departmentService.CreateDepartment("role_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[len(departmentList)-1].ID
+	err = userDao.Create(model.User{
+		UserName:     "role_user",
+		Password:     utils.CreateMD5(password),
+		EntityID:     entityID,
+		DepartmentID: departmentID,
+	})
+	assert.Equal(t, nil, err, "service error")
+	roleUser, err := userDao.GetUserByName("role_user")
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reader = GetJsonBody(body)
+		}
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, reader)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(username string) string {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: username, Password: password})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data["data"].(map[string]interface{})["token"].(string)
+	}
+	managerToken := login("role_manager")
+	userToken := login("role_user")
+
+	rolesURL := fmt.Sprintf("/entity/%d/roles", entityID)
+	res = call(http.MethodGet, rolesURL, userToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, fmt.Sprintf("/entity/%d/roles", otherID), managerToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodPost, rolesURL, managerToken, define.RoleReq{Name: "bad", Permissions: []string{"asset.fly"}})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, rolesURL, managerToken, define.RoleReq{Name: define.ROLE_ENTITY_SUPER, Permissions: []string{define.PERM_LOG_VIEW}})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, rolesURL, managerToken, define.RoleReq{Name: "auditor", Permissions: []string{define.PERM_LOG_VIEW}})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	var roleRes struct {
+		Data define.RoleInfo `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &roleRes)
+	assert.Equal(t, []string{define.PERM_LOG_VIEW}, roleRes.Data.Permissions, "response failed")
+
+	res = call(http.MethodGet, rolesURL, managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	var listRes struct {
+		Data define.RoleListResponse `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &listRes)
+	assert.Equal(t, len(define.BUILT_IN_ROLES)+1, len(listRes.Data.RoleList), "response failed")
+	builtInID := listRes.Data.RoleList[0].ID
+	res = call(http.MethodPut, fmt.Sprintf("%s/%d", rolesURL, builtInID), managerToken, define.RoleReq{Name: "x", Permissions: []string{}})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	// the auditor role opens the entity's logs to a plain employee
+	logsURL := fmt.Sprintf("/entity/%d/login-logs?page_size=10&page_num=1", entityID)
+	res = call(http.MethodGet, logsURL, userToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	assignURL := fmt.Sprintf("/entity/%d/role-assignments", entityID)
+	// an entity super can't hand out the system super role it doesn't hold
+	for _, builtIn := range listRes.Data.RoleList {
+		if builtIn.Name == define.ROLE_SYSTEM_SUPER {
+			res = call(http.MethodPost, assignURL, managerToken, define.RoleAssignmentReq{UserID: roleUser.ID, RoleID: builtIn.ID})
+			assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+		}
+	}
+	res = call(http.MethodPost, assignURL, managerToken, define.RoleAssignmentReq{UserID: roleUser.ID, RoleID: roleRes.Data.ID, DepartmentID: departmentID + 1000})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, assignURL, managerToken, define.RoleAssignmentReq{UserID: roleUser.ID, RoleID: roleRes.Data.ID})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	var assignmentRes struct {
+		Data define.RoleAssignmentInfo `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &assignmentRes)
+	assert.Equal(t, "auditor", assignmentRes.Data.RoleName, "response failed")
+
+	res = call(http.MethodGet, logsURL, userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/permissions", userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	var permissionRes struct {
+		Data define.PermissionListResponse `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &permissionRes)
+	assert.Equal(t, []string{define.PERM_LOG_VIEW}, permissionRes.Data.Permissions, "response failed")
+
+	res = call(http.MethodDelete, fmt.Sprintf("/entity/%d/role-assignments/%d", otherID, assignmentRes.Data.ID), managerToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", assignURL, assignmentRes.Data.ID), managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, logsURL, userToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodDelete, fmt.Sprintf("%s/%d", rolesURL, roleRes.Data.ID), managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+	// managing users is a permission like any other, not the entity super flag
+	err = userDao.Create(model.User{UserName: "role_colleague", Password: utils.CreateMD5(password), EntityID: entityID})
+	assert.Equal(t, nil, err, "service error")
+	res = call(http.MethodGet, "/user/role_colleague/lock", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, rolesURL, managerToken, define.RoleReq{Name: "user_admin", Permissions: []string{define.PERM_USER_MANAGE}})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &roleRes)
+	res = call(http.MethodPost, assignURL, managerToken, define.RoleAssignmentReq{UserID: roleUser.ID, RoleID: roleRes.Data.ID})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/role_colleague/lock", userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/role_colleague/unlock", userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, fmt.Sprintf("/entity/%d/department/list", entityID), userToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+}
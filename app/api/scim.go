@@ -0,0 +1,646 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+/*
+SCIM 2.0 provisioning: users of the entity are Users, its departments are Groups.
+Every handler answers in the SCIM format, the entity is the one of the bearer token
+*/
+type ScimApi struct {
+	assetService      service.AssetServiceInterface
+	asyncService      service.AsyncServiceInterface
+	departmentService service.DepartmentServiceInterface
+	scimService       service.ScimServiceInterface
+	taskService       service.TaskServiceInterface
+	tokenService      service.TokenServiceInterface
+	userService       service.UserServiceInterface
+}
+
+func NewScimApi(
+	assetService service.AssetServiceInterface,
+	asyncService service.AsyncServiceInterface,
+	departmentService service.DepartmentServiceInterface,
+	scimService service.ScimServiceInterface,
+	taskService service.TaskServiceInterface,
+	tokenService service.TokenServiceInterface,
+	userService service.UserServiceInterface,
+) *ScimApi {
+	return &ScimApi{
+		assetService:      assetService,
+		asyncService:      asyncService,
+		departmentService: departmentService,
+		scimService:       scimService,
+		taskService:       taskService,
+		tokenService:      tokenService,
+		userService:       userService,
+	}
+}
+
+func scimEntityID(ctx *utils.Context) uint {
+	if operator := GetOperatorInfo(ctx); operator != nil {
+		return operator.EntityID
+	}
+	return 0
+}
+
+/*
+The 1-based window of a list request, count is capped at SCIM_MAX_PAGE_SIZE
+*/
+func scimPage(ctx *utils.Context, total int) (start int, end int, ok bool) {
+	startIndex, count := 1, define.SCIM_MAX_PAGE_SIZE
+	var err error
+	if value := ctx.Query("startIndex"); value != "" {
+		if startIndex, err = strconv.Atoi(value); err != nil {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, "startIndex must be an integer")
+			return 0, 0, false
+		}
+	}
+	if value := ctx.Query("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, "count must be an integer")
+			return 0, 0, false
+		}
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	} else if count > define.SCIM_MAX_PAGE_SIZE {
+		count = define.SCIM_MAX_PAGE_SIZE
+	}
+	start = startIndex - 1
+	if start > total {
+		start = total
+	}
+	end = start + count
+	if end > total {
+		end = total
+	}
+	return start, end, true
+}
+
+func scimList(ctx *utils.Context, start int, total int, resources interface{}, itemsPerPage int) {
+	ctx.Scim(http.StatusOK, define.ScimListResponse{
+		Schemas:      []string{define.SCIM_LIST_SCHEMA},
+		TotalResults: total,
+		StartIndex:   start + 1,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	})
+}
+
+func scimParamID(ctx *utils.Context, key string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(key), 10, 64)
+	if err != nil {
+		ctx.ScimError(http.StatusNotFound, "", "Resource "+ctx.Param(key)+" not found")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func scimListError(ctx *utils.Context, err error) {
+	if errors.Is(err, service.ErrScimFilter) {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_FILTER_INVALID, err.Error())
+		return
+	}
+	ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+}
+
+func scimPatchError(ctx *utils.Context, err error) {
+	if errors.Is(err, service.ErrScimPatch) {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, err.Error())
+		return
+	}
+	ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+}
+
+/*
+A password the entity's policy rejects is the client's fault, anything else is ours
+*/
+func scimUserError(ctx *utils.Context, err error) {
+	if errors.Is(err, service.ErrPasswordPolicy) || errors.Is(err, service.ErrPasswordReused) {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, err.Error())
+		return
+	}
+	ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+}
+
+/*
+Handle func for GET /scim/v2/ServiceProviderConfig
+*/
+func (scim *ScimApi) GetServiceProviderConfig(ctx *utils.Context) {
+	ctx.Scim(http.StatusOK, define.ScimServiceProviderConfig{
+		Schemas: []string{define.SCIM_CONFIG_SCHEMA},
+		Patch:   define.ScimSupported{Supported: true},
+		Filter: define.ScimFilterSupported{
+			Supported:  true,
+			MaxResults: define.SCIM_MAX_PAGE_SIZE,
+		},
+		AuthenticationSchemes: []define.ScimAuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "API key",
+			Description: "An entity API key sent as a bearer token",
+		}},
+	})
+}
+
+/*
+A user that is to change department must hold no assets and have no task in progress
+*/
+func (scim *ScimApi) checkUserMovable(ctx *utils.Context, userID uint) bool {
+	assets, err := scim.assetService.GetAssetByUser(userID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return false
+	}
+	if len(assets) != 0 {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, myerror.USER_HAS_ASSETS_INFO)
+		return false
+	}
+
+	tasks, err := scim.taskService.GetTasksByUserID(userID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return false
+	}
+	for _, task := range tasks {
+		if task.State == 0 {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, myerror.USER_HAS_TASKS_INFO)
+			return false
+		}
+	}
+
+	asyncTasks, err := scim.asyncService.GetUserAsyncTasks(userID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return false
+	}
+	for _, asyncTask := range asyncTasks {
+		if asyncTask.State == 0 || asyncTask.State == 1 {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, myerror.USER_HAS_TASKS_INFO)
+			return false
+		}
+	}
+	return true
+}
+
+func (scim *ScimApi) bindUser(ctx *utils.Context) (define.ScimUser, bool) {
+	var resource define.ScimUser
+	if err := ctx.ShouldBindWith(&resource, binding.JSON); err != nil {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_SYNTAX_INVALID, myerror.INVALID_BODY_INFO)
+		return resource, false
+	}
+	if resource.UserName == "" {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, "userName is required")
+		return resource, false
+	}
+	return resource, true
+}
+
+func (scim *ScimApi) getUser(ctx *utils.Context) (*model.User, bool) {
+	userID, ok := scimParamID(ctx, "user_id")
+	if !ok {
+		return nil, false
+	}
+	thisUser, err := scim.scimService.GetUser(scimEntityID(ctx), userID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return nil, false
+	} else if thisUser == nil {
+		ctx.ScimError(http.StatusNotFound, "", "User "+ctx.Param("user_id")+" not found")
+		return nil, false
+	}
+	return thisUser, true
+}
+
+/*
+Writes the desired state of a user and answers with the stored resource.
+Deactivating a user ends their sessions, the key's owner cannot deactivate themselves
+*/
+func (scim *ScimApi) applyUser(ctx *utils.Context, thisUser *model.User, resource define.ScimUser) {
+	deactivate := resource.Active != nil && !*resource.Active && !thisUser.Ban
+	if deactivate && thisUser.ID == GetOperatorID(ctx) {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, "The owner of the token cannot deactivate themselves")
+		return
+	}
+	if resource.UserName != thisUser.UserName {
+		exists, err := scim.userService.ExistsUser(resource.UserName)
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		} else if exists {
+			ctx.ScimError(http.StatusConflict, define.SCIM_UNIQUENESS, myerror.USER_HAS_EXISTED_INFO)
+			return
+		}
+	}
+
+	err := scim.scimService.ModifyUser(thisUser, resource)
+	if err != nil {
+		scimUserError(ctx, err)
+		return
+	}
+	if deactivate {
+		err = scim.tokenService.RevokeUserTokens(thisUser.ID)
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+
+	thisUser, err = scim.userService.GetUserByID(thisUser.ID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	ctx.Scim(http.StatusOK, scim.scimService.UserResource(thisUser))
+}
+
+/*
+Handle func for GET /scim/v2/Users
+*/
+func (scim *ScimApi) GetUsers(ctx *utils.Context) {
+	users, err := scim.scimService.GetUsers(scimEntityID(ctx), ctx.Query("filter"))
+	if err != nil {
+		scimListError(ctx, err)
+		return
+	}
+	start, end, ok := scimPage(ctx, len(users))
+	if !ok {
+		return
+	}
+	scimList(ctx, start, len(users), users[start:end], end-start)
+}
+
+/*
+Handle func for GET /scim/v2/Users/{user_id}
+*/
+func (scim *ScimApi) GetUser(ctx *utils.Context) {
+	thisUser, ok := scim.getUser(ctx)
+	if !ok {
+		return
+	}
+	ctx.Scim(http.StatusOK, scim.scimService.UserResource(thisUser))
+}
+
+/*
+Handle func for POST /scim/v2/Users
+*/
+func (scim *ScimApi) CreateUser(ctx *utils.Context) {
+	resource, ok := scim.bindUser(ctx)
+	if !ok {
+		return
+	}
+	exists, err := scim.userService.ExistsUser(resource.UserName)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	} else if exists {
+		ctx.ScimError(http.StatusConflict, define.SCIM_UNIQUENESS, myerror.USER_HAS_EXISTED_INFO)
+		return
+	}
+
+	thisUser, err := scim.scimService.CreateUser(scimEntityID(ctx), resource)
+	if err != nil {
+		scimUserError(ctx, err)
+		return
+	}
+	created := scim.scimService.UserResource(thisUser)
+	ctx.Header("Location", created.Meta.Location)
+	ctx.Scim(http.StatusCreated, created)
+}
+
+/*
+Handle func for PUT /scim/v2/Users/{user_id}
+*/
+func (scim *ScimApi) ReplaceUser(ctx *utils.Context) {
+	thisUser, ok := scim.getUser(ctx)
+	if !ok {
+		return
+	}
+	resource, ok := scim.bindUser(ctx)
+	if !ok {
+		return
+	}
+	scim.applyUser(ctx, thisUser, resource)
+}
+
+/*
+Handle func for PATCH /scim/v2/Users/{user_id}
+*/
+func (scim *ScimApi) PatchUser(ctx *utils.Context) {
+	thisUser, ok := scim.getUser(ctx)
+	if !ok {
+		return
+	}
+	var patchReq define.ScimPatchReq
+	if err := ctx.ShouldBindWith(&patchReq, binding.JSON); err != nil {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_SYNTAX_INVALID, myerror.INVALID_BODY_INFO)
+		return
+	}
+	resource, err := scim.scimService.PatchUser(scim.scimService.UserResource(thisUser), patchReq.Operations)
+	if err != nil {
+		scimPatchError(ctx, err)
+		return
+	}
+	scim.applyUser(ctx, thisUser, resource)
+}
+
+/*
+Handle func for DELETE /scim/v2/Users/{user_id}
+*/
+func (scim *ScimApi) DeleteUser(ctx *utils.Context) {
+	thisUser, ok := scim.getUser(ctx)
+	if !ok {
+		return
+	}
+	if thisUser.ID == GetOperatorID(ctx) {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, myerror.DELETE_USER_SELF_INFO)
+		return
+	}
+
+	err := scim.tokenService.RevokeUserTokens(thisUser.ID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	err = scim.userService.DeleteUser(thisUser.ID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (scim *ScimApi) bindGroup(ctx *utils.Context) (define.ScimGroup, bool) {
+	var resource define.ScimGroup
+	if err := ctx.ShouldBindWith(&resource, binding.JSON); err != nil {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_SYNTAX_INVALID, myerror.INVALID_BODY_INFO)
+		return resource, false
+	}
+	if resource.DisplayName == "" {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, "displayName is required")
+		return resource, false
+	}
+	return resource, true
+}
+
+func (scim *ScimApi) getGroup(ctx *utils.Context) (*model.Department, bool) {
+	departmentID, ok := scimParamID(ctx, "group_id")
+	if !ok {
+		return nil, false
+	}
+	thisDepartment, err := scim.scimService.GetGroup(scimEntityID(ctx), departmentID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return nil, false
+	} else if thisDepartment == nil {
+		ctx.ScimError(http.StatusNotFound, "", "Group "+ctx.Param("group_id")+" not found")
+		return nil, false
+	}
+	return thisDepartment, true
+}
+
+func hasScimMember(members []define.ScimMember, value string) bool {
+	for _, member := range members {
+		if member.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Users joining or leaving the department, all of them checked before any is moved
+*/
+func (scim *ScimApi) memberChanges(ctx *utils.Context, current define.ScimGroup, desired define.ScimGroup) (joined []uint, left []uint, ok bool) {
+	for _, member := range desired.Members {
+		if hasScimMember(current.Members, member.Value) {
+			continue
+		}
+		userID, err := strconv.ParseUint(member.Value, 10, 64)
+		if err != nil {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, fmt.Sprintf("Member %s is not a user", member.Value))
+			return nil, nil, false
+		}
+		thisUser, err := scim.scimService.GetUser(scimEntityID(ctx), uint(userID))
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return nil, nil, false
+		} else if thisUser == nil {
+			ctx.ScimError(http.StatusBadRequest, define.SCIM_VALUE_INVALID, fmt.Sprintf("Member %s is not a user of the entity", member.Value))
+			return nil, nil, false
+		}
+		if !scim.checkUserMovable(ctx, thisUser.ID) {
+			return nil, nil, false
+		}
+		joined = append(joined, thisUser.ID)
+	}
+	for _, member := range current.Members {
+		if hasScimMember(desired.Members, member.Value) {
+			continue
+		}
+		userID, _ := strconv.ParseUint(member.Value, 10, 64)
+		if !scim.checkUserMovable(ctx, uint(userID)) {
+			return nil, nil, false
+		}
+		left = append(left, uint(userID))
+	}
+	return joined, left, true
+}
+
+/*
+Writes the desired name and members of a department and answers with the stored resource
+*/
+func (scim *ScimApi) applyGroup(ctx *utils.Context, thisDepartment *model.Department, current define.ScimGroup, desired define.ScimGroup, status int) {
+	if desired.DisplayName != thisDepartment.Name {
+		exists, err := scim.departmentService.ExistsDepartmentSub(desired.DisplayName, thisDepartment.EntityID, thisDepartment.ParentID)
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		} else if exists {
+			ctx.ScimError(http.StatusConflict, define.SCIM_UNIQUENESS, myerror.DUPLICATED_NAME_INFO)
+			return
+		}
+	}
+	joined, left, ok := scim.memberChanges(ctx, current, desired)
+	if !ok {
+		return
+	}
+
+	if desired.DisplayName != thisDepartment.Name {
+		err := scim.scimService.RenameGroup(thisDepartment.ID, desired.DisplayName)
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		thisDepartment.Name = desired.DisplayName
+	}
+	for _, userID := range joined {
+		if err := scim.scimService.MoveUser(userID, thisDepartment.ID); err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+	for _, userID := range left {
+		if err := scim.scimService.MoveUser(userID, 0); err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+
+	resource, err := scim.scimService.GroupResource(thisDepartment)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if status == http.StatusCreated {
+		ctx.Header("Location", resource.Meta.Location)
+	}
+	ctx.Scim(status, resource)
+}
+
+/*
+Handle func for GET /scim/v2/Groups
+*/
+func (scim *ScimApi) GetGroups(ctx *utils.Context) {
+	groups, err := scim.scimService.GetGroups(scimEntityID(ctx), ctx.Query("filter"))
+	if err != nil {
+		scimListError(ctx, err)
+		return
+	}
+	start, end, ok := scimPage(ctx, len(groups))
+	if !ok {
+		return
+	}
+	scimList(ctx, start, len(groups), groups[start:end], end-start)
+}
+
+/*
+Handle func for GET /scim/v2/Groups/{group_id}
+*/
+func (scim *ScimApi) GetGroup(ctx *utils.Context) {
+	thisDepartment, ok := scim.getGroup(ctx)
+	if !ok {
+		return
+	}
+	resource, err := scim.scimService.GroupResource(thisDepartment)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	ctx.Scim(http.StatusOK, resource)
+}
+
+/*
+Handle func for POST /scim/v2/Groups
+*/
+func (scim *ScimApi) CreateGroup(ctx *utils.Context) {
+	desired, ok := scim.bindGroup(ctx)
+	if !ok {
+		return
+	}
+	entityID := scimEntityID(ctx)
+	exists, err := scim.departmentService.ExistsDepartmentSub(desired.DisplayName, entityID, 0)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	} else if exists {
+		ctx.ScimError(http.StatusConflict, define.SCIM_UNIQUENESS, myerror.DUPLICATED_NAME_INFO)
+		return
+	}
+	// check the members before the department exists, a rejected member leaves nothing behind
+	if _, _, ok := scim.memberChanges(ctx, define.ScimGroup{}, desired); !ok {
+		return
+	}
+
+	thisDepartment, err := scim.scimService.CreateGroup(entityID, desired.DisplayName)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scim.applyGroup(ctx, thisDepartment, define.ScimGroup{}, desired, http.StatusCreated)
+}
+
+/*
+Handle func for PUT /scim/v2/Groups/{group_id}
+*/
+func (scim *ScimApi) ReplaceGroup(ctx *utils.Context) {
+	thisDepartment, ok := scim.getGroup(ctx)
+	if !ok {
+		return
+	}
+	desired, ok := scim.bindGroup(ctx)
+	if !ok {
+		return
+	}
+	current, err := scim.scimService.GroupResource(thisDepartment)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scim.applyGroup(ctx, thisDepartment, current, desired, http.StatusOK)
+}
+
+/*
+Handle func for PATCH /scim/v2/Groups/{group_id}
+*/
+func (scim *ScimApi) PatchGroup(ctx *utils.Context) {
+	thisDepartment, ok := scim.getGroup(ctx)
+	if !ok {
+		return
+	}
+	var patchReq define.ScimPatchReq
+	if err := ctx.ShouldBindWith(&patchReq, binding.JSON); err != nil {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_SYNTAX_INVALID, myerror.INVALID_BODY_INFO)
+		return
+	}
+	current, err := scim.scimService.GroupResource(thisDepartment)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	desired, err := scim.scimService.PatchGroup(current, patchReq.Operations)
+	if err != nil {
+		scimPatchError(ctx, err)
+		return
+	}
+	scim.applyGroup(ctx, thisDepartment, current, desired, http.StatusOK)
+}
+
+/*
+Handle func for DELETE /scim/v2/Groups/{group_id}
+*/
+func (scim *ScimApi) DeleteGroup(ctx *utils.Context) {
+	thisDepartment, ok := scim.getGroup(ctx)
+	if !ok {
+		return
+	}
+	hasUsers, err := scim.departmentService.DepartmentHasUsers(thisDepartment.ID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	} else if hasUsers {
+		ctx.ScimError(http.StatusBadRequest, define.SCIM_MUTABILITY, myerror.DEPARTMENT_HAS_USERS_INFO)
+		return
+	}
+
+	err = scim.departmentService.DeleteDepartment(thisDepartment.ID)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/middleware"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func InitForScim(r *gin.Engine) {
+	group := r.Group(define.SCIM_BASE_PATH)
+	group.Use(utils.Handler(middleware.ScimMiddleware(tokenChecker)))
+	group.GET("/ServiceProviderConfig", utils.Handler(apis.Scim.GetServiceProviderConfig))
+	group.GET("/Users", utils.Handler(apis.Scim.GetUsers))
+	group.POST("/Users", utils.Handler(apis.Scim.CreateUser))
+	group.GET("/Users/:user_id", utils.Handler(apis.Scim.GetUser))
+	group.PUT("/Users/:user_id", utils.Handler(apis.Scim.ReplaceUser))
+	group.PATCH("/Users/:user_id", utils.Handler(apis.Scim.PatchUser))
+	group.DELETE("/Users/:user_id", utils.Handler(apis.Scim.DeleteUser))
+	group.GET("/Groups", utils.Handler(apis.Scim.GetGroups))
+	group.POST("/Groups", utils.Handler(apis.Scim.CreateGroup))
+	group.GET("/Groups/:group_id", utils.Handler(apis.Scim.GetGroup))
+	group.PUT("/Groups/:group_id", utils.Handler(apis.Scim.ReplaceGroup))
+	group.PATCH("/Groups/:group_id", utils.Handler(apis.Scim.PatchGroup))
+	group.DELETE("/Groups/:group_id", utils.Handler(apis.Scim.DeleteGroup))
+}
+
+func TestScim(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("scim_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("scim_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reader = GetJsonBody(body)
+		}
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/scim+json",
+			"Authorization": token,
+		}, reader)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: "scim_manager", Password: password})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data := map[string]interface{}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	jwtToken := data["data"].(map[string]interface{})["token"].(string)
+	newKey := func(scope string) string {
+		res := call(http.MethodPost, fmt.Sprintf("/entity/%d/api-keys", entityID), jwtToken, define.CreateApiTokenReq{Name: "scim_" + scope, Scope: scope, ExpiresInDays: 30})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		var tokenRes struct {
+			Data define.CreateApiTokenResponse `json:"data"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &tokenRes)
+		return "Bearer " + tokenRes.Data.Token
+	}
+	writeKey, readKey := newKey(define.API_SCOPE_WRITE), newKey(define.API_SCOPE_READ)
+
+	// only an API token in the bearer scheme is accepted, a login token is not
+	res = call(http.MethodGet, "/scim/v2/Users", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	assert.Equal(t, utils.SCIM_CONTENT_TYPE, res.Header().Get("Content-Type"), "response failed")
+	res = call(http.MethodGet, "/scim/v2/Users", "Bearer "+jwtToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/scim/v2/ServiceProviderConfig", readKey, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, "/scim/v2/Users", readKey, define.ScimUser{UserName: "scim_read"})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	var user define.ScimUser
+	res = call(http.MethodPost, "/scim/v2/Users", writeKey, define.ScimUser{UserName: "scim_alice"})
+	assert.Equal(t, http.StatusCreated, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &user)
+	assert.Equal(t, true, *user.Active, "response failed")
+	assert.Equal(t, "/scim/v2/Users/"+user.ID, res.Header().Get("Location"), "response failed")
+	res = call(http.MethodPost, "/scim/v2/Users", writeKey, define.ScimUser{UserName: "scim_alice"})
+	assert.Equal(t, http.StatusConflict, res.Result().StatusCode, "response failed")
+
+	// a provisioned password follows the entity's policy and is changed at first login
+	res = call(http.MethodPost, "/scim/v2/Users", writeKey, define.ScimUser{UserName: "scim_bob", Password: "abc"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Contains(t, res.Body.String(), define.SCIM_VALUE_INVALID, "response failed")
+	res = call(http.MethodPost, "/scim/v2/Users", writeKey, define.ScimUser{UserName: "scim_bob", Password: "provisioned"})
+	assert.Equal(t, http.StatusCreated, res.Result().StatusCode, "response failed")
+	bob, err := userDao.GetUserByName("scim_bob")
+	assert.Equal(t, nil, err, "dao error")
+	assert.Equal(t, true, bob.MustChangePassword, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("/scim/v2/Users/%d", bob.ID), writeKey, nil)
+	assert.Equal(t, http.StatusNoContent, res.Result().StatusCode, "response failed")
+
+	var list struct {
+		TotalResults int               `json:"totalResults"`
+		Resources    []json.RawMessage `json:"Resources"`
+	}
+	res = call(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq "SCIM_ALICE"`), writeKey, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &list)
+	assert.Equal(t, 1, list.TotalResults, "response failed")
+	res = call(http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", writeKey, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &list)
+	assert.Equal(t, 2, list.TotalResults, "response failed")
+	assert.Equal(t, 1, len(list.Resources), "response failed")
+	res = call(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`emails co "x"`), writeKey, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	assert.Contains(t, res.Body.String(), define.SCIM_FILTER_INVALID, "response failed")
+
+	// groups are the entity's departments, joining one moves the user into it
+	var group define.ScimGroup
+	res = call(http.MethodPost, "/scim/v2/Groups", writeKey, define.ScimGroup{
+		DisplayName: "scim_dev",
+		Members:     []define.ScimMember{{Value: user.ID}},
+	})
+	assert.Equal(t, http.StatusCreated, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &group)
+	assert.Equal(t, 1, len(group.Members), "response failed")
+	res = call(http.MethodGet, "/scim/v2/Users/"+user.ID, writeKey, nil)
+	json.Unmarshal(res.Body.Bytes(), &user)
+	assert.Equal(t, group.ID, user.Groups[0].Value, "response failed")
+
+	var other define.ScimGroup
+	res = call(http.MethodPost, "/scim/v2/Groups", writeKey, define.ScimGroup{DisplayName: "scim_ops"})
+	assert.Equal(t, http.StatusCreated, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &other)
+	res = call(http.MethodPatch, "/scim/v2/Groups/"+other.ID, writeKey, json.RawMessage(
+		`{"schemas":["`+define.SCIM_PATCH_SCHEMA+`"],"Operations":[{"op":"add","path":"members","value":[{"value":"`+user.ID+`"}]}]}`,
+	))
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/scim/v2/Groups/"+group.ID, writeKey, nil)
+	json.Unmarshal(res.Body.Bytes(), &group)
+	assert.Equal(t, 0, len(group.Members), "response failed")
+	res = call(http.MethodPatch, "/scim/v2/Groups/"+other.ID, writeKey, json.RawMessage(
+		`{"Operations":[{"op":"remove","path":"members[value eq \"`+user.ID+`\"]"},{"op":"replace","path":"displayName","value":"scim_dev"}]}`,
+	))
+	assert.Equal(t, http.StatusConflict, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPatch, "/scim/v2/Groups/"+other.ID, writeKey, json.RawMessage(
+		`{"Operations":[{"op":"remove","path":"members[value eq \"`+user.ID+`\"]"}]}`,
+	))
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	thisUser, err := userDao.GetUserByName("scim_alice")
+	assert.Equal(t, nil, err, "dao error")
+	assert.Equal(t, uint(0), thisUser.DepartmentID, "response failed")
+
+	// deactivation bans the user
+	res = call(http.MethodPatch, "/scim/v2/Users/"+user.ID, writeKey, json.RawMessage(
+		`{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`,
+	))
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &user)
+	assert.Equal(t, false, *user.Active, "response failed")
+	thisUser, err = userDao.GetUserByName("scim_alice")
+	assert.Equal(t, nil, err, "dao error")
+	assert.Equal(t, true, thisUser.Ban, "response failed")
+
+	active := true
+	res = call(http.MethodPut, "/scim/v2/Users/"+user.ID, writeKey, define.ScimUser{UserName: "scim_alice2", Active: &active})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	json.Unmarshal(res.Body.Bytes(), &user)
+	assert.Equal(t, "scim_alice2", user.UserName, "response failed")
+	assert.Equal(t, true, *user.Active, "response failed")
+
+	// nothing outside the entity is visible
+	err = apis.Entity.entityService.CreateEntity("scim_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err = apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateManager("scim_other_manager", password, entityList[len(entityList)-1].ID)
+	assert.Equal(t, nil, err, "service error")
+	otherUser, err := userDao.GetUserByName("scim_other_manager")
+	assert.Equal(t, nil, err, "dao error")
+	res = call(http.MethodGet, fmt.Sprintf("/scim/v2/Users/%d", otherUser.ID), writeKey, nil)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+	assert.Equal(t, true, strings.Contains(res.Body.String(), "urn:ietf:params:scim:api:messages:2.0:Error"), "response failed")
+
+	res = call(http.MethodDelete, "/scim/v2/Groups/"+group.ID, writeKey, nil)
+	assert.Equal(t, http.StatusNoContent, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, "/scim/v2/Users/"+user.ID, writeKey, nil)
+	assert.Equal(t, http.StatusNoContent, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/scim/v2/Users/"+user.ID, writeKey, nil)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"strconv"
+
+	"github.com/jinzhu/copier"
+)
+
+type SessionApi struct {
+	entityService service.EntityServiceInterface
+	tokenService  service.TokenServiceInterface
+	userService   service.UserServiceInterface
+	userApi       *UserApi
+	permissionApi *PermissionApi
+}
+
+func NewSessionApi(
+	entityService service.EntityServiceInterface,
+	tokenService service.TokenServiceInterface,
+	userService service.UserServiceInterface,
+	userApi *UserApi,
+	permissionApi *PermissionApi,
+) *SessionApi {
+	return &SessionApi{
+		entityService: entityService,
+		tokenService:  tokenService,
+		userService:   userService,
+		userApi:       userApi,
+		permissionApi: permissionApi,
+	}
+}
+
+/*
+The client a login request comes from, a client may name its device itself.
+Headers are cut to the size of the session columns
+*/
+func sessionClient(ctx *utils.Context) define.SessionClient {
+	userAgent := ctx.GetHeader("User-Agent")
+	device := ctx.GetHeader("X-Device-Name")
+	if device == "" {
+		device = utils.DeviceName(userAgent)
+	}
+	return define.SessionClient{
+		Device:    utils.Truncate(device, model.SESSION_DEVICE_SIZE),
+		IP:        utils.Truncate(ctx.ClientIP(), model.SESSION_IP_SIZE),
+		UserAgent: utils.Truncate(userAgent, model.SESSION_USER_AGENT_SIZE),
+	}
+}
+
+func (session *SessionApi) sessionList(ctx *utils.Context, userID uint) {
+	sessionList, err := session.tokenService.GetUserSessions(userID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	sessionListRes := []define.SessionInfo{}
+	err = copier.Copy(&sessionListRes, sessionList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	current := ctx.GetString("token_id")
+	for i := range sessionListRes {
+		sessionListRes[i].Current = strconv.FormatUint(uint64(sessionListRes[i].ID), 10) == current
+	}
+	ctx.Success(define.SessionListResponse{
+		SessionList: sessionListRes,
+	})
+}
+
+/*
+The user named by the path, if the operator may manage their sessions:
+super admin, or entity super of the user's entity
+*/
+func (session *SessionApi) managedUser(ctx *utils.Context) *model.User {
+	userID, err := session.entityService.GetParamID(ctx, "user_id")
+	if err != nil {
+		return nil
+	}
+	thisUser, err := session.userService.GetUserByID(userID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil
+	} else if thisUser == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return nil
+	}
+	if !session.userApi.CheckIdentity(ctx, thisUser.EntityID) {
+		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+		return nil
+	}
+	return thisUser
+}
+
+/*
+Handle func for GET /user/sessions
+*/
+func (session *SessionApi) GetMySessions(ctx *utils.Context) {
+	session.sessionList(ctx, GetOperatorID(ctx))
+}
+
+/*
+Handle func for DELETE /user/sessions, logs out everywhere including the current session
+*/
+func (session *SessionApi) RevokeMySessions(ctx *utils.Context) {
+	err := session.tokenService.RevokeUserTokens(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for DELETE /user/sessions/:session_id
+*/
+func (session *SessionApi) RevokeMySession(ctx *utils.Context) {
+	sessionID, err := session.entityService.GetParamID(ctx, "session_id")
+	if err != nil {
+		return
+	}
+	thisSession, err := session.tokenService.GetSession(sessionID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisSession == nil || thisSession.Revoked || thisSession.UserID != GetOperatorID(ctx) {
+		ctx.NotFound(myerror.SESSION_NOT_FOUND, myerror.SESSION_NOT_FOUND_INFO)
+		return
+	}
+
+	err = session.tokenService.RevokeSession(sessionID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for GET /user/info/:user_id/sessions
+*/
+func (session *SessionApi) GetUserSessions(ctx *utils.Context) {
+	thisUser := session.managedUser(ctx)
+	if thisUser == nil {
+		return
+	}
+	session.sessionList(ctx, thisUser.ID)
+}
+
+/*
+Handle func for DELETE /user/info/:user_id/sessions, forces the user to log in again on every device
+*/
+func (session *SessionApi) ForceLogout(ctx *utils.Context) {
+	thisUser := session.managedUser(ctx)
+	if thisUser == nil {
+		return
+	}
+	err := session.tokenService.RevokeUserTokens(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Credentials stay with their owner, an admin acting as a user cannot change them
+*/
+func checkNotImpersonating(ctx *utils.Context) bool {
+	if operator := GetOperatorInfo(ctx); operator != nil && operator.Impersonator != nil {
+		ctx.Forbidden(myerror.IMPERSONATION_NOT_ALLOWED, myerror.IMPERSONATION_NOT_ALLOWED_INFO)
+		return false
+	}
+	return true
+}
+
+/*
+Handle func for POST /user/info/{user_id}/impersonate, for those who manage the
+users of the user's entity. Only super admins can act as a super admin
+*/
+func (session *SessionApi) Impersonate(ctx *utils.Context) {
+	if !checkNotApiToken(ctx) || !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser := session.managedUser(ctx)
+	if thisUser == nil {
+		return
+	}
+	admin, err := session.userService.GetUserByID(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if admin == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return
+	}
+	if admin.ID == thisUser.ID {
+		ctx.BadRequest(myerror.IMPERSONATE_SELF, myerror.IMPERSONATE_SELF_INFO)
+		return
+	}
+	superTarget, err := session.permissionApi.UserAllowed(thisUser, define.PERM_SYSTEM_MANAGE, 0, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if superTarget && !session.permissionApi.Check(ctx, define.PERM_SYSTEM_MANAGE, 0, 0) {
+		return
+	}
+	if thisUser.Ban {
+		ctx.BadRequest(myerror.IMPERSONATE_BANNED, myerror.IMPERSONATE_BANNED_INFO)
+		return
+	}
+
+	token, expiresAt, err := session.tokenService.IssueImpersonationToken(admin, thisUser, sessionClient(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var userInfo define.UserInfo
+	err = copier.Copy(&userInfo, thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      userInfo,
+	})
+}
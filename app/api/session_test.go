@@ -0,0 +1,233 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("session_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("session_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("session_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateManager("session_outsider", password, otherID)
+	assert.Equal(t, nil, err, "service error")
+	err = userDao.Create(model.User{
+		UserName: "session_user",
+		Password: utils.CreateMD5(password),
+		EntityID: entityID,
+	})
+	assert.Equal(t, nil, err, "service error")
+	sessionUser, err := userDao.GetUserByName("session_user")
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(username string, userAgent string) string {
+		req := GetRequest(http.MethodPost, "/user/login", map[string]string{
+			"Content-Type": "application/json",
+			"User-Agent":   userAgent,
+		}, GetJsonBody(define.UserLoginReq{UserName: username, Password: password}))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data["data"].(map[string]interface{})["token"].(string)
+	}
+	sessions := func(res *httptest.ResponseRecorder) []define.SessionInfo {
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := struct {
+			Data define.SessionListResponse `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data.Data.SessionList
+	}
+
+	laptopToken := login("session_user", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+	phoneToken := login("session_user", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	managerToken := login("session_manager", "")
+	outsiderToken := login("session_outsider", "")
+
+	list := sessions(call(http.MethodGet, "/user/sessions", laptopToken))
+	assert.Equal(t, 2, len(list), "response failed")
+	assert.Equal(t, "iPhone", list[0].Device, "response failed")
+	assert.Equal(t, false, list[0].Current, "response failed")
+	assert.Equal(t, "Windows", list[1].Device, "response failed")
+	assert.Equal(t, true, list[1].Current, "response failed")
+
+	// the lost phone is logged out from the laptop
+	res = call(http.MethodDelete, fmt.Sprintf("/user/sessions/%d", list[0].ID), managerToken)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("/user/sessions/%d", list[0].ID), laptopToken)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/sessions", phoneToken)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, fmt.Sprintf("/user/sessions/%d", list[0].ID), laptopToken)
+	assert.Equal(t, http.StatusNotFound, res.Result().StatusCode, "response failed")
+
+	userSessionsURL := fmt.Sprintf("/user/info/%d/sessions", sessionUser.ID)
+	res = call(http.MethodGet, userSessionsURL, outsiderToken)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodDelete, userSessionsURL, laptopToken)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	list = sessions(call(http.MethodGet, userSessionsURL, managerToken))
+	assert.Equal(t, 1, len(list), "response failed")
+	assert.Equal(t, false, list[0].Current, "response failed")
+
+	// the entity super forces the user out everywhere
+	res = call(http.MethodDelete, userSessionsURL, managerToken)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/sessions", laptopToken)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+
+	// logging out everywhere includes the current session
+	login("session_user", "")
+	otherToken := login("session_user", "")
+	res = call(http.MethodDelete, "/user/sessions", otherToken)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	res = call(http.MethodGet, "/user/sessions", otherToken)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	assert.Equal(t, 0, len(sessions(call(http.MethodGet, userSessionsURL, managerToken))), "response failed")
+
+	// oversized client headers are cut to the columns instead of failing the login
+	req := GetRequest(http.MethodPost, "/user/login", map[string]string{
+		"Content-Type":  "application/json",
+		"User-Agent":    strings.Repeat("a", 300),
+		"X-Device-Name": strings.Repeat("设", 100),
+	}, GetJsonBody(define.UserLoginReq{UserName: "session_user", Password: password}))
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	list = sessions(call(http.MethodGet, userSessionsURL, managerToken))
+	assert.Equal(t, 1, len(list), "response failed")
+	assert.Equal(t, strings.Repeat("设", model.SESSION_DEVICE_SIZE), list[0].Device, "response failed")
+	assert.Equal(t, strings.Repeat("a", model.SESSION_USER_AGENT_SIZE), list[0].UserAgent, "response failed")
+}
+
+func TestImpersonation(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("impersonation_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateEntity("impersonation_other")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID, otherID := entityList[len(entityList)-2].ID, entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("impersonation_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+	err = apis.Entity.entityService.CreateManager("impersonation_outsider", password, otherID)
+	assert.Equal(t, nil, err, "service error")
+	for _, username := range []string{"impersonation_user", "impersonation_banned"} {
+		err = userDao.Create(model.User{
+			UserName: username,
+			Password: utils.CreateMD5(password),
+			EntityID: entityID,
+		})
+		assert.Equal(t, nil, err, "service error")
+	}
+	thisUser, err := userDao.GetUserByName("impersonation_user")
+	assert.Equal(t, nil, err, "service error")
+	banned, err := userDao.GetUserByName("impersonation_banned")
+	assert.Equal(t, nil, err, "service error")
+	err = userDao.Update(banned.ID, map[string]interface{}{"ban": true})
+	assert.Equal(t, nil, err, "service error")
+	manager, err := userDao.GetUserByName("impersonation_manager")
+	assert.Equal(t, nil, err, "service error")
+	outsider, err := userDao.GetUserByName("impersonation_outsider")
+	assert.Equal(t, nil, err, "service error")
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(username string) string {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: username, Password: password})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		data := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		return data["data"].(map[string]interface{})["token"].(string)
+	}
+	impersonateURL := func(userID uint) string {
+		return fmt.Sprintf("/user/info/%d/impersonate", userID)
+	}
+	managerToken := login("impersonation_manager")
+	outsiderToken := login("impersonation_outsider")
+	userToken := login("impersonation_user")
+
+	// only supers with scope over the user's entity may act as them
+	res = call(http.MethodPost, impersonateURL(thisUser.ID), outsiderToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, impersonateURL(manager.ID), userToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, impersonateURL(outsider.ID), managerToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, impersonateURL(manager.ID), managerToken, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, impersonateURL(banned.ID), managerToken, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	res = call(http.MethodPost, impersonateURL(thisUser.ID), managerToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	data := struct {
+		Data define.ImpersonationResponse `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &data)
+	assert.Equal(t, thisUser.ID, data.Data.User.UserID, "response failed")
+	assert.Equal(t, true, data.Data.ExpiresAt.Before(time.Now().Add(utils.ImpersonationExpiredDuration+time.Minute)), "response failed")
+	impersonationToken := data.Data.Token
+
+	// the admin acts as the user but cannot touch their credentials or go further
+	res = call(http.MethodGet, "/user/sessions", impersonationToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.Contains(t, res.Body.String(), "Impersonated by impersonation_manager", "response failed")
+	res = call(http.MethodPost, fmt.Sprintf("/user/info/%d/password", thisUser.ID), impersonationToken, define.ChangePasswordReq{Password: "123456"})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, "/user/tokens", impersonationToken, define.CreateApiTokenReq{Name: "impersonated", Scope: define.API_SCOPE_READ, ExpiresInDays: 30})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+	res = call(http.MethodPost, impersonateURL(banned.ID), impersonationToken, nil)
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+
+	// the token dies with the admin's identity
+	err = userDao.Update(manager.ID, map[string]interface{}{"security_version": manager.SecurityVersion + 1})
+	assert.Equal(t, nil, err, "service error")
+	res = call(http.MethodGet, "/user/sessions", impersonationToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+}
@@ -9,24 +9,29 @@ import (
 	"github.com/thoas/go-funk"
 )
 
-type statApi struct {
+type StatApi struct {
+	departmentService service.DepartmentServiceInterface
+	statService       service.StatServiceInterface
+	assetClassApi     *AssetClassApi
 }
 
-var StatApi *statApi
-
-func newStatApi() *statApi {
-	return &statApi{}
-}
-
-func init() {
-	StatApi = newStatApi()
+func NewStatApi(
+	departmentService service.DepartmentServiceInterface,
+	statService service.StatServiceInterface,
+	assetClassApi *AssetClassApi,
+) *StatApi {
+	return &StatApi{
+		departmentService: departmentService,
+		statService:       statService,
+		assetClassApi:     assetClassApi,
+	}
 }
 
 /*
 Handle func for GET /department/:department_id/asset/stat/total
 */
-func (stat *statApi) GetDepartmentStatTotal(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (stat *StatApi) GetDepartmentStatTotal(ctx *utils.Context) {
+	hasIdentity, departmentID, err := stat.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -34,7 +39,7 @@ func (stat *statApi) GetDepartmentStatTotal(ctx *utils.Context) {
 		return
 	}
 
-	stats, err := service.StatService.GetDepartmentStat(departmentID)
+	stats, err := stat.statService.GetDepartmentStat(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -50,8 +55,8 @@ func (stat *statApi) GetDepartmentStatTotal(ctx *utils.Context) {
 /*
 Handle func for GET /department/:department_id/asset/stat/distribution
 */
-func (stat *statApi) GetDepartmentStatDistribution(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (stat *StatApi) GetDepartmentStatDistribution(ctx *utils.Context) {
+	hasIdentity, departmentID, err := stat.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -59,7 +64,7 @@ func (stat *statApi) GetDepartmentStatDistribution(ctx *utils.Context) {
 		return
 	}
 
-	distribution, err := service.StatService.GetDepartmentAssetDistribution(departmentID)
+	distribution, err := stat.statService.GetDepartmentAssetDistribution(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -75,8 +80,8 @@ func (stat *statApi) GetDepartmentStatDistribution(ctx *utils.Context) {
 /*
 Handle func for GET /department/:department_id/asset/stat/sub
 */
-func (stat *statApi) GetSubDepartmentsAssetDistribution(ctx *utils.Context) {
-	hasIdentity, departmentID, err := AssetClassApi.CheckAssetIdentity(ctx)
+func (stat *StatApi) GetSubDepartmentsAssetDistribution(ctx *utils.Context) {
+	hasIdentity, departmentID, err := stat.assetClassApi.CheckAssetIdentity(ctx)
 	if err != nil {
 		return
 	} else if !hasIdentity {
@@ -84,7 +89,7 @@ func (stat *statApi) GetSubDepartmentsAssetDistribution(ctx *utils.Context) {
 		return
 	}
 
-	subIDs, err := service.DepartmentService.GetSubDepartmentIDs(departmentID)
+	subIDs, err := stat.departmentService.GetSubDepartmentIDs(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -95,7 +100,7 @@ func (stat *statApi) GetSubDepartmentsAssetDistribution(ctx *utils.Context) {
 			DepartmentID: id,
 		}
 	}).([]*define.DepartmentAssetDistribution)
-	err = service.StatService.GetAssetDepartmentDistribution(subIDs, subStats)
+	err = stat.statService.GetAssetDepartmentDistribution(subIDs, subStats)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
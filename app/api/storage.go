@@ -0,0 +1,92 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/storage"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+type StorageApi struct {
+	permissionApi *PermissionApi
+}
+
+func NewStorageApi(permissionApi *PermissionApi) *StorageApi {
+	return &StorageApi{
+		permissionApi: permissionApi,
+	}
+}
+
+const maxImportFileSize = 10 << 20
+
+/*
+Handle func for GET /storage/:bucket/*key
+*/
+func (st *StorageApi) Download(ctx *utils.Context) {
+	bucketName := ctx.Param("bucket")
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+	if !storage.VerifyLink(bucketName, key, ctx.Query("expires"), ctx.Query("signature")) {
+		ctx.Forbidden(myerror.DOWNLOAD_LINK_INVALID, myerror.DOWNLOAD_LINK_INVALID_INFO)
+		return
+	}
+
+	bucket := storage.GetBucket(bucketName)
+	if bucket == nil {
+		ctx.NotFound(myerror.OBJECT_NOT_FOUND, myerror.OBJECT_NOT_FOUND_INFO)
+		return
+	}
+
+	reader, err := bucket.Get(key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		ctx.NotFound(myerror.OBJECT_NOT_FOUND, myerror.OBJECT_NOT_FOUND_INFO)
+		return
+	} else if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	defer reader.Close()
+
+	ctx.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", path.Base(key)),
+	})
+}
+
+/*
+Handle func for POST /storage/import
+*/
+func (st *StorageApi) UploadImportFile(ctx *utils.Context) {
+	// the department is only known once the import task is created, which checks it again
+	thisUser := GetOperatorInfo(ctx)
+	if !st.permissionApi.CheckInEntity(ctx, define.PERM_ASSET_IMPORT, thisUser.EntityID) {
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil || fileHeader.Size > maxImportFileSize {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	defer file.Close()
+
+	objectKey := fmt.Sprintf("imports/%d/%s_%s", thisUser.UserID, time.Now().Format("2006-01-02_15-04-05"), path.Base(fileHeader.Filename))
+	err = storage.ImportBucket.Put(objectKey, file)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+
+	ctx.Success(define.UploadImportFileResponse{
+		ObjectKey: objectKey,
+	})
+}
@@ -12,20 +12,37 @@ import (
 	"github.com/thoas/go-funk"
 )
 
-type taskApi struct {
+type TaskApi struct {
+	assetService      service.AssetServiceInterface
+	departmentService service.DepartmentServiceInterface
+	entityService     service.EntityServiceInterface
+	feishuService     service.FeishuServiceInterface
+	taskService       service.TaskServiceInterface
+	userService       service.UserServiceInterface
+	permissionApi     *PermissionApi
 }
 
-var TaskApi *taskApi
-
-func newTaskApi() *taskApi {
-	return &taskApi{}
-}
-
-func init() {
-	TaskApi = newTaskApi()
+func NewTaskApi(
+	assetService service.AssetServiceInterface,
+	departmentService service.DepartmentServiceInterface,
+	entityService service.EntityServiceInterface,
+	feishuService service.FeishuServiceInterface,
+	taskService service.TaskServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *TaskApi {
+	return &TaskApi{
+		assetService:      assetService,
+		departmentService: departmentService,
+		entityService:     entityService,
+		feishuService:     feishuService,
+		taskService:       taskService,
+		userService:       userService,
+		permissionApi:     permissionApi,
+	}
 }
 
-func (task *taskApi) getTaskInfoRes(taskList []*model.Task) define.TaskListResponse {
+func (task *TaskApi) getTaskInfoRes(taskList []*model.Task) define.TaskListResponse {
 	taskInfoList := funk.Map(taskList, func(thisTask *model.Task) define.TaskBasicInfo {
 		taskInfo := define.TaskBasicInfo{
 			ID:              thisTask.ID,
@@ -45,22 +62,22 @@ func (task *taskApi) getTaskInfoRes(taskList []*model.Task) define.TaskListRespo
 	return taskListRes
 }
 
-func (task *taskApi) userTaskPrevilige(ctx *utils.Context) (*model.Task, bool) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (task *TaskApi) userTaskPrevilige(ctx *utils.Context) (*model.Task, bool) {
+	userID, err := task.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return nil, false
 	}
-	taskID, err := service.EntityService.GetParamID(ctx, "task_id")
+	taskID, err := task.entityService.GetParamID(ctx, "task_id")
 	if err != nil {
 		return nil, false
 	}
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return nil, false
 	}
 
-	taskInfo, err := service.TaskService.GetTaskInfoByID(taskID)
+	taskInfo, err := task.taskService.GetTaskInfoByID(taskID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return nil, false
@@ -77,22 +94,21 @@ func (task *taskApi) userTaskPrevilige(ctx *utils.Context) (*model.Task, bool) {
 	return taskInfo, true
 }
 
-func (task *taskApi) departmentTaskPrevillige(ctx *utils.Context) (*model.Task, *define.UserBasicInfo, bool) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (task *TaskApi) departmentTaskPrevillige(ctx *utils.Context) (*model.Task, *define.UserBasicInfo, bool) {
+	departmentID, err := task.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return nil, nil, false
 	}
-	taskID, err := service.EntityService.GetParamID(ctx, "task_id")
+	taskID, err := task.entityService.GetParamID(ctx, "task_id")
 	if err != nil {
 		return nil, nil, false
 	}
-	thisUser := UserApi.GetOperatorInfo(ctx)
-	if !thisUser.DepartmentSuper || thisUser.DepartmentID != departmentID {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	thisUser := GetOperatorInfo(ctx)
+	if !task.permissionApi.Check(ctx, define.PERM_ASSET_APPROVE, 0, departmentID) {
 		return nil, nil, false
 	}
 
-	taskInfo, err := service.TaskService.GetTaskInfoByID(taskID)
+	taskInfo, err := task.taskService.GetTaskInfoByID(taskID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return nil, nil, false
@@ -112,19 +128,19 @@ func (task *taskApi) departmentTaskPrevillige(ctx *utils.Context) (*model.Task,
 /*
 Handle func for /users/:user_id/assets/task
 */
-func (task *taskApi) CreateNewTask(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (task *TaskApi) CreateNewTask(ctx *utils.Context) {
+	userID, err := task.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	existUser, err := service.UserService.ExistsUserByID(userID)
+	existUser, err := task.userService.ExistsUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -155,7 +171,7 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 	assetIdList = funk.UniqUInt(assetIdList)
 
 	if req.TaskType == 0 {
-		assetList, err = service.AssetService.GetDepartmentAssetsByIDs(assetIdList, thisUser.DepartmentID)
+		assetList, err = task.assetService.GetDepartmentAssetsByIDs(assetIdList, thisUser.DepartmentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -166,7 +182,7 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 		}
 		req.TargetID = 0
 	} else if req.TaskType == 1 {
-		assetList, err = service.AssetService.GetUserAssetsByIDs(assetIdList, thisUser.UserID)
+		assetList, err = task.assetService.GetUserAssetsByIDs(assetIdList, thisUser.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -181,7 +197,7 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 			ctx.BadRequest(myerror.TARGET_EMPTY, myerror.TARGET_EMPTY_INFO)
 			return
 		}
-		targetUser, err := service.UserService.GetUserByID(userID)
+		targetUser, err := task.userService.GetUserByID(userID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -199,7 +215,7 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 			return
 		}
 
-		assetList, err = service.AssetService.GetUserAssetsByIDs(assetIdList, thisUser.UserID)
+		assetList, err = task.assetService.GetUserAssetsByIDs(assetIdList, thisUser.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -210,19 +226,29 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 		}
 	}
 
-	task_id, err := service.TaskService.CreateTask(req, thisUser.UserID, thisUser.DepartmentID, assetList)
+	err = service.CheckAssetEvent(assetList, service.TaskAssetEvent(req.TaskType))
 	if err != nil {
-		ctx.InternalError(err.Error())
+		assetStateError(ctx, err)
+		return
+	}
+
+	task_id, err := task.taskService.CreateTask(req, thisUser.UserID, thisUser.DepartmentID, assetList)
+	if err != nil {
+		assetStateError(ctx, err)
 		return
 	}
 
 	go func() {
 		//向飞书发信息
-		user, err := service.UserService.GetUserByID(thisUser.UserID)
+		user, err := task.userService.GetUserByID(thisUser.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
+		// the user may be gone by the time the notice is sent
+		if user == nil {
+			return
+		}
 		TaskTypeMap := map[uint]string{
 			0: "领用",
 			1: "退库",
@@ -231,14 +257,14 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 		}
 		if len(user.FeishuID) != 0 {
 			text := fmt.Sprintf("您发送的描述为“%s”的%s请求已发送成功，等待管理员审批", req.TaskDescription, TaskTypeMap[req.TaskType])
-			err = service.FeishuService.SendMessage(user.ID, text)
+			err = task.feishuService.SendMessage(user.ID, text)
 			if err != nil {
 				// log.Println("1")
 				ctx.InternalError(err.Error())
 				return
 			}
 		}
-		managers, err := service.DepartmentService.GetDepartmentManagerList(user.DepartmentID)
+		managers, err := task.departmentService.GetDepartmentManagerList(user.DepartmentID)
 		if err != nil {
 			// log.Println("2")
 			ctx.InternalError(err.Error())
@@ -253,13 +279,13 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 			TargetID:        req.TargetID,
 			AssetList:       assetList,
 		}
-		approval_code, err := service.FeishuService.CreateApprovalDefination()
+		approval_code, err := task.feishuService.CreateApprovalDefination()
 		if err != nil {
 			// log.Println("3")
 			ctx.InternalError(err.Error())
 			return
 		}
-		err = service.FeishuService.PutApproval(this_task, user.FeishuID, approval_code)
+		err = task.feishuService.PutApproval(this_task, user.FeishuID, approval_code)
 		if err != nil {
 			// log.Println("4")
 			ctx.InternalError(err.Error())
@@ -268,7 +294,7 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 		for _, manager := range managers {
 			if len(manager.FeishuID) != 0 {
 				text := fmt.Sprintf("%s发送了一条描述为“%s”的%s申请，请注意审批", user.UserName, req.TaskDescription, TaskTypeMap[req.TaskType])
-				err = service.FeishuService.SendMessage(manager.ID, text)
+				err = task.feishuService.SendMessage(manager.ID, text)
 				if err != nil {
 					// log.Println("5")
 					ctx.InternalError(err.Error())
@@ -284,19 +310,19 @@ func (task *taskApi) CreateNewTask(ctx *utils.Context) {
 /*
 Handle func for GET /user/:user_id/assets/tasks
 */
-func (task *taskApi) GetUserTaskList(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (task *TaskApi) GetUserTaskList(ctx *utils.Context) {
+	userID, err := task.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser := UserApi.GetOperatorInfo(ctx)
+	thisUser := GetOperatorInfo(ctx)
 	if thisUser.UserID != userID {
 		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 		return
 	}
 
-	existUser, err := service.UserService.ExistsUserByID(userID)
+	existUser, err := task.userService.ExistsUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -305,7 +331,7 @@ func (task *taskApi) GetUserTaskList(ctx *utils.Context) {
 		return
 	}
 
-	taskList, err := service.TaskService.GetTasksByUserID(userID)
+	taskList, err := task.taskService.GetTasksByUserID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -335,20 +361,17 @@ func (task *taskApi) GetUserTaskList(ctx *utils.Context) {
 /*
 Handle func for GET /departments/:department_id/assets/tasks
 */
-func (task *taskApi) GetDepartmentTaskList(ctx *utils.Context) {
-	departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (task *TaskApi) GetDepartmentTaskList(ctx *utils.Context) {
+	departmentID, err := task.entityService.GetParamID(ctx, "department_id")
 	if err != nil {
 		return
 	}
 
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	thisUser := UserApi.GetOperatorInfo(ctx)
-	if !departmentSuper || thisUser.DepartmentID != departmentID {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !task.permissionApi.Check(ctx, define.PERM_ASSET_APPROVE, 0, departmentID) {
 		return
 	}
 
-	existDepartment, err := service.DepartmentService.ExistsDepartmentByID(departmentID)
+	existDepartment, err := task.departmentService.ExistsDepartmentByID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -357,7 +380,7 @@ func (task *taskApi) GetDepartmentTaskList(ctx *utils.Context) {
 		return
 	}
 
-	taskList, err := service.TaskService.GetTasksByDepartmentID(departmentID)
+	taskList, err := task.taskService.GetTasksByDepartmentID(departmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -371,22 +394,22 @@ func (task *taskApi) GetDepartmentTaskList(ctx *utils.Context) {
 /*
 Handle func for GET /departments/:department_id/assets/tasks/:task_id
 */
-func (task *taskApi) GetDepartmentTaskInfo(ctx *utils.Context) {
-	// departmentID, err := service.EntityService.GetParamID(ctx, "department_id")
+func (task *TaskApi) GetDepartmentTaskInfo(ctx *utils.Context) {
+	// departmentID, err := task.entityService.GetParamID(ctx, "department_id")
 	// if err != nil {
 	// 	return
 	// }
-	// task_id, err := service.EntityService.GetParamID(ctx, "task_id")
+	// task_id, err := task.entityService.GetParamID(ctx, "task_id")
 	// if err != nil {
 	// 	return
 	// }
-	// thisUser := UserApi.GetOperatorInfo(ctx)
+	// thisUser := GetOperatorInfo(ctx)
 	// if !thisUser.DepartmentSuper || thisUser.DepartmentID != departmentID {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
 
-	// taskInfo, err := service.TaskService.GetTaskInfoByID(task_id)
+	// taskInfo, err := task.taskService.GetTaskInfoByID(task_id)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -416,6 +439,7 @@ func (task *taskApi) GetDepartmentTaskInfo(ctx *utils.Context) {
 		DepartmentID:    taskInfo.DepartmentID,
 		DepartmentName:  taskInfo.Department.Name,
 		AssetList:       taskInfo.AssetList,
+		Quantities:      taskInfo.Quantities,
 		State:           taskInfo.State,
 	}
 
@@ -425,22 +449,22 @@ func (task *taskApi) GetDepartmentTaskInfo(ctx *utils.Context) {
 /*
 Handle func for GET /users/:user_id/assets/tasks/:task_id
 */
-func (task *taskApi) GetUserTaskInfo(ctx *utils.Context) {
-	// userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (task *TaskApi) GetUserTaskInfo(ctx *utils.Context) {
+	// userID, err := task.entityService.GetParamID(ctx, "user_id")
 	// if err != nil {
 	// 	return
 	// }
-	// task_id, err := service.EntityService.GetParamID(ctx, "task_id")
+	// task_id, err := task.entityService.GetParamID(ctx, "task_id")
 	// if err != nil {
 	// 	return
 	// }
-	// thisUser := UserApi.GetOperatorInfo(ctx)
+	// thisUser := GetOperatorInfo(ctx)
 	// if thisUser.UserID != userID {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
 	// 	return
 	// }
 
-	// taskInfo, err := service.TaskService.GetTaskInfoByID(task_id)
+	// taskInfo, err := task.taskService.GetTaskInfoByID(task_id)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -470,6 +494,7 @@ func (task *taskApi) GetUserTaskInfo(ctx *utils.Context) {
 		DepartmentID:    taskInfo.DepartmentID,
 		DepartmentName:  taskInfo.Department.Name,
 		AssetList:       taskInfo.AssetList,
+		Quantities:      taskInfo.Quantities,
 		State:           taskInfo.State,
 	}
 
@@ -479,7 +504,7 @@ func (task *taskApi) GetUserTaskInfo(ctx *utils.Context) {
 /*
 Handle func for POST /departments/:department_id/assets/tasks/:task_id
 */
-func (task *taskApi) ApproveTask(ctx *utils.Context) {
+func (task *TaskApi) ApproveTask(ctx *utils.Context) {
 	taskInfo, thisUser, isOK := task.departmentTaskPrevillige(ctx)
 	if !isOK {
 		return
@@ -500,7 +525,7 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 	}).([]uint)
 
 	if taskInfo.TaskType == 0 {
-		assetList, err := service.AssetService.GetDepartmentIdleAssets(assetIDs, taskInfo.DepartmentID)
+		assetList, err := task.assetService.GetDepartmentIdleAssets(assetIDs, taskInfo.DepartmentID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -509,14 +534,8 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 			ctx.BadRequest(myerror.ASSET_LIST_INVALID, myerror.ASSET_LIST_INVALID_INFO)
 			return
 		}
-
-		err = service.AssetService.AcquireAssets(assetIDs, taskInfo.UserID)
-		if err != nil {
-			ctx.InternalError(err.Error())
-			return
-		}
 	} else if taskInfo.TaskType == 1 {
-		assetList, err := service.AssetService.GetUserAssetsByIDs(assetIDs, taskInfo.UserID)
+		assetList, err := task.assetService.GetUserAssetsByIDs(assetIDs, taskInfo.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -525,14 +544,8 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 			ctx.BadRequest(myerror.ASSET_LIST_INVALID, myerror.ASSET_LIST_INVALID_INFO)
 			return
 		}
-
-		err = service.AssetService.CancelAssets(assetIDs, thisUser.UserID)
-		if err != nil {
-			ctx.InternalError(err.Error())
-			return
-		}
 	} else {
-		assetList, err := service.AssetService.GetUserAssetsByIDs(assetIDs, taskInfo.UserID)
+		assetList, err := task.assetService.GetUserAssetsByIDs(assetIDs, taskInfo.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -542,7 +555,7 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 			return
 		}
 
-		targetUser, err := service.UserService.GetUserByID(taskInfo.TargetID)
+		targetUser, err := task.userService.GetUserByID(taskInfo.TargetID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -558,34 +571,25 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 			ctx.BadRequest(myerror.TARGET_NOT_IN_DEPARTMENT, myerror.TARGET_NOT_IN_DEPARTMENT_INFO)
 			return
 		}
-		if taskInfo.TaskType == 2 {
-			err = service.AssetService.ModifyAssetMaintainerAndState(assetIDs, taskInfo.TargetID)
-			if err != nil {
-				ctx.InternalError(err.Error())
-				return
-			}
-		} else {
-			err = service.AssetService.TransferAssets(assetIDs, taskInfo.TargetID, taskInfo.Target.DepartmentID, taskInfo.DepartmentID)
-			if err != nil {
-				ctx.InternalError(err.Error())
-				return
-			}
-		}
 	}
 
-	err := service.TaskService.ModifyTaskState(taskInfo.ID, 1)
+	err := task.taskService.ApproveTask(taskInfo, thisUser.UserID)
 	if err != nil {
-		ctx.InternalError(err.Error())
+		assetStateError(ctx, err)
 		return
 	}
 
 	go func() {
 		//向飞书发信息
-		user, err := service.UserService.GetUserByID(taskInfo.UserID)
+		user, err := task.userService.GetUserByID(taskInfo.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
+		// the user may be gone by the time the notice is sent
+		if user == nil {
+			return
+		}
 		TaksTypeMap := map[uint]string{
 			0: "领用",
 			1: "退库",
@@ -594,32 +598,32 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 		}
 		if len(user.FeishuID) != 0 {
 			text := fmt.Sprintf("您发送的描述为“%s”的%s请求已审批通过", taskInfo.TaskDescription, TaksTypeMap[taskInfo.TaskType])
-			err = service.FeishuService.SendMessage(user.ID, text)
+			err = task.feishuService.SendMessage(user.ID, text)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
 			}
 		}
 		taskInfo.State = 1
-		approval_code, err := service.FeishuService.CreateApprovalDefination()
+		approval_code, err := task.feishuService.CreateApprovalDefination()
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
-		err = service.FeishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
+		err = task.feishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
 		if taskInfo.TaskType == 2 || taskInfo.TaskType == 3 {
-			target, err := service.UserService.GetUserByID(taskInfo.TargetID)
+			target, err := task.userService.GetUserByID(taskInfo.TargetID)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
 			}
 			if len(target.FeishuID) != 0 {
 				text := fmt.Sprintf("您收到一条来自%s的%s请求，描述为“%s”，请注意处理", user.UserName, TaksTypeMap[taskInfo.TaskType], taskInfo.TaskDescription)
-				err = service.FeishuService.SendMessage(target.ID, text)
+				err = task.feishuService.SendMessage(target.ID, text)
 				if err != nil {
 					ctx.InternalError(err.Error())
 					return
@@ -634,7 +638,7 @@ func (task *taskApi) ApproveTask(ctx *utils.Context) {
 /*
 Handle func for DELETE /departments/:department_id/assets/tasks/:task_id
 */
-func (task *taskApi) RejectTask(ctx *utils.Context) {
+func (task *TaskApi) RejectTask(ctx *utils.Context) {
 	taskInfo, _, isOK := task.departmentTaskPrevillige(ctx)
 	if !isOK {
 		return
@@ -645,18 +649,22 @@ func (task *taskApi) RejectTask(ctx *utils.Context) {
 		return
 	}
 
-	err := service.TaskService.ModifyTaskState(taskInfo.ID, 2)
+	err := task.taskService.ModifyTaskState(taskInfo.ID, 2)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
 	go func() {
 		//向飞书发信息
-		user, err := service.UserService.GetUserByID(taskInfo.UserID)
+		user, err := task.userService.GetUserByID(taskInfo.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
+		// the user may be gone by the time the notice is sent
+		if user == nil {
+			return
+		}
 		TaksTypeMap := map[uint]string{
 			0: "领用",
 			1: "退库",
@@ -665,19 +673,19 @@ func (task *taskApi) RejectTask(ctx *utils.Context) {
 		}
 		if len(user.FeishuID) != 0 {
 			text := fmt.Sprintf("您发送的描述为“%s”的%s请求被管理员拒绝", taskInfo.TaskDescription, TaksTypeMap[taskInfo.TaskType])
-			err = service.FeishuService.SendMessage(user.ID, text)
+			err = task.feishuService.SendMessage(user.ID, text)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
 			}
 		}
 		taskInfo.State = 2
-		approval_code, err := service.FeishuService.CreateApprovalDefination()
+		approval_code, err := task.feishuService.CreateApprovalDefination()
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
-		err = service.FeishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
+		err = task.feishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -690,7 +698,7 @@ func (task *taskApi) RejectTask(ctx *utils.Context) {
 /*
 Handle func for DELETE /users/:user_id/assets/tasks/:task_id
 */
-func (task *taskApi) CancelTasks(ctx *utils.Context) {
+func (task *TaskApi) CancelTasks(ctx *utils.Context) {
 	taskInfo, isOK := task.userTaskPrevilige(ctx)
 	if !isOK {
 		return
@@ -701,7 +709,7 @@ func (task *taskApi) CancelTasks(ctx *utils.Context) {
 		return
 	}
 
-	err := service.TaskService.ModifyTaskState(taskInfo.ID, 3)
+	err := task.taskService.ModifyTaskState(taskInfo.ID, 3)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -709,11 +717,15 @@ func (task *taskApi) CancelTasks(ctx *utils.Context) {
 
 	go func() {
 		//向飞书发信息
-		user, err := service.UserService.GetUserByID(taskInfo.UserID)
+		user, err := task.userService.GetUserByID(taskInfo.UserID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
+		// the user may be gone by the time the notice is sent
+		if user == nil {
+			return
+		}
 		TaksTypeMap := map[uint]string{
 			0: "领用",
 			1: "退库",
@@ -722,19 +734,19 @@ func (task *taskApi) CancelTasks(ctx *utils.Context) {
 		}
 		if len(user.FeishuID) != 0 {
 			text := fmt.Sprintf("您发送的描述为“%s”的%s请求已撤销", taskInfo.TaskDescription, TaksTypeMap[taskInfo.TaskType])
-			err = service.FeishuService.SendMessage(user.ID, text)
+			err = task.feishuService.SendMessage(user.ID, text)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
 			}
 		}
 		taskInfo.State = 3
-		approval_code, err := service.FeishuService.CreateApprovalDefination()
+		approval_code, err := task.feishuService.CreateApprovalDefination()
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
-		err = service.FeishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
+		err = task.feishuService.PutApproval(*taskInfo, user.FeishuID, approval_code)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/middleware"
+	"asset-management/myerror"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func InitForTask(r *gin.Engine) {
+	userGroup := r.Group("/users")
+	userGroup.Use(utils.Handler(middleware.JWTMiddleware(tokenChecker)))
+	userGroup.POST("/:user_id/assets/task", utils.Handler(apis.Task.CreateNewTask))
+	departmentGroup := r.Group("/department")
+	departmentGroup.Use(utils.Handler(middleware.JWTMiddleware(tokenChecker)))
+	departmentGroup.GET("/:department_id/assets/tasks/:task_id", utils.Handler(apis.Task.GetDepartmentTaskInfo))
+	departmentGroup.POST("/:department_id/assets/tasks/:task_id", utils.Handler(apis.Task.ApproveTask))
+}
+
+func TestTaskQuantity(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("quantity_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Department.departmentService.CreateDepartment("quantity_department", entityID, 0)
+	assert.Equal(t, nil, err, "service error")
+	departmentList, err := apis.Entity.entityService.GetAllDepartmentsUnderEntity(entityID)
+	assert.Equal(t, nil, err, "service error")
+	departmentID := departmentList[0].ID
+	for _, name := range []string{"quantity_super", "quantity_user"} {
+		err = apis.Department.departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+			UserName:        name,
+			Password:        password,
+			DepartmentSuper: name == "quantity_super",
+		}, entityID, departmentID)
+		assert.Equal(t, nil, err, "service error")
+	}
+	err = apis.Asset.assetService.CreateAsset(&define.CreateAssetReq{
+		AssetName: "quantity_asset",
+		Price:     decimal.New(100, 0),
+		Number:    10,
+		Type:      define.ASSET_TYPE_QUANTITY,
+	}, departmentID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	assetList, err := apis.Asset.assetService.GetDepartmentAssetBasicList(departmentID)
+	assert.Equal(t, nil, err, "service error")
+	stockID := assetList[0].ID
+
+	call := func(method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+		req := GetRequest(method, url, map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": token,
+		}, GetJsonBody(body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	login := func(name string) (string, uint) {
+		res := call(http.MethodPost, "/user/login", "", define.UserLoginReq{UserName: name, Password: password})
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		login := struct {
+			Data define.UserLoginResponse `json:"data"`
+		}{}
+		json.Unmarshal(res.Body.Bytes(), &login)
+		thisUser, err := apis.User.userService.GetUserByName(name)
+		assert.Equal(t, nil, err, "service error")
+		return login.Data.Token, thisUser.ID
+	}
+	superToken, _ := login("quantity_super")
+	userToken, userID := login("quantity_user")
+	createTask := func(taskType uint, assetID uint, quantity int) *httptest.ResponseRecorder {
+		return call(http.MethodPost, fmt.Sprintf("/users/%d/assets/task", userID), userToken, define.CreateTaskReq{
+			TaskType:  taskType,
+			AssetList: []define.TaskAssetReq{{AssetID: assetID, Quantity: quantity}},
+		})
+	}
+	approveLast := func() uint {
+		taskList, err := apis.Task.taskService.GetTasksByUserID(userID)
+		assert.Equal(t, nil, err, "service error")
+		taskID := taskList[len(taskList)-1].ID
+		res := call(http.MethodPost, fmt.Sprintf("/department/%d/assets/tasks/%d", departmentID, taskID), superToken, nil)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+		return taskID
+	}
+
+	// the stock holds only 10
+	res = createTask(0, stockID, 11)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	errData := utils.ResponseData{}
+	json.Unmarshal(res.Body.Bytes(), &errData)
+	assert.Equal(t, myerror.ASSET_QUANTITY_INVALID, errData.Error.Code, "response failed")
+	res = createTask(0, stockID, -1)
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+
+	res = createTask(0, stockID, 3)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	taskID := approveLast()
+	res = call(http.MethodGet, fmt.Sprintf("/department/%d/assets/tasks/%d", departmentID, taskID), superToken, nil)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	taskInfo := struct {
+		Data define.TaskInfo `json:"data"`
+	}{}
+	json.Unmarshal(res.Body.Bytes(), &taskInfo)
+	assert.Equal(t, 3, taskInfo.Data.Quantities[0].Quantity, "response failed")
+	stock, err := apis.Asset.assetService.GetAssetByID(stockID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 7, stock.Number, "response failed")
+	heldList, err := apis.Asset.assetService.GetUserAssetsByIDs([]uint{stockID + 1}, userID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 3, heldList[0].Number, "response failed")
+
+	// the whole portion comes back onto the stock
+	res = createTask(1, heldList[0].ID, 0)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	approveLast()
+	stock, err = apis.Asset.assetService.GetAssetByID(stockID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 10, stock.Number, "response failed")
+	assert.Equal(t, "100", stock.Price.String(), "response failed")
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/myerror"
+	"asset-management/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+)
+
+type TwoFactorApi struct {
+	loginLockService service.LoginLockServiceInterface
+	tokenService     service.TokenServiceInterface
+	twoFactorService service.TwoFactorServiceInterface
+	userService      service.UserServiceInterface
+}
+
+func NewTwoFactorApi(
+	loginLockService service.LoginLockServiceInterface,
+	tokenService service.TokenServiceInterface,
+	twoFactorService service.TwoFactorServiceInterface,
+	userService service.UserServiceInterface,
+) *TwoFactorApi {
+	return &TwoFactorApi{
+		loginLockService: loginLockService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		userService:      userService,
+	}
+}
+
+/*
+Called once the first factor passed, answers with a two-factor token instead of
+access tokens when the user has 2FA enabled or must enroll, true means the response is written
+*/
+func (twoFactor *TwoFactorApi) Challenge(ctx *utils.Context, thisUser *model.User) bool {
+	enabled, err := twoFactor.twoFactorService.Enabled(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return true
+	}
+	setup := false
+	if !enabled {
+		setup, err = twoFactor.twoFactorService.Required(thisUser)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			return true
+		}
+		if !setup {
+			return false
+		}
+	}
+
+	token, err := utils.CreateTwoFactorToken(thisUser.ID, setup)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return true
+	}
+	ctx.Success(define.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		TwoFactorSetup:    setup,
+		TwoFactorToken:    token,
+	})
+	return true
+}
+
+func (twoFactor *TwoFactorApi) challengeUser(ctx *utils.Context, token string) (*define.TwoFactorClaims, *model.User, bool) {
+	claims, err := utils.ParseTwoFactorToken(token)
+	if err != nil {
+		ctx.Unauthorized(myerror.TWO_FACTOR_TOKEN_INVALID, myerror.TWO_FACTOR_TOKEN_INVALID_INFO)
+		return nil, nil, false
+	}
+	thisUser, err := twoFactor.userService.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, nil, false
+	} else if thisUser == nil || thisUser.Ban {
+		ctx.Unauthorized(myerror.TWO_FACTOR_TOKEN_INVALID, myerror.TWO_FACTOR_TOKEN_INVALID_INFO)
+		return nil, nil, false
+	}
+	return claims, thisUser, true
+}
+
+/*
+Handle func for POST /user/login/two-factor/setup
+*/
+func (twoFactor *TwoFactorApi) LoginSetup(ctx *utils.Context) {
+	var req define.TwoFactorTokenReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	claims, thisUser, ok := twoFactor.challengeUser(ctx, req.TwoFactorToken)
+	if !ok {
+		return
+	} else if !claims.Setup {
+		ctx.BadRequest(myerror.TWO_FACTOR_ALREADY_ENABLED, myerror.TWO_FACTOR_ALREADY_ENABLED_INFO)
+		return
+	}
+
+	setupRes, err := twoFactor.twoFactorService.Setup(thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if setupRes == nil {
+		ctx.BadRequest(myerror.TWO_FACTOR_ALREADY_ENABLED, myerror.TWO_FACTOR_ALREADY_ENABLED_INFO)
+		return
+	}
+	ctx.Success(setupRes)
+}
+
+/*
+Handle func for POST /user/login/two-factor, the second login step,
+completes enrollment as well when the two-factor token asks for setup
+*/
+func (twoFactor *TwoFactorApi) Login(ctx *utils.Context) {
+	var req define.TwoFactorLoginReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	claims, thisUser, ok := twoFactor.challengeUser(ctx, req.TwoFactorToken)
+	if !ok {
+		return
+	}
+
+	ip := ctx.ClientIP()
+	lockedUntil, err := twoFactor.loginLockService.CheckLocked(thisUser.UserName, ip)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if lockedUntil != nil {
+		ctx.Forbidden(myerror.LOGIN_LOCKED, myerror.LOGIN_LOCKED_INFO)
+		return
+	}
+
+	var recoveryCodes []string
+	if claims.Setup {
+		recoveryCodes, err = twoFactor.twoFactorService.Enable(thisUser.ID, req.Code)
+		ok = recoveryCodes != nil
+	} else {
+		ok, err = twoFactor.twoFactorService.Verify(thisUser.ID, req.Code)
+	}
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if !ok {
+		if _, err := twoFactor.loginLockService.RecordFailure(thisUser.UserName, ip); err != nil {
+			ctx.InternalError(err.Error())
+			return
+		}
+		ctx.BadRequest(myerror.TWO_FACTOR_CODE_INVALID, myerror.TWO_FACTOR_CODE_INVALID_INFO)
+		return
+	}
+
+	tokens, err := twoFactor.tokenService.IssueTokens(thisUser, sessionClient(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	var userInfo define.UserInfo
+	err = copier.Copy(&userInfo, thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.UserLoginResponse{
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		User:                   userInfo,
+		FeishuID:               thisUser.FeishuID,
+		RecoveryCodes:          recoveryCodes,
+		PasswordChangeRequired: tokens.PasswordChangeRequired,
+	})
+}
+
+func (twoFactor *TwoFactorApi) operator(ctx *utils.Context) (*model.User, bool) {
+	thisUser, err := twoFactor.userService.GetUserByID(GetOperatorID(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return nil, false
+	} else if thisUser == nil {
+		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
+		return nil, false
+	}
+	return thisUser, true
+}
+
+/*
+Check the code of a user who already has 2FA enabled, the response is written when false
+*/
+func (twoFactor *TwoFactorApi) verifyCode(ctx *utils.Context, thisUser *model.User) bool {
+	var req define.TwoFactorCodeReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return false
+	}
+	enabled, err := twoFactor.twoFactorService.Enabled(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	} else if !enabled {
+		ctx.BadRequest(myerror.TWO_FACTOR_NOT_ENABLED, myerror.TWO_FACTOR_NOT_ENABLED_INFO)
+		return false
+	}
+	ok, err := twoFactor.twoFactorService.Verify(thisUser.ID, req.Code)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	} else if !ok {
+		ctx.BadRequest(myerror.TWO_FACTOR_CODE_INVALID, myerror.TWO_FACTOR_CODE_INVALID_INFO)
+		return false
+	}
+	return true
+}
+
+/*
+Handle func for GET /user/two-factor
+*/
+func (twoFactor *TwoFactorApi) GetStatus(ctx *utils.Context) {
+	thisUser, ok := twoFactor.operator(ctx)
+	if !ok {
+		return
+	}
+	status, err := twoFactor.twoFactorService.Status(thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(status)
+}
+
+/*
+Handle func for POST /user/two-factor/setup
+*/
+func (twoFactor *TwoFactorApi) Setup(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser, ok := twoFactor.operator(ctx)
+	if !ok {
+		return
+	}
+	setupRes, err := twoFactor.twoFactorService.Setup(thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if setupRes == nil {
+		ctx.BadRequest(myerror.TWO_FACTOR_ALREADY_ENABLED, myerror.TWO_FACTOR_ALREADY_ENABLED_INFO)
+		return
+	}
+	ctx.Success(setupRes)
+}
+
+/*
+Handle func for POST /user/two-factor/enable
+*/
+func (twoFactor *TwoFactorApi) Enable(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
+	var req define.TwoFactorCodeReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+	codes, err := twoFactor.twoFactorService.Enable(GetOperatorID(ctx), req.Code)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if codes == nil {
+		ctx.BadRequest(myerror.TWO_FACTOR_CODE_INVALID, myerror.TWO_FACTOR_CODE_INVALID_INFO)
+		return
+	}
+	ctx.Success(define.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+/*
+Handle func for POST /user/two-factor/disable
+*/
+func (twoFactor *TwoFactorApi) Disable(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser, ok := twoFactor.operator(ctx)
+	if !ok {
+		return
+	}
+	required, err := twoFactor.twoFactorService.Required(thisUser)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if required {
+		ctx.Forbidden(myerror.TWO_FACTOR_REQUIRED, myerror.TWO_FACTOR_REQUIRED_INFO)
+		return
+	}
+	if !twoFactor.verifyCode(ctx, thisUser) {
+		return
+	}
+
+	err = twoFactor.twoFactorService.Disable(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for POST /user/two-factor/recovery-codes
+*/
+func (twoFactor *TwoFactorApi) RegenerateRecoveryCodes(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser, ok := twoFactor.operator(ctx)
+	if !ok {
+		return
+	}
+	if !twoFactor.verifyCode(ctx, thisUser) {
+		return
+	}
+
+	codes, err := twoFactor.twoFactorService.RegenerateRecoveryCodes(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(define.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
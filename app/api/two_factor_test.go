@@ -0,0 +1,92 @@
+package api
+
+import (
+	"asset-management/app/define"
+	"asset-management/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	InitForTest(r)
+
+	password := "21232f297a57a5a743894a0e4a801fc3"
+	err := apis.Entity.entityService.CreateEntity("two_factor_entity")
+	assert.Equal(t, nil, err, "service error")
+	entityList, err := apis.Entity.entityService.GetAllEntity()
+	assert.Equal(t, nil, err, "service error")
+	entityID := entityList[len(entityList)-1].ID
+	err = apis.Entity.entityService.CreateManager("two_factor_manager", password, entityID)
+	assert.Equal(t, nil, err, "service error")
+
+	post := func(url string, header map[string]string, body interface{}) map[string]interface{} {
+		req := GetRequest(http.MethodPost, url, header, GetJsonBody(body))
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		data := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &data)
+		response, _ := data["data"].(map[string]interface{})
+		return response
+	}
+	login := define.UserLoginReq{UserName: "two_factor_manager", Password: password}
+
+	data := post("/user/login", headerJson, login)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	headerJsonToken["Authorization"] = data["token"].(string)
+	{
+		req := GetRequest(http.MethodPut, fmt.Sprintf("/entity/%d/two-factor", entityID), headerJsonToken, GetJsonBody(map[string]bool{"required": true}))
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	}
+
+	// the entity now requires 2FA, login asks for enrollment first
+	data = post("/user/login", headerJson, login)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.Equal(t, true, data["two_factor_setup"], "response failed")
+	assert.Equal(t, nil, data["token"], "response failed")
+	twoFactorToken := data["two_factor_token"].(string)
+
+	data = post("/user/login/two-factor/setup", headerJson, define.TwoFactorTokenReq{TwoFactorToken: "invalid"})
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	data = post("/user/login/two-factor/setup", headerJson, define.TwoFactorTokenReq{TwoFactorToken: twoFactorToken})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	secret := data["secret"].(string)
+
+	post("/user/login/two-factor", headerJson, define.TwoFactorLoginReq{TwoFactorToken: twoFactorToken, Code: "000000"})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	assert.Equal(t, nil, err, "totp error")
+	data = post("/user/login/two-factor", headerJson, define.TwoFactorLoginReq{TwoFactorToken: twoFactorToken, Code: code})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.NotEqual(t, nil, data["token"], "response failed")
+	recoveryCodes := data["recovery_codes"].([]interface{})
+	assert.Equal(t, 10, len(recoveryCodes), "response failed")
+
+	// enrolled, the second step now takes a code or a recovery code
+	data = post("/user/login", headerJson, login)
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	assert.Equal(t, false, data["two_factor_setup"], "response failed")
+	twoFactorToken = data["two_factor_token"].(string)
+	post("/user/login/two-factor/setup", headerJson, define.TwoFactorTokenReq{TwoFactorToken: twoFactorToken})
+	assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode, "response failed")
+	data = post("/user/login/two-factor", headerJson, define.TwoFactorLoginReq{TwoFactorToken: twoFactorToken, Code: recoveryCodes[0].(string)})
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+	headerJsonToken["Authorization"] = data["token"].(string)
+
+	// a two-factor token is not an access token
+	headerForged := map[string]string{"Content-Type": "application/json", "Authorization": twoFactorToken}
+	post("/user/two-factor/disable", headerForged, define.TwoFactorCodeReq{Code: recoveryCodes[1].(string)})
+	assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	post("/user/two-factor/disable", headerJsonToken, define.TwoFactorCodeReq{Code: recoveryCodes[1].(string)})
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode, "response failed")
+}
@@ -9,29 +9,37 @@ import (
 	"github.com/gin-gonic/gin/binding"
 )
 
-type urlApi struct {
+type UrlApi struct {
+	entityService service.EntityServiceInterface
+	urlService    service.UrlServiceInterface
+	userService   service.UserServiceInterface
+	permissionApi *PermissionApi
 }
 
-var UrlApi *urlApi
-
-func newUrlApi() *urlApi {
-	return &urlApi{}
-}
-
-func init() {
-	UrlApi = newUrlApi()
+func NewUrlApi(
+	entityService service.EntityServiceInterface,
+	urlService service.UrlServiceInterface,
+	userService service.UserServiceInterface,
+	permissionApi *PermissionApi,
+) *UrlApi {
+	return &UrlApi{
+		entityService: entityService,
+		urlService:    urlService,
+		userService:   userService,
+		permissionApi: permissionApi,
+	}
 }
 
 /*
 Handle func for POST /entity/{entity_id}/url
 */
-func (url *urlApi) CreateUrl(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (url *UrlApi) CreateUrl(ctx *utils.Context) {
+	entityID, err := url.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := url.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -40,9 +48,7 @@ func (url *urlApi) CreateUrl(ctx *utils.Context) {
 		return
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !url.permissionApi.Check(ctx, define.PERM_ENTITY_MANAGE, entityID, 0) {
 		return
 	}
 
@@ -53,7 +59,7 @@ func (url *urlApi) CreateUrl(ctx *utils.Context) {
 		return
 	}
 
-	exists, err = service.UrlService.CheckIfUrlExists(req.Name, entityID)
+	exists, err = url.urlService.CheckIfUrlExists(req.Name, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -62,7 +68,7 @@ func (url *urlApi) CreateUrl(ctx *utils.Context) {
 		return
 	}
 
-	err = service.UrlService.CreateUrl(req, entityID)
+	err = url.urlService.CreateUrl(req, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -73,13 +79,13 @@ func (url *urlApi) CreateUrl(ctx *utils.Context) {
 /*
 Handle func for PATCH /entity/{entity_id}/url
 */
-func (url *urlApi) ModifyUrl(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (url *UrlApi) ModifyUrl(ctx *utils.Context) {
+	entityID, err := url.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := url.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -88,9 +94,7 @@ func (url *urlApi) ModifyUrl(ctx *utils.Context) {
 		return
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !url.permissionApi.Check(ctx, define.PERM_ENTITY_MANAGE, entityID, 0) {
 		return
 	}
 
@@ -101,7 +105,7 @@ func (url *urlApi) ModifyUrl(ctx *utils.Context) {
 		return
 	}
 
-	exists, err = service.UrlService.CheckIfUrlExists(req.OldName, entityID)
+	exists, err = url.urlService.CheckIfUrlExists(req.OldName, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -111,7 +115,7 @@ func (url *urlApi) ModifyUrl(ctx *utils.Context) {
 	}
 
 	if req.Name != req.OldName {
-		exists, err = service.UrlService.CheckIfUrlExists(req.Name, entityID)
+		exists, err = url.urlService.CheckIfUrlExists(req.Name, entityID)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
@@ -121,7 +125,7 @@ func (url *urlApi) ModifyUrl(ctx *utils.Context) {
 		}
 	}
 
-	err = service.UrlService.ModifyUrlInfo(req, entityID)
+	err = url.urlService.ModifyUrlInfo(req, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -132,13 +136,13 @@ func (url *urlApi) ModifyUrl(ctx *utils.Context) {
 /*
 Handle func for DELETE /entity/{entity_id}/url
 */
-func (url *urlApi) DeleteUrl(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (url *UrlApi) DeleteUrl(ctx *utils.Context) {
+	entityID, err := url.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := url.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -147,9 +151,7 @@ func (url *urlApi) DeleteUrl(ctx *utils.Context) {
 		return
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !url.permissionApi.Check(ctx, define.PERM_ENTITY_MANAGE, entityID, 0) {
 		return
 	}
 
@@ -160,7 +162,7 @@ func (url *urlApi) DeleteUrl(ctx *utils.Context) {
 		return
 	}
 
-	exists, err = service.UrlService.CheckIfUrlExists(req.Name, entityID)
+	exists, err = url.urlService.CheckIfUrlExists(req.Name, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -169,7 +171,7 @@ func (url *urlApi) DeleteUrl(ctx *utils.Context) {
 		return
 	}
 
-	err = service.UrlService.DeleteUrl(req.Name, entityID)
+	err = url.urlService.DeleteUrl(req.Name, entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -180,13 +182,13 @@ func (url *urlApi) DeleteUrl(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/url
 */
-func (url *urlApi) GetUrlsByEntity(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (url *UrlApi) GetUrlsByEntity(ctx *utils.Context) {
+	entityID, err := url.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := url.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -195,10 +197,23 @@ func (url *urlApi) GetUrlsByEntity(ctx *utils.Context) {
 		return
 	}
 
-	departmentSuper := service.UserService.DepartmentSuper(ctx)
-	entitySuper := service.UserService.EntitySuper(ctx)
-	systemSuper := service.UserService.SystemSuper(ctx)
-	url_list, err := service.UrlService.GetUrlsByEntity(entityID, departmentSuper, entitySuper, systemSuper)
+	// the flags on a url name the built-in role it is shown to
+	departmentSuper, err := url.permissionApi.AllowedInEntity(ctx, define.PERM_ASSET_MANAGE, entityID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	entitySuper, err := url.permissionApi.Allowed(ctx, define.PERM_DEPARTMENT_MANAGE, entityID, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	systemSuper, err := url.permissionApi.Allowed(ctx, define.PERM_SYSTEM_MANAGE, 0, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	url_list, err := url.urlService.GetUrlsByEntity(entityID, departmentSuper, entitySuper, systemSuper)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -221,13 +236,13 @@ func (url *urlApi) GetUrlsByEntity(ctx *utils.Context) {
 /*
 Handle func for GET /entity/{entity_id}/url/list
 */
-func (url *urlApi) GetUrlList(ctx *utils.Context) {
-	entityID, err := service.EntityService.GetParamID(ctx, "entity_id")
+func (url *UrlApi) GetUrlList(ctx *utils.Context) {
+	entityID, err := url.entityService.GetParamID(ctx, "entity_id")
 	if err != nil {
 		return
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(entityID)
+	exists, err := url.entityService.ExistsEntityByID(entityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -236,13 +251,11 @@ func (url *urlApi) GetUrlList(ctx *utils.Context) {
 		return
 	}
 
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !url.permissionApi.Check(ctx, define.PERM_ENTITY_MANAGE, entityID, 0) {
 		return
 	}
 
-	url_list, err := service.UrlService.GetUrlsByEntity(entityID, true, true, true)
+	url_list, err := url.urlService.GetUrlsByEntity(entityID, true, true, true)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -6,39 +6,70 @@ import (
 	"asset-management/app/service"
 	"asset-management/myerror"
 	"asset-management/utils"
+	"errors"
+	"log"
 	"strconv"
 
 	"github.com/gin-gonic/gin/binding"
 	"github.com/jinzhu/copier"
 )
 
-type userApi struct {
+type UserApi struct {
+	assetService     service.AssetServiceInterface
+	asyncService     service.AsyncServiceInterface
+	entityService    service.EntityServiceInterface
+	feishuService    service.FeishuServiceInterface
+	inviteService    service.InviteServiceInterface
+	loginLockService service.LoginLockServiceInterface
+	passwordService  service.PasswordServiceInterface
+	taskService      service.TaskServiceInterface
+	tokenService     service.TokenServiceInterface
+	userService      service.UserServiceInterface
+	departmentApi    *DepartmentApi
+	twoFactorApi     *TwoFactorApi
+	permissionApi    *PermissionApi
 }
 
-var UserApi *userApi
-
-func newUserApi() *userApi {
-	return &userApi{}
-}
-
-func init() {
-	UserApi = newUserApi()
+func NewUserApi(
+	assetService service.AssetServiceInterface,
+	asyncService service.AsyncServiceInterface,
+	entityService service.EntityServiceInterface,
+	feishuService service.FeishuServiceInterface,
+	inviteService service.InviteServiceInterface,
+	loginLockService service.LoginLockServiceInterface,
+	passwordService service.PasswordServiceInterface,
+	taskService service.TaskServiceInterface,
+	tokenService service.TokenServiceInterface,
+	userService service.UserServiceInterface,
+	departmentApi *DepartmentApi,
+	twoFactorApi *TwoFactorApi,
+	permissionApi *PermissionApi,
+) *UserApi {
+	return &UserApi{
+		assetService:     assetService,
+		asyncService:     asyncService,
+		entityService:    entityService,
+		feishuService:    feishuService,
+		inviteService:    inviteService,
+		loginLockService: loginLockService,
+		passwordService:  passwordService,
+		taskService:      taskService,
+		tokenService:     tokenService,
+		userService:      userService,
+		departmentApi:    departmentApi,
+		twoFactorApi:     twoFactorApi,
+		permissionApi:    permissionApi,
+	}
 }
 
-func (user *userApi) CheckIdentity(ctx *utils.Context, entityID uint) bool {
-	systemSuper := service.UserService.SystemSuper(ctx)
-	if systemSuper {
-		return true
-	}
-	entitySuper := service.UserService.EntitySuper(ctx)
-	if !entitySuper {
-		return false
-	}
-	isInEntity := service.EntityService.CheckIsInEntity(ctx, entityID)
-	return isInEntity
+/*
+Whether the operator manages the users of the entity, the response is written when false
+*/
+func (user *UserApi) CheckIdentity(ctx *utils.Context, entityID uint) bool {
+	return user.permissionApi.Check(ctx, define.PERM_USER_MANAGE, entityID, 0)
 }
 
-func (user *userApi) GetOperatorID(ctx *utils.Context) uint {
+func GetOperatorID(ctx *utils.Context) uint {
 	userInfo, exists := ctx.Get("user")
 	if exists {
 		if userInfo, ok := userInfo.(define.UserBasicInfo); ok {
@@ -48,7 +79,7 @@ func (user *userApi) GetOperatorID(ctx *utils.Context) uint {
 	return 0
 }
 
-func (user *userApi) GetOperatorInfo(ctx *utils.Context) *define.UserBasicInfo {
+func GetOperatorInfo(ctx *utils.Context) *define.UserBasicInfo {
 	userInfo, exists := ctx.Get("user")
 	if exists {
 		if userInfo, ok := userInfo.(define.UserBasicInfo); ok {
@@ -58,14 +89,14 @@ func (user *userApi) GetOperatorInfo(ctx *utils.Context) *define.UserBasicInfo {
 	return nil
 }
 
-func (user *userApi) UserRegister(ctx *utils.Context) {
+func (user *UserApi) UserRegister(ctx *utils.Context) {
 	var req define.UserRegisterReq
 
 	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
 		ctx.BadRequest(-1, "Invalid request body.")
 		return
 	}
-	exists, err := service.UserService.ExistsUser(req.UserName)
+	exists, err := user.userService.ExistsUser(req.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -73,15 +104,54 @@ func (user *userApi) UserRegister(ctx *utils.Context) {
 		ctx.BadRequest(1, "Duplicated Name")
 		return
 	}
-	err = service.UserService.CreateUser(req.UserName, req.Password)
+	if req.InviteToken != "" {
+		err = user.inviteService.Register(req.InviteToken, req.UserName, req.Password)
+	} else if err = user.passwordService.Validate(0, req.Password); err == nil {
+		err = user.userService.CreateUser(req.UserName, req.Password)
+	}
+	if errors.Is(err, service.ErrInviteInvalid) {
+		ctx.BadRequest(myerror.INVITE_INVALID, myerror.INVITE_INVALID_INFO)
+		return
+	} else if err != nil {
+		passwordError(ctx, err)
+		return
+	}
+	ctx.Success(nil)
+}
+
+/*
+Count a wrong password, when it locks the username or ip the lockout
+is handed to LogMiddleware through the context
+*/
+func (user *UserApi) LoginFailed(ctx *utils.Context, username string, ip string) {
+	newLock, err := user.loginLockService.RecordFailure(username, ip)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
+	} else if newLock == nil {
+		ctx.BadRequest(1, "Wrong Username Or Password")
+		return
 	}
-	ctx.Success(nil)
+
+	event := define.LoginLockoutEvent{
+		UserName:    username,
+		Kind:        newLock.Kind,
+		LockedUntil: *newLock.LockedUntil,
+	}
+	thisUser, err := user.userService.GetUserByName(username)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisUser != nil {
+		event.UserID = thisUser.ID
+		event.EntityID = thisUser.EntityID
+		event.DepartmentID = thisUser.DepartmentID
+	}
+	ctx.Set("login_lockout", event)
+	ctx.Forbidden(myerror.LOGIN_LOCKED, myerror.LOGIN_LOCKED_INFO)
 }
 
-func (user *userApi) UserLogin(ctx *utils.Context) {
+func (user *UserApi) UserLogin(ctx *utils.Context) {
 	// 是否需要加密传输？
 	// 是否需要通过中间件处理？
 	var req define.UserLoginReq
@@ -91,19 +161,41 @@ func (user *userApi) UserLogin(ctx *utils.Context) {
 		ctx.BadRequest(-1, "Invalid request body.")
 		return
 	}
-	var err error
-	var token string
-	token, thisUser, err = service.UserService.VerifyPasswordAndGetUser(req.UserName, req.Password)
+	ip := ctx.ClientIP()
+	lockedUntil, err := user.loginLockService.CheckLocked(req.UserName, ip)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if lockedUntil != nil {
+		ctx.Forbidden(myerror.LOGIN_LOCKED, myerror.LOGIN_LOCKED_INFO)
+		return
+	}
+
+	thisUser, err = user.userService.VerifyPasswordAndGetUser(req.UserName, req.Password)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	} else if thisUser == nil {
-		ctx.BadRequest(1, "Wrong Username Or Password")
+		user.LoginFailed(ctx, req.UserName, ip)
 		return
 	} else if thisUser.Ban {
 		ctx.BadRequest(3, "User Banned")
 		return
 	}
+	err = user.loginLockService.RecordSuccess(req.UserName)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	if user.twoFactorApi.Challenge(ctx, thisUser) {
+		return
+	}
+
+	tokens, err := user.tokenService.IssueTokens(thisUser, sessionClient(ctx))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
 
 	var userInfo define.UserInfo
 	err = copier.Copy(&userInfo, thisUser)
@@ -113,14 +205,16 @@ func (user *userApi) UserLogin(ctx *utils.Context) {
 	}
 
 	data := define.UserLoginResponse{
-		Token:    token,
-		User:     userInfo,
-		FeishuID: thisUser.FeishuID,
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		User:                   userInfo,
+		FeishuID:               thisUser.FeishuID,
+		PasswordChangeRequired: tokens.PasswordChangeRequired,
 	}
 
 	// if len(thisUser.FeishuID) != 0 {
 	// 	go func() {
-	// 		err = service.FeishuService.FeishuSync(userInfo.EntityID)
+	// 		err = user.feishuService.FeishuSync(userInfo.EntityID)
 	// 		if err != nil {
 	// 			// ctx.InternalError(err.Error())
 	// 			log.Println(err.Error())
@@ -132,12 +226,41 @@ func (user *userApi) UserLogin(ctx *utils.Context) {
 	ctx.Success(data)
 }
 
-func (user *userApi) UserLogout(ctx *utils.Context) {
-	// 使用中间件验证 token 是否正确
+/*
+Handle func for POST /user/logout, revokes the login grant behind the current token
+*/
+func (user *UserApi) UserLogout(ctx *utils.Context) {
+	err := user.tokenService.RevokeToken(ctx.GetString("token_id"))
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
 	ctx.Success(nil)
 }
 
-func (user *userApi) UserCreate(ctx *utils.Context) {
+/*
+Handle func for POST /user/token/refresh
+*/
+func (user *UserApi) RefreshToken(ctx *utils.Context) {
+	var req define.RefreshTokenReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	tokens, err := user.tokenService.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if tokens == nil {
+		ctx.Unauthorized(myerror.REFRESH_TOKEN_INVALID, myerror.REFRESH_TOKEN_INVALID_INFO)
+		return
+	}
+
+	ctx.Success(tokens)
+}
+
+func (user *UserApi) UserCreate(ctx *utils.Context) {
 	// TODO: 暂时使用 register 的 req
 	var req define.UserRegisterReq
 	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
@@ -145,11 +268,15 @@ func (user *userApi) UserCreate(ctx *utils.Context) {
 		return
 	}
 	// 暂时按照只有超级用户可以创建来处理
-	if !service.UserService.SystemSuper(ctx) {
+	allowed, err := user.permissionApi.Allowed(ctx, define.PERM_SYSTEM_MANAGE, 0, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if !allowed {
 		ctx.Forbidden(2, "Permission Denied.")
 		return
 	}
-	exists, err := service.UserService.ExistsUser(req.UserName)
+	exists, err := user.userService.ExistsUser(req.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -157,7 +284,17 @@ func (user *userApi) UserCreate(ctx *utils.Context) {
 		ctx.BadRequest(1, "Duplicated Name")
 		return
 	}
-	err = service.UserService.CreateUser(req.UserName, req.Password)
+	err = user.passwordService.Validate(0, req.Password)
+	if err != nil {
+		passwordError(ctx, err)
+		return
+	}
+	err = user.userService.CreateUser(req.UserName, req.Password)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	err = user.passwordService.RequireChange(req.UserName)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -165,7 +302,7 @@ func (user *userApi) UserCreate(ctx *utils.Context) {
 	ctx.Success(nil)
 }
 
-func (user *userApi) ResetContent(ctx *utils.Context) {
+func (user *UserApi) ResetContent(ctx *utils.Context) {
 	var req define.ResetReq
 	username := ctx.Param("username")
 	errReq := ctx.MustBindWith(&req, binding.JSON)
@@ -174,7 +311,7 @@ func (user *userApi) ResetContent(ctx *utils.Context) {
 		return
 	}
 	//查找用户是否存在
-	exists, err := service.UserService.ExistsUser(username)
+	exists, err := user.userService.ExistsUser(username)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -182,14 +319,19 @@ func (user *userApi) ResetContent(ctx *utils.Context) {
 		ctx.BadRequest(1, "User Not Found")
 		return
 	}
+	systemSuper, err := user.permissionApi.Allowed(ctx, define.PERM_SYSTEM_MANAGE, 0, 0)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
 	if req.Method == 0 {
 		// 修改身份
 		// 暂时按照只有超级用户可以创建来处理
-		if !service.UserService.SystemSuper(ctx) {
+		if !systemSuper {
 			ctx.Forbidden(2, "Permission Denied.")
 			return
 		} else {
-			err = service.UserService.ModifyUserIdentity(username, req.Identity)
+			err = user.userService.ModifyUserIdentity(username, req.Identity)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
@@ -199,21 +341,25 @@ func (user *userApi) ResetContent(ctx *utils.Context) {
 		// 修改密码
 		// 超级用户和自己都应该可以修改密码
 		// 自己修改密码需要验证是否为本人
-		if !service.UserService.SystemSuper(ctx) {
-			get_username, err := service.UserService.UserName(ctx)
+		if !systemSuper {
+			get_username, err := user.userService.UserName(ctx)
 			if err != nil {
 				ctx.InternalError(err.Error())
 				return
 			}
 			if username != get_username {
 				ctx.Forbidden(2, "Permission Denied.")
+				return
 			}
 		}
-		err = service.UserService.ModifyUserPassword(username, req.Password)
+		thisUser, err := user.userService.GetUserByName(username)
 		if err != nil {
 			ctx.InternalError(err.Error())
 			return
 		}
+		if !user.setPassword(ctx, thisUser, req.Password) {
+			return
+		}
 	} else {
 		ctx.BadRequest(-1, "Invalid request body.")
 		return
@@ -221,10 +367,10 @@ func (user *userApi) ResetContent(ctx *utils.Context) {
 	ctx.Success(nil)
 }
 
-func (user *userApi) LockUser(ctx *utils.Context) {
+func (user *UserApi) LockUser(ctx *utils.Context) {
 	username := ctx.Param("username")
 
-	thisUser, err := service.UserService.GetUserByName(username)
+	thisUser, err := user.userService.GetUserByName(username)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -232,13 +378,16 @@ func (user *userApi) LockUser(ctx *utils.Context) {
 		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
 		return
 	}
-	hasIdentity := user.CheckIdentity(ctx, thisUser.EntityID)
-	if !hasIdentity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !user.CheckIdentity(ctx, thisUser.EntityID) {
 		return
 	}
 
-	err = service.UserService.ModifyUserBanstate(username, true)
+	err = user.userService.ModifyUserBanstate(username, true)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	err = user.tokenService.RevokeUserTokens(thisUser.ID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -247,10 +396,10 @@ func (user *userApi) LockUser(ctx *utils.Context) {
 	ctx.Success(nil)
 }
 
-func (user *userApi) UnlockUser(ctx *utils.Context) {
+func (user *UserApi) UnlockUser(ctx *utils.Context) {
 	username := ctx.Param("username")
 
-	thisUser, err := service.UserService.GetUserByName(username)
+	thisUser, err := user.userService.GetUserByName(username)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -258,13 +407,11 @@ func (user *userApi) UnlockUser(ctx *utils.Context) {
 		ctx.BadRequest(myerror.USER_NOT_FOUND, myerror.USER_NOT_FOUND_INFO)
 		return
 	}
-	hasIdentity := user.CheckIdentity(ctx, thisUser.EntityID)
-	if !hasIdentity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !user.CheckIdentity(ctx, thisUser.EntityID) {
 		return
 	}
 
-	err = service.UserService.ModifyUserBanstate(username, false)
+	err = user.userService.ModifyUserBanstate(username, false)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -273,16 +420,59 @@ func (user *userApi) UnlockUser(ctx *utils.Context) {
 	ctx.Success(nil)
 }
 
+/*
+Handle func for GET /user/login-locks, the client ip locks no entity super can see
+*/
+func (user *UserApi) GetIPLoginLocks(ctx *utils.Context) {
+	lockList, err := user.loginLockService.GetIPLocks()
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	lockListRes, err := loginLockList(lockList)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(lockListRes)
+}
+
+/*
+Handle func for DELETE /user/login-locks/{lock_id}
+*/
+func (user *UserApi) ClearIPLoginLock(ctx *utils.Context) {
+	lockID, err := user.entityService.GetParamID(ctx, "lock_id")
+	if err != nil {
+		return
+	}
+
+	thisLock, err := user.loginLockService.GetLockByID(lockID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	} else if thisLock == nil || thisLock.Kind != model.LOCK_BY_IP {
+		ctx.NotFound(myerror.LOGIN_LOCK_NOT_FOUND, myerror.LOGIN_LOCK_NOT_FOUND_INFO)
+		return
+	}
+
+	err = user.loginLockService.ClearLock(lockID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	ctx.Success(nil)
+}
+
 /*
 Handle func for DELETE /user/{user_id}
 */
-func (user *userApi) DeleteUser(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) DeleteUser(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -291,17 +481,20 @@ func (user *userApi) DeleteUser(ctx *utils.Context) {
 		return
 	}
 
-	hasIdentity := user.CheckIdentity(ctx, thisUser.EntityID)
-	if !hasIdentity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	if !user.CheckIdentity(ctx, thisUser.EntityID) {
 		return
 	}
-	if user.GetOperatorID(ctx) == userID {
+	if GetOperatorID(ctx) == userID {
 		ctx.BadRequest(myerror.DELETE_USER_SELF, myerror.DELETE_USER_SELF_INFO)
 		return
 	}
 
-	err = service.UserService.DeleteUser(userID)
+	err = user.tokenService.RevokeUserTokens(userID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
+	err = user.userService.DeleteUser(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -313,13 +506,13 @@ func (user *userApi) DeleteUser(ctx *utils.Context) {
 /*
 修改密码权限：超级管理员或实体系统管理员或自己
 */
-func (user *userApi) CheckChangePasswdIdentity(ctx *utils.Context) (*model.User, bool) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) CheckChangePasswdIdentity(ctx *utils.Context) (*model.User, bool) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return nil, false
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return nil, false
@@ -328,10 +521,8 @@ func (user *userApi) CheckChangePasswdIdentity(ctx *utils.Context) (*model.User,
 		return nil, false
 	}
 
-	isSelf := user.GetOperatorID(ctx) == userID
-	hasIdentity := user.CheckIdentity(ctx, thisUser.EntityID)
-	if !hasIdentity && !isSelf {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	isSelf := GetOperatorID(ctx) == userID
+	if !isSelf && !user.CheckIdentity(ctx, thisUser.EntityID) {
 		return nil, false
 	}
 
@@ -341,13 +532,13 @@ func (user *userApi) CheckChangePasswdIdentity(ctx *utils.Context) (*model.User,
 /*
 Handle func for GET /user/info/{user_id}
 */
-func (user *userApi) GetUserInfoByID(ctx *utils.Context) {
-	// userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) GetUserInfoByID(ctx *utils.Context) {
+	// userID, err := user.entityService.GetParamID(ctx, "user_id")
 	// if err != nil {
 	// 	return
 	// }
 
-	// thisUser, err := service.UserService.GetUserByID(userID)
+	// thisUser, err := user.userService.GetUserByID(userID)
 	// if err != nil {
 	// 	ctx.InternalError(err.Error())
 	// 	return
@@ -356,7 +547,7 @@ func (user *userApi) GetUserInfoByID(ctx *utils.Context) {
 	// 	return
 	// }
 
-	// isSelf := user.GetOperatorID(ctx) == userID
+	// isSelf := GetOperatorID(ctx) == userID
 	// hasIdentity := user.CheckIdentity(ctx, thisUser.EntityID)
 	// if !hasIdentity && !isSelf {
 	// 	ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
@@ -383,10 +574,8 @@ func (user *userApi) GetUserInfoByID(ctx *utils.Context) {
 /*
 Handle func for GET /user/list
 */
-func (user *userApi) GetAllUsers(ctx *utils.Context) {
-	systemSuper := service.UserService.SystemSuper(ctx)
-	if !systemSuper {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+func (user *UserApi) GetAllUsers(ctx *utils.Context) {
+	if !user.permissionApi.Check(ctx, define.PERM_SYSTEM_MANAGE, 0, 0) {
 		return
 	}
 
@@ -401,7 +590,7 @@ func (user *userApi) GetAllUsers(ctx *utils.Context) {
 		return
 	}
 
-	userList, count, err := service.UserService.GetAllUsers(uint(page_size), uint(page_num))
+	userList, count, err := user.userService.GetAllUsers(uint(page_size), uint(page_num))
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -425,12 +614,22 @@ func (user *userApi) GetAllUsers(ctx *utils.Context) {
 /*
 Handle func for POST /user/info/:user_id/password
 */
-func (user *userApi) ChangePassword(ctx *utils.Context) {
+func (user *UserApi) ChangePassword(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
 	thisUser, isOK := user.CheckChangePasswdIdentity(ctx)
 	if !isOK {
 		return
 	}
 
+	// a temporary password has to be replaced before its owner may set anyone else's
+	operatorInfo := GetOperatorInfo(ctx)
+	if operatorInfo.PasswordChangeRequired && operatorInfo.UserID != thisUser.ID {
+		ctx.Forbidden(myerror.PASSWORD_CHANGE_REQUIRED, myerror.PASSWORD_CHANGE_REQUIRED_INFO)
+		return
+	}
+
 	var changePasswordReq define.ChangePasswordReq
 	err := ctx.MustBindWith(&changePasswordReq, binding.JSON)
 	if err != nil {
@@ -438,25 +637,127 @@ func (user *userApi) ChangePassword(ctx *utils.Context) {
 		return
 	}
 
-	err = service.UserService.ModifyUserPassword(thisUser.UserName, changePasswordReq.Password)
+	if !user.setPassword(ctx, thisUser, changePasswordReq.Password) {
+		return
+	}
+
+	ctx.Success(nil)
+}
+
+/*
+Set the password under the policy of the user's entity and end their sessions,
+a password set by someone else has to be changed on its owner's next login
+*/
+func (user *UserApi) setPassword(ctx *utils.Context, thisUser *model.User, password string) bool {
+	temporary := GetOperatorID(ctx) != thisUser.ID
+	err := user.passwordService.SetPassword(thisUser, password, temporary)
+	if err != nil {
+		passwordError(ctx, err)
+		return false
+	}
+	err = user.tokenService.RevokeUserTokens(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return false
+	}
+	return true
+}
+
+/*
+A password the policy rejects is the client's fault, anything else is ours
+*/
+func passwordError(ctx *utils.Context, err error) {
+	if errors.Is(err, service.ErrPasswordPolicy) {
+		ctx.BadRequest(myerror.PASSWORD_POLICY_VIOLATED, err.Error())
+	} else if errors.Is(err, service.ErrPasswordReused) {
+		ctx.BadRequest(myerror.PASSWORD_REUSED, myerror.PASSWORD_REUSED_INFO)
+	} else {
+		ctx.InternalError(err.Error())
+	}
+}
+
+/*
+Handle func for PUT /user/info/:user_id/email
+*/
+func (user *UserApi) ChangeEmail(ctx *utils.Context) {
+	if !checkNotImpersonating(ctx) {
+		return
+	}
+	thisUser, isOK := user.CheckChangePasswdIdentity(ctx)
+	if !isOK {
+		return
+	}
+
+	var req define.ChangeEmailReq
+	err := ctx.MustBindWith(&req, binding.JSON)
+	if err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	err = user.userService.ModifyUserEmail(thisUser.ID, req.Email)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
 	}
+	ctx.Success(nil)
+}
 
+/*
+Handle func for POST /user/password/forgot, the answer is the same
+whether or not a reset token was sent
+*/
+func (user *UserApi) ForgotPassword(ctx *utils.Context) {
+	var req define.ForgotPasswordReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	// a failure, even of the delivery, is only logged, telling the caller would tell them the account exists
+	err := user.passwordService.RequestReset(req.UserName, req.Channel, ctx.ClientIP())
+	if err != nil {
+		log.Println(err.Error())
+	}
+	ctx.Success(nil)
+}
+
+/*
+Handle func for POST /user/password/reset
+*/
+func (user *UserApi) ResetPassword(ctx *utils.Context) {
+	var req define.ResetPasswordReq
+	if err := ctx.MustBindWith(&req, binding.JSON); err != nil {
+		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
+		return
+	}
+
+	thisUser, err := user.passwordService.ResetPassword(req.Token, req.Password)
+	if errors.Is(err, service.ErrResetInvalid) {
+		ctx.BadRequest(myerror.PASSWORD_RESET_INVALID, myerror.PASSWORD_RESET_INVALID_INFO)
+		return
+	} else if err != nil {
+		passwordError(ctx, err)
+		return
+	}
+	err = user.tokenService.RevokeUserTokens(thisUser.ID)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		return
+	}
 	ctx.Success(nil)
 }
 
 /*
 Handle func for PATCH /user/info/:user_id/identity
 */
-func (user *userApi) ModifyUserIdentity(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) ModifyUserIdentity(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -472,27 +773,20 @@ func (user *userApi) ModifyUserIdentity(ctx *utils.Context) {
 		return
 	}
 
-	identity := false
-	operatorInfo := user.GetOperatorInfo(ctx)
-	if operatorInfo.UserID == userID {
+	if GetOperatorID(ctx) == userID {
 		ctx.BadRequest(myerror.CANNOT_MODIFY_SELF_IDENTITY, myerror.CANNOT_MODIFY_SELF_IDENTITY_INFO)
 		return
 	}
+	// the flags hand out the built-in roles, making a super admin takes one
 	if req.SystemSuper {
-		identity = operatorInfo.SystemSuper
-	} else {
-		if operatorInfo.SystemSuper {
-			identity = true
-		} else if operatorInfo.EntitySuper {
-			identity = (thisUser.EntityID == operatorInfo.EntityID)
+		if !user.permissionApi.Check(ctx, define.PERM_SYSTEM_MANAGE, 0, 0) {
+			return
 		}
-	}
-	if !identity {
-		ctx.Forbidden(myerror.PERMISSION_DENIED, myerror.PERMISSION_DENIED_INFO)
+	} else if !user.permissionApi.Check(ctx, define.PERM_ROLE_MANAGE, thisUser.EntityID, 0) {
 		return
 	}
 
-	err = service.UserService.ModifyUserIdentityUpdate(userID, &req)
+	err = user.userService.ModifyUserIdentityUpdate(userID, &req)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -504,13 +798,13 @@ func (user *userApi) ModifyUserIdentity(ctx *utils.Context) {
 /*
 Handle func for POST /user/info/:user_id/entity
 */
-func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) ChangeUserEntity(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -525,7 +819,7 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 		ctx.BadRequest(myerror.INVALID_BODY, myerror.INVALID_BODY_INFO)
 	}
 
-	exists, err := service.EntityService.ExistsEntityByID(changeUserEntityReq.EntityID)
+	exists, err := user.entityService.ExistsEntityByID(changeUserEntityReq.EntityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -535,7 +829,7 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 		return
 	}
 
-	Assets, err := service.AssetService.GetAssetByUser(userID)
+	Assets, err := user.assetService.GetAssetByUser(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -545,7 +839,7 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 		return
 	}
 
-	Tasks, err := service.TaskService.GetTasksByUserID(userID)
+	Tasks, err := user.taskService.GetTasksByUserID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -557,7 +851,7 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 		}
 	}
 
-	Asyns, err := service.AsyncService.GetUserAsyncTasks(userID)
+	Asyns, err := user.asyncService.GetUserAsyncTasks(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -569,7 +863,7 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 		}
 	}
 
-	err = service.UserService.ModifyUserEntity(userID, changeUserEntityReq.EntityID)
+	err = user.userService.ModifyUserEntity(userID, changeUserEntityReq.EntityID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -581,13 +875,13 @@ func (user *userApi) ChangeUserEntity(ctx *utils.Context) {
 /*
 Handle func for POST /user/info/{user_id}/department
 */
-func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) ChangeUserDepartment(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -596,7 +890,7 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 		return
 	}
 
-	hasIdentity := DepartmentApi.CheckDepartmentModifyIdentity(ctx, thisUser.EntityID)
+	hasIdentity := user.departmentApi.CheckDepartmentModifyIdentity(ctx, thisUser.EntityID)
 	if !hasIdentity {
 		return
 	}
@@ -608,12 +902,12 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 		return
 	}
 
-	isValid := DepartmentApi.CheckEntityDepartmentValid(ctx, thisUser.EntityID, changeUserDepartmentReq.DepartmentID)
+	isValid := user.departmentApi.CheckEntityDepartmentValid(ctx, thisUser.EntityID, changeUserDepartmentReq.DepartmentID)
 	if !isValid {
 		return
 	}
 
-	Assets, err := service.AssetService.GetAssetByUser(userID)
+	Assets, err := user.assetService.GetAssetByUser(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -623,7 +917,7 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 		return
 	}
 
-	Tasks, err := service.TaskService.GetTasksByUserID(userID)
+	Tasks, err := user.taskService.GetTasksByUserID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -635,7 +929,7 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 		}
 	}
 
-	Asyns, err := service.AsyncService.GetUserAsyncTasks(userID)
+	Asyns, err := user.asyncService.GetUserAsyncTasks(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -647,7 +941,7 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 		}
 	}
 
-	err = service.UserService.ModifyUserDepartment(userID, changeUserDepartmentReq.DepartmentID)
+	err = user.userService.ModifyUserDepartment(userID, changeUserDepartmentReq.DepartmentID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -659,13 +953,13 @@ func (user *userApi) ChangeUserDepartment(ctx *utils.Context) {
 /*
 Handle func for GET /users/{userId}/assets
 */
-func (user *userApi) GetAssetsByUser(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) GetAssetsByUser(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -674,7 +968,7 @@ func (user *userApi) GetAssetsByUser(ctx *utils.Context) {
 		return
 	}
 
-	assets, err := service.AssetService.GetAssetByUser(thisUser.ID)
+	assets, err := user.assetService.GetAssetByUser(thisUser.ID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -686,13 +980,13 @@ func (user *userApi) GetAssetsByUser(ctx *utils.Context) {
 /*
 Handle func for GET /users/{userId}/assets
 */
-func (user *userApi) GetUserUsedAssets(ctx *utils.Context) {
-	userID, err := service.EntityService.GetParamID(ctx, "user_id")
+func (user *UserApi) GetUserUsedAssets(ctx *utils.Context) {
+	userID, err := user.entityService.GetParamID(ctx, "user_id")
 	if err != nil {
 		return
 	}
 
-	thisUser, err := service.UserService.GetUserByID(userID)
+	thisUser, err := user.userService.GetUserByID(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
@@ -704,7 +998,7 @@ func (user *userApi) GetUserUsedAssets(ctx *utils.Context) {
 		return
 	}
 
-	assets, err := service.AssetService.GetUserUsedAssets(userID)
+	assets, err := user.assetService.GetUserUsedAssets(userID)
 	if err != nil {
 		ctx.InternalError(err.Error())
 		return
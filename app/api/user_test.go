@@ -4,10 +4,13 @@ import (
 	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/config"
 	"asset-management/middleware"
 	"asset-management/utils"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -37,32 +40,94 @@ var (
 	}
 )
 
+var (
+	apis         *Apis
+	userDao      dao.UserDaoInterface
+	tokenChecker middleware.TokenChecker
+)
+
 func InitForTest(r *gin.Engine) {
+	InitForTestWithConfig(r, config.ForTest())
+}
+
+func InitForTestWithConfig(r *gin.Engine, conf *config.Config) {
+	utils.Initial(conf.Security)
+	daos := dao.NewDaos(dao.InitForTest())
+	services := service.NewServices(conf, daos)
+	apis = NewApis(conf, services)
+	userDao = daos.User
+	tokenChecker = services.Token
+
 	InitForUser(r)
 	InitForEntity(r)
 	InitForAssetClass(r)
 	InitForAsset(r)
-
-	dao.InitForTest()
+	InitForTask(r)
+	InitForScim(r)
 }
 
 func InitForUser(r *gin.Engine) {
 	group := r.Group("/user")
 
-	group.POST("/register", utils.Handler(UserApi.UserRegister))
-	group.POST("/login", utils.Handler(UserApi.UserLogin))
-	group.GET("/logout", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.UserLogout))
-	group.POST("", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.UserCreate))
-	group.PATCH("/:username", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.ResetContent))
-	group.GET("/:username/lock", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.LockUser))
-	group.GET("/:username/unlock", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.UnlockUser))
-
-	group.GET("/info/:user_id", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.GetUserInfoByID))
-	group.GET("/list", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.GetAllUsers))
-	group.DELETE("/:user_id", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.DeleteUser))
-	group.POST("/info/:user_id/password", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.ChangePassword))
-	group.DELETE("/info/:user_id/entity", utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.CheckSystemSuper()), utils.Handler(UserApi.ChangeUserEntity))
-	group.DELETE("/info/:user_id/department", utils.Handler(middleware.JWTMiddleware()), utils.Handler(UserApi.ChangeUserDepartment))
+	group.POST("/register", utils.Handler(apis.User.UserRegister))
+	group.POST("/login", utils.Handler(apis.User.UserLogin))
+	group.POST("/password/forgot", utils.Handler(apis.User.ForgotPassword))
+	group.POST("/password/reset", utils.Handler(apis.User.ResetPassword))
+	group.POST("/login/two-factor/setup", utils.Handler(apis.TwoFactor.LoginSetup))
+	group.POST("/login/two-factor", utils.Handler(apis.TwoFactor.Login))
+	group.GET("/two-factor", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.TwoFactor.GetStatus))
+	group.POST("/two-factor/disable", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.TwoFactor.Disable))
+	group.GET("/tokens", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.ApiToken.GetPersonalTokens))
+	group.POST("/tokens", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.ApiToken.CreatePersonalToken))
+	group.DELETE("/tokens/:token_id", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.ApiToken.RevokePersonalToken))
+	group.GET("/permissions", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Permission.GetMyPermissions))
+	group.GET("/sessions", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.GetMySessions))
+	group.DELETE("/sessions", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.RevokeMySessions))
+	group.DELETE("/sessions/:session_id", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.RevokeMySession))
+	group.GET("/info/:user_id/sessions", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.GetUserSessions))
+	group.DELETE("/info/:user_id/sessions", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.ForceLogout))
+	group.POST("/info/:user_id/impersonate", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Session.Impersonate))
+	group.GET("/oauth/providers", utils.Handler(apis.Identity.GetLoginProviders))
+	group.POST("/oauth/:provider_id/authorize", utils.Handler(apis.Identity.Authorize))
+	group.POST("/oauth/:provider_id/login", utils.Handler(apis.Identity.Login))
+	group.GET("/oauth/bindings", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Identity.GetMyBindings))
+	group.POST("/oauth/:provider_id/bind/authorize", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Identity.BindAuthorize))
+	group.POST("/oauth/:provider_id/bind", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Identity.Bind))
+	group.DELETE("/oauth/:provider_id/bind", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Identity.Unbind))
+	group.GET("/logout", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.UserLogout))
+	group.POST("", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.UserCreate))
+	group.PATCH("/:username", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.ResetContent))
+	group.GET("/:username/lock", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.LockUser))
+	group.GET("/:username/unlock", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.UnlockUser))
+	group.GET("/login-locks", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(apis.User.GetIPLoginLocks))
+	group.DELETE("/login-locks/:lock_id", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(apis.User.ClearIPLoginLock))
+
+	group.GET("/info/:user_id", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.GetUserInfoByID))
+	group.GET("/list", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.GetAllUsers))
+	group.DELETE("/:user_id", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.DeleteUser))
+	group.POST("/info/:user_id/password", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.ChangePassword))
+	group.PUT("/info/:user_id/email", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.ChangeEmail))
+	group.DELETE("/info/:user_id/entity", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(apis.User.ChangeUserEntity))
+	group.DELETE("/info/:user_id/department", utils.Handler(middleware.JWTMiddleware(tokenChecker)), utils.Handler(apis.User.ChangeUserDepartment))
+}
+
+/*
+Log in with the password a super set for the user and change it to itself,
+lifting the change the user is otherwise held to
+*/
+func changeInitialPassword(r *gin.Engine, username string, password string) {
+	req := GetRequest(http.MethodPost, "/user/login", headerJson, GetJsonBody(define.UserLoginReq{UserName: username, Password: password}))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	data := struct {
+		Data define.UserLoginResponse `json:"data"`
+	}{}
+	json.NewDecoder(res.Result().Body).Decode(&data)
+
+	path := fmt.Sprintf("/user/info/%d/password", data.Data.User.UserID)
+	header := map[string]string{"Content-Type": "application/json", "Authorization": data.Data.Token}
+	req = GetRequest(http.MethodPost, path, header, GetJsonBody(define.ChangePasswordReq{Password: password}))
+	r.ServeHTTP(httptest.NewRecorder(), req)
 }
 
 func GetJsonBody(data interface{}) io.Reader {
@@ -190,7 +255,7 @@ func TestAdmin(t *testing.T) {
 		// EntityID:     nil,
 		// DepartmentID: nil,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -246,6 +311,7 @@ func TestAdmin(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
 	}
+	changeInitialPassword(r, UserCreate.UserName, UserCreate.Password)
 
 	{
 		req := GetRequest(http.MethodPost, "/user", headerJsonToken, GetJsonBody(UserCreate))
@@ -660,7 +726,7 @@ func TestUserInfo(t *testing.T) {
 		// EntityID:     nil,
 		// DepartmentID: nil,
 	}
-	dao.UserDao.Create(admin)
+	userDao.Create(admin)
 
 	UserLogin := define.UserLoginReq{
 		UserName: "admin",
@@ -765,6 +831,27 @@ func TestUserInfo(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
 	}
+	// changing the password revokes every token of the user
+	{
+		req := GetRequest(http.MethodGet, "/user/info/1", headerFormToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	}
+	UserLogin.Password = ChangePassword.Password
+	{
+		req := GetRequest(http.MethodPost, "/user/login", headerJson, GetJsonBody(UserLogin))
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+		b, _ := io.ReadAll(res.Result().Body)
+		data := map[string]interface{}{}
+		json.Unmarshal(b, &data)
+		token = data["data"].(map[string]interface{})["token"].(string)
+		headerJsonToken["Authorization"] = token
+		headerFormToken["Authorization"] = token
+	}
 	{
 		req := GetRequest(http.MethodPost, "/user/info/2/password", headerFormToken, GetJsonBody(ChangePassword))
 		res = httptest.NewRecorder()
@@ -773,6 +860,8 @@ func TestUserInfo(t *testing.T) {
 	}
 
 	// DELETE /info/:user_id/entity
+	err = apis.Entity.entityService.CreateEntity("user_info_entity")
+	assert.Equal(t, nil, err, "service error")
 	ChangeEntity := define.ChangeUserEntityReq{
 		EntityID: 1,
 	}
@@ -788,6 +877,26 @@ func TestUserInfo(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
 	}
+	// moving a user to another entity outdates the tokens they hold
+	{
+		req := GetRequest(http.MethodGet, "/user/info/1", headerFormToken, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "response failed")
+	}
+	{
+		req := GetRequest(http.MethodPost, "/user/login", headerJson, GetJsonBody(UserLogin))
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "response failed")
+
+		b, _ := io.ReadAll(res.Result().Body)
+		data := map[string]interface{}{}
+		json.Unmarshal(b, &data)
+		token = data["data"].(map[string]interface{})["token"].(string)
+		headerJsonToken["Authorization"] = token
+		headerFormToken["Authorization"] = token
+	}
 	{
 		req := GetRequest(http.MethodDelete, "/user/info/2/entity", headerFormToken, GetJsonBody(ChangeEntity))
 		res = httptest.NewRecorder()
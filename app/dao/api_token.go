@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ApiTokenDaoInterface interface {
+	Create(newToken *model.ApiToken) error
+	Update(id uint, data map[string]interface{}) error
+	GetTokenByID(id uint) (*model.ApiToken, error)
+	GetTokenByHash(hash string) (*model.ApiToken, error)
+	GetUserTokens(userID uint) ([]*model.ApiToken, error)
+	GetEntityKeys(entityID uint) ([]*model.ApiToken, error)
+	RevokeToken(id uint) error
+	TouchToken(id uint, usedAt time.Time) error
+}
+
+type apiTokenDao struct {
+	db *gorm.DB
+}
+
+func NewApiTokenDao(db *gorm.DB) ApiTokenDaoInterface {
+	return &apiTokenDao{db: db}
+}
+
+func (apiToken *apiTokenDao) Create(newToken *model.ApiToken) error {
+	result := apiToken.db.Model(&model.ApiToken{}).Create(newToken)
+	return utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) Update(id uint, data map[string]interface{}) error {
+	result := apiToken.db.Model(&model.ApiToken{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) GetTokenByID(id uint) (*model.ApiToken, error) {
+	ret := &model.ApiToken{}
+	result := apiToken.db.Model(&model.ApiToken{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) GetTokenByHash(hash string) (*model.ApiToken, error) {
+	ret := &model.ApiToken{}
+	result := apiToken.db.Model(&model.ApiToken{}).Where("token_hash = ?", hash).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) GetUserTokens(userID uint) ([]*model.ApiToken, error) {
+	var tokenList []*model.ApiToken
+	result := apiToken.db.Model(&model.ApiToken{}).Where("user_id = ? and kind = ?", userID, model.API_TOKEN_PERSONAL).Find(&tokenList)
+	return tokenList, utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) GetEntityKeys(entityID uint) ([]*model.ApiToken, error) {
+	var tokenList []*model.ApiToken
+	result := apiToken.db.Model(&model.ApiToken{}).Where("entity_id = ? and kind = ?", entityID, model.API_TOKEN_ENTITY).Find(&tokenList)
+	return tokenList, utils.DBError(result)
+}
+
+func (apiToken *apiTokenDao) RevokeToken(id uint) error {
+	return apiToken.Update(id, map[string]interface{}{
+		"revoked": true,
+	})
+}
+
+func (apiToken *apiTokenDao) TouchToken(id uint, usedAt time.Time) error {
+	return apiToken.Update(id, map[string]interface{}{
+		"last_used_at": usedAt,
+	})
+}
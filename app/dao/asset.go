@@ -11,65 +11,103 @@ import (
 	"gorm.io/gorm"
 )
 
-type assetDao struct {
+type AssetDaoInterface interface {
+	Create(newAsset model.Asset) error
+	CreateAndGetID(newAsset model.Asset) (uint, error)
+	Update(id uint, data map[string]interface{}) error
+	UpdateByStruct(id uint, data model.Asset) error
+	AllUpdate(ids []uint, data map[string]interface{}) error
+	Delete(id []uint) error
+	SaveAsset(thisAsset *model.Asset) error
+	GetAllAssets(offset int, limit int) (assetList []*model.Asset, err error)
+	GetAllAssetsCount() (count int64, err error)
+	GetAssetByName(name string) (list []model.Asset, err error)
+	GetAssetByID(id uint) (*model.Asset, error)
+	AssetCount() (count int64, err error)
+	ModifyAssetPrice(id uint, price decimal.Decimal) error
+	ModifyAssetDescription(id uint, description string) error
+	ModifyAssetPosition(id uint, position string) error
+	ModifyAssetNum(id uint, num int) error
+	GetSubAsset(id uint, offset int, limit int) (assets []*model.Asset, count int64, err error)
+	GetAssetDirectDepartment(departmentID uint, offset int, limit int) (assets []*model.Asset, count int64, err error)
+	GetParentAsset(id uint) (ParentAsset *model.Asset, err error)
+	ModifyParentAsset(ChildID uint, ParentID uint) error
+	GetAssetUser(id uint) (user model.User, err error)
+	ModifyAssetUser(AssetID uint, Username string) error
+	GetDirectAssetsByUser(userID uint) (assets []*model.Asset, err error)
+	GetUserAssetsInUsed(userID uint) (assetList []*model.Asset, err error)
+	GetAssetClass(id uint) (class model.AssetClass, err error)
+	ModifyAssetClass(AssetID uint, ClassID uint) error
+	GetAssetListByClassID(assetClassID uint) ([]*model.Asset, error)
+	GetSubAssetsByParents(ids []uint) (assets []*model.Asset, err error)
+	GetDepartmentAssetsByIDs(ids []uint, departmentID uint) (assets []*model.Asset, err error)
+	GetUserAssetsByIDs(ids []uint, userID uint) (assets []*model.Asset, err error)
+	GetDepartmentIdleAssetsByIDs(ids []uint, departmentID uint) (assets []*model.Asset, err error)
+	GetUserMaintainAssets(userID uint) (assetList []*model.Asset, err error)
+	CheckAssetPropertyExist(assetID uint, key string) (bool, error)
+	SetAssetProperty(assetID uint, key string, value string) error
+	GetAssetProperty(assetID uint) (*model.Asset, error)
+	GetAssetTask(assetID uint) ([]*model.Task, error)
+	SearchDepartmentAsset(departmentID uint, req *define.SearchAssetReq, offset int, limit int) (assetList []*model.Asset, count int64, err error)
+	GetDepartmentAssetCount(departmentID uint) (count int64, err error)
+	GetDepartmentWarnAsset(departmentID uint) (assetList []*model.Asset, err error)
+	GetDepartmentAssetBasicList(departmentID uint) (assetList []*model.Asset, err error)
 }
 
-var AssetDao *assetDao
-
-func newAssetDao() *assetDao {
-	return &assetDao{}
+type assetDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	AssetDao = newAssetDao()
+func NewAssetDao(db *gorm.DB) AssetDaoInterface {
+	return &assetDao{db: db}
 }
 
 func (asset *assetDao) Create(newAsset model.Asset) error {
-	result := db.Model(&model.Asset{}).Create(&newAsset)
+	result := asset.db.Model(&model.Asset{}).Create(&newAsset)
 	return utils.DBError(result)
 }
 
 func (asset *assetDao) CreateAndGetID(newAsset model.Asset) (uint, error) {
-	result := db.Model(&model.Asset{}).Create(&newAsset)
+	result := asset.db.Model(&model.Asset{}).Create(&newAsset)
 	return newAsset.ID, utils.DBError(result)
 }
 
 func (asset *assetDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.Asset{}).Where("id = ?", id).Updates(data)
+	result := asset.db.Model(&model.Asset{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (asset *assetDao) UpdateByStruct(id uint, data model.Asset) error {
-	result := db.Model(model.Asset{}).Where("id = ?", id).Updates(data)
+	result := asset.db.Model(model.Asset{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (asset *assetDao) AllUpdate(ids []uint, data map[string]interface{}) error {
-	result := db.Model(&model.Asset{}).Where("id IN (?)", ids).Updates(data)
+	result := asset.db.Model(&model.Asset{}).Where("id IN (?)", ids).Updates(data)
 	return utils.DBError(result)
 }
 
 func (asset *assetDao) Delete(id []uint) error {
-	result := db.Model(&model.Asset{}).Where("id in (?)", id).Delete(&model.Asset{})
+	result := asset.db.Model(&model.Asset{}).Where("id in (?)", id).Delete(&model.Asset{})
 	return utils.DBError(result)
 }
 
 func (asset *assetDao) SaveAsset(thisAsset *model.Asset) error {
-	result := db.Save(thisAsset)
+	result := asset.db.Save(thisAsset)
 	return utils.DBError(result)
 }
 
 // func (asset *assetDao) AllAsset() (list []model.Asset, err error) {
-// 	result := db.Model(&model.Asset{}).Find(&list)
+// 	result := asset.db.Model(&model.Asset{}).Find(&list)
 // 	for _, asset := range list {
 // 		user := &model.User{}
-// 		err = db.Model(&asset).Association("User").Find(&user)
+// 		err = asset.db.Model(&asset).Association("User").Find(&user)
 // 		if err != nil {
 // 			return
 // 		}
 // 		asset.UserID = user.ID
 // 		asset_class := &model.AssetClass{}
-// 		err = db.Model(&asset).Association("Class").Find(&asset_class)
+// 		err = asset.db.Model(&asset).Association("Class").Find(&asset_class)
 // 		if err != nil {
 // 			return
 // 		}
@@ -80,10 +118,10 @@ func (asset *assetDao) SaveAsset(thisAsset *model.Asset) error {
 // }
 
 /*
-Note: This function doesn't preload any association function
+Note: This function only preloads the class, which depreciation needs
 */
 func (asset *assetDao) GetAllAssets(offset int, limit int) (assetList []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Offset(offset).Limit(limit).Find(&assetList)
+	result := asset.db.Model(&model.Asset{}).Preload("Class").Offset(offset).Limit(limit).Find(&assetList)
 
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
@@ -93,29 +131,29 @@ func (asset *assetDao) GetAllAssets(offset int, limit int) (assetList []*model.A
 }
 
 func (asset *assetDao) GetAllAssetsCount() (count int64, err error) {
-	result := db.Model(&model.Asset{}).Count(&count)
+	result := asset.db.Model(&model.Asset{}).Count(&count)
 	err = result.Error
 	return
 }
 
 func (asset *assetDao) GetAssetByName(name string) (list []model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Where("name = ?", name).Find(&list)
+	result := asset.db.Model(&model.Asset{}).Where("name = ?", name).Find(&list)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
-	for _, asset := range list {
+	for _, thisAsset := range list {
 		user := &model.User{}
-		err = db.Model(&asset).Association("User").Find(&user)
+		err = asset.db.Model(&thisAsset).Association("User").Find(&user)
 		if err != nil {
 			return
 		}
-		asset.UserID = user.ID
+		thisAsset.UserID = user.ID
 		asset_class := &model.AssetClass{}
-		err = db.Model(&asset).Association("Class").Find(&asset_class)
+		err = asset.db.Model(&thisAsset).Association("Class").Find(&asset_class)
 		if err != nil {
 			return
 		}
-		asset.ClassID = asset_class.ID
+		thisAsset.ClassID = asset_class.ID
 	}
 	err = utils.DBError(result)
 	return
@@ -123,20 +161,20 @@ func (asset *assetDao) GetAssetByName(name string) (list []model.Asset, err erro
 
 func (asset *assetDao) GetAssetByID(id uint) (*model.Asset, error) {
 	ret := &model.Asset{}
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("ID = ?", id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
 	// user := &model.User{}
-	// err := db.Model(&ret).Association("User").Find(&user)
+	// err := asset.db.Model(&ret).Association("User").Find(&user)
 	// if err != nil {
 	// 	return nil, err
 	// }
 	// ret.User = *user
 	// asset_class := &model.AssetClass{}
-	// err = db.Model(&ret).Association("Class").Find(&asset_class)
+	// err = asset.db.Model(&ret).Association("Class").Find(&asset_class)
 	// if err != nil {
 	// 	return nil, err
 	// }
@@ -145,7 +183,7 @@ func (asset *assetDao) GetAssetByID(id uint) (*model.Asset, error) {
 }
 
 func (asset *assetDao) AssetCount() (count int64, err error) {
-	result := db.Model(&model.Asset{}).Count(&count)
+	result := asset.db.Model(&model.Asset{}).Count(&count)
 	err = utils.DBError(result)
 	return
 }
@@ -208,39 +246,18 @@ func (asset *assetDao) ModifyAssetNum(id uint, num int) error {
 	return err
 }
 
-func (asset *assetDao) ModifyAssetState(id uint, state uint) error {
-	thisAsset, err := asset.GetAssetByID(id)
-	if err != nil {
-		return err
-	}
-	if thisAsset == nil {
-		return errors.New(asset_not_exist)
-	}
-	err = asset.Update(thisAsset.ID, map[string]interface{}{
-		"State": state,
-	})
-	return err
-}
-
-func (asset *assetDao) ExpireAsset(ids []uint) error {
-	return asset.AllUpdate(ids, map[string]interface{}{
-		"state":     3,
-		"net_worth": decimal.NewFromFloat(0),
-	})
-}
-
 // asset and asset
 func (asset *assetDao) GetSubAsset(id uint, offset int, limit int) (assets []*model.Asset, count int64, err error) {
-	err = utils.DBError(db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	err = utils.DBError(asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
-		Where("parent_id = ?", id).Count(&count).Offset(offset).Limit(limit).Find(&assets))
+		Where("parent_id = ? and state <> ?", id, define.ASSET_DELETED).Count(&count).Offset(offset).Limit(limit).Find(&assets))
 	return
 }
 
 func (asset *assetDao) GetAssetDirectDepartment(departmentID uint, offset int, limit int) (assets []*model.Asset, count int64, err error) {
-	err = utils.DBError(db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	err = utils.DBError(asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
-		Where("department_id = ? and parent_id IS NULL", departmentID).Count(&count).Offset(offset).Limit(limit).Find(&assets))
+		Where("department_id = ? and parent_id IS NULL and state <> ?", departmentID, define.ASSET_DELETED).Count(&count).Offset(offset).Limit(limit).Find(&assets))
 	return
 }
 
@@ -249,7 +266,7 @@ func (asset *assetDao) GetParentAsset(id uint) (ParentAsset *model.Asset, err er
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&query_asset).Where("id = ?", query_asset.ParentID).Find(&ParentAsset))
+	err = utils.DBError(asset.db.Model(&query_asset).Where("id = ?", query_asset.ParentID).Find(&ParentAsset))
 	return
 }
 
@@ -263,7 +280,7 @@ func (asset *assetDao) ModifyParentAsset(ChildID uint, ParentID uint) error {
 		return err
 	}
 	child_asset.ParentID = parent_asset.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_asset))
+	return utils.DBError(asset.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_asset))
 }
 
 // asset and user
@@ -272,7 +289,7 @@ func (asset *assetDao) GetAssetUser(id uint) (user model.User, err error) {
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&model.User{}).Where("id = ?", query_asset.UserID).Find(&user))
+	err = utils.DBError(asset.db.Model(&model.User{}).Where("id = ?", query_asset.UserID).Find(&user))
 	return
 }
 
@@ -281,29 +298,29 @@ func (asset *assetDao) ModifyAssetUser(AssetID uint, Username string) error {
 	if err != nil {
 		return err
 	}
-	target_user, err := UserDao.GetUserByName(Username)
+	target_user, err := NewUserDao(asset.db).GetUserByName(Username)
 	if err != nil {
 		return err
 	}
 	query_asset.UserID = target_user.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
+	return utils.DBError(asset.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
 }
 
 func (asset *assetDao) GetDirectAssetsByUser(userID uint) (assets []*model.Asset, err error) {
-	/*result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	/*result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 	Preload("Department").Preload("Class").Preload("Maintainer").
 	Where("user_id = ? and parent_id = 0", userID).Find(&assets)*/
-	//db = db.Debug()
-	sub_query := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	//db = asset.db.Debug()
+	sub_query := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("user_id = ?", userID).Select("id")
 	var all_assets []uint
 	sub_query.Find(&all_assets)
 	//log.Print("all: ", len(all_assets), all_assets[0], all_assets[1])
 
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
-		Where("user_id = ?", userID).Where("parent_id not in (?) or parent_id is null", all_assets).Find(&assets)
+		Where("user_id = ? and state <> ?", userID, define.ASSET_DELETED).Where("parent_id not in (?) or parent_id is null", all_assets).Find(&assets)
 	//log.Print("fliter: ", len(assets) /*assets[0].ParentID, assets[1].ParentID*/)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
@@ -313,7 +330,7 @@ func (asset *assetDao) GetDirectAssetsByUser(userID uint) (assets []*model.Asset
 }
 
 func (asset *assetDao) GetUserAssetsInUsed(userID uint) (assetList []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("user_id = ? and state > ? and state < ?", userID, 0, 3).Find(&assetList)
 
@@ -330,7 +347,7 @@ func (asset *assetDao) GetAssetClass(id uint) (class model.AssetClass, err error
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&model.AssetClass{}).Where("id = ?", query_asset.ClassID).Find(&class))
+	err = utils.DBError(asset.db.Model(&model.AssetClass{}).Where("id = ?", query_asset.ClassID).Find(&class))
 	return
 }
 
@@ -341,7 +358,7 @@ func (asset *assetDao) ModifyAssetClass(AssetID uint, ClassID uint) error {
 	if err != nil {
 		return err
 	}
-	target_class, err := AssetClassDao.GetAssetClassByID(ClassID)
+	target_class, err := NewAssetClassDao(asset.db).GetAssetClassByID(ClassID)
 	if err != nil {
 		return err
 	}
@@ -349,19 +366,19 @@ func (asset *assetDao) ModifyAssetClass(AssetID uint, ClassID uint) error {
 		return errors.New(type_not_match)
 	}
 	query_asset.ClassID = target_class.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
+	return utils.DBError(asset.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
 }
 
 func (asset *assetDao) GetAssetListByClassID(assetClassID uint) ([]*model.Asset, error) {
 	var assetList []*model.Asset
-	err := utils.DBError(db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	err := utils.DBError(asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
-		Where("class_id = ?", assetClassID).Find(&assetList))
+		Where("class_id = ? and state <> ?", assetClassID, define.ASSET_DELETED).Find(&assetList))
 	return assetList, err
 }
 
 func (asset *assetDao) GetSubAssetsByParents(ids []uint) (assets []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("parent_id IN (?)", ids).Find(&assets)
 	if result.Error == gorm.ErrRecordNotFound {
@@ -372,7 +389,7 @@ func (asset *assetDao) GetSubAssetsByParents(ids []uint) (assets []*model.Asset,
 }
 
 func (asset *assetDao) GetDepartmentAssetsByIDs(ids []uint, departmentID uint) (assets []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("id IN (?) and department_id = ? and state <= ?", ids, departmentID, 2).Find(&assets)
 
@@ -384,7 +401,7 @@ func (asset *assetDao) GetDepartmentAssetsByIDs(ids []uint, departmentID uint) (
 }
 
 func (asset *assetDao) GetUserAssetsByIDs(ids []uint, userID uint) (assets []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("id IN (?) and user_id = ? and state = ?", ids, userID, 1).Find(&assets)
 
@@ -396,7 +413,7 @@ func (asset *assetDao) GetUserAssetsByIDs(ids []uint, userID uint) (assets []*mo
 }
 
 func (asset *assetDao) GetDepartmentIdleAssetsByIDs(ids []uint, departmentID uint) (assets []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
 		Where("id IN (?) and department_id = ? and state = ?", ids, departmentID, 0).Find(&assets)
 
@@ -407,18 +424,10 @@ func (asset *assetDao) GetDepartmentIdleAssetsByIDs(ids []uint, departmentID uin
 	return
 }
 
-func (asset *assetDao) ModifyAssetsUserAndState(ids []uint, userID uint, state uint) error {
-	result := db.Model(&model.Asset{}).Where("id IN (?)", ids).Updates(map[string]interface{}{
-		"user_id": userID,
-		"state":   state,
-	})
-	return utils.DBError(result)
-}
-
 func (asset *assetDao) GetUserMaintainAssets(userID uint) (assetList []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Preload("Parent").Preload("User").
+	result := asset.db.Model(&model.Asset{}).Preload("Parent").Preload("User").
 		Preload("Department").Preload("Class").Preload("Maintainer").
-		Where("maintainer_id = ?", userID).Find(&assetList)
+		Where("maintainer_id = ? and state <> ?", userID, define.ASSET_DELETED).Find(&assetList)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -426,26 +435,9 @@ func (asset *assetDao) GetUserMaintainAssets(userID uint) (assetList []*model.As
 	return
 }
 
-func (asset *assetDao) ModifyAssetMaintainerAndState(assetIDs []uint, maintainerID uint) error {
-	var result *gorm.DB
-	if maintainerID == 0 {
-		result = db.Model(&model.Asset{}).Where("id IN (?)", assetIDs).Updates(map[string]interface{}{
-			"maintainer_id": gorm.Expr("NULL"),
-			"state":         1,
-		})
-	} else {
-		result = db.Model(&model.Asset{}).Where("id IN (?)", assetIDs).Updates(map[string]interface{}{
-			"maintainer_id": maintainerID,
-			"state":         2,
-		})
-	}
-	err := utils.DBError(result)
-	return err
-}
-
 func (asset *assetDao) CheckAssetPropertyExist(assetID uint, key string) (bool, error) {
 	var thisAsset *model.Asset
-	result := db.Model(&model.Asset{}).Where("id = ?", assetID).
+	result := asset.db.Model(&model.Asset{}).Where("id = ?", assetID).
 		First(&thisAsset, datatypes.JSONQuery("property").HasKey(key))
 
 	if result.Error == gorm.ErrRecordNotFound {
@@ -458,7 +450,7 @@ func (asset *assetDao) CheckAssetPropertyExist(assetID uint, key string) (bool,
 }
 
 func (asset *assetDao) SetAssetProperty(assetID uint, key string, value string) error {
-	result := db.Model(&model.Asset{}).Where("id = ?", assetID).
+	result := asset.db.Model(&model.Asset{}).Where("id = ?", assetID).
 		UpdateColumn("property", datatypes.JSONSet("property").Set(key, value))
 
 	return utils.DBError(result)
@@ -467,7 +459,7 @@ func (asset *assetDao) SetAssetProperty(assetID uint, key string, value string)
 func (asset *assetDao) GetAssetProperty(assetID uint) (*model.Asset, error) {
 	var thisAsset *model.Asset
 
-	result := db.Model(&model.Asset{}).Where("id = ?", assetID).First(&thisAsset)
+	result := asset.db.Model(&model.Asset{}).Where("id = ?", assetID).First(&thisAsset)
 
 	return thisAsset, utils.DBError(result)
 }
@@ -476,7 +468,7 @@ func (asset *assetDao) GetAssetTask(assetID uint) ([]*model.Task, error) {
 	var taskList []*model.Task
 	var thisAsset *model.Asset
 
-	result := db.Model(&model.Asset{}).Preload("TaskList.User").Preload("TaskList.Target").Where("id = ?", assetID).First(&thisAsset)
+	result := asset.db.Model(&model.Asset{}).Preload("TaskList.User").Preload("TaskList.Target").Where("id = ?", assetID).First(&thisAsset)
 
 	taskList = thisAsset.TaskList
 
@@ -484,7 +476,8 @@ func (asset *assetDao) GetAssetTask(assetID uint) ([]*model.Task, error) {
 }
 
 func (asset *assetDao) SearchDepartmentAsset(departmentID uint, req *define.SearchAssetReq, offset int, limit int) (assetList []*model.Asset, count int64, err error) {
-	result := db.Model(&model.Asset{}).Where("department_id = ?", departmentID)
+	// deleted assets are only listed in the recycle bin
+	result := asset.db.Model(&model.Asset{}).Where("department_id = ? and state <> ?", departmentID, define.ASSET_DELETED)
 
 	if req.Name != "" {
 		result = result.Where("name LIKE ?", req.Name)
@@ -533,19 +526,19 @@ func (asset *assetDao) SearchDepartmentAsset(departmentID uint, req *define.Sear
 }
 
 func (asset *assetDao) GetDepartmentAssetCount(departmentID uint) (count int64, err error) {
-	result := db.Model(&model.Asset{}).Where("department_id = ? and state <= ?", departmentID, 2).Count(&count)
+	result := asset.db.Model(&model.Asset{}).Where("department_id = ? and state <= ?", departmentID, 2).Count(&count)
 	err = utils.DBError(result)
 	return
 }
 
 func (asset *assetDao) GetDepartmentWarnAsset(departmentID uint) (assetList []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Where("department_id = ? and state <= ? and warn = ?", departmentID, 2, true).Find(&assetList)
+	result := asset.db.Model(&model.Asset{}).Where("department_id = ? and state <= ? and warn = ?", departmentID, 2, true).Find(&assetList)
 	err = utils.DBError(result)
 	return
 }
 
 func (asset *assetDao) GetDepartmentAssetBasicList(departmentID uint) (assetList []*model.Asset, err error) {
-	result := db.Model(&model.Asset{}).Where("department_id = ? and state <= ?", departmentID, 2).Find(&assetList)
+	result := asset.db.Model(&model.Asset{}).Where("department_id = ? and state <= ?", departmentID, 2).Find(&assetList)
 	err = utils.DBError(result)
 	return
 }
@@ -7,52 +7,62 @@ import (
 	"gorm.io/gorm"
 )
 
-type assetClassDao struct {
+type AssetClassDaoInterface interface {
+	Create(newAssetClass model.AssetClass) error
+	Update(id uint, data map[string]interface{}) error
+	UpdateByStruct(id uint, data model.AssetClass) error
+	AllUpdate(ids []uint, data map[string]interface{}) error
+	Delete(id []uint) error
+	GetAssetClassByID(id uint) (*model.AssetClass, error)
+	GetDepartmentDirectClass(departmentID uint) (assetClasses []*model.AssetClass, err error)
+	GetSubAssetClass(id uint) (assetClasses []*model.AssetClass, err error)
+	GetParentAssetClass(id uint) (ParentAssetClass *model.AssetClass, err error)
+	ModifyParentAssetClass(ChildID uint, ParentID uint) error
+	GetAssetClassDepartment(id uint) (department model.Department, err error)
+	ModifyAssetClassDepartment(AssetClassID uint, DepartmentID uint) error
 }
 
-var AssetClassDao *assetClassDao
-
-func newAssetClassDao() *assetClassDao {
-	return &assetClassDao{}
+type assetClassDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	AssetClassDao = newAssetClassDao()
+func NewAssetClassDao(db *gorm.DB) AssetClassDaoInterface {
+	return &assetClassDao{db: db}
 }
 
 func (assetclass *assetClassDao) Create(newAssetClass model.AssetClass) error {
-	result := db.Model(&model.AssetClass{}).Create(&newAssetClass)
+	result := assetclass.db.Model(&model.AssetClass{}).Create(&newAssetClass)
 	return utils.DBError(result)
 }
 
 func (assetclass *assetClassDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.AssetClass{}).Where("id = ?", id).Updates(data)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (assetclass *assetClassDao) UpdateByStruct(id uint, data model.AssetClass) error {
-	result := db.Model(&model.AssetClass{}).Where("id = ?", id).Updates(data)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (assetclass *assetClassDao) AllUpdate(ids []uint, data map[string]interface{}) error {
-	result := db.Model(&model.AssetClass{}).Where("id IN (?)", ids).Updates(data)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("id IN (?)", ids).Updates(data)
 	return utils.DBError(result)
 }
 
 func (assetclass *assetClassDao) Delete(id []uint) error {
-	result := db.Model(&model.AssetClass{}).Where("id in (?)", id).Delete(&model.AssetClass{})
+	result := assetclass.db.Model(&model.AssetClass{}).Where("id in (?)", id).Delete(&model.AssetClass{})
 	return utils.DBError(result)
 }
 
 func (assetclass *assetClassDao) GetAssetClassByID(id uint) (*model.AssetClass, error) {
 	ret := &model.AssetClass{}
-	result := db.Model(&model.AssetClass{}).Where("id = ?", id).First(ret)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("id = ?", id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
 	department := &model.Department{}
-	err := db.Model(&ret).Association("Department").Find(&department)
+	err := assetclass.db.Model(&ret).Association("Department").Find(&department)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +71,7 @@ func (assetclass *assetClassDao) GetAssetClassByID(id uint) (*model.AssetClass,
 }
 
 func (assetclass *assetClassDao) GetDepartmentDirectClass(departmentID uint) (assetClasses []*model.AssetClass, err error) {
-	result := db.Model(&model.AssetClass{}).Where("department_id = ? and parent_id IS NULL", departmentID).Find(&assetClasses)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("department_id = ? and parent_id IS NULL", departmentID).Find(&assetClasses)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 	} else {
@@ -72,7 +82,7 @@ func (assetclass *assetClassDao) GetDepartmentDirectClass(departmentID uint) (as
 
 // assetclass and assetclass
 func (assetclass *assetClassDao) GetSubAssetClass(id uint) (assetClasses []*model.AssetClass, err error) {
-	result := db.Model(&model.AssetClass{}).Where("parent_id = ?", id).Find(&assetClasses)
+	result := assetclass.db.Model(&model.AssetClass{}).Where("parent_id = ?", id).Find(&assetClasses)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 	} else {
@@ -86,7 +96,7 @@ func (assetclass *assetClassDao) GetParentAssetClass(id uint) (ParentAssetClass
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&query_asset).Where("id = ?", query_asset.ParentID).Find(&ParentAssetClass))
+	err = utils.DBError(assetclass.db.Model(&query_asset).Where("id = ?", query_asset.ParentID).Find(&ParentAssetClass))
 	return
 }
 
@@ -100,7 +110,7 @@ func (assetclass *assetClassDao) ModifyParentAssetClass(ChildID uint, ParentID u
 		return err
 	}
 	child_asset.ParentID = parent_asset.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_asset))
+	return utils.DBError(assetclass.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_asset))
 }
 
 // assetclass and entity
@@ -118,10 +128,10 @@ func (assetclass *assetClassDao) ModifyAssetClassDepartment(AssetClassID uint, D
 	if err != nil {
 		return err
 	}
-	target_department, err := DepartmentDao.GetDepartmentByID(DepartmentID)
+	target_department, err := NewDepartmentDao(assetclass.db).GetDepartmentByID(DepartmentID)
 	if err != nil {
 		return err
 	}
 	query_asset.DepartmentID = target_department.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
+	return utils.DBError(assetclass.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_asset))
 }
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var asset_not_deleted = "asset is not in the recycle bin"
+
+type AssetRecycleDaoInterface interface {
+	GetDepartmentRecycleBin(departmentID uint, offset int, limit int) ([]*model.Asset, int64, error)
+	GetExpiredAssets(before time.Time, afterID uint, limit int) ([]*model.Asset, error)
+	GetAssetTaskIDs(assetID uint) ([]uint, error)
+	CreateArchive(archive *model.AssetArchive) error
+	Purge(assetID uint) error
+}
+
+type assetRecycleDao struct {
+	db *gorm.DB
+}
+
+func NewAssetRecycleDao(db *gorm.DB) AssetRecycleDaoInterface {
+	return &assetRecycleDao{db: db}
+}
+
+/*
+Most recently deleted first
+*/
+func (recycle *assetRecycleDao) GetDepartmentRecycleBin(departmentID uint, offset int, limit int) (assetList []*model.Asset, count int64, err error) {
+	result := recycle.db.Model(&model.Asset{}).Preload("User").Preload("Class").
+		Where("department_id = ? and state = ?", departmentID, define.ASSET_DELETED).
+		// portions merged back into their stock hold nothing, they wait for the purge only
+		Where("stock_id IS NULL or stock_id = 0 or number <> 0").
+		Count(&count).Order("deleted_at desc, id desc").Offset(offset).Limit(limit).Find(&assetList)
+	err = utils.DBError(result)
+	return
+}
+
+/*
+Deleted before the given time, in id order from afterID on so a caller can walk them in batches
+*/
+func (recycle *assetRecycleDao) GetExpiredAssets(before time.Time, afterID uint, limit int) ([]*model.Asset, error) {
+	var assetList []*model.Asset
+	result := recycle.db.Model(&model.Asset{}).
+		Where("state = ? and deleted_at < ? and id > ?", define.ASSET_DELETED, before, afterID).
+		Order("id").Limit(limit).Find(&assetList)
+	return assetList, utils.DBError(result)
+}
+
+func (recycle *assetRecycleDao) GetAssetTaskIDs(assetID uint) ([]uint, error) {
+	var taskIDs []uint
+	result := recycle.db.Table("task_assets").Where("asset_id = ?", assetID).Order("task_id").Pluck("task_id", &taskIDs)
+	return taskIDs, utils.DBError(result)
+}
+
+func (recycle *assetRecycleDao) CreateArchive(archive *model.AssetArchive) error {
+	result := recycle.db.Model(&model.AssetArchive{}).Create(archive)
+	return utils.DBError(result)
+}
+
+/*
+Remove the asset with its task links and quantities, state history and versions, only a deleted asset is touched
+*/
+func (recycle *assetRecycleDao) Purge(assetID uint) error {
+	result := recycle.db.Exec("DELETE FROM task_assets WHERE asset_id = ?", assetID)
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	for _, table := range []interface{}{&model.AssetStateHistory{}, &model.AssetVersion{}, &model.TaskQuantity{}} {
+		result = recycle.db.Where("asset_id = ?", assetID).Delete(table)
+		if err := utils.DBError(result); err != nil {
+			return err
+		}
+	}
+	result = recycle.db.Where("id = ? and state = ?", assetID, define.ASSET_DELETED).Delete(&model.Asset{})
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	// restored meanwhile, the caller's transaction must roll back what was removed above
+	if result.RowsAffected == 0 {
+		return errors.New(asset_not_deleted)
+	}
+	return nil
+}
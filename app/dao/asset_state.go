@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type AssetStateDaoInterface interface {
+	GetAssetsByIDs(ids []uint) ([]*model.Asset, error)
+	UpdateState(ids []uint, fromStates []uint, data map[string]interface{}) (int64, error)
+	TakeQuantity(id uint, from int, quantity int, price decimal.Decimal, netWorth decimal.Decimal) (int64, error)
+	AddQuantity(id uint, quantity int, price decimal.Decimal, netWorth decimal.Decimal) (int64, error)
+	CountPortions(stockID uint) (int64, error)
+	CreateHistory(records []*model.AssetStateHistory) error
+	GetHistory(assetID uint) ([]*model.AssetStateHistory, error)
+}
+
+type assetStateDao struct {
+	db *gorm.DB
+}
+
+func NewAssetStateDao(db *gorm.DB) AssetStateDaoInterface {
+	return &assetStateDao{db: db}
+}
+
+func (state *assetStateDao) GetAssetsByIDs(ids []uint) ([]*model.Asset, error) {
+	var assetList []*model.Asset
+	result := state.db.Model(&model.Asset{}).Where("id IN (?)", ids).Order("id").Find(&assetList)
+	return assetList, utils.DBError(result)
+}
+
+/*
+Only rows still in one of fromStates are touched, the caller compares the
+affected count to catch a concurrent change
+*/
+func (state *assetStateDao) UpdateState(ids []uint, fromStates []uint, data map[string]interface{}) (int64, error) {
+	result := state.db.Model(&model.Asset{}).Where("id IN (?) and state IN (?)", ids, fromStates).Updates(data)
+	return result.RowsAffected, utils.DBError(result)
+}
+
+/*
+Take units off a quantity-type asset that still holds from units, nothing is taken
+if another request changed the count meanwhile
+*/
+func (state *assetStateDao) TakeQuantity(id uint, from int, quantity int, price decimal.Decimal, netWorth decimal.Decimal) (int64, error) {
+	result := state.db.Model(&model.Asset{}).Where("id = ? and number = ? and number > ?", id, from, quantity).Updates(map[string]interface{}{
+		"number":    gorm.Expr("number - ?", quantity),
+		"price":     gorm.Expr("price - ?", price),
+		"net_worth": gorm.Expr("net_worth - ?", netWorth),
+	})
+	return result.RowsAffected, utils.DBError(result)
+}
+
+/*
+Put units back onto an idle quantity-type asset
+*/
+func (state *assetStateDao) AddQuantity(id uint, quantity int, price decimal.Decimal, netWorth decimal.Decimal) (int64, error) {
+	result := state.db.Model(&model.Asset{}).Where("id = ? and state = ?", id, define.ASSET_IDLE).Updates(map[string]interface{}{
+		"number":    gorm.Expr("number + ?", quantity),
+		"price":     gorm.Expr("price + ?", price),
+		"net_worth": gorm.Expr("net_worth + ?", netWorth),
+	})
+	return result.RowsAffected, utils.DBError(result)
+}
+
+/*
+Portions split off the stock that are not deleted
+*/
+func (state *assetStateDao) CountPortions(stockID uint) (int64, error) {
+	var count int64
+	result := state.db.Model(&model.Asset{}).Where("stock_id = ? and deleted_at IS NULL", stockID).Count(&count)
+	return count, utils.DBError(result)
+}
+
+func (state *assetStateDao) CreateHistory(records []*model.AssetStateHistory) error {
+	if len(records) == 0 {
+		return nil
+	}
+	result := state.db.Model(&model.AssetStateHistory{}).Create(records)
+	return utils.DBError(result)
+}
+
+func (state *assetStateDao) GetHistory(assetID uint) ([]*model.AssetStateHistory, error) {
+	var historyList []*model.AssetStateHistory
+	result := state.db.Model(&model.AssetStateHistory{}).Preload("Actor").
+		Where("asset_id = ?", assetID).Order("id desc").Find(&historyList)
+	return historyList, utils.DBError(result)
+}
@@ -89,12 +89,8 @@ func TestUserAsset(t *testing.T) {
 	AssetDao.GetDepartmentAssetsByIDs([]uint{1, 2, 3}, 1)
 	AssetDao.GetDepartmentIdleAssetsByIDs([]uint{0}, 1)
 	AssetDao.GetDepartmentIdleAssetsByIDs([]uint{1, 2, 3}, 1)
-	AssetDao.ModifyAssetsUserAndState([]uint{0}, 1, 2)
-	AssetDao.ModifyAssetsUserAndState([]uint{1, 2, 3}, 1, 3)
 	AssetDao.GetUserMaintainAssets(1)
 	AssetDao.GetUserMaintainAssets(9)
-	AssetDao.ModifyAssetMaintainerAndState([]uint{0}, 1)
-	AssetDao.ModifyAssetMaintainerAndState([]uint{1, 2, 3}, 0)
 	AssetDao.GetAllAssets(-1, -1)
 	AssetDao.GetAssetDirectDepartment(1, -1, -1)
 	AssetDao.CheckAssetPropertyExist(1, "line")
@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+
+	"gorm.io/gorm"
+)
+
+type AssetVersionDaoInterface interface {
+	Create(newVersion *model.AssetVersion) error
+	GetLatestVersion(assetID uint) (uint, error)
+	GetVersions(assetID uint) ([]*model.AssetVersion, error)
+	GetVersionsAfter(assetID uint, version uint) ([]*model.AssetVersion, error)
+}
+
+type assetVersionDao struct {
+	db *gorm.DB
+}
+
+func NewAssetVersionDao(db *gorm.DB) AssetVersionDaoInterface {
+	return &assetVersionDao{db: db}
+}
+
+func (version *assetVersionDao) Create(newVersion *model.AssetVersion) error {
+	result := version.db.Model(&model.AssetVersion{}).Create(newVersion)
+	return utils.DBError(result)
+}
+
+/*
+0 when the asset has never been edited
+*/
+func (version *assetVersionDao) GetLatestVersion(assetID uint) (uint, error) {
+	var latest uint
+	result := version.db.Model(&model.AssetVersion{}).Where("asset_id = ?", assetID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest)
+	return latest, utils.DBError(result)
+}
+
+func (version *assetVersionDao) GetVersions(assetID uint) ([]*model.AssetVersion, error) {
+	var versionList []*model.AssetVersion
+	result := version.db.Model(&model.AssetVersion{}).Preload("Actor").
+		Where("asset_id = ?", assetID).Order("version desc").Find(&versionList)
+	return versionList, utils.DBError(result)
+}
+
+/*
+Newest first, the order they are undone in
+*/
+func (version *assetVersionDao) GetVersionsAfter(assetID uint, after uint) ([]*model.AssetVersion, error) {
+	var versionList []*model.AssetVersion
+	result := version.db.Model(&model.AssetVersion{}).
+		Where("asset_id = ? and version > ?", assetID, after).Order("version desc").Find(&versionList)
+	return versionList, utils.DBError(result)
+}
@@ -11,18 +11,25 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+type AsyncDaoInterface interface {
+	GetPendingTask() (task *model.AsyncTask, err error)
+	GetAsyncTaskListByUserID(userID uint) (taskList []*model.AsyncTask, err error)
+	CreateAsyncTask(newTask model.AsyncTask) (err error)
+	ModifyAsyncTaskInfo(taskID uint, data map[string]interface{}) (err error)
+	GetAsyncTaskByID(taskID uint) (task *model.AsyncTask, err error)
+}
+
 type asyncDao struct {
+	db *gorm.DB
 }
 
-var AsyncDao *asyncDao
 var newLogger logger.Interface
 
-func newAsyncDao() *asyncDao {
-	return &asyncDao{}
+func NewAsyncDao(db *gorm.DB) AsyncDaoInterface {
+	return &asyncDao{db: db}
 }
 
 func init() {
-	AsyncDao = newAsyncDao()
 	newLogger = logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
 		logger.Config{
@@ -36,7 +43,7 @@ func init() {
 }
 
 func (asy *asyncDao) GetPendingTask() (task *model.AsyncTask, err error) {
-	result := db.Session(&gorm.Session{Logger: newLogger}).Model(&model.AsyncTask{}).Where("state = ?", 0).First(&task)
+	result := asy.db.Session(&gorm.Session{Logger: newLogger}).Model(&model.AsyncTask{}).Where("state = ?", 0).First(&task)
 	if result.Error == gorm.ErrRecordNotFound {
 		task = nil
 		err = nil
@@ -47,25 +54,25 @@ func (asy *asyncDao) GetPendingTask() (task *model.AsyncTask, err error) {
 }
 
 func (asy *asyncDao) GetAsyncTaskListByUserID(userID uint) (taskList []*model.AsyncTask, err error) {
-	result := db.Model(&model.AsyncTask{}).Preload("User").Where("user_id = ?", userID).Find(&taskList)
+	result := asy.db.Model(&model.AsyncTask{}).Preload("User").Where("user_id = ?", userID).Find(&taskList)
 	err = utils.DBError(result)
 	return
 }
 
 func (asy *asyncDao) CreateAsyncTask(newTask model.AsyncTask) (err error) {
-	result := db.Model(&model.AsyncTask{}).Create(&newTask)
+	result := asy.db.Model(&model.AsyncTask{}).Create(&newTask)
 	err = utils.DBError(result)
 	return
 }
 
 func (asy *asyncDao) ModifyAsyncTaskInfo(taskID uint, data map[string]interface{}) (err error) {
-	result := db.Model(&model.AsyncTask{}).Where("id = ?", taskID).Updates(data)
+	result := asy.db.Model(&model.AsyncTask{}).Where("id = ?", taskID).Updates(data)
 	err = utils.DBError(result)
 	return
 }
 
 func (asy *asyncDao) GetAsyncTaskByID(taskID uint) (task *model.AsyncTask, err error) {
-	result := db.Model(&model.AsyncTask{}).Preload("User").Where("id = ?", taskID).First(&task)
+	result := asy.db.Model(&model.AsyncTask{}).Preload("User").Where("id = ?", taskID).First(&task)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 	} else {
@@ -2,18 +2,18 @@ package dao
 
 import (
 	"asset-management/app/model"
+	"asset-management/config"
+	"fmt"
 	"log"
-	"os"
+	"sync/atomic"
 
-	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var db *gorm.DB
-
-func connect() {
+func connect(db *gorm.DB) {
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatal(err)
@@ -22,45 +22,170 @@ func connect() {
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+/*
+Every table the models describe, the migrations have to arrive at the same schema
+*/
+var schemaModels = []interface{}{
+	&model.Entity{},
+	&model.Department{},
+	&model.User{},
+	&model.Asset{},
+	&model.AssetClass{},
+	&model.Task{},
+	&model.Url{},
+	&model.Log{},
+	&model.Stat{},
+	&model.AsyncTask{},
+	&model.RefreshToken{},
+	&model.LoginLock{},
+	&model.TwoFactor{},
+	&model.RecoveryCode{},
+	&model.ApiToken{},
+	&model.Role{},
+	&model.RoleAssignment{},
+	&model.IdentityProvider{},
+	&model.IdentityBinding{},
+	&model.AuthorizationState{},
+	&model.Invite{},
+	&model.PasswordPolicy{},
+	&model.PasswordHistory{},
+	&model.PasswordReset{},
+	&model.AssetStateHistory{},
+	&model.AssetVersion{},
+	&model.AssetArchive{},
+	&model.TaskQuantity{},
+}
 
-	db.AutoMigrate(&model.Entity{},
-		&model.Department{},
-		&model.User{},
-		&model.Asset{},
-		&model.AssetClass{},
-		&model.Task{},
-		&model.Url{},
-		&model.Log{},
-		&model.Stat{},
-		&model.AsyncTask{},
-	)
-
-	//tables := make([]string, 0)
-	//db.Raw("SELECT name FROM sqlite_master WHERE type='table' order by name").Scan(&tables)
-	//fmt.Println("table is", tables)
+/*
+Dev mode only: sync tables with models, skipping the migration history
+*/
+func autoMigrate(db *gorm.DB) {
+	db.AutoMigrate(schemaModels...)
+	if err := seedBuiltInRoles(db); err != nil {
+		log.Fatal(err)
+	}
+}
 
+func checkSchema(db *gorm.DB) {
 	if !db.Migrator().HasTable(&model.User{}) {
 		log.Fatal("database error")
 	}
 }
 
-func Initial() {
+/*
+Open the database without touching the schema, used by the migrate command
+*/
+func Open(conf config.DatabaseConfig) *gorm.DB {
+	var db *gorm.DB
 	var err error
 
-	if gin.Mode() == gin.DebugMode {
-		if os.Getenv("DEBUG") == "" {
-			db, err = gorm.Open(mysql.Open("manager:BinaryAbstract@tcp(49.233.51.221:25000)/asset?parseTime=True&loc=Local"), nil)
-		} else {
-			db, err = gorm.Open(mysql.Open("manager:BinaryAbstract@tcp(AssetManagement-Database-dev.BinaryAbstract.secoder.local:80)/asset?parseTime=True&loc=Asia%2fShanghai"), nil)
-		}
-	} else {
-		db, err = gorm.Open(mysql.Open("manager:BinaryAbstract@tcp(AssetManagement-Database.BinaryAbstract.secoder.local:3306)/asset?parseTime=True&loc=Asia%2fShanghai"), nil)
+	switch conf.Driver {
+	case "sqlite":
+		db, err = gorm.Open(sqlite.Open(conf.DSN), nil)
+	default:
+		db, err = gorm.Open(mysql.Open(conf.DSN), nil)
 	}
 
 	if err != nil {
 		log.Fatal(err)
 	}
-	connect()
+	connect(db)
+	return db
+}
+
+func Initial(conf config.DatabaseConfig) *gorm.DB {
+	db := Open(conf)
+
+	if conf.AutoMigrate {
+		autoMigrate(db)
+	} else {
+		pending, err := PendingMigrations(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if pending != 0 {
+			log.Fatalf("database schema is %d migration(s) behind, run `migrate up` first", pending)
+		}
+	}
+	checkSchema(db)
+	return db
+}
+
+/*
+All data access objects sharing one connection
+*/
+type Daos struct {
+	ApiToken     ApiTokenDaoInterface
+	Asset        AssetDaoInterface
+	AssetClass   AssetClassDaoInterface
+	AssetRecycle AssetRecycleDaoInterface
+	AssetState   AssetStateDaoInterface
+	AssetVersion AssetVersionDaoInterface
+	Async        AsyncDaoInterface
+	Department   DepartmentDaoInterface
+	Entity       EntityDaoInterface
+	Identity     IdentityDaoInterface
+	Invite       InviteDaoInterface
+	Log          LogDaoInterface
+	LoginLock    LoginLockDaoInterface
+	Password     PasswordDaoInterface
+	Role         RoleDaoInterface
+	Stat         StatDaoInterface
+	Task         TaskDaoInterface
+	Token        TokenDaoInterface
+	TwoFactor    TwoFactorDaoInterface
+	Url          UrlDaoInterface
+	User         UserDaoInterface
+	LogHook      logrus.Hook
+
+	db *gorm.DB
+}
+
+/*
+Multi-step mutations go through a unit of work: every dao handed to fn
+shares one transaction, which commits when fn returns nil and rolls back otherwise
+*/
+type UnitOfWork interface {
+	Transaction(fn func(tx *Daos) error) error
+}
+
+/*
+Nested calls reuse the outer transaction through a savepoint
+*/
+func (daos *Daos) Transaction(fn func(tx *Daos) error) error {
+	return daos.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewDaos(tx))
+	})
+}
+
+func NewDaos(db *gorm.DB) *Daos {
+	return &Daos{
+		ApiToken:     NewApiTokenDao(db),
+		Asset:        NewAssetDao(db),
+		AssetClass:   NewAssetClassDao(db),
+		AssetRecycle: NewAssetRecycleDao(db),
+		AssetState:   NewAssetStateDao(db),
+		AssetVersion: NewAssetVersionDao(db),
+		Async:        NewAsyncDao(db),
+		Department:   NewDepartmentDao(db),
+		Entity:       NewEntityDao(db),
+		Identity:     NewIdentityDao(db),
+		Invite:       NewInviteDao(db),
+		Log:          NewLogDao(db),
+		LoginLock:    NewLoginLockDao(db),
+		Password:     NewPasswordDao(db),
+		Role:         NewRoleDao(db),
+		Stat:         NewStatDao(db),
+		Task:         NewTaskDao(db),
+		Token:        NewTokenDao(db),
+		TwoFactor:    NewTwoFactorDao(db),
+		Url:          NewUrlDao(db),
+		User:         NewUserDao(db),
+		LogHook:      NewMysqlHook(db),
+		db:           db,
+	}
 }
 
 func ClearDatabase(db *gorm.DB) {
@@ -71,6 +196,29 @@ func ClearDatabase(db *gorm.DB) {
 		Delete(&model.Asset{}).
 		Delete(&model.AssetClass{}).
 		Delete(&model.Url{})
+	// login state keyed by user id must not leak into a reused id
+	for _, table := range []interface{}{
+		&model.RefreshToken{},
+		&model.LoginLock{},
+		&model.TwoFactor{},
+		&model.RecoveryCode{},
+		&model.ApiToken{},
+		&model.RoleAssignment{},
+		&model.IdentityBinding{},
+		&model.IdentityProvider{},
+		&model.AuthorizationState{},
+		&model.Invite{},
+		&model.PasswordPolicy{},
+		&model.PasswordHistory{},
+		&model.PasswordReset{},
+		&model.AssetStateHistory{},
+		&model.AssetVersion{},
+		&model.AssetArchive{},
+		&model.TaskQuantity{},
+	} {
+		db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(table)
+	}
+	db.Where("built_in = ?", false).Delete(&model.Role{})
 }
 
 /*type lockDb struct {
@@ -80,13 +228,24 @@ func ClearDatabase(db *gorm.DB) {
 
 var Lockdb *lockDb*/
 
-func InitForTest() {
-	var err error
-	db, err = gorm.Open(sqlite.Open("file::memory:?cache=shared&parseTime=True&loc=Local"))
+var testDatabases atomic.Int64
+
+/*
+A fresh in-memory database for every call, the name keeps it apart from the
+databases of other tests while the shared cache lets its connections see each other
+*/
+func InitForTest() *gorm.DB {
+	dsn := fmt.Sprintf("file:test%d?mode=memory&cache=shared&parseTime=True&loc=Local", testDatabases.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn))
 	if err != nil {
 		log.Fatal(err)
 	}
-	connect()
+	connect(db)
+	if _, err = MigrateUp(db); err != nil {
+		log.Fatal(err)
+	}
+	checkSchema(db)
 	ClearDatabase(db)
 	//Lockdb = &lockDb{Db: db}
+	return db
 }
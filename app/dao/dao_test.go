@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	Init()
+	daos := NewDaos(db)
+
+	err := daos.Transaction(func(tx *Daos) error {
+		return tx.Entity.Create(model.Entity{Name: "committed"})
+	})
+	assert.Equal(t, nil, err)
+	entity, err := EntityDao.GetEntityByName("committed")
+	assert.Equal(t, nil, err)
+	assert.NotNil(t, entity)
+
+	failure := errors.New("second step failed")
+	err = daos.Transaction(func(tx *Daos) error {
+		if err := tx.Entity.Create(model.Entity{Name: "rolled_back"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	entity, err = EntityDao.GetEntityByName("rolled_back")
+	assert.Equal(t, nil, err)
+	assert.Nil(t, entity)
+
+	err = daos.Transaction(func(tx *Daos) error {
+		if err := tx.Entity.Create(model.Entity{Name: "outer"}); err != nil {
+			return err
+		}
+		return tx.Transaction(func(nested *Daos) error {
+			if err := nested.Entity.Create(model.Entity{Name: "inner"}); err != nil {
+				return err
+			}
+			return failure
+		})
+	})
+	assert.Equal(t, failure, err)
+	entity, err = EntityDao.GetEntityByName("outer")
+	assert.Equal(t, nil, err)
+	assert.Nil(t, entity)
+}
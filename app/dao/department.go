@@ -7,51 +7,70 @@ import (
 	"gorm.io/gorm"
 )
 
-type departmentDao struct {
+type DepartmentDaoInterface interface {
+	Create(newDepartment model.Department) error
+	Delete(id []uint) error
+	Update(id uint, data map[string]interface{}) error
+	AllDepartment() (list []model.Department, err error)
+	GetDepartmentByName(name string) (*model.Department, error)
+	GetDepartmentSub(name string, entityID uint, departmentID uint) (*model.Department, error)
+	GetDepartmentsSubByID(entityID uint, departmentID uint) ([]*model.Department, error)
+	GetDepartmentByID(id uint) (*model.Department, error)
+	GetDepartmentsByNames(name []string) (list []model.Department, err error)
+	DepartmentCount() (count int64, err error)
+	GetSubDepartmentByID(id uint) (departments []*model.Department, err error)
+	GetSubDepartment(name string) (departments []*model.Department, err error)
+	GetParentDepartment(name string) (departments *model.Department, err error)
+	ModifyParentDepartment(child_name string, parent_name string) error
+	GetDepartmentDirectUser(name string) (users []*model.User, err error)
+	GetDepartmentDirectUserByID(id uint) (users []*model.User, err error)
+	GetDepartmentAllUserByID(id uint) (users []*model.User, err error)
+	GetDepartmentAllUser(name string) (users []*model.User, err error)
+	GetDepartmentEntity(name string) (entity model.Entity, err error)
+	ModifyDepartmentEntity(department_name string, entity_name string) error
+	GetDepartmentManager(id uint) (managers []*model.User, err error)
 }
 
-var DepartmentDao *departmentDao
-
-func newDepartmentDao() *departmentDao {
-	return &departmentDao{}
+type departmentDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	DepartmentDao = newDepartmentDao()
+func NewDepartmentDao(db *gorm.DB) DepartmentDaoInterface {
+	return &departmentDao{db: db}
 }
 
 func (department *departmentDao) Create(newDepartment model.Department) error {
-	result := db.Model(&model.Department{}).Create(&newDepartment)
+	result := department.db.Model(&model.Department{}).Create(&newDepartment)
 	return utils.DBError(result)
 }
 
 func (department *departmentDao) Delete(id []uint) error {
-	result := db.Model(&model.Department{}).Where("id in (?)", id).Delete(&model.Department{})
+	result := department.db.Model(&model.Department{}).Where("id in (?)", id).Delete(&model.Department{})
 	return utils.DBError(result)
 }
 
 func (department *departmentDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.Department{}).Where("id = ?", id).Updates(data)
+	result := department.db.Model(&model.Department{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (department *departmentDao) AllDepartment() (list []model.Department, err error) {
-	result := db.Model(&model.Department{}).Find(&list)
+	result := department.db.Model(&model.Department{}).Find(&list)
 	err = utils.DBError(result)
 	return
 }
 
 func (department *departmentDao) GetDepartmentByName(name string) (*model.Department, error) {
 	ret := &model.Department{}
-	result := db.Model(&model.Department{}).Where("name = ?", name).First(ret)
+	result := department.db.Model(&model.Department{}).Where("name = ?", name).First(ret)
 	/*parent := &model.Department{}
-	err := db.Model(&ret).Association("Department").Find(&parent)
+	err := department.db.Model(&ret).Association("Department").Find(&parent)
 	if err != nil {
 		return nil, err
 	}
 	ret.Parent = parent*/
 	entity := &model.Entity{}
-	err := db.Model(&ret).Association("Entity").Find(&entity)
+	err := department.db.Model(&ret).Association("Entity").Find(&entity)
 	if err != nil {
 		return nil, err
 	}
@@ -66,9 +85,9 @@ func (department *departmentDao) GetDepartmentSub(name string, entityID uint, de
 	ret := &model.Department{}
 	var result *gorm.DB
 	if departmentID != 0 {
-		result = db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("name = ? and entity_id = ? and parent_id = ?", name, entityID, departmentID).First(ret)
+		result = department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("name = ? and entity_id = ? and parent_id = ?", name, entityID, departmentID).First(ret)
 	} else {
-		result = db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("name = ? and entity_id = ? and parent_id IS NULL", name, entityID).First(ret)
+		result = department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("name = ? and entity_id = ? and parent_id IS NULL", name, entityID).First(ret)
 	}
 
 	if result.Error == gorm.ErrRecordNotFound {
@@ -81,9 +100,9 @@ func (department *departmentDao) GetDepartmentsSubByID(entityID uint, department
 	ret := []*model.Department{}
 	var result *gorm.DB
 	if departmentID != 0 {
-		result = db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id = ?", entityID, departmentID).Find(&ret)
+		result = department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id = ?", entityID, departmentID).Find(&ret)
 	} else {
-		result = db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", entityID).Find(&ret)
+		result = department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", entityID).Find(&ret)
 	}
 
 	if result.Error == gorm.ErrRecordNotFound {
@@ -94,7 +113,7 @@ func (department *departmentDao) GetDepartmentsSubByID(entityID uint, department
 
 func (department *departmentDao) GetDepartmentByID(id uint) (*model.Department, error) {
 	ret := &model.Department{}
-	result := db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("id = ?", id).First(ret)
+	result := department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("id = ?", id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -102,19 +121,19 @@ func (department *departmentDao) GetDepartmentByID(id uint) (*model.Department,
 }
 
 func (department *departmentDao) GetDepartmentsByNames(name []string) (list []model.Department, err error) {
-	result := db.Model(&model.Department{}).Where("name IN (?)", name).Order("id").Find(&list)
+	result := department.db.Model(&model.Department{}).Where("name IN (?)", name).Order("id").Find(&list)
 	err = utils.DBError(result)
 	return
 }
 
 func (department *departmentDao) DepartmentCount() (count int64, err error) {
-	result := db.Model(&model.Entity{}).Count(&count)
+	result := department.db.Model(&model.Entity{}).Count(&count)
 	err = utils.DBError(result)
 	return
 }
 
 func (department *departmentDao) GetSubDepartmentByID(id uint) (departments []*model.Department, err error) {
-	result := db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("parent_id = ?", id).Find(&departments)
+	result := department.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("parent_id = ?", id).Find(&departments)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -127,7 +146,7 @@ func (department *departmentDao) GetSubDepartment(name string) (departments []*m
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&query_department).Where("parent_id = ?", query_department.ID).Find(&departments))
+	err = utils.DBError(department.db.Model(&query_department).Where("parent_id = ?", query_department.ID).Find(&departments))
 	return
 }
 
@@ -136,7 +155,7 @@ func (department *departmentDao) GetParentDepartment(name string) (departments *
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&query_department).Where("id = ?", query_department.ParentID).Find(&departments))
+	err = utils.DBError(department.db.Model(&query_department).Where("id = ?", query_department.ParentID).Find(&departments))
 	return
 }
 
@@ -150,7 +169,7 @@ func (department *departmentDao) ModifyParentDepartment(child_name string, paren
 		return err
 	}
 	child_department.ParentID = parent_department.ID
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_department))
+	return utils.DBError(department.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&child_department))
 }
 
 // department and user
@@ -159,7 +178,7 @@ func (department *departmentDao) GetDepartmentDirectUser(name string) (users []*
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&model.User{}).Where("department_id = ?", query_department.ID).Find(&users))
+	err = utils.DBError(department.db.Model(&model.User{}).Where("department_id = ?", query_department.ID).Find(&users))
 	return
 }
 
@@ -168,7 +187,7 @@ func (department *departmentDao) GetDepartmentDirectUserByID(id uint) (users []*
 	if err != nil {
 		return
 	}
-	result := db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("department_id = ?", id).Find(&users)
+	result := department.db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("department_id = ?", id).Find(&users)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -227,15 +246,15 @@ func (department *departmentDao) ModifyDepartmentEntity(department_name string,
 	if err != nil {
 		return err
 	}
-	target_entity, err := EntityDao.GetEntityByName(entity_name)
+	target_entity, err := NewEntityDao(department.db).GetEntityByName(entity_name)
 	if err != nil {
 		return err
 	}
 	query_department.Entity = *target_entity
-	return utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_department))
+	return utils.DBError(department.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&query_department))
 }
 
 func (department *departmentDao) GetDepartmentManager(id uint) (managers []*model.User, err error) {
-	err = utils.DBError(db.Model(&model.User{}).Where("department_id = ? and department_super = ?", id, true).Find(&managers))
+	err = utils.DBError(department.db.Model(&model.User{}).Where("department_id = ? and department_super = ?", id, true).Find(&managers))
 	return
 }
@@ -7,43 +7,54 @@ import (
 	"gorm.io/gorm"
 )
 
-type entityDao struct {
+type EntityDaoInterface interface {
+	Create(newEntity model.Entity) error
+	Delete(id []uint) error
+	Update(id uint, data map[string]interface{}) error
+	AllEntity() (list []model.Entity, err error)
+	GetEntityByName(name string) (*model.Entity, error)
+	GetEntityByID(id uint) (*model.Entity, error)
+	GetEntitysByNames(name []string) (list []model.Entity, err error)
+	EntityCount() (count int64, err error)
+	GetEntityAllUser(id uint, offset int, limit int) (users []*model.User, count int64, err error)
+	GetEntityManager(id uint) (managers []*model.User, err error)
+	GetEntityAllDepartment(id uint) (departments []*model.Department, err error)
+	GetEntitySubDepartment(name string) (departments []*model.Department, err error)
+	GetEntitySubDepartmentByID(id uint) (departments []*model.Department, err error)
 }
 
-var EntityDao *entityDao
-
-func newEntityDao() *entityDao {
-	return &entityDao{}
+type entityDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	EntityDao = newEntityDao()
+func NewEntityDao(db *gorm.DB) EntityDaoInterface {
+	return &entityDao{db: db}
 }
 
 func (entity *entityDao) Create(newEntity model.Entity) error {
-	result := db.Model(&model.Entity{}).Create(&newEntity)
+	result := entity.db.Model(&model.Entity{}).Create(&newEntity)
 	return utils.DBError(result)
 }
 
 func (entity *entityDao) Delete(id []uint) error {
-	result := db.Model(&model.Entity{}).Where("id in (?)", id).Delete(&model.Entity{})
+	result := entity.db.Model(&model.Entity{}).Where("id in (?)", id).Delete(&model.Entity{})
 	return utils.DBError(result)
 }
 
 func (entity *entityDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.Entity{}).Where("id = ?", id).Updates(data)
+	result := entity.db.Model(&model.Entity{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (entity *entityDao) AllEntity() (list []model.Entity, err error) {
-	result := db.Model(&model.Entity{}).Find(&list)
+	result := entity.db.Model(&model.Entity{}).Find(&list)
 	err = utils.DBError(result)
 	return
 }
 
 func (entity *entityDao) GetEntityByName(name string) (*model.Entity, error) {
 	ret := &model.Entity{}
-	result := db.Model(&model.Entity{}).Where("name = ?", name).First(ret)
+	result := entity.db.Model(&model.Entity{}).Where("name = ?", name).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -52,7 +63,7 @@ func (entity *entityDao) GetEntityByName(name string) (*model.Entity, error) {
 
 func (entity *entityDao) GetEntityByID(id uint) (*model.Entity, error) {
 	ret := &model.Entity{}
-	result := db.Model(&model.Entity{}).Where("id = ?", id).First(ret)
+	result := entity.db.Model(&model.Entity{}).Where("id = ?", id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -60,13 +71,13 @@ func (entity *entityDao) GetEntityByID(id uint) (*model.Entity, error) {
 }
 
 func (entity *entityDao) GetEntitysByNames(name []string) (list []model.Entity, err error) {
-	result := db.Model(&model.Entity{}).Where("name IN (?)", name).Order("id").Find(&list)
+	result := entity.db.Model(&model.Entity{}).Where("name IN (?)", name).Order("id").Find(&list)
 	err = utils.DBError(result)
 	return
 }
 
 func (entity *entityDao) EntityCount() (count int64, err error) {
-	result := db.Model(&model.Entity{}).Count(&count)
+	result := entity.db.Model(&model.Entity{}).Count(&count)
 	err = utils.DBError(result)
 	return
 }
@@ -77,12 +88,12 @@ func (entity *entityDao) GetEntityAllUser(id uint, offset int, limit int) (users
 	// if err != nil {
 	// 	return
 	// }
-	err = utils.DBError(db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("entity_id = ?", id).Count(&count).Offset(offset).Limit(limit).Find(&users))
+	err = utils.DBError(entity.db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("entity_id = ?", id).Count(&count).Offset(offset).Limit(limit).Find(&users))
 	return
 }
 
 func (entity *entityDao) GetEntityManager(id uint) (managers []*model.User, err error) {
-	err = utils.DBError(db.Model(&model.User{}).Where("entity_id = ? and entity_super = ?", id, true).Find(&managers))
+	err = utils.DBError(entity.db.Model(&model.User{}).Where("entity_id = ? and entity_super = ?", id, true).Find(&managers))
 	return
 }
 
@@ -92,7 +103,7 @@ func (entity *entityDao) GetEntityAllDepartment(id uint) (departments []*model.D
 	// if err != nil {
 	// 	return
 	// }
-	err = utils.DBError(db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ?", id).Find(&departments))
+	err = utils.DBError(entity.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ?", id).Find(&departments))
 	return
 }
 
@@ -101,12 +112,12 @@ func (entity *entityDao) GetEntitySubDepartment(name string) (departments []*mod
 	if err != nil {
 		return
 	}
-	err = utils.DBError(db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", query_entity.ID, 0).Find(&departments))
+	err = utils.DBError(entity.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", query_entity.ID, 0).Find(&departments))
 	return
 }
 
 func (entity *entityDao) GetEntitySubDepartmentByID(id uint) (departments []*model.Department, err error) {
-	result := db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", id).Find(&departments)
+	result := entity.db.Model(&model.Department{}).Preload("Parent").Preload("Entity").Where("entity_id = ? and parent_id IS NULL", id).Find(&departments)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 		departments = nil
@@ -0,0 +1,148 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdentityDaoInterface interface {
+	CreateProvider(newProvider *model.IdentityProvider) error
+	UpdateProvider(id uint, data map[string]interface{}) error
+	DeleteProvider(id uint) error
+	GetProviderByID(id uint) (*model.IdentityProvider, error)
+	GetProviderByName(entityID uint, name string) (*model.IdentityProvider, error)
+	GetEntityProviders(entityID uint) ([]*model.IdentityProvider, error)
+	GetEnabledProviders(entityID uint) ([]*model.IdentityProvider, error)
+	CreateBinding(newBinding *model.IdentityBinding) error
+	DeleteBinding(providerID uint, userID uint) error
+	GetBindingBySubject(providerID uint, subject string) (*model.IdentityBinding, error)
+	GetUserBindings(userID uint) ([]*model.IdentityBinding, error)
+	CreateState(newState *model.AuthorizationState) error
+	TakeState(hash string) (*model.AuthorizationState, error)
+	DeleteExpiredStates(now time.Time) error
+}
+
+type identityDao struct {
+	db *gorm.DB
+}
+
+func NewIdentityDao(db *gorm.DB) IdentityDaoInterface {
+	return &identityDao{db: db}
+}
+
+func (identity *identityDao) CreateProvider(newProvider *model.IdentityProvider) error {
+	result := identity.db.Model(&model.IdentityProvider{}).Create(newProvider)
+	return utils.DBError(result)
+}
+
+func (identity *identityDao) UpdateProvider(id uint, data map[string]interface{}) error {
+	result := identity.db.Model(&model.IdentityProvider{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (identity *identityDao) DeleteProvider(id uint) error {
+	result := identity.db.Where("provider_id = ?", id).Delete(&model.IdentityBinding{})
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	result = identity.db.Where("provider_id = ?", id).Delete(&model.AuthorizationState{})
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	result = identity.db.Delete(&model.IdentityProvider{}, id)
+	return utils.DBError(result)
+}
+
+func (identity *identityDao) GetProviderByID(id uint) (*model.IdentityProvider, error) {
+	ret := &model.IdentityProvider{}
+	result := identity.db.Model(&model.IdentityProvider{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (identity *identityDao) GetProviderByName(entityID uint, name string) (*model.IdentityProvider, error) {
+	ret := &model.IdentityProvider{}
+	result := identity.db.Model(&model.IdentityProvider{}).Where("entity_id = ? and name = ?", entityID, name).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (identity *identityDao) GetEntityProviders(entityID uint) (list []*model.IdentityProvider, err error) {
+	result := identity.db.Model(&model.IdentityProvider{}).Where("entity_id = ?", entityID).Order("id").Find(&list)
+	err = utils.DBError(result)
+	return
+}
+
+/*
+Providers offered on the login page, of one entity or of all entities when entityID is 0
+*/
+func (identity *identityDao) GetEnabledProviders(entityID uint) (list []*model.IdentityProvider, err error) {
+	query := identity.db.Model(&model.IdentityProvider{}).Where("enabled = ?", true)
+	if entityID != 0 {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	result := query.Order("id").Find(&list)
+	err = utils.DBError(result)
+	return
+}
+
+func (identity *identityDao) CreateBinding(newBinding *model.IdentityBinding) error {
+	result := identity.db.Model(&model.IdentityBinding{}).Create(newBinding)
+	return utils.DBError(result)
+}
+
+func (identity *identityDao) DeleteBinding(providerID uint, userID uint) error {
+	result := identity.db.Where("provider_id = ? and user_id = ?", providerID, userID).Delete(&model.IdentityBinding{})
+	return utils.DBError(result)
+}
+
+func (identity *identityDao) GetBindingBySubject(providerID uint, subject string) (*model.IdentityBinding, error) {
+	ret := &model.IdentityBinding{}
+	result := identity.db.Model(&model.IdentityBinding{}).Where("provider_id = ? and subject = ?", providerID, subject).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (identity *identityDao) GetUserBindings(userID uint) (list []*model.IdentityBinding, err error) {
+	result := identity.db.Model(&model.IdentityBinding{}).Preload("Provider").Where("user_id = ?", userID).Order("id").Find(&list)
+	err = utils.DBError(result)
+	return
+}
+
+func (identity *identityDao) CreateState(newState *model.AuthorizationState) error {
+	result := identity.db.Model(&model.AuthorizationState{}).Create(newState)
+	return utils.DBError(result)
+}
+
+/*
+A state is single use, it is deleted as it is read
+*/
+func (identity *identityDao) TakeState(hash string) (*model.AuthorizationState, error) {
+	ret := &model.AuthorizationState{}
+	result := identity.db.Model(&model.AuthorizationState{}).Where("state_hash = ?", hash).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err := utils.DBError(result); err != nil {
+		return nil, err
+	}
+	result = identity.db.Delete(&model.AuthorizationState{}, ret.ID)
+	if result.RowsAffected == 0 {
+		// taken by a concurrent request
+		return nil, utils.DBError(result)
+	}
+	return ret, utils.DBError(result)
+}
+
+func (identity *identityDao) DeleteExpiredStates(now time.Time) error {
+	result := identity.db.Where("expires_at < ?", now).Delete(&model.AuthorizationState{})
+	return utils.DBError(result)
+}
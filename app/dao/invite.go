@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InviteDaoInterface interface {
+	Create(newInvite *model.Invite) error
+	Update(id uint, data map[string]interface{}) error
+	GetInviteByID(id uint) (*model.Invite, error)
+	GetInviteByHash(hash string) (*model.Invite, error)
+	GetDepartmentInvites(departmentID uint) ([]*model.Invite, error)
+	RevokeInvite(id uint) error
+	UseInvite(id uint, usedAt time.Time) (bool, error)
+}
+
+type inviteDao struct {
+	db *gorm.DB
+}
+
+func NewInviteDao(db *gorm.DB) InviteDaoInterface {
+	return &inviteDao{db: db}
+}
+
+func (invite *inviteDao) Create(newInvite *model.Invite) error {
+	result := invite.db.Model(&model.Invite{}).Create(newInvite)
+	return utils.DBError(result)
+}
+
+func (invite *inviteDao) Update(id uint, data map[string]interface{}) error {
+	result := invite.db.Model(&model.Invite{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (invite *inviteDao) GetInviteByID(id uint) (*model.Invite, error) {
+	ret := &model.Invite{}
+	result := invite.db.Model(&model.Invite{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (invite *inviteDao) GetInviteByHash(hash string) (*model.Invite, error) {
+	ret := &model.Invite{}
+	result := invite.db.Model(&model.Invite{}).Where("token_hash = ?", hash).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (invite *inviteDao) GetDepartmentInvites(departmentID uint) ([]*model.Invite, error) {
+	var inviteList []*model.Invite
+	result := invite.db.Model(&model.Invite{}).Where("department_id = ?", departmentID).Order("id desc").Find(&inviteList)
+	return inviteList, utils.DBError(result)
+}
+
+func (invite *inviteDao) RevokeInvite(id uint) error {
+	return invite.Update(id, map[string]interface{}{
+		"revoked": true,
+	})
+}
+
+/*
+Count one registration against the invite, false when it was revoked,
+expired or used up in the meantime
+*/
+func (invite *inviteDao) UseInvite(id uint, usedAt time.Time) (bool, error) {
+	result := invite.db.Model(&model.Invite{}).
+		Where("id = ? and revoked = ? and uses < max_uses and expires_at > ?", id, false, usedAt).
+		Update("uses", gorm.Expr("uses + 1"))
+	return result.RowsAffected == 1, utils.DBError(result)
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/utils"
 	"log"
@@ -10,22 +11,29 @@ import (
 )
 
 type mysqlHook struct {
+	db *gorm.DB
 }
 
-var MysqlHook *mysqlHook
-
-type logDao struct {
+/*
+logrus hook writing operation logs into the logs table
+*/
+func NewMysqlHook(db *gorm.DB) logrus.Hook {
+	return &mysqlHook{db: db}
 }
 
-var LogDao *logDao
+type LogDaoInterface interface {
+	GetLoginLogByEntityID(entityID uint, offset int, limit int) (logList []*model.Log, count int64, err error)
+	GetLoginLogsForExport(entityID uint, fromTime *model.ModelTime, logType uint) (logList []*model.Log, err error)
+	GetDataLogByEntityID(entityID uint, offset int, limit int) (logList []*model.Log, count int64, err error)
+	GetDataLogsForExport(entityID uint, fromTime *model.ModelTime, logType uint) (logList []*model.Log, err error)
+}
 
-func newLogDao() *logDao {
-	return &logDao{}
+type logDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	LogDao = newLogDao()
-	MysqlHook = &mysqlHook{}
+func NewLogDao(db *gorm.DB) LogDaoInterface {
+	return &logDao{db: db}
 }
 
 func (hook *mysqlHook) Fire(entry *logrus.Entry) error {
@@ -33,23 +41,28 @@ func (hook *mysqlHook) Fire(entry *logrus.Entry) error {
 	if err != nil {
 		return err
 	}
+	// only operations done through an impersonation token carry the impersonator
+	impersonatorID, _ := entry.Data["impersonator_id"].(uint)
+	impersonatorName, _ := entry.Data["impersonator_name"].(string)
 	go func() {
 		mylog := &model.Log{
-			Method:       entry.Data["method"].(string),
-			URL:          entry.Data["url"].(string),
-			Status:       entry.Data["status"].(int),
-			ErrorCode:    entry.Data["error_code"].(int),
-			ErrorMessage: entry.Data["error_message"].(string),
-			UserID:       entry.Data["user_id"].(uint),
-			Username:     entry.Data["username"].(string),
-			EntityID:     entry.Data["entity_id"].(uint),
-			DepartmentID: entry.Data["department_id"].(uint),
-			Time:         (*model.ModelTime)(&entry.Time),
-			Level:        entry.Level.String(),
-			Message:      entry.Message,
+			Method:           entry.Data["method"].(string),
+			URL:              entry.Data["url"].(string),
+			Status:           entry.Data["status"].(int),
+			ErrorCode:        entry.Data["error_code"].(int),
+			ErrorMessage:     entry.Data["error_message"].(string),
+			UserID:           entry.Data["user_id"].(uint),
+			Username:         entry.Data["username"].(string),
+			EntityID:         entry.Data["entity_id"].(uint),
+			DepartmentID:     entry.Data["department_id"].(uint),
+			Time:             (*model.ModelTime)(&entry.Time),
+			Level:            entry.Level.String(),
+			Message:          entry.Message,
+			ImpersonatorID:   impersonatorID,
+			ImpersonatorName: impersonatorName,
 		}
 
-		result := db.Model(&model.Log{}).Create(mylog)
+		result := hook.db.Model(&model.Log{}).Create(mylog)
 		if result.Error != nil {
 			log.Println("Write log to database error: ", result.Error.Error())
 		}
@@ -69,7 +82,7 @@ func (hook *mysqlHook) Fire(entry *logrus.Entry) error {
 	// 	Message:      entry.Message,
 	// }
 
-	// result := db.Model(&model.Log{}).Create(log)
+	// result := mylog.db.Model(&model.Log{}).Create(log)
 	// return utils.DBError(result)
 	return nil
 }
@@ -79,7 +92,7 @@ func (hook *mysqlHook) Levels() []logrus.Level {
 }
 
 func (mylog *logDao) GetLoginLogByEntityID(entityID uint, offset int, limit int) (logList []*model.Log, count int64, err error) {
-	result := db.Model(&model.Log{}).Where("entity_id = ? and url = ?", entityID, "/user/login").Count(&count).Offset(offset).Limit(limit).Find(&logList)
+	result := mylog.db.Model(&model.Log{}).Where("entity_id = ? and url in ?", entityID, define.LOGIN_URLS).Count(&count).Offset(offset).Limit(limit).Find(&logList)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 		return
@@ -91,11 +104,11 @@ func (mylog *logDao) GetLoginLogByEntityID(entityID uint, offset int, limit int)
 func (mylog *logDao) GetLoginLogsForExport(entityID uint, fromTime *model.ModelTime, logType uint) (logList []*model.Log, err error) {
 	var result *gorm.DB
 	if logType == 0 {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url = ? and time >= ?", entityID, "/user/login", fromTime)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url in ? and time >= ?", entityID, define.LOGIN_URLS, fromTime)
 	} else if logType == 1 {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url = ? and time >= ? and status = ?", entityID, "/user/login", fromTime, 200)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url in ? and time >= ? and status = ?", entityID, define.LOGIN_URLS, fromTime, 200)
 	} else {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url = ? and time >= ? and status <> ?", entityID, "/user/login", fromTime, 200)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url in ? and time >= ? and status <> ?", entityID, define.LOGIN_URLS, fromTime, 200)
 	}
 
 	result = result.Find(&logList)
@@ -108,7 +121,7 @@ func (mylog *logDao) GetLoginLogsForExport(entityID uint, fromTime *model.ModelT
 }
 
 func (mylog *logDao) GetDataLogByEntityID(entityID uint, offset int, limit int) (logList []*model.Log, count int64, err error) {
-	result := db.Model(&model.Log{}).Where("entity_id = ? and url <> ?", entityID, "/user/login").Count(&count).Offset(offset).Limit(limit).Find(&logList)
+	result := mylog.db.Model(&model.Log{}).Where("entity_id = ? and url not in ?", entityID, define.LOGIN_URLS).Count(&count).Offset(offset).Limit(limit).Find(&logList)
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 		return
@@ -120,11 +133,11 @@ func (mylog *logDao) GetDataLogByEntityID(entityID uint, offset int, limit int)
 func (mylog *logDao) GetDataLogsForExport(entityID uint, fromTime *model.ModelTime, logType uint) (logList []*model.Log, err error) {
 	var result *gorm.DB
 	if logType == 0 {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url <> ? and time >= ?", entityID, "/user/login", fromTime)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url not in ? and time >= ?", entityID, define.LOGIN_URLS, fromTime)
 	} else if logType == 1 {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url <> ? and time >= ? and status = ?", entityID, "/user/login", fromTime, 200)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url not in ? and time >= ? and status = ?", entityID, define.LOGIN_URLS, fromTime, 200)
 	} else {
-		result = db.Model(&model.Log{}).Where("entity_id = ? and url <> ? and time >= ? and status <> ?", entityID, "/user/login", fromTime, 200)
+		result = mylog.db.Model(&model.Log{}).Where("entity_id = ? and url not in ? and time >= ? and status <> ?", entityID, define.LOGIN_URLS, fromTime, 200)
 	}
 
 	result = result.Find(&logList)
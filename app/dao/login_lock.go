@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+
+	"gorm.io/gorm"
+)
+
+type LoginLockDaoInterface interface {
+	Create(newLock *model.LoginLock) error
+	Update(id uint, data map[string]interface{}) error
+	Delete(id uint) error
+	DeleteByKey(kind string, key string) error
+	GetLockByID(id uint) (*model.LoginLock, error)
+	GetLockByKey(kind string, key string) (*model.LoginLock, error)
+	GetEntityLocks(entityID uint) ([]*model.LoginLock, error)
+	GetIPLocks() ([]*model.LoginLock, error)
+}
+
+type loginLockDao struct {
+	db *gorm.DB
+}
+
+func NewLoginLockDao(db *gorm.DB) LoginLockDaoInterface {
+	return &loginLockDao{db: db}
+}
+
+func (lock *loginLockDao) Create(newLock *model.LoginLock) error {
+	result := lock.db.Model(&model.LoginLock{}).Create(newLock)
+	return utils.DBError(result)
+}
+
+func (lock *loginLockDao) Update(id uint, data map[string]interface{}) error {
+	result := lock.db.Model(&model.LoginLock{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (lock *loginLockDao) Delete(id uint) error {
+	result := lock.db.Model(&model.LoginLock{}).Where("id = ?", id).Delete(&model.LoginLock{})
+	return utils.DBError(result)
+}
+
+func (lock *loginLockDao) DeleteByKey(kind string, key string) error {
+	result := lock.db.Model(&model.LoginLock{}).Where("kind = ? and lock_key = ?", kind, key).Delete(&model.LoginLock{})
+	return utils.DBError(result)
+}
+
+func (lock *loginLockDao) GetLockByID(id uint) (*model.LoginLock, error) {
+	ret := &model.LoginLock{}
+	result := lock.db.Model(&model.LoginLock{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (lock *loginLockDao) GetLockByKey(kind string, key string) (*model.LoginLock, error) {
+	ret := &model.LoginLock{}
+	result := lock.db.Model(&model.LoginLock{}).Where("kind = ? and lock_key = ?", kind, key).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (lock *loginLockDao) GetEntityLocks(entityID uint) ([]*model.LoginLock, error) {
+	var lockList []*model.LoginLock
+	result := lock.db.Model(&model.LoginLock{}).Where("entity_id = ? and kind = ?", entityID, model.LOCK_BY_USERNAME).Find(&lockList)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return lockList, utils.DBError(result)
+}
+
+func (lock *loginLockDao) GetIPLocks() ([]*model.LoginLock, error) {
+	var lockList []*model.LoginLock
+	result := lock.db.Model(&model.LoginLock{}).Where("kind = ?", model.LOCK_BY_IP).Find(&lockList)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return lockList, utils.DBError(result)
+}
@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMigrate(t *testing.T) {
+	Init()
+
+	pending, err := PendingMigrations(db)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, pending)
+
+	versions, err := MigrateDown(db, len(migrations))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(migrations), len(versions))
+	assert.Equal(t, false, db.Migrator().HasTable(&model.User{}))
+
+	states, err := MigrationStatus(db)
+	assert.Equal(t, nil, err)
+	for _, state := range states {
+		assert.Nil(t, state.AppliedAt)
+	}
+
+	versions, err = MigrateUp(db)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(migrations), len(versions))
+	assert.Equal(t, true, db.Migrator().HasTable(&model.User{}))
+	assert.Equal(t, true, db.Migrator().HasColumn(&model.Asset{}, "warn"))
+	assert.Equal(t, true, db.Migrator().HasColumn(&model.Task{}, "state"))
+	// the frozen baseline plus every later migration add up to the current models
+	for _, table := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		assert.Equal(t, nil, stmt.Parse(table))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.Equal(t, true, db.Migrator().HasColumn(table, field.DBName), stmt.Schema.Table+"."+field.DBName)
+			}
+		}
+	}
+
+	versions, err = MigrateUp(db)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(versions))
+}
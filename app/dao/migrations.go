@@ -0,0 +1,402 @@
+package dao
+
+import (
+	"asset-management/app/define"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+Schema history, append new migrations with the next version number
+and never edit one that has been released
+*/
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up:      createBaselineSchema,
+		Down:    dropBaselineSchema,
+	},
+	{
+		Version: 2,
+		Name:    "refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&refreshTokenV2{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&refreshTokenV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&refreshTokenV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "login_locks",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&loginLockV3{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&loginLockV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginLockV3{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "two_factor",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&entityV4{}, "RequireTwoFactor") {
+				if err := tx.Migrator().AddColumn(&entityV4{}, "RequireTwoFactor"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasTable(&twoFactorV4{}) {
+				if err := tx.Migrator().CreateTable(&twoFactorV4{}); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasTable(&recoveryCodeV4{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&recoveryCodeV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&recoveryCodeV4{}, &twoFactorV4{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&entityV4{}, "RequireTwoFactor")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "api_tokens",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&apiTokenV5{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&apiTokenV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiTokenV5{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "roles",
+		Up: func(tx *gorm.DB) error {
+			// the super flags stay as the assignment of the built-in roles
+			if !tx.Migrator().HasTable(&roleV6{}) {
+				if err := tx.Migrator().CreateTable(&roleV6{}); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasTable(&roleAssignmentV6{}) {
+				if err := tx.Migrator().CreateTable(&roleAssignmentV6{}); err != nil {
+					return err
+				}
+			}
+			return seedRolesV6(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&roleAssignmentV6{}, &roleV6{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "user_security_version",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&userV7{}, "SecurityVersion") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&userV7{}, "SecurityVersion")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&userV7{}, "SecurityVersion")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "refresh_token_sessions",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"Device", "IP", "UserAgent", "LastSeenAt"} {
+				if tx.Migrator().HasColumn(&refreshTokenV8{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&refreshTokenV8{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"Device", "IP", "UserAgent", "LastSeenAt"} {
+				if err := tx.Migrator().DropColumn(&refreshTokenV8{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 9,
+		Name:    "identity_providers",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&identityProviderV9{}, &identityBindingV9{}, &authorizationStateV9{}} {
+				if tx.Migrator().HasTable(table) {
+					continue
+				}
+				if err := tx.Migrator().CreateTable(table); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&authorizationStateV9{}, &identityBindingV9{}, &identityProviderV9{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "log_impersonator",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"ImpersonatorID", "ImpersonatorName"} {
+				if tx.Migrator().HasColumn(&logV10{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&logV10{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"ImpersonatorID", "ImpersonatorName"} {
+				if err := tx.Migrator().DropColumn(&logV10{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "invites",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&inviteV11{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&inviteV11{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&inviteV11{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "password_policy",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&passwordPolicyV12{}, &passwordHistoryV12{}} {
+				if tx.Migrator().HasTable(table) {
+					continue
+				}
+				if err := tx.Migrator().CreateTable(table); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"MustChangePassword", "PasswordChangedAt"} {
+				if tx.Migrator().HasColumn(&userV12{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&userV12{}, field); err != nil {
+					return err
+				}
+			}
+			// existing passwords start aging from the upgrade
+			return tx.Model(&userV12{}).Where("password_changed_at is null").Update("password_changed_at", time.Now()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"MustChangePassword", "PasswordChangedAt"} {
+				if err := tx.Migrator().DropColumn(&userV12{}, field); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&passwordHistoryV12{}, &passwordPolicyV12{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "password_resets",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&userV13{}, "Email") {
+				if err := tx.Migrator().AddColumn(&userV13{}, "Email"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasTable(&passwordResetV13{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&passwordResetV13{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&passwordResetV13{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&userV13{}, "Email")
+		},
+	},
+	{
+		Version: 14,
+		Name:    "asset_state_history",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&assetStateHistoryV14{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&assetStateHistoryV14{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&assetStateHistoryV14{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "asset_versions",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(&assetVersionV15{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&assetVersionV15{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&assetVersionV15{})
+		},
+	},
+	{
+		Version: 16,
+		Name:    "asset_recycle_bin",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"DeletedAt", "DeletedParentID", "DeletedState"} {
+				if tx.Migrator().HasColumn(&assetV16{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&assetV16{}, field); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&assetV16{}, "DeletedAt") {
+				if err := tx.Migrator().CreateIndex(&assetV16{}, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasTable(&assetArchiveV16{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&assetArchiveV16{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&assetArchiveV16{}); err != nil {
+				return err
+			}
+			for _, field := range []string{"DeletedAt", "DeletedParentID", "DeletedState"} {
+				if err := tx.Migrator().DropColumn(&assetV16{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 17,
+		Name:    "asset_quantities",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&assetV17{}, "StockID") {
+				if err := tx.Migrator().AddColumn(&assetV17{}, "StockID"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasColumn(&assetStateHistoryV17{}, "Quantity") {
+				if err := tx.Migrator().AddColumn(&assetStateHistoryV17{}, "Quantity"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasTable(&taskQuantityV17{}) {
+				return nil
+			}
+			// parsing tasks first hands their foreign key on task_id to the new table
+			if err := (&gorm.Statement{DB: tx}).Parse(&taskV17{}); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&taskQuantityV17{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&taskQuantityV17{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&assetStateHistoryV17{}, "Quantity"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&assetV17{}, "StockID")
+		},
+	},
+	{
+		Version: 18,
+		Name:    "depreciation_methods",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&assetClassV18{}, &assetV18{}} {
+				for _, field := range []string{"DepreciationMethod", "ResidualRate"} {
+					if tx.Migrator().HasColumn(table, field) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(table, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&assetClassV18{}, &assetV18{}} {
+				if err := tx.Migrator().DropColumn(table, "DepreciationMethod"); err != nil {
+					return err
+				}
+				// the sqlite migrator cuts a column definition at its first comma, decimal(5,4) has one
+				stmt := &gorm.Statement{DB: tx}
+				if err := stmt.Parse(table); err != nil {
+					return err
+				}
+				err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: stmt.Table}, clause.Column{Name: "residual_rate"}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+/*
+Same as seedBuiltInRoles, on the roles table as migration 6 created it
+*/
+func seedRolesV6(tx *gorm.DB) error {
+	for _, builtIn := range define.BUILT_IN_ROLES {
+		permissions := datatypes.JSONSlice[string](builtIn.Permissions)
+		var count int64
+		if err := tx.Model(&roleV6{}).Where("built_in = ? and name = ?", true, builtIn.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		var err error
+		if count == 0 {
+			err = tx.Create(&roleV6{Name: builtIn.Name, BuiltIn: true, Permissions: permissions}).Error
+		} else {
+			err = tx.Model(&roleV6{}).Where("built_in = ? and name = ?", true, builtIn.Name).Update("permissions", permissions).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
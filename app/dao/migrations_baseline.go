@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+/*
+The schema as it was before migrations existed, frozen here so that migration 1
+keeps creating exactly these tables however the models change later
+*/
+type baselineEntity struct {
+	ID          uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name        string     `gorm:"column:name;unique;not null"`
+	Description string     `gorm:"column:description"`
+	CreatedAt   *time.Time `gorm:"column:created_at"`
+}
+
+func (baselineEntity) TableName() string { return "entities" }
+
+type baselineDepartment struct {
+	ID        uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name      string                      `gorm:"column:name;not null"`
+	ParentID  uint                        `gorm:"default:null;column:parent_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Parent    *baselineDepartment         `gorm:"foreignKey:ParentID;references:ID;default:null"`
+	EntityID  uint                        `gorm:"default:null;column:entity_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Entity    baselineEntity              `gorm:"foreignKey:EntityID;references:ID;default:null"`
+	KeyList   datatypes.JSONSlice[string] `gorm:"column:key_list"`
+	Threshold uint                        `gorm:"column:threshold"`
+}
+
+func (baselineDepartment) TableName() string { return "departments" }
+
+type baselineUser struct {
+	ID              uint                `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserName        string              `gorm:"column:username;unique;not null"`
+	Password        string              `gorm:"column:password;not null"`
+	EntityID        uint                `gorm:"default:null;column:entity_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Entity          *baselineEntity     `gorm:"foreignKey:EntityID;references:ID;default:null"`
+	EntitySuper     bool                `gorm:"column:entity_super;default:false"`
+	DepartmentID    uint                `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department      *baselineDepartment `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	DepartmentSuper bool                `gorm:"column:department_super;default:false"`
+	SystemSuper     bool                `gorm:"column:system_super;default:false"`
+	IsEmployee      bool                `gorm:"column:is_employee;default:true"`
+	Ban             bool                `gorm:"column:ban;default:false"`
+	FeishuID        string              `gorm:"column:feishu_id;default:null"`
+	FeishuToken     string              `gorm:"column:feishu_token;default:null"`
+	RefreshToken    string              `gorm:"column:refresh_token;default:null"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineAssetClass struct {
+	ID           uint                `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name         string              `gorm:"column:name;not null"`
+	ParentID     uint                `gorm:"default:null;column:parent_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Parent       *baselineAssetClass `gorm:"foreignKey:ParentID;references:ID;default:null"`
+	DepartmentID uint                `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department   baselineDepartment  `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	Type         int                 `gorm:"column:type"`
+}
+
+func (baselineAssetClass) TableName() string { return "asset_classes" }
+
+type baselineAsset struct {
+	ID           uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name         string                      `gorm:"column:name;not null"`
+	ParentID     uint                        `gorm:"default:null;column:parent_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Parent       *baselineAsset              `gorm:"foreignKey:ParentID;references:ID;default:null"`
+	UserID       uint                        `gorm:"default:null;column:user_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	User         baselineUser                `gorm:"foreignKey:UserID;references:ID;default:null"`
+	DepartmentID uint                        `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department   baselineDepartment          `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	Price        decimal.Decimal             `gorm:"type:decimal(10,2);column:price"`
+	Description  string                      `gorm:"column:description"`
+	Position     string                      `gorm:"column:position"`
+	Expire       uint                        `gorm:"column:expire;default:0"`
+	ClassID      uint                        `gorm:"default:null;column:class_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Class        baselineAssetClass          `gorm:"foreignKey:ClassID;references:ID;default:null"`
+	Number       int                         `gorm:"column:number"`
+	Type         int                         `gorm:"column:type"`
+	State        uint                        `gorm:"column:state"`
+	MaintainerID uint                        `gorm:"default:null;column:maintainer_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Maintainer   baselineUser                `gorm:"foreignKey:MaintainerID;references:ID;default:null"`
+	Property     datatypes.JSON              `gorm:"column:property;"`
+	CreatedAt    *time.Time                  `gorm:"column:created_at"`
+	NetWorth     decimal.Decimal             `gorm:"type:decimal(10,2);column:net_worth"`
+	ImgList      datatypes.JSONSlice[string] `gorm:"column:img_list"`
+	Warn         bool                        `gorm:"default:false;column:warn"`
+	Threshold    uint                        `gorm:"default:0;column:threshold"`
+}
+
+func (baselineAsset) TableName() string { return "assets" }
+
+type baselineTask struct {
+	ID              uint               `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	TaskType        uint               `gorm:"column:task_type"`
+	TaskDescription string             `gorm:"column:task_description"`
+	UserID          uint               `gorm:"default:null;column:user_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	User            baselineUser       `gorm:"foreignKey:UserID;references:ID;default:null"`
+	TargetID        uint               `gorm:"default:null;column:target_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Target          baselineUser       `gorm:"foreignKey:TargetID;references:ID;default:null"`
+	DepartmentID    uint               `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department      baselineDepartment `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	AssetList       []*baselineAsset   `gorm:"many2many:task_assets;joinForeignKey:TaskID;joinReferences:AssetID"`
+	State           uint               `gorm:"default:0;column:state"`
+	CreatedAt       *time.Time         `gorm:"column:created_at"`
+	ReviewAt        *time.Time         `gorm:"column:review_at"`
+}
+
+func (baselineTask) TableName() string { return "tasks" }
+
+type baselineUrl struct {
+	ID              uint           `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name            string         `gorm:"column:name;not null"`
+	Url             string         `gorm:"column:url"`
+	EntityID        uint           `gorm:"default:null;column:entity_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Entity          baselineEntity `gorm:"foreignKey:EntityID;references:ID;default:null"`
+	DepartmentSuper bool           `gorm:"column:department_super;default:false"`
+	EntitySuper     bool           `gorm:"column:entity_super;default:false"`
+	SystemSuper     bool           `gorm:"column:system_super;default:false"`
+}
+
+func (baselineUrl) TableName() string { return "urls" }
+
+type baselineLog struct {
+	ID           uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Method       string     `gorm:"column:method"`
+	URL          string     `gorm:"column:url"`
+	Status       int        `gorm:"column:status"`
+	ErrorCode    int        `gorm:"column:error_code"`
+	ErrorMessage string     `gorm:"default:None;column:error_message"`
+	UserID       uint       `gorm:"column:user_id"`
+	Username     string     `gorm:"column:username"`
+	EntityID     uint       `gorm:"column:entity_id"`
+	DepartmentID uint       `gorm:"column:department_id"`
+	Time         *time.Time `gorm:"column:time"`
+	Level        string     `gorm:"column:level"`
+	Message      string     `gorm:"column:message"`
+}
+
+func (baselineLog) TableName() string { return "logs" }
+
+type baselineStat struct {
+	ID           uint               `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	DepartmentID uint               `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department   baselineDepartment `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	Total        decimal.Decimal    `gorm:"type:decimal(60,2);column:total"`
+	Time         time.Time          `gorm:"column:time"`
+}
+
+func (baselineStat) TableName() string { return "stats" }
+
+type baselineAsyncTask struct {
+	ID           uint               `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Type         uint               `gorm:"column:type"`
+	UserID       uint               `gorm:"default:null;column:user_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	User         baselineUser       `gorm:"foreignKey:UserID;references:ID;default:null"`
+	DepartmentID uint               `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Department   baselineDepartment `gorm:"foreignKey:DepartmentID;references:ID;default:null"`
+	EntityID     uint               `gorm:"default:null;column:entity_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Entity       baselineEntity     `gorm:"foreignKey:EntityID;references:ID;default:null"`
+	ObjectKey    string             `gorm:"column:object_key"`
+	DownloadLink string             `gorm:"column:download_link"`
+	State        uint               `gorm:"column:state"`
+	Message      string             `gorm:"column:message"`
+	FromTime     *time.Time         `gorm:"column:from_time"`
+	LogType      uint               `gorm:"column:log_type"`
+}
+
+func (baselineAsyncTask) TableName() string { return "async_tasks" }
+
+func createBaselineSchema(tx *gorm.DB) error {
+	return tx.AutoMigrate(&baselineEntity{},
+		&baselineDepartment{},
+		&baselineUser{},
+		&baselineAsset{},
+		&baselineAssetClass{},
+		&baselineTask{},
+		&baselineUrl{},
+		&baselineLog{},
+		&baselineStat{},
+		&baselineAsyncTask{},
+	)
+}
+
+func dropBaselineSchema(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("task_assets",
+		&baselineAsyncTask{},
+		&baselineStat{},
+		&baselineLog{},
+		&baselineUrl{},
+		&baselineTask{},
+		&baselineAsset{},
+		&baselineAssetClass{},
+		&baselineUser{},
+		&baselineDepartment{},
+		&baselineEntity{},
+	)
+}
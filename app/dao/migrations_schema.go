@@ -0,0 +1,337 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
+)
+
+/*
+The tables and columns each migration after the baseline adds, frozen as they were
+when it was written so that it keeps doing the same however the models change later.
+A struct holds only what its migration adds to the table
+*/
+type refreshTokenV2 struct {
+	ID        uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID    uint         `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User      baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	TokenHash string       `gorm:"column:token_hash;size:64;uniqueIndex"`
+	ExpiresAt time.Time    `gorm:"column:expires_at"`
+	Revoked   bool         `gorm:"column:revoked;default:false"`
+	CreatedAt time.Time    `gorm:"column:created_at"`
+}
+
+func (refreshTokenV2) TableName() string { return "refresh_tokens" }
+
+type loginLockV3 struct {
+	ID            uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Kind          string     `gorm:"column:kind;size:16;uniqueIndex:idx_login_lock_key"`
+	Key           string     `gorm:"column:lock_key;size:128;uniqueIndex:idx_login_lock_key"`
+	EntityID      uint       `gorm:"column:entity_id;index"`
+	Failures      uint       `gorm:"column:failures;default:0"`
+	Lockouts      uint       `gorm:"column:lockouts;default:0"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at"`
+}
+
+func (loginLockV3) TableName() string { return "login_locks" }
+
+type entityV4 struct {
+	RequireTwoFactor bool `gorm:"column:require_two_factor;default:false"`
+}
+
+func (entityV4) TableName() string { return "entities" }
+
+type twoFactorV4 struct {
+	ID        uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID    uint         `gorm:"column:user_id;uniqueIndex;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User      baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	Secret    string       `gorm:"column:secret;size:64"`
+	Enabled   bool         `gorm:"column:enabled;default:false"`
+	LastStep  int64        `gorm:"column:last_step;default:0"`
+	EnabledAt *time.Time   `gorm:"column:enabled_at"`
+	CreatedAt time.Time    `gorm:"column:created_at"`
+}
+
+func (twoFactorV4) TableName() string { return "two_factors" }
+
+type recoveryCodeV4 struct {
+	ID       uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID   uint         `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User     baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	CodeHash string       `gorm:"column:code_hash;size:64"`
+	Used     bool         `gorm:"column:used;default:false"`
+}
+
+func (recoveryCodeV4) TableName() string { return "recovery_codes" }
+
+type apiTokenV5 struct {
+	ID           uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name         string       `gorm:"column:name;size:64"`
+	Kind         string       `gorm:"column:kind;size:16"`
+	UserID       uint         `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User         baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	EntityID     uint         `gorm:"column:entity_id;index"`
+	DepartmentID uint         `gorm:"column:department_id"`
+	Scope        string       `gorm:"column:scope;size:16"`
+	Prefix       string       `gorm:"column:prefix;size:16"`
+	TokenHash    string       `gorm:"column:token_hash;size:64;uniqueIndex"`
+	ExpiresAt    time.Time    `gorm:"column:expires_at"`
+	LastUsedAt   *time.Time   `gorm:"column:last_used_at"`
+	Revoked      bool         `gorm:"column:revoked;default:false"`
+	CreatedAt    time.Time    `gorm:"column:created_at"`
+}
+
+func (apiTokenV5) TableName() string { return "api_tokens" }
+
+type roleV6 struct {
+	ID          uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Name        string                      `gorm:"column:name;size:64"`
+	EntityID    uint                        `gorm:"column:entity_id;index"`
+	BuiltIn     bool                        `gorm:"column:built_in;default:false"`
+	Permissions datatypes.JSONSlice[string] `gorm:"column:permissions"`
+	CreatedAt   time.Time                   `gorm:"column:created_at"`
+}
+
+func (roleV6) TableName() string { return "roles" }
+
+type roleAssignmentV6 struct {
+	ID           uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID       uint         `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User         baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	RoleID       uint         `gorm:"column:role_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Role         roleV6       `gorm:"foreignKey:RoleID;references:ID"`
+	EntityID     uint         `gorm:"column:entity_id;index"`
+	DepartmentID uint         `gorm:"column:department_id"`
+}
+
+func (roleAssignmentV6) TableName() string { return "role_assignments" }
+
+type userV7 struct {
+	SecurityVersion uint `gorm:"column:security_version;default:0"`
+}
+
+func (userV7) TableName() string { return "users" }
+
+type refreshTokenV8 struct {
+	Device     string     `gorm:"column:device;size:64"`
+	IP         string     `gorm:"column:ip;size:64"`
+	UserAgent  string     `gorm:"column:user_agent;size:255"`
+	LastSeenAt *time.Time `gorm:"column:last_seen_at"`
+}
+
+func (refreshTokenV8) TableName() string { return "refresh_tokens" }
+
+type identityProviderV9 struct {
+	ID           uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	EntityID     uint                        `gorm:"column:entity_id;index"`
+	Name         string                      `gorm:"column:name;size:64"`
+	Kind         string                      `gorm:"column:kind;size:16"`
+	Issuer       string                      `gorm:"column:issuer;size:255"`
+	ClientID     string                      `gorm:"column:client_id;size:255"`
+	ClientSecret string                      `gorm:"column:client_secret;size:255"`
+	Scopes       datatypes.JSONSlice[string] `gorm:"column:scopes"`
+	Enabled      bool                        `gorm:"column:enabled;default:true"`
+	CreatedAt    time.Time                   `gorm:"column:created_at"`
+}
+
+func (identityProviderV9) TableName() string { return "identity_providers" }
+
+type identityBindingV9 struct {
+	ID         uint               `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID     uint               `gorm:"column:user_id;uniqueIndex:idx_binding_user_provider;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User       baselineUser       `gorm:"foreignKey:UserID;references:ID"`
+	ProviderID uint               `gorm:"column:provider_id;uniqueIndex:idx_binding_user_provider;uniqueIndex:idx_binding_provider_subject;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Provider   identityProviderV9 `gorm:"foreignKey:ProviderID;references:ID"`
+	Subject    string             `gorm:"column:subject;size:255;uniqueIndex:idx_binding_provider_subject"`
+	Email      string             `gorm:"column:email;size:255"`
+	CreatedAt  time.Time          `gorm:"column:created_at"`
+}
+
+func (identityBindingV9) TableName() string { return "identity_bindings" }
+
+type authorizationStateV9 struct {
+	ID           uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	StateHash    string    `gorm:"column:state_hash;size:64;uniqueIndex"`
+	ProviderID   uint      `gorm:"column:provider_id;index"`
+	Purpose      string    `gorm:"column:purpose;size:16"`
+	UserID       uint      `gorm:"column:user_id"`
+	CodeVerifier string    `gorm:"column:code_verifier;size:128"`
+	Nonce        string    `gorm:"column:nonce;size:128"`
+	RedirectURI  string    `gorm:"column:redirect_uri;size:512"`
+	ExpiresAt    time.Time `gorm:"column:expires_at"`
+}
+
+func (authorizationStateV9) TableName() string { return "authorization_states" }
+
+type logV10 struct {
+	ImpersonatorID   uint   `gorm:"column:impersonator_id;default:0"`
+	ImpersonatorName string `gorm:"column:impersonator_name"`
+}
+
+func (logV10) TableName() string { return "logs" }
+
+type inviteV11 struct {
+	ID           uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	EntityID     uint      `gorm:"column:entity_id;index"`
+	DepartmentID uint      `gorm:"column:department_id;index"`
+	RoleID       uint      `gorm:"column:role_id;default:0"`
+	CreatorID    uint      `gorm:"column:creator_id"`
+	Prefix       string    `gorm:"column:prefix;size:16"`
+	TokenHash    string    `gorm:"column:token_hash;size:64;uniqueIndex"`
+	MaxUses      uint      `gorm:"column:max_uses"`
+	Uses         uint      `gorm:"column:uses;default:0"`
+	ExpiresAt    time.Time `gorm:"column:expires_at"`
+	Revoked      bool      `gorm:"column:revoked;default:false"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+func (inviteV11) TableName() string { return "invites" }
+
+type passwordPolicyV12 struct {
+	ID            uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	EntityID      uint      `gorm:"column:entity_id;uniqueIndex"`
+	MinLength     uint      `gorm:"column:min_length"`
+	RequireUpper  bool      `gorm:"column:require_upper;default:false"`
+	RequireLower  bool      `gorm:"column:require_lower;default:false"`
+	RequireDigit  bool      `gorm:"column:require_digit;default:false"`
+	RequireSymbol bool      `gorm:"column:require_symbol;default:false"`
+	HistorySize   uint      `gorm:"column:history_size;default:0"`
+	MaxAgeDays    uint      `gorm:"column:max_age_days;default:0"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+}
+
+func (passwordPolicyV12) TableName() string { return "password_policies" }
+
+type passwordHistoryV12 struct {
+	ID           uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID       uint         `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User         baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	PasswordHash string       `gorm:"column:password_hash"`
+	CreatedAt    time.Time    `gorm:"column:created_at"`
+}
+
+func (passwordHistoryV12) TableName() string { return "password_histories" }
+
+type userV12 struct {
+	MustChangePassword bool       `gorm:"column:must_change_password;default:false"`
+	PasswordChangedAt  *time.Time `gorm:"column:password_changed_at"`
+}
+
+func (userV12) TableName() string { return "users" }
+
+type userV13 struct {
+	Email string `gorm:"column:email;size:255;default:null"`
+}
+
+func (userV13) TableName() string { return "users" }
+
+type passwordResetV13 struct {
+	ID        uint         `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	UserID    uint         `gorm:"default:null;column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User      baselineUser `gorm:"foreignKey:UserID;references:ID"`
+	TokenHash string       `gorm:"column:token_hash;size:64;uniqueIndex"`
+	Channel   string       `gorm:"column:channel;size:16"`
+	IP        string       `gorm:"column:ip;size:64;index"`
+	ExpiresAt time.Time    `gorm:"column:expires_at"`
+	Used      bool         `gorm:"column:used;default:false"`
+	CreatedAt time.Time    `gorm:"column:created_at;index"`
+}
+
+func (passwordResetV13) TableName() string { return "password_resets" }
+
+type assetStateHistoryV14 struct {
+	ID        uint          `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	AssetID   uint          `gorm:"column:asset_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Asset     baselineAsset `gorm:"foreignKey:AssetID;references:ID"`
+	Event     string        `gorm:"column:event;size:32"`
+	FromState uint          `gorm:"column:from_state"`
+	ToState   uint          `gorm:"column:to_state"`
+	ActorID   uint          `gorm:"default:null;column:actor_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Actor     baselineUser  `gorm:"foreignKey:ActorID;references:ID;default:null"`
+	TaskID    uint          `gorm:"default:null;column:task_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Task      baselineTask  `gorm:"foreignKey:TaskID;references:ID;default:null"`
+	Reason    string        `gorm:"column:reason;size:255"`
+	CreatedAt time.Time     `gorm:"column:created_at;index"`
+}
+
+func (assetStateHistoryV14) TableName() string { return "asset_state_histories" }
+
+type assetVersionV15 struct {
+	ID        uint           `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	AssetID   uint           `gorm:"column:asset_id;uniqueIndex:idx_asset_version;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Asset     baselineAsset  `gorm:"foreignKey:AssetID;references:ID"`
+	Version   uint           `gorm:"column:version;uniqueIndex:idx_asset_version"`
+	ActorID   uint           `gorm:"default:null;column:actor_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Actor     baselineUser   `gorm:"foreignKey:ActorID;references:ID;default:null"`
+	Changes   datatypes.JSON `gorm:"column:changes"`
+	RevertOf  *uint          `gorm:"column:revert_of;default:null"`
+	CreatedAt time.Time      `gorm:"column:created_at;index"`
+}
+
+func (assetVersionV15) TableName() string { return "asset_versions" }
+
+type assetV16 struct {
+	DeletedAt       *time.Time `gorm:"column:deleted_at;index"`
+	DeletedParentID uint       `gorm:"default:null;column:deleted_parent_id"`
+	DeletedState    uint       `gorm:"default:0;column:deleted_state"`
+}
+
+func (assetV16) TableName() string { return "assets" }
+
+type assetArchiveV16 struct {
+	ID           uint           `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	AssetID      uint           `gorm:"column:asset_id;index"`
+	DepartmentID uint           `gorm:"column:department_id;index"`
+	Name         string         `gorm:"column:name"`
+	Snapshot     datatypes.JSON `gorm:"column:snapshot"`
+	DeletedAt    time.Time      `gorm:"column:deleted_at"`
+	PurgedAt     time.Time      `gorm:"column:purged_at;index"`
+}
+
+func (assetArchiveV16) TableName() string { return "asset_archives" }
+
+type assetV17 struct {
+	StockID uint `gorm:"default:null;column:stock_id"`
+}
+
+func (assetV17) TableName() string { return "assets" }
+
+type assetStateHistoryV17 struct {
+	Quantity int `gorm:"column:quantity;default:0"`
+}
+
+func (assetStateHistoryV17) TableName() string { return "asset_state_histories" }
+
+type taskQuantityV17 struct {
+	ID       uint          `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	TaskID   uint          `gorm:"column:task_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	AssetID  uint          `gorm:"column:asset_id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Asset    baselineAsset `gorm:"foreignKey:AssetID;references:ID"`
+	Quantity int           `gorm:"column:quantity"`
+}
+
+func (taskQuantityV17) TableName() string { return "task_quantities" }
+
+// only to name the foreign key tasks hold on their quantities
+type taskV17 struct {
+	ID         uint               `gorm:"primaryKey;column:id;AUTO_INCREMENT"`
+	Quantities []*taskQuantityV17 `gorm:"foreignKey:TaskID"`
+}
+
+func (taskV17) TableName() string { return "tasks" }
+
+type assetClassV18 struct {
+	DepreciationMethod string          `gorm:"column:depreciation_method;size:32"`
+	ResidualRate       decimal.Decimal `gorm:"type:decimal(5,4);column:residual_rate;default:0"`
+}
+
+func (assetClassV18) TableName() string { return "asset_classes" }
+
+type assetV18 struct {
+	DepreciationMethod string              `gorm:"column:depreciation_method;size:32"`
+	ResidualRate       decimal.NullDecimal `gorm:"type:decimal(5,4);column:residual_rate;default:null"`
+}
+
+func (assetV18) TableName() string { return "assets" }
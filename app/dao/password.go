@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordDaoInterface interface {
+	GetPolicy(entityID uint) (*model.PasswordPolicy, error)
+	SavePolicy(policy *model.PasswordPolicy) error
+	AddHistory(userID uint, passwordHash string) error
+	GetHistory(userID uint, limit int) ([]*model.PasswordHistory, error)
+	TrimHistory(userID uint, keep int) error
+	CreateReset(newReset *model.PasswordReset) error
+	GetResetByHash(hash string) (*model.PasswordReset, error)
+	UseReset(id uint, usedAt time.Time) (bool, error)
+	ExpireUserResets(userID uint) error
+	CountUserResets(userID uint, since time.Time) (int64, error)
+	CountIPResets(ip string, since time.Time) (int64, error)
+}
+
+type passwordDao struct {
+	db *gorm.DB
+}
+
+func NewPasswordDao(db *gorm.DB) PasswordDaoInterface {
+	return &passwordDao{db: db}
+}
+
+func (password *passwordDao) GetPolicy(entityID uint) (*model.PasswordPolicy, error) {
+	ret := &model.PasswordPolicy{}
+	result := password.db.Model(&model.PasswordPolicy{}).Where("entity_id = ?", entityID).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+/*
+Replaces the entity's policy, creating it on first use
+*/
+func (password *passwordDao) SavePolicy(policy *model.PasswordPolicy) error {
+	oldPolicy, err := password.GetPolicy(policy.EntityID)
+	if err != nil {
+		return err
+	}
+	if oldPolicy != nil {
+		policy.ID = oldPolicy.ID
+	}
+	result := password.db.Save(policy)
+	return utils.DBError(result)
+}
+
+func (password *passwordDao) AddHistory(userID uint, passwordHash string) error {
+	result := password.db.Model(&model.PasswordHistory{}).Create(&model.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	})
+	return utils.DBError(result)
+}
+
+/*
+The user's most recent passwords first
+*/
+func (password *passwordDao) GetHistory(userID uint, limit int) ([]*model.PasswordHistory, error) {
+	var historyList []*model.PasswordHistory
+	result := password.db.Model(&model.PasswordHistory{}).Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&historyList)
+	return historyList, utils.DBError(result)
+}
+
+/*
+Drops all but the user's keep most recent passwords
+*/
+func (password *passwordDao) TrimHistory(userID uint, keep int) error {
+	historyList, err := password.GetHistory(userID, keep)
+	if err != nil {
+		return err
+	}
+	query := password.db.Where("user_id = ?", userID)
+	if keep > 0 {
+		if len(historyList) < keep {
+			return nil
+		}
+		query = query.Where("id < ?", historyList[len(historyList)-1].ID)
+	}
+	result := query.Delete(&model.PasswordHistory{})
+	return utils.DBError(result)
+}
+
+func (password *passwordDao) CreateReset(newReset *model.PasswordReset) error {
+	result := password.db.Model(&model.PasswordReset{}).Create(newReset)
+	return utils.DBError(result)
+}
+
+func (password *passwordDao) GetResetByHash(hash string) (*model.PasswordReset, error) {
+	ret := &model.PasswordReset{}
+	result := password.db.Model(&model.PasswordReset{}).Where("token_hash = ?", hash).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+/*
+Marks the token used unless it already is or has expired, false means it could not be used
+*/
+func (password *passwordDao) UseReset(id uint, usedAt time.Time) (bool, error) {
+	result := password.db.Model(&model.PasswordReset{}).
+		Where("id = ? and used = ? and expires_at > ?", id, false, usedAt).
+		Update("used", true)
+	return result.RowsAffected == 1, utils.DBError(result)
+}
+
+func (password *passwordDao) ExpireUserResets(userID uint) error {
+	result := password.db.Model(&model.PasswordReset{}).Where("user_id = ? and used = ?", userID, false).Update("used", true)
+	return utils.DBError(result)
+}
+
+func (password *passwordDao) CountUserResets(userID uint, since time.Time) (int64, error) {
+	var count int64
+	result := password.db.Model(&model.PasswordReset{}).Where("user_id = ? and created_at > ?", userID, since).Count(&count)
+	return count, utils.DBError(result)
+}
+
+func (password *passwordDao) CountIPResets(ip string, since time.Time) (int64, error) {
+	var count int64
+	result := password.db.Model(&model.PasswordReset{}).Where("ip = ? and created_at > ?", ip, since).Count(&count)
+	return count, utils.DBError(result)
+}
@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type RoleDaoInterface interface {
+	Create(newRole *model.Role) error
+	Update(id uint, data map[string]interface{}) error
+	Delete(id uint) error
+	GetRoleByID(id uint) (*model.Role, error)
+	GetRoleByName(entityID uint, name string) (*model.Role, error)
+	GetEntityRoles(entityID uint) ([]*model.Role, error)
+	CreateAssignment(newAssignment *model.RoleAssignment) error
+	DeleteAssignment(id uint) error
+	GetAssignmentByID(id uint) (*model.RoleAssignment, error)
+	GetEntityAssignments(entityID uint) ([]*model.RoleAssignment, error)
+	GetUserAssignments(userID uint) ([]*model.RoleAssignment, error)
+}
+
+type roleDao struct {
+	db *gorm.DB
+}
+
+func NewRoleDao(db *gorm.DB) RoleDaoInterface {
+	return &roleDao{db: db}
+}
+
+/*
+Keep one row per built-in role, with the permissions the code defines for it
+*/
+func seedBuiltInRoles(db *gorm.DB) error {
+	for _, builtIn := range define.BUILT_IN_ROLES {
+		permissions := datatypes.JSONSlice[string](builtIn.Permissions)
+		thisRole := &model.Role{}
+		result := db.Model(&model.Role{}).Where("built_in = ? and name = ?", true, builtIn.Name).First(thisRole)
+		if result.Error == gorm.ErrRecordNotFound {
+			result = db.Model(&model.Role{}).Create(&model.Role{
+				Name:        builtIn.Name,
+				BuiltIn:     true,
+				Permissions: permissions,
+			})
+		} else if result.Error == nil {
+			result = db.Model(&model.Role{}).Where("id = ?", thisRole.ID).Update("permissions", permissions)
+		}
+		if err := utils.DBError(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (role *roleDao) Create(newRole *model.Role) error {
+	result := role.db.Model(&model.Role{}).Create(newRole)
+	return utils.DBError(result)
+}
+
+func (role *roleDao) Update(id uint, data map[string]interface{}) error {
+	result := role.db.Model(&model.Role{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (role *roleDao) Delete(id uint) error {
+	result := role.db.Where("role_id = ?", id).Delete(&model.RoleAssignment{})
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	result = role.db.Delete(&model.Role{}, id)
+	return utils.DBError(result)
+}
+
+func (role *roleDao) GetRoleByID(id uint) (*model.Role, error) {
+	ret := &model.Role{}
+	result := role.db.Model(&model.Role{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (role *roleDao) GetRoleByName(entityID uint, name string) (*model.Role, error) {
+	ret := &model.Role{}
+	result := role.db.Model(&model.Role{}).Where("(entity_id = ? or built_in = ?) and name = ?", entityID, true, name).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+/*
+The built-in roles followed by the entity's own
+*/
+func (role *roleDao) GetEntityRoles(entityID uint) ([]*model.Role, error) {
+	var roleList []*model.Role
+	result := role.db.Model(&model.Role{}).Where("built_in = ? or entity_id = ?", true, entityID).Order("built_in desc, id").Find(&roleList)
+	return roleList, utils.DBError(result)
+}
+
+func (role *roleDao) CreateAssignment(newAssignment *model.RoleAssignment) error {
+	result := role.db.Model(&model.RoleAssignment{}).Create(newAssignment)
+	return utils.DBError(result)
+}
+
+func (role *roleDao) DeleteAssignment(id uint) error {
+	result := role.db.Delete(&model.RoleAssignment{}, id)
+	return utils.DBError(result)
+}
+
+func (role *roleDao) GetAssignmentByID(id uint) (*model.RoleAssignment, error) {
+	ret := &model.RoleAssignment{}
+	result := role.db.Model(&model.RoleAssignment{}).Preload("Role").Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (role *roleDao) GetEntityAssignments(entityID uint) ([]*model.RoleAssignment, error) {
+	var assignmentList []*model.RoleAssignment
+	result := role.db.Model(&model.RoleAssignment{}).Preload("Role").Where("entity_id = ?", entityID).Find(&assignmentList)
+	return assignmentList, utils.DBError(result)
+}
+
+func (role *roleDao) GetUserAssignments(userID uint) ([]*model.RoleAssignment, error) {
+	var assignmentList []*model.RoleAssignment
+	result := role.db.Model(&model.RoleAssignment{}).Preload("Role").Where("user_id = ?", userID).Find(&assignmentList)
+	return assignmentList, utils.DBError(result)
+}
@@ -4,25 +4,33 @@ import (
 	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/utils"
+
+	"gorm.io/gorm"
 )
 
-type statDao struct {
+type StatDaoInterface interface {
+	GetAllAssetStat() ([]*model.Stat, error)
+	CreateAssetStats(stats []*model.Stat) error
+	GetDepartmentStat(departmentID uint) (stats []*model.Stat, err error)
+	GetDepartmentAssetDistribution(departmentID uint) (distribution []*define.AssetDistribution, err error)
+	GetDepartmentsAssetDistribution(IDs []uint, distribution []*define.DepartmentAssetDistribution) (err error)
 }
 
-var StatDao *statDao
-
-func newStatDao() *statDao {
-	return &statDao{}
+type statDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	StatDao = newStatDao()
+func NewStatDao(db *gorm.DB) StatDaoInterface {
+	return &statDao{db: db}
 }
 
+/*
+Assets in the recycle bin are no longer worth anything to their department
+*/
 func (stat *statDao) GetAllAssetStat() ([]*model.Stat, error) {
 	var stats []*model.Stat
 
-	result := db.Model(model.Asset{}).Select("department_id, SUM(net_worth) as total").Group("department_id").Scan(&stats)
+	result := stat.db.Model(model.Asset{}).Where("deleted_at IS NULL").Select("department_id, SUM(net_worth) as total").Group("department_id").Scan(&stats)
 	if result.Error != nil {
 		return nil, utils.DBError(result)
 	}
@@ -31,26 +39,26 @@ func (stat *statDao) GetAllAssetStat() ([]*model.Stat, error) {
 }
 
 func (stat *statDao) CreateAssetStats(stats []*model.Stat) error {
-	result := db.Model(&model.Stat{}).Create(stats)
+	result := stat.db.Model(&model.Stat{}).Create(stats)
 
 	return utils.DBError(result)
 }
 
 func (stat *statDao) GetDepartmentStat(departmentID uint) (stats []*model.Stat, err error) {
-	result := db.Model(&model.Stat{}).Where("department_id = ?", departmentID).Find(&stats)
+	result := stat.db.Model(&model.Stat{}).Where("department_id = ?", departmentID).Find(&stats)
 
 	err = utils.DBError(result)
 	return
 }
 
 func (stat *statDao) GetDepartmentAssetDistribution(departmentID uint) (distribution []*define.AssetDistribution, err error) {
-	result := db.Model(&model.Asset{}).Where("department_id = ?", departmentID).Select("state, COUNT(*) as count, SUM(net_worth) as total").Group("state").Scan(&distribution)
+	result := stat.db.Model(&model.Asset{}).Where("department_id = ? and deleted_at IS NULL", departmentID).Select("state, COUNT(*) as count, SUM(net_worth) as total").Group("state").Scan(&distribution)
 	err = utils.DBError(result)
 	return
 }
 
 func (stat *statDao) GetDepartmentsAssetDistribution(IDs []uint, distribution []*define.DepartmentAssetDistribution) (err error) {
-	result := db.Model(&model.Asset{}).Where("department_id IN (?)", IDs).Select("department_id, departments.name as department_name, COUNT(*) as count, SUM(net_worth) as total").Group("department_id").Joins("left join departments on assets.department_id = departments.id").Scan(&distribution)
+	result := stat.db.Model(&model.Asset{}).Where("department_id IN (?) and assets.deleted_at IS NULL", IDs).Select("department_id, departments.name as department_name, COUNT(*) as count, SUM(net_worth) as total").Group("department_id").Joins("left join departments on assets.department_id = departments.id").Scan(&distribution)
 	err = utils.DBError(result)
 	return
 }
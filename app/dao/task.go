@@ -9,37 +9,50 @@ import (
 	"gorm.io/gorm"
 )
 
-type taskDao struct {
+type TaskDaoInterface interface {
+	Create(newTask model.Task) (uint, error)
+	Delete(id []uint) error
+	Update(id uint, data map[string]interface{}) error
+	GetTaskByID(id uint) (*model.Task, error)
+	ModifyTaskType(id uint, TaskType uint) error
+	ModifyTaskDescription(id uint, description string) error
+	GetTaskUser(id uint) (user model.User, err error)
+	ModifyTaskUser(id uint, user model.User) error
+	GetTaskTarget(id uint) (user model.User, err error)
+	ModifyTaskTarget(id uint, user model.User) error
+	GetTaskAssetList(id uint) (list []*model.Asset, err error)
+	ModifyAssetList(id uint, list []*model.Asset) error
+	GetTaskListByUserID(userID uint) (taskList []*model.Task, err error)
+	GetTaskListByDepartmentID(departmentID uint) (taskList []*model.Task, err error)
+	ModifyTaskState(taskID uint, state uint) error
 }
 
-var TaskDao *taskDao
-
-func newTaskDao() *taskDao {
-	return &taskDao{}
+type taskDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	TaskDao = newTaskDao()
+func NewTaskDao(db *gorm.DB) TaskDaoInterface {
+	return &taskDao{db: db}
 }
 
 func (task *taskDao) Create(newTask model.Task) (uint, error) {
-	result := db.Model(&model.Task{}).Create(&newTask)
+	result := task.db.Model(&model.Task{}).Create(&newTask)
 	return newTask.ID, utils.DBError(result)
 }
 
 func (task *taskDao) Delete(id []uint) error {
-	result := db.Model(&model.Task{}).Where("id in (?)", id).Delete(&model.Task{})
+	result := task.db.Model(&model.Task{}).Where("id in (?)", id).Delete(&model.Task{})
 	return utils.DBError(result)
 }
 
 func (task *taskDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.Task{}).Where("id = ?", id).Updates(data)
+	result := task.db.Model(&model.Task{}).Where("id = ?", id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (task *taskDao) GetTaskByID(id uint) (*model.Task, error) {
 	ret := &model.Task{}
-	result := db.Model(&model.Task{}).Preload("User").Preload("Target").Preload("Department").Preload("AssetList.Class").Where("id = ?", id).First(ret)
+	result := task.db.Model(&model.Task{}).Preload("User").Preload("Target").Preload("Department").Preload("AssetList.Class").Preload("Quantities").Where("id = ?", id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -56,7 +69,7 @@ func (task *taskDao) ModifyTaskType(id uint, TaskType uint) error {
 		return err
 	}
 	thisTask.TaskType = TaskType
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
+	err = utils.DBError(task.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
 	return err
 }
 
@@ -70,7 +83,7 @@ func (task *taskDao) ModifyTaskDescription(id uint, description string) error {
 		return err
 	}
 	thisTask.TaskDescription = description
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
+	err = utils.DBError(task.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
 	return err
 }
 
@@ -98,7 +111,7 @@ func (task *taskDao) ModifyTaskUser(id uint, user model.User) error {
 	}
 	thisTask.UserID = user.ID
 	// thisTask.UserName = user.UserName
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
+	err = utils.DBError(task.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
 	return err
 }
 
@@ -126,7 +139,7 @@ func (task *taskDao) ModifyTaskTarget(id uint, user model.User) error {
 	}
 	thisTask.TargetID = user.ID
 	// thisTask.TargetName = user.UserName
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
+	err = utils.DBError(task.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
 	return err
 }
 
@@ -154,12 +167,12 @@ func (task *taskDao) ModifyAssetList(id uint, list []*model.Asset) error {
 		return err
 	}
 	thisTask.AssetList = list
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
+	err = utils.DBError(task.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisTask))
 	return err
 }
 
 func (task *taskDao) GetTaskListByUserID(userID uint) (taskList []*model.Task, err error) {
-	result := db.Model(&model.Task{}).Preload("User").
+	result := task.db.Model(&model.Task{}).Preload("User").
 		Preload("Target").Preload("Department").
 		Preload("AssetList.Class").Where("user_id = ?", userID).Find(&taskList)
 	if result.Error == gorm.ErrRecordNotFound {
@@ -170,7 +183,7 @@ func (task *taskDao) GetTaskListByUserID(userID uint) (taskList []*model.Task, e
 }
 
 func (task *taskDao) GetTaskListByDepartmentID(departmentID uint) (taskList []*model.Task, err error) {
-	result := db.Model(&model.Task{}).Preload("User").
+	result := task.db.Model(&model.Task{}).Preload("User").
 		Preload("Target").Preload("Department").
 		Preload("AssetList.Class").Where("department_id = ? and state <= ?", departmentID, 2).Find(&taskList)
 	if result.Error == gorm.ErrRecordNotFound {
@@ -181,7 +194,7 @@ func (task *taskDao) GetTaskListByDepartmentID(departmentID uint) (taskList []*m
 }
 
 func (task *taskDao) ModifyTaskState(taskID uint, state uint) error {
-	result := db.Model(&model.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+	result := task.db.Model(&model.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
 		"state":     state,
 		"review_at": model.ModelTime(time.Now()),
 	})
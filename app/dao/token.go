@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenDaoInterface interface {
+	Create(newToken *model.RefreshToken) error
+	Update(id uint, data map[string]interface{}) error
+	RotateToken(id uint, oldHash string, data map[string]interface{}) (bool, error)
+	GetTokenByID(id uint) (*model.RefreshToken, error)
+	GetTokenByHash(hash string) (*model.RefreshToken, error)
+	GetUserSessions(userID uint, now time.Time) ([]*model.RefreshToken, error)
+	TouchToken(id uint, seenAt time.Time) error
+	RevokeToken(id uint) error
+	RevokeUserTokens(userID uint) error
+}
+
+type tokenDao struct {
+	db *gorm.DB
+}
+
+func NewTokenDao(db *gorm.DB) TokenDaoInterface {
+	return &tokenDao{db: db}
+}
+
+func (token *tokenDao) Create(newToken *model.RefreshToken) error {
+	result := token.db.Model(&model.RefreshToken{}).Create(newToken)
+	return utils.DBError(result)
+}
+
+func (token *tokenDao) Update(id uint, data map[string]interface{}) error {
+	result := token.db.Model(&model.RefreshToken{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+/*
+Replace the grant's refresh token only if it still holds oldHash, false when another
+refresh got there first
+*/
+func (token *tokenDao) RotateToken(id uint, oldHash string, data map[string]interface{}) (bool, error) {
+	result := token.db.Model(&model.RefreshToken{}).Where("id = ? and token_hash = ? and revoked = ?", id, oldHash, false).Updates(data)
+	if err := utils.DBError(result); err != nil {
+		return false, err
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (token *tokenDao) GetTokenByID(id uint) (*model.RefreshToken, error) {
+	ret := &model.RefreshToken{}
+	result := token.db.Model(&model.RefreshToken{}).Where("id = ?", id).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (token *tokenDao) GetTokenByHash(hash string) (*model.RefreshToken, error) {
+	ret := &model.RefreshToken{}
+	result := token.db.Model(&model.RefreshToken{}).Where("token_hash = ?", hash).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+/*
+Grants that can still be refreshed, most recently started first
+*/
+func (token *tokenDao) GetUserSessions(userID uint, now time.Time) (list []*model.RefreshToken, err error) {
+	result := token.db.Model(&model.RefreshToken{}).Where("user_id = ? and revoked = ? and expires_at > ?", userID, false, now).
+		Order("id desc").Find(&list)
+	err = utils.DBError(result)
+	return
+}
+
+func (token *tokenDao) TouchToken(id uint, seenAt time.Time) error {
+	return token.Update(id, map[string]interface{}{
+		"last_seen_at": seenAt,
+	})
+}
+
+func (token *tokenDao) RevokeToken(id uint) error {
+	return token.Update(id, map[string]interface{}{
+		"revoked": true,
+	})
+}
+
+func (token *tokenDao) RevokeUserTokens(userID uint) error {
+	result := token.db.Model(&model.RefreshToken{}).Where("user_id = ? and revoked = ?", userID, false).Update("revoked", true)
+	return utils.DBError(result)
+}
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"asset-management/app/model"
+	"asset-management/utils"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorDaoInterface interface {
+	Create(newTwoFactor *model.TwoFactor) error
+	Update(id uint, data map[string]interface{}) error
+	GetByUserID(userID uint) (*model.TwoFactor, error)
+	DeleteByUserID(userID uint) error
+	UseStep(id uint, step int64) (bool, error)
+	CreateRecoveryCodes(codes []*model.RecoveryCode) error
+	DeleteRecoveryCodes(userID uint) error
+	GetRecoveryCode(userID uint, hash string) (*model.RecoveryCode, error)
+	UseRecoveryCode(id uint) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+type twoFactorDao struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorDao(db *gorm.DB) TwoFactorDaoInterface {
+	return &twoFactorDao{db: db}
+}
+
+func (twoFactor *twoFactorDao) Create(newTwoFactor *model.TwoFactor) error {
+	result := twoFactor.db.Model(&model.TwoFactor{}).Create(newTwoFactor)
+	return utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) Update(id uint, data map[string]interface{}) error {
+	result := twoFactor.db.Model(&model.TwoFactor{}).Where("id = ?", id).Updates(data)
+	return utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) GetByUserID(userID uint) (*model.TwoFactor, error) {
+	ret := &model.TwoFactor{}
+	result := twoFactor.db.Model(&model.TwoFactor{}).Where("user_id = ?", userID).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) DeleteByUserID(userID uint) error {
+	result := twoFactor.db.Model(&model.TwoFactor{}).Where("user_id = ?", userID).Delete(&model.TwoFactor{})
+	return utils.DBError(result)
+}
+
+/*
+Record step as used, false when it (or a later one) was already used
+*/
+func (twoFactor *twoFactorDao) UseStep(id uint, step int64) (bool, error) {
+	result := twoFactor.db.Model(&model.TwoFactor{}).Where("id = ? and last_step < ?", id, step).Update("last_step", step)
+	return result.RowsAffected == 1, utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) CreateRecoveryCodes(codes []*model.RecoveryCode) error {
+	result := twoFactor.db.Model(&model.RecoveryCode{}).Create(codes)
+	return utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) DeleteRecoveryCodes(userID uint) error {
+	result := twoFactor.db.Model(&model.RecoveryCode{}).Where("user_id = ?", userID).Delete(&model.RecoveryCode{})
+	return utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) GetRecoveryCode(userID uint, hash string) (*model.RecoveryCode, error) {
+	ret := &model.RecoveryCode{}
+	result := twoFactor.db.Model(&model.RecoveryCode{}).Where("user_id = ? and code_hash = ? and used = ?", userID, hash, false).First(ret)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return ret, utils.DBError(result)
+}
+
+/*
+Mark the code used, false when a concurrent request used it first
+*/
+func (twoFactor *twoFactorDao) UseRecoveryCode(id uint) (bool, error) {
+	result := twoFactor.db.Model(&model.RecoveryCode{}).Where("id = ? and used = ?", id, false).Update("used", true)
+	return result.RowsAffected == 1, utils.DBError(result)
+}
+
+func (twoFactor *twoFactorDao) CountRecoveryCodes(userID uint) (count int64, err error) {
+	result := twoFactor.db.Model(&model.RecoveryCode{}).Where("user_id = ? and used = ?", userID, false).Count(&count)
+	err = utils.DBError(result)
+	return
+}
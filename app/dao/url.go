@@ -7,37 +7,40 @@ import (
 	"gorm.io/gorm"
 )
 
-type urlDao struct {
+type UrlDaoInterface interface {
+	Create(newUrl model.Url) error
+	Delete(names []string, entity_id uint) error
+	Update(name string, entity_id uint, data map[string]interface{}) error
+	GetUrlByName(name string, entity_id uint) (*model.Url, error)
+	GetUrlsByEntity(entity_id uint, DepartmentSuper bool, EntitySuper bool, SystemSuper bool) (urls []*model.Url, err error)
 }
 
-var UrlDao *urlDao
-
-func newUrlDao() *urlDao {
-	return &urlDao{}
+type urlDao struct {
+	db *gorm.DB
 }
 
-func init() {
-	UrlDao = newUrlDao()
+func NewUrlDao(db *gorm.DB) UrlDaoInterface {
+	return &urlDao{db: db}
 }
 
 func (url *urlDao) Create(newUrl model.Url) error {
-	result := db.Model(&model.Url{}).Create(&newUrl)
+	result := url.db.Model(&model.Url{}).Create(&newUrl)
 	return utils.DBError(result)
 }
 
 func (url *urlDao) Delete(names []string, entity_id uint) error {
-	result := db.Model(&model.Url{}).Where("name in (?) and entity_id = ?", names, entity_id).Delete(&model.Url{})
+	result := url.db.Model(&model.Url{}).Where("name in (?) and entity_id = ?", names, entity_id).Delete(&model.Url{})
 	return utils.DBError(result)
 }
 
 func (url *urlDao) Update(name string, entity_id uint, data map[string]interface{}) error {
-	result := db.Model(&model.Url{}).Where("name = ? and entity_id = ?", name, entity_id).Updates(data)
+	result := url.db.Model(&model.Url{}).Where("name = ? and entity_id = ?", name, entity_id).Updates(data)
 	return utils.DBError(result)
 }
 
 func (url *urlDao) GetUrlByName(name string, entity_id uint) (*model.Url, error) {
 	ret := &model.Url{}
-	result := db.Model(&model.Url{}).Preload("Entity").Where("name = ? and entity_id = ?", name, entity_id).First(ret)
+	result := url.db.Model(&model.Url{}).Preload("Entity").Where("name = ? and entity_id = ?", name, entity_id).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -45,7 +48,7 @@ func (url *urlDao) GetUrlByName(name string, entity_id uint) (*model.Url, error)
 }
 
 func (url *urlDao) GetUrlsByEntity(entity_id uint, DepartmentSuper bool, EntitySuper bool, SystemSuper bool) (urls []*model.Url, err error) {
-	all_urls := db.Model(&model.Url{}).Preload("Entity").
+	all_urls := url.db.Model(&model.Url{}).Preload("Entity").
 		Where("entity_id = ?", entity_id)
 	if !DepartmentSuper && !EntitySuper && !SystemSuper {
 		all_urls = all_urls.Where("department_super = false and entity_super = false and system_super = false")
@@ -4,45 +4,141 @@ import (
 	"asset-management/app/model"
 	"asset-management/utils"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+type UserDaoInterface interface {
+	Create(newUser model.User) error
+	Update(id uint, data map[string]interface{}) error
+	UpdateByName(username string, data map[string]interface{}) error
+	Delete(id []uint) error
+	AllUser(Offset int, Limit int) (list []*model.User, count int64, err error)
+	GetLimitUser(begin int, length int) (list []model.User, err error)
+	GetUserByName(username string) (*model.User, error)
+	GetUserByID(id uint) (*model.User, error)
+	GetUsersByNames(username []string) (list []model.User, err error)
+	UserCount() (count int64, err error)
+	ModifyUserIdentity(username string, identity int) error
+	ModifyUserPassword(username string, password string) error
+	ModifyUserBanstate(username string, ban bool) error
+	GetUserEntity(username string) (entity model.Entity, err error)
+	ModifyUserEntity(username string, entity model.Entity) error
+	ModifyUserEntityByID(id uint, entityID uint) error
+	GetUserDepartment(username string) (department model.Department, err error)
+	ModifyUserDepartment(username string, department model.Department) error
+	ModifyUserDepartmentByID(id uint, departmentID uint) error
+	BindFeishu(UserID uint, FeishuID string) error
+	UpdateFeishuToken(UserID uint, FeishuToken string, RefreshToken string) error
+	GetUserByFeishuID(FeishuID string) (*model.User, error)
+}
+
 type userDao struct {
+	db *gorm.DB
 }
 
-var UserDao *userDao
+/*
+Columns a token's identity is built from, writing any of them outdates the user's tokens
+*/
+var securityColumns = []string{"system_super", "entity_super", "department_super", "entity_id", "department_id", "ban", "must_change_password"}
 
-func newUserDao() *userDao {
-	return &userDao{}
+func withSecurityVersion(data map[string]interface{}) map[string]interface{} {
+	for _, column := range securityColumns {
+		if _, ok := data[column]; ok {
+			data["security_version"] = gorm.Expr("security_version + 1")
+			break
+		}
+	}
+	return data
 }
 
-func init() {
-	UserDao = newUserDao()
+func NewUserDao(db *gorm.DB) UserDaoInterface {
+	return &userDao{db: db}
 }
 
 func (user *userDao) Create(newUser model.User) error {
-	result := db.Model(&model.User{}).Create(&newUser)
+	if newUser.PasswordChangedAt == nil {
+		now := time.Now()
+		newUser.PasswordChangedAt = &now
+	}
+	result := user.db.Model(&model.User{}).Create(&newUser)
 	return utils.DBError(result)
 }
 
 func (user *userDao) Update(id uint, data map[string]interface{}) error {
-	result := db.Model(&model.User{}).Where("id = ?", id).Updates(data)
-	return utils.DBError(result)
+	return user.updateWhere(data, "id = ?", id)
 }
 
 func (user *userDao) UpdateByName(username string, data map[string]interface{}) error {
-	result := db.Model(&model.User{}).Where("username = ?", username).Updates(data)
+	return user.updateWhere(data, "username = ?", username)
+}
+
+/*
+Moving users to another entity or department drops the role assignments they leave behind
+in the same transaction
+*/
+func (user *userDao) updateWhere(data map[string]interface{}, query string, args ...interface{}) error {
+	_, entityMoved := data["entity_id"]
+	_, departmentMoved := data["department_id"]
+	if !entityMoved && !departmentMoved {
+		result := user.db.Model(&model.User{}).Where(query, args...).Updates(withSecurityVersion(data))
+		return utils.DBError(result)
+	}
+	return user.db.Transaction(func(tx *gorm.DB) error {
+		var beforeList []*model.User
+		if err := utils.DBError(tx.Model(&model.User{}).Where(query, args...).Find(&beforeList)); err != nil {
+			return err
+		}
+		result := tx.Model(&model.User{}).Where(query, args...).Updates(withSecurityVersion(data))
+		if err := utils.DBError(result); err != nil {
+			return err
+		}
+		for _, before := range beforeList {
+			after := &model.User{}
+			if err := utils.DBError(tx.Model(&model.User{}).Where("id = ?", before.ID).First(after)); err != nil {
+				return err
+			}
+			if err := dropStrayAssignments(tx, before, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/*
+Role assignments hold within the user's entity only, and a grant on the department
+the user leaves goes with them
+*/
+func dropStrayAssignments(tx *gorm.DB, before *model.User, after *model.User) error {
+	result := tx.Where("user_id = ? and entity_id <> ?", after.ID, after.EntityID).Delete(&model.RoleAssignment{})
+	if err := utils.DBError(result); err != nil {
+		return err
+	}
+	if before.DepartmentID == 0 || before.DepartmentID == after.DepartmentID {
+		return nil
+	}
+	result = tx.Where("user_id = ? and department_id = ?", after.ID, before.DepartmentID).Delete(&model.RoleAssignment{})
 	return utils.DBError(result)
 }
 
+/*
+Role assignments are removed here rather than left to the foreign key, which SQLite does not enforce
+*/
 func (user *userDao) Delete(id []uint) error {
-	result := db.Model(&model.User{}).Where("id in (?)", id).Delete(&model.User{})
-	return utils.DBError(result)
+	return user.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id in (?)", id).Delete(&model.RoleAssignment{})
+		if err := utils.DBError(result); err != nil {
+			return err
+		}
+		result = tx.Model(&model.User{}).Where("id in (?)", id).Delete(&model.User{})
+		return utils.DBError(result)
+	})
 }
 
 func (user *userDao) AllUser(Offset int, Limit int) (list []*model.User, count int64, err error) {
-	result := db.Model(&model.User{}).Preload("Department").Preload("Entity").Count(&count).Offset(Offset).Limit(Limit).Find(&list)
+	result := user.db.Model(&model.User{}).Preload("Department").Preload("Entity").Count(&count).Offset(Offset).Limit(Limit).Find(&list)
 	err = utils.DBError(result)
 	return
 }
@@ -53,22 +149,22 @@ func (user *userDao) GetLimitUser(begin int, length int) (list []model.User, err
 		err = errors.New("invalid number")
 		return
 	}
-	result := db.Model(&model.User{}).Find(&list).Offset(begin - 1).Limit(length)
+	result := user.db.Model(&model.User{}).Find(&list).Offset(begin - 1).Limit(length)
 	err = utils.DBError(result)
 	return
 }
 
 func (user *userDao) GetUserByName(username string) (*model.User, error) {
 	ret := &model.User{}
-	result := db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("username = ?", username).First(ret)
+	result := user.db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("username = ?", username).First(ret)
 	// department := model.Department{}
-	// err := db.Model(&ret).Association("Department").Find(&department)
+	// err := user.db.Model(&ret).Association("Department").Find(&department)
 	// if err != nil {
 	// 	return nil, err
 	// }
 	// ret.Department = &department
 	// entity := &model.Entity{}
-	// err = db.Model(&ret).Association("Entity").Find(&entity)
+	// err = user.db.Model(&ret).Association("Entity").Find(&entity)
 	// if err != nil {
 	// 	return nil, err
 	// }
@@ -81,15 +177,15 @@ func (user *userDao) GetUserByName(username string) (*model.User, error) {
 
 func (user *userDao) GetUserByID(id uint) (*model.User, error) {
 	ret := &model.User{}
-	result := db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("id = ?", id).First(ret)
+	result := user.db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("id = ?", id).First(ret)
 	// department := model.Department{}
-	// err := db.Model(&ret).Association("Department").Find(&department)
+	// err := user.db.Model(&ret).Association("Department").Find(&department)
 	// if err != nil {
 	// 	return nil, err
 	// }
 	// ret.Department = &department
 	// entity := &model.Entity{}
-	// err = db.Model(&ret).Association("Entity").Find(&entity)
+	// err = user.db.Model(&ret).Association("Entity").Find(&entity)
 	// if err != nil {
 	// 	return nil, err
 	// }
@@ -101,13 +197,13 @@ func (user *userDao) GetUserByID(id uint) (*model.User, error) {
 }
 
 func (user *userDao) GetUsersByNames(username []string) (list []model.User, err error) {
-	result := db.Model(&model.User{}).Where("username IN ?", username).Order("id").Find(&list)
+	result := user.db.Model(&model.User{}).Where("username IN ?", username).Order("id").Find(&list)
 	err = utils.DBError(result)
 	return
 }
 
 func (user *userDao) UserCount() (count int64, err error) {
-	result := db.Model(&model.User{}).Count(&count)
+	result := user.db.Model(&model.User{}).Count(&count)
 	err = utils.DBError(result)
 	return
 }
@@ -183,7 +279,7 @@ func (user *userDao) GetUserEntity(username string) (entity model.Entity, err er
 		return
 	}
 	entity = *thisUser.Entity
-	//db.Model(&thisUser).Where("id = ?", thisUser.ID).Preload("entity").Find(&entity)
+	//user.db.Model(&thisUser).Where("id = ?", thisUser.ID).Preload("entity").Find(&entity)
 	return
 }
 
@@ -196,7 +292,8 @@ func (user *userDao) ModifyUserEntity(username string, entity model.Entity) erro
 		return errors.New("user doesn't exist")
 	}
 	thisUser.EntityID = entity.ID
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisUser))
+	thisUser.SecurityVersion++
+	err = utils.DBError(user.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisUser))
 	return err
 }
 
@@ -208,10 +305,9 @@ func (user *userDao) ModifyUserEntityByID(id uint, entityID uint) error {
 	if thisUser == nil {
 		return errors.New("user doesn't exist")
 	}
-	err = utils.DBError(db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+	return user.Update(id, map[string]interface{}{
 		"entity_id": entityID,
-	}))
-	return err
+	})
 }
 
 // User Department Part
@@ -225,7 +321,7 @@ func (user *userDao) GetUserDepartment(username string) (department model.Depart
 		return
 	}
 	department = *thisUser.Department
-	//err = db.Model(&thisUser).Association("Department").Find(&department)
+	//err = user.db.Model(&thisUser).Association("Department").Find(&department)
 	return
 }
 
@@ -237,13 +333,13 @@ func (user *userDao) ModifyUserDepartment(username string, department model.Depa
 	if thisUser == nil {
 		return errors.New("user doesn't exist")
 	}
-	thisUser.DepartmentID = department.ID
+	data := map[string]interface{}{
+		"department_id": department.ID,
+	}
 	if department.EntityID != 0 {
-		thisUser.EntityID = department.Entity.ID
+		data["entity_id"] = department.EntityID
 	}
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisUser))
-	//err = utils.DBError(db.Save(&thisUser))
-	return err
+	return user.Update(thisUser.ID, data)
 }
 
 func (user *userDao) ModifyUserDepartmentByID(id uint, departmentID uint) error {
@@ -254,10 +350,9 @@ func (user *userDao) ModifyUserDepartmentByID(id uint, departmentID uint) error
 	if thisUser == nil {
 		return errors.New("user doesn't exist")
 	}
-	err = utils.DBError(db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+	return user.Update(id, map[string]interface{}{
 		"department_id": departmentID,
-	}))
-	return err
+	})
 }
 
 // feishu
@@ -269,7 +364,7 @@ func (user *userDao) BindFeishu(UserID uint, FeishuID string) error {
 	if thisUser == nil {
 		return errors.New("user doesn't exist")
 	}
-	err = utils.DBError(db.Model(&model.User{}).Where("id = ?", UserID).Updates(map[string]interface{}{
+	err = utils.DBError(user.db.Model(&model.User{}).Where("id = ?", UserID).Updates(map[string]interface{}{
 		"feishu_id": FeishuID,
 	}))
 	return err
@@ -285,13 +380,13 @@ func (user *userDao) UpdateFeishuToken(UserID uint, FeishuToken string, RefreshT
 	}
 	thisUser.FeishuToken = FeishuToken
 	thisUser.RefreshToken = RefreshToken
-	err = utils.DBError(db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisUser))
+	err = utils.DBError(user.db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&thisUser))
 	return err
 }
 
 func (user *userDao) GetUserByFeishuID(FeishuID string) (*model.User, error) {
 	ret := &model.User{}
-	result := db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("feishu_id = ?", FeishuID).First(ret)
+	result := user.db.Model(&model.User{}).Preload("Department").Preload("Entity").Where("feishu_id = ?", FeishuID).First(ret)
 	if result.Error == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
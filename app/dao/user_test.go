@@ -2,19 +2,50 @@ package dao
 
 import (
 	"asset-management/app/model"
+	"asset-management/config"
 	"log"
 	"testing"
 
 	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var (
+	db            *gorm.DB
+	AssetDao      AssetDaoInterface
+	AssetClassDao AssetClassDaoInterface
+	DepartmentDao DepartmentDaoInterface
+	EntityDao     EntityDaoInterface
+	LogDao        LogDaoInterface
+	TaskDao       TaskDaoInterface
+	UrlDao        UrlDaoInterface
+	UserDao       UserDaoInterface
+	MysqlHook     logrus.Hook
 )
 
 func Init() {
-	InitForTest()
+	db = InitForTest()
+	daos := NewDaos(db)
+	AssetDao = daos.Asset
+	AssetClassDao = daos.AssetClass
+	DepartmentDao = daos.Department
+	EntityDao = daos.Entity
+	LogDao = daos.Log
+	TaskDao = daos.Task
+	UrlDao = daos.Url
+	UserDao = daos.User
+	MysqlHook = daos.LogHook
 }
 
 func TestInit(t *testing.T) {
-	Initial()
+	conf := config.ForTest().Database
+	// a named in-memory database lives while the migrating connection stays open
+	conf.DSN = "file:init_test?mode=memory&cache=shared"
+	_, err := MigrateUp(Open(conf))
+	assert.Equal(t, nil, err, "database error")
+	Initial(conf)
 }
 
 func TestUser(t *testing.T) {
@@ -159,8 +190,8 @@ func TestDepartmentEntity(t *testing.T) {
 	assert.Equal(t, nil, err, "database error")
 	err = DepartmentDao.Create(department)
 	assert.Equal(t, nil, err, "database error")
-	/*err = EntityDao.Create(entity)
-	assert.Equal(t, nil, err, "database error")*/
+	err = EntityDao.Create(entity)
+	assert.Equal(t, nil, err, "database error")
 
 	departments, err := DepartmentDao.AllDepartment()
 	if err != nil {
@@ -371,7 +402,7 @@ func TestAsset(t *testing.T) {
 	err = DepartmentDao.Create(department)
 	assert.Equal(t, nil, err, database_error)
 
-	new_department, err := DepartmentDao.GetDepartmentByID(3)
+	new_department, err := DepartmentDao.GetDepartmentByName("test_asset_department")
 	assert.Equal(t, nil, err, database_error)
 
 	new_class, err := AssetClassDao.GetAssetClassByID(1)
@@ -458,8 +489,6 @@ func TestAsset(t *testing.T) {
 	assert.Equal(t, "M78", new_line.Position, database_error)
 	err = AssetDao.ModifyAssetNum(2, 233)
 	assert.Equal(t, nil, err, database_error)
-	err = AssetDao.ModifyAssetState(2, 3)
-	assert.Equal(t, nil, err, database_error)
 	new_line, err = AssetDao.GetAssetByID(2)
 	assert.Equal(t, nil, err, database_error)
 	assert.Equal(t, 233, new_line.Number, database_error)
@@ -502,18 +531,6 @@ func TestAsset(t *testing.T) {
 	assert.Equal(t, nil, err, database_error)
 	//assert.Equal(t, "test_class", class.Name, database_error)
 
-	expire_list := []uint{1, 2}
-	err = AssetDao.ExpireAsset(expire_list)
-	assert.Equal(t, nil, err, database_error)
-	new_line, err = AssetDao.GetAssetByID(1)
-	assert.Equal(t, nil, err, database_error)
-	new_num_s, err = AssetDao.GetAssetByID(2)
-	assert.Equal(t, nil, err, database_error)
-	// assert.Equal(t, true, new_line.Expire, database_error)
-	assert.Equal(t, decimal.New(0, 0), new_line.NetWorth, database_error)
-	// assert.Equal(t, true, new_num_s.Expire, database_error)
-	assert.Equal(t, decimal.New(0, 0), new_num_s.NetWorth, database_error)
-
 	AssetClassDao.Update(1, map[string]interface{}{
 		"name": "asdkfjhjk",
 	})
@@ -0,0 +1,64 @@
+package define
+
+import (
+	"strings"
+	"time"
+)
+
+/*
+Prefixes of the long-lived credentials, anything else in the Authorization header is a JWT
+*/
+const (
+	PERSONAL_TOKEN_PREFIX = "amp_"
+	API_KEY_PREFIX        = "amk_"
+	API_SCOPE_READ        = "read"
+	API_SCOPE_WRITE       = "write"
+	API_TOKEN_MAX_DAYS    = 365
+)
+
+func IsApiToken(token string) bool {
+	return strings.HasPrefix(token, PERSONAL_TOKEN_PREFIX) || strings.HasPrefix(token, API_KEY_PREFIX)
+}
+
+/*
+What an API token resolves to, the identity is already narrowed to the token's scope
+*/
+type ApiTokenIdentity struct {
+	UserBasicInfo
+	TokenID  uint
+	ReadOnly bool
+}
+
+type CreateApiTokenReq struct {
+	Name          string `json:"name" binding:"required,max=64"`
+	Scope         string `json:"scope" binding:"required,oneof=read write"`
+	DepartmentID  uint   `json:"department_id"` // 0 means no department restriction
+	ExpiresInDays uint   `json:"expires_in_days" binding:"required,min=1,max=365"`
+}
+
+type ApiTokenInfo struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Kind         string     `json:"kind"`
+	Prefix       string     `json:"prefix"`
+	Scope        string     `json:"scope"`
+	EntityID     uint       `json:"entity_id"`
+	DepartmentID uint       `json:"department_id"`
+	UserID       uint       `json:"user_id"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	Revoked      bool       `json:"revoked"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ApiTokenListResponse struct {
+	TokenList []ApiTokenInfo `json:"token_list"`
+}
+
+/*
+The plain token is only shown once, on creation
+*/
+type CreateApiTokenResponse struct {
+	Token string       `json:"token"`
+	Info  ApiTokenInfo `json:"info"`
+}
@@ -2,11 +2,52 @@ package define
 
 import (
 	"asset-management/app/model"
+	"encoding/json"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"gorm.io/datatypes"
 )
 
+/*
+Asset lifecycle states, kept in model.Asset.State
+*/
+const (
+	ASSET_IDLE        uint = 0
+	ASSET_IN_USE      uint = 1
+	ASSET_IN_MAINTAIN uint = 2
+	ASSET_RETIRED     uint = 3
+	ASSET_DELETED     uint = 4
+)
+
+/*
+Lifecycle events, each one a transition of the asset state machine
+*/
+const (
+	ASSET_ACQUIRE         = "acquire"
+	ASSET_RETURN          = "return"
+	ASSET_MAINTAIN        = "maintain"
+	ASSET_FINISH_MAINTAIN = "finish_maintain"
+	ASSET_TRANSFER        = "transfer"
+	ASSET_RETIRE          = "retire"
+	ASSET_DELETE          = "delete"
+	ASSET_RESTORE         = "restore"
+	ASSET_SPLIT           = "split"
+	ASSET_MERGE           = "merge"
+)
+
+/*
+Values of model.Asset.Type, only a quantity-type asset can be split
+*/
+const (
+	ASSET_TYPE_ITEM     = 1
+	ASSET_TYPE_QUANTITY = 2
+)
+
+const ASSET_EXPIRED_REASON = "expired"
+
+const ASSET_PROPERTY_PREFIX = "property." // versioned property keys, beside plain column names
+
 type AssetInfo struct {
 	AssetID     uint                        `json:"asset_id" copier:"ID"`
 	AssetName   string                      `json:"asset_name" copier:"Name"`
@@ -74,7 +115,7 @@ type ModifyAssetInfoReq struct {
 	Position    string                      `json:"position"`
 	ClassID     uint                        `json:"class_id"`
 	Type        int                         `json:"type"`
-	Number      int                         `json:"count"`
+	Number      int                         `json:"count" binding:"gte=0"`
 	Expire      uint                        `json:"expire" binding:"gte=0"`
 	ImgList     datatypes.JSONSlice[string] `json:"img_list" binging:"dive,uri"`
 	Threshold   uint                        `json:"threshold"`
@@ -86,7 +127,7 @@ type CreateAssetReq struct {
 	Description string                      `json:"description"`
 	Position    string                      `json:"position"`
 	ClassID     uint                        `json:"class_id"`
-	Number      int                         `json:"count"`
+	Number      int                         `json:"count" binding:"gte=0"`
 	Type        int                         `json:"type"`
 	ParentID    uint                        `json:"parent_id"`
 	Expire      uint                        `json:"expire" binding:"gte=0"`
@@ -105,6 +146,7 @@ type ExpireAssetReq struct {
 
 type ExpireAssetListReq struct {
 	ExpireList []ExpireAssetReq `json:"asset_list"`
+	Reason     string           `json:"reason" binding:"max=255"`
 }
 
 type AssetListResponse struct {
@@ -116,6 +158,7 @@ type AssetListResponse struct {
 type AssetTransferReq struct {
 	UserID uint             `json:"user_id"`
 	Assets []ExpireAssetReq `json:"assets"`
+	Reason string           `json:"reason" binding:"max=255"`
 }
 
 type AssetPropertyReq struct {
@@ -142,6 +185,74 @@ type AssetHistoryResponse struct {
 	History []*AssetHistory `json:"history"`
 }
 
+type AssetStateHistory struct {
+	ID        uint      `json:"id"`
+	Event     string    `json:"event"`
+	FromState uint      `json:"from_state"`
+	ToState   uint      `json:"to_state"`
+	ActorID   uint      `json:"actor_id"` // 0 for timing jobs
+	ActorName string    `json:"actor_name"`
+	TaskID    uint      `json:"task_id"`
+	Reason    string    `json:"reason"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AssetStateHistoryResponse struct {
+	History []*AssetStateHistory `json:"history"`
+}
+
+// null stands for a property that did not exist
+type AssetFieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+type AssetVersionInfo struct {
+	Version   uint                        `json:"version"`
+	ActorID   uint                        `json:"actor_id"`
+	ActorName string                      `json:"actor_name"`
+	Changes   map[string]AssetFieldChange `json:"changes"`
+	RevertOf  *uint                       `json:"revert_of"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+type AssetVersionListResponse struct {
+	Versions []*AssetVersionInfo `json:"versions"`
+}
+
+type AssetRecycleReq struct {
+	Assets []ExpireAssetReq `json:"assets"`
+	Reason string           `json:"reason" binding:"max=255"`
+}
+
+type AssetRecycleInfo struct {
+	AssetID   uint                `json:"asset_id"`
+	AssetName string              `json:"asset_name"`
+	ParentID  uint                `json:"parent_id"` // reattached on restore if it is still there
+	User      AssetUserBasicInfo  `json:"user"`
+	Class     AssetClassBasicInfo `json:"asset_class"`
+	Price     decimal.Decimal     `json:"price"`
+	State     uint                `json:"state"` // the state a restore brings back
+	DeletedAt *model.ModelTime    `json:"deleted_at"`
+	PurgeAt   model.ModelTime     `json:"purge_at"`
+}
+
+type AssetRecycleBinResponse struct {
+	AssetList []*AssetRecycleInfo `json:"asset_list"`
+	AllCount  uint                `json:"all_count"`
+}
+
+/*
+Kept in model.AssetArchive.Snapshot when a deleted asset is purged
+*/
+type AssetArchiveSnapshot struct {
+	Asset    *model.Asset               `json:"asset"`
+	States   []*model.AssetStateHistory `json:"states"`
+	Versions []*model.AssetVersion      `json:"versions"`
+	TaskIDs  []uint                     `json:"task_ids"`
+}
+
 type SearchAssetReq struct {
 	Name        string `json:"name" binding:"gte=0,lte=20"`
 	UserID      uint   `json:"user_id"`
@@ -1,23 +1,31 @@
 package define
 
+import "github.com/shopspring/decimal"
+
 type CreateAssetClassReq struct {
-	ClassName string `json:"class_name"`
-	ParentID  uint   `json:"parent_id"`
-	Type      int    `json:"type"`
+	ClassName          string          `json:"class_name"`
+	ParentID           uint            `json:"parent_id"`
+	Type               int             `json:"type"`
+	DepreciationMethod string          `json:"depreciation_method"`
+	ResidualRate       decimal.Decimal `json:"residual_rate"`
 }
 
 type ModifyAssetClassReq struct {
-	ClassName string `json:"class_name"`
-	ParentID  *uint  `json:"parent_id"`
-	Type      int    `json:"type"`
+	ClassName          string           `json:"class_name"`
+	ParentID           *uint            `json:"parent_id"`
+	Type               int              `json:"type"`
+	DepreciationMethod *string          `json:"depreciation_method"`
+	ResidualRate       *decimal.Decimal `json:"residual_rate"`
 }
 
 type AssetClassTreeNode struct {
-	ClassID   uint                  `json:"class_id" copier:"ID"`
-	ClassName string                `json:"class_name" copier:"Name"`
-	ParentID  uint                  `json:"parent_id"`
-	Type      int                   `json:"type"`
-	Children  []*AssetClassTreeNode `json:"children"`
+	ClassID            uint                  `json:"class_id" copier:"ID"`
+	ClassName          string                `json:"class_name" copier:"Name"`
+	ParentID           uint                  `json:"parent_id"`
+	Type               int                   `json:"type"`
+	DepreciationMethod string                `json:"depreciation_method"`
+	ResidualRate       decimal.Decimal       `json:"residual_rate"`
+	Children           []*AssetClassTreeNode `json:"children"`
 }
 
 type AssetClassTreeResponse struct {
@@ -30,3 +30,7 @@ type AsyncTaskInfo struct {
 type AsyncTaskListResponse struct {
 	AsyncList []AsyncTaskInfo `json:"async_list"`
 }
+
+type UploadImportFileResponse struct {
+	ObjectKey string `json:"object_key"`
+}
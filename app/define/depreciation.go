@@ -0,0 +1,46 @@
+package define
+
+import (
+	"asset-management/app/model"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DEPRECIATION_STRAIGHT_LINE     = "straight_line"
+	DEPRECIATION_DECLINING_BALANCE = "declining_balance"
+	DEPRECIATION_DOUBLE_DECLINING  = "double_declining"
+	DEPRECIATION_SUM_OF_YEARS      = "sum_of_years"
+)
+
+/*
+Days between two points of a depreciation preview when none is asked for
+*/
+const DEPRECIATION_PREVIEW_STEP = 30
+
+/*
+Most points a depreciation preview has besides its last one, longer lives get a longer step
+*/
+const DEPRECIATION_PREVIEW_MAX_POINTS = 500
+
+/*
+Replaces the asset's own depreciation, an empty method or a null rate follows the class
+*/
+type AssetDepreciationReq struct {
+	DepreciationMethod string              `json:"depreciation_method"`
+	ResidualRate       decimal.NullDecimal `json:"residual_rate"`
+}
+
+type DepreciationPoint struct {
+	Day      int             `json:"day"`
+	Date     model.ModelTime `json:"date"`
+	NetWorth decimal.Decimal `json:"net_worth"`
+}
+
+type AssetDepreciationResponse struct {
+	DepreciationMethod string               `json:"depreciation_method"`
+	ResidualRate       decimal.Decimal      `json:"residual_rate"`
+	ResidualValue      decimal.Decimal      `json:"residual_value"`
+	Expire             uint                 `json:"expire"`
+	Schedule           []*DepreciationPoint `json:"schedule"`
+}
@@ -1,6 +1,9 @@
 package define
 
-import "asset-management/app/model"
+import (
+	"asset-management/app/model"
+	"time"
+)
 
 type CreateEntityReq struct {
 	EntityName string `json:"entity_name" binding:"required"`
@@ -66,3 +69,18 @@ type EntityUserListResponse struct {
 	UserList []EntityUserInfo `json:"user_list"`
 	AllCount uint             `json:"all_count"`
 }
+
+type LoginLockInfo struct {
+	ID            uint       `json:"id"`
+	Kind          string     `json:"kind"`
+	Key           string     `json:"key"`
+	Failures      uint       `json:"failures"`
+	Lockouts      uint       `json:"lockouts"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	Locked        bool       `json:"locked"`
+}
+
+type LoginLockListResponse struct {
+	LockList []LoginLockInfo `json:"lock_list"`
+}
@@ -0,0 +1,88 @@
+package define
+
+import "time"
+
+const (
+	AUTH_PURPOSE_LOGIN = "login"
+	AUTH_PURPOSE_BIND  = "bind"
+)
+
+/*
+Who the user is at an external provider, with the provider's tokens when it hands them out
+*/
+type ExternalIdentity struct {
+	Subject      string
+	Email        string
+	AccessToken  string
+	RefreshToken string
+}
+
+type IdentityProviderReq struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	Kind         string   `json:"kind" binding:"required,oneof=oidc feishu"`
+	Issuer       string   `json:"issuer" binding:"omitempty,url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"` // empty keeps the stored secret when modifying
+	Scopes       []string `json:"scopes"`
+	Enabled      *bool    `json:"enabled"` // defaults to true
+}
+
+type IdentityProviderInfo struct {
+	ID        uint      `json:"id"`
+	EntityID  uint      `json:"entity_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Issuer    string    `json:"issuer"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IdentityProviderListResponse struct {
+	ProviderList []IdentityProviderInfo `json:"provider_list"`
+}
+
+/*
+What the login page needs to offer a provider
+*/
+type LoginProviderInfo struct {
+	ID       uint   `json:"id"`
+	EntityID uint   `json:"entity_id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+}
+
+type LoginProviderListResponse struct {
+	ProviderList []LoginProviderInfo `json:"provider_list"`
+}
+
+type AuthorizeReq struct {
+	RedirectURI string `json:"redirect_uri" binding:"required,url"`
+}
+
+type AuthorizeResponse struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+/*
+What the provider redirected back with
+*/
+type AuthorizationCodeReq struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+type IdentityBindingInfo struct {
+	ProviderID   uint      `json:"provider_id"`
+	ProviderName string    `json:"provider_name"`
+	Kind         string    `json:"kind"`
+	Subject      string    `json:"subject"`
+	Email        string    `json:"email"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type IdentityBindingListResponse struct {
+	BindingList []IdentityBindingInfo `json:"binding_list"`
+}
@@ -0,0 +1,41 @@
+package define
+
+import "time"
+
+const (
+	INVITE_TOKEN_PREFIX = "ami_"
+	INVITE_MAX_DAYS     = 30
+	INVITE_MAX_USES     = 1000
+)
+
+type CreateInviteReq struct {
+	RoleID        uint `json:"role_id"` // 0 grants no role
+	MaxUses       uint `json:"max_uses" binding:"required,min=1,max=1000"`
+	ExpiresInDays uint `json:"expires_in_days" binding:"required,min=1,max=30"`
+}
+
+type InviteInfo struct {
+	ID           uint      `json:"id"`
+	EntityID     uint      `json:"entity_id"`
+	DepartmentID uint      `json:"department_id"`
+	RoleID       uint      `json:"role_id"`
+	CreatorID    uint      `json:"creator_id"`
+	Prefix       string    `json:"prefix"`
+	MaxUses      uint      `json:"max_uses"`
+	Uses         uint      `json:"uses"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Revoked      bool      `json:"revoked"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type InviteListResponse struct {
+	InviteList []InviteInfo `json:"invite_list"`
+}
+
+/*
+The plain token is only shown once, on creation
+*/
+type CreateInviteResponse struct {
+	Token string     `json:"token"`
+	Info  InviteInfo `json:"info"`
+}
@@ -1,23 +1,46 @@
 package define
 
-import "asset-management/app/model"
+import (
+	"asset-management/app/model"
+	"time"
+)
+
+/*
+Requests that complete a login, their logs are login logs and not data logs
+*/
+var LOGIN_URLS = []string{"/user/login", "/user/login/two-factor"}
 
 type LogInfo struct {
-	Method       string           `json:"method"`
-	URL          string           `json:"url"`
-	Status       int              `json:"status"`
-	ErrorCode    int              `json:"error_code"`
-	ErrorMessage string           `json:"error_message"`
-	UserID       uint             `json:"user_id"`
-	Username     string           `json:"username"`
-	EntityID     uint             `json:"entity_id"`
-	DepartmentID uint             `json:"department_id"`
-	Time         *model.ModelTime `json:"time"`
-	Level        string           `json:"level"`
-	Message      string           `json:"message"`
+	Method           string           `json:"method"`
+	URL              string           `json:"url"`
+	Status           int              `json:"status"`
+	ErrorCode        int              `json:"error_code"`
+	ErrorMessage     string           `json:"error_message"`
+	UserID           uint             `json:"user_id"`
+	Username         string           `json:"username"`
+	EntityID         uint             `json:"entity_id"`
+	DepartmentID     uint             `json:"department_id"`
+	ImpersonatorID   uint             `json:"impersonator_id"`
+	ImpersonatorName string           `json:"impersonator_name"`
+	Time             *model.ModelTime `json:"time"`
+	Level            string           `json:"level"`
+	Message          string           `json:"message"`
 }
 
 type LogListResponse struct {
 	LogList  []*LogInfo `json:"log_list"`
 	AllCount uint       `json:"all_count"`
 }
+
+/*
+Set on the context by the login handler when a failure locks a username or ip,
+LogMiddleware records it as a login log of the locked user's entity
+*/
+type LoginLockoutEvent struct {
+	UserID       uint
+	UserName     string
+	EntityID     uint
+	DepartmentID uint
+	Kind         string
+	LockedUntil  time.Time
+}
@@ -0,0 +1,44 @@
+package define
+
+const (
+	PASSWORD_CHANGE_PATH = "/user/info/:user_id/password" // the one route open while a password change is required
+	PASSWORD_MAX_LENGTH  = 72                             // bcrypt ignores anything past 72 bytes
+	PASSWORD_HISTORY_MAX = 12
+)
+
+const (
+	PASSWORD_RESET_PREFIX = "amr_"
+	RESET_BY_EMAIL        = "email"
+	RESET_BY_FEISHU       = "feishu"
+)
+
+type PasswordPolicyReq struct {
+	MinLength     uint `json:"min_length" binding:"required,min=1,max=72"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistorySize   uint `json:"history_size" binding:"max=12"`
+	MaxAgeDays    uint `json:"max_age_days" binding:"max=3650"` // 0 keeps passwords from expiring
+}
+
+type PasswordPolicyInfo struct {
+	EntityID      uint `json:"entity_id"`
+	MinLength     uint `json:"min_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistorySize   uint `json:"history_size"`
+	MaxAgeDays    uint `json:"max_age_days"`
+}
+
+type ForgotPasswordReq struct {
+	UserName string `json:"userName" binding:"required"`
+	Channel  string `json:"channel" binding:"required,oneof=email feishu"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
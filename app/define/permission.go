@@ -0,0 +1,112 @@
+package define
+
+import "github.com/thoas/go-funk"
+
+/*
+Named permissions, granted through roles on an entity or on one department
+*/
+const (
+	PERM_ASSET_MANAGE      = "asset.manage"
+	PERM_ASSET_APPROVE     = "asset.approve"
+	PERM_ASSET_IMPORT      = "asset.import"
+	PERM_LOG_VIEW          = "log.view"
+	PERM_LOG_EXPORT        = "log.export"
+	PERM_ROLE_MANAGE       = "role.manage"
+	PERM_USER_MANAGE       = "user.manage"       // view, lock and reset the users of the entity or department
+	PERM_DEPARTMENT_MANAGE = "department.manage" // create and remove departments, move users between them
+	PERM_ENTITY_MANAGE     = "entity.manage"     // the entity's settings, policies, login locks, tokens and urls
+	PERM_SYSTEM_MANAGE     = "system.manage"     // entities and users outside any one entity
+)
+
+/*
+Every permission a role may carry, system.manage belongs to system supers alone
+*/
+var PERMISSIONS = []string{
+	PERM_ASSET_MANAGE,
+	PERM_ASSET_APPROVE,
+	PERM_ASSET_IMPORT,
+	PERM_LOG_VIEW,
+	PERM_LOG_EXPORT,
+	PERM_ROLE_MANAGE,
+	PERM_USER_MANAGE,
+	PERM_DEPARTMENT_MANAGE,
+	PERM_ENTITY_MANAGE,
+}
+
+func IsPermission(permission string) bool {
+	return funk.ContainsString(PERMISSIONS, permission)
+}
+
+/*
+The built-in roles stand for the old super flags: system_super holds everywhere,
+entity_super on the user's entity, department_super on the user's department only
+*/
+const (
+	ROLE_SYSTEM_SUPER     = "system_super"
+	ROLE_ENTITY_SUPER     = "entity_super"
+	ROLE_DEPARTMENT_SUPER = "department_super"
+)
+
+type BuiltInRole struct {
+	Name        string
+	Permissions []string
+}
+
+var BUILT_IN_ROLES = []BuiltInRole{
+	{ROLE_SYSTEM_SUPER, []string{
+		PERM_LOG_VIEW, PERM_LOG_EXPORT, PERM_ROLE_MANAGE, PERM_USER_MANAGE, PERM_ENTITY_MANAGE, PERM_SYSTEM_MANAGE,
+	}},
+	{ROLE_ENTITY_SUPER, []string{
+		PERM_LOG_VIEW, PERM_LOG_EXPORT, PERM_ROLE_MANAGE, PERM_USER_MANAGE, PERM_DEPARTMENT_MANAGE, PERM_ENTITY_MANAGE,
+	}},
+	{ROLE_DEPARTMENT_SUPER, []string{PERM_ASSET_MANAGE, PERM_ASSET_APPROVE, PERM_ASSET_IMPORT, PERM_USER_MANAGE}},
+}
+
+func BuiltInPermissions(name string) []string {
+	for _, role := range BUILT_IN_ROLES {
+		if role.Name == name {
+			return role.Permissions
+		}
+	}
+	return nil
+}
+
+type RoleReq struct {
+	Name        string   `json:"name" binding:"required,max=64"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type RoleInfo struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	EntityID    uint     `json:"entity_id"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleListResponse struct {
+	RoleList []RoleInfo `json:"role_list"`
+}
+
+type RoleAssignmentReq struct {
+	UserID       uint `json:"user_id" binding:"required"`
+	RoleID       uint `json:"role_id" binding:"required"`
+	DepartmentID uint `json:"department_id"` // 0 means the whole entity
+}
+
+type RoleAssignmentInfo struct {
+	ID           uint   `json:"id"`
+	UserID       uint   `json:"user_id"`
+	RoleID       uint   `json:"role_id"`
+	RoleName     string `json:"role_name"`
+	EntityID     uint   `json:"entity_id"`
+	DepartmentID uint   `json:"department_id"`
+}
+
+type RoleAssignmentListResponse struct {
+	AssignmentList []RoleAssignmentInfo `json:"assignment_list"`
+}
+
+type PermissionListResponse struct {
+	Permissions []string `json:"permissions"`
+}
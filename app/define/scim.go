@@ -0,0 +1,127 @@
+package define
+
+import "encoding/json"
+
+/*
+Schema URNs of the SCIM 2.0 core resources and messages (RFC 7643, RFC 7644)
+*/
+const (
+	SCIM_USER_SCHEMA    = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIM_GROUP_SCHEMA   = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIM_LIST_SCHEMA    = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIM_PATCH_SCHEMA   = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIM_CONFIG_SCHEMA  = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIM_BASE_PATH      = "/scim/v2"
+	SCIM_RESOURCE_USER  = "User"
+	SCIM_RESOURCE_GROUP = "Group"
+	SCIM_MAX_PAGE_SIZE  = 200
+)
+
+/*
+Patch operations, compared case-insensitively since some clients capitalize them
+*/
+const (
+	SCIM_PATCH_ADD     = "add"
+	SCIM_PATCH_REMOVE  = "remove"
+	SCIM_PATCH_REPLACE = "replace"
+)
+
+/*
+The scimType of an error response
+*/
+const (
+	SCIM_FILTER_INVALID = "invalidFilter"
+	SCIM_SYNTAX_INVALID = "invalidSyntax"
+	SCIM_VALUE_INVALID  = "invalidValue"
+	SCIM_UNIQUENESS     = "uniqueness"
+	SCIM_MUTABILITY     = "mutability"
+)
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+/*
+A department a user is in, users belong to at most one
+*/
+type ScimGroupRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+/*
+A user of the entity, active is the opposite of the user's ban state
+*/
+type ScimUser struct {
+	Schemas  []string       `json:"schemas"`
+	ID       string         `json:"id,omitempty"`
+	UserName string         `json:"userName"`
+	Active   *bool          `json:"active,omitempty"`
+	Password string         `json:"password,omitempty"` // write only
+	Groups   []ScimGroupRef `json:"groups,omitempty"`   // read only, membership is managed on the group
+	Meta     *ScimMeta      `json:"meta,omitempty"`
+}
+
+type ScimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+/*
+A department of the entity with the users directly in it
+*/
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []ScimMember `json:"members"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type ScimPatchReq struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations" binding:"required"`
+}
+
+type ScimSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type ScimFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type ScimAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ScimServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 ScimSupported              `json:"patch"`
+	Bulk                  ScimSupported              `json:"bulk"`
+	Filter                ScimFilterSupported        `json:"filter"`
+	ChangePassword        ScimSupported              `json:"changePassword"`
+	Sort                  ScimSupported              `json:"sort"`
+	Etag                  ScimSupported              `json:"etag"`
+	AuthenticationSchemes []ScimAuthenticationScheme `json:"authenticationSchemes"`
+}
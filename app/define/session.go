@@ -0,0 +1,27 @@
+package define
+
+import "time"
+
+/*
+Where a login comes from, recorded on the session it starts
+*/
+type SessionClient struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+type SessionInfo struct {
+	ID         uint       `json:"id"`
+	Device     string     `json:"device"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Current    bool       `json:"current"` // the session of the requesting token
+}
+
+type SessionListResponse struct {
+	SessionList []SessionInfo `json:"session_list"`
+}
@@ -3,10 +3,18 @@ package define
 import "asset-management/app/model"
 
 type CreateTaskReq struct {
-	TaskType        uint             `json:"task_type" binding:"gte=0,lte=3"`
-	TaskDescription string           `json:"task_description"`
-	TargetID        uint             `json:"target_id" binding:"gte=0"`
-	AssetList       []ExpireAssetReq `json:"asset_list" binding:"gt=0,dive,gt=0"`
+	TaskType        uint           `json:"task_type" binding:"gte=0,lte=3"`
+	TaskDescription string         `json:"task_description"`
+	TargetID        uint           `json:"target_id" binding:"gte=0"`
+	AssetList       []TaskAssetReq `json:"asset_list" binding:"gt=0,dive"`
+}
+
+/*
+Quantity asks for part of a quantity-type asset, 0 moves the whole asset
+*/
+type TaskAssetReq struct {
+	AssetID  uint `json:"asset_id"`
+	Quantity int  `json:"quantity" binding:"gte=0"`
 }
 
 type TaskBasicInfo struct {
@@ -19,17 +27,18 @@ type TaskBasicInfo struct {
 }
 
 type TaskInfo struct {
-	ID              uint           `json:"task_id"`
-	TaskType        uint           `json:"task_type"` // 0领用、1退库、2维保、3转移
-	TaskDescription string         `json:"task_description"`
-	UserID          uint           `json:"user_id"`
-	UserName        string         `json:"username"`
-	TargetID        uint           `json:"target_id"`
-	TargetName      string         `json:"target_name"`
-	DepartmentID    uint           `json:"department_id"`
-	DepartmentName  string         `json:"department"`
-	AssetList       []*model.Asset `json:"asset_list"`
-	State           uint           `json:"state"`
+	ID              uint                  `json:"task_id"`
+	TaskType        uint                  `json:"task_type"` // 0领用、1退库、2维保、3转移
+	TaskDescription string                `json:"task_description"`
+	UserID          uint                  `json:"user_id"`
+	UserName        string                `json:"username"`
+	TargetID        uint                  `json:"target_id"`
+	TargetName      string                `json:"target_name"`
+	DepartmentID    uint                  `json:"department_id"`
+	DepartmentName  string                `json:"department"`
+	AssetList       []*model.Asset        `json:"asset_list"`
+	Quantities      []*model.TaskQuantity `json:"quantities"`
+	State           uint                  `json:"state"`
 }
 
 type TaskListResponse struct {
@@ -0,0 +1,54 @@
+package define
+
+import "github.com/dgrijalva/jwt-go"
+
+/*
+Claims of the short-lived token handed out between the password and the
+second factor, Setup means the user must enroll first because their entity requires it
+*/
+type TwoFactorClaims struct {
+	UserID uint `json:"user_id"`
+	Setup  bool `json:"setup"`
+	jwt.StandardClaims
+}
+
+/*
+Login answer when a second factor is needed instead of a UserLoginResponse
+*/
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorSetup    bool   `json:"two_factor_setup"`
+	TwoFactorToken    string `json:"two_factor_token"`
+}
+
+type TwoFactorLoginReq struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorTokenReq struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorPolicyReq struct {
+	Required *bool `json:"required" binding:"required"`
+}
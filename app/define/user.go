@@ -2,6 +2,7 @@ package define
 
 import (
 	"asset-management/app/model"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
@@ -10,8 +11,9 @@ import (
 .*Req struct are strictly defined according to the api
 */
 type UserRegisterReq struct {
-	UserName string `form:"userName" binding:"required" json:"userName" map:"userName,omitempty"`
-	Password string `form:"password" binding:"required" json:"password" map:"password,omitempty"`
+	UserName    string `form:"userName" binding:"required" json:"userName" map:"userName,omitempty"`
+	Password    string `form:"password" binding:"required" json:"password" map:"password,omitempty"`
+	InviteToken string `form:"inviteToken" json:"inviteToken" map:"inviteToken,omitempty"` // places the user in the invite's department
 }
 
 type UserLoginReq struct {
@@ -33,6 +35,10 @@ type ChangePasswordReq struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type ChangeEmailReq struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
 type GetAllUsersReq struct {
 	PageSize uint `json:"page_size"`
 	PageNum  uint `json:"page_num"`
@@ -42,13 +48,25 @@ type GetAllUsersReq struct {
 Basic info of user, can be included in other info struct
 */
 type UserBasicInfo struct {
+	UserID                 uint          `json:"user_id"`
+	UserName               string        `json:"username"`
+	EntitySuper            bool          `json:"entity_super"`
+	DepartmentSuper        bool          `json:"department_super"`
+	SystemSuper            bool          `json:"system_super"`
+	EntityID               uint          `json:"entity_id"`
+	DepartmentID           uint          `json:"department_id"`
+	SecurityVersion        uint          `json:"security_version"`
+	Impersonator           *Impersonator `json:"impersonator,omitempty"`             // set when an admin acts as this user
+	PasswordChangeRequired bool          `json:"password_change_required,omitempty"` // only the password change is allowed
+}
+
+/*
+The admin behind an impersonation token, their identity is checked alongside the user's
+*/
+type Impersonator struct {
 	UserID          uint   `json:"user_id"`
 	UserName        string `json:"username"`
-	EntitySuper     bool   `json:"entity_super"`
-	DepartmentSuper bool   `json:"department_super"`
-	SystemSuper     bool   `json:"system_super"`
-	EntityID        uint   `json:"entity_id"`
-	DepartmentID    uint   `json:"department_id"`
+	SecurityVersion uint   `json:"security_version"`
 }
 
 /*
@@ -64,6 +82,7 @@ type UserInfo struct {
 	UserName        string            `json:"username" copier:"UserName"`
 	Ban             bool              `json:"lock"`
 	IsEmployee      bool              `json:"id0" default:"true"`
+	Email           string            `json:"email"`
 	DepartmentSuper bool              `json:"id1"`
 	EntitySuper     bool              `json:"id2"`
 	SystemSuper     bool              `json:"id3"`
@@ -74,9 +93,31 @@ type UserInfo struct {
 }
 
 type UserLoginResponse struct {
-	Token    string   `json:"token"`
-	User     UserInfo `json:"user"`
-	FeishuID string   `json:"feishu_id"`
+	Token                  string   `json:"token"`
+	RefreshToken           string   `json:"refresh_token"`
+	User                   UserInfo `json:"user"`
+	FeishuID               string   `json:"feishu_id"`
+	RecoveryCodes          []string `json:"recovery_codes,omitempty"` // only when two-factor was enrolled during this login
+	PasswordChangeRequired bool     `json:"password_change_required"`
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token                  string `json:"token"`
+	RefreshToken           string `json:"refresh_token"`
+	PasswordChangeRequired bool   `json:"password_change_required"`
+}
+
+/*
+An impersonation token cannot be refreshed, a new one is needed once it expires
+*/
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      UserInfo  `json:"user"`
 }
 
 type UserInfoResponse struct {
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"asset-management/config"
+	"errors"
+)
+
+var ErrMailDisabled = errors.New("mail is not configured")
+
+/*
+Delivers plain text mail, through the configured SMTP server or a local catcher in tests
+*/
+type Sender interface {
+	Send(to string, subject string, body string) error
+}
+
+/*
+A sender for the configured SMTP server, without a host every mail fails with ErrMailDisabled
+*/
+func NewSender(conf config.MailConfig) Sender {
+	if conf.Host == "" {
+		return disabledSender{}
+	}
+	return &smtpSender{conf: conf}
+}
+
+type disabledSender struct{}
+
+func (disabledSender) Send(to string, subject string, body string) error {
+	return ErrMailDisabled
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"asset-management/app/mail/mailtest"
+	"asset-management/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSender(t *testing.T) {
+	err := NewSender(config.MailConfig{}).Send("alice@example.com", "subject", "body")
+	assert.Equal(t, ErrMailDisabled, err, "mail error")
+
+	catcher := mailtest.NewServer()
+	defer catcher.Close()
+	sender := NewSender(catcher.Config())
+
+	err = sender.Send("Alice <alice@example.com>", "密码重置", "line one\n.line two")
+	assert.Equal(t, nil, err, "mail error")
+	messages := catcher.Messages()
+	assert.Equal(t, 1, len(messages), "mail error")
+	assert.Equal(t, "asset-management@example.com", messages[0].From, "mail error")
+	assert.Equal(t, []string{"alice@example.com"}, messages[0].To, "mail error")
+	assert.Contains(t, messages[0].Data, "Subject: =?utf-8?q?", "mail error")
+	assert.Equal(t, true, strings.HasSuffix(messages[0].Data, "\r\n\r\nline one\r\n.line two\r\n"), "mail error")
+
+	// a recipient cannot smuggle in headers of their own
+	err = sender.Send("alice@example.com\r\nBcc: eve@example.com", "subject", "body")
+	assert.NotEqual(t, nil, err, "mail error")
+	assert.Equal(t, 1, len(catcher.Messages()), "mail error")
+}
@@ -0,0 +1,134 @@
+package mailtest
+
+import (
+	"asset-management/config"
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+A local SMTP catcher for tests: it accepts every mail without authentication
+and keeps it for the test to read
+*/
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []Message
+}
+
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	server := &Server{listener: listener}
+	go server.serve()
+	return server
+}
+
+/*
+Mail settings pointing at the catcher
+*/
+func (server *Server) Config() config.MailConfig {
+	addr := server.listener.Addr().(*net.TCPAddr)
+	return config.MailConfig{
+		Host: addr.IP.String(),
+		Port: int64(addr.Port),
+		From: "asset-management@example.com",
+	}
+}
+
+func (server *Server) Messages() []Message {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]Message(nil), server.messages...)
+}
+
+func (server *Server) Close() {
+	server.listener.Close()
+}
+
+func (server *Server) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.handle(conn)
+	}
+}
+
+func (server *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(code int, text string) {
+		conn.Write([]byte(strconv.Itoa(code) + " " + text + "\r\n"))
+	}
+
+	reply(220, "mailtest ready")
+	message := Message{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply(250, "mailtest")
+		case "MAIL":
+			message = Message{From: addressOf(line)}
+			reply(250, "OK")
+		case "RCPT":
+			message.To = append(message.To, addressOf(line))
+			reply(250, "OK")
+		case "DATA":
+			reply(354, "end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			message.Data = data.String()
+			server.mu.Lock()
+			server.messages = append(server.messages, message)
+			server.mu.Unlock()
+			reply(250, "OK")
+		case "RSET", "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+/*
+The address in "MAIL FROM:<a@b>" or "RCPT TO:<a@b>"
+*/
+func addressOf(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"asset-management/config"
+	"fmt"
+	"mime"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type smtpSender struct {
+	conf config.MailConfig
+}
+
+/*
+Addresses are parsed before they go into headers, so a crafted one cannot add headers of its own
+*/
+func (sender *smtpSender) Send(to string, subject string, body string) error {
+	toAddress, err := netmail.ParseAddress(to)
+	if err != nil {
+		return err
+	}
+	fromAddress, err := netmail.ParseAddress(sender.conf.From)
+	if err != nil {
+		return err
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", fromAddress.String())
+	fmt.Fprintf(&message, "To: %s\r\n", toAddress.String())
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if sender.conf.Username != "" {
+		auth = smtp.PlainAuth("", sender.conf.Username, sender.conf.Password, sender.conf.Host)
+	}
+	addr := sender.conf.Host + ":" + strconv.FormatInt(sender.conf.Port, 10)
+	return smtp.SendMail(addr, auth, fromAddress.Address, []string{toAddress.Address}, []byte(message.String()))
+}
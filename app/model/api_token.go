@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	API_TOKEN_PERSONAL = "personal"
+	API_TOKEN_ENTITY   = "entity"
+)
+
+/*
+A long-lived credential for scripts: personal tokens act as their owner,
+entity keys act as a manager of the entity on behalf of the user who created them.
+Scope is read or write, a non-zero DepartmentID narrows it to that department
+*/
+type ApiToken struct {
+	ID           uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	Name         string     `gorm:"column:name;size:64" json:"name"`
+	Kind         string     `gorm:"column:kind;size:16" json:"kind"`
+	UserID       uint       `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User         User       `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	EntityID     uint       `gorm:"column:entity_id;index" json:"entity_id"`
+	DepartmentID uint       `gorm:"column:department_id" json:"department_id"`
+	Scope        string     `gorm:"column:scope;size:16" json:"scope"`
+	Prefix       string     `gorm:"column:prefix;size:16" json:"prefix"`
+	TokenHash    string     `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt   *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	Revoked      bool       `gorm:"column:revoked;default:false" json:"revoked"`
+	CreatedAt    time.Time  `gorm:"column:created_at" json:"created_at"`
+}
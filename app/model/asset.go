@@ -21,8 +21,9 @@ type Asset struct {
 	ClassID      uint                        `gorm:"default:null;column:class_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"class_id"`
 	Class        AssetClass                  `gorm:"foreignKey:ClassID;references:ID;default:null" json:"class"`
 	Number       int                         `gorm:"column:number" json:"count"`
-	Type         int                         `gorm:"column:type" json:"type"`   // 1-条目型资产 2-数量型资产
-	State        uint                        `gorm:"column:state" json:"state"` // 0idle;1in_use;2in_maintain;3retired;4deleted
+	StockID      uint                        `gorm:"default:null;column:stock_id" json:"stock_id"` // the quantity-type asset this portion was split from
+	Type         int                         `gorm:"column:type" json:"type"`                      // 1-条目型资产 2-数量型资产
+	State        uint                        `gorm:"column:state" json:"state"`                    // 0idle;1in_use;2in_maintain;3retired;4deleted
 	MaintainerID uint                        `gorm:"default:null;column:maintainer_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"maintainer_id"`
 	Maintainer   User                        `gorm:"foreignKey:MaintainerID;references:ID;default:null" json:"maintainer"`
 	Property     datatypes.JSON              `gorm:"column:property;" json:"property"`
@@ -30,6 +31,13 @@ type Asset struct {
 	CreatedAt    *ModelTime                  `gorm:"column:created_at" json:"created_at"`
 	NetWorth     decimal.Decimal             `gorm:"type:decimal(10,2);column:net_worth" json:"net_worth"`
 	ImgList      datatypes.JSONSlice[string] `gorm:"column:img_list" json:"img_list"`
-	Warn         bool                        `gorm:"default:false;colimn:warn" json:"warn"`
+	Warn         bool                        `gorm:"default:false;column:warn" json:"warn"`
 	Threshold    uint                        `gorm:"default:0;column:threshold" json:"threshold"`
+	// where a deleted asset sat before it went to the recycle bin
+	DeletedAt       *ModelTime `gorm:"column:deleted_at;index" json:"deleted_at"`
+	DeletedParentID uint       `gorm:"default:null;column:deleted_parent_id" json:"deleted_parent_id"`
+	DeletedState    uint       `gorm:"default:0;column:deleted_state" json:"deleted_state"`
+	// overrides of the class's depreciation, empty follows the class
+	DepreciationMethod string              `gorm:"column:depreciation_method;size:32" json:"depreciation_method"`
+	ResidualRate       decimal.NullDecimal `gorm:"type:decimal(5,4);column:residual_rate;default:null" json:"residual_rate"`
 }
@@ -1,5 +1,11 @@
 package model
 
+import "github.com/shopspring/decimal"
+
+/*
+DepreciationMethod and ResidualRate are how the class's assets lose value,
+ResidualRate is the share of the price left at the end of an asset's life
+*/
 type AssetClass struct {
 	ID           uint        `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
 	Name         string      `gorm:"column:name;not null" json:"name"`
@@ -8,4 +14,7 @@ type AssetClass struct {
 	DepartmentID uint        `gorm:"default:null;column:department_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"department_id"`
 	Department   Department  `gorm:"foreignKey:DepartmentID;references:ID;default:null" json:"department"`
 	Type         int         `gorm:"column:type" json:"type"` // 1-条目型资产 2-数量型资产
+	// empty means straight line
+	DepreciationMethod string          `gorm:"column:depreciation_method;size:32" json:"depreciation_method"`
+	ResidualRate       decimal.Decimal `gorm:"type:decimal(5,4);column:residual_rate;default:0" json:"residual_rate"`
 }
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+/*
+What is left of a purged asset. Snapshot holds the asset row with its state history,
+versions and task ids; the asset itself is gone, so nothing references it
+*/
+type AssetArchive struct {
+	ID           uint           `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	AssetID      uint           `gorm:"column:asset_id;index" json:"asset_id"`
+	DepartmentID uint           `gorm:"column:department_id;index" json:"department_id"`
+	Name         string         `gorm:"column:name" json:"name"`
+	Snapshot     datatypes.JSON `gorm:"column:snapshot" json:"snapshot"`
+	DeletedAt    time.Time      `gorm:"column:deleted_at" json:"deleted_at"`
+	PurgedAt     time.Time      `gorm:"column:purged_at;index" json:"purged_at"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+/*
+One applied lifecycle transition of an asset. ActorID is empty for changes
+made by timing jobs, TaskID is empty for changes not driven by a task.
+Quantity is how many units the transition moved
+*/
+type AssetStateHistory struct {
+	ID        uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	AssetID   uint      `gorm:"column:asset_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"asset_id"`
+	Asset     Asset     `gorm:"foreignKey:AssetID;references:ID" json:"-"`
+	Event     string    `gorm:"column:event;size:32" json:"event"`
+	FromState uint      `gorm:"column:from_state" json:"from_state"`
+	ToState   uint      `gorm:"column:to_state" json:"to_state"`
+	ActorID   uint      `gorm:"default:null;column:actor_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"actor_id"`
+	Actor     User      `gorm:"foreignKey:ActorID;references:ID;default:null" json:"-"`
+	TaskID    uint      `gorm:"default:null;column:task_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"task_id"`
+	Task      Task      `gorm:"foreignKey:TaskID;references:ID;default:null" json:"-"`
+	Reason    string    `gorm:"column:reason;size:255" json:"reason"`
+	Quantity  int       `gorm:"column:quantity;default:0" json:"quantity"`
+	CreatedAt time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+/*
+One recorded edit of an asset's descriptive fields and properties. Changes maps each
+touched field to its old and new value, RevertOf is set when the edit restored a version
+*/
+type AssetVersion struct {
+	ID        uint           `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	AssetID   uint           `gorm:"column:asset_id;uniqueIndex:idx_asset_version;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"asset_id"`
+	Asset     Asset          `gorm:"foreignKey:AssetID;references:ID" json:"-"`
+	Version   uint           `gorm:"column:version;uniqueIndex:idx_asset_version" json:"version"` // counts from 1 per asset
+	ActorID   uint           `gorm:"default:null;column:actor_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"actor_id"`
+	Actor     User           `gorm:"foreignKey:ActorID;references:ID;default:null" json:"-"`
+	Changes   datatypes.JSON `gorm:"column:changes" json:"changes"`
+	RevertOf  *uint          `gorm:"column:revert_of;default:null" json:"revert_of"`
+	CreatedAt time.Time      `gorm:"column:created_at;index" json:"created_at"`
+}
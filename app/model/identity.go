@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	IDENTITY_PROVIDER_OIDC   = "oidc"
+	IDENTITY_PROVIDER_FEISHU = "feishu"
+)
+
+/*
+An external login provider an entity offers its users. OIDC providers carry their
+own client settings, Feishu ones use the application from the Feishu config
+*/
+type IdentityProvider struct {
+	ID           uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	EntityID     uint                        `gorm:"column:entity_id;index" json:"entity_id"`
+	Name         string                      `gorm:"column:name;size:64" json:"name"`
+	Kind         string                      `gorm:"column:kind;size:16" json:"kind"`
+	Issuer       string                      `gorm:"column:issuer;size:255" json:"issuer"`
+	ClientID     string                      `gorm:"column:client_id;size:255" json:"client_id"`
+	ClientSecret string                      `gorm:"column:client_secret;size:255" json:"-"`
+	Scopes       datatypes.JSONSlice[string] `gorm:"column:scopes" json:"scopes"`
+	Enabled      bool                        `gorm:"column:enabled;default:true" json:"enabled"`
+	CreatedAt    time.Time                   `gorm:"column:created_at" json:"created_at"`
+}
+
+/*
+Links an OIDC subject to a user, Feishu bindings stay on the user's feishu_id
+because approvals and messages address users by it
+*/
+type IdentityBinding struct {
+	ID         uint             `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID     uint             `gorm:"column:user_id;uniqueIndex:idx_binding_user_provider;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User       User             `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	ProviderID uint             `gorm:"column:provider_id;uniqueIndex:idx_binding_user_provider;uniqueIndex:idx_binding_provider_subject;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"provider_id"`
+	Provider   IdentityProvider `gorm:"foreignKey:ProviderID;references:ID" json:"-"`
+	Subject    string           `gorm:"column:subject;size:255;uniqueIndex:idx_binding_provider_subject" json:"subject"`
+	Email      string           `gorm:"column:email;size:255" json:"email"`
+	CreatedAt  time.Time        `gorm:"column:created_at" json:"created_at"`
+}
+
+/*
+One authorization in flight: the state is stored hashed, the PKCE verifier and nonce
+never leave the server. UserID is set when an account is binding a provider
+*/
+type AuthorizationState struct {
+	ID           uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	StateHash    string    `gorm:"column:state_hash;size:64;uniqueIndex" json:"-"`
+	ProviderID   uint      `gorm:"column:provider_id;index" json:"provider_id"`
+	Purpose      string    `gorm:"column:purpose;size:16" json:"purpose"`
+	UserID       uint      `gorm:"column:user_id" json:"user_id"`
+	CodeVerifier string    `gorm:"column:code_verifier;size:128" json:"-"`
+	Nonce        string    `gorm:"column:nonce;size:128" json:"-"`
+	RedirectURI  string    `gorm:"column:redirect_uri;size:512" json:"redirect_uri"`
+	ExpiresAt    time.Time `gorm:"column:expires_at" json:"expires_at"`
+}
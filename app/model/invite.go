@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+/*
+A link for registering straight into a department, optionally with a role granted there.
+The token is stored hashed, an invite with MaxUses of 1 is single-use
+*/
+type Invite struct {
+	ID           uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	EntityID     uint      `gorm:"column:entity_id;index" json:"entity_id"`
+	DepartmentID uint      `gorm:"column:department_id;index" json:"department_id"`
+	RoleID       uint      `gorm:"column:role_id;default:0" json:"role_id"` // 0 grants no role
+	CreatorID    uint      `gorm:"column:creator_id" json:"creator_id"`
+	Prefix       string    `gorm:"column:prefix;size:16" json:"prefix"`
+	TokenHash    string    `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	MaxUses      uint      `gorm:"column:max_uses" json:"max_uses"`
+	Uses         uint      `gorm:"column:uses;default:0" json:"uses"`
+	ExpiresAt    time.Time `gorm:"column:expires_at" json:"expires_at"`
+	Revoked      bool      `gorm:"column:revoked;default:false" json:"revoked"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+const (
+	LOCK_BY_USERNAME = "username"
+	LOCK_BY_IP       = "ip"
+)
+
+/*
+Failed login tracking for one username or one client ip, Lockouts counts
+how often the key has been locked and drives the growing backoff
+*/
+type LoginLock struct {
+	ID            uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	Kind          string     `gorm:"column:kind;size:16;uniqueIndex:idx_login_lock_key" json:"kind"`
+	Key           string     `gorm:"column:lock_key;size:128;uniqueIndex:idx_login_lock_key" json:"key"`
+	EntityID      uint       `gorm:"column:entity_id;index" json:"entity_id"`
+	Failures      uint       `gorm:"column:failures;default:0" json:"failures"`
+	Lockouts      uint       `gorm:"column:lockouts;default:0" json:"lockouts"`
+	LockedUntil   *time.Time `gorm:"column:locked_until" json:"locked_until"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at" json:"last_failure_at"`
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;column:version;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"column:name;not null" json:"name"`
+	AppliedAt time.Time `gorm:"column:applied_at" json:"applied_at"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+/*
+An entity's rules for new passwords, HistorySize of N rejects the current and the
+previous N-1 passwords, MaxAgeDays of 0 keeps passwords from expiring
+*/
+type PasswordPolicy struct {
+	ID            uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	EntityID      uint      `gorm:"column:entity_id;uniqueIndex" json:"entity_id"`
+	MinLength     uint      `gorm:"column:min_length" json:"min_length"`
+	RequireUpper  bool      `gorm:"column:require_upper;default:false" json:"require_upper"`
+	RequireLower  bool      `gorm:"column:require_lower;default:false" json:"require_lower"`
+	RequireDigit  bool      `gorm:"column:require_digit;default:false" json:"require_digit"`
+	RequireSymbol bool      `gorm:"column:require_symbol;default:false" json:"require_symbol"`
+	HistorySize   uint      `gorm:"column:history_size;default:0" json:"history_size"`
+	MaxAgeDays    uint      `gorm:"column:max_age_days;default:0" json:"max_age_days"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+/*
+Hashes of passwords a user has had before, kept to stop them being reused
+*/
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID       uint      `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User         User      `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	PasswordHash string    `gorm:"column:password_hash" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+/*
+A single use token for resetting a forgotten password, sent to the user and stored hashed.
+IP is the client that asked for it, resets are rate limited by user and by ip.
+A request that reached nobody is kept used up and without a user for the ip limit
+*/
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID    uint      `gorm:"default:null;column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	TokenHash string    `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	Channel   string    `gorm:"column:channel;size:16" json:"channel"`
+	IP        string    `gorm:"column:ip;size:64;index" json:"ip"`
+	ExpiresAt time.Time `gorm:"column:expires_at" json:"expires_at"`
+	Used      bool      `gorm:"column:used;default:false" json:"used"`
+	CreatedAt time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+/*
+A named set of permissions, built-in roles have no entity and cannot be changed,
+custom roles belong to the entity that defined them
+*/
+type Role struct {
+	ID          uint                        `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	Name        string                      `gorm:"column:name;size:64" json:"name"`
+	EntityID    uint                        `gorm:"column:entity_id;index" json:"entity_id"`
+	BuiltIn     bool                        `gorm:"column:built_in;default:false" json:"built_in"`
+	Permissions datatypes.JSONSlice[string] `gorm:"column:permissions" json:"permissions"`
+	CreatedAt   time.Time                   `gorm:"column:created_at" json:"created_at"`
+}
+
+/*
+Grants a role to a user on a whole entity, or on one department when DepartmentID is set
+*/
+type RoleAssignment struct {
+	ID           uint `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID       uint `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User         User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	RoleID       uint `gorm:"column:role_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"role_id"`
+	Role         Role `gorm:"foreignKey:RoleID;references:ID" json:"role"`
+	EntityID     uint `gorm:"column:entity_id;index" json:"entity_id"`
+	DepartmentID uint `gorm:"column:department_id" json:"department_id"`
+}
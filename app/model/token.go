@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+/*
+Sizes of the client columns of a session, longer values are cut to fit
+*/
+const (
+	SESSION_DEVICE_SIZE     = 64
+	SESSION_IP_SIZE         = 64
+	SESSION_USER_AGENT_SIZE = 255
+)
+
+/*
+One login grant, shown to the user as a session: the refresh token is stored hashed,
+access tokens carry the row ID so revoking the row revokes every token issued from it
+*/
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID     uint       `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	TokenHash  string     `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	Device     string     `gorm:"column:device;size:64" json:"device"`
+	IP         string     `gorm:"column:ip;size:64" json:"ip"`
+	UserAgent  string     `gorm:"column:user_agent;size:255" json:"user_agent"`
+	LastSeenAt *time.Time `gorm:"column:last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	Revoked    bool       `gorm:"column:revoked;default:false" json:"revoked"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+}
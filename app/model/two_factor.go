@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+/*
+A user's TOTP enrollment, Enabled stays false until the first code
+is confirmed, LastStep keeps a code from being accepted twice
+*/
+type TwoFactor struct {
+	ID        uint       `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID    uint       `gorm:"column:user_id;uniqueIndex;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	Secret    string     `gorm:"column:secret;size:64" json:"-"`
+	Enabled   bool       `gorm:"column:enabled;default:false" json:"enabled"`
+	LastStep  int64      `gorm:"column:last_step;default:0" json:"-"`
+	EnabledAt *time.Time `gorm:"column:enabled_at" json:"enabled_at"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+/*
+Single use fallback codes, stored hashed
+*/
+type RecoveryCode struct {
+	ID       uint   `gorm:"primaryKey;column:id;AUTO_INCREMENT" json:"id"`
+	UserID   uint   `gorm:"column:user_id;index;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
+	User     User   `gorm:"foreignKey:UserID;references:ID" json:"-"`
+	CodeHash string `gorm:"column:code_hash;size:64" json:"-"`
+	Used     bool   `gorm:"column:used;default:false" json:"used"`
+}
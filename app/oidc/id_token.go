@@ -0,0 +1,99 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+/*
+Clock skew tolerated between us and the provider
+*/
+const CLOCK_SKEW = time.Minute
+
+/*
+The aud claim is a single string or a list of them
+*/
+type audience []string
+
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*aud = list
+	return nil
+}
+
+func (aud audience) contains(clientID string) bool {
+	for _, item := range aud {
+		if item == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+type IDToken struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expiry   int64    `json:"exp"`
+	IssuedAt int64    `json:"iat"`
+	Nonce    string   `json:"nonce"`
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+}
+
+/*
+Time checks only, issuer, audience and nonce are checked by VerifyIDToken
+*/
+func (token *IDToken) Valid() error {
+	now := time.Now()
+	if token.Expiry == 0 || now.After(time.Unix(token.Expiry, 0).Add(CLOCK_SKEW)) {
+		return errors.New("oidc: id token expired")
+	}
+	if token.IssuedAt != 0 && now.Add(CLOCK_SKEW).Before(time.Unix(token.IssuedAt, 0)) {
+		return errors.New("oidc: id token issued in the future")
+	}
+	return nil
+}
+
+/*
+Check the signature of the ID token against the provider's JWKS and that it was issued
+by the provider, for us, in answer to the authorization that carried the nonce
+*/
+func (client *Client) VerifyIDToken(raw string, nonce string) (*IDToken, error) {
+	token := &IDToken{}
+	_, err := jwt.ParseWithClaims(raw, token, func(parsed *jwt.Token) (interface{}, error) {
+		if _, ok := parsed.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("oidc: unexpected signing method " + parsed.Method.Alg())
+		}
+		kid, _ := parsed.Header["kid"].(string)
+		return client.keys.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(token.Issuer, "/") != strings.TrimRight(client.discovery.Issuer, "/") {
+		return nil, errors.New("oidc: id token issued by " + token.Issuer)
+	}
+	if !token.Audience.contains(client.conf.ClientID) {
+		return nil, errors.New("oidc: id token not issued for this client")
+	}
+	if token.Nonce != nonce {
+		return nil, errors.New("oidc: id token nonce mismatch")
+	}
+	if token.Subject == "" {
+		return nil, errors.New("oidc: id token has no subject")
+	}
+	return token, nil
+}
@@ -0,0 +1,77 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"sync"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+/*
+The provider's signing keys by key ID, fetched again when a token names an unknown key
+so that key rotation needs no restart
+*/
+type keySet struct {
+	uri  string
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+func newKeySet(uri string) *keySet {
+	return &keySet{uri: uri}
+}
+
+func (set *keySet) refresh() error {
+	var jwks jsonWebKeySet
+	if err := getJSON(set.uri, &jwks); err != nil {
+		return err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	set.keys = keys
+	return nil
+}
+
+func (set *keySet) key(kid string) (*rsa.PublicKey, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if key, ok := set.keys[kid]; ok {
+		return key, nil
+	}
+	if err := set.refresh(); err != nil {
+		return nil, err
+	}
+	if key, ok := set.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errors.New("oidc: unknown signing key " + kid)
+}
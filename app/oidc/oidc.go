@@ -0,0 +1,149 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DISCOVERY_PATH = "/.well-known/openid-configuration"
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+/*
+The part of a provider's discovery document the authorization code flow needs
+*/
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+/*
+A relying party of one provider, built from its discovery document
+*/
+type Client struct {
+	conf      Config
+	discovery Discovery
+	keys      *keySet
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func getJSON(target string, v interface{}) error {
+	res, err := httpClient.Get(target)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s returned %s", target, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+/*
+Fetch the discovery document of the issuer, which must name itself as that issuer
+*/
+func Discover(conf Config) (*Client, error) {
+	issuer := strings.TrimRight(conf.Issuer, "/")
+	var discovery Discovery
+	if err := getJSON(issuer+DISCOVERY_PATH, &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", discovery.Issuer, conf.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, errors.New("oidc: incomplete discovery document")
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid"}
+	}
+	return &Client{
+		conf:      conf,
+		discovery: discovery,
+		keys:      newKeySet(discovery.JwksURI),
+	}, nil
+}
+
+/*
+Where to send the browser, the code challenge binds the code to the verifier kept on our side
+*/
+func (client *Client) AuthCodeURL(redirectURI string, state string, nonce string, challenge string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", client.conf.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", strings.Join(client.conf.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+	separator := "?"
+	if strings.Contains(client.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return client.discovery.AuthorizationEndpoint + separator + query.Encode()
+}
+
+/*
+Redeem the authorization code, the client authenticates with client_secret_post
+*/
+func (client *Client) Exchange(code string, redirectURI string, verifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", client.conf.ClientID)
+	form.Set("code_verifier", verifier)
+	if client.conf.ClientSecret != "" {
+		form.Set("client_secret", client.conf.ClientSecret)
+	}
+	res, err := httpClient.PostForm(client.discovery.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		var tokenErr tokenError
+		json.Unmarshal(body, &tokenErr)
+		return nil, fmt.Errorf("oidc: token endpoint returned %s: %s %s", res.Status, tokenErr.Error, tokenErr.ErrorDescription)
+	}
+	token := &Token{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	return token, nil
+}
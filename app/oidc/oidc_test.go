@@ -0,0 +1,54 @@
+package oidc
+
+import (
+	"asset-management/app/oidc/oidctest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("asset-client", "asset-secret")
+	defer idp.Close()
+	idp.Login("alice", "alice@example.com")
+
+	client, err := Discover(Config{Issuer: idp.URL, ClientID: "asset-client", ClientSecret: "asset-secret"})
+	assert.Equal(t, nil, err, "oidc error")
+
+	verifier, err := RandomString()
+	assert.Equal(t, nil, err, "oidc error")
+	redirectURI := "http://localhost/callback"
+	code, state, err := oidctest.Authorize(client.AuthCodeURL(redirectURI, "state-1", "nonce-1", CodeChallenge(verifier)))
+	assert.Equal(t, nil, err, "oidc error")
+	assert.Equal(t, "state-1", state, "oidc error")
+
+	// the code is bound to the verifier
+	_, err = client.Exchange(code, redirectURI, "wrong-verifier")
+	assert.NotEqual(t, nil, err, "oidc error")
+	code, _, err = oidctest.Authorize(client.AuthCodeURL(redirectURI, "state-1", "nonce-1", CodeChallenge(verifier)))
+	assert.Equal(t, nil, err, "oidc error")
+	token, err := client.Exchange(code, redirectURI, verifier)
+	assert.Equal(t, nil, err, "oidc error")
+	_, err = client.Exchange(code, redirectURI, verifier)
+	assert.NotEqual(t, nil, err, "oidc error")
+
+	_, err = client.VerifyIDToken(token.IDToken, "other-nonce")
+	assert.NotEqual(t, nil, err, "oidc error")
+	idToken, err := client.VerifyIDToken(token.IDToken, "nonce-1")
+	assert.Equal(t, nil, err, "oidc error")
+	assert.Equal(t, "alice", idToken.Subject, "oidc error")
+	assert.Equal(t, "alice@example.com", idToken.Email, "oidc error")
+
+	// a token issued to another client is not accepted
+	other, err := Discover(Config{Issuer: idp.URL, ClientID: "other-client"})
+	assert.Equal(t, nil, err, "oidc error")
+	_, err = other.VerifyIDToken(token.IDToken, "nonce-1")
+	assert.NotEqual(t, nil, err, "oidc error")
+
+	// a tampered token fails the signature check
+	_, err = client.VerifyIDToken(token.IDToken+"x", "nonce-1")
+	assert.NotEqual(t, nil, err, "oidc error")
+
+	_, err = Discover(Config{Issuer: idp.URL + "/elsewhere", ClientID: "asset-client"})
+	assert.NotEqual(t, nil, err, "oidc error")
+}
@@ -0,0 +1,215 @@
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const KEY_ID = "mock-key"
+
+/*
+A local OpenID provider for tests: every authorization is approved at once
+for the user set with Login, codes require the PKCE verifier and are single use
+*/
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key     *rsa.PrivateKey
+	mu      sync.Mutex
+	subject string
+	email   string
+	codes   map[string]pendingCode
+}
+
+type pendingCode struct {
+	subject     string
+	email       string
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+func NewServer(clientID string, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	server := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        map[string]pendingCode{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", server.discovery)
+	mux.HandleFunc("/authorize", server.authorize)
+	mux.HandleFunc("/token", server.token)
+	mux.HandleFunc("/jwks", server.jwks)
+	server.Server = httptest.NewServer(mux)
+	return server
+}
+
+/*
+The identity the next authorizations log in as
+*/
+func (server *Server) Login(subject string, email string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.subject = subject
+	server.email = email
+}
+
+/*
+Follow an authorization URL like a browser would and return the code and state
+the provider redirects back with
+*/
+func Authorize(authURL string) (code string, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	location, err := res.Location()
+	if err != nil {
+		return
+	}
+	query := location.Query()
+	if query.Get("error") != "" {
+		err = errors.New(query.Get("error"))
+		return
+	}
+	return query.Get("code"), query.Get("state"), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func (server *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                           server.URL,
+		"authorization_endpoint":           server.URL + "/authorize",
+		"token_endpoint":                   server.URL + "/token",
+		"jwks_uri":                         server.URL + "/jwks",
+		"response_types_supported":         []string{"code"},
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (server *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	back := url.Values{}
+	back.Set("state", query.Get("state"))
+	server.mu.Lock()
+	switch {
+	case query.Get("client_id") != server.ClientID:
+		back.Set("error", "unauthorized_client")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		back.Set("error", "invalid_request")
+	case server.subject == "":
+		back.Set("error", "access_denied")
+	default:
+		code := randomString()
+		server.codes[code] = pendingCode{
+			subject:     server.subject,
+			email:       server.email,
+			clientID:    query.Get("client_id"),
+			redirectURI: query.Get("redirect_uri"),
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+		}
+		back.Set("code", code)
+	}
+	server.mu.Unlock()
+	redirect.RawQuery = back.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (server *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if r.PostForm.Get("client_id") != server.ClientID || r.PostForm.Get("client_secret") != server.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	server.mu.Lock()
+	pending, ok := server.codes[r.PostForm.Get("code")]
+	delete(server.codes, r.PostForm.Get("code"))
+	server.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || pending.clientID != server.ClientID || pending.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   server.URL,
+		"sub":   pending.subject,
+		"aud":   server.ClientID,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": pending.nonce,
+		"email": pending.email,
+	})
+	idToken.Header["kid"] = KEY_ID
+	signed, err := idToken.SignedString(server.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  randomString(),
+		"refresh_token": randomString(),
+		"id_token":      signed,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func (server *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KEY_ID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(server.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(server.key.E)).Bytes()),
+		}},
+	})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const API_TOKEN_TOUCH_INTERVAL = time.Minute
+
+func newApiToken(kind string) (token string, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	prefix := define.PERSONAL_TOKEN_PREFIX
+	if kind == model.API_TOKEN_ENTITY {
+		prefix = define.API_KEY_PREFIX
+	}
+	token = prefix + hex.EncodeToString(buf)
+	hash = hashRefreshToken(token)
+	return
+}
+
+/*
+Whether the department lies within what the user can see: the whole entity for
+entity supers, the own subtree for department supers, the own department otherwise
+*/
+func (token *tokenService) coversDepartment(thisUser *model.User, entityID uint, departmentID uint) (bool, error) {
+	thisDepartment, err := token.departmentDao.GetDepartmentByID(departmentID)
+	if err != nil || thisDepartment == nil || thisDepartment.EntityID != entityID {
+		return false, err
+	}
+	if thisUser.SystemSuper || thisUser.EntitySuper {
+		return true, nil
+	}
+	if !thisUser.DepartmentSuper {
+		return thisUser.DepartmentID == departmentID, nil
+	}
+	for thisDepartment != nil {
+		if thisDepartment.ID == thisUser.DepartmentID {
+			return true, nil
+		}
+		thisDepartment, err = token.departmentDao.GetDepartmentByID(thisDepartment.ParentID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+/*
+Checked on creation and again on every use, so a token dies with its owner's rights:
+entity keys need a creator who still manages the entity, personal tokens an owner
+still in the entity who can still see the scoped department
+*/
+func (token *tokenService) scopeAllowed(thisUser *model.User, kind string, entityID uint, departmentID uint) (bool, error) {
+	if thisUser == nil || thisUser.Ban {
+		return false, nil
+	}
+	if kind == model.API_TOKEN_ENTITY {
+		if !thisUser.SystemSuper && !(thisUser.EntitySuper && thisUser.EntityID == entityID) {
+			return false, nil
+		}
+	} else if thisUser.EntityID != entityID {
+		return false, nil
+	}
+	if departmentID == 0 {
+		return true, nil
+	}
+	return token.coversDepartment(thisUser, entityID, departmentID)
+}
+
+/*
+Create a personal token (acting as its owner) or an entity key (acting as a manager
+of the entity), the plain token is returned once and only its hash is kept,
+an empty token means the requested department is out of scope
+*/
+func (token *tokenService) CreateApiToken(ownerID uint, kind string, entityID uint, req define.CreateApiTokenReq) (string, *model.ApiToken, error) {
+	owner, err := token.userDao.GetUserByID(ownerID)
+	if err != nil {
+		return "", nil, err
+	}
+	allowed, err := token.scopeAllowed(owner, kind, entityID, req.DepartmentID)
+	if err != nil || !allowed {
+		return "", nil, err
+	}
+
+	plain, hash, err := newApiToken(kind)
+	if err != nil {
+		return "", nil, err
+	}
+	newToken := &model.ApiToken{
+		Name:         req.Name,
+		Kind:         kind,
+		UserID:       ownerID,
+		EntityID:     entityID,
+		DepartmentID: req.DepartmentID,
+		Scope:        req.Scope,
+		Prefix:       plain[:8],
+		TokenHash:    hash,
+		ExpiresAt:    token.now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour),
+	}
+	if err := token.apiTokenDao.Create(newToken); err != nil {
+		return "", nil, err
+	}
+	return plain, newToken, nil
+}
+
+func (token *tokenService) GetUserApiTokens(userID uint) ([]*model.ApiToken, error) {
+	return token.apiTokenDao.GetUserTokens(userID)
+}
+
+func (token *tokenService) GetEntityApiKeys(entityID uint) ([]*model.ApiToken, error) {
+	return token.apiTokenDao.GetEntityKeys(entityID)
+}
+
+func (token *tokenService) GetApiTokenByID(id uint) (*model.ApiToken, error) {
+	return token.apiTokenDao.GetTokenByID(id)
+}
+
+func (token *tokenService) RevokeApiToken(id uint) error {
+	return token.apiTokenDao.RevokeToken(id)
+}
+
+/*
+Turn a presented API token into the identity it acts with, narrowed to its scope,
+nil means the token is unknown, expired, revoked or its owner lost the rights behind it
+*/
+func (token *tokenService) ResolveApiToken(plain string) (*define.ApiTokenIdentity, error) {
+	thisToken, err := token.apiTokenDao.GetTokenByHash(hashRefreshToken(plain))
+	if err != nil {
+		return nil, err
+	}
+	now := token.now()
+	if thisToken == nil || thisToken.Revoked || now.After(thisToken.ExpiresAt) {
+		return nil, nil
+	}
+	owner, err := token.userDao.GetUserByID(thisToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := token.scopeAllowed(owner, thisToken.Kind, thisToken.EntityID, thisToken.DepartmentID)
+	if err != nil || !allowed {
+		return nil, err
+	}
+
+	// a personal token acts as its owner and is held to their password change like a login
+	info, err := token.loginInfo(owner)
+	if err != nil {
+		return nil, err
+	}
+	if thisToken.Kind == model.API_TOKEN_ENTITY {
+		info = define.UserBasicInfo{
+			UserID:      owner.ID,
+			UserName:    "apikey:" + thisToken.Name,
+			EntitySuper: true,
+			EntityID:    thisToken.EntityID,
+		}
+	}
+	if thisToken.DepartmentID != 0 {
+		info.DepartmentSuper = info.EntitySuper || info.SystemSuper || info.DepartmentSuper
+		info.SystemSuper = false
+		info.EntitySuper = false
+		info.EntityID = thisToken.EntityID
+		info.DepartmentID = thisToken.DepartmentID
+	}
+
+	// last use is only a hint, no need to write on every request
+	if thisToken.LastUsedAt == nil || now.Sub(*thisToken.LastUsedAt) >= API_TOKEN_TOUCH_INTERVAL {
+		if err := token.apiTokenDao.TouchToken(thisToken.ID, now); err != nil {
+			return nil, err
+		}
+	}
+	return &define.ApiTokenIdentity{
+		UserBasicInfo: info,
+		TokenID:       thisToken.ID,
+		ReadOnly:      thisToken.Scope == define.API_SCOPE_READ,
+	}, nil
+}
@@ -8,22 +8,61 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/shopspring/decimal"
 	"github.com/thoas/go-funk"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-type assetService struct{}
-
-var AssetService *assetService
+type AssetServiceInterface interface {
+	TransformAssetBasicInfo(assetList []*model.Asset) []*define.AssetBasicInfo
+	GetSubAsset(parentID uint, departmentID uint) ([]*define.AssetBasicInfo, error)
+	GetSubAssetPage(parentID uint, departmentID uint, page_size uint, page_num uint) ([]*define.AssetBasicInfo, int64, error)
+	GetAssetByID(assetID uint) (*model.Asset, error)
+	ExistAsset(assetID uint) (bool, error)
+	CheckAssetInDepartment(assetID uint, departmentID uint) (bool, error)
+	CheckIsAncestor(srcID uint, targetID uint) (bool, error)
+	ModifyAssetInfo(id uint, req define.ModifyAssetInfoReq, actorID uint) error
+	UpdateNetWorth(assetID uint) error
+	UpdateClassNetWorth(classID uint) error
+	CreateAsset(req *define.CreateAssetReq, departmentID uint, parentID uint, userID uint) error
+	ExpireAssets(assetIDs []uint, actorID uint, reason string) error
+	TransferAssets(assetIDs []uint, userID uint, departmentID uint, oldDepartmentID uint, actorID uint, reason string) error
+	GetAssetByUser(userID uint) (assets []*define.AssetBasicInfo, err error)
+	GetUserUsedAssets(userID uint) (assets []*define.AssetBasicInfo, err error)
+	GetDepartmentAssetsByIDs(ids []uint, departmentID uint) ([]*model.Asset, error)
+	GetUserAssetsByIDs(ids []uint, userID uint) ([]*model.Asset, error)
+	GetDepartmentIdleAssets(ids []uint, departmentID uint) ([]*model.Asset, error)
+	ChangeAssetState(assetIDs []uint, change AssetStateChange) error
+	GetAssetStateHistory(assetID uint) ([]*model.AssetStateHistory, error)
+	GetUserMaintainAssets(userID uint) ([]*model.Asset, error)
+	ExistsProperty(assetID uint, key string) (bool, error)
+	SetProperty(assetID uint, key string, value string, actorID uint) error
+	DeleteProperty(assetID uint, key string, actorID uint) error
+	GetAssetHistory(assetID uint) ([]*model.Task, error)
+	GetAssetVersions(assetID uint) ([]*model.AssetVersion, error)
+	RevertAsset(assetID uint, version uint, actorID uint) error
+	ModifyAssetDepreciation(assetID uint, req define.AssetDepreciationReq, actorID uint) error
+	PreviewDepreciation(assetID uint, method string, step int) (*define.AssetDepreciationResponse, error)
+	SearchDepartmentAssets(departmentID uint, req *define.SearchAssetReq, page_size uint, page_num uint) ([]*model.Asset, int64, error)
+	GetDepartmentAssetCount(departmentID uint) (int64, error)
+	GetDepartmentAssetInWarn(departmentID uint) ([]*model.Asset, error)
+	GetDepartmentAssetBasicList(departmentID uint) ([]*model.Asset, error)
+}
 
-func newAssetService() *assetService {
-	return &assetService{}
+type assetService struct {
+	assetDao        dao.AssetDaoInterface
+	assetStateDao   dao.AssetStateDaoInterface
+	assetVersionDao dao.AssetVersionDaoInterface
+	uow             dao.UnitOfWork
 }
 
-func init() {
-	AssetService = newAssetService()
+func NewAssetService(assetDao dao.AssetDaoInterface, assetStateDao dao.AssetStateDaoInterface, assetVersionDao dao.AssetVersionDaoInterface, uow dao.UnitOfWork) AssetServiceInterface {
+	return &assetService{
+		assetDao:        assetDao,
+		assetStateDao:   assetStateDao,
+		assetVersionDao: assetVersionDao,
+		uow:             uow,
+	}
 }
 
 func (asset *assetService) TransformAssetBasicInfo(assetList []*model.Asset) []*define.AssetBasicInfo {
@@ -62,9 +101,9 @@ func (asset *assetService) GetSubAsset(parentID uint, departmentID uint) ([]*def
 	var err error
 
 	if parentID == 0 {
-		subAssetList, _, err = dao.AssetDao.GetAssetDirectDepartment(departmentID, -1, -1)
+		subAssetList, _, err = asset.assetDao.GetAssetDirectDepartment(departmentID, -1, -1)
 	} else {
-		subAssetList, _, err = dao.AssetDao.GetSubAsset(parentID, -1, -1)
+		subAssetList, _, err = asset.assetDao.GetSubAsset(parentID, -1, -1)
 	}
 
 	if err != nil {
@@ -98,9 +137,9 @@ func (asset *assetService) GetSubAssetPage(parentID uint, departmentID uint, pag
 	limit := page_size
 
 	if parentID == 0 {
-		subAssetList, count, err = dao.AssetDao.GetAssetDirectDepartment(departmentID, int(offset), int(limit))
+		subAssetList, count, err = asset.assetDao.GetAssetDirectDepartment(departmentID, int(offset), int(limit))
 	} else {
-		subAssetList, count, err = dao.AssetDao.GetSubAsset(parentID, int(offset), int(limit))
+		subAssetList, count, err = asset.assetDao.GetSubAsset(parentID, int(offset), int(limit))
 	}
 
 	if err != nil {
@@ -127,11 +166,11 @@ func (asset *assetService) GetSubAssetPage(parentID uint, departmentID uint, pag
 }
 
 func (asset *assetService) GetAssetByID(assetID uint) (*model.Asset, error) {
-	return dao.AssetDao.GetAssetByID(assetID)
+	return asset.assetDao.GetAssetByID(assetID)
 }
 
 func (asset *assetService) ExistAsset(assetID uint) (bool, error) {
-	thisAsset, err := dao.AssetDao.GetAssetByID(assetID)
+	thisAsset, err := asset.assetDao.GetAssetByID(assetID)
 	if err != nil {
 		return false, err
 	}
@@ -139,7 +178,7 @@ func (asset *assetService) ExistAsset(assetID uint) (bool, error) {
 }
 
 func (asset *assetService) CheckAssetInDepartment(assetID uint, departmentID uint) (bool, error) {
-	thisAsset, err := dao.AssetDao.GetAssetByID(assetID)
+	thisAsset, err := asset.assetDao.GetAssetByID(assetID)
 	if err != nil || thisAsset == nil {
 		return false, nil
 	}
@@ -147,7 +186,7 @@ func (asset *assetService) CheckAssetInDepartment(assetID uint, departmentID uin
 }
 
 func (asset *assetService) CheckIsAncestor(srcID uint, targetID uint) (bool, error) {
-	targetAsset, err := dao.AssetDao.GetAssetByID(targetID)
+	targetAsset, err := asset.assetDao.GetAssetByID(targetID)
 	if err != nil {
 		return true, err
 	}
@@ -161,7 +200,7 @@ func (asset *assetService) CheckIsAncestor(srcID uint, targetID uint) (bool, err
 			flag = true
 			break
 		}
-		targetAsset, err = dao.AssetDao.GetAssetByID(targetAsset.ParentID)
+		targetAsset, err = asset.assetDao.GetAssetByID(targetAsset.ParentID)
 		if err != nil {
 			return true, err
 		}
@@ -170,88 +209,92 @@ func (asset *assetService) CheckIsAncestor(srcID uint, targetID uint) (bool, err
 	return flag, nil
 }
 
-func (asset *assetService) ModifyAssetInfo(id uint, req define.ModifyAssetInfoReq) error {
-	err := dao.AssetDao.UpdateByStruct(id, model.Asset{
-		Name:        req.AssetName,
-		Price:       req.Price,
-		Description: req.Description,
-		Position:    req.Position,
-		ClassID:     req.ClassID,
-		Type:        req.Type,
-		Number:      req.Number,
-		Expire:      req.Expire,
-		ImgList:     req.ImgList,
-		Threshold:   req.Threshold,
-	})
-	if err != nil {
-		return err
-	}
-	if req.ParentID != nil {
-		if *req.ParentID != 0 {
-			err = dao.AssetDao.Update(id, map[string]interface{}{
-				"parent_id": *req.ParentID,
-			})
-		} else {
-			err = dao.AssetDao.Update(id, map[string]interface{}{
-				"parent_id": gorm.Expr("NULL"),
-			})
+func (asset *assetService) ModifyAssetInfo(id uint, req define.ModifyAssetInfoReq, actorID uint) error {
+	return asset.editAsset(id, actorID, func(tx *dao.Daos) error {
+		err := tx.Asset.UpdateByStruct(id, model.Asset{
+			Name:        req.AssetName,
+			Price:       req.Price,
+			Description: req.Description,
+			Position:    req.Position,
+			ClassID:     req.ClassID,
+			Type:        req.Type,
+			Number:      req.Number,
+			Expire:      req.Expire,
+			ImgList:     req.ImgList,
+			Threshold:   req.Threshold,
+		})
+		if err != nil {
+			return err
 		}
-	}
-	return err
+		if req.ParentID != nil {
+			if *req.ParentID != 0 {
+				err = tx.Asset.Update(id, map[string]interface{}{
+					"parent_id": *req.ParentID,
+				})
+			} else {
+				err = tx.Asset.Update(id, map[string]interface{}{
+					"parent_id": gorm.Expr("NULL"),
+				})
+			}
+		}
+		return err
+	})
 }
 
 /*
 Update net worth and warning tag
 */
 func (asset *assetService) UpdateNetWorth(assetID uint) error {
-	thisAsset, err := dao.AssetDao.GetAssetByID(assetID)
+	thisAsset, err := asset.assetDao.GetAssetByID(assetID)
 	if err != nil {
 		return err
 	} else if thisAsset == nil {
 		return nil
 	}
 
-	if thisAsset.Expire == 0 || thisAsset.State >= 3 {
+	if thisAsset.Expire == 0 || thisAsset.State >= define.ASSET_RETIRED {
 		return nil
 	}
 
-	price := thisAsset.Price
 	expire := thisAsset.Expire
 	interval := utils.GetDiffDays(time.Time(*thisAsset.CreatedAt), time.Now())
 
 	if interval >= int(thisAsset.Expire) {
-		err = dao.AssetDao.Update(assetID, map[string]interface{}{
-			"net_worth": decimal.Zero,
-			"state":     3,
-			"parent_id": gorm.Expr("NULL"),
-		})
-		if err != nil {
-			return err
-		}
-
-		subAssets, _, err := dao.AssetDao.GetSubAsset(assetID, -1, -1)
-		if err != nil {
-			return err
-		}
+		err = asset.uow.Transaction(func(tx *dao.Daos) error {
+			err := ApplyAssetTransition(tx, []uint{assetID}, AssetStateChange{
+				Event:  define.ASSET_RETIRE,
+				Reason: define.ASSET_EXPIRED_REASON,
+			})
+			if err != nil {
+				return err
+			}
+			// an expired asset is still worth its residual, only retiring it by hand writes it off
+			method, residualRate := AssetDepreciation(thisAsset)
+			err = tx.Asset.Update(assetID, map[string]interface{}{
+				"parent_id": gorm.Expr("NULL"),
+				"net_worth": DepreciatedNetWorth(thisAsset, method, residualRate, int(expire)),
+			})
+			if err != nil {
+				return err
+			}
 
-		if subAssets != nil {
+			subAssets, _, err := tx.Asset.GetSubAsset(assetID, -1, -1)
+			if err != nil || subAssets == nil {
+				return err
+			}
 			subIds := funk.Map(subAssets, func(currentAsset *model.Asset) uint {
 				return currentAsset.ID
 			}).([]uint)
 
-			err = dao.AssetDao.AllUpdate(subIds, map[string]interface{}{
+			return tx.Asset.AllUpdate(subIds, map[string]interface{}{
 				"parent_id": gorm.Expr("NULL"),
 			})
-
-			if err != nil {
-				return err
-			}
-		}
+		})
 	} else {
-		rate := 1.0 - float64(interval)/float64(expire)
+		method, residualRate := AssetDepreciation(thisAsset)
 		isWarn := (int(expire) - interval) <= int(thisAsset.Threshold)
-		err = dao.AssetDao.Update(assetID, map[string]interface{}{
-			"net_worth": price.Mul(decimal.NewFromFloat(rate)),
+		err = asset.assetDao.Update(assetID, map[string]interface{}{
+			"net_worth": DepreciatedNetWorth(thisAsset, method, residualRate, interval),
 			"warn":      isWarn,
 		})
 	}
@@ -259,8 +302,33 @@ func (asset *assetService) UpdateNetWorth(assetID uint) error {
 	return err
 }
 
+/*
+Update the net worth of every asset of the class, for when the class's depreciation changes
+*/
+func (asset *assetService) UpdateClassNetWorth(classID uint) error {
+	assetList, err := asset.assetDao.GetAssetListByClassID(classID)
+	if err != nil {
+		return err
+	}
+	for _, thisAsset := range assetList {
+		if err = asset.UpdateNetWorth(thisAsset.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+The asset and all its children are created in one transaction
+*/
 func (asset *assetService) CreateAsset(req *define.CreateAssetReq, departmentID uint, parentID uint, userID uint) error {
-	thisID, err := dao.AssetDao.CreateAndGetID(model.Asset{
+	return asset.uow.Transaction(func(tx *dao.Daos) error {
+		return createAssetTree(tx.Asset, req, departmentID, parentID, userID)
+	})
+}
+
+func createAssetTree(assetDao dao.AssetDaoInterface, req *define.CreateAssetReq, departmentID uint, parentID uint, userID uint) error {
+	thisID, err := assetDao.CreateAndGetID(model.Asset{
 		Name:         req.AssetName,
 		Price:        req.Price,
 		Description:  req.Description,
@@ -281,7 +349,7 @@ func (asset *assetService) CreateAsset(req *define.CreateAssetReq, departmentID
 		return err
 	}
 	for _, child := range req.Children {
-		err = asset.CreateAsset(child, departmentID, thisID, userID)
+		err = createAssetTree(assetDao, child, departmentID, thisID, userID)
 		if err != nil {
 			return err
 		}
@@ -289,37 +357,58 @@ func (asset *assetService) CreateAsset(req *define.CreateAssetReq, departmentID
 	return nil
 }
 
-func (asset *assetService) ExpireAssets(assetIDs []uint) error {
-	err := dao.AssetDao.ExpireAsset(assetIDs)
-	return err
+func (asset *assetService) ExpireAssets(assetIDs []uint, actorID uint, reason string) error {
+	return asset.ChangeAssetState(assetIDs, AssetStateChange{
+		Event:   define.ASSET_RETIRE,
+		ActorID: actorID,
+		Reason:  reason,
+	})
 }
 
-func (asset *assetService) TransferAssets(assetIDs []uint, userID uint, departmentID uint, oldDepartmentID uint) error {
+/*
+Detaching the sub assets and moving the assets commit or roll back together
+*/
+func (asset *assetService) TransferAssets(assetIDs []uint, userID uint, departmentID uint, oldDepartmentID uint, actorID uint, reason string) error {
+	return asset.uow.Transaction(func(tx *dao.Daos) error {
+		err := ApplyAssetTransition(tx, assetIDs, AssetStateChange{
+			Event:    define.ASSET_TRANSFER,
+			ActorID:  actorID,
+			TargetID: userID,
+			Reason:   reason,
+		})
+		if err != nil {
+			return err
+		}
+		return transferAssets(tx.Asset, assetIDs, userID, departmentID, oldDepartmentID)
+	})
+}
+
+func transferAssets(assetDao dao.AssetDaoInterface, assetIDs []uint, userID uint, departmentID uint, oldDepartmentID uint) error {
 	if departmentID != oldDepartmentID {
-		subAssets, err := dao.AssetDao.GetSubAssetsByParents(assetIDs)
+		subAssets, err := assetDao.GetSubAssetsByParents(assetIDs)
 		if err != nil {
 			return err
 		}
 		subAssetIDs := []uint{}
 
-		for _, asset := range subAssets {
-			subAssetIDs = append(subAssetIDs, asset.ID)
+		for _, subAsset := range subAssets {
+			subAssetIDs = append(subAssetIDs, subAsset.ID)
 		}
 
-		err = dao.AssetDao.AllUpdate(subAssetIDs, map[string]interface{}{
+		err = assetDao.AllUpdate(subAssetIDs, map[string]interface{}{
 			"parent_id": gorm.Expr("NULL"),
 		})
 		if err != nil {
 			return err
 		}
-		err = dao.AssetDao.AllUpdate(assetIDs, map[string]interface{}{
+		err = assetDao.AllUpdate(assetIDs, map[string]interface{}{
 			"user_id":       userID,
 			"parent_id":     gorm.Expr("NULL"),
 			"department_id": departmentID,
 		})
 		return err
 	} else {
-		err := dao.AssetDao.AllUpdate(assetIDs, map[string]interface{}{
+		err := assetDao.AllUpdate(assetIDs, map[string]interface{}{
 			"user_id": userID,
 		})
 		return err
@@ -327,7 +416,7 @@ func (asset *assetService) TransferAssets(assetIDs []uint, userID uint, departme
 }
 
 func (asset *assetService) GetAssetByUser(userID uint) (assets []*define.AssetBasicInfo, err error) {
-	assetList, err := dao.AssetDao.GetDirectAssetsByUser(userID)
+	assetList, err := asset.assetDao.GetDirectAssetsByUser(userID)
 	if err != nil {
 		return
 	}
@@ -346,7 +435,7 @@ func (asset *assetService) GetAssetByUser(userID uint) (assets []*define.AssetBa
 }
 
 func (asset *assetService) GetUserUsedAssets(userID uint) (assets []*define.AssetBasicInfo, err error) {
-	assetList, err := dao.AssetDao.GetUserAssetsInUsed(userID)
+	assetList, err := asset.assetDao.GetUserAssetsInUsed(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -356,7 +445,7 @@ func (asset *assetService) GetUserUsedAssets(userID uint) (assets []*define.Asse
 }
 
 func (asset *assetService) GetDepartmentAssetsByIDs(ids []uint, departmentID uint) ([]*model.Asset, error) {
-	assetList, err := dao.AssetDao.GetDepartmentAssetsByIDs(ids, departmentID)
+	assetList, err := asset.assetDao.GetDepartmentAssetsByIDs(ids, departmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -364,7 +453,7 @@ func (asset *assetService) GetDepartmentAssetsByIDs(ids []uint, departmentID uin
 }
 
 func (asset *assetService) GetUserAssetsByIDs(ids []uint, userID uint) ([]*model.Asset, error) {
-	assetList, err := dao.AssetDao.GetUserAssetsByIDs(ids, userID)
+	assetList, err := asset.assetDao.GetUserAssetsByIDs(ids, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -372,69 +461,66 @@ func (asset *assetService) GetUserAssetsByIDs(ids []uint, userID uint) ([]*model
 }
 
 func (asset *assetService) GetDepartmentIdleAssets(ids []uint, departmentID uint) ([]*model.Asset, error) {
-	assetList, err := dao.AssetDao.GetDepartmentIdleAssetsByIDs(ids, departmentID)
+	assetList, err := asset.assetDao.GetDepartmentIdleAssetsByIDs(ids, departmentID)
 	if err != nil {
 		return nil, err
 	}
 	return assetList, nil
 }
 
-func (asset *assetService) AcquireAssets(ids []uint, userID uint) error {
-	err := dao.AssetDao.ModifyAssetsUserAndState(ids, userID, 1)
-	return err
+func (asset *assetService) ChangeAssetState(assetIDs []uint, change AssetStateChange) error {
+	return asset.uow.Transaction(func(tx *dao.Daos) error {
+		return ApplyAssetTransition(tx, assetIDs, change)
+	})
 }
 
-func (asset *assetService) CancelAssets(ids []uint, userID uint) error {
-	err := dao.AssetDao.ModifyAssetsUserAndState(ids, userID, 0)
-	return err
+func (asset *assetService) GetAssetStateHistory(assetID uint) ([]*model.AssetStateHistory, error) {
+	return asset.assetStateDao.GetHistory(assetID)
 }
 
 func (asset *assetService) GetUserMaintainAssets(userID uint) ([]*model.Asset, error) {
-	assetList, err := dao.AssetDao.GetUserMaintainAssets(userID)
+	assetList, err := asset.assetDao.GetUserMaintainAssets(userID)
 	return assetList, err
 }
 
-func (asset *assetService) ModifyAssetMaintainerAndState(assetIDs []uint, maintainerID uint) error {
-	err := dao.AssetDao.ModifyAssetMaintainerAndState(assetIDs, maintainerID)
-	return err
-}
-
 func (asset *assetService) ExistsProperty(assetID uint, key string) (bool, error) {
-	return dao.AssetDao.CheckAssetPropertyExist(assetID, key)
+	return asset.assetDao.CheckAssetPropertyExist(assetID, key)
 }
 
-func (asset *assetService) SetProperty(assetID uint, key string, value string) error {
-	return dao.AssetDao.SetAssetProperty(assetID, key, value)
+func (asset *assetService) SetProperty(assetID uint, key string, value string, actorID uint) error {
+	return asset.editAsset(assetID, actorID, func(tx *dao.Daos) error {
+		return tx.Asset.SetAssetProperty(assetID, key, value)
+	})
 }
 
-func (asset *assetService) DeleteProperty(assetID uint, key string) error {
-	thisAsset, err := dao.AssetDao.GetAssetProperty(assetID)
-	if err != nil {
-		return err
-	}
+func (asset *assetService) DeleteProperty(assetID uint, key string, actorID uint) error {
+	return asset.editAsset(assetID, actorID, func(tx *dao.Daos) error {
+		thisAsset, err := tx.Asset.GetAssetProperty(assetID)
+		if err != nil {
+			return err
+		}
 
-	var data map[string]interface{}
-	err = json.Unmarshal(thisAsset.Property, &data)
-	if err != nil {
-		return err
-	}
+		var data map[string]interface{}
+		err = json.Unmarshal(thisAsset.Property, &data)
+		if err != nil {
+			return err
+		}
 
-	delete(data, key)
+		delete(data, key)
 
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
 
-	err = dao.AssetDao.Update(assetID, map[string]interface{}{
-		"property": jsonData,
+		return tx.Asset.Update(assetID, map[string]interface{}{
+			"property": jsonData,
+		})
 	})
-
-	return err
 }
 
 func (asset *assetService) GetAssetHistory(assetID uint) ([]*model.Task, error) {
-	taskList, err := dao.AssetDao.GetAssetTask(assetID)
+	taskList, err := asset.assetDao.GetAssetTask(assetID)
 	if err != nil {
 		return nil, err
 	}
@@ -462,17 +548,17 @@ func (asset *assetService) SearchDepartmentAssets(departmentID uint, req *define
 	offset := page_num * page_size
 	limit := page_size
 
-	return dao.AssetDao.SearchDepartmentAsset(departmentID, req, int(offset), int(limit))
+	return asset.assetDao.SearchDepartmentAsset(departmentID, req, int(offset), int(limit))
 }
 
 func (asset *assetService) GetDepartmentAssetCount(departmentID uint) (int64, error) {
-	return dao.AssetDao.GetDepartmentAssetCount(departmentID)
+	return asset.assetDao.GetDepartmentAssetCount(departmentID)
 }
 
 func (asset *assetService) GetDepartmentAssetInWarn(departmentID uint) ([]*model.Asset, error) {
-	return dao.AssetDao.GetDepartmentWarnAsset(departmentID)
+	return asset.assetDao.GetDepartmentWarnAsset(departmentID)
 }
 
 func (asset *assetService) GetDepartmentAssetBasicList(departmentID uint) ([]*model.Asset, error) {
-	return dao.AssetDao.GetDepartmentAssetBasicList(departmentID)
+	return asset.assetDao.GetDepartmentAssetBasicList(departmentID)
 }
@@ -9,20 +9,36 @@ import (
 	"gorm.io/gorm"
 )
 
-type assetClassService struct{}
-
-var AssetClassService *assetClassService
+type AssetClassServiceInterface interface {
+	GetAssetClassByID(id uint) (*model.AssetClass, error)
+	ExistsAssetClass(id uint) (bool, error)
+	CreateAssetClass(req define.CreateAssetClassReq, departmentID uint) error
+	GetSubAssetClass(parentID uint, departmentID uint) ([]*define.AssetClassTreeNode, error)
+	ModifyAssetClassInfo(req define.ModifyAssetClassReq, id uint) error
+	CheckIsAncestor(srcClassID uint, targetClassID uint) (bool, error)
+	ClassHasAsset(classID uint) (bool, error)
+	DeleteAssetClass(classID uint) error
+	GetSubClass(parentID uint, departmentID uint) ([]*define.AssetClassTreeNode, error)
+	ClassHasSubClass(classID uint, departmentID uint) (bool, error)
+}
 
-func newAssetClassService() *assetClassService {
-	return &assetClassService{}
+type assetClassService struct {
+	assetClassDao dao.AssetClassDaoInterface
+	assetDao      dao.AssetDaoInterface
 }
 
-func init() {
-	AssetClassService = newAssetClassService()
+func NewAssetClassService(
+	assetClassDao dao.AssetClassDaoInterface,
+	assetDao dao.AssetDaoInterface,
+) AssetClassServiceInterface {
+	return &assetClassService{
+		assetClassDao: assetClassDao,
+		assetDao:      assetDao,
+	}
 }
 
 func (assetClass *assetClassService) GetAssetClassByID(id uint) (*model.AssetClass, error) {
-	thisAssetClass, err := dao.AssetClassDao.GetAssetClassByID(id)
+	thisAssetClass, err := assetClass.assetClassDao.GetAssetClassByID(id)
 	return thisAssetClass, err
 }
 
@@ -35,11 +51,13 @@ func (assetClass *assetClassService) ExistsAssetClass(id uint) (bool, error) {
 }
 
 func (assetClass *assetClassService) CreateAssetClass(req define.CreateAssetClassReq, departmentID uint) error {
-	return dao.AssetClassDao.Create(model.AssetClass{
-		Name:         req.ClassName,
-		ParentID:     req.ParentID,
-		DepartmentID: departmentID,
-		Type:         req.Type,
+	return assetClass.assetClassDao.Create(model.AssetClass{
+		Name:               req.ClassName,
+		ParentID:           req.ParentID,
+		DepartmentID:       departmentID,
+		Type:               req.Type,
+		DepreciationMethod: req.DepreciationMethod,
+		ResidualRate:       req.ResidualRate,
 	})
 }
 
@@ -47,9 +65,9 @@ func (assetClass *assetClassService) GetSubAssetClass(parentID uint, departmentI
 	var subClassList []*model.AssetClass
 	var err error
 	if parentID == 0 {
-		subClassList, err = dao.AssetClassDao.GetDepartmentDirectClass(departmentID)
+		subClassList, err = assetClass.assetClassDao.GetDepartmentDirectClass(departmentID)
 	} else {
-		subClassList, err = dao.AssetClassDao.GetSubAssetClass(parentID)
+		subClassList, err = assetClass.assetClassDao.GetSubAssetClass(parentID)
 	}
 
 	if err != nil {
@@ -72,20 +90,33 @@ func (assetClass *assetClassService) GetSubAssetClass(parentID uint, departmentI
 }
 
 func (assetClass *assetClassService) ModifyAssetClassInfo(req define.ModifyAssetClassReq, id uint) error {
-	err := dao.AssetClassDao.UpdateByStruct(id, model.AssetClass{
+	err := assetClass.assetClassDao.UpdateByStruct(id, model.AssetClass{
 		Name: req.ClassName,
 		Type: req.Type,
 	})
 	if err != nil {
 		return err
 	}
+	depreciation := map[string]interface{}{}
+	if req.DepreciationMethod != nil {
+		depreciation["depreciation_method"] = *req.DepreciationMethod
+	}
+	if req.ResidualRate != nil {
+		depreciation["residual_rate"] = *req.ResidualRate
+	}
+	if len(depreciation) != 0 {
+		err = assetClass.assetClassDao.Update(id, depreciation)
+		if err != nil {
+			return err
+		}
+	}
 	if req.ParentID != nil {
 		if *req.ParentID != 0 {
-			err = dao.AssetClassDao.Update(id, map[string]interface{}{
+			err = assetClass.assetClassDao.Update(id, map[string]interface{}{
 				"parent_id": *req.ParentID,
 			})
 		} else {
-			err = dao.AssetClassDao.Update(id, map[string]interface{}{
+			err = assetClass.assetClassDao.Update(id, map[string]interface{}{
 				"parent_id": gorm.Expr("NULL"),
 			})
 		}
@@ -94,7 +125,7 @@ func (assetClass *assetClassService) ModifyAssetClassInfo(req define.ModifyAsset
 }
 
 func (assetClass *assetClassService) CheckIsAncestor(srcClassID uint, targetClassID uint) (bool, error) {
-	targetClass, err := dao.AssetClassDao.GetAssetClassByID(targetClassID)
+	targetClass, err := assetClass.assetClassDao.GetAssetClassByID(targetClassID)
 	if err != nil {
 		return false, nil
 	}
@@ -108,7 +139,7 @@ func (assetClass *assetClassService) CheckIsAncestor(srcClassID uint, targetClas
 			flag = true
 			break
 		}
-		targetClass, err = dao.AssetClassDao.GetAssetClassByID(targetClass.ParentID)
+		targetClass, err = assetClass.assetClassDao.GetAssetClassByID(targetClass.ParentID)
 		if err != nil {
 			return true, err
 		}
@@ -117,7 +148,7 @@ func (assetClass *assetClassService) CheckIsAncestor(srcClassID uint, targetClas
 }
 
 func (assetClass *assetClassService) ClassHasAsset(classID uint) (bool, error) {
-	assetList, err := dao.AssetDao.GetAssetListByClassID(classID)
+	assetList, err := assetClass.assetDao.GetAssetListByClassID(classID)
 	if err != nil {
 		return true, err
 	}
@@ -125,16 +156,16 @@ func (assetClass *assetClassService) ClassHasAsset(classID uint) (bool, error) {
 }
 
 func (assetClass *assetClassService) DeleteAssetClass(classID uint) error {
-	return dao.AssetClassDao.Delete([]uint{classID})
+	return assetClass.assetClassDao.Delete([]uint{classID})
 }
 
 func (assetClass *assetClassService) GetSubClass(parentID uint, departmentID uint) ([]*define.AssetClassTreeNode, error) {
 	var subClassList []*model.AssetClass
 	var err error
 	if parentID == 0 {
-		subClassList, err = dao.AssetClassDao.GetDepartmentDirectClass(departmentID)
+		subClassList, err = assetClass.assetClassDao.GetDepartmentDirectClass(departmentID)
 	} else {
-		subClassList, err = dao.AssetClassDao.GetSubAssetClass(parentID)
+		subClassList, err = assetClass.assetClassDao.GetSubAssetClass(parentID)
 	}
 
 	if err != nil {
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrAssetQuantity = errors.New("asset quantity is not available")
+
+/*
+Check a task may ask for quantity units of the asset, 0 asks for the whole asset
+*/
+func CheckAssetQuantity(thisAsset *model.Asset, quantity int) error {
+	if quantity == 0 {
+		return nil
+	}
+	if quantity < 0 {
+		return fmt.Errorf("%w: quantity %d of asset %d", ErrAssetQuantity, quantity, thisAsset.ID)
+	}
+	if thisAsset.Type != define.ASSET_TYPE_QUANTITY {
+		return fmt.Errorf("%w: asset %d is not a quantity-type asset", ErrAssetQuantity, thisAsset.ID)
+	}
+	if quantity > thisAsset.Number {
+		return fmt.Errorf("%w: asset %d holds only %d", ErrAssetQuantity, thisAsset.ID, thisAsset.Number)
+	}
+	return nil
+}
+
+/*
+The share of value that part of whole units are worth
+*/
+func prorate(value decimal.Decimal, part int, whole int) decimal.Decimal {
+	if whole == 0 {
+		return decimal.Zero
+	}
+	return value.Mul(decimal.NewFromInt(int64(part))).Div(decimal.NewFromInt(int64(whole))).Round(2)
+}
+
+/*
+Split quantity units off the asset into a new asset in the same state and hands,
+and return the asset the task goes on with. Asking for none or all of the units
+moves the asset itself
+*/
+func splitAsset(tx *dao.Daos, thisAsset *model.Asset, quantity int, change AssetStateChange) (uint, error) {
+	if quantity == 0 || quantity == thisAsset.Number {
+		return thisAsset.ID, nil
+	}
+	if err := CheckAssetQuantity(thisAsset, quantity); err != nil {
+		return 0, err
+	}
+	change.Event = define.ASSET_SPLIT
+	change.Quantity = quantity
+	if err := ApplyAssetTransition(tx, []uint{thisAsset.ID}, change); err != nil {
+		return 0, err
+	}
+
+	price := prorate(thisAsset.Price, quantity, thisAsset.Number)
+	netWorth := prorate(thisAsset.NetWorth, quantity, thisAsset.Number)
+	affected, err := tx.AssetState.TakeQuantity(thisAsset.ID, thisAsset.Number, quantity, price, netWorth)
+	if err != nil {
+		return 0, err
+	}
+	if affected != 1 {
+		return 0, fmt.Errorf("%w: asset %d changed concurrently", ErrAssetQuantity, thisAsset.ID)
+	}
+
+	// a portion of a portion still belongs to the first stock
+	stockID := thisAsset.StockID
+	if stockID == 0 {
+		stockID = thisAsset.ID
+	}
+	return tx.Asset.CreateAndGetID(model.Asset{
+		Name:               thisAsset.Name,
+		ParentID:           thisAsset.ParentID,
+		UserID:             thisAsset.UserID,
+		DepartmentID:       thisAsset.DepartmentID,
+		Price:              price,
+		Description:        thisAsset.Description,
+		Position:           thisAsset.Position,
+		Expire:             thisAsset.Expire,
+		ClassID:            thisAsset.ClassID,
+		Number:             quantity,
+		StockID:            stockID,
+		Type:               thisAsset.Type,
+		State:              thisAsset.State,
+		MaintainerID:       thisAsset.MaintainerID,
+		Property:           thisAsset.Property,
+		CreatedAt:          thisAsset.CreatedAt,
+		NetWorth:           netWorth,
+		ImgList:            thisAsset.ImgList,
+		Warn:               thisAsset.Warn,
+		Threshold:          thisAsset.Threshold,
+		DepreciationMethod: thisAsset.DepreciationMethod,
+		ResidualRate:       thisAsset.ResidualRate,
+	})
+}
+
+/*
+Split off the units each asset of the task asks for, and return the assets the task moves
+*/
+func splitTaskAssets(tx *dao.Daos, taskInfo *model.Task, change AssetStateChange) ([]uint, error) {
+	assetIDs := make([]uint, 0, len(taskInfo.AssetList))
+	for _, thisAsset := range taskInfo.AssetList {
+		assetIDs = append(assetIDs, thisAsset.ID)
+	}
+	if len(taskInfo.Quantities) == 0 {
+		return assetIDs, nil
+	}
+
+	quantities := map[uint]int{}
+	for _, item := range taskInfo.Quantities {
+		quantities[item.AssetID] = item.Quantity
+	}
+	assetList, err := tx.AssetState.GetAssetsByIDs(assetIDs)
+	if err != nil {
+		return nil, err
+	}
+	movedIDs := make([]uint, 0, len(assetList))
+	for _, thisAsset := range assetList {
+		movedID, err := splitAsset(tx, thisAsset, quantities[thisAsset.ID], change)
+		if err != nil {
+			return nil, err
+		}
+		movedIDs = append(movedIDs, movedID)
+	}
+	return movedIDs, nil
+}
+
+/*
+Put returned portions back onto the stock they were split from. A portion whose stock
+is gone, in use or moved to another department stays an asset of its own
+*/
+func mergeAssetStock(tx *dao.Daos, assetIDs []uint, change AssetStateChange) error {
+	assetList, err := tx.AssetState.GetAssetsByIDs(assetIDs)
+	if err != nil {
+		return err
+	}
+	for _, portion := range assetList {
+		if portion.StockID == 0 || portion.State != define.ASSET_IDLE {
+			continue
+		}
+		stock, err := tx.Asset.GetAssetByID(portion.StockID)
+		if err != nil {
+			return err
+		}
+		if stock == nil || stock.State != define.ASSET_IDLE || stock.DepartmentID != portion.DepartmentID ||
+			stock.Type != define.ASSET_TYPE_QUANTITY {
+			continue
+		}
+
+		err = ApplyAssetTransition(tx, []uint{stock.ID}, AssetStateChange{
+			Event:    define.ASSET_MERGE,
+			ActorID:  change.ActorID,
+			TaskID:   change.TaskID,
+			Reason:   change.Reason,
+			Quantity: portion.Number,
+		})
+		if err != nil {
+			return err
+		}
+		affected, err := tx.AssetState.AddQuantity(stock.ID, portion.Number, portion.Price, portion.NetWorth)
+		if err != nil {
+			return err
+		}
+		if affected != 1 {
+			return fmt.Errorf("%w: asset %d changed concurrently", ErrAssetQuantity, stock.ID)
+		}
+
+		// the emptied portion is deleted holding nothing, its tasks still point at it. It stays
+		// out of the recycle bin and cannot be restored, the purge archives it
+		err = ApplyAssetTransition(tx, []uint{portion.ID}, AssetStateChange{
+			Event:   define.ASSET_DELETE,
+			ActorID: change.ActorID,
+			TaskID:  change.TaskID,
+			Reason:  fmt.Sprintf("merged into asset %d", stock.ID),
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Asset.Update(portion.ID, map[string]interface{}{
+			"number":    0,
+			"price":     decimal.Zero,
+			"net_worth": decimal.Zero,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
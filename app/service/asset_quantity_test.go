@@ -0,0 +1,122 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/config"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestAssetQuantity(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	taskService := NewTaskService(daos.Task, daos)
+
+	for _, name := range []string{"quantity_manager", "quantity_user"} {
+		err := daos.User.Create(model.User{UserName: name, Password: "21232f297a57a5a743894a0e4a801fc3"})
+		assert.Equal(t, nil, err, "service error")
+	}
+	manager, _ := daos.User.GetUserByName("quantity_manager")
+	thisUser, _ := daos.User.GetUserByName("quantity_user")
+	err := daos.Department.Create(model.Department{Name: "quantity_department"})
+	assert.Equal(t, nil, err, "service error")
+	department, _ := daos.Department.GetDepartmentByName("quantity_department")
+	stockID, err := daos.Asset.CreateAndGetID(model.Asset{
+		Name:         "cable",
+		DepartmentID: department.ID,
+		Type:         define.ASSET_TYPE_QUANTITY,
+		Number:       10,
+		Price:        decimal.NewFromInt(100),
+		NetWorth:     decimal.NewFromInt(100),
+		Property:     datatypes.JSON([]byte(`{}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+	getAsset := func(assetID uint) *model.Asset {
+		thisAsset, err := daos.Asset.GetAssetByID(assetID)
+		assert.Equal(t, nil, err, "service error")
+		return thisAsset
+	}
+	runTask := func(taskType uint, assetID uint, quantity int) {
+		taskID, err := taskService.CreateTask(define.CreateTaskReq{
+			TaskType:  taskType,
+			AssetList: []define.TaskAssetReq{{AssetID: assetID, Quantity: quantity}},
+		}, thisUser.ID, department.ID, []*model.Asset{getAsset(assetID)})
+		assert.Equal(t, nil, err, "service error")
+		taskInfo, err := taskService.GetTaskInfoByID(taskID)
+		assert.Equal(t, nil, err, "service error")
+		err = taskService.ApproveTask(taskInfo, manager.ID)
+		assert.Equal(t, nil, err, "service error")
+	}
+
+	// more than the stock holds, or a part of an item-type asset, is refused
+	_, err = taskService.CreateTask(define.CreateTaskReq{
+		AssetList: []define.TaskAssetReq{{AssetID: stockID, Quantity: 11}},
+	}, thisUser.ID, department.ID, []*model.Asset{getAsset(stockID)})
+	assert.ErrorIs(t, err, ErrAssetQuantity, "service error")
+	err = CheckAssetQuantity(&model.Asset{ID: 1, Type: define.ASSET_TYPE_ITEM, Number: 1}, 1)
+	assert.ErrorIs(t, err, ErrAssetQuantity, "service error")
+
+	// acquiring 4 splits them off, the rest stays idle with its share of the value
+	runTask(0, stockID, 4)
+	stock := getAsset(stockID)
+	assert.Equal(t, 6, stock.Number, "service error")
+	assert.Equal(t, define.ASSET_IDLE, stock.State, "service error")
+	assert.Equal(t, "60", stock.Price.String(), "service error")
+	heldList, err := daos.Asset.GetUserAssetsByIDs([]uint{stockID + 1}, thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(heldList), "service error")
+	portion := heldList[0]
+	assert.Equal(t, 4, portion.Number, "service error")
+	assert.Equal(t, stockID, portion.StockID, "service error")
+	assert.Equal(t, define.ASSET_IN_USE, portion.State, "service error")
+	assert.Equal(t, "40", portion.Price.String(), "service error")
+	history, _ := daos.AssetState.GetHistory(stockID)
+	assert.Equal(t, define.ASSET_SPLIT, history[0].Event, "service error")
+	assert.Equal(t, 4, history[0].Quantity, "service error")
+
+	// a stale count takes nothing, so a quantity never goes negative
+	affected, err := daos.AssetState.TakeQuantity(stockID, 10, 4, decimal.Zero, decimal.Zero)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int64(0), affected, "service error")
+	affected, err = daos.AssetState.TakeQuantity(stockID, 6, 6, decimal.Zero, decimal.Zero)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int64(0), affected, "service error")
+
+	// returning 1 merges it back, the user keeps 3
+	runTask(1, portion.ID, 1)
+	assert.Equal(t, 7, getAsset(stockID).Number, "service error")
+	assert.Equal(t, "70", getAsset(stockID).Price.String(), "service error")
+	assert.Equal(t, 3, getAsset(portion.ID).Number, "service error")
+	assert.Equal(t, thisUser.ID, getAsset(portion.ID).UserID, "service error")
+
+	// returning the rest merges the portion away
+	runTask(1, portion.ID, 0)
+	stock = getAsset(stockID)
+	assert.Equal(t, 10, stock.Number, "service error")
+	assert.Equal(t, "100", stock.Price.String(), "service error")
+	merged := getAsset(portion.ID)
+	assert.Equal(t, define.ASSET_DELETED, merged.State, "service error")
+	assert.Equal(t, 0, merged.Number, "service error")
+	assert.Equal(t, true, merged.NetWorth.IsZero(), "service error")
+	taskIDs, err := daos.AssetRecycle.GetAssetTaskIDs(portion.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 2, len(taskIDs), "service error")
+	// the merged portion is kept out of the bin and cannot come back from it
+	recycleService := NewAssetRecycleService(daos.AssetRecycle, daos, config.AssetConfig{RecycleRetentionDays: 30})
+	binList, _, err := recycleService.GetRecycleBin(department.ID, 10, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(binList), "service error")
+	err = recycleService.RestoreAssets([]uint{portion.ID}, manager.ID, "")
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	assert.Equal(t, define.ASSET_DELETED, getAsset(portion.ID).State, "service error")
+	history, _ = daos.AssetState.GetHistory(stockID)
+	assert.Equal(t, define.ASSET_MERGE, history[0].Event, "service error")
+	assert.Equal(t, 3, history[0].Quantity, "service error")
+	heldList, err = daos.Asset.GetDirectAssetsByUser(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(heldList), "service error")
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"gorm.io/gorm"
+)
+
+const PURGE_BATCH_SIZE = 200
+
+type AssetRecycleServiceInterface interface {
+	DeleteAssets(assetIDs []uint, actorID uint, reason string) error
+	RestoreAssets(assetIDs []uint, actorID uint, reason string) error
+	GetRecycleBin(departmentID uint, page_size uint, page_num uint) ([]*model.Asset, int64, error)
+	PurgeAt(thisAsset *model.Asset) time.Time
+	PurgeExpired() (int, error)
+}
+
+type assetRecycleService struct {
+	assetRecycleDao dao.AssetRecycleDaoInterface
+	uow             dao.UnitOfWork
+	conf            config.AssetConfig
+	now             func() time.Time
+}
+
+func NewAssetRecycleService(assetRecycleDao dao.AssetRecycleDaoInterface, uow dao.UnitOfWork, conf config.AssetConfig) AssetRecycleServiceInterface {
+	return &assetRecycleService{
+		assetRecycleDao: assetRecycleDao,
+		uow:             uow,
+		conf:            conf,
+		now:             time.Now,
+	}
+}
+
+/*
+Move the assets to the recycle bin. Like on retirement their sub assets are detached,
+they stay where they are
+*/
+func (recycle *assetRecycleService) DeleteAssets(assetIDs []uint, actorID uint, reason string) error {
+	return recycle.uow.Transaction(func(tx *dao.Daos) error {
+		err := ApplyAssetTransition(tx, assetIDs, AssetStateChange{
+			Event:   define.ASSET_DELETE,
+			ActorID: actorID,
+			Reason:  reason,
+		})
+		if err != nil {
+			return err
+		}
+		subAssets, err := tx.Asset.GetSubAssetsByParents(assetIDs)
+		if err != nil || len(subAssets) == 0 {
+			return err
+		}
+		subAssetIDs := funk.Map(subAssets, func(thisAsset *model.Asset) uint {
+			return thisAsset.ID
+		}).([]uint)
+		return tx.Asset.AllUpdate(subAssetIDs, map[string]interface{}{
+			"parent_id": gorm.Expr("NULL"),
+		})
+	})
+}
+
+/*
+Bring the assets back in the state they were deleted from, under their old parent
+if it is still an active asset of the same department
+*/
+func (recycle *assetRecycleService) RestoreAssets(assetIDs []uint, actorID uint, reason string) error {
+	return recycle.uow.Transaction(func(tx *dao.Daos) error {
+		assetList, err := tx.AssetState.GetAssetsByIDs(assetIDs)
+		if err != nil {
+			return err
+		}
+		err = ApplyAssetTransition(tx, assetIDs, AssetStateChange{
+			Event:   define.ASSET_RESTORE,
+			ActorID: actorID,
+			Reason:  reason,
+		})
+		if err != nil {
+			return err
+		}
+		// parents restored in the same batch are back already
+		for _, thisAsset := range assetList {
+			if thisAsset.DeletedParentID == 0 {
+				continue
+			}
+			parent, err := tx.Asset.GetAssetByID(thisAsset.DeletedParentID)
+			if err != nil {
+				return err
+			}
+			if parent == nil || parent.DepartmentID != thisAsset.DepartmentID || parent.State >= define.ASSET_RETIRED {
+				continue
+			}
+			err = tx.Asset.Update(thisAsset.ID, map[string]interface{}{
+				"parent_id": parent.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (recycle *assetRecycleService) GetRecycleBin(departmentID uint, page_size uint, page_num uint) ([]*model.Asset, int64, error) {
+	offset := page_size * page_num
+	return recycle.assetRecycleDao.GetDepartmentRecycleBin(departmentID, int(offset), int(page_size))
+}
+
+func (recycle *assetRecycleService) PurgeAt(thisAsset *model.Asset) time.Time {
+	if thisAsset.DeletedAt == nil {
+		return recycle.now()
+	}
+	return time.Time(*thisAsset.DeletedAt).AddDate(0, 0, int(recycle.conf.RecycleRetentionDays))
+}
+
+/*
+Archive and remove every asset that has been in a recycle bin longer than the retention period,
+one transaction each so a failing asset does not hold back the others
+*/
+func (recycle *assetRecycleService) PurgeExpired() (int, error) {
+	now := recycle.now()
+	before := now.AddDate(0, 0, -int(recycle.conf.RecycleRetentionDays))
+	purged := 0
+	var errs []error
+	var afterID uint
+	for {
+		assetList, err := recycle.assetRecycleDao.GetExpiredAssets(before, afterID, PURGE_BATCH_SIZE)
+		if err != nil {
+			return purged, err
+		}
+		if len(assetList) == 0 {
+			break
+		}
+		for _, thisAsset := range assetList {
+			afterID = thisAsset.ID
+			err = recycle.uow.Transaction(func(tx *dao.Daos) error {
+				return purgeAsset(tx, thisAsset, now)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("asset %d: %w", thisAsset.ID, err))
+				continue
+			}
+			purged++
+		}
+	}
+	return purged, errors.Join(errs...)
+}
+
+func purgeAsset(tx *dao.Daos, thisAsset *model.Asset, now time.Time) error {
+	snapshot := define.AssetArchiveSnapshot{Asset: thisAsset}
+	var err error
+	if snapshot.States, err = tx.AssetState.GetHistory(thisAsset.ID); err != nil {
+		return err
+	}
+	if snapshot.Versions, err = tx.AssetVersion.GetVersions(thisAsset.ID); err != nil {
+		return err
+	}
+	if snapshot.TaskIDs, err = tx.AssetRecycle.GetAssetTaskIDs(thisAsset.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	archive := &model.AssetArchive{
+		AssetID:      thisAsset.ID,
+		DepartmentID: thisAsset.DepartmentID,
+		Name:         thisAsset.Name,
+		Snapshot:     data,
+		PurgedAt:     now,
+	}
+	if thisAsset.DeletedAt != nil {
+		archive.DeletedAt = time.Time(*thisAsset.DeletedAt)
+	}
+	if err = tx.AssetRecycle.CreateArchive(archive); err != nil {
+		return err
+	}
+	return tx.AssetRecycle.Purge(thisAsset.ID)
+}
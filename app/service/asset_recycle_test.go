@@ -0,0 +1,134 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/config"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestAssetRecycle(t *testing.T) {
+	db := dao.InitForTest()
+	daos := dao.NewDaos(db)
+	recycle := NewAssetRecycleService(daos.AssetRecycle, daos, config.AssetConfig{RecycleRetentionDays: 30}).(*assetRecycleService)
+	assetService := NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos)
+
+	err := daos.Department.Create(model.Department{Name: "recycle_department"})
+	assert.Equal(t, nil, err, "service error")
+	department, _ := daos.Department.GetDepartmentByName("recycle_department")
+	newAsset := func(name string, parentID uint) uint {
+		assetID, err := daos.Asset.CreateAndGetID(model.Asset{
+			Name:         name,
+			ParentID:     parentID,
+			DepartmentID: department.ID,
+			Property:     datatypes.JSON([]byte(`{}`)),
+		})
+		assert.Equal(t, nil, err, "service error")
+		return assetID
+	}
+	getAsset := func(assetID uint) *model.Asset {
+		thisAsset, err := daos.Asset.GetAssetByID(assetID)
+		assert.Equal(t, nil, err, "service error")
+		return thisAsset
+	}
+	rackID := newAsset("rack", 0)
+	serverID := newAsset("server", rackID)
+	diskID := newAsset("disk", serverID)
+	err = assetService.ExpireAssets([]uint{serverID}, 0, "old")
+	assert.Equal(t, nil, err, "service error")
+
+	// only idle and retired assets go to the bin, the disk is detached and stays
+	err = daos.Asset.Update(rackID, map[string]interface{}{"state": define.ASSET_IN_USE})
+	assert.Equal(t, nil, err, "service error")
+	err = recycle.DeleteAssets([]uint{rackID}, 0, "")
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = recycle.DeleteAssets([]uint{serverID}, 0, "replaced")
+	assert.Equal(t, nil, err, "service error")
+	server := getAsset(serverID)
+	assert.Equal(t, define.ASSET_DELETED, server.State, "service error")
+	assert.Equal(t, uint(0), server.ParentID, "service error")
+	assert.Equal(t, rackID, server.DeletedParentID, "service error")
+	assert.Equal(t, define.ASSET_RETIRED, server.DeletedState, "service error")
+	assert.Equal(t, uint(0), getAsset(diskID).ParentID, "service error")
+	assert.Equal(t, true, recycle.PurgeAt(server).After(time.Now().AddDate(0, 0, 29)), "service error")
+
+	binList, count, err := recycle.GetRecycleBin(department.ID, 10, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int64(1), count, "service error")
+	assert.Equal(t, serverID, binList[0].ID, "service error")
+	// the department tree no longer shows it
+	treeList, _, err := daos.Asset.GetAssetDirectDepartment(department.ID, -1, -1)
+	assert.Equal(t, nil, err, "service error")
+	for _, thisAsset := range treeList {
+		assert.NotEqual(t, serverID, thisAsset.ID, "service error")
+	}
+	// nor do the stats
+	distribution, err := daos.Stat.GetDepartmentAssetDistribution(department.ID)
+	assert.Equal(t, nil, err, "service error")
+	for _, item := range distribution {
+		assert.NotEqual(t, define.ASSET_DELETED, item.State, "service error")
+	}
+	err = daos.Asset.Update(serverID, map[string]interface{}{"net_worth": 100})
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Asset.Update(rackID, map[string]interface{}{"net_worth": 50})
+	assert.Equal(t, nil, err, "service error")
+	stats, err := daos.Stat.GetAllAssetStat()
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, "50", stats[0].Total.String(), "service error")
+
+	// restored as retired under its rack, a second restore is refused
+	err = recycle.RestoreAssets([]uint{serverID}, 0, "")
+	assert.Equal(t, nil, err, "service error")
+	server = getAsset(serverID)
+	assert.Equal(t, define.ASSET_RETIRED, server.State, "service error")
+	assert.Equal(t, rackID, server.ParentID, "service error")
+	assert.Equal(t, (*model.ModelTime)(nil), server.DeletedAt, "service error")
+	err = recycle.RestoreAssets([]uint{serverID}, 0, "")
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	history, _ := assetService.GetAssetStateHistory(serverID)
+	assert.Equal(t, define.ASSET_RESTORE, history[0].Event, "service error")
+	assert.Equal(t, define.ASSET_RETIRED, history[0].ToState, "service error")
+
+	// a parent that is gone leaves the restored asset at the top
+	err = recycle.DeleteAssets([]uint{diskID}, 0, "")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Asset.Update(diskID, map[string]interface{}{"deleted_parent_id": serverID})
+	assert.Equal(t, nil, err, "service error")
+	err = recycle.DeleteAssets([]uint{serverID}, 0, "")
+	assert.Equal(t, nil, err, "service error")
+	err = recycle.RestoreAssets([]uint{diskID}, 0, "")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, uint(0), getAsset(diskID).ParentID, "service error")
+	assert.Equal(t, define.ASSET_IDLE, getAsset(diskID).State, "service error")
+
+	// within the retention period nothing is purged, after it the server is archived
+	purged, err := recycle.PurgeExpired()
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, purged, "service error")
+	recycle.now = func() time.Time {
+		return time.Now().AddDate(0, 0, 31)
+	}
+	purged, err = recycle.PurgeExpired()
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, purged, "service error")
+	assert.Equal(t, (*model.Asset)(nil), getAsset(serverID), "service error")
+	history, _ = assetService.GetAssetStateHistory(serverID)
+	assert.Equal(t, 0, len(history), "service error")
+
+	archive := model.AssetArchive{}
+	err = db.Where("asset_id = ?", serverID).First(&archive).Error
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, "server", archive.Name, "service error")
+	assert.Equal(t, department.ID, archive.DepartmentID, "service error")
+	snapshot := define.AssetArchiveSnapshot{}
+	err = json.Unmarshal(archive.Snapshot, &snapshot)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, serverID, snapshot.Asset.ID, "service error")
+	assert.Equal(t, 4, len(snapshot.States), "service error")
+}
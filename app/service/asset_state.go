@@ -0,0 +1,256 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thoas/go-funk"
+	"gorm.io/gorm"
+)
+
+var ErrAssetState = errors.New("asset state does not allow this change")
+
+/*
+Who moved the assets and why. TargetID is the user an asset goes to, or its maintainer,
+Quantity is left empty when the whole asset moves
+*/
+type AssetStateChange struct {
+	Event    string
+	ActorID  uint
+	TaskID   uint
+	TargetID uint
+	Reason   string
+	Quantity int
+}
+
+type assetTransition struct {
+	from []uint
+	to   uint
+	keep bool // the state stays put, the caller moves the asset itself
+	// the state goes back to model.Asset.DeletedState instead of to
+	restore bool
+	// guard rejects a change the source state alone would allow
+	guard func(thisAsset *model.Asset, change AssetStateChange) error
+	// columns written together with the new state
+	apply func(change AssetStateChange) map[string]interface{}
+	// columns copied from the ones the new state changes, written in their own update first
+	save map[string]interface{}
+}
+
+var assetStateNames = map[uint]string{
+	define.ASSET_IDLE:        "idle",
+	define.ASSET_IN_USE:      "in use",
+	define.ASSET_IN_MAINTAIN: "in maintenance",
+	define.ASSET_RETIRED:     "retired",
+	define.ASSET_DELETED:     "deleted",
+}
+
+func needsTarget(thisAsset *model.Asset, change AssetStateChange) error {
+	if change.TargetID == 0 {
+		return fmt.Errorf("%w: %s needs a target user", ErrAssetState, change.Event)
+	}
+	return nil
+}
+
+/*
+Every allowed transition of model.Asset.State, anything missing here is refused
+*/
+var assetTransitions = map[string]assetTransition{
+	define.ASSET_ACQUIRE: {
+		from:  []uint{define.ASSET_IDLE},
+		to:    define.ASSET_IN_USE,
+		guard: needsTarget,
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{"user_id": change.TargetID}
+		},
+	},
+	define.ASSET_RETURN: {
+		from:  []uint{define.ASSET_IN_USE},
+		to:    define.ASSET_IDLE,
+		guard: needsTarget,
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{"user_id": change.TargetID}
+		},
+	},
+	define.ASSET_MAINTAIN: {
+		from:  []uint{define.ASSET_IDLE, define.ASSET_IN_USE},
+		to:    define.ASSET_IN_MAINTAIN,
+		guard: needsTarget,
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{"maintainer_id": change.TargetID}
+		},
+	},
+	define.ASSET_FINISH_MAINTAIN: {
+		from: []uint{define.ASSET_IN_MAINTAIN},
+		to:   define.ASSET_IN_USE,
+		guard: func(thisAsset *model.Asset, change AssetStateChange) error {
+			if change.ActorID != 0 && thisAsset.MaintainerID != change.ActorID {
+				return fmt.Errorf("%w: asset %d is maintained by someone else", ErrAssetState, thisAsset.ID)
+			}
+			return nil
+		},
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{"maintainer_id": gorm.Expr("NULL")}
+		},
+	},
+	define.ASSET_TRANSFER: {
+		from:  []uint{define.ASSET_IDLE, define.ASSET_IN_USE},
+		keep:  true,
+		guard: needsTarget,
+	},
+	// units leave a quantity-type asset, or come back to it, the caller moves them
+	define.ASSET_SPLIT: {
+		from: []uint{define.ASSET_IDLE, define.ASSET_IN_USE},
+		keep: true,
+	},
+	define.ASSET_MERGE: {
+		from: []uint{define.ASSET_IDLE},
+		keep: true,
+	},
+	define.ASSET_RETIRE: {
+		from: []uint{define.ASSET_IDLE, define.ASSET_IN_USE, define.ASSET_IN_MAINTAIN},
+		to:   define.ASSET_RETIRED,
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{"net_worth": decimal.Zero}
+		},
+	},
+	define.ASSET_DELETE: {
+		from: []uint{define.ASSET_IDLE, define.ASSET_RETIRED},
+		to:   define.ASSET_DELETED,
+		save: map[string]interface{}{
+			"deleted_parent_id": gorm.Expr("parent_id"),
+			"deleted_state":     gorm.Expr("state"),
+		},
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{
+				"deleted_at": time.Now(),
+				"parent_id":  gorm.Expr("NULL"),
+			}
+		},
+	},
+	define.ASSET_RESTORE: {
+		from:    []uint{define.ASSET_DELETED},
+		restore: true,
+		guard: func(thisAsset *model.Asset, change AssetStateChange) error {
+			if thisAsset.StockID != 0 && thisAsset.Number == 0 {
+				return fmt.Errorf("%w: asset %d was merged into asset %d", ErrAssetState, thisAsset.ID, thisAsset.StockID)
+			}
+			return nil
+		},
+		// deleted_state is left as it is, state is read from it in the same statement
+		apply: func(change AssetStateChange) map[string]interface{} {
+			return map[string]interface{}{
+				"deleted_at":        gorm.Expr("NULL"),
+				"deleted_parent_id": gorm.Expr("NULL"),
+			}
+		},
+	},
+}
+
+/*
+Check the assets' source states only, for requests like tasks that learn the rest of the change later
+*/
+func CheckAssetEvent(assetList []*model.Asset, event string) error {
+	transition, ok := assetTransitions[event]
+	if !ok {
+		return fmt.Errorf("%w: unknown event %q", ErrAssetState, event)
+	}
+	for _, thisAsset := range assetList {
+		if !funk.ContainsUInt(transition.from, thisAsset.State) {
+			return fmt.Errorf("%w: asset %d is %s, cannot %s",
+				ErrAssetState, thisAsset.ID, assetStateNames[thisAsset.State], event)
+		}
+	}
+	return nil
+}
+
+func checkAssetTransition(assetList []*model.Asset, change AssetStateChange) error {
+	if err := CheckAssetEvent(assetList, change.Event); err != nil {
+		return err
+	}
+	guard := assetTransitions[change.Event].guard
+	if guard == nil {
+		return nil
+	}
+	for _, thisAsset := range assetList {
+		if err := guard(thisAsset, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Move the assets along one transition and record it, for callers already inside a transaction.
+A transition that keeps the state is only checked and recorded
+*/
+func ApplyAssetTransition(tx *dao.Daos, assetIDs []uint, change AssetStateChange) error {
+	assetIDs = funk.UniqUInt(assetIDs)
+	if len(assetIDs) == 0 {
+		return nil
+	}
+	assetList, err := tx.AssetState.GetAssetsByIDs(assetIDs)
+	if err != nil {
+		return err
+	}
+	if len(assetList) != len(assetIDs) {
+		return fmt.Errorf("%w: asset not found", ErrAssetState)
+	}
+	if err = checkAssetTransition(assetList, change); err != nil {
+		return err
+	}
+
+	transition := assetTransitions[change.Event]
+	if !transition.keep {
+		if transition.save != nil {
+			if err = tx.Asset.AllUpdate(assetIDs, transition.save); err != nil {
+				return err
+			}
+		}
+		data := map[string]interface{}{}
+		if transition.apply != nil {
+			data = transition.apply(change)
+		}
+		data["state"] = transition.to
+		if transition.restore {
+			data["state"] = gorm.Expr("deleted_state")
+		}
+		affected, err := tx.AssetState.UpdateState(assetIDs, transition.from, data)
+		if err != nil {
+			return err
+		}
+		// another request moved one of them since it was read
+		if affected != int64(len(assetIDs)) {
+			return fmt.Errorf("%w: asset changed concurrently", ErrAssetState)
+		}
+	}
+
+	records := funk.Map(assetList, func(thisAsset *model.Asset) *model.AssetStateHistory {
+		toState := transition.to
+		if transition.keep {
+			toState = thisAsset.State
+		} else if transition.restore {
+			toState = thisAsset.DeletedState
+		}
+		quantity := change.Quantity
+		if quantity == 0 {
+			quantity = thisAsset.Number
+		}
+		return &model.AssetStateHistory{
+			AssetID:   thisAsset.ID,
+			Event:     change.Event,
+			FromState: thisAsset.State,
+			ToState:   toState,
+			ActorID:   change.ActorID,
+			TaskID:    change.TaskID,
+			Reason:    change.Reason,
+			Quantity:  quantity,
+		}
+	}).([]*model.AssetStateHistory)
+	return tx.AssetState.CreateHistory(records)
+}
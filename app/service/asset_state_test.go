@@ -0,0 +1,94 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestAssetStateMachine(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	assetService := NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos)
+
+	for _, name := range []string{"state_manager", "state_user", "state_maintainer"} {
+		err := daos.User.Create(model.User{UserName: name, Password: "21232f297a57a5a743894a0e4a801fc3"})
+		assert.Equal(t, nil, err, "service error")
+	}
+	manager, _ := daos.User.GetUserByName("state_manager")
+	thisUser, _ := daos.User.GetUserByName("state_user")
+	maintainer, _ := daos.User.GetUserByName("state_maintainer")
+	assetID, err := daos.Asset.CreateAndGetID(model.Asset{
+		Name:     "state_asset",
+		UserID:   manager.ID,
+		Property: datatypes.JSON([]byte(`{}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+	stateOf := func() *model.Asset {
+		thisAsset, err := daos.Asset.GetAssetByID(assetID)
+		assert.Equal(t, nil, err, "service error")
+		return thisAsset
+	}
+
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_ACQUIRE, ActorID: manager.ID, TargetID: thisUser.ID, Reason: "onboarding"})
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.ASSET_IN_USE, stateOf().State, "service error")
+	assert.Equal(t, thisUser.ID, stateOf().UserID, "service error")
+
+	// an asset in use cannot be acquired again, and nothing changes
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_ACQUIRE, ActorID: manager.ID, TargetID: manager.ID})
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	assert.Equal(t, thisUser.ID, stateOf().UserID, "service error")
+
+	// guards: maintenance needs a maintainer and only the maintainer finishes it
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_MAINTAIN, ActorID: manager.ID})
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_MAINTAIN, ActorID: manager.ID, TargetID: maintainer.ID})
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, maintainer.ID, stateOf().MaintainerID, "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_FINISH_MAINTAIN, ActorID: thisUser.ID})
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_FINISH_MAINTAIN, ActorID: maintainer.ID})
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.ASSET_IN_USE, stateOf().State, "service error")
+	assert.Equal(t, uint(0), stateOf().MaintainerID, "service error")
+
+	// a retired asset can be neither acquired nor transferred, only deleted
+	err = assetService.ExpireAssets([]uint{assetID}, manager.ID, "broken")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.ASSET_RETIRED, stateOf().State, "service error")
+	assert.Equal(t, true, stateOf().NetWorth.IsZero(), "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_ACQUIRE, TargetID: thisUser.ID})
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = assetService.TransferAssets([]uint{assetID}, thisUser.ID, 0, 0, manager.ID, "")
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: define.ASSET_DELETE, ActorID: manager.ID})
+	assert.Equal(t, nil, err, "service error")
+	err = assetService.TransferAssets([]uint{assetID}, thisUser.ID, 0, 0, manager.ID, "")
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+	err = assetService.ChangeAssetState([]uint{assetID}, AssetStateChange{Event: "teleport"})
+	assert.ErrorIs(t, err, ErrAssetState, "service error")
+
+	// only applied transitions are recorded, newest first
+	history, err := assetService.GetAssetStateHistory(assetID)
+	assert.Equal(t, nil, err, "service error")
+	events := []string{}
+	for _, record := range history {
+		events = append(events, record.Event)
+	}
+	assert.Equal(t, []string{
+		define.ASSET_DELETE,
+		define.ASSET_RETIRE,
+		define.ASSET_FINISH_MAINTAIN,
+		define.ASSET_MAINTAIN,
+		define.ASSET_ACQUIRE,
+	}, events, "service error")
+	assert.Equal(t, define.ASSET_IN_USE, history[1].FromState, "service error")
+	assert.Equal(t, "broken", history[1].Reason, "service error")
+	assert.Equal(t, manager.ID, history[4].ActorID, "service error")
+	assert.Equal(t, "state_manager", history[4].Actor.UserName, "service error")
+	assert.Equal(t, "onboarding", history[4].Reason, "service error")
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"asset-management/app/dao"
 	"asset-management/app/define"
+	"asset-management/app/model"
 	"testing"
 
 	"github.com/shopspring/decimal"
@@ -64,7 +66,7 @@ func TestAsset(t *testing.T) {
 		Type:        1,
 	}
 
-	AssetService.ModifyAssetInfo(1, ModifyAsset)
+	AssetService.ModifyAssetInfo(1, ModifyAsset, 1)
 
 	AssetService.CreateAsset(&CreateAsset, 1, 1, 1)
 
@@ -76,10 +78,10 @@ func TestAsset(t *testing.T) {
 	AssetService.CheckAssetInDepartment(1, 1)
 	AssetService.CheckIsAncestor(1, 2)
 	assets := []uint{1}
-	AssetService.ExpireAssets(assets)
+	AssetService.ExpireAssets(assets, 1, "")
 	assets = []uint{2}
-	AssetService.ExpireAssets(assets)
-	AssetService.TransferAssets([]uint{1}, 1, 1, 1)
+	AssetService.ExpireAssets(assets, 1, "")
+	AssetService.TransferAssets([]uint{1}, 1, 1, 1, 1, "")
 
 	AssetService.GetAssetByUser(1)
 	AssetService.GetAssetByUser(2)
@@ -90,13 +92,91 @@ func TestAsset(t *testing.T) {
 	AssetService.GetUserAssetsByIDs([]uint{1, 2, 3}, 1)
 	AssetService.GetDepartmentIdleAssets([]uint{0}, 1)
 	AssetService.GetDepartmentIdleAssets([]uint{1, 2, 3}, 1)
-	AssetService.AcquireAssets([]uint{0}, 1)
-	AssetService.AcquireAssets([]uint{1, 2, 3}, 1)
-	AssetService.CancelAssets([]uint{0}, 1)
-	AssetService.CancelAssets([]uint{1, 2, 3}, 1)
+	AssetService.ChangeAssetState([]uint{0}, AssetStateChange{Event: define.ASSET_ACQUIRE, TargetID: 1})
+	AssetService.ChangeAssetState([]uint{1, 2, 3}, AssetStateChange{Event: define.ASSET_ACQUIRE, TargetID: 1})
+	AssetService.ChangeAssetState([]uint{0}, AssetStateChange{Event: define.ASSET_RETURN, TargetID: 1})
+	AssetService.ChangeAssetState([]uint{1, 2, 3}, AssetStateChange{Event: define.ASSET_RETURN, TargetID: 1})
 	AssetService.GetUserMaintainAssets(1)
 	AssetService.GetUserMaintainAssets(9)
-	AssetService.ModifyAssetMaintainerAndState([]uint{0}, 1)
-	AssetService.ModifyAssetMaintainerAndState([]uint{1, 2, 3}, 1)
+	AssetService.ChangeAssetState([]uint{0}, AssetStateChange{Event: define.ASSET_MAINTAIN, TargetID: 1})
+	AssetService.ChangeAssetState([]uint{1, 2, 3}, AssetStateChange{Event: define.ASSET_MAINTAIN, TargetID: 1})
 
 }
+
+type fakeAssetDao struct {
+	dao.AssetDaoInterface
+	subAssets []*model.Asset
+	updated   map[uint]map[string]interface{}
+}
+
+func (fake *fakeAssetDao) GetSubAssetsByParents(ids []uint) ([]*model.Asset, error) {
+	return fake.subAssets, nil
+}
+
+func (fake *fakeAssetDao) AllUpdate(ids []uint, data map[string]interface{}) error {
+	for _, id := range ids {
+		fake.updated[id] = data
+	}
+	return nil
+}
+
+type fakeAssetStateDao struct {
+	dao.AssetStateDaoInterface
+	assets  map[uint]*model.Asset
+	history []*model.AssetStateHistory
+}
+
+func (fake *fakeAssetStateDao) GetAssetsByIDs(ids []uint) ([]*model.Asset, error) {
+	assetList := []*model.Asset{}
+	for _, id := range ids {
+		if thisAsset, ok := fake.assets[id]; ok {
+			assetList = append(assetList, thisAsset)
+		}
+	}
+	return assetList, nil
+}
+
+func (fake *fakeAssetStateDao) CreateHistory(records []*model.AssetStateHistory) error {
+	fake.history = append(fake.history, records...)
+	return nil
+}
+
+type fakeUnitOfWork struct {
+	tx *dao.Daos
+}
+
+func (fake *fakeUnitOfWork) Transaction(fn func(tx *dao.Daos) error) error {
+	return fn(fake.tx)
+}
+
+func TestTransferAssetsWithFakeDao(t *testing.T) {
+	fake := &fakeAssetDao{
+		subAssets: []*model.Asset{{ID: 3}},
+		updated:   map[uint]map[string]interface{}{},
+	}
+	stateDao := &fakeAssetStateDao{assets: map[uint]*model.Asset{
+		1: {ID: 1, State: define.ASSET_IN_USE},
+		2: {ID: 2, State: define.ASSET_IDLE},
+	}}
+	assetService := NewAssetService(fake, stateDao, nil, &fakeUnitOfWork{tx: &dao.Daos{Asset: fake, AssetState: stateDao}})
+
+	err := assetService.TransferAssets([]uint{1, 2}, 7, 1, 1, 5, "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]interface{}{"user_id": uint(7)}, fake.updated[1])
+	assert.NotContains(t, fake.updated, uint(3))
+	assert.Equal(t, 2, len(stateDao.history))
+	assert.Equal(t, define.ASSET_IN_USE, stateDao.history[0].ToState)
+	assert.Equal(t, uint(5), stateDao.history[1].ActorID)
+
+	err = assetService.TransferAssets([]uint{1, 2}, 8, 2, 1, 5, "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint(2), fake.updated[2]["department_id"])
+	assert.Contains(t, fake.updated[3], "parent_id")
+
+	// a retired asset stays where it is
+	stateDao.assets[2].State = define.ASSET_RETIRED
+	fake.updated = map[uint]map[string]interface{}{}
+	err = assetService.TransferAssets([]uint{1, 2}, 8, 2, 1, 5, "")
+	assert.ErrorIs(t, err, ErrAssetState)
+	assert.Equal(t, 0, len(fake.updated))
+}
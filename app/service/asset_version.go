@@ -0,0 +1,302 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var ErrAssetVersionNotFound = errors.New("asset version not found")
+var ErrRevertParent = errors.New("restored parent asset is not available")
+var ErrRevertCycle = errors.New("restored parent asset is a successor of the asset")
+var ErrRevertClass = errors.New("restored asset class is not available")
+
+func decodeAs[T any](raw json.RawMessage) (interface{}, error) {
+	var value T
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func decodeAssetRef(raw json.RawMessage) (interface{}, error) {
+	var id uint
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return nil, err
+	} else if id == 0 {
+		return gorm.Expr("NULL"), nil
+	}
+	return id, nil
+}
+
+/*
+Column fields a version records, each with how a recorded value is written back
+*/
+var assetFieldDecoders = map[string]func(raw json.RawMessage) (interface{}, error){
+	"name":                decodeAs[string],
+	"price":               decodeAs[decimal.Decimal],
+	"description":         decodeAs[string],
+	"position":            decodeAs[string],
+	"class_id":            decodeAssetRef,
+	"type":                decodeAs[int],
+	"number":              decodeAs[int],
+	"expire":              decodeAs[uint],
+	"img_list":            decodeAs[datatypes.JSONSlice[string]],
+	"threshold":           decodeAs[uint],
+	"parent_id":           decodeAssetRef,
+	"depreciation_method": decodeAs[string],
+	"residual_rate":       decodeAs[decimal.NullDecimal],
+}
+
+func assetVersionFields(thisAsset *model.Asset) map[string]interface{} {
+	fields := map[string]interface{}{
+		"name":                thisAsset.Name,
+		"price":               thisAsset.Price,
+		"description":         thisAsset.Description,
+		"position":            thisAsset.Position,
+		"class_id":            thisAsset.ClassID,
+		"type":                thisAsset.Type,
+		"number":              thisAsset.Number,
+		"expire":              thisAsset.Expire,
+		"img_list":            thisAsset.ImgList,
+		"threshold":           thisAsset.Threshold,
+		"parent_id":           thisAsset.ParentID,
+		"depreciation_method": thisAsset.DepreciationMethod,
+		"residual_rate":       thisAsset.ResidualRate,
+	}
+	property := map[string]interface{}{}
+	_ = json.Unmarshal(thisAsset.Property, &property)
+	for key, value := range property {
+		fields[define.ASSET_PROPERTY_PREFIX+key] = value
+	}
+	return fields
+}
+
+func diffAssetFields(before *model.Asset, after *model.Asset) (map[string]define.AssetFieldChange, error) {
+	oldFields := assetVersionFields(before)
+	newFields := assetVersionFields(after)
+	for key := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			newFields[key] = nil
+		}
+	}
+
+	changes := map[string]define.AssetFieldChange{}
+	for key, newValue := range newFields {
+		oldRaw, err := json.Marshal(oldFields[key])
+		if err != nil {
+			return nil, err
+		}
+		newRaw, err := json.Marshal(newValue)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(oldRaw, newRaw) {
+			changes[key] = define.AssetFieldChange{Old: oldRaw, New: newRaw}
+		}
+	}
+	return changes, nil
+}
+
+/*
+Record the difference between two reads of one asset as its next version, nothing if they match
+*/
+func recordAssetVersion(tx *dao.Daos, before *model.Asset, after *model.Asset, actorID uint, revertOf *uint) error {
+	changes, err := diffAssetFields(before, after)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	latest, err := tx.AssetVersion.GetLatestVersion(after.ID)
+	if err != nil {
+		return err
+	}
+	return tx.AssetVersion.Create(&model.AssetVersion{
+		AssetID:  after.ID,
+		Version:  latest + 1,
+		ActorID:  actorID,
+		Changes:  datatypes.JSON(data),
+		RevertOf: revertOf,
+	})
+}
+
+/*
+The column updates that put recorded values back, properties rewritten as a whole
+*/
+func assetFieldUpdates(thisAsset *model.Asset, restore map[string]json.RawMessage) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	property := map[string]interface{}{}
+	_ = json.Unmarshal(thisAsset.Property, &property)
+	propertyChanged := false
+
+	for key, raw := range restore {
+		if name, ok := strings.CutPrefix(key, define.ASSET_PROPERTY_PREFIX); ok {
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			} else if value == nil {
+				delete(property, name)
+			} else {
+				property[name] = value
+			}
+			propertyChanged = true
+			continue
+		}
+		decode, ok := assetFieldDecoders[key]
+		if !ok {
+			continue
+		}
+		value, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = value
+	}
+
+	if propertyChanged {
+		propertyJSON, err := json.Marshal(property)
+		if err != nil {
+			return nil, err
+		}
+		data["property"] = datatypes.JSON(propertyJSON)
+	}
+	return data, nil
+}
+
+/*
+Whether the column updates may still be made. A restored parent or class has to be
+in the asset's department and not deleted, the parent must not be a successor of the
+asset, and a stock with portions split off keeps its number and type
+*/
+func checkAssetRestore(tx *dao.Daos, thisAsset *model.Asset, data map[string]interface{}) error {
+	if parentID, ok := data["parent_id"].(uint); ok {
+		parent, err := tx.Asset.GetAssetByID(parentID)
+		if err != nil {
+			return err
+		} else if parent == nil || parent.DeletedAt != nil || parent.DepartmentID != thisAsset.DepartmentID {
+			return fmt.Errorf("%w: asset %d", ErrRevertParent, parentID)
+		}
+		for ancestor := parent; ancestor != nil; {
+			if ancestor.ID == thisAsset.ID {
+				return fmt.Errorf("%w: asset %d", ErrRevertCycle, parentID)
+			} else if ancestor.ParentID == 0 {
+				break
+			}
+			ancestor, err = tx.Asset.GetAssetByID(ancestor.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if classID, ok := data["class_id"].(uint); ok {
+		class, err := tx.AssetClass.GetAssetClassByID(classID)
+		if err != nil {
+			return err
+		} else if class == nil || class.DepartmentID != thisAsset.DepartmentID {
+			return fmt.Errorf("%w: class %d", ErrRevertClass, classID)
+		}
+	}
+
+	number, hasNumber := data["number"].(int)
+	assetType, hasType := data["type"].(int)
+	if (hasNumber && number != thisAsset.Number) || (hasType && assetType != thisAsset.Type) {
+		portions, err := tx.AssetState.CountPortions(thisAsset.ID)
+		if err != nil {
+			return err
+		} else if portions != 0 {
+			return fmt.Errorf("%w: asset %d has %d portions split off", ErrAssetQuantity, thisAsset.ID, portions)
+		}
+	}
+	return nil
+}
+
+/*
+Run an edit of one asset and record what it changed in the same transaction
+*/
+func (asset *assetService) editAsset(assetID uint, actorID uint, edit func(tx *dao.Daos) error) error {
+	return asset.uow.Transaction(func(tx *dao.Daos) error {
+		before, err := tx.Asset.GetAssetByID(assetID)
+		if err != nil {
+			return err
+		}
+		if err = edit(tx); err != nil || before == nil {
+			return err
+		}
+		after, err := tx.Asset.GetAssetByID(assetID)
+		if err != nil {
+			return err
+		}
+		return recordAssetVersion(tx, before, after, actorID, nil)
+	})
+}
+
+func (asset *assetService) GetAssetVersions(assetID uint) ([]*model.AssetVersion, error) {
+	return asset.assetVersionDao.GetVersions(assetID)
+}
+
+/*
+Put the asset back to how it was right after the given version by undoing every later
+version, newest first. Version 0 is the asset before its first recorded edit.
+The revert is itself recorded as a new version
+*/
+func (asset *assetService) RevertAsset(assetID uint, version uint, actorID uint) error {
+	return asset.uow.Transaction(func(tx *dao.Daos) error {
+		latest, err := tx.AssetVersion.GetLatestVersion(assetID)
+		if err != nil {
+			return err
+		} else if version > latest {
+			return ErrAssetVersionNotFound
+		}
+		before, err := tx.Asset.GetAssetByID(assetID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return ErrAssetVersionNotFound
+		}
+
+		laterVersions, err := tx.AssetVersion.GetVersionsAfter(assetID, version)
+		if err != nil {
+			return err
+		}
+		restore := map[string]json.RawMessage{}
+		for _, laterVersion := range laterVersions {
+			var changes map[string]define.AssetFieldChange
+			if err = json.Unmarshal(laterVersion.Changes, &changes); err != nil {
+				return err
+			}
+			// older versions come later and win
+			for key, change := range changes {
+				restore[key] = change.Old
+			}
+		}
+		if len(restore) == 0 {
+			return nil
+		}
+
+		data, err := assetFieldUpdates(before, restore)
+		if err != nil {
+			return err
+		}
+		if err = checkAssetRestore(tx, before, data); err != nil {
+			return err
+		}
+		if err = tx.Asset.Update(assetID, data); err != nil {
+			return err
+		}
+		after, err := tx.Asset.GetAssetByID(assetID)
+		if err != nil {
+			return err
+		}
+		return recordAssetVersion(tx, before, after, actorID, &version)
+	})
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestAssetVersion(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	assetService := NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos)
+
+	err := daos.User.Create(model.User{UserName: "version_auditor", Password: "21232f297a57a5a743894a0e4a801fc3"})
+	assert.Equal(t, nil, err, "service error")
+	auditor, _ := daos.User.GetUserByName("version_auditor")
+	assetID, err := daos.Asset.CreateAndGetID(model.Asset{
+		Name:        "laptop",
+		Price:       decimal.New(1000, 0),
+		Description: "14 inch",
+		Property:    datatypes.JSON([]byte(`{"color":"grey"}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+	getAsset := func() *model.Asset {
+		thisAsset, err := daos.Asset.GetAssetByID(assetID)
+		assert.Equal(t, nil, err, "service error")
+		return thisAsset
+	}
+	property := func() map[string]interface{} {
+		data := map[string]interface{}{}
+		json.Unmarshal(getAsset().Property, &data)
+		return data
+	}
+
+	// version 1: price and name, the untouched description is not recorded
+	err = assetService.ModifyAssetInfo(assetID, define.ModifyAssetInfoReq{
+		AssetName:   "laptop pro",
+		Price:       decimal.New(1500, 0),
+		Description: "14 inch",
+	}, auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	// an edit that changes nothing is no version
+	err = assetService.ModifyAssetInfo(assetID, define.ModifyAssetInfoReq{AssetName: "laptop pro"}, auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	// versions 2 to 4: properties
+	assert.Equal(t, nil, assetService.SetProperty(assetID, "owner", "it", auditor.ID), "service error")
+	assert.Equal(t, nil, assetService.SetProperty(assetID, "color", "black", auditor.ID), "service error")
+	assert.Equal(t, nil, assetService.DeleteProperty(assetID, "owner", auditor.ID), "service error")
+
+	versions, err := assetService.GetAssetVersions(assetID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 4, len(versions), "service error")
+	first := versions[len(versions)-1]
+	assert.Equal(t, uint(1), first.Version, "service error")
+	assert.Equal(t, "version_auditor", first.Actor.UserName, "service error")
+	changes := map[string]define.AssetFieldChange{}
+	err = json.Unmarshal(first.Changes, &changes)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 2, len(changes), "service error")
+	assert.Equal(t, `"laptop"`, string(changes["name"].Old), "service error")
+	assert.Equal(t, `"laptop pro"`, string(changes["name"].New), "service error")
+	assert.Contains(t, changes, "price", "service error")
+
+	// back to right after version 2: the owner returns and the color is grey again
+	err = assetService.RevertAsset(assetID, 2, auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, map[string]interface{}{"color": "grey", "owner": "it"}, property(), "service error")
+	assert.Equal(t, "laptop pro", getAsset().Name, "service error")
+	versions, _ = assetService.GetAssetVersions(assetID)
+	assert.Equal(t, uint(5), versions[0].Version, "service error")
+	assert.Equal(t, uint(2), *versions[0].RevertOf, "service error")
+
+	// version 0 is the asset as created, and a revert can itself be undone
+	err = assetService.RevertAsset(assetID, 0, auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, "laptop", getAsset().Name, "service error")
+	assert.Equal(t, true, decimal.New(1000, 0).Equal(getAsset().Price), "service error")
+	assert.Equal(t, map[string]interface{}{"color": "grey"}, property(), "service error")
+	err = assetService.RevertAsset(assetID, 5, auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, "laptop pro", getAsset().Name, "service error")
+	assert.Equal(t, map[string]interface{}{"color": "grey", "owner": "it"}, property(), "service error")
+
+	err = assetService.RevertAsset(assetID, 99, auditor.ID)
+	assert.ErrorIs(t, err, ErrAssetVersionNotFound, "service error")
+}
+
+func TestAssetRevertChecks(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	assetService := NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos)
+
+	for _, name := range []string{"revert_department", "revert_other"} {
+		err := daos.Department.Create(model.Department{Name: name})
+		assert.Equal(t, nil, err, "service error")
+	}
+	department, _ := daos.Department.GetDepartmentByName("revert_department")
+	other, _ := daos.Department.GetDepartmentByName("revert_other")
+	err := daos.AssetClass.Create(model.AssetClass{Name: "revert_foreign", DepartmentID: other.ID})
+	assert.Equal(t, nil, err, "service error")
+	err = daos.AssetClass.Create(model.AssetClass{Name: "revert_class", DepartmentID: department.ID})
+	assert.Equal(t, nil, err, "service error")
+	foreignClass, _ := daos.AssetClass.GetDepartmentDirectClass(other.ID)
+	ownClass, _ := daos.AssetClass.GetDepartmentDirectClass(department.ID)
+	newAsset := func(name string, classID uint) uint {
+		assetID, err := daos.Asset.CreateAndGetID(model.Asset{
+			Name:         name,
+			DepartmentID: department.ID,
+			ClassID:      classID,
+			Type:         define.ASSET_TYPE_QUANTITY,
+			Number:       10,
+			Property:     datatypes.JSON([]byte(`{}`)),
+		})
+		assert.Equal(t, nil, err, "service error")
+		return assetID
+	}
+	setParent := func(assetID uint, parentID uint) {
+		err := assetService.ModifyAssetInfo(assetID, define.ModifyAssetInfoReq{ParentID: &parentID}, 0)
+		assert.Equal(t, nil, err, "service error")
+	}
+
+	// a parent that has become a successor is not restored
+	first, second := newAsset("revert_first", 0), newAsset("revert_second", 0)
+	setParent(first, second)
+	setParent(first, 0)
+	setParent(second, first)
+	assert.ErrorIs(t, assetService.RevertAsset(first, 1, 0), ErrRevertCycle, "service error")
+	// neither is a deleted one
+	setParent(second, 0)
+	err = daos.Asset.Update(second, map[string]interface{}{"deleted_at": time.Now(), "state": define.ASSET_DELETED})
+	assert.Equal(t, nil, err, "service error")
+	assert.ErrorIs(t, assetService.RevertAsset(first, 1, 0), ErrRevertParent, "service error")
+
+	// nor a class of another department
+	classed := newAsset("revert_classed", foreignClass[0].ID)
+	err = assetService.ModifyAssetInfo(classed, define.ModifyAssetInfoReq{ClassID: ownClass[0].ID}, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.ErrorIs(t, assetService.RevertAsset(classed, 0, 0), ErrRevertClass, "service error")
+	thisAsset, _ := daos.Asset.GetAssetByID(classed)
+	assert.Equal(t, ownClass[0].ID, thisAsset.ClassID, "service error")
+
+	// a stock keeps its number while portions are split off it
+	stock := newAsset("revert_stock", 0)
+	err = assetService.ModifyAssetInfo(stock, define.ModifyAssetInfoReq{Number: 8}, 0)
+	assert.Equal(t, nil, err, "service error")
+	_, err = daos.Asset.CreateAndGetID(model.Asset{
+		Name:         "revert_stock",
+		DepartmentID: department.ID,
+		StockID:      stock,
+		Type:         define.ASSET_TYPE_QUANTITY,
+		Number:       2,
+		Property:     datatypes.JSON([]byte(`{}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+	assert.ErrorIs(t, assetService.RevertAsset(stock, 0, 0), ErrAssetQuantity, "service error")
+}
@@ -4,23 +4,27 @@ import (
 	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
+	"asset-management/app/storage"
 )
 
-type asyncService struct {
+type AsyncServiceInterface interface {
+	GetUserAsyncTasks(userID uint) ([]*model.AsyncTask, error)
+	CreateAsyncTask(userID uint, req *define.CreateAsyncTaskReq) error
+	GetAsyncTaskByID(taskID uint) (*model.AsyncTask, error)
+	ModifyAsyncTaskState(taskID uint, state uint) error
+	GetDownloadLink(task *model.AsyncTask) string
 }
 
-var AsyncService *asyncService
-
-func newAsyncService() *asyncService {
-	return &asyncService{}
+type asyncService struct {
+	asyncDao dao.AsyncDaoInterface
 }
 
-func init() {
-	AsyncService = newAsyncService()
+func NewAsyncService(asyncDao dao.AsyncDaoInterface) AsyncServiceInterface {
+	return &asyncService{asyncDao: asyncDao}
 }
 
 func (asy *asyncService) GetUserAsyncTasks(userID uint) ([]*model.AsyncTask, error) {
-	taskList, err := dao.AsyncDao.GetAsyncTaskListByUserID(userID)
+	taskList, err := asy.asyncDao.GetAsyncTaskListByUserID(userID)
 	return taskList, err
 }
 
@@ -38,17 +42,27 @@ func (asy *asyncService) CreateAsyncTask(userID uint, req *define.CreateAsyncTas
 		task.DepartmentID = req.DepartmentID
 	}
 
-	err := dao.AsyncDao.CreateAsyncTask(task)
+	err := asy.asyncDao.CreateAsyncTask(task)
 
 	return err
 }
 
 func (asy *asyncService) GetAsyncTaskByID(taskID uint) (*model.AsyncTask, error) {
-	return dao.AsyncDao.GetAsyncTaskByID(taskID)
+	return asy.asyncDao.GetAsyncTaskByID(taskID)
 }
 
 func (asy *asyncService) ModifyAsyncTaskState(taskID uint, state uint) error {
-	return dao.AsyncDao.ModifyAsyncTaskInfo(taskID, map[string]interface{}{
+	return asy.asyncDao.ModifyAsyncTaskInfo(taskID, map[string]interface{}{
 		"state": state,
 	})
 }
+
+/*
+Finished export tasks get a fresh signed link, older tasks keep the link stored with them
+*/
+func (asy *asyncService) GetDownloadLink(task *model.AsyncTask) string {
+	if task.Type != 0 && task.State == 2 && task.ObjectKey != "" {
+		return storage.SignedLink(storage.ExportBucketName, task.ObjectKey)
+	}
+	return task.DownloadLink
+}
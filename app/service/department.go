@@ -11,16 +11,52 @@ import (
 	"github.com/jinzhu/copier"
 )
 
-type departmentService struct{}
-
-var DepartmentService *departmentService
+type DepartmentServiceInterface interface {
+	GetHeaderDepartmentID(ctx *utils.Context) uint
+	CheckIsInDepartment(ctx *utils.Context, departmentID uint) bool
+	CheckIsAncestor(userDepartmentID uint, operateDepartmentID uint) (bool, error)
+	ExistsDepartmentByID(departmentID uint) (bool, error)
+	ExistsDepartmentSub(departmentName string, entityID uint, departmentID uint) (bool, error)
+	CheckDepartmentInEntity(entityID uint, departmentID uint) (bool, error)
+	CreateDepartment(name string, entityID uint, departmentID uint) error
+	GetDepartmentInfoByID(departmentID uint) (*model.Department, error)
+	GetSubDepartments(departmentID uint) ([]*model.Department, error)
+	GetAllUsers(departmentID uint) ([]*model.User, error)
+	CreateDepartmentUser(req define.CreateDepartmentUserReq, entityID uint, departmentID uint) error
+	SetDepartmentManager(username string, departmentID uint) error
+	DeleteDepartmentManager(userID uint) error
+	GetDepartmentManagerList(id uint) ([]*model.User, error)
+	DeleteDepartment(id uint) error
+	DepartmentHasUsers(departmentID uint) (bool, error)
+	GetSubDepartmentTreeNodes(parentID uint, entityID uint) ([]*define.DepartmentTreeNodeInfo, error)
+	GetSubUsers(departmentID uint) (userList []*model.User, err error)
+	ModifyDepartmentTemplate(departmentID uint, req *define.DepartmentTemplateReq) error
+	ModifyDepartmentThreshold(departmentID uint, threshold uint) error
+	GetSubDepartmentIDs(departmentID uint) ([]uint, error)
+}
 
-func newDepartmentService() *departmentService {
-	return &departmentService{}
+type departmentService struct {
+	departmentDao dao.DepartmentDaoInterface
+	entityDao     dao.EntityDaoInterface
+	userDao       dao.UserDaoInterface
+	entityService EntityServiceInterface
+	userService   UserServiceInterface
 }
 
-func init() {
-	DepartmentService = newDepartmentService()
+func NewDepartmentService(
+	departmentDao dao.DepartmentDaoInterface,
+	entityDao dao.EntityDaoInterface,
+	userDao dao.UserDaoInterface,
+	entityService EntityServiceInterface,
+	userService UserServiceInterface,
+) DepartmentServiceInterface {
+	return &departmentService{
+		departmentDao: departmentDao,
+		entityDao:     entityDao,
+		userDao:       userDao,
+		entityService: entityService,
+		userService:   userService,
+	}
 }
 
 func (department *departmentService) GetHeaderDepartmentID(ctx *utils.Context) uint {
@@ -63,7 +99,7 @@ func (department *departmentService) CheckIsAncestor(userDepartmentID uint, oper
 			flag = true
 			break
 		}
-		operateDepartment, err = dao.DepartmentDao.GetDepartmentByID(operateDepartment.ParentID)
+		operateDepartment, err = department.departmentDao.GetDepartmentByID(operateDepartment.ParentID)
 		if err != nil {
 			return false, err
 		}
@@ -72,7 +108,7 @@ func (department *departmentService) CheckIsAncestor(userDepartmentID uint, oper
 }
 
 func (department *departmentService) ExistsDepartmentByID(departmentID uint) (bool, error) {
-	thisDepartment, err := dao.DepartmentDao.GetDepartmentByID(departmentID)
+	thisDepartment, err := department.departmentDao.GetDepartmentByID(departmentID)
 	if err != nil {
 		return false, err
 	} else if thisDepartment == nil {
@@ -82,7 +118,7 @@ func (department *departmentService) ExistsDepartmentByID(departmentID uint) (bo
 }
 
 func (department *departmentService) ExistsDepartmentSub(departmentName string, entityID uint, departmentID uint) (bool, error) {
-	thisDepartment, err := dao.DepartmentDao.GetDepartmentSub(departmentName, entityID, departmentID)
+	thisDepartment, err := department.departmentDao.GetDepartmentSub(departmentName, entityID, departmentID)
 	if err != nil {
 		return false, err
 	} else if thisDepartment == nil {
@@ -92,44 +128,23 @@ func (department *departmentService) ExistsDepartmentSub(departmentName string,
 }
 
 func (department *departmentService) CheckDepartmentInEntity(entityID uint, departmentID uint) (bool, error) {
-	thisDepartment, err := dao.DepartmentDao.GetDepartmentByID(departmentID)
+	thisDepartment, err := department.departmentDao.GetDepartmentByID(departmentID)
 	if err != nil {
 		return false, err
 	}
 	return thisDepartment.EntityID == entityID, nil
 }
 
-/*
-拥有部门权限，也即可以看到本部门及下属部门的信息
-*/
-func (department *departmentService) CheckDepartmentIdentity(ctx *utils.Context, entityID uint, departmentID uint) (bool, error) {
-	// 不在实体中必定没有权限
-	if !EntityService.CheckIsInEntity(ctx, entityID) {
-		return false, nil
-	}
-	// 实体的系统管理员必然有权限
-	if UserService.EntitySuper(ctx) {
-		return true, nil
-	}
-	// 是资产管理员，有对下属部门的权限
-	if UserService.DepartmentSuper(ctx) {
-		return department.CheckIsAncestor(department.GetHeaderDepartmentID(ctx), departmentID)
-	} else {
-		// 普通员工只有对自己所在部门的权限
-		return department.CheckIsInDepartment(ctx, departmentID), nil
-	}
-}
-
 func (department *departmentService) CreateDepartment(name string, entityID uint, departmentID uint) error {
 	var err error
 	if departmentID != 0 {
-		err = dao.DepartmentDao.Create(model.Department{
+		err = department.departmentDao.Create(model.Department{
 			Name:     name,
 			EntityID: entityID,
 			ParentID: departmentID,
 		})
 	} else {
-		err = dao.DepartmentDao.Create(model.Department{
+		err = department.departmentDao.Create(model.Department{
 			Name:     name,
 			EntityID: entityID,
 		})
@@ -138,7 +153,7 @@ func (department *departmentService) CreateDepartment(name string, entityID uint
 }
 
 func (department *departmentService) GetDepartmentInfoByID(departmentID uint) (*model.Department, error) {
-	thisDepartment, err := dao.DepartmentDao.GetDepartmentByID(departmentID)
+	thisDepartment, err := department.departmentDao.GetDepartmentByID(departmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -146,20 +161,23 @@ func (department *departmentService) GetDepartmentInfoByID(departmentID uint) (*
 }
 
 func (department *departmentService) GetSubDepartments(departmentID uint) ([]*model.Department, error) {
-	departmentList, err := dao.DepartmentDao.GetSubDepartmentByID(departmentID)
+	departmentList, err := department.departmentDao.GetSubDepartmentByID(departmentID)
 	return departmentList, err
 }
 
 func (department *departmentService) GetAllUsers(departmentID uint) ([]*model.User, error) {
-	userList, err := dao.DepartmentDao.GetDepartmentAllUserByID(departmentID)
+	userList, err := department.departmentDao.GetDepartmentAllUserByID(departmentID)
 	return userList, err
 }
 
 func (department *departmentService) CreateDepartmentUser(req define.CreateDepartmentUserReq, entityID uint, departmentID uint) error {
-	req.Password = utils.CreateMD5(req.Password)
-	err := dao.UserDao.Create(model.User{
+	password, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+	err = department.userDao.Create(model.User{
 		UserName:        req.UserName,
-		Password:        req.Password,
+		Password:        password,
 		DepartmentSuper: req.DepartmentSuper,
 		EntityID:        entityID,
 		DepartmentID:    departmentID,
@@ -168,7 +186,7 @@ func (department *departmentService) CreateDepartmentUser(req define.CreateDepar
 }
 
 func (department *departmentService) SetDepartmentManager(username string, departmentID uint) error {
-	err := dao.UserDao.UpdateByName(username, map[string]interface{}{
+	err := department.userDao.UpdateByName(username, map[string]interface{}{
 		"department_id":    departmentID,
 		"department_super": true,
 	})
@@ -176,23 +194,23 @@ func (department *departmentService) SetDepartmentManager(username string, depar
 }
 
 func (department *departmentService) DeleteDepartmentManager(userID uint) error {
-	err := dao.UserDao.Update(userID, map[string]interface{}{
+	err := department.userDao.Update(userID, map[string]interface{}{
 		"department_super": false,
 	})
 	return err
 }
 
 func (department *departmentService) GetDepartmentManagerList(id uint) ([]*model.User, error) {
-	managerList, err := dao.DepartmentDao.GetDepartmentManager(id)
+	managerList, err := department.departmentDao.GetDepartmentManager(id)
 	return managerList, err
 }
 
 func (department *departmentService) DeleteDepartment(id uint) error {
-	return dao.DepartmentDao.Delete([]uint{id})
+	return department.departmentDao.Delete([]uint{id})
 }
 
 func (department *departmentService) DepartmentHasUsers(departmentID uint) (bool, error) {
-	hasUsers, err := dao.DepartmentDao.GetDepartmentAllUserByID(departmentID)
+	hasUsers, err := department.departmentDao.GetDepartmentAllUserByID(departmentID)
 	if err != nil {
 		return true, err
 	}
@@ -203,9 +221,9 @@ func (department *departmentService) GetSubDepartmentTreeNodes(parentID uint, en
 	var subDepartments []*model.Department
 	var err error
 	if parentID == 0 {
-		subDepartments, err = dao.EntityDao.GetEntitySubDepartmentByID(entityID)
+		subDepartments, err = department.entityDao.GetEntitySubDepartmentByID(entityID)
 	} else {
-		subDepartments, err = dao.DepartmentDao.GetSubDepartmentByID(parentID)
+		subDepartments, err = department.departmentDao.GetSubDepartmentByID(parentID)
 	}
 	if err != nil {
 		return nil, err
@@ -228,12 +246,12 @@ func (department *departmentService) GetSubDepartmentTreeNodes(parentID uint, en
 }
 
 func (department *departmentService) GetSubUsers(departmentID uint) (userList []*model.User, err error) {
-	userList, err = dao.DepartmentDao.GetDepartmentDirectUserByID(departmentID)
+	userList, err = department.departmentDao.GetDepartmentDirectUserByID(departmentID)
 	return
 }
 
 func (department *departmentService) ModifyDepartmentTemplate(departmentID uint, req *define.DepartmentTemplateReq) error {
-	err := dao.DepartmentDao.Update(departmentID, map[string]interface{}{
+	err := department.departmentDao.Update(departmentID, map[string]interface{}{
 		"key_list": req.KeyList,
 	})
 
@@ -241,7 +259,7 @@ func (department *departmentService) ModifyDepartmentTemplate(departmentID uint,
 }
 
 func (department *departmentService) ModifyDepartmentThreshold(departmentID uint, threshold uint) error {
-	err := dao.DepartmentDao.Update(departmentID, map[string]interface{}{
+	err := department.departmentDao.Update(departmentID, map[string]interface{}{
 		"threshold": threshold,
 	})
 
@@ -251,7 +269,7 @@ func (department *departmentService) ModifyDepartmentThreshold(departmentID uint
 func (department *departmentService) GetSubDepartmentIDs(departmentID uint) ([]uint, error) {
 	subIDs := []uint{departmentID}
 
-	subDepartments, err := dao.DepartmentDao.GetSubDepartmentByID(departmentID)
+	subDepartments, err := department.departmentDao.GetSubDepartmentByID(departmentID)
 	if err != nil {
 		return []uint{}, err
 	}
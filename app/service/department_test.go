@@ -69,6 +69,5 @@ func TestDepartment(t *testing.T) {
 	DepartmentService.CheckIsAncestor(1, 2)
 	DepartmentService.ExistsDepartmentByID(1)
 	DepartmentService.ExistsDepartmentSub("sub_department", 1, 1)
-	DepartmentService.CheckDepartmentIdentity(&context, 1, 1)
 	DepartmentService.GetSubDepartmentTreeNodes(1, 1)
 }
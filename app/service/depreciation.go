@@ -0,0 +1,200 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrDepreciation = errors.New("depreciation is invalid")
+
+/*
+Net worth of an asset bought at price after day of its life days, ending at residual.
+Periods are days, the unit of model.Asset.Expire
+*/
+type DepreciationFunc func(price decimal.Decimal, residual decimal.Decimal, life int, day int) decimal.Decimal
+
+/*
+Every depreciation method a class or an asset can choose, anything missing here is refused
+*/
+var depreciationMethods = map[string]DepreciationFunc{
+	define.DEPRECIATION_STRAIGHT_LINE:     straightLine,
+	define.DEPRECIATION_DECLINING_BALANCE: decliningBalance(decliningFactors[define.DEPRECIATION_DECLINING_BALANCE]),
+	define.DEPRECIATION_DOUBLE_DECLINING:  decliningBalance(decliningFactors[define.DEPRECIATION_DOUBLE_DECLINING]),
+	define.DEPRECIATION_SUM_OF_YEARS:      sumOfYearsDigits,
+}
+
+/*
+Methods that walk the life day by day, a schedule carries the worth from one point to the next
+*/
+var decliningFactors = map[string]float64{
+	define.DEPRECIATION_DECLINING_BALANCE: 1.5,
+	define.DEPRECIATION_DOUBLE_DECLINING:  2,
+}
+
+func straightLine(price decimal.Decimal, residual decimal.Decimal, life int, day int) decimal.Decimal {
+	return price.Sub(price.Sub(residual).Mul(decimal.NewFromInt(int64(day))).Div(decimal.NewFromInt(int64(life))))
+}
+
+/*
+A fixed share factor/life of the remaining worth each day, switching to straight line
+once that writes off more, so the residual is reached at the end of the life
+*/
+func decliningBalance(factor float64) DepreciationFunc {
+	return func(price decimal.Decimal, residual decimal.Decimal, life int, day int) decimal.Decimal {
+		return decimal.NewFromFloat(decliningWorth(factor, price.InexactFloat64(), residual.InexactFloat64(), life, 0, day))
+	}
+}
+
+/*
+Walk worth, as it is on day from, on to day to
+*/
+func decliningWorth(factor float64, worth float64, floor float64, life int, from int, to int) float64 {
+	rate := factor / float64(life)
+	for past := from; past < to; past++ {
+		charge := worth * rate
+		if straight := (worth - floor) / float64(life-past); straight > charge {
+			charge = straight
+		}
+		worth = math.Max(worth-charge, floor)
+	}
+	return worth
+}
+
+/*
+Day n of the life writes off (life-n+1) parts of the life's digit sum
+*/
+func sumOfYearsDigits(price decimal.Decimal, residual decimal.Decimal, life int, day int) decimal.Decimal {
+	digits := int64(day)*int64(life) - int64(day)*int64(day-1)/2
+	total := int64(life) * int64(life+1) / 2
+	return price.Sub(price.Sub(residual).Mul(decimal.NewFromInt(digits)).Div(decimal.NewFromInt(total)))
+}
+
+/*
+Check a method and residual rate a class or an asset is given, an empty method is allowed
+*/
+func CheckDepreciation(method string, residualRate decimal.Decimal) error {
+	if _, ok := depreciationMethods[method]; method != "" && !ok {
+		return fmt.Errorf("%w: unknown method %q", ErrDepreciation, method)
+	}
+	if residualRate.IsNegative() || residualRate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return fmt.Errorf("%w: residual rate %s is not within [0, 1)", ErrDepreciation, residualRate)
+	}
+	// the column keeps 4 decimal places
+	if !residualRate.Equal(residualRate.Round(4)) {
+		return fmt.Errorf("%w: residual rate %s has more than 4 decimal places", ErrDepreciation, residualRate)
+	}
+	return nil
+}
+
+/*
+The method and residual rate the asset depreciates by, its own before its class's.
+Needs model.Asset.Class loaded
+*/
+func AssetDepreciation(thisAsset *model.Asset) (string, decimal.Decimal) {
+	method := thisAsset.DepreciationMethod
+	if method == "" {
+		method = thisAsset.Class.DepreciationMethod
+	}
+	if method == "" {
+		method = define.DEPRECIATION_STRAIGHT_LINE
+	}
+	residualRate := thisAsset.Class.ResidualRate
+	if thisAsset.ResidualRate.Valid {
+		residualRate = thisAsset.ResidualRate.Decimal
+	}
+	return method, residualRate
+}
+
+/*
+Net worth of the asset day days after it was created, by the given method
+*/
+func DepreciatedNetWorth(thisAsset *model.Asset, method string, residualRate decimal.Decimal, day int) decimal.Decimal {
+	residual := thisAsset.Price.Mul(residualRate).Round(2)
+	if day <= 0 {
+		return thisAsset.Price
+	} else if day >= int(thisAsset.Expire) {
+		return residual
+	}
+	depreciate, ok := depreciationMethods[method]
+	if !ok {
+		depreciate = straightLine
+	}
+	return depreciate(thisAsset.Price, residual, int(thisAsset.Expire), day).Round(2)
+}
+
+/*
+Replace the asset's own depreciation, recorded as a version like any other edit
+*/
+func (asset *assetService) ModifyAssetDepreciation(assetID uint, req define.AssetDepreciationReq, actorID uint) error {
+	return asset.editAsset(assetID, actorID, func(tx *dao.Daos) error {
+		return tx.Asset.Update(assetID, map[string]interface{}{
+			"depreciation_method": req.DepreciationMethod,
+			"residual_rate":       req.ResidualRate,
+		})
+	})
+}
+
+/*
+The net worth the asset is projected to have every step days of its life, by method,
+or by its own depreciation if method is empty
+*/
+func (asset *assetService) PreviewDepreciation(assetID uint, method string, step int) (*define.AssetDepreciationResponse, error) {
+	thisAsset, err := asset.assetDao.GetAssetByID(assetID)
+	if err != nil || thisAsset == nil {
+		return nil, err
+	}
+	assetMethod, residualRate := AssetDepreciation(thisAsset)
+	if method == "" {
+		method = assetMethod
+	}
+	if step <= 0 {
+		step = define.DEPRECIATION_PREVIEW_STEP
+	}
+	life := int(thisAsset.Expire)
+	if minStep := (life + define.DEPRECIATION_PREVIEW_MAX_POINTS - 1) / define.DEPRECIATION_PREVIEW_MAX_POINTS; step < minStep {
+		step = minStep
+	}
+	start := time.Now()
+	if thisAsset.CreatedAt != nil {
+		start = time.Time(*thisAsset.CreatedAt)
+	}
+
+	preview := &define.AssetDepreciationResponse{
+		DepreciationMethod: method,
+		ResidualRate:       residualRate,
+		ResidualValue:      thisAsset.Price.Mul(residualRate).Round(2),
+		Expire:             thisAsset.Expire,
+		Schedule:           []*define.DepreciationPoint{},
+	}
+	factor, declining := decliningFactors[method]
+	worth, floor := thisAsset.Price.InexactFloat64(), preview.ResidualValue.InexactFloat64()
+	walked := 0
+	for day := 0; ; day += step {
+		if day > life {
+			day = life
+		}
+		var netWorth decimal.Decimal
+		if declining && day > 0 && day < life {
+			worth, walked = decliningWorth(factor, worth, floor, life, walked, day), day
+			netWorth = decimal.NewFromFloat(worth).Round(2)
+		} else {
+			netWorth = DepreciatedNetWorth(thisAsset, method, residualRate, day)
+		}
+		preview.Schedule = append(preview.Schedule, &define.DepreciationPoint{
+			Day:      day,
+			Date:     model.ModelTime(start.AddDate(0, 0, day)),
+			NetWorth: netWorth,
+		})
+		if day == life {
+			break
+		}
+	}
+	return preview, nil
+}
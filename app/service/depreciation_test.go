@@ -0,0 +1,156 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestDepreciationMethods(t *testing.T) {
+	thisAsset := &model.Asset{Price: decimal.NewFromInt(1000), Expire: 100}
+	rate := decimal.NewFromFloat(0.1)
+	worthAt := func(method string, day int) string {
+		return DepreciatedNetWorth(thisAsset, method, rate, day).String()
+	}
+
+	// every method starts at the price and ends at the residual value
+	for method := range depreciationMethods {
+		assert.Equal(t, "1000", worthAt(method, 0), method)
+		assert.Equal(t, "100", worthAt(method, 100), method)
+		assert.Equal(t, "100", worthAt(method, 120), method)
+	}
+	assert.Equal(t, "550", worthAt(define.DEPRECIATION_STRAIGHT_LINE, 50), "service error")
+	// (100 + 99 + ... + 51) / 5050 of 900 is gone after half the life
+	assert.Equal(t, "327.23", worthAt(define.DEPRECIATION_SUM_OF_YEARS, 50), "service error")
+	assert.Equal(t, "980", worthAt(define.DEPRECIATION_DOUBLE_DECLINING, 1), "service error")
+	assert.Equal(t, "985", worthAt(define.DEPRECIATION_DECLINING_BALANCE, 1), "service error")
+
+	// accelerated methods write off more early on and never fall below the residual
+	straight := DepreciatedNetWorth(thisAsset, define.DEPRECIATION_STRAIGHT_LINE, rate, 30)
+	previous := thisAsset.Price
+	for day := 1; day <= 100; day++ {
+		worth := DepreciatedNetWorth(thisAsset, define.DEPRECIATION_DOUBLE_DECLINING, rate, day)
+		assert.Equal(t, true, worth.LessThanOrEqual(previous), "service error")
+		assert.Equal(t, true, worth.GreaterThanOrEqual(decimal.NewFromInt(100)), "service error")
+		previous = worth
+	}
+	assert.Equal(t, true, DepreciatedNetWorth(thisAsset, define.DEPRECIATION_DOUBLE_DECLINING, rate, 30).LessThan(straight), "service error")
+
+	assert.ErrorIs(t, CheckDepreciation("units_of_production", decimal.Zero), ErrDepreciation, "service error")
+	assert.ErrorIs(t, CheckDepreciation("", decimal.NewFromInt(1)), ErrDepreciation, "service error")
+	assert.Equal(t, nil, CheckDepreciation(define.DEPRECIATION_SUM_OF_YEARS, rate), "service error")
+	assert.ErrorIs(t, CheckDepreciation("", decimal.RequireFromString("0.05001")), ErrDepreciation, "service error")
+	assert.Equal(t, nil, CheckDepreciation("", decimal.RequireFromString("0.05010")), "service error")
+}
+
+func TestAssetDepreciation(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	assetService := NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos)
+
+	err := daos.Department.Create(model.Department{Name: "depreciation_department"})
+	assert.Equal(t, nil, err, "service error")
+	department, _ := daos.Department.GetDepartmentByName("depreciation_department")
+	err = daos.AssetClass.Create(model.AssetClass{
+		Name:               "depreciation_class",
+		DepartmentID:       department.ID,
+		DepreciationMethod: define.DEPRECIATION_SUM_OF_YEARS,
+		ResidualRate:       decimal.NewFromFloat(0.05),
+	})
+	assert.Equal(t, nil, err, "service error")
+	classList, err := daos.AssetClass.GetDepartmentDirectClass(department.ID)
+	assert.Equal(t, nil, err, "service error")
+	assetID, err := daos.Asset.CreateAndGetID(model.Asset{
+		Name:     "depreciation_asset",
+		ClassID:  classList[0].ID,
+		Price:    decimal.NewFromInt(2000),
+		NetWorth: decimal.NewFromInt(2000),
+		Expire:   365,
+		Property: datatypes.JSON([]byte(`{}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+
+	// the class decides until the asset overrides it
+	preview, err := assetService.PreviewDepreciation(assetID, "", 100)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.DEPRECIATION_SUM_OF_YEARS, preview.DepreciationMethod, "service error")
+	assert.Equal(t, "100", preview.ResidualValue.String(), "service error")
+	assert.Equal(t, []int{0, 100, 200, 300, 365}, []int{
+		preview.Schedule[0].Day, preview.Schedule[1].Day, preview.Schedule[2].Day,
+		preview.Schedule[3].Day, preview.Schedule[4].Day,
+	}, "service error")
+	assert.Equal(t, "100", preview.Schedule[4].NetWorth.String(), "service error")
+
+	// a declining schedule is walked once and matches the worth of each day
+	preview, err = assetService.PreviewDepreciation(assetID, define.DEPRECIATION_DOUBLE_DECLINING, 1)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 366, len(preview.Schedule), "service error")
+	thisAsset, _ := daos.Asset.GetAssetByID(assetID)
+	for _, point := range preview.Schedule {
+		worth := DepreciatedNetWorth(thisAsset, define.DEPRECIATION_DOUBLE_DECLINING, decimal.NewFromFloat(0.05), point.Day)
+		assert.Equal(t, worth.String(), point.NetWorth.String(), "service error")
+	}
+
+	// a long life gets a longer step instead of more points
+	longID, err := daos.Asset.CreateAndGetID(model.Asset{
+		Name:     "depreciation_long",
+		ClassID:  classList[0].ID,
+		Price:    decimal.NewFromInt(2000),
+		NetWorth: decimal.NewFromInt(2000),
+		Expire:   1000000,
+		Property: datatypes.JSON([]byte(`{}`)),
+	})
+	assert.Equal(t, nil, err, "service error")
+	preview, err = assetService.PreviewDepreciation(longID, define.DEPRECIATION_DOUBLE_DECLINING, 1)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.DEPRECIATION_PREVIEW_MAX_POINTS+1, len(preview.Schedule), "service error")
+	assert.Equal(t, 2000, preview.Schedule[1].Day, "service error")
+	assert.Equal(t, "100", preview.Schedule[define.DEPRECIATION_PREVIEW_MAX_POINTS].NetWorth.String(), "service error")
+
+	err = assetService.ModifyAssetDepreciation(assetID, define.AssetDepreciationReq{
+		DepreciationMethod: define.DEPRECIATION_STRAIGHT_LINE,
+		ResidualRate:       decimal.NewNullDecimal(decimal.Zero),
+	}, 0)
+	assert.Equal(t, nil, err, "service error")
+	preview, err = assetService.PreviewDepreciation(assetID, "", 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.DEPRECIATION_STRAIGHT_LINE, preview.DepreciationMethod, "service error")
+	assert.Equal(t, "0", preview.ResidualValue.String(), "service error")
+	assert.Equal(t, define.DEPRECIATION_PREVIEW_STEP, preview.Schedule[1].Day, "service error")
+
+	// the override is a version, reverting it follows the class again
+	versions, _ := assetService.GetAssetVersions(assetID)
+	assert.Equal(t, 1, len(versions), "service error")
+	err = assetService.RevertAsset(assetID, 0, 0)
+	assert.Equal(t, nil, err, "service error")
+	thisAsset, _ = daos.Asset.GetAssetByID(assetID)
+	assert.Equal(t, "", thisAsset.DepreciationMethod, "service error")
+	assert.Equal(t, false, thisAsset.ResidualRate.Valid, "service error")
+
+	// a class change reaches the net worth of its assets right away
+	err = daos.Asset.Update(assetID, map[string]interface{}{"created_at": time.Now().AddDate(0, 0, -100)})
+	assert.Equal(t, nil, err, "service error")
+	err = daos.AssetClass.Update(classList[0].ID, map[string]interface{}{
+		"depreciation_method": define.DEPRECIATION_STRAIGHT_LINE,
+		"residual_rate":       decimal.NewFromFloat(0.1),
+	})
+	assert.Equal(t, nil, err, "service error")
+	err = assetService.UpdateClassNetWorth(classList[0].ID)
+	assert.Equal(t, nil, err, "service error")
+	thisAsset, _ = daos.Asset.GetAssetByID(assetID)
+	assert.Equal(t, "1506.85", thisAsset.NetWorth.String(), "service error")
+
+	// expiring keeps the residual, 10% of the price
+	err = daos.Asset.Update(assetID, map[string]interface{}{"created_at": time.Now().AddDate(0, 0, -400)})
+	assert.Equal(t, nil, err, "service error")
+	err = assetService.UpdateNetWorth(assetID)
+	assert.Equal(t, nil, err, "service error")
+	thisAsset, _ = daos.Asset.GetAssetByID(assetID)
+	assert.Equal(t, define.ASSET_RETIRED, thisAsset.State, "service error")
+	assert.Equal(t, "200", thisAsset.NetWorth.String(), "service error")
+}
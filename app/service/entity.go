@@ -9,16 +9,42 @@ import (
 	"strconv"
 )
 
-type entityService struct{}
-
-var EntityService *entityService
-
-func newEntityService() *entityService {
-	return &entityService{}
-}
-
-func init() {
-	EntityService = newEntityService()
+type EntityServiceInterface interface {
+	CheckIsInEntity(ctx *utils.Context, entityID uint) bool
+	GetParamID(ctx *utils.Context, key string) (uint, error)
+	CreateEntity(name string) error
+	DeleteEntity(id uint) error
+	GetAllEntity() ([]model.Entity, error)
+	ExistsEntityByName(name string) (bool, error)
+	ExistsEntityByID(id uint) (bool, error)
+	GetEntityInfoByID(id uint) (*model.Entity, error)
+	GetUsersUnderEntity(id uint, page_size uint, page_num uint) ([]*model.User, int64, error)
+	GetAllDepartmentsUnderEntity(id uint) ([]*model.Department, error)
+	CreateManager(name string, password string, entityID uint) error
+	SetManager(name string) error
+	GetEntityManagerList(id uint) ([]*model.User, error)
+	DeleteManager(userID uint) error
+	ModifyEntity(entityID uint, modifyInfo define.ModifyEntityInfoReq) error
+	EntityHasUser(entityID uint) (bool, error)
+	GetEntitySubDepartments(entityID uint) ([]*model.Department, error)
+}
+
+type entityService struct {
+	departmentDao dao.DepartmentDaoInterface
+	entityDao     dao.EntityDaoInterface
+	userDao       dao.UserDaoInterface
+}
+
+func NewEntityService(
+	departmentDao dao.DepartmentDaoInterface,
+	entityDao dao.EntityDaoInterface,
+	userDao dao.UserDaoInterface,
+) EntityServiceInterface {
+	return &entityService{
+		departmentDao: departmentDao,
+		entityDao:     entityDao,
+		userDao:       userDao,
+	}
 }
 
 func (entity *entityService) CheckIsInEntity(ctx *utils.Context, entityID uint) bool {
@@ -45,19 +71,19 @@ func (entity *entityService) GetParamID(ctx *utils.Context, key string) (uint, e
 }
 
 func (entity *entityService) CreateEntity(name string) error {
-	err := dao.EntityDao.Create(model.Entity{
+	err := entity.entityDao.Create(model.Entity{
 		Name: name,
 	})
 	return err
 }
 
 func (entity *entityService) DeleteEntity(id uint) error {
-	err := dao.EntityDao.Delete([]uint{id})
+	err := entity.entityDao.Delete([]uint{id})
 	return err
 }
 
 func (entity *entityService) GetAllEntity() ([]model.Entity, error) {
-	entityList, err := dao.EntityDao.AllEntity()
+	entityList, err := entity.entityDao.AllEntity()
 	if err != nil {
 		return []model.Entity{}, err
 	}
@@ -65,7 +91,7 @@ func (entity *entityService) GetAllEntity() ([]model.Entity, error) {
 }
 
 func (entity *entityService) ExistsEntityByName(name string) (bool, error) {
-	thisEntity, err := dao.EntityDao.GetEntityByName(name)
+	thisEntity, err := entity.entityDao.GetEntityByName(name)
 	if err != nil {
 		return false, err
 	} else if thisEntity == nil {
@@ -76,7 +102,7 @@ func (entity *entityService) ExistsEntityByName(name string) (bool, error) {
 }
 
 func (entity *entityService) ExistsEntityByID(id uint) (bool, error) {
-	thisEntity, err := dao.EntityDao.GetEntityByID(id)
+	thisEntity, err := entity.entityDao.GetEntityByID(id)
 	if err != nil {
 		return false, err
 	} else if thisEntity == nil {
@@ -87,7 +113,7 @@ func (entity *entityService) ExistsEntityByID(id uint) (bool, error) {
 }
 
 func (entity *entityService) GetEntityInfoByID(id uint) (*model.Entity, error) {
-	thisEntity, err := dao.EntityDao.GetEntityByID(id)
+	thisEntity, err := entity.entityDao.GetEntityByID(id)
 	if err != nil {
 		return nil, err
 	} else if thisEntity == nil {
@@ -100,22 +126,25 @@ func (entity *entityService) GetEntityInfoByID(id uint) (*model.Entity, error) {
 func (entity *entityService) GetUsersUnderEntity(id uint, page_size uint, page_num uint) ([]*model.User, int64, error) {
 	offset := page_size * page_num
 	limit := page_size
-	userList, count, err := dao.EntityDao.GetEntityAllUser(id, int(offset), int(limit))
+	userList, count, err := entity.entityDao.GetEntityAllUser(id, int(offset), int(limit))
 	return userList, count, err
 }
 
 func (entity *entityService) GetAllDepartmentsUnderEntity(id uint) ([]*model.Department, error) {
-	departmentList, err := dao.EntityDao.GetEntityAllDepartment(id)
+	departmentList, err := entity.entityDao.GetEntityAllDepartment(id)
 	return departmentList, err
 }
 
 func (entity *entityService) CreateManager(name string, password string, entityID uint) error {
-	password = utils.CreateMD5(password)
-	thisEntity, err := dao.EntityDao.GetEntityByID(entityID)
+	password, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	thisEntity, err := entity.entityDao.GetEntityByID(entityID)
 	if err != nil {
 		return err
 	}
-	err = dao.UserDao.Create(model.User{
+	err = entity.userDao.Create(model.User{
 		UserName:    name,
 		Password:    password,
 		EntityID:    entityID,
@@ -126,19 +155,19 @@ func (entity *entityService) CreateManager(name string, password string, entityI
 }
 
 func (entity *entityService) SetManager(name string) error {
-	err := dao.UserDao.UpdateByName(name, map[string]interface{}{
+	err := entity.userDao.UpdateByName(name, map[string]interface{}{
 		"entity_super": true,
 	})
 	return err
 }
 
 func (entity *entityService) GetEntityManagerList(id uint) ([]*model.User, error) {
-	managerList, err := dao.EntityDao.GetEntityManager(id)
+	managerList, err := entity.entityDao.GetEntityManager(id)
 	return managerList, err
 }
 
 func (entity *entityService) DeleteManager(userID uint) error {
-	err := dao.UserDao.Update(userID, map[string]interface{}{
+	err := entity.userDao.Update(userID, map[string]interface{}{
 		"entity_super": false,
 	})
 	return err
@@ -146,18 +175,18 @@ func (entity *entityService) DeleteManager(userID uint) error {
 
 func (entity *entityService) ModifyEntity(entityID uint, modifyInfo define.ModifyEntityInfoReq) error {
 	if modifyInfo.EntityName != nil && modifyInfo.Description != nil {
-		err := dao.EntityDao.Update(entityID, map[string]interface{}{
+		err := entity.entityDao.Update(entityID, map[string]interface{}{
 			"name":        *modifyInfo.EntityName,
 			"description": *modifyInfo.Description,
 		})
 		return err
 	} else if modifyInfo.EntityName != nil {
-		err := dao.EntityDao.Update(entityID, map[string]interface{}{
+		err := entity.entityDao.Update(entityID, map[string]interface{}{
 			"name": *modifyInfo.EntityName,
 		})
 		return err
 	} else if modifyInfo.Description != nil {
-		err := dao.EntityDao.Update(entityID, map[string]interface{}{
+		err := entity.entityDao.Update(entityID, map[string]interface{}{
 			"description": *modifyInfo.Description,
 		})
 		return err
@@ -166,7 +195,7 @@ func (entity *entityService) ModifyEntity(entityID uint, modifyInfo define.Modif
 }
 
 func (entity *entityService) EntityHasUser(entityID uint) (bool, error) {
-	userList, _, err := dao.EntityDao.GetEntityAllUser(entityID, -1, -1)
+	userList, _, err := entity.entityDao.GetEntityAllUser(entityID, -1, -1)
 	if err != nil {
 		return true, err
 	}
@@ -174,7 +203,7 @@ func (entity *entityService) EntityHasUser(entityID uint) (bool, error) {
 }
 
 func (entity *entityService) GetEntitySubDepartments(entityID uint) ([]*model.Department, error) {
-	departmentList, err := dao.DepartmentDao.GetDepartmentsSubByID(entityID, 0)
+	departmentList, err := entity.departmentDao.GetDepartmentsSubByID(entityID, 0)
 	if err != nil {
 		return nil, err
 	}
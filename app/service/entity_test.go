@@ -10,7 +10,9 @@ import (
 func TestEntity(t *testing.T) {
 	InitForTest()
 
-	err := EntityService.CreateEntity("test_entity222")
+	err := EntityService.CreateEntity("test_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = EntityService.CreateEntity("test_entity222")
 	assert.Equal(t, nil, err, "service error")
 
 	entities, err := EntityService.GetAllEntity()
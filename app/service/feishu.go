@@ -3,13 +3,16 @@ package service
 import (
 	"asset-management/app/dao"
 	"asset-management/app/model"
+	"asset-management/app/oidc"
+	"asset-management/config"
+	"asset-management/utils"
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
 	larkapproval "github.com/larksuite/oapi-sdk-go/v3/service/approval/v4"
@@ -18,26 +21,64 @@ import (
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
 
-type feishuService struct{}
-
-var FeishuService *feishuService
+type FeishuServiceInterface interface {
+	CallbackToken() string
+	AuthorizeURL(redirectURI string, state string) string
+	GetAccessToken(code string) (res *larkext.AuthenAccessTokenResp, err error)
+	GetUserInfo(token string) (res *larkext.AuthenUserInfoResp, err error)
+	RefreshToken(token string) (res *larkext.RefreshAuthenAccessTokenResp, err error)
+	FindUserByFeishuID(FeishuID string) (user *model.User, err error)
+	StoreToken(UserID uint, AccessToken string, RefreshToken string) error
+	BindFeishu(UserID uint, FeishuID string) error
+	GetTokenByUserID(UserID uint) (access_token string, refresh_token string, err error)
+	TextTokenAndRefresh(UserID uint) (bool, error)
+	SendMessage(UserId uint, text string) error
+	CreateApprovalDefination() (approval_code string, err error)
+	PutApproval(task model.Task, FeishuID string, approval_code string) error
+	GetSubDEpartments(PageToken string) (departments []*string, err error)
+	GetDepartmentUser(DepartmentID string, PageToken string) (feishuIDs []*larkcontact.User, err error)
+	GetAllUsers() (feishuIDs []*larkcontact.User, err error)
+	CheckUserAndBind(FeishuUser *larkcontact.User, EntityID uint) (bool, error)
+	FeishuSync(EntityID uint) error
+}
 
-var appId = "cli_a4d26de640e4500c"
+type feishuService struct {
+	conf          config.FeishuConfig
+	client        *lark.Client
+	departmentDao dao.DepartmentDaoInterface
+	userDao       dao.UserDaoInterface
+}
 
-var appSecret = "qTGl1gT9HReTRxZAxAwAjewGlxeyZTfr"
+func NewFeishuService(conf config.FeishuConfig, departmentDao dao.DepartmentDaoInterface, userDao dao.UserDaoInterface) FeishuServiceInterface {
+	return &feishuService{
+		conf:          conf,
+		client:        lark.NewClient(conf.AppID, conf.AppSecret, lark.WithEnableTokenCache(true)),
+		departmentDao: departmentDao,
+		userDao:       userDao,
+	}
+}
 
-var Client = lark.NewClient(appId, appSecret, lark.WithEnableTokenCache(true))
+func (feishu *feishuService) CallbackToken() string {
+	return feishu.conf.CallbackToken
+}
 
-func newFeishuService() *feishuService {
-	return &feishuService{}
+/*
+Feishu's web login page, it redirects back with the code and the state
+*/
+func (feishu *feishuService) AuthorizeURL(redirectURI string, state string) string {
+	query := url.Values{}
+	query.Set("app_id", feishu.conf.AppID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	return "https://open.feishu.cn/open-apis/authen/v1/index?" + query.Encode()
 }
 
-func init() {
-	FeishuService = newFeishuService()
+func (feishu *feishuService) assetListLink() string {
+	return feishu.conf.FrontendURL + "/#/asset/list"
 }
 
 func (feishu *feishuService) GetAccessToken(code string) (res *larkext.AuthenAccessTokenResp, err error) {
-	res, err = Client.Ext.Authen.AuthenAccessToken(context.Background(),
+	res, err = feishu.client.Ext.Authen.AuthenAccessToken(context.Background(),
 		larkext.NewAuthenAccessTokenReqBuilder().
 			Body(larkext.NewAuthenAccessTokenReqBodyBuilder().
 				GrantType(larkext.GrantTypeAuthorizationCode).
@@ -54,7 +95,7 @@ func (feishu *feishuService) GetAccessToken(code string) (res *larkext.AuthenAcc
 }
 
 func (feishu *feishuService) GetUserInfo(token string) (res *larkext.AuthenUserInfoResp, err error) {
-	res, err = Client.Ext.Authen.AuthenUserInfo(context.Background(),
+	res, err = feishu.client.Ext.Authen.AuthenUserInfo(context.Background(),
 		larkcore.WithUserAccessToken(token))
 	if err != nil {
 		return
@@ -66,7 +107,7 @@ func (feishu *feishuService) GetUserInfo(token string) (res *larkext.AuthenUserI
 }
 
 func (feishu *feishuService) RefreshToken(token string) (res *larkext.RefreshAuthenAccessTokenResp, err error) {
-	res, err = Client.Ext.Authen.RefreshAuthenAccessToken(context.Background(),
+	res, err = feishu.client.Ext.Authen.RefreshAuthenAccessToken(context.Background(),
 		larkext.NewRefreshAuthenAccessTokenReqBuilder().
 			Body(larkext.NewRefreshAuthenAccessTokenReqBodyBuilder().
 				GrantType(larkext.GrantTypeRefreshCode).
@@ -81,7 +122,7 @@ func (feishu *feishuService) RefreshToken(token string) (res *larkext.RefreshAut
 }
 
 func (feishu *feishuService) FindUserByFeishuID(FeishuID string) (user *model.User, err error) {
-	user, err = dao.UserDao.GetUserByFeishuID(FeishuID)
+	user, err = feishu.userDao.GetUserByFeishuID(FeishuID)
 	return
 }
 
@@ -101,15 +142,15 @@ func (feishu *feishuService) FindUserByFeishuID(FeishuID string) (user *model.Us
 }*/
 
 func (feishu *feishuService) StoreToken(UserID uint, AccessToken string, RefreshToken string) error {
-	return dao.UserDao.UpdateFeishuToken(UserID, AccessToken, RefreshToken)
+	return feishu.userDao.UpdateFeishuToken(UserID, AccessToken, RefreshToken)
 }
 
 func (feishu *feishuService) BindFeishu(UserID uint, FeishuID string) error {
-	return dao.UserDao.BindFeishu(UserID, FeishuID)
+	return feishu.userDao.BindFeishu(UserID, FeishuID)
 }
 
 func (feishu *feishuService) GetTokenByUserID(UserID uint) (access_token string, refresh_token string, err error) {
-	user, err := dao.UserDao.GetUserByID(UserID)
+	user, err := feishu.userDao.GetUserByID(UserID)
 	if err != nil {
 		return
 	}
@@ -139,7 +180,7 @@ func (feishu *feishuService) TextTokenAndRefresh(UserID uint) (bool, error) {
 }
 
 func (feishu *feishuService) SendMessage(UserId uint, text string) error {
-	user, err := dao.UserDao.GetUserByID(UserId)
+	user, err := feishu.userDao.GetUserByID(UserId)
 	if err != nil {
 		return err
 	}
@@ -154,7 +195,7 @@ func (feishu *feishuService) SendMessage(UserId uint, text string) error {
 			Build()).
 		Build()
 
-	resp, err := Client.Im.Message.Create(context.Background(), req)
+	resp, err := feishu.client.Im.Message.Create(context.Background(), req)
 	if err != nil {
 		// log.Println(err.Error())
 		return err
@@ -173,13 +214,7 @@ func (feishu *feishuService) SendMessage(UserId uint, text string) error {
 
 // 审批相关
 func (feishu *feishuService) CreateApprovalDefination() (approval_code string, err error) {
-	var CallBackUrl string
-	if gin.Mode() == gin.DebugMode {
-		CallBackUrl = "http://AssetManagement-Backend-dev-BinaryAbstract.app.secoder.net/user/feishu/callback"
-		// CallBackUrl = "http://49.233.51.221:8080/user/feishu/callback"
-	} else {
-		CallBackUrl = "http://AssetManagement-Backend-BinaryAbstract.app.secoder.net/user/feishu/callback"
-	}
+	CallBackUrl := feishu.conf.CallbackURL
 	req := larkapproval.NewCreateExternalApprovalReqBuilder().
 		DepartmentIdType(`open_department_id`).
 		UserIdType("user_id").
@@ -189,15 +224,15 @@ func (feishu *feishuService) CreateApprovalDefination() (approval_code string, e
 			GroupCode(`ApprovalRequest`).
 			GroupName(`@i18n@2`).
 			External(larkapproval.NewApprovalCreateExternalBuilder().
-				CreateLinkMobile(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
-				CreateLinkPc(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
+				CreateLinkMobile(feishu.assetListLink()).
+				CreateLinkPc(feishu.assetListLink()).
 				SupportPc(true).
 				SupportMobile(true).
 				SupportBatchRead(false).
 				EnableMarkReaded(false).
 				EnableQuickOperate(true).
 				ActionCallbackUrl(CallBackUrl). //记得改
-				ActionCallbackToken(`sdjkljkx9lsadf110`).
+				ActionCallbackToken(feishu.conf.CallbackToken).
 				Build()).
 			Viewers([]*larkapproval.ApprovalCreateViewers{
 				larkapproval.NewApprovalCreateViewersBuilder().
@@ -226,7 +261,7 @@ func (feishu *feishuService) CreateApprovalDefination() (approval_code string, e
 
 	// 发起请求
 	// 如开启了SDK的Token管理功能，就无需在请求时调用larkcore.WithTenantAccessToken("-xxx")来手动设置租户Token了
-	resp, err := Client.Approval.ExternalApproval.Create(context.Background(), req)
+	resp, err := feishu.client.Approval.ExternalApproval.Create(context.Background(), req)
 
 	// 处理错误
 	if err != nil {
@@ -251,7 +286,7 @@ func (feishu *feishuService) PutApproval(task model.Task, FeishuID string, appro
 		3: `REJECTED`,
 	}
 
-	managers, err := dao.DepartmentDao.GetDepartmentManager(task.DepartmentID)
+	managers, err := feishu.departmentDao.GetDepartmentManager(task.DepartmentID)
 	if err != nil {
 		return err
 	}
@@ -263,8 +298,8 @@ func (feishu *feishuService) PutApproval(task model.Task, FeishuID string, appro
 				UserId(manager.FeishuID).
 				Title(task.TaskDescription).
 				Links(larkapproval.NewExternalInstanceLinkBuilder().
-					PcLink(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
-					MobileLink(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
+					PcLink(feishu.assetListLink()).
+					MobileLink(feishu.assetListLink()).
 					Build()).
 				Status(StateMap[task.State]).
 				Extra(``).
@@ -299,8 +334,8 @@ func (feishu *feishuService) PutApproval(task model.Task, FeishuID string, appro
 			Extra(``).
 			InstanceId(strconv.FormatInt(int64(task.ID), 10)).
 			Links(larkapproval.NewExternalInstanceLinkBuilder().
-				PcLink(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
-				MobileLink(`http://assetmanagement-frontend-binaryabstract.app.secoder.net/#/asset/list`).
+				PcLink(feishu.assetListLink()).
+				MobileLink(feishu.assetListLink()).
 				Build()).
 			Form([]*larkapproval.ExternalInstanceForm{
 				larkapproval.NewExternalInstanceFormBuilder().
@@ -368,7 +403,7 @@ func (feishu *feishuService) PutApproval(task model.Task, FeishuID string, appro
 
 	// 发起请求
 	// 如开启了SDK的Token管理功能，就无需在请求时调用larkcore.WithTenantAccessToken("-xxx")来手动设置租户Token了
-	resp, err := Client.Approval.ExternalInstance.Create(context.Background(), req)
+	resp, err := feishu.client.Approval.ExternalInstance.Create(context.Background(), req)
 
 	// 处理错误
 	if err != nil {
@@ -393,7 +428,7 @@ func (feishu *feishuService) GetSubDEpartments(PageToken string) (departments []
 
 	// 发起请求
 	// 如开启了SDK的Token管理功能，就无需在请求时调用larkcore.WithTenantAccessToken("-xxx")来手动设置租户Token了
-	resp, err := Client.Contact.Department.Children(context.Background(), req)
+	resp, err := feishu.client.Contact.Department.Children(context.Background(), req)
 
 	// 处理错误
 	if err != nil {
@@ -433,7 +468,7 @@ func (feishu *feishuService) GetDepartmentUser(DepartmentID string, PageToken st
 
 	// 发起请求
 	// 如开启了SDK的Token管理功能，就无需在请求时调用larkcore.WithTenantAccessToken("-xxx")来手动设置租户Token了
-	resp, err := Client.Contact.User.FindByDepartment(context.Background(), req, larkcore.WithTenantAccessToken("t-g1045beaMUXCE5AKFM5T2GNNVOM5BDZESUIMVTZT"))
+	resp, err := feishu.client.Contact.User.FindByDepartment(context.Background(), req, larkcore.WithTenantAccessToken("t-g1045beaMUXCE5AKFM5T2GNNVOM5BDZESUIMVTZT"))
 
 	// 处理错误
 	if err != nil {
@@ -481,7 +516,7 @@ func (feishu *feishuService) GetAllUsers() (feishuIDs []*larkcontact.User, err e
 }
 
 func (feishu *feishuService) CheckUserAndBind(FeishuUser *larkcontact.User, EntityID uint) (bool, error) {
-	user, err := dao.UserDao.GetUserByFeishuID(*FeishuUser.UserId)
+	user, err := feishu.userDao.GetUserByFeishuID(*FeishuUser.UserId)
 	if err != nil {
 		return false, err
 	}
@@ -489,13 +524,22 @@ func (feishu *feishuService) CheckUserAndBind(FeishuUser *larkcontact.User, Enti
 		return false, err
 	}
 
+	// synced users log in through feishu until a password is set for them
+	random, err := oidc.RandomString()
+	if err != nil {
+		return false, err
+	}
+	password, err := utils.HashPassword(random)
+	if err != nil {
+		return false, err
+	}
 	new_user := model.User{
 		UserName: *FeishuUser.Name,
-		Password: *FeishuUser.Name,
+		Password: password,
 		EntityID: EntityID,
 		FeishuID: *FeishuUser.UserId,
 	}
-	err = dao.UserDao.Create(new_user)
+	err = feishu.userDao.Create(new_user)
 
 	return true, err
 }
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/oidc"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"gorm.io/datatypes"
+)
+
+const AUTHORIZATION_STATE_EXPIRE = 10 * time.Minute
+
+type IdentityServiceInterface interface {
+	GetEntityProviders(entityID uint) ([]*model.IdentityProvider, error)
+	GetLoginProviders(entityID uint) ([]*model.IdentityProvider, error)
+	GetProviderByID(id uint) (*model.IdentityProvider, error)
+	ExistsProviderName(entityID uint, name string) (bool, error)
+	CreateProvider(entityID uint, req define.IdentityProviderReq) (*model.IdentityProvider, error)
+	ModifyProvider(id uint, req define.IdentityProviderReq) error
+	DeleteProvider(id uint) error
+	Provider(setting *model.IdentityProvider) IdentityProvider
+	FeishuProvider() IdentityProvider
+	StartAuthorization(setting *model.IdentityProvider, purpose string, userID uint, redirectURI string) (*define.AuthorizeResponse, error)
+	TakeAuthorizationState(setting *model.IdentityProvider, purpose string, userID uint, state string) (*model.AuthorizationState, error)
+	GetUserBindings(thisUser *model.User) ([]define.IdentityBindingInfo, error)
+}
+
+/*
+A discovered client stays valid while the settings it was built from are unchanged
+*/
+type cachedClient struct {
+	settings string
+	client   *oidc.Client
+}
+
+type identityService struct {
+	identityDao   dao.IdentityDaoInterface
+	userDao       dao.UserDaoInterface
+	feishuService FeishuServiceInterface
+	mu            sync.Mutex
+	clients       map[uint]cachedClient
+	now           func() time.Time
+}
+
+func NewIdentityService(identityDao dao.IdentityDaoInterface, userDao dao.UserDaoInterface, feishuService FeishuServiceInterface) IdentityServiceInterface {
+	return &identityService{
+		identityDao:   identityDao,
+		userDao:       userDao,
+		feishuService: feishuService,
+		clients:       map[uint]cachedClient{},
+		now:           time.Now,
+	}
+}
+
+func hashAuthorizationState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+OIDC always needs the openid scope, whatever else the entity asks for
+*/
+func providerScopes(scopes []string) datatypes.JSONSlice[string] {
+	ret := datatypes.JSONSlice[string]{"openid"}
+	for _, scope := range scopes {
+		if scope != "" && !funk.ContainsString(ret, scope) {
+			ret = append(ret, scope)
+		}
+	}
+	return ret
+}
+
+func (identity *identityService) GetEntityProviders(entityID uint) ([]*model.IdentityProvider, error) {
+	return identity.identityDao.GetEntityProviders(entityID)
+}
+
+/*
+Enabled providers of one entity, or of every entity when entityID is 0
+*/
+func (identity *identityService) GetLoginProviders(entityID uint) ([]*model.IdentityProvider, error) {
+	return identity.identityDao.GetEnabledProviders(entityID)
+}
+
+func (identity *identityService) GetProviderByID(id uint) (*model.IdentityProvider, error) {
+	return identity.identityDao.GetProviderByID(id)
+}
+
+func (identity *identityService) ExistsProviderName(entityID uint, name string) (bool, error) {
+	thisProvider, err := identity.identityDao.GetProviderByName(entityID, name)
+	return thisProvider != nil, err
+}
+
+func (identity *identityService) CreateProvider(entityID uint, req define.IdentityProviderReq) (*model.IdentityProvider, error) {
+	newProvider := &model.IdentityProvider{
+		EntityID:     entityID,
+		Name:         req.Name,
+		Kind:         req.Kind,
+		Issuer:       strings.TrimRight(req.Issuer, "/"),
+		ClientID:     req.ClientID,
+		ClientSecret: req.ClientSecret,
+		Scopes:       providerScopes(req.Scopes),
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	err := identity.identityDao.CreateProvider(newProvider)
+	if err != nil {
+		return nil, err
+	}
+	if !newProvider.Enabled {
+		// gorm skips a false default on create
+		err = identity.identityDao.UpdateProvider(newProvider.ID, map[string]interface{}{"enabled": false})
+	}
+	return newProvider, err
+}
+
+func (identity *identityService) ModifyProvider(id uint, req define.IdentityProviderReq) error {
+	data := map[string]interface{}{
+		"name":      req.Name,
+		"kind":      req.Kind,
+		"issuer":    strings.TrimRight(req.Issuer, "/"),
+		"client_id": req.ClientID,
+		"scopes":    providerScopes(req.Scopes),
+	}
+	if req.ClientSecret != "" {
+		data["client_secret"] = req.ClientSecret
+	}
+	if req.Enabled != nil {
+		data["enabled"] = *req.Enabled
+	}
+	return identity.identityDao.UpdateProvider(id, data)
+}
+
+func (identity *identityService) DeleteProvider(id uint) error {
+	identity.mu.Lock()
+	delete(identity.clients, id)
+	identity.mu.Unlock()
+	return identity.identityDao.DeleteProvider(id)
+}
+
+func (identity *identityService) oidcClient(setting *model.IdentityProvider) (*oidc.Client, error) {
+	settings := strings.Join(append([]string{setting.Issuer, setting.ClientID, setting.ClientSecret}, setting.Scopes...), "\n")
+	identity.mu.Lock()
+	cached, ok := identity.clients[setting.ID]
+	identity.mu.Unlock()
+	if ok && cached.settings == settings {
+		return cached.client, nil
+	}
+
+	client, err := oidc.Discover(oidc.Config{
+		Issuer:       setting.Issuer,
+		ClientID:     setting.ClientID,
+		ClientSecret: setting.ClientSecret,
+		Scopes:       setting.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	identity.mu.Lock()
+	identity.clients[setting.ID] = cachedClient{settings: settings, client: client}
+	identity.mu.Unlock()
+	return client, nil
+}
+
+/*
+The provider behind a setting, an OIDC provider is discovered on first use
+*/
+func (identity *identityService) Provider(setting *model.IdentityProvider) IdentityProvider {
+	if setting.Kind == model.IDENTITY_PROVIDER_FEISHU {
+		return identity.FeishuProvider()
+	}
+	return &oidcProvider{
+		providerID: setting.ID,
+		discover: func() (*oidc.Client, error) {
+			return identity.oidcClient(setting)
+		},
+		identityDao: identity.identityDao,
+		userDao:     identity.userDao,
+	}
+}
+
+/*
+The Feishu application from the config, also used by the Feishu login and bind endpoints
+*/
+func (identity *identityService) FeishuProvider() IdentityProvider {
+	return &feishuProvider{feishuService: identity.feishuService}
+}
+
+/*
+Remember the PKCE verifier and nonce of a new authorization and build the URL to send the browser to
+*/
+func (identity *identityService) StartAuthorization(setting *model.IdentityProvider, purpose string, userID uint, redirectURI string) (*define.AuthorizeResponse, error) {
+	var state, verifier, nonce string
+	var err error
+	for _, value := range []*string{&state, &verifier, &nonce} {
+		if *value, err = oidc.RandomString(); err != nil {
+			return nil, err
+		}
+	}
+	authURL, err := identity.Provider(setting).AuthCodeURL(redirectURI, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	now := identity.now()
+	if err := identity.identityDao.DeleteExpiredStates(now); err != nil {
+		return nil, err
+	}
+	err = identity.identityDao.CreateState(&model.AuthorizationState{
+		StateHash:    hashAuthorizationState(state),
+		ProviderID:   setting.ID,
+		Purpose:      purpose,
+		UserID:       userID,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		RedirectURI:  redirectURI,
+		ExpiresAt:    now.Add(AUTHORIZATION_STATE_EXPIRE),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &define.AuthorizeResponse{
+		URL:   authURL,
+		State: state,
+	}, nil
+}
+
+/*
+The authorization the state was issued for, nil when the state is unknown, used,
+expired, or was issued for another provider, purpose or user
+*/
+func (identity *identityService) TakeAuthorizationState(setting *model.IdentityProvider, purpose string, userID uint, state string) (*model.AuthorizationState, error) {
+	thisState, err := identity.identityDao.TakeState(hashAuthorizationState(state))
+	if err != nil || thisState == nil {
+		return nil, err
+	}
+	if thisState.ProviderID != setting.ID || thisState.Purpose != purpose || thisState.UserID != userID ||
+		identity.now().After(thisState.ExpiresAt) {
+		return nil, nil
+	}
+	return thisState, nil
+}
+
+/*
+OIDC bindings of the user, and the Feishu binding under the Feishu provider of their entity if it has one
+*/
+func (identity *identityService) GetUserBindings(thisUser *model.User) ([]define.IdentityBindingInfo, error) {
+	bindings, err := identity.identityDao.GetUserBindings(thisUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	ret := []define.IdentityBindingInfo{}
+	for _, binding := range bindings {
+		ret = append(ret, define.IdentityBindingInfo{
+			ProviderID:   binding.ProviderID,
+			ProviderName: binding.Provider.Name,
+			Kind:         binding.Provider.Kind,
+			Subject:      binding.Subject,
+			Email:        binding.Email,
+			CreatedAt:    binding.CreatedAt,
+		})
+	}
+	if thisUser.FeishuID == "" {
+		return ret, nil
+	}
+
+	feishuBinding := define.IdentityBindingInfo{
+		ProviderName: model.IDENTITY_PROVIDER_FEISHU,
+		Kind:         model.IDENTITY_PROVIDER_FEISHU,
+		Subject:      thisUser.FeishuID,
+	}
+	providers, err := identity.identityDao.GetEntityProviders(thisUser.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	for _, thisProvider := range providers {
+		if thisProvider.Kind == model.IDENTITY_PROVIDER_FEISHU {
+			feishuBinding.ProviderID = thisProvider.ID
+			feishuBinding.ProviderName = thisProvider.Name
+			break
+		}
+	}
+	return append(ret, feishuBinding), nil
+}
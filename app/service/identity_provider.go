@@ -0,0 +1,155 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/oidc"
+	"errors"
+)
+
+/*
+An external login provider: it runs the authorization code flow and keeps
+the link between its subjects and our users
+*/
+type IdentityProvider interface {
+	AuthCodeURL(redirectURI string, state string, nonce string, challenge string) (string, error)
+	Exchange(code string, redirectURI string, nonce string, verifier string) (*define.ExternalIdentity, error)
+	// nil when the identity is not bound to any user
+	Login(identity *define.ExternalIdentity) (*model.User, error)
+	// false when the identity is already bound to another user
+	Bind(userID uint, identity *define.ExternalIdentity) (bool, error)
+	Unbind(userID uint) error
+}
+
+/*
+A standard OpenID provider, bindings are kept per provider setting.
+Discovery only happens once the flow needs the provider's endpoints
+*/
+type oidcProvider struct {
+	providerID  uint
+	discover    func() (*oidc.Client, error)
+	identityDao dao.IdentityDaoInterface
+	userDao     dao.UserDaoInterface
+}
+
+func (provider *oidcProvider) AuthCodeURL(redirectURI string, state string, nonce string, challenge string) (string, error) {
+	client, err := provider.discover()
+	if err != nil {
+		return "", err
+	}
+	return client.AuthCodeURL(redirectURI, state, nonce, challenge), nil
+}
+
+func (provider *oidcProvider) Exchange(code string, redirectURI string, nonce string, verifier string) (*define.ExternalIdentity, error) {
+	client, err := provider.discover()
+	if err != nil {
+		return nil, err
+	}
+	token, err := client.Exchange(code, redirectURI, verifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := client.VerifyIDToken(token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &define.ExternalIdentity{
+		Subject:      idToken.Subject,
+		Email:        idToken.Email,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+func (provider *oidcProvider) Login(identity *define.ExternalIdentity) (*model.User, error) {
+	binding, err := provider.identityDao.GetBindingBySubject(provider.providerID, identity.Subject)
+	if err != nil || binding == nil {
+		return nil, err
+	}
+	return provider.userDao.GetUserByID(binding.UserID)
+}
+
+func (provider *oidcProvider) Bind(userID uint, identity *define.ExternalIdentity) (bool, error) {
+	binding, err := provider.identityDao.GetBindingBySubject(provider.providerID, identity.Subject)
+	if err != nil {
+		return false, err
+	} else if binding != nil {
+		return binding.UserID == userID, nil
+	}
+	// binding again replaces the subject the user had at this provider
+	err = provider.identityDao.DeleteBinding(provider.providerID, userID)
+	if err != nil {
+		return false, err
+	}
+	err = provider.identityDao.CreateBinding(&model.IdentityBinding{
+		UserID:     userID,
+		ProviderID: provider.providerID,
+		Subject:    identity.Subject,
+		Email:      identity.Email,
+	})
+	return err == nil, err
+}
+
+func (provider *oidcProvider) Unbind(userID uint) error {
+	return provider.identityDao.DeleteBinding(provider.providerID, userID)
+}
+
+/*
+Feishu through its own authen API: it knows no PKCE or nonce, and the binding
+is the user's feishu_id so that approvals and messages keep reaching them
+*/
+type feishuProvider struct {
+	feishuService FeishuServiceInterface
+}
+
+func (provider *feishuProvider) AuthCodeURL(redirectURI string, state string, nonce string, challenge string) (string, error) {
+	return provider.feishuService.AuthorizeURL(redirectURI, state), nil
+}
+
+func (provider *feishuProvider) Exchange(code string, redirectURI string, nonce string, verifier string) (*define.ExternalIdentity, error) {
+	tokenRes, err := provider.feishuService.GetAccessToken(code)
+	if err != nil {
+		return nil, err
+	}
+	infoRes, err := provider.feishuService.GetUserInfo(tokenRes.Data.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if infoRes.Data.UserID == "" {
+		return nil, errors.New("feishu user info has no user id")
+	}
+	return &define.ExternalIdentity{
+		Subject:      infoRes.Data.UserID,
+		Email:        infoRes.Data.Email,
+		AccessToken:  tokenRes.Data.AccessToken,
+		RefreshToken: tokenRes.Data.RefreshToken,
+	}, nil
+}
+
+func (provider *feishuProvider) Login(identity *define.ExternalIdentity) (*model.User, error) {
+	thisUser, err := provider.feishuService.FindUserByFeishuID(identity.Subject)
+	if err != nil || thisUser == nil {
+		return nil, err
+	}
+	return thisUser, provider.feishuService.StoreToken(thisUser.ID, identity.AccessToken, identity.RefreshToken)
+}
+
+func (provider *feishuProvider) Bind(userID uint, identity *define.ExternalIdentity) (bool, error) {
+	existUser, err := provider.feishuService.FindUserByFeishuID(identity.Subject)
+	if err != nil {
+		return false, err
+	} else if existUser != nil && existUser.ID != userID {
+		return false, nil
+	}
+	err = provider.feishuService.BindFeishu(userID, identity.Subject)
+	if err != nil {
+		return false, err
+	}
+	err = provider.feishuService.StoreToken(userID, identity.AccessToken, identity.RefreshToken)
+	return err == nil, err
+}
+
+func (provider *feishuProvider) Unbind(userID uint) error {
+	return provider.feishuService.BindFeishu(userID, "")
+}
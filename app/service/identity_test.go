@@ -0,0 +1,111 @@
+package service
+
+import (
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/oidc/oidctest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityProvider(t *testing.T) {
+	InitForTest()
+
+	idp := oidctest.NewServer("asset-client", "asset-secret")
+	defer idp.Close()
+
+	err := UserService.CreateUser("identity_user", "123456")
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := UserService.GetUserByName("identity_user")
+	assert.Equal(t, nil, err, "service error")
+	err = UserService.CreateUser("identity_other", "123456")
+	assert.Equal(t, nil, err, "service error")
+	otherUser, err := UserService.GetUserByName("identity_other")
+	assert.Equal(t, nil, err, "service error")
+
+	setting, err := IdentityService.CreateProvider(thisUser.EntityID, define.IdentityProviderReq{
+		Name:         "mock",
+		Kind:         model.IDENTITY_PROVIDER_OIDC,
+		Issuer:       idp.URL + "/",
+		ClientID:     "asset-client",
+		ClientSecret: "asset-secret",
+		Scopes:       []string{"email"},
+	})
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, setting.Enabled, "service error")
+	assert.Equal(t, []string{"openid", "email"}, []string(setting.Scopes), "service error")
+	exists, err := IdentityService.ExistsProviderName(thisUser.EntityID, "mock")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, exists, "service error")
+
+	redirectURI := "http://localhost/oauth/callback"
+	authorize := func(purpose string, userID uint) (string, string) {
+		res, err := IdentityService.StartAuthorization(setting, purpose, userID, redirectURI)
+		assert.Equal(t, nil, err, "service error")
+		authURL, err := url.Parse(res.URL)
+		assert.Equal(t, nil, err, "service error")
+		assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"), "service error")
+		code, state, err := oidctest.Authorize(res.URL)
+		assert.Equal(t, nil, err, "service error")
+		assert.Equal(t, res.State, state, "service error")
+		return code, state
+	}
+	exchange := func(purpose string, userID uint, code string, state string) *define.ExternalIdentity {
+		thisState, err := IdentityService.TakeAuthorizationState(setting, purpose, userID, state)
+		assert.Equal(t, nil, err, "service error")
+		assert.NotNil(t, thisState, "service error")
+		identity, err := IdentityService.Provider(setting).Exchange(code, thisState.RedirectURI, thisState.Nonce, thisState.CodeVerifier)
+		assert.Equal(t, nil, err, "service error")
+		return identity
+	}
+
+	idp.Login("alice", "alice@example.com")
+	code, state := authorize(define.AUTH_PURPOSE_BIND, thisUser.ID)
+	// a state belongs to one purpose and user and is used once
+	thisState, err := IdentityService.TakeAuthorizationState(setting, define.AUTH_PURPOSE_LOGIN, 0, state)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, thisState, "service error")
+	code, state = authorize(define.AUTH_PURPOSE_BIND, thisUser.ID)
+	identity := exchange(define.AUTH_PURPOSE_BIND, thisUser.ID, code, state)
+	assert.Equal(t, "alice", identity.Subject, "service error")
+	thisState, err = IdentityService.TakeAuthorizationState(setting, define.AUTH_PURPOSE_BIND, thisUser.ID, state)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, thisState, "service error")
+
+	bound, err := IdentityService.Provider(setting).Bind(thisUser.ID, identity)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, bound, "service error")
+	bound, err = IdentityService.Provider(setting).Bind(otherUser.ID, identity)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, bound, "service error")
+	bindings, err := IdentityService.GetUserBindings(thisUser)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(bindings), "service error")
+	assert.Equal(t, "mock", bindings[0].ProviderName, "service error")
+	assert.Equal(t, "alice@example.com", bindings[0].Email, "service error")
+
+	code, state = authorize(define.AUTH_PURPOSE_LOGIN, 0)
+	loginUser, err := IdentityService.Provider(setting).Login(exchange(define.AUTH_PURPOSE_LOGIN, 0, code, state))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, thisUser.ID, loginUser.ID, "service error")
+
+	err = IdentityService.Provider(setting).Unbind(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	code, state = authorize(define.AUTH_PURPOSE_LOGIN, 0)
+	loginUser, err = IdentityService.Provider(setting).Login(exchange(define.AUTH_PURPOSE_LOGIN, 0, code, state))
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, loginUser, "service error")
+
+	// Feishu sits behind the same interface
+	feishuURL, err := IdentityService.FeishuProvider().AuthCodeURL(redirectURI, "feishu-state", "", "")
+	assert.Equal(t, nil, err, "service error")
+	assert.Contains(t, feishuURL, "state=feishu-state", "service error")
+
+	err = IdentityService.DeleteProvider(setting.ID)
+	assert.Equal(t, nil, err, "service error")
+	deleted, err := IdentityService.GetProviderByID(setting.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, deleted, "service error")
+}
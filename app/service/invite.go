@@ -0,0 +1,156 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+/*
+The invite is unknown, revoked, expired, used up, or its department or role is gone
+*/
+var ErrInviteInvalid = errors.New("invite is invalid")
+
+type InviteServiceInterface interface {
+	CreateInvite(creatorID uint, entityID uint, departmentID uint, req define.CreateInviteReq) (string, *model.Invite, error)
+	GetDepartmentInvites(departmentID uint) ([]*model.Invite, error)
+	GetInviteByID(id uint) (*model.Invite, error)
+	RevokeInvite(id uint) error
+	Register(plain string, username string, password string) error
+}
+
+type inviteService struct {
+	inviteDao       dao.InviteDaoInterface
+	uow             dao.UnitOfWork
+	passwordService PasswordServiceInterface
+	now             func() time.Time
+}
+
+func NewInviteService(inviteDao dao.InviteDaoInterface, uow dao.UnitOfWork, passwordService PasswordServiceInterface) InviteServiceInterface {
+	return &inviteService{
+		inviteDao:       inviteDao,
+		uow:             uow,
+		passwordService: passwordService,
+		now:             time.Now,
+	}
+}
+
+func newInviteToken() (token string, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	token = define.INVITE_TOKEN_PREFIX + hex.EncodeToString(buf)
+	hash = hashRefreshToken(token)
+	return
+}
+
+/*
+The plain token is returned once and only its hash is kept
+*/
+func (invite *inviteService) CreateInvite(creatorID uint, entityID uint, departmentID uint, req define.CreateInviteReq) (string, *model.Invite, error) {
+	plain, hash, err := newInviteToken()
+	if err != nil {
+		return "", nil, err
+	}
+	newInvite := &model.Invite{
+		EntityID:     entityID,
+		DepartmentID: departmentID,
+		RoleID:       req.RoleID,
+		CreatorID:    creatorID,
+		Prefix:       plain[:8],
+		TokenHash:    hash,
+		MaxUses:      req.MaxUses,
+		ExpiresAt:    invite.now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour),
+	}
+	if err := invite.inviteDao.Create(newInvite); err != nil {
+		return "", nil, err
+	}
+	return plain, newInvite, nil
+}
+
+func (invite *inviteService) GetDepartmentInvites(departmentID uint) ([]*model.Invite, error) {
+	return invite.inviteDao.GetDepartmentInvites(departmentID)
+}
+
+func (invite *inviteService) GetInviteByID(id uint) (*model.Invite, error) {
+	return invite.inviteDao.GetInviteByID(id)
+}
+
+func (invite *inviteService) RevokeInvite(id uint) error {
+	return invite.inviteDao.RevokeInvite(id)
+}
+
+/*
+Create the user in the invite's entity and department and grant its role on that
+department. The use is counted in the same transaction, so a failed registration
+does not use up the invite, ErrInviteInvalid when the invite cannot be used
+*/
+func (invite *inviteService) Register(plain string, username string, password string) error {
+	return invite.uow.Transaction(func(tx *dao.Daos) error {
+		thisInvite, err := tx.Invite.GetInviteByHash(hashRefreshToken(plain))
+		if err != nil {
+			return err
+		} else if thisInvite == nil {
+			return ErrInviteInvalid
+		}
+		// the password has to meet the policy of the entity the invite leads into
+		err = invite.passwordService.Validate(thisInvite.EntityID, password)
+		if err != nil {
+			return err
+		}
+		password, err = utils.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		used, err := tx.Invite.UseInvite(thisInvite.ID, invite.now())
+		if err != nil {
+			return err
+		} else if !used {
+			return ErrInviteInvalid
+		}
+
+		thisDepartment, err := tx.Department.GetDepartmentByID(thisInvite.DepartmentID)
+		if err != nil {
+			return err
+		} else if thisDepartment == nil || thisDepartment.EntityID != thisInvite.EntityID {
+			return ErrInviteInvalid
+		}
+		if thisInvite.RoleID != 0 {
+			thisRole, err := tx.Role.GetRoleByID(thisInvite.RoleID)
+			if err != nil {
+				return err
+			} else if thisRole == nil {
+				return ErrInviteInvalid
+			}
+		}
+
+		err = tx.User.Create(model.User{
+			UserName:     username,
+			Password:     password,
+			EntityID:     thisInvite.EntityID,
+			DepartmentID: thisInvite.DepartmentID,
+		})
+		if err != nil {
+			return err
+		}
+		if thisInvite.RoleID == 0 {
+			return nil
+		}
+		thisUser, err := tx.User.GetUserByName(username)
+		if err != nil {
+			return err
+		}
+		return tx.Role.CreateAssignment(&model.RoleAssignment{
+			UserID:       thisUser.ID,
+			RoleID:       thisInvite.RoleID,
+			EntityID:     thisInvite.EntityID,
+			DepartmentID: thisInvite.DepartmentID,
+		})
+	})
+}
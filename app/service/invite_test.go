@@ -0,0 +1,76 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/mail"
+	"asset-management/app/model"
+	"asset-management/config"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvite(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	invite := NewInviteService(daos.Invite, daos, NewPasswordService(daos.Password, daos.User, config.ForTest().Security, mail.NewSender(config.MailConfig{}), daos, nil)).(*inviteService)
+	permission := NewPermissionService(daos.Department, daos.Role)
+
+	err := daos.Entity.Create(model.Entity{Name: "invite_entity"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("invite_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "invite_department", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+	department, err := daos.Department.GetDepartmentByName("invite_department")
+	assert.Equal(t, nil, err, "service error")
+	role, err := permission.CreateRole(entity.ID, define.RoleReq{Name: "invite_approver", Permissions: []string{define.PERM_ASSET_APPROVE}})
+	assert.Equal(t, nil, err, "service error")
+
+	plain, newInvite, err := invite.CreateInvite(1, entity.ID, department.ID, define.CreateInviteReq{RoleID: role.ID, MaxUses: 1, ExpiresInDays: 7})
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, plain[:8], newInvite.Prefix, "service error")
+	assert.NotEqual(t, plain, newInvite.TokenHash, "service error")
+
+	// the user lands in the department with the role granted there
+	err = invite.Register(plain, "invite_user", "123456")
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := daos.User.GetUserByName("invite_user")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, entity.ID, thisUser.EntityID, "service error")
+	assert.Equal(t, department.ID, thisUser.DepartmentID, "service error")
+	can, err := permission.Can(define.UserBasicInfo{UserID: thisUser.ID, EntityID: entity.ID, DepartmentID: department.ID}, define.PERM_ASSET_APPROVE, 0, department.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+
+	// a single-use invite is used up, a failed registration does not use one up
+	err = invite.Register(plain, "invite_second", "123456")
+	assert.Equal(t, true, errors.Is(err, ErrInviteInvalid), "service error")
+	plain, newInvite, err = invite.CreateInvite(1, entity.ID, department.ID, define.CreateInviteReq{MaxUses: 2, ExpiresInDays: 1})
+	assert.Equal(t, nil, err, "service error")
+	err = invite.Register(plain, "invite_user", "123456")
+	assert.NotEqual(t, nil, err, "service error")
+	thisInvite, err := invite.GetInviteByID(newInvite.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, uint(0), thisInvite.Uses, "service error")
+
+	invite.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	err = invite.Register(plain, "invite_late", "123456")
+	assert.Equal(t, true, errors.Is(err, ErrInviteInvalid), "service error")
+	invite.now = time.Now
+	err = invite.RevokeInvite(newInvite.ID)
+	assert.Equal(t, nil, err, "service error")
+	err = invite.Register(plain, "invite_revoked", "123456")
+	assert.Equal(t, true, errors.Is(err, ErrInviteInvalid), "service error")
+	err = invite.Register("ami_unknown", "invite_unknown", "123456")
+	assert.Equal(t, true, errors.Is(err, ErrInviteInvalid), "service error")
+
+	inviteList, err := invite.GetDepartmentInvites(department.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 2, len(inviteList), "service error")
+	exists, err := daos.User.GetUserByName("invite_revoked")
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, exists, "service error")
+}
@@ -5,29 +5,29 @@ import (
 	"asset-management/app/model"
 )
 
-type logService struct {
+type LogServiceInterface interface {
+	GetLoginLog(entityID uint, page_size uint, page_num uint) (logList []*model.Log, count int64, err error)
+	GetDataLog(entityID uint, page_size uint, page_num uint) (logList []*model.Log, count int64, err error)
 }
 
-var LogService *logService
-
-func newLogService() *logService {
-	return &logService{}
+type logService struct {
+	logDao dao.LogDaoInterface
 }
 
-func init() {
-	LogService = newLogService()
+func NewLogService(logDao dao.LogDaoInterface) LogServiceInterface {
+	return &logService{logDao: logDao}
 }
 
 func (mylog *logService) GetLoginLog(entityID uint, page_size uint, page_num uint) (logList []*model.Log, count int64, err error) {
 	offset := page_size * page_num
 	limit := page_size
-	logList, count, err = dao.LogDao.GetLoginLogByEntityID(entityID, int(offset), int(limit))
+	logList, count, err = mylog.logDao.GetLoginLogByEntityID(entityID, int(offset), int(limit))
 	return
 }
 
 func (mylog *logService) GetDataLog(entityID uint, page_size uint, page_num uint) (logList []*model.Log, count int64, err error) {
 	offset := page_size * page_num
 	limit := page_size
-	logList, count, err = dao.LogDao.GetDataLogByEntityID(entityID, int(offset), int(limit))
+	logList, count, err = mylog.logDao.GetDataLogByEntityID(entityID, int(offset), int(limit))
 	return
 }
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/model"
+	"asset-management/config"
+	"time"
+)
+
+type LoginLockServiceInterface interface {
+	CheckLocked(username string, ip string) (*time.Time, error)
+	RecordFailure(username string, ip string) (*model.LoginLock, error)
+	RecordSuccess(username string) error
+	GetEntityLocks(entityID uint) ([]*model.LoginLock, error)
+	GetIPLocks() ([]*model.LoginLock, error)
+	GetLockByID(id uint) (*model.LoginLock, error)
+	ClearLock(id uint) error
+}
+
+type loginLockService struct {
+	loginLockDao dao.LoginLockDaoInterface
+	userDao      dao.UserDaoInterface
+	conf         config.SecurityConfig
+	now          func() time.Time
+}
+
+func NewLoginLockService(loginLockDao dao.LoginLockDaoInterface, userDao dao.UserDaoInterface, conf config.SecurityConfig) LoginLockServiceInterface {
+	return &loginLockService{
+		loginLockDao: loginLockDao,
+		userDao:      userDao,
+		conf:         conf,
+		now:          time.Now,
+	}
+}
+
+/*
+Lock duration for the n-th lockout, doubling from the base up to the max
+*/
+func (lock *loginLockService) backoff(lockouts uint) time.Duration {
+	duration := time.Duration(lock.conf.LoginLockoutSeconds) * time.Second
+	max := time.Duration(lock.conf.LoginLockoutMaxSeconds) * time.Second
+	for i := uint(1); i < lockouts && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		duration = max
+	}
+	return duration
+}
+
+func (lock *loginLockService) lockedUntil(kind string, key string, now time.Time) (*time.Time, error) {
+	thisLock, err := lock.loginLockDao.GetLockByKey(kind, key)
+	if err != nil || thisLock == nil {
+		return nil, err
+	}
+	if thisLock.LockedUntil == nil || !now.Before(*thisLock.LockedUntil) {
+		return nil, nil
+	}
+	return thisLock.LockedUntil, nil
+}
+
+/*
+The later of the username lock and the ip lock, nil when neither is locked
+*/
+func (lock *loginLockService) CheckLocked(username string, ip string) (*time.Time, error) {
+	now := lock.now()
+	userUntil, err := lock.lockedUntil(model.LOCK_BY_USERNAME, username, now)
+	if err != nil {
+		return nil, err
+	}
+	ipUntil, err := lock.lockedUntil(model.LOCK_BY_IP, ip, now)
+	if err != nil {
+		return nil, err
+	}
+	if userUntil == nil || (ipUntil != nil && ipUntil.After(*userUntil)) {
+		return ipUntil, nil
+	}
+	return userUntil, nil
+}
+
+/*
+Count one failure, return the lock when this failure locked the key
+*/
+func (lock *loginLockService) recordFailure(kind string, key string, entityID uint, now time.Time) (*model.LoginLock, error) {
+	thisLock, err := lock.loginLockDao.GetLockByKey(kind, key)
+	if err != nil {
+		return nil, err
+	}
+	isNew := thisLock == nil
+	if isNew {
+		thisLock = &model.LoginLock{Kind: kind, Key: key}
+	} else if now.Sub(thisLock.LastFailureAt) > time.Duration(lock.conf.LoginLockoutMaxSeconds)*time.Second {
+		// quiet for longer than the longest lock, start over
+		thisLock.Failures = 0
+		thisLock.Lockouts = 0
+	}
+	thisLock.EntityID = entityID
+	thisLock.Failures++
+	thisLock.LastFailureAt = now
+
+	locked := int64(thisLock.Failures) >= lock.conf.LoginMaxFailures
+	if locked {
+		thisLock.Lockouts++
+		until := now.Add(lock.backoff(thisLock.Lockouts))
+		thisLock.LockedUntil = &until
+		thisLock.Failures = 0
+	}
+
+	if isNew {
+		err = lock.loginLockDao.Create(thisLock)
+	} else {
+		err = lock.loginLockDao.Update(thisLock.ID, map[string]interface{}{
+			"entity_id":       thisLock.EntityID,
+			"failures":        thisLock.Failures,
+			"lockouts":        thisLock.Lockouts,
+			"locked_until":    thisLock.LockedUntil,
+			"last_failure_at": thisLock.LastFailureAt,
+		})
+	}
+	if err != nil || !locked {
+		return nil, err
+	}
+	return thisLock, nil
+}
+
+/*
+Both the username and the client ip are counted, the username lock
+carries the user's entity so its supers can see and clear it
+*/
+func (lock *loginLockService) RecordFailure(username string, ip string) (*model.LoginLock, error) {
+	now := lock.now()
+	var entityID uint
+	thisUser, err := lock.userDao.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+	if thisUser != nil {
+		entityID = thisUser.EntityID
+	}
+
+	userLock, err := lock.recordFailure(model.LOCK_BY_USERNAME, username, entityID, now)
+	if err != nil {
+		return nil, err
+	}
+	ipLock, err := lock.recordFailure(model.LOCK_BY_IP, ip, 0, now)
+	if err != nil {
+		return nil, err
+	}
+	if userLock != nil {
+		return userLock, nil
+	}
+	return ipLock, nil
+}
+
+/*
+Only the username is forgiven, one valid account must not reset the
+counter of an ip that is guessing other accounts
+*/
+func (lock *loginLockService) RecordSuccess(username string) error {
+	return lock.loginLockDao.DeleteByKey(model.LOCK_BY_USERNAME, username)
+}
+
+func (lock *loginLockService) GetEntityLocks(entityID uint) ([]*model.LoginLock, error) {
+	return lock.loginLockDao.GetEntityLocks(entityID)
+}
+
+/*
+Ip locks belong to no entity, only system supers see them
+*/
+func (lock *loginLockService) GetIPLocks() ([]*model.LoginLock, error) {
+	return lock.loginLockDao.GetIPLocks()
+}
+
+func (lock *loginLockService) GetLockByID(id uint) (*model.LoginLock, error) {
+	return lock.loginLockDao.GetLockByID(id)
+}
+
+func (lock *loginLockService) ClearLock(id uint) error {
+	return lock.loginLockDao.Delete(id)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/model"
+	"asset-management/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLock(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	conf := config.ForTest().Security
+	conf.LoginMaxFailures = 3
+	conf.LoginLockoutSeconds = 60
+	conf.LoginLockoutMaxSeconds = 150
+	now := time.Now()
+	lockService := &loginLockService{
+		loginLockDao: daos.LoginLock,
+		userDao:      daos.User,
+		conf:         conf,
+		now:          func() time.Time { return now },
+	}
+
+	err := daos.Entity.Create(model.Entity{Name: "lock"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("lock")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "locked", Password: "x", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+
+	for i := 0; i < 2; i++ {
+		newLock, err := lockService.RecordFailure("locked", "10.0.0.1")
+		assert.Equal(t, nil, err, "service error")
+		assert.Equal(t, (*model.LoginLock)(nil), newLock, "service error")
+	}
+	until, err := lockService.CheckLocked("locked", "10.0.0.1")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, (*time.Time)(nil), until, "service error")
+
+	newLock, err := lockService.RecordFailure("locked", "10.0.0.1")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, model.LOCK_BY_USERNAME, newLock.Kind, "service error")
+	assert.Equal(t, now.Add(60*time.Second), *newLock.LockedUntil, "service error")
+	until, err = lockService.CheckLocked("locked", "10.0.0.2")
+	assert.Equal(t, nil, err, "service error")
+	assert.NotEqual(t, (*time.Time)(nil), until, "service error")
+	until, err = lockService.CheckLocked("other", "10.0.0.1")
+	assert.Equal(t, nil, err, "service error")
+	assert.NotEqual(t, (*time.Time)(nil), until, "service error")
+
+	// second lockout doubles, third is capped
+	now = now.Add(61 * time.Second)
+	until, err = lockService.CheckLocked("locked", "10.0.0.2")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, (*time.Time)(nil), until, "service error")
+	for i := 0; i < 3; i++ {
+		newLock, err = lockService.RecordFailure("locked", "10.0.0.2")
+		assert.Equal(t, nil, err, "service error")
+	}
+	assert.Equal(t, now.Add(120*time.Second), *newLock.LockedUntil, "service error")
+	now = now.Add(121 * time.Second)
+	for i := 0; i < 3; i++ {
+		newLock, err = lockService.RecordFailure("locked", "10.0.0.3")
+		assert.Equal(t, nil, err, "service error")
+	}
+	assert.Equal(t, now.Add(150*time.Second), *newLock.LockedUntil, "service error")
+
+	lockList, err := lockService.GetEntityLocks(entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(lockList), "service error")
+	assert.Equal(t, uint(3), lockList[0].Lockouts, "service error")
+
+	err = lockService.ClearLock(lockList[0].ID)
+	assert.Equal(t, nil, err, "service error")
+	until, err = lockService.CheckLocked("locked", "10.0.0.4")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, (*time.Time)(nil), until, "service error")
+
+	// a success forgives the username but not the ip
+	_, err = lockService.RecordFailure("locked", "10.0.0.4")
+	assert.Equal(t, nil, err, "service error")
+	err = lockService.RecordSuccess("locked")
+	assert.Equal(t, nil, err, "service error")
+	lock, err := daos.LoginLock.GetLockByKey(model.LOCK_BY_USERNAME, "locked")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, (*model.LoginLock)(nil), lock, "service error")
+	lock, err = daos.LoginLock.GetLockByKey(model.LOCK_BY_IP, "10.0.0.4")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, uint(1), lock.Failures, "service error")
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/mail"
+	"asset-management/app/model"
+	"asset-management/config"
+	"asset-management/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jinzhu/copier"
+)
+
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+var ErrPasswordReused = errors.New("password has been used recently")
+var ErrResetInvalid = errors.New("password reset token is invalid")
+
+type PasswordServiceInterface interface {
+	GetPolicy(entityID uint) (*model.PasswordPolicy, error)
+	SetPolicy(entityID uint, req define.PasswordPolicyReq) error
+	Validate(entityID uint, password string) error
+	SetPassword(thisUser *model.User, password string, temporary bool) error
+	RequireChange(username string) error
+	ChangeRequired(thisUser *model.User) (bool, error)
+	RequestReset(username string, channel string, ip string) error
+	ResetPassword(token string, newPassword string) (*model.User, error)
+}
+
+type passwordService struct {
+	passwordDao   dao.PasswordDaoInterface
+	userDao       dao.UserDaoInterface
+	conf          config.SecurityConfig
+	sender        mail.Sender
+	uow           dao.UnitOfWork
+	feishuService FeishuServiceInterface
+	now           func() time.Time
+}
+
+func NewPasswordService(
+	passwordDao dao.PasswordDaoInterface,
+	userDao dao.UserDaoInterface,
+	conf config.SecurityConfig,
+	sender mail.Sender,
+	uow dao.UnitOfWork,
+	feishuService FeishuServiceInterface,
+) PasswordServiceInterface {
+	return &passwordService{
+		passwordDao:   passwordDao,
+		userDao:       userDao,
+		conf:          conf,
+		sender:        sender,
+		uow:           uow,
+		feishuService: feishuService,
+		now:           time.Now,
+	}
+}
+
+/*
+The entity's policy, an entity without one only gets the configured minimum length
+*/
+func (password *passwordService) GetPolicy(entityID uint) (*model.PasswordPolicy, error) {
+	policy, err := password.passwordDao.GetPolicy(entityID)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+	return &model.PasswordPolicy{
+		EntityID:  entityID,
+		MinLength: uint(password.conf.PasswordMinLength),
+	}, nil
+}
+
+func (password *passwordService) SetPolicy(entityID uint, req define.PasswordPolicyReq) error {
+	policy := &model.PasswordPolicy{}
+	if err := copier.Copy(policy, &req); err != nil {
+		return err
+	}
+	policy.EntityID = entityID
+	return password.passwordDao.SavePolicy(policy)
+}
+
+/*
+Checks length and character classes, the error names every rule the password misses
+*/
+func (password *passwordService) Validate(entityID uint, newPassword string) error {
+	policy, err := password.GetPolicy(entityID)
+	if err != nil {
+		return err
+	}
+	var upper, lower, digit, symbol bool
+	for _, char := range newPassword {
+		switch {
+		case unicode.IsUpper(char):
+			upper = true
+		case unicode.IsLower(char):
+			lower = true
+		case unicode.IsDigit(char):
+			digit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if uint(len([]rune(newPassword))) < policy.MinLength {
+		missing = append(missing, fmt.Sprintf("at least %d characters", policy.MinLength))
+	}
+	if len(newPassword) > define.PASSWORD_MAX_LENGTH {
+		missing = append(missing, fmt.Sprintf("at most %d bytes", define.PASSWORD_MAX_LENGTH))
+	}
+	if policy.RequireUpper && !upper {
+		missing = append(missing, "an upper case letter")
+	}
+	if policy.RequireLower && !lower {
+		missing = append(missing, "a lower case letter")
+	}
+	if policy.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%w: needs %s", ErrPasswordPolicy, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+/*
+The current password counts towards the history size, so a size of 1
+only rejects keeping the same password
+*/
+func (password *passwordService) reused(thisUser *model.User, newPassword string, historySize uint) (bool, error) {
+	if historySize == 0 {
+		return false, nil
+	}
+	if ok, _ := utils.CheckPassword(thisUser.Password, newPassword); ok {
+		return true, nil
+	}
+	historyList, err := password.passwordDao.GetHistory(thisUser.ID, int(historySize)-1)
+	if err != nil {
+		return false, err
+	}
+	for _, history := range historyList {
+		if ok, _ := utils.CheckPassword(history.PasswordHash, newPassword); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+Whether the user may take the password under the policy of their entity
+*/
+func (password *passwordService) checkNewPassword(thisUser *model.User, newPassword string) error {
+	err := password.Validate(thisUser.EntityID, newPassword)
+	if err != nil {
+		return err
+	}
+	policy, err := password.GetPolicy(thisUser.EntityID)
+	if err != nil {
+		return err
+	}
+	reused, err := password.reused(thisUser, newPassword, policy.HistorySize)
+	if err != nil {
+		return err
+	} else if reused {
+		return ErrPasswordReused
+	}
+	return nil
+}
+
+/*
+A temporary password is one chosen by someone else, its owner has to replace it on next login
+*/
+func (password *passwordService) SetPassword(thisUser *model.User, newPassword string, temporary bool) error {
+	err := password.checkNewPassword(thisUser, newPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return password.uow.Transaction(func(tx *dao.Daos) error {
+		return password.writePassword(tx, thisUser, hash, temporary)
+	})
+}
+
+/*
+Store an already checked password hash and keep the old one in the history
+*/
+func (password *passwordService) writePassword(tx *dao.Daos, thisUser *model.User, hash string, temporary bool) error {
+	err := tx.Password.AddHistory(thisUser.ID, thisUser.Password)
+	if err != nil {
+		return err
+	}
+	// kept up to the largest size a policy may ask for, raising the size later finds them
+	err = tx.Password.TrimHistory(thisUser.ID, define.PASSWORD_HISTORY_MAX)
+	if err != nil {
+		return err
+	}
+	return tx.User.Update(thisUser.ID, map[string]interface{}{
+		"password":             hash,
+		"password_changed_at":  password.now(),
+		"must_change_password": temporary,
+	})
+}
+
+/*
+Used on accounts created with a password chosen by an admin
+*/
+func (password *passwordService) RequireChange(username string) error {
+	return password.userDao.UpdateByName(username, map[string]interface{}{
+		"must_change_password": true,
+	})
+}
+
+/*
+Whether the user has to change their password before doing anything else,
+either because it was set for them or because it has expired
+*/
+func (password *passwordService) ChangeRequired(thisUser *model.User) (bool, error) {
+	if thisUser.MustChangePassword {
+		return true, nil
+	}
+	policy, err := password.GetPolicy(thisUser.EntityID)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxAgeDays == 0 || thisUser.PasswordChangedAt == nil {
+		return false, nil
+	}
+	expiresAt := thisUser.PasswordChangedAt.AddDate(0, 0, int(policy.MaxAgeDays))
+	return !password.now().Before(expiresAt), nil
+}
+
+func newResetToken() (token string, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	token = define.PASSWORD_RESET_PREFIX + hex.EncodeToString(buf)
+	hash = hashRefreshToken(token)
+	return
+}
+
+/*
+Whether the channel can reach the user at all
+*/
+func resetReachable(thisUser *model.User, channel string) bool {
+	if thisUser == nil || thisUser.Ban {
+		return false
+	}
+	switch channel {
+	case define.RESET_BY_EMAIL:
+		return thisUser.Email != ""
+	case define.RESET_BY_FEISHU:
+		return thisUser.FeishuID != ""
+	}
+	return false
+}
+
+/*
+Sends a reset token through the channel. Unknown users, users the channel cannot reach
+and requests over the rate limit get no token but the same nil, so the answer
+does not tell whether an account exists. Every request counts towards the hourly limit
+of its client ip, a user gets at most the same number of tokens whichever ips ask
+*/
+func (password *passwordService) RequestReset(username string, channel string, ip string) error {
+	since := password.now().Add(-time.Hour)
+	count, err := password.passwordDao.CountIPResets(ip, since)
+	if err != nil || count >= password.conf.PasswordResetHourlyLimit {
+		return err
+	}
+	thisUser, err := password.userDao.GetUserByName(username)
+	if err != nil {
+		return err
+	}
+	plain, hash, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	now := password.now()
+	reset := &model.PasswordReset{
+		TokenHash: hash,
+		Channel:   channel,
+		IP:        ip,
+		ExpiresAt: now.Add(time.Duration(password.conf.PasswordResetMinutes) * time.Minute),
+		CreatedAt: now,
+	}
+	if !resetReachable(thisUser, channel) {
+		// kept used up and without a user, it only counts towards the ip limit
+		reset.Used = true
+		return password.passwordDao.CreateReset(reset)
+	}
+	count, err = password.passwordDao.CountUserResets(thisUser.ID, since)
+	if err != nil || count >= password.conf.PasswordResetHourlyLimit {
+		return err
+	}
+	reset.UserID = thisUser.ID
+	err = password.passwordDao.CreateReset(reset)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("%s 您好，您的密码重置码为 %s，%d 分钟内有效且只能使用一次。如果不是您本人的操作，请忽略此消息。",
+		thisUser.UserName, plain, password.conf.PasswordResetMinutes)
+	if channel == define.RESET_BY_EMAIL {
+		return password.sender.Send(thisUser.Email, "资产管理系统密码重置", text)
+	}
+	return password.feishuService.SendMessage(thisUser.ID, text)
+}
+
+/*
+Sets the password a reset token was issued for. The password is checked before the
+token is used up, so a rejected password leaves the token for another try.
+Every other outstanding token of the user is used up in the same transaction as the password is set
+*/
+func (password *passwordService) ResetPassword(token string, newPassword string) (*model.User, error) {
+	reset, err := password.passwordDao.GetResetByHash(hashRefreshToken(token))
+	if err != nil {
+		return nil, err
+	} else if reset == nil || reset.Used || !password.now().Before(reset.ExpiresAt) {
+		return nil, ErrResetInvalid
+	}
+	thisUser, err := password.userDao.GetUserByID(reset.UserID)
+	if err != nil {
+		return nil, err
+	} else if thisUser == nil || thisUser.Ban {
+		return nil, ErrResetInvalid
+	}
+	err = password.checkNewPassword(thisUser, newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	err = password.uow.Transaction(func(tx *dao.Daos) error {
+		used, err := tx.Password.UseReset(reset.ID, password.now())
+		if err != nil {
+			return err
+		} else if !used {
+			return ErrResetInvalid
+		}
+		err = tx.Password.ExpireUserResets(thisUser.ID)
+		if err != nil {
+			return err
+		}
+		return password.writePassword(tx, thisUser, hash, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return thisUser, nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/mail"
+	"asset-management/app/mail/mailtest"
+	"asset-management/app/model"
+	"asset-management/config"
+	"asset-management/utils"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	password := NewPasswordService(daos.Password, daos.User, config.ForTest().Security, mail.NewSender(config.MailConfig{}), daos, nil).(*passwordService)
+
+	err := daos.Entity.Create(model.Entity{Name: "password_entity"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("password_entity")
+	assert.Equal(t, nil, err, "service error")
+
+	// without a policy of its own the entity gets the configured minimum length
+	policy, err := password.GetPolicy(entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, uint(6), policy.MinLength, "service error")
+	assert.Equal(t, true, errors.Is(password.Validate(entity.ID, "12345"), ErrPasswordPolicy), "service error")
+	assert.Equal(t, nil, password.Validate(entity.ID, "123456"), "service error")
+
+	err = password.SetPolicy(entity.ID, define.PasswordPolicyReq{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		HistorySize:   3,
+		MaxAgeDays:    30,
+	})
+	assert.Equal(t, nil, err, "service error")
+	err = password.Validate(entity.ID, "abcdefg")
+	assert.Equal(t, true, errors.Is(err, ErrPasswordPolicy), "service error")
+	assert.Contains(t, err.Error(), "at least 8 characters, an upper case letter, a digit, a symbol", "service error")
+	assert.Equal(t, nil, password.Validate(entity.ID, "Abcdefg1!"), "service error")
+	// other entities keep the default
+	assert.Equal(t, nil, password.Validate(entity.ID+1, "abcdefg"), "service error")
+
+	// a password set by someone else has to be changed
+	err = daos.User.Create(model.User{UserName: "password_user", Password: "-", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := daos.User.GetUserByName("password_user")
+	assert.Equal(t, nil, err, "service error")
+	err = password.SetPassword(thisUser, "Initial1!", true)
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err = daos.User.GetUserByName("password_user")
+	assert.Equal(t, nil, err, "service error")
+	ok, _ := utils.CheckPassword(thisUser.Password, "Initial1!")
+	assert.Equal(t, true, ok, "service error")
+	required, err := password.ChangeRequired(thisUser)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, required, "service error")
+
+	// the current and the two passwords before it cannot be reused
+	for _, newPassword := range []string{"Second22!", "Third333!", "Fourth44!"} {
+		err = password.SetPassword(thisUser, newPassword, false)
+		assert.Equal(t, nil, err, "service error")
+		thisUser, err = daos.User.GetUserByName("password_user")
+		assert.Equal(t, nil, err, "service error")
+	}
+	required, err = password.ChangeRequired(thisUser)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, required, "service error")
+	for _, oldPassword := range []string{"Fourth44!", "Third333!", "Second22!"} {
+		err = password.SetPassword(thisUser, oldPassword, false)
+		assert.Equal(t, ErrPasswordReused, err, "service error")
+	}
+	err = password.SetPassword(thisUser, "Initial1!", false)
+	assert.Equal(t, nil, err, "service error")
+
+	// history is kept up to the largest size a policy may ask for
+	for i := 0; i < define.PASSWORD_HISTORY_MAX+2; i++ {
+		err = daos.Password.AddHistory(thisUser.ID, "-")
+		assert.Equal(t, nil, err, "service error")
+	}
+	err = daos.Password.TrimHistory(thisUser.ID, define.PASSWORD_HISTORY_MAX)
+	assert.Equal(t, nil, err, "service error")
+	historyList, err := daos.Password.GetHistory(thisUser.ID, 100)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, define.PASSWORD_HISTORY_MAX, len(historyList), "service error")
+
+	// a password expires once it is older than the policy allows
+	thisUser, err = daos.User.GetUserByName("password_user")
+	assert.Equal(t, nil, err, "service error")
+	password.now = func() time.Time { return time.Now().AddDate(0, 0, 29) }
+	required, err = password.ChangeRequired(thisUser)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, required, "service error")
+	password.now = func() time.Time { return time.Now().AddDate(0, 0, 31) }
+	required, err = password.ChangeRequired(thisUser)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, required, "service error")
+}
+
+func TestPasswordReset(t *testing.T) {
+	catcher := mailtest.NewServer()
+	defer catcher.Close()
+	daos := dao.NewDaos(dao.InitForTest())
+	conf := config.ForTest().Security
+	password := NewPasswordService(daos.Password, daos.User, conf, mail.NewSender(catcher.Config()), daos, nil).(*passwordService)
+	tokenPattern := regexp.MustCompile(define.PASSWORD_RESET_PREFIX + "[0-9a-f]+")
+	lastToken := func() string {
+		messages := catcher.Messages()
+		return tokenPattern.FindString(messages[len(messages)-1].Data)
+	}
+
+	hash, err := utils.HashPassword("123456")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "reset_user", Password: hash, Email: "reset@example.com"})
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "reset_no_email", Password: hash})
+	assert.Equal(t, nil, err, "service error")
+
+	// nobody to send to looks the same as a sent token, and still counts towards the ip limit
+	assert.Equal(t, nil, password.RequestReset("reset_nobody", define.RESET_BY_EMAIL, "10.0.0.9"), "service error")
+	assert.Equal(t, nil, password.RequestReset("reset_no_email", define.RESET_BY_EMAIL, "10.0.0.9"), "service error")
+	assert.Equal(t, nil, password.RequestReset("reset_user", define.RESET_BY_FEISHU, "10.0.0.9"), "service error")
+	assert.Equal(t, nil, password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.9"), "service error")
+	assert.Equal(t, 0, len(catcher.Messages()), "service error")
+
+	err = password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.1")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(catcher.Messages()), "service error")
+	assert.Equal(t, []string{"reset@example.com"}, catcher.Messages()[0].To, "service error")
+	token := lastToken()
+	assert.NotEqual(t, "", token, "service error")
+
+	// a rejected password leaves the token usable, a used token is gone
+	_, err = password.ResetPassword(token, "123")
+	assert.Equal(t, true, errors.Is(err, ErrPasswordPolicy), "service error")
+	thisUser, err := password.ResetPassword(token, "654321")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, "reset_user", thisUser.UserName, "service error")
+	_, err = password.ResetPassword(token, "7654321")
+	assert.Equal(t, ErrResetInvalid, err, "service error")
+	thisUser, err = daos.User.GetUserByName("reset_user")
+	assert.Equal(t, nil, err, "service error")
+	ok, _ := utils.CheckPassword(thisUser.Password, "654321")
+	assert.Equal(t, true, ok, "service error")
+	_, err = password.ResetPassword("amr_unknown", "7654321")
+	assert.Equal(t, ErrResetInvalid, err, "service error")
+
+	// tokens expire
+	err = password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.2")
+	assert.Equal(t, nil, err, "service error")
+	token = lastToken()
+	password.now = func() time.Time { return time.Now().Add(time.Duration(conf.PasswordResetMinutes+1) * time.Minute) }
+	_, err = password.ResetPassword(token, "7654321")
+	assert.Equal(t, ErrResetInvalid, err, "service error")
+
+	// a user gets at most the hourly limit of tokens, whichever ip asks
+	password.now = time.Now
+	err = password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.3")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int(conf.PasswordResetHourlyLimit), len(catcher.Messages()), "service error")
+	err = password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.4")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int(conf.PasswordResetHourlyLimit), len(catcher.Messages()), "service error")
+	password.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	err = password.RequestReset("reset_user", define.RESET_BY_EMAIL, "10.0.0.4")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int(conf.PasswordResetHourlyLimit)+1, len(catcher.Messages()), "service error")
+
+	// so does a client ip, whichever users it asks for
+	err = daos.User.Create(model.User{UserName: "reset_other", Password: hash, Email: "other@example.com"})
+	assert.Equal(t, nil, err, "service error")
+	for i := 0; i < 3; i++ {
+		err = password.RequestReset("reset_other", define.RESET_BY_EMAIL, "10.0.0.4")
+		assert.Equal(t, nil, err, "service error")
+	}
+	assert.Equal(t, int(conf.PasswordResetHourlyLimit)*2, len(catcher.Messages()), "service error")
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+
+	"github.com/thoas/go-funk"
+	"gorm.io/datatypes"
+)
+
+type PermissionServiceInterface interface {
+	Can(operator define.UserBasicInfo, permission string, entityID uint, departmentID uint) (bool, error)
+	CanInEntity(operator define.UserBasicInfo, permission string, entityID uint) (bool, error)
+	CanInDepartmentTree(operator define.UserBasicInfo, permission string, departmentID uint) (bool, error)
+	Permissions(operator define.UserBasicInfo, entityID uint, departmentID uint) ([]string, error)
+	GetEntityRoles(entityID uint) ([]*model.Role, error)
+	GetRoleByID(id uint) (*model.Role, error)
+	ExistsRoleName(entityID uint, name string) (bool, error)
+	CreateRole(entityID uint, req define.RoleReq) (*model.Role, error)
+	ModifyRole(id uint, req define.RoleReq) error
+	DeleteRole(id uint) error
+	GetEntityAssignments(entityID uint) ([]*model.RoleAssignment, error)
+	GetAssignmentByID(id uint) (*model.RoleAssignment, error)
+	Assign(entityID uint, req define.RoleAssignmentReq) (*model.RoleAssignment, error)
+	Unassign(id uint) error
+}
+
+type permissionService struct {
+	departmentDao dao.DepartmentDaoInterface
+	roleDao       dao.RoleDaoInterface
+}
+
+func NewPermissionService(departmentDao dao.DepartmentDaoInterface, roleDao dao.RoleDaoInterface) PermissionServiceInterface {
+	return &permissionService{
+		departmentDao: departmentDao,
+		roleDao:       roleDao,
+	}
+}
+
+/*
+Every permission the operator holds on the entity, or on the department when
+departmentID is set (entityID may then be left 0). The super flags grant their
+built-in roles, assignments are looked up by user id. Department grants hold for
+that department only, entity grants for the entity and all of its departments
+*/
+func (permission *permissionService) Permissions(operator define.UserBasicInfo, entityID uint, departmentID uint) ([]string, error) {
+	if departmentID != 0 {
+		thisDepartment, err := permission.departmentDao.GetDepartmentByID(departmentID)
+		if err != nil || thisDepartment == nil {
+			return nil, err
+		}
+		if entityID != 0 && entityID != thisDepartment.EntityID {
+			return nil, nil
+		}
+		entityID = thisDepartment.EntityID
+	}
+
+	var granted []string
+	if operator.SystemSuper {
+		granted = append(granted, define.BuiltInPermissions(define.ROLE_SYSTEM_SUPER)...)
+	}
+	if operator.EntitySuper && operator.EntityID == entityID {
+		granted = append(granted, define.BuiltInPermissions(define.ROLE_ENTITY_SUPER)...)
+	}
+	if operator.DepartmentSuper && departmentID != 0 && operator.DepartmentID == departmentID && operator.EntityID == entityID {
+		granted = append(granted, define.BuiltInPermissions(define.ROLE_DEPARTMENT_SUPER)...)
+	}
+
+	if operator.UserID != 0 {
+		assignmentList, err := permission.roleDao.GetUserAssignments(operator.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, assignment := range assignmentList {
+			if assignment.EntityID != entityID {
+				continue
+			}
+			if assignment.DepartmentID != 0 && assignment.DepartmentID != departmentID {
+				continue
+			}
+			granted = append(granted, assignment.Role.Permissions...)
+		}
+	}
+	return funk.UniqString(granted), nil
+}
+
+func (permission *permissionService) Can(operator define.UserBasicInfo, name string, entityID uint, departmentID uint) (bool, error) {
+	granted, err := permission.Permissions(operator, entityID, departmentID)
+	if err != nil {
+		return false, err
+	}
+	return funk.ContainsString(granted, name), nil
+}
+
+/*
+Whether the operator holds the permission on the entity or on any one of its departments
+*/
+func (permission *permissionService) CanInEntity(operator define.UserBasicInfo, name string, entityID uint) (bool, error) {
+	allowed, err := permission.Can(operator, name, entityID, 0)
+	if allowed || err != nil {
+		return allowed, err
+	}
+	if operator.DepartmentSuper && operator.DepartmentID != 0 && operator.EntityID == entityID &&
+		funk.ContainsString(define.BuiltInPermissions(define.ROLE_DEPARTMENT_SUPER), name) {
+		return true, nil
+	}
+	if operator.UserID == 0 {
+		return false, nil
+	}
+	assignmentList, err := permission.roleDao.GetUserAssignments(operator.UserID)
+	if err != nil {
+		return false, err
+	}
+	for _, assignment := range assignmentList {
+		if assignment.EntityID == entityID && funk.ContainsString(assignment.Role.Permissions, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+Whether the operator holds the permission on the department or on any department above it
+*/
+func (permission *permissionService) CanInDepartmentTree(operator define.UserBasicInfo, name string, departmentID uint) (bool, error) {
+	for departmentID != 0 {
+		allowed, err := permission.Can(operator, name, 0, departmentID)
+		if allowed || err != nil {
+			return allowed, err
+		}
+		thisDepartment, err := permission.departmentDao.GetDepartmentByID(departmentID)
+		if err != nil || thisDepartment == nil {
+			return false, err
+		}
+		departmentID = thisDepartment.ParentID
+	}
+	return false, nil
+}
+
+func (permission *permissionService) GetEntityRoles(entityID uint) ([]*model.Role, error) {
+	return permission.roleDao.GetEntityRoles(entityID)
+}
+
+func (permission *permissionService) GetRoleByID(id uint) (*model.Role, error) {
+	return permission.roleDao.GetRoleByID(id)
+}
+
+func (permission *permissionService) ExistsRoleName(entityID uint, name string) (bool, error) {
+	thisRole, err := permission.roleDao.GetRoleByName(entityID, name)
+	return thisRole != nil, err
+}
+
+func (permission *permissionService) CreateRole(entityID uint, req define.RoleReq) (*model.Role, error) {
+	newRole := &model.Role{
+		Name:        req.Name,
+		EntityID:    entityID,
+		Permissions: funk.UniqString(req.Permissions),
+	}
+	return newRole, permission.roleDao.Create(newRole)
+}
+
+func (permission *permissionService) ModifyRole(id uint, req define.RoleReq) error {
+	return permission.roleDao.Update(id, map[string]interface{}{
+		"name":        req.Name,
+		"permissions": datatypes.JSONSlice[string](funk.UniqString(req.Permissions)),
+	})
+}
+
+func (permission *permissionService) DeleteRole(id uint) error {
+	return permission.roleDao.Delete(id)
+}
+
+func (permission *permissionService) GetEntityAssignments(entityID uint) ([]*model.RoleAssignment, error) {
+	return permission.roleDao.GetEntityAssignments(entityID)
+}
+
+func (permission *permissionService) GetAssignmentByID(id uint) (*model.RoleAssignment, error) {
+	return permission.roleDao.GetAssignmentByID(id)
+}
+
+func (permission *permissionService) Assign(entityID uint, req define.RoleAssignmentReq) (*model.RoleAssignment, error) {
+	newAssignment := &model.RoleAssignment{
+		UserID:       req.UserID,
+		RoleID:       req.RoleID,
+		EntityID:     entityID,
+		DepartmentID: req.DepartmentID,
+	}
+	return newAssignment, permission.roleDao.CreateAssignment(newAssignment)
+}
+
+func (permission *permissionService) Unassign(id uint) error {
+	return permission.roleDao.DeleteAssignment(id)
+}
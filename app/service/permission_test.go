@@ -0,0 +1,183 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermission(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	permission := NewPermissionService(daos.Department, daos.Role)
+
+	err := daos.Entity.Create(model.Entity{Name: "permission_entity"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("permission_entity")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "permission_parent", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+	parent, err := daos.Department.GetDepartmentByName("permission_parent")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "permission_child", EntityID: entity.ID, ParentID: parent.ID})
+	assert.Equal(t, nil, err, "service error")
+	child, err := daos.Department.GetDepartmentByName("permission_child")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "permission_user", Password: "x", EntityID: entity.ID, DepartmentID: parent.ID})
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := daos.User.GetUserByName("permission_user")
+	assert.Equal(t, nil, err, "service error")
+
+	// the flags map onto the built-in roles
+	manager := define.UserBasicInfo{DepartmentSuper: true, EntityID: entity.ID, DepartmentID: parent.ID}
+	can, err := permission.Can(manager, define.PERM_ASSET_APPROVE, 0, parent.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+	can, err = permission.Can(manager, define.PERM_ASSET_APPROVE, 0, child.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+	can, err = permission.Can(manager, define.PERM_LOG_VIEW, entity.ID, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+	entitySuper := define.UserBasicInfo{EntitySuper: true, EntityID: entity.ID}
+	can, err = permission.Can(entitySuper, define.PERM_LOG_EXPORT, entity.ID, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+	can, err = permission.Can(entitySuper, define.PERM_LOG_EXPORT, entity.ID+1, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+
+	// custom roles by assignment
+	auditor, err := permission.CreateRole(entity.ID, define.RoleReq{Name: "auditor", Permissions: []string{define.PERM_LOG_VIEW}})
+	assert.Equal(t, nil, err, "service error")
+	approver, err := permission.CreateRole(entity.ID, define.RoleReq{Name: "approver", Permissions: []string{define.PERM_ASSET_APPROVE, define.PERM_ASSET_APPROVE}})
+	assert.Equal(t, nil, err, "service error")
+	exists, err := permission.ExistsRoleName(entity.ID, "approver")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, exists, "service error")
+	exists, err = permission.ExistsRoleName(entity.ID, define.ROLE_ENTITY_SUPER)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, exists, "service error")
+
+	_, err = permission.Assign(entity.ID, define.RoleAssignmentReq{UserID: thisUser.ID, RoleID: auditor.ID})
+	assert.Equal(t, nil, err, "service error")
+	assignment, err := permission.Assign(entity.ID, define.RoleAssignmentReq{UserID: thisUser.ID, RoleID: approver.ID, DepartmentID: child.ID})
+	assert.Equal(t, nil, err, "service error")
+
+	employee := define.UserBasicInfo{UserID: thisUser.ID, EntityID: entity.ID, DepartmentID: parent.ID}
+	granted, err := permission.Permissions(employee, 0, child.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.ElementsMatch(t, []string{define.PERM_LOG_VIEW, define.PERM_ASSET_APPROVE}, granted, "service error")
+	granted, err = permission.Permissions(employee, 0, parent.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.ElementsMatch(t, []string{define.PERM_LOG_VIEW}, granted, "service error")
+	granted, err = permission.Permissions(employee, entity.ID, 0)
+	assert.Equal(t, nil, err, "service error")
+	assert.ElementsMatch(t, []string{define.PERM_LOG_VIEW}, granted, "service error")
+
+	err = permission.ModifyRole(approver.ID, define.RoleReq{Name: "approver", Permissions: []string{define.PERM_ASSET_IMPORT}})
+	assert.Equal(t, nil, err, "service error")
+	can, err = permission.Can(employee, define.PERM_ASSET_IMPORT, 0, child.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+
+	// a department grant counts for the entity as a whole and for the departments below
+	can, err = permission.CanInEntity(employee, define.PERM_ASSET_IMPORT, entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+	can, err = permission.CanInEntity(employee, define.PERM_ASSET_IMPORT, entity.ID+1)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+	can, err = permission.CanInEntity(manager, define.PERM_USER_MANAGE, entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+	can, err = permission.CanInDepartmentTree(employee, define.PERM_ASSET_IMPORT, parent.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+	can, err = permission.CanInDepartmentTree(manager, define.PERM_ASSET_APPROVE, child.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, can, "service error")
+
+	err = permission.Unassign(assignment.ID)
+	assert.Equal(t, nil, err, "service error")
+	can, err = permission.Can(employee, define.PERM_ASSET_IMPORT, 0, child.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can, "service error")
+
+	err = permission.DeleteRole(auditor.ID)
+	assert.Equal(t, nil, err, "service error")
+	assignmentList, err := permission.GetEntityAssignments(entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(assignmentList), "service error")
+	roleList, err := permission.GetEntityRoles(entity.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, len(define.BUILT_IN_ROLES)+1, len(roleList), "service error")
+}
+
+func TestPermissionAfterMove(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	permission := NewPermissionService(daos.Department, daos.Role)
+
+	for _, name := range []string{"move_from", "move_to"} {
+		err := daos.Entity.Create(model.Entity{Name: name})
+		assert.Equal(t, nil, err, "service error")
+	}
+	from, _ := daos.Entity.GetEntityByName("move_from")
+	to, _ := daos.Entity.GetEntityByName("move_to")
+	for _, name := range []string{"move_old", "move_new", "move_other"} {
+		err := daos.Department.Create(model.Department{Name: name, EntityID: from.ID})
+		assert.Equal(t, nil, err, "service error")
+	}
+	oldDepartment, _ := daos.Department.GetDepartmentByName("move_old")
+	newDepartment, _ := daos.Department.GetDepartmentByName("move_new")
+	otherDepartment, _ := daos.Department.GetDepartmentByName("move_other")
+	err := daos.User.Create(model.User{UserName: "move_user", Password: "x", EntityID: from.ID, DepartmentID: oldDepartment.ID})
+	assert.Equal(t, nil, err, "service error")
+	thisUser, _ := daos.User.GetUserByName("move_user")
+
+	auditor, err := permission.CreateRole(from.ID, define.RoleReq{Name: "auditor", Permissions: []string{define.PERM_LOG_VIEW}})
+	assert.Equal(t, nil, err, "service error")
+	approver, err := permission.CreateRole(from.ID, define.RoleReq{Name: "approver", Permissions: []string{define.PERM_ASSET_APPROVE}})
+	assert.Equal(t, nil, err, "service error")
+	for _, req := range []define.RoleAssignmentReq{
+		{UserID: thisUser.ID, RoleID: auditor.ID},
+		{UserID: thisUser.ID, RoleID: approver.ID, DepartmentID: oldDepartment.ID},
+		{UserID: thisUser.ID, RoleID: approver.ID, DepartmentID: otherDepartment.ID},
+	} {
+		_, err = permission.Assign(from.ID, req)
+		assert.Equal(t, nil, err, "service error")
+	}
+	can := func(name string, entityID uint, departmentID uint) bool {
+		thisUser, _ := daos.User.GetUserByName("move_user")
+		allowed, err := permission.Can(define.UserBasicInfo{UserID: thisUser.ID, EntityID: thisUser.EntityID, DepartmentID: thisUser.DepartmentID}, name, entityID, departmentID)
+		assert.Equal(t, nil, err, "service error")
+		return allowed
+	}
+
+	// a grant on the department left behind goes, others in the entity stay
+	err = daos.User.ModifyUserDepartmentByID(thisUser.ID, newDepartment.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can(define.PERM_ASSET_APPROVE, 0, oldDepartment.ID), "service error")
+	assert.Equal(t, true, can(define.PERM_ASSET_APPROVE, 0, otherDepartment.ID), "service error")
+	assert.Equal(t, true, can(define.PERM_LOG_VIEW, from.ID, 0), "service error")
+
+	// nothing held on the old entity survives a move to another one
+	err = daos.User.ModifyUserEntityByID(thisUser.ID, to.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, can(define.PERM_LOG_VIEW, from.ID, 0), "service error")
+	assert.Equal(t, false, can(define.PERM_ASSET_APPROVE, 0, otherDepartment.ID), "service error")
+	assignmentList, err := daos.Role.GetUserAssignments(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(assignmentList), "service error")
+
+	// and deleting a user takes their assignments along
+	_, err = permission.Assign(to.ID, define.RoleAssignmentReq{UserID: thisUser.ID, RoleID: auditor.ID})
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Delete([]uint{thisUser.ID})
+	assert.Equal(t, nil, err, "service error")
+	assignmentList, err = daos.Role.GetUserAssignments(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(assignmentList), "service error")
+}
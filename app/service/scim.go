@@ -0,0 +1,281 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/app/oidc"
+	"asset-management/utils"
+	"strconv"
+)
+
+type ScimServiceInterface interface {
+	GetUsers(entityID uint, filter string) ([]define.ScimUser, error)
+	GetUser(entityID uint, userID uint) (*model.User, error)
+	UserResource(thisUser *model.User) define.ScimUser
+	PatchUser(resource define.ScimUser, operations []define.ScimPatchOperation) (define.ScimUser, error)
+	CreateUser(entityID uint, resource define.ScimUser) (*model.User, error)
+	ModifyUser(thisUser *model.User, resource define.ScimUser) error
+	GetGroups(entityID uint, filter string) ([]define.ScimGroup, error)
+	GetGroup(entityID uint, departmentID uint) (*model.Department, error)
+	GroupResource(thisDepartment *model.Department) (define.ScimGroup, error)
+	PatchGroup(resource define.ScimGroup, operations []define.ScimPatchOperation) (define.ScimGroup, error)
+	CreateGroup(entityID uint, displayName string) (*model.Department, error)
+	RenameGroup(departmentID uint, displayName string) error
+	MoveUser(userID uint, departmentID uint) error
+}
+
+var scimUserAttributes = []string{"id", "userName", "active"}
+var scimGroupAttributes = []string{"id", "displayName"}
+
+type scimService struct {
+	departmentDao   dao.DepartmentDaoInterface
+	entityDao       dao.EntityDaoInterface
+	userDao         dao.UserDaoInterface
+	passwordService PasswordServiceInterface
+}
+
+func NewScimService(
+	departmentDao dao.DepartmentDaoInterface,
+	entityDao dao.EntityDaoInterface,
+	userDao dao.UserDaoInterface,
+	passwordService PasswordServiceInterface,
+) ScimServiceInterface {
+	return &scimService{
+		departmentDao:   departmentDao,
+		entityDao:       entityDao,
+		userDao:         userDao,
+		passwordService: passwordService,
+	}
+}
+
+func scimID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func scimLocation(resourceType string, id uint) string {
+	return define.SCIM_BASE_PATH + "/" + resourceType + "s/" + scimID(id)
+}
+
+func (scim *scimService) GetUsers(entityID uint, filter string) ([]define.ScimUser, error) {
+	conditions, err := parseScimFilter(filter, scimUserAttributes)
+	if err != nil {
+		return nil, err
+	}
+	users, _, err := scim.entityDao.GetEntityAllUser(entityID, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+	ret := []define.ScimUser{}
+	for _, thisUser := range users {
+		matched := conditions.match(map[string]string{
+			"id":       scimID(thisUser.ID),
+			"username": thisUser.UserName,
+			"active":   strconv.FormatBool(!thisUser.Ban),
+		})
+		if matched {
+			ret = append(ret, scim.UserResource(thisUser))
+		}
+	}
+	return ret, nil
+}
+
+/*
+nil when the user does not exist or belongs to another entity
+*/
+func (scim *scimService) GetUser(entityID uint, userID uint) (*model.User, error) {
+	thisUser, err := scim.userDao.GetUserByID(userID)
+	if err != nil || thisUser == nil || thisUser.EntityID != entityID {
+		return nil, err
+	}
+	return thisUser, nil
+}
+
+func (scim *scimService) UserResource(thisUser *model.User) define.ScimUser {
+	active := !thisUser.Ban
+	ret := define.ScimUser{
+		Schemas:  []string{define.SCIM_USER_SCHEMA},
+		ID:       scimID(thisUser.ID),
+		UserName: thisUser.UserName,
+		Active:   &active,
+		Meta: &define.ScimMeta{
+			ResourceType: define.SCIM_RESOURCE_USER,
+			Location:     scimLocation(define.SCIM_RESOURCE_USER, thisUser.ID),
+		},
+	}
+	if thisUser.DepartmentID != 0 && thisUser.Department != nil {
+		ret.Groups = []define.ScimGroupRef{{
+			Value:   scimID(thisUser.DepartmentID),
+			Display: thisUser.Department.Name,
+			Ref:     scimLocation(define.SCIM_RESOURCE_GROUP, thisUser.DepartmentID),
+		}}
+	}
+	return ret
+}
+
+func (scim *scimService) PatchUser(resource define.ScimUser, operations []define.ScimPatchOperation) (define.ScimUser, error) {
+	return patchScimUser(resource, operations)
+}
+
+/*
+A user provisioned without a password can only log in through
+an identity provider until a password is set for them. A provisioned
+password has to meet the entity's policy and be changed on first login
+*/
+func (scim *scimService) CreateUser(entityID uint, resource define.ScimUser) (*model.User, error) {
+	password := resource.Password
+	if password == "" {
+		random, err := oidc.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		password = random
+	} else if err := scim.passwordService.Validate(entityID, password); err != nil {
+		return nil, err
+	}
+	password, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	err = scim.userDao.Create(model.User{
+		UserName:           resource.UserName,
+		Password:           password,
+		EntityID:           entityID,
+		Ban:                resource.Active != nil && !*resource.Active,
+		MustChangePassword: resource.Password != "",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scim.userDao.GetUserByName(resource.UserName)
+}
+
+/*
+Writes the user name, active state and password of the resource, a change of the
+active state or the password outdates the tokens the user holds. The password goes
+through the entity's policy and history like one set by an admin
+*/
+func (scim *scimService) ModifyUser(thisUser *model.User, resource define.ScimUser) error {
+	if resource.Password != "" {
+		err := scim.passwordService.SetPassword(thisUser, resource.Password, true)
+		if err != nil {
+			return err
+		}
+	}
+	data := map[string]interface{}{}
+	if resource.UserName != thisUser.UserName {
+		data["username"] = resource.UserName
+	}
+	if resource.Active != nil && *resource.Active == thisUser.Ban {
+		data["ban"] = !*resource.Active
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return scim.userDao.Update(thisUser.ID, data)
+}
+
+func (scim *scimService) GetGroups(entityID uint, filter string) ([]define.ScimGroup, error) {
+	conditions, err := parseScimFilter(filter, scimGroupAttributes)
+	if err != nil {
+		return nil, err
+	}
+	departments, err := scim.entityDao.GetEntityAllDepartment(entityID)
+	if err != nil {
+		return nil, err
+	}
+	ret := []define.ScimGroup{}
+	for _, thisDepartment := range departments {
+		matched := conditions.match(map[string]string{
+			"id":          scimID(thisDepartment.ID),
+			"displayname": thisDepartment.Name,
+		})
+		if !matched {
+			continue
+		}
+		resource, err := scim.GroupResource(thisDepartment)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resource)
+	}
+	return ret, nil
+}
+
+/*
+nil when the department does not exist or belongs to another entity
+*/
+func (scim *scimService) GetGroup(entityID uint, departmentID uint) (*model.Department, error) {
+	thisDepartment, err := scim.departmentDao.GetDepartmentByID(departmentID)
+	if err != nil || thisDepartment == nil || thisDepartment.EntityID != entityID {
+		return nil, err
+	}
+	return thisDepartment, nil
+}
+
+/*
+Members are the users directly in the department, not those of its sub-departments
+*/
+func (scim *scimService) GroupResource(thisDepartment *model.Department) (define.ScimGroup, error) {
+	users, err := scim.departmentDao.GetDepartmentDirectUserByID(thisDepartment.ID)
+	if err != nil {
+		return define.ScimGroup{}, err
+	}
+	members := []define.ScimMember{}
+	for _, thisUser := range users {
+		members = append(members, define.ScimMember{
+			Value:   scimID(thisUser.ID),
+			Display: thisUser.UserName,
+			Ref:     scimLocation(define.SCIM_RESOURCE_USER, thisUser.ID),
+		})
+	}
+	return define.ScimGroup{
+		Schemas:     []string{define.SCIM_GROUP_SCHEMA},
+		ID:          scimID(thisDepartment.ID),
+		DisplayName: thisDepartment.Name,
+		Members:     members,
+		Meta: &define.ScimMeta{
+			ResourceType: define.SCIM_RESOURCE_GROUP,
+			Location:     scimLocation(define.SCIM_RESOURCE_GROUP, thisDepartment.ID),
+		},
+	}, nil
+}
+
+func (scim *scimService) PatchGroup(resource define.ScimGroup, operations []define.ScimPatchOperation) (define.ScimGroup, error) {
+	return patchScimGroup(resource, operations)
+}
+
+/*
+Provisioned groups become top-level departments of the entity
+*/
+func (scim *scimService) CreateGroup(entityID uint, displayName string) (*model.Department, error) {
+	err := scim.departmentDao.Create(model.Department{
+		Name:     displayName,
+		EntityID: entityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scim.departmentDao.GetDepartmentSub(displayName, entityID, 0)
+}
+
+func (scim *scimService) RenameGroup(departmentID uint, displayName string) error {
+	return scim.departmentDao.Update(departmentID, map[string]interface{}{
+		"name": displayName,
+	})
+}
+
+/*
+Moves the user into the department, or out of any department when it is 0.
+A department manager does not stay a manager in the department they move to
+*/
+func (scim *scimService) MoveUser(userID uint, departmentID uint) error {
+	var department interface{}
+	if departmentID != 0 {
+		department = departmentID
+	}
+	return scim.userDao.Update(userID, map[string]interface{}{
+		"department_id":    department,
+		"department_super": false,
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrScimFilter = errors.New("invalid filter")
+
+/*
+One `attribute operator value` comparison of a SCIM filter
+*/
+type scimCondition struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+/*
+The conditions of a filter joined by `and`, an empty filter matches everything.
+Provisioning clients look resources up by a single attribute, so `or`,
+`not` and grouping are not supported
+*/
+type scimFilter []scimCondition
+
+type scimToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeScimFilter(filter string) ([]scimToken, error) {
+	tokens := []scimToken{}
+	for i := 0; i < len(filter); {
+		switch {
+		case filter[i] == ' ':
+			i++
+		case filter[i] == '"':
+			var value strings.Builder
+			i++
+			for ; i < len(filter) && filter[i] != '"'; i++ {
+				if filter[i] == '\\' && i+1 < len(filter) {
+					i++
+				}
+				value.WriteByte(filter[i])
+			}
+			if i == len(filter) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrScimFilter)
+			}
+			i++
+			tokens = append(tokens, scimToken{text: value.String(), quoted: true})
+		default:
+			start := i
+			for i < len(filter) && filter[i] != ' ' && filter[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, scimToken{text: filter[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+/*
+Attribute names and operators are case-insensitive, so both are kept lower-cased
+*/
+func parseScimFilter(filter string, attributes []string) (scimFilter, error) {
+	tokens, err := tokenizeScimFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	ret := scimFilter{}
+	for i := 0; i < len(tokens); {
+		if len(ret) != 0 {
+			if tokens[i].quoted || !strings.EqualFold(tokens[i].text, "and") {
+				return nil, fmt.Errorf("%w: expected and, got %s", ErrScimFilter, tokens[i].text)
+			}
+			i++
+		}
+		if i+1 >= len(tokens) || tokens[i].quoted || tokens[i+1].quoted {
+			return nil, fmt.Errorf("%w: expected attribute and operator", ErrScimFilter)
+		}
+		condition := scimCondition{
+			attribute: strings.ToLower(tokens[i].text),
+			operator:  strings.ToLower(tokens[i+1].text),
+		}
+		if !containsFold(attributes, condition.attribute) {
+			return nil, fmt.Errorf("%w: unsupported attribute %s", ErrScimFilter, tokens[i].text)
+		}
+		i += 2
+		switch condition.operator {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("%w: %s needs a value", ErrScimFilter, condition.operator)
+			}
+			condition.value = tokens[i].text
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unsupported operator %s", ErrScimFilter, tokens[i-1].text)
+		}
+		ret = append(ret, condition)
+	}
+	return ret, nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Values are compared case-insensitively, none of the supported attributes is case exact
+*/
+func (filter scimFilter) match(values map[string]string) bool {
+	for _, condition := range filter {
+		value, ok := values[condition.attribute]
+		value, expected := strings.ToLower(value), strings.ToLower(condition.value)
+		var matched bool
+		switch condition.operator {
+		case "pr":
+			matched = ok && value != ""
+		case "eq":
+			matched = value == expected
+		case "ne":
+			matched = value != expected
+		case "co":
+			matched = strings.Contains(value, expected)
+		case "sw":
+			matched = strings.HasPrefix(value, expected)
+		case "ew":
+			matched = strings.HasSuffix(value, expected)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"asset-management/app/define"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrScimPatch = errors.New("invalid patch")
+
+/*
+The attributes an add or replace sets, keyed by lower-cased name.
+Without a path the value is an object holding several attributes
+*/
+func scimPatchValues(operation define.ScimPatchOperation) (map[string]json.RawMessage, error) {
+	if operation.Path != "" {
+		return map[string]json.RawMessage{strings.ToLower(operation.Path): operation.Value}, nil
+	}
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(operation.Value, &object); err != nil {
+		return nil, fmt.Errorf("%w: value without a path must be an object", ErrScimPatch)
+	}
+	ret := map[string]json.RawMessage{}
+	for key, value := range object {
+		ret[strings.ToLower(key)] = value
+	}
+	return ret, nil
+}
+
+func scimString(attribute string, value json.RawMessage) (string, error) {
+	var ret string
+	if err := json.Unmarshal(value, &ret); err != nil || ret == "" {
+		return "", fmt.Errorf("%w: %s must be a non-empty string", ErrScimPatch, attribute)
+	}
+	return ret, nil
+}
+
+/*
+Some clients send booleans as the strings "True" and "False"
+*/
+func scimBool(attribute string, value json.RawMessage) (bool, error) {
+	var ret bool
+	if err := json.Unmarshal(value, &ret); err == nil {
+		return ret, nil
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		switch strings.ToLower(text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%w: %s must be a boolean", ErrScimPatch, attribute)
+}
+
+func scimMembers(value json.RawMessage) ([]define.ScimMember, error) {
+	members := []define.ScimMember{}
+	if err := json.Unmarshal(value, &members); err != nil {
+		member := define.ScimMember{}
+		if err := json.Unmarshal(value, &member); err != nil {
+			return nil, fmt.Errorf("%w: members must be a list of objects with a value", ErrScimPatch)
+		}
+		members = append(members, member)
+	}
+	for _, member := range members {
+		if member.Value == "" {
+			return nil, fmt.Errorf("%w: every member needs a value", ErrScimPatch)
+		}
+	}
+	return members, nil
+}
+
+/*
+Applies the operations to a user resource. Attributes we do not keep,
+such as name or emails, are accepted and dropped
+*/
+func patchScimUser(resource define.ScimUser, operations []define.ScimPatchOperation) (define.ScimUser, error) {
+	for _, operation := range operations {
+		switch strings.ToLower(operation.Op) {
+		case define.SCIM_PATCH_ADD, define.SCIM_PATCH_REPLACE:
+			values, err := scimPatchValues(operation)
+			if err != nil {
+				return resource, err
+			}
+			for attribute, value := range values {
+				switch attribute {
+				case "username":
+					if resource.UserName, err = scimString(attribute, value); err != nil {
+						return resource, err
+					}
+				case "password":
+					if resource.Password, err = scimString(attribute, value); err != nil {
+						return resource, err
+					}
+				case "active":
+					active, err := scimBool(attribute, value)
+					if err != nil {
+						return resource, err
+					}
+					resource.Active = &active
+				}
+			}
+		case define.SCIM_PATCH_REMOVE:
+			switch strings.ToLower(operation.Path) {
+			case "username", "active":
+				return resource, fmt.Errorf("%w: %s cannot be removed", ErrScimPatch, operation.Path)
+			}
+		default:
+			return resource, fmt.Errorf("%w: unsupported op %s", ErrScimPatch, operation.Op)
+		}
+	}
+	return resource, nil
+}
+
+func addScimMembers(members []define.ScimMember, added []define.ScimMember) []define.ScimMember {
+	for _, member := range added {
+		if !hasScimMember(members, member.Value) {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+func hasScimMember(members []define.ScimMember, value string) bool {
+	for _, member := range members {
+		if member.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeScimMembers(members []define.ScimMember, removed func(member define.ScimMember) bool) []define.ScimMember {
+	ret := []define.ScimMember{}
+	for _, member := range members {
+		if !removed(member) {
+			ret = append(ret, member)
+		}
+	}
+	return ret
+}
+
+/*
+Applies the operations to a group resource, members are removed either
+by listing them in the value or by a filter such as members[value eq "3"]
+*/
+func patchScimGroup(resource define.ScimGroup, operations []define.ScimPatchOperation) (define.ScimGroup, error) {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case define.SCIM_PATCH_ADD, define.SCIM_PATCH_REPLACE:
+			values, err := scimPatchValues(operation)
+			if err != nil {
+				return resource, err
+			}
+			for attribute, value := range values {
+				switch attribute {
+				case "displayname":
+					if resource.DisplayName, err = scimString(attribute, value); err != nil {
+						return resource, err
+					}
+				case "members":
+					members, err := scimMembers(value)
+					if err != nil {
+						return resource, err
+					}
+					if op == define.SCIM_PATCH_REPLACE {
+						resource.Members = addScimMembers([]define.ScimMember{}, members)
+					} else {
+						resource.Members = addScimMembers(resource.Members, members)
+					}
+				}
+			}
+		case define.SCIM_PATCH_REMOVE:
+			path := strings.ToLower(operation.Path)
+			switch {
+			case path == "members":
+				if len(operation.Value) == 0 || string(operation.Value) == "null" {
+					resource.Members = []define.ScimMember{}
+					break
+				}
+				members, err := scimMembers(operation.Value)
+				if err != nil {
+					return resource, err
+				}
+				resource.Members = removeScimMembers(resource.Members, func(member define.ScimMember) bool {
+					return hasScimMember(members, member.Value)
+				})
+			case strings.HasPrefix(path, "members[") && strings.HasSuffix(path, "]"):
+				filter, err := parseScimFilter(operation.Path[len("members["):len(operation.Path)-1], []string{"value"})
+				if err != nil {
+					return resource, fmt.Errorf("%w: %s", ErrScimPatch, err.Error())
+				}
+				resource.Members = removeScimMembers(resource.Members, func(member define.ScimMember) bool {
+					return filter.match(map[string]string{"value": member.Value})
+				})
+			case path == "displayname":
+				return resource, fmt.Errorf("%w: %s cannot be removed", ErrScimPatch, operation.Path)
+			}
+		default:
+			return resource, fmt.Errorf("%w: unsupported op %s", ErrScimPatch, operation.Op)
+		}
+	}
+	return resource, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"asset-management/app/define"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScimFilter(t *testing.T) {
+	filter, err := parseScimFilter(`userName eq "Alice" and active eq true`, scimUserAttributes)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, filter.match(map[string]string{"username": "alice", "active": "true"}), "service error")
+	assert.Equal(t, false, filter.match(map[string]string{"username": "alice", "active": "false"}), "service error")
+
+	filter, err = parseScimFilter(`userName sw "al" and id pr`, scimUserAttributes)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, filter.match(map[string]string{"username": "alice", "id": "3"}), "service error")
+	assert.Equal(t, false, filter.match(map[string]string{"username": "alice"}), "service error")
+
+	filter, err = parseScimFilter("", scimUserAttributes)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, filter.match(map[string]string{}), "service error")
+
+	for _, invalid := range []string{`userName eq`, `userName eq "alice`, `password eq "x"`, `userName gt "a"`, `userName eq "a" or id eq "1"`} {
+		_, err = parseScimFilter(invalid, scimUserAttributes)
+		assert.Equal(t, true, errors.Is(err, ErrScimFilter), invalid)
+	}
+}
+
+func TestScimPatch(t *testing.T) {
+	operations := func(raw string) []define.ScimPatchOperation {
+		ret := []define.ScimPatchOperation{}
+		json.Unmarshal([]byte(raw), &ret)
+		return ret
+	}
+
+	active := true
+	user, err := patchScimUser(define.ScimUser{UserName: "alice", Active: &active}, operations(
+		`[{"op":"Replace","path":"active","value":"False"},{"op":"replace","value":{"userName":"alice2","name":{"givenName":"A"}}}]`,
+	))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, *user.Active, "service error")
+	assert.Equal(t, true, active, "service error")
+	assert.Equal(t, "alice2", user.UserName, "service error")
+	_, err = patchScimUser(user, operations(`[{"op":"remove","path":"userName"}]`))
+	assert.Equal(t, true, errors.Is(err, ErrScimPatch), "service error")
+	_, err = patchScimUser(user, operations(`[{"op":"move","path":"userName"}]`))
+	assert.Equal(t, true, errors.Is(err, ErrScimPatch), "service error")
+
+	group, err := patchScimGroup(define.ScimGroup{DisplayName: "dev", Members: []define.ScimMember{{Value: "1"}}}, operations(
+		`[{"op":"add","path":"members","value":[{"value":"2"},{"value":"1"}]},{"op":"add","path":"members","value":{"value":"3"}}]`,
+	))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, []define.ScimMember{{Value: "1"}, {Value: "2"}, {Value: "3"}}, group.Members, "service error")
+
+	group, err = patchScimGroup(group, operations(
+		`[{"op":"remove","path":"members[value eq \"2\"]"},{"op":"remove","path":"members","value":[{"value":"3"}]},{"op":"replace","path":"displayName","value":"ops"}]`,
+	))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, []define.ScimMember{{Value: "1"}}, group.Members, "service error")
+	assert.Equal(t, "ops", group.DisplayName, "service error")
+
+	group, err = patchScimGroup(group, operations(`[{"op":"replace","path":"members","value":[{"value":"4"}]}]`))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, []define.ScimMember{{Value: "4"}}, group.Members, "service error")
+	group, err = patchScimGroup(group, operations(`[{"op":"remove","path":"members"}]`))
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(group.Members), "service error")
+	_, err = patchScimGroup(group, operations(`[{"op":"add","path":"members","value":[{"display":"no value"}]}]`))
+	assert.Equal(t, true, errors.Is(err, ErrScimPatch), "service error")
+}
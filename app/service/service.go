@@ -2,8 +2,58 @@ package service
 
 import (
 	"asset-management/app/dao"
+	"asset-management/app/mail"
+	"asset-management/config"
 )
 
-func Initial() {
-	dao.Initial()
+/*
+All services, each built from the daos it needs
+*/
+type Services struct {
+	Asset        AssetServiceInterface
+	AssetClass   AssetClassServiceInterface
+	AssetRecycle AssetRecycleServiceInterface
+	Async        AsyncServiceInterface
+	Department   DepartmentServiceInterface
+	Entity       EntityServiceInterface
+	Feishu       FeishuServiceInterface
+	Identity     IdentityServiceInterface
+	Invite       InviteServiceInterface
+	Log          LogServiceInterface
+	LoginLock    LoginLockServiceInterface
+	Password     PasswordServiceInterface
+	Permission   PermissionServiceInterface
+	Scim         ScimServiceInterface
+	Stat         StatServiceInterface
+	Task         TaskServiceInterface
+	Token        TokenServiceInterface
+	TwoFactor    TwoFactorServiceInterface
+	Url          UrlServiceInterface
+	User         UserServiceInterface
+}
+
+func NewServices(conf *config.Config, daos *dao.Daos) *Services {
+	services := &Services{
+		Asset:        NewAssetService(daos.Asset, daos.AssetState, daos.AssetVersion, daos),
+		AssetClass:   NewAssetClassService(daos.AssetClass, daos.Asset),
+		AssetRecycle: NewAssetRecycleService(daos.AssetRecycle, daos, conf.Asset),
+		Async:        NewAsyncService(daos.Async),
+		Entity:       NewEntityService(daos.Department, daos.Entity, daos.User),
+		Feishu:       NewFeishuService(conf.Feishu, daos.Department, daos.User),
+		Log:          NewLogService(daos.Log),
+		LoginLock:    NewLoginLockService(daos.LoginLock, daos.User, conf.Security),
+		Permission:   NewPermissionService(daos.Department, daos.Role),
+		Stat:         NewStatService(daos.Stat),
+		Task:         NewTaskService(daos.Task, daos),
+		TwoFactor:    NewTwoFactorService(daos.TwoFactor, daos.Entity, daos),
+		Url:          NewUrlService(daos.Url),
+		User:         NewUserService(daos.User),
+	}
+	services.Department = NewDepartmentService(daos.Department, daos.Entity, daos.User, services.Entity, services.User)
+	services.Password = NewPasswordService(daos.Password, daos.User, conf.Security, mail.NewSender(conf.Mail), daos, services.Feishu)
+	services.Token = NewTokenService(daos.ApiToken, daos.Department, daos.Token, daos.User, services.Password)
+	services.Invite = NewInviteService(daos.Invite, daos, services.Password)
+	services.Scim = NewScimService(daos.Department, daos.Entity, daos.User, services.Password)
+	services.Identity = NewIdentityService(daos.Identity, daos.User, services.Feishu)
+	return services
 }
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"asset-management/app/model"
+	"time"
+)
+
+const SESSION_TOUCH_INTERVAL = time.Minute
+
+/*
+Last seen is only a hint, no need to write on every request
+*/
+func (token *tokenService) touchSession(grant *model.RefreshToken) error {
+	now := token.now()
+	if grant.LastSeenAt != nil && now.Sub(*grant.LastSeenAt) < SESSION_TOUCH_INTERVAL {
+		return nil
+	}
+	return token.tokenDao.TouchToken(grant.ID, now)
+}
+
+/*
+Sessions of the user that are neither revoked nor expired
+*/
+func (token *tokenService) GetUserSessions(userID uint) ([]*model.RefreshToken, error) {
+	return token.tokenDao.GetUserSessions(userID, token.now())
+}
+
+func (token *tokenService) GetSession(id uint) (*model.RefreshToken, error) {
+	return token.tokenDao.GetTokenByID(id)
+}
+
+func (token *tokenService) RevokeSession(id uint) error {
+	return token.tokenDao.RevokeToken(id)
+}
@@ -6,27 +6,28 @@ import (
 	"asset-management/app/model"
 )
 
-type statService struct {
+type StatServiceInterface interface {
+	GetDepartmentStat(departmentID uint) ([]*model.Stat, error)
+	GetDepartmentAssetDistribution(departmentID uint) ([]*define.AssetDistribution, error)
+	GetAssetDepartmentDistribution(departmentIDs []uint, subStats []*define.DepartmentAssetDistribution) error
 }
 
-var StatService *statService
-
-func newStatService() *statService {
-	return &statService{}
+type statService struct {
+	statDao dao.StatDaoInterface
 }
 
-func init() {
-	StatService = newStatService()
+func NewStatService(statDao dao.StatDaoInterface) StatServiceInterface {
+	return &statService{statDao: statDao}
 }
 
 func (stat *statService) GetDepartmentStat(departmentID uint) ([]*model.Stat, error) {
-	return dao.StatDao.GetDepartmentStat(departmentID)
+	return stat.statDao.GetDepartmentStat(departmentID)
 }
 
 func (stat *statService) GetDepartmentAssetDistribution(departmentID uint) ([]*define.AssetDistribution, error) {
-	return dao.StatDao.GetDepartmentAssetDistribution(departmentID)
+	return stat.statDao.GetDepartmentAssetDistribution(departmentID)
 }
 
 func (stat *statService) GetAssetDepartmentDistribution(departmentIDs []uint, subStats []*define.DepartmentAssetDistribution) error {
-	return dao.StatDao.GetDepartmentsAssetDistribution(departmentIDs, subStats)
+	return stat.statDao.GetDepartmentsAssetDistribution(departmentIDs, subStats)
 }
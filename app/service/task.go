@@ -4,48 +4,145 @@ import (
 	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
+
+	"github.com/thoas/go-funk"
 )
 
-type taskService struct {
+type TaskServiceInterface interface {
+	CreateTask(req define.CreateTaskReq, userID uint, departmentID uint, assetList []*model.Asset) (uint, error)
+	GetTasksByUserID(userID uint) (taskList []*model.Task, err error)
+	GetTasksByDepartmentID(departmentID uint) (taskList []*model.Task, err error)
+	GetTaskInfoByID(taskID uint) (taskInfo *model.Task, err error)
+	ModifyTaskState(taskID uint, state uint) error
+	ApproveTask(taskInfo *model.Task, operatorID uint) error
 }
 
-var TaskService *taskService
-
-func newTaskService() *taskService {
-	return &taskService{}
+type taskService struct {
+	taskDao dao.TaskDaoInterface
+	uow     dao.UnitOfWork
 }
 
-func init() {
-	TaskService = newTaskService()
+func NewTaskService(taskDao dao.TaskDaoInterface, uow dao.UnitOfWork) TaskServiceInterface {
+	return &taskService{
+		taskDao: taskDao,
+		uow:     uow,
+	}
 }
 
+/*
+Quantities are only kept for the assets a part of is asked for
+*/
 func (task *taskService) CreateTask(req define.CreateTaskReq, userID uint, departmentID uint, assetList []*model.Asset) (uint, error) {
-	return dao.TaskDao.Create(model.Task{
+	quantities := []*model.TaskQuantity{}
+	for _, item := range req.AssetList {
+		if item.Quantity == 0 {
+			continue
+		}
+		thisAsset, ok := funk.Find(assetList, func(thisAsset *model.Asset) bool {
+			return thisAsset.ID == item.AssetID
+		}).(*model.Asset)
+		if !ok {
+			continue
+		}
+		if err := CheckAssetQuantity(thisAsset, item.Quantity); err != nil {
+			return 0, err
+		}
+		quantities = append(quantities, &model.TaskQuantity{
+			AssetID:  item.AssetID,
+			Quantity: item.Quantity,
+		})
+	}
+
+	return task.taskDao.Create(model.Task{
 		TaskType:        req.TaskType,
 		TaskDescription: req.TaskDescription,
 		UserID:          userID,
 		DepartmentID:    departmentID,
 		TargetID:        req.TargetID,
 		AssetList:       assetList,
+		Quantities:      quantities,
 	})
 }
 
 func (task *taskService) GetTasksByUserID(userID uint) (taskList []*model.Task, err error) {
-	taskList, err = dao.TaskDao.GetTaskListByUserID(userID)
+	taskList, err = task.taskDao.GetTaskListByUserID(userID)
 	return
 }
 
 func (task *taskService) GetTasksByDepartmentID(departmentID uint) (taskList []*model.Task, err error) {
-	taskList, err = dao.TaskDao.GetTaskListByDepartmentID(departmentID)
+	taskList, err = task.taskDao.GetTaskListByDepartmentID(departmentID)
 	return
 }
 
 func (task *taskService) GetTaskInfoByID(taskID uint) (taskInfo *model.Task, err error) {
-	taskInfo, err = dao.TaskDao.GetTaskByID(taskID)
+	taskInfo, err = task.taskDao.GetTaskByID(taskID)
 	return
 }
 
 func (task *taskService) ModifyTaskState(taskID uint, state uint) error {
-	err := dao.TaskDao.ModifyTaskState(taskID, state)
+	err := task.taskDao.ModifyTaskState(taskID, state)
 	return err
 }
+
+/*
+The lifecycle event a task of each type drives once approved
+*/
+func TaskAssetEvent(taskType uint) string {
+	switch taskType {
+	case 0:
+		return define.ASSET_ACQUIRE
+	case 1:
+		return define.ASSET_RETURN
+	case 2:
+		return define.ASSET_MAINTAIN
+	default:
+		return define.ASSET_TRANSFER
+	}
+}
+
+/*
+Move the task's assets and mark the task approved in one transaction,
+so an approved task never leaves its assets behind. Units asked for are split off first,
+returned units go back onto their stock
+*/
+func (task *taskService) ApproveTask(taskInfo *model.Task, operatorID uint) error {
+	change := AssetStateChange{
+		Event:    TaskAssetEvent(taskInfo.TaskType),
+		ActorID:  operatorID,
+		TaskID:   taskInfo.ID,
+		TargetID: taskInfo.TargetID,
+		Reason:   taskInfo.TaskDescription,
+	}
+	switch taskInfo.TaskType {
+	case 0:
+		change.TargetID = taskInfo.UserID
+	case 1:
+		// a returned asset goes back to whoever approved the return
+		change.TargetID = operatorID
+	}
+
+	return task.uow.Transaction(func(tx *dao.Daos) error {
+		assetIDs, err := splitTaskAssets(tx, taskInfo, change)
+		if err != nil {
+			return err
+		}
+		err = ApplyAssetTransition(tx, assetIDs, change)
+		if err != nil {
+			return err
+		}
+		if change.Event == define.ASSET_TRANSFER {
+			err = transferAssets(tx.Asset, assetIDs, taskInfo.TargetID, taskInfo.Target.DepartmentID, taskInfo.DepartmentID)
+			if err != nil {
+				return err
+			}
+		}
+		if change.Event == define.ASSET_RETURN {
+			err = mergeAssetStock(tx, assetIDs, change)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Task.ModifyTaskState(taskInfo.ID, 1)
+	})
+}
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
+	"errors"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTask(t *testing.T) {
@@ -22,3 +26,49 @@ func TestTask(t *testing.T) {
 	TaskService.GetTaskInfoByID(1)
 	TaskService.ModifyTaskState(1, 2)
 }
+
+type fakeTaskDao struct {
+	dao.TaskDaoInterface
+	err   error
+	state map[uint]uint
+}
+
+func (fake *fakeTaskDao) ModifyTaskState(taskID uint, state uint) error {
+	if fake.err != nil {
+		return fake.err
+	}
+	fake.state[taskID] = state
+	return nil
+}
+
+func TestApproveTaskWithFakeDao(t *testing.T) {
+	assetDao := &fakeAssetDao{updated: map[uint]map[string]interface{}{}}
+	taskDao := &fakeTaskDao{state: map[uint]uint{}}
+	stateDao := &fakeAssetStateDao{assets: map[uint]*model.Asset{
+		1: {ID: 1, State: define.ASSET_IN_USE},
+		2: {ID: 2, State: define.ASSET_IN_USE},
+	}}
+	uow := &fakeUnitOfWork{tx: &dao.Daos{Asset: assetDao, AssetState: stateDao, Task: taskDao}}
+	taskService := NewTaskService(taskDao, uow)
+
+	transfer := &model.Task{
+		ID:           5,
+		TaskType:     3,
+		DepartmentID: 1,
+		TargetID:     9,
+		Target:       model.User{DepartmentID: 1},
+		AssetList:    []*model.Asset{{ID: 1}, {ID: 2}},
+	}
+	err := taskService.ApproveTask(transfer, 7)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint(1), taskDao.state[5])
+	assert.Equal(t, uint(9), assetDao.updated[2]["user_id"])
+	assert.Equal(t, define.ASSET_TRANSFER, stateDao.history[0].Event)
+	assert.Equal(t, uint(5), stateDao.history[0].TaskID)
+	assert.Equal(t, uint(7), stateDao.history[0].ActorID)
+
+	failure := errors.New("task update failed")
+	taskDao.err = failure
+	err = taskService.ApproveTask(transfer, 7)
+	assert.Equal(t, failure, err)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+type TokenServiceInterface interface {
+	IssueTokens(thisUser *model.User, client define.SessionClient) (*define.TokenResponse, error)
+	IssueImpersonationToken(admin *model.User, thisUser *model.User, client define.SessionClient) (string, time.Time, error)
+	RefreshTokens(refreshToken string) (*define.TokenResponse, error)
+	CheckToken(claims *define.UserClaims) (bool, error)
+	CheckSecurityVersion(userID uint, version uint) (bool, error)
+	RevokeToken(tokenID string) error
+	RevokeUserTokens(userID uint) error
+	GetUserSessions(userID uint) ([]*model.RefreshToken, error)
+	GetSession(id uint) (*model.RefreshToken, error)
+	RevokeSession(id uint) error
+	CreateApiToken(ownerID uint, kind string, entityID uint, req define.CreateApiTokenReq) (string, *model.ApiToken, error)
+	GetUserApiTokens(userID uint) ([]*model.ApiToken, error)
+	GetEntityApiKeys(entityID uint) ([]*model.ApiToken, error)
+	GetApiTokenByID(id uint) (*model.ApiToken, error)
+	RevokeApiToken(id uint) error
+	ResolveApiToken(token string) (*define.ApiTokenIdentity, error)
+}
+
+type tokenService struct {
+	apiTokenDao     dao.ApiTokenDaoInterface
+	departmentDao   dao.DepartmentDaoInterface
+	tokenDao        dao.TokenDaoInterface
+	userDao         dao.UserDaoInterface
+	passwordService PasswordServiceInterface
+	now             func() time.Time
+}
+
+func NewTokenService(
+	apiTokenDao dao.ApiTokenDaoInterface,
+	departmentDao dao.DepartmentDaoInterface,
+	tokenDao dao.TokenDaoInterface,
+	userDao dao.UserDaoInterface,
+	passwordService PasswordServiceInterface,
+) TokenServiceInterface {
+	return &tokenService{
+		apiTokenDao:     apiTokenDao,
+		departmentDao:   departmentDao,
+		tokenDao:        tokenDao,
+		userDao:         userDao,
+		passwordService: passwordService,
+		now:             time.Now,
+	}
+}
+
+/*
+The identity a user acts with, also what their permissions are looked up for
+*/
+func UserBasicInfo(thisUser *model.User) define.UserBasicInfo {
+	return define.UserBasicInfo{
+		UserID:          thisUser.ID,
+		UserName:        thisUser.UserName,
+		EntitySuper:     thisUser.EntitySuper,
+		DepartmentSuper: thisUser.DepartmentSuper,
+		SystemSuper:     thisUser.SystemSuper,
+		EntityID:        thisUser.EntityID,
+		DepartmentID:    thisUser.DepartmentID,
+		SecurityVersion: thisUser.SecurityVersion,
+	}
+}
+
+/*
+The identity of a login token, flagged while the user's password has to be changed
+*/
+func (token *tokenService) loginInfo(thisUser *model.User) (define.UserBasicInfo, error) {
+	info := UserBasicInfo(thisUser)
+	changeRequired, err := token.passwordService.ChangeRequired(thisUser)
+	info.PasswordChangeRequired = changeRequired
+	return info, err
+}
+
+func newRefreshToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	token = hex.EncodeToString(buf)
+	hash = hashRefreshToken(token)
+	return
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Start a new login grant, recorded as a session of the client, and hand out its first access / refresh token pair
+*/
+func (token *tokenService) IssueTokens(thisUser *model.User, client define.SessionClient) (*define.TokenResponse, error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := token.now()
+	grant := &model.RefreshToken{
+		UserID:     thisUser.ID,
+		TokenHash:  hash,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: &now,
+		ExpiresAt:  time.Now().Add(utils.RTokenExpiredDuration),
+	}
+	if err := token.tokenDao.Create(grant); err != nil {
+		return nil, err
+	}
+
+	info, err := token.loginInfo(thisUser)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.CreateToken(info, strconv.FormatUint(uint64(grant.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+	return &define.TokenResponse{
+		Token:                  accessToken,
+		RefreshToken:           refreshToken,
+		PasswordChangeRequired: info.PasswordChangeRequired,
+	}, nil
+}
+
+/*
+Start a short login grant on the user's account for an admin acting as them. It shows in the
+user's sessions and goes with them, its refresh token is dropped so it cannot outlive its access token
+*/
+func (token *tokenService) IssueImpersonationToken(admin *model.User, thisUser *model.User, client define.SessionClient) (string, time.Time, error) {
+	_, hash, err := newRefreshToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := token.now()
+	grant := &model.RefreshToken{
+		UserID:     thisUser.ID,
+		TokenHash:  hash,
+		Device:     utils.Truncate("Impersonated by "+admin.UserName, model.SESSION_DEVICE_SIZE),
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: &now,
+		ExpiresAt:  now.Add(utils.ImpersonationExpiredDuration),
+	}
+	if err := token.tokenDao.Create(grant); err != nil {
+		return "", time.Time{}, err
+	}
+
+	info := UserBasicInfo(thisUser)
+	info.Impersonator = &define.Impersonator{
+		UserID:          admin.ID,
+		UserName:        admin.UserName,
+		SecurityVersion: admin.SecurityVersion,
+	}
+	accessToken, err := utils.CreateImpersonationToken(info, strconv.FormatUint(uint64(grant.ID), 10))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return accessToken, grant.ExpiresAt, nil
+}
+
+/*
+Rotate the refresh token and issue a fresh access token with the user's current identity,
+nil means the refresh token is unknown, expired or revoked
+*/
+func (token *tokenService) RefreshTokens(refreshToken string) (*define.TokenResponse, error) {
+	grant, err := token.tokenDao.GetTokenByHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.Revoked || time.Now().After(grant.ExpiresAt) {
+		return nil, nil
+	}
+
+	thisUser, err := token.userDao.GetUserByID(grant.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if thisUser == nil || thisUser.Ban {
+		return nil, token.tokenDao.RevokeToken(grant.ID)
+	}
+
+	newToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := token.tokenDao.RotateToken(grant.ID, grant.TokenHash, map[string]interface{}{
+		"token_hash":   hash,
+		"expires_at":   time.Now().Add(utils.RTokenExpiredDuration),
+		"last_seen_at": token.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the same refresh token was used twice at once, one of the two is not the user
+	if !rotated {
+		return nil, token.tokenDao.RevokeToken(grant.ID)
+	}
+
+	info, err := token.loginInfo(thisUser)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.CreateToken(info, strconv.FormatUint(uint64(grant.ID), 10))
+	if err != nil {
+		return nil, err
+	}
+	return &define.TokenResponse{
+		Token:                  accessToken,
+		RefreshToken:           newToken,
+		PasswordChangeRequired: info.PasswordChangeRequired,
+	}, nil
+}
+
+/*
+An access token stays usable only while its login grant is not revoked,
+every use counts as activity of the session
+*/
+func (token *tokenService) CheckToken(claims *define.UserClaims) (bool, error) {
+	grantID, err := strconv.ParseUint(claims.Id, 10, 0)
+	if err != nil {
+		return false, nil
+	}
+	grant, err := token.tokenDao.GetTokenByID(uint(grantID))
+	if err != nil {
+		return false, err
+	}
+	if grant == nil || grant.Revoked || grant.UserID != claims.UserID {
+		return false, nil
+	}
+	return true, token.touchSession(grant)
+}
+
+/*
+An access token carries the identity of its issue time, it is outdated
+once the user's identity, department, entity or ban state changed
+*/
+func (token *tokenService) CheckSecurityVersion(userID uint, version uint) (bool, error) {
+	thisUser, err := token.userDao.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return thisUser != nil && thisUser.SecurityVersion == version, nil
+}
+
+func (token *tokenService) RevokeToken(tokenID string) error {
+	grantID, err := strconv.ParseUint(tokenID, 10, 0)
+	if err != nil {
+		return nil
+	}
+	return token.tokenDao.RevokeToken(uint(grantID))
+}
+
+func (token *tokenService) RevokeUserTokens(userID uint) error {
+	return token.tokenDao.RevokeUserTokens(userID)
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/mail"
+	"asset-management/app/model"
+	"asset-management/config"
+	"asset-management/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	InitForTest()
+
+	err := UserService.CreateUser("token_user", "123456")
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := UserService.GetUserByName("token_user")
+	assert.Equal(t, nil, err, "service error")
+
+	pair, err := TokenService.IssueTokens(thisUser, define.SessionClient{})
+	assert.Equal(t, nil, err, "service error")
+	claims, err := utils.ParseToken(pair.Token)
+	assert.Equal(t, nil, err, "service error")
+	valid, err := TokenService.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, valid, "service error")
+
+	refreshed, err := TokenService.RefreshTokens(pair.RefreshToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.NotEqual(t, pair.RefreshToken, refreshed.RefreshToken, "service error")
+
+	// a rotated refresh token cannot be used twice
+	reused, err := TokenService.RefreshTokens(pair.RefreshToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, reused, "service error")
+
+	err = TokenService.RevokeToken(claims.Id)
+	assert.Equal(t, nil, err, "service error")
+	valid, err = TokenService.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, valid, "service error")
+	revoked, err := TokenService.RefreshTokens(refreshed.RefreshToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, revoked, "service error")
+
+	first, err := TokenService.IssueTokens(thisUser, define.SessionClient{})
+	assert.Equal(t, nil, err, "service error")
+	second, err := TokenService.IssueTokens(thisUser, define.SessionClient{})
+	assert.Equal(t, nil, err, "service error")
+	err = TokenService.RevokeUserTokens(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	for _, pair := range []string{first.Token, second.Token} {
+		claims, err := utils.ParseToken(pair)
+		assert.Equal(t, nil, err, "service error")
+		valid, err := TokenService.CheckToken(claims)
+		assert.Equal(t, nil, err, "service error")
+		assert.Equal(t, false, valid, "service error")
+	}
+
+	version := thisUser.SecurityVersion
+	current, err := TokenService.CheckSecurityVersion(thisUser.ID, version)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, current, "service error")
+	err = UserService.ModifyUserBanstate("token_user", true)
+	assert.Equal(t, nil, err, "service error")
+	// banning is an identity change, so tokens issued before it are outdated
+	current, err = TokenService.CheckSecurityVersion(thisUser.ID, version)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, current, "service error")
+	third, err := TokenService.IssueTokens(thisUser, define.SessionClient{})
+	assert.Equal(t, nil, err, "service error")
+	banned, err := TokenService.RefreshTokens(third.RefreshToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, banned, "service error")
+}
+
+func TestApiTokenScope(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	now := time.Now()
+	token := &tokenService{
+		apiTokenDao:     daos.ApiToken,
+		departmentDao:   daos.Department,
+		tokenDao:        daos.Token,
+		userDao:         daos.User,
+		passwordService: NewPasswordService(daos.Password, daos.User, config.ForTest().Security, mail.NewSender(config.MailConfig{}), daos, nil),
+		now:             func() time.Time { return now },
+	}
+
+	err := daos.Entity.Create(model.Entity{Name: "api_scope"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("api_scope")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "api_scope_parent", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+	parent, err := daos.Department.GetDepartmentByName("api_scope_parent")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "api_scope_child", EntityID: entity.ID, ParentID: parent.ID})
+	assert.Equal(t, nil, err, "service error")
+	child, err := daos.Department.GetDepartmentByName("api_scope_child")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.Department.Create(model.Department{Name: "api_scope_sibling", EntityID: entity.ID})
+	assert.Equal(t, nil, err, "service error")
+	sibling, err := daos.Department.GetDepartmentByName("api_scope_sibling")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "api_scope_manager", Password: "x", EntityID: entity.ID, DepartmentID: parent.ID, DepartmentSuper: true})
+	assert.Equal(t, nil, err, "service error")
+	manager, err := daos.User.GetUserByName("api_scope_manager")
+	assert.Equal(t, nil, err, "service error")
+
+	req := define.CreateApiTokenReq{Name: "child", Scope: define.API_SCOPE_WRITE, DepartmentID: child.ID, ExpiresInDays: 1}
+	plain, newToken, err := token.CreateApiToken(manager.ID, model.API_TOKEN_PERSONAL, entity.ID, req)
+	assert.Equal(t, nil, err, "service error")
+	assert.NotNil(t, newToken, "service error")
+
+	identity, err := token.ResolveApiToken(plain)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, child.ID, identity.DepartmentID, "service error")
+	assert.Equal(t, true, identity.DepartmentSuper, "service error")
+	assert.Equal(t, false, identity.ReadOnly, "service error")
+
+	// a department super cannot reach outside the own subtree
+	req.DepartmentID = sibling.ID
+	_, newToken, err = token.CreateApiToken(manager.ID, model.API_TOKEN_PERSONAL, entity.ID, req)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, newToken, "service error")
+	// nor create entity keys
+	req.DepartmentID = 0
+	_, newToken, err = token.CreateApiToken(manager.ID, model.API_TOKEN_ENTITY, entity.ID, req)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, newToken, "service error")
+
+	// losing the manager role kills the scoped token
+	err = daos.User.Update(manager.ID, map[string]interface{}{"department_super": false})
+	assert.Equal(t, nil, err, "service error")
+	identity, err = token.ResolveApiToken(plain)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, identity, "service error")
+	err = daos.User.Update(manager.ID, map[string]interface{}{"department_super": true})
+	assert.Equal(t, nil, err, "service error")
+
+	now = now.Add(25 * time.Hour)
+	identity, err = token.ResolveApiToken(plain)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, identity, "service error")
+}
+
+func TestSession(t *testing.T) {
+	InitForTest()
+
+	err := UserService.CreateUser("session_user", "123456")
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := UserService.GetUserByName("session_user")
+	assert.Equal(t, nil, err, "service error")
+
+	client := define.SessionClient{Device: "Windows", IP: "10.0.0.1", UserAgent: "Mozilla/5.0 (Windows NT 10.0)"}
+	laptop, err := TokenService.IssueTokens(thisUser, client)
+	assert.Equal(t, nil, err, "service error")
+	_, err = TokenService.IssueTokens(thisUser, define.SessionClient{Device: "iPhone", IP: "10.0.0.2"})
+	assert.Equal(t, nil, err, "service error")
+
+	sessions, err := TokenService.GetUserSessions(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 2, len(sessions), "service error")
+	assert.Equal(t, "iPhone", sessions[0].Device, "service error")
+	assert.Equal(t, "10.0.0.1", sessions[1].IP, "service error")
+	assert.Equal(t, client.UserAgent, sessions[1].UserAgent, "service error")
+	assert.NotNil(t, sessions[1].LastSeenAt, "service error")
+
+	// revoking the session of the laptop logs out its access token
+	err = TokenService.RevokeSession(sessions[1].ID)
+	assert.Equal(t, nil, err, "service error")
+	claims, err := utils.ParseToken(laptop.Token)
+	assert.Equal(t, nil, err, "service error")
+	valid, err := TokenService.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, valid, "service error")
+	sessions, err = TokenService.GetUserSessions(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(sessions), "service error")
+
+	err = TokenService.RevokeUserTokens(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	sessions, err = TokenService.GetUserSessions(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 0, len(sessions), "service error")
+}
+
+func TestImpersonationToken(t *testing.T) {
+	InitForTest()
+
+	err := UserService.CreateUser("impersonation_admin", "123456")
+	assert.Equal(t, nil, err, "service error")
+	admin, err := UserService.GetUserByName("impersonation_admin")
+	assert.Equal(t, nil, err, "service error")
+	err = UserService.CreateUser("impersonation_user", "123456")
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := UserService.GetUserByName("impersonation_user")
+	assert.Equal(t, nil, err, "service error")
+
+	accessToken, expiresAt, err := TokenService.IssueImpersonationToken(admin, thisUser, define.SessionClient{IP: "10.0.0.3"})
+	assert.Equal(t, nil, err, "service error")
+	assert.WithinDuration(t, time.Now().Add(utils.ImpersonationExpiredDuration), expiresAt, time.Minute, "service error")
+	claims, err := utils.ParseToken(accessToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, thisUser.ID, claims.UserID, "service error")
+	assert.Equal(t, admin.ID, claims.Impersonator.UserID, "service error")
+	assert.Equal(t, "impersonation_admin", claims.Impersonator.UserName, "service error")
+	assert.Equal(t, expiresAt.Unix(), claims.ExpiresAt, "service error")
+	valid, err := TokenService.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, valid, "service error")
+
+	// the user sees the admin in their sessions and can end it
+	sessions, err := TokenService.GetUserSessions(thisUser.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, 1, len(sessions), "service error")
+	assert.Equal(t, "Impersonated by impersonation_admin", sessions[0].Device, "service error")
+	err = TokenService.RevokeSession(sessions[0].ID)
+	assert.Equal(t, nil, err, "service error")
+	valid, err = TokenService.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, valid, "service error")
+}
+
+/*
+Lets a second refresh rotate the grant between the lookup and the rotation of the first
+*/
+type racingTokenDao struct {
+	dao.TokenDaoInterface
+}
+
+func (racing racingTokenDao) GetTokenByHash(hash string) (*model.RefreshToken, error) {
+	grant, err := racing.TokenDaoInterface.GetTokenByHash(hash)
+	if err != nil || grant == nil {
+		return grant, err
+	}
+	_, err = racing.TokenDaoInterface.RotateToken(grant.ID, hash, map[string]interface{}{"token_hash": "rotated"})
+	return grant, err
+}
+
+func TestRefreshTokenRace(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	token := &tokenService{
+		apiTokenDao:     daos.ApiToken,
+		departmentDao:   daos.Department,
+		tokenDao:        racingTokenDao{daos.Token},
+		userDao:         daos.User,
+		passwordService: NewPasswordService(daos.Password, daos.User, config.ForTest().Security, mail.NewSender(config.MailConfig{}), daos, nil),
+		now:             time.Now,
+	}
+	err := daos.User.Create(model.User{UserName: "race_user", Password: "x"})
+	assert.Equal(t, nil, err, "service error")
+	thisUser, err := daos.User.GetUserByName("race_user")
+	assert.Equal(t, nil, err, "service error")
+
+	pair, err := token.IssueTokens(thisUser, define.SessionClient{})
+	assert.Equal(t, nil, err, "service error")
+	claims, err := utils.ParseToken(pair.Token)
+	assert.Equal(t, nil, err, "service error")
+
+	// the loser of the race gets nothing and the session ends for both
+	refreshed, err := token.RefreshTokens(pair.RefreshToken)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, refreshed, "service error")
+	valid, err := token.CheckToken(claims)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, valid, "service error")
+}
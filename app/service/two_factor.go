@@ -0,0 +1,244 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	TWO_FACTOR_ISSUER    = "AssetManagement"
+	RECOVERY_CODE_NUMBER = 10
+)
+
+type TwoFactorServiceInterface interface {
+	Required(thisUser *model.User) (bool, error)
+	Enabled(userID uint) (bool, error)
+	Status(thisUser *model.User) (*define.TwoFactorStatusResponse, error)
+	Setup(thisUser *model.User) (*define.TwoFactorSetupResponse, error)
+	Enable(userID uint, code string) ([]string, error)
+	Verify(userID uint, code string) (bool, error)
+	Disable(userID uint) error
+	RegenerateRecoveryCodes(userID uint) ([]string, error)
+	SetEntityRequired(entityID uint, required bool) error
+}
+
+type twoFactorService struct {
+	twoFactorDao dao.TwoFactorDaoInterface
+	entityDao    dao.EntityDaoInterface
+	uow          dao.UnitOfWork
+	now          func() time.Time
+}
+
+func NewTwoFactorService(twoFactorDao dao.TwoFactorDaoInterface, entityDao dao.EntityDaoInterface, uow dao.UnitOfWork) TwoFactorServiceInterface {
+	return &twoFactorService{
+		twoFactorDao: twoFactorDao,
+		entityDao:    entityDao,
+		uow:          uow,
+		now:          time.Now,
+	}
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRecoveryCodes(userID uint) ([]string, []*model.RecoveryCode, error) {
+	codes := make([]string, 0, RECOVERY_CODE_NUMBER)
+	rows := make([]*model.RecoveryCode, 0, RECOVERY_CODE_NUMBER)
+	for i := 0; i < RECOVERY_CODE_NUMBER; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		rows = append(rows, &model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+	return codes, rows, nil
+}
+
+func replaceRecoveryCodes(twoFactorDao dao.TwoFactorDaoInterface, userID uint) ([]string, error) {
+	codes, rows, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := twoFactorDao.DeleteRecoveryCodes(userID); err != nil {
+		return nil, err
+	}
+	if err := twoFactorDao.CreateRecoveryCodes(rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+/*
+Managers (entity and department supers) of an entity that requires it
+*/
+func (twoFactor *twoFactorService) Required(thisUser *model.User) (bool, error) {
+	if thisUser.EntityID == 0 || (!thisUser.EntitySuper && !thisUser.DepartmentSuper) {
+		return false, nil
+	}
+	thisEntity, err := twoFactor.entityDao.GetEntityByID(thisUser.EntityID)
+	if err != nil || thisEntity == nil {
+		return false, err
+	}
+	return thisEntity.RequireTwoFactor, nil
+}
+
+func (twoFactor *twoFactorService) Enabled(userID uint) (bool, error) {
+	thisTwoFactor, err := twoFactor.twoFactorDao.GetByUserID(userID)
+	if err != nil || thisTwoFactor == nil {
+		return false, err
+	}
+	return thisTwoFactor.Enabled, nil
+}
+
+func (twoFactor *twoFactorService) Status(thisUser *model.User) (*define.TwoFactorStatusResponse, error) {
+	enabled, err := twoFactor.Enabled(thisUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := twoFactor.Required(thisUser)
+	if err != nil {
+		return nil, err
+	}
+	left, err := twoFactor.twoFactorDao.CountRecoveryCodes(thisUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &define.TwoFactorStatusResponse{
+		Enabled:           enabled,
+		Required:          required,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+/*
+Start (or restart) enrollment with a fresh secret, nil when 2FA is already enabled
+*/
+func (twoFactor *twoFactorService) Setup(thisUser *model.User) (*define.TwoFactorSetupResponse, error) {
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	thisTwoFactor, err := twoFactor.twoFactorDao.GetByUserID(thisUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if thisTwoFactor == nil {
+		err = twoFactor.twoFactorDao.Create(&model.TwoFactor{
+			UserID: thisUser.ID,
+			Secret: secret,
+		})
+	} else if thisTwoFactor.Enabled {
+		return nil, nil
+	} else {
+		err = twoFactor.twoFactorDao.Update(thisTwoFactor.ID, map[string]interface{}{
+			"secret":    secret,
+			"last_step": 0,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &define.TwoFactorSetupResponse{
+		Secret:     secret,
+		OtpauthURL: utils.TOTPURL(TWO_FACTOR_ISSUER, thisUser.UserName, secret),
+	}, nil
+}
+
+/*
+Confirm enrollment with the first code, return the recovery codes,
+nil when the code is wrong or there is no pending enrollment
+*/
+func (twoFactor *twoFactorService) Enable(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := twoFactor.uow.Transaction(func(tx *dao.Daos) error {
+		thisTwoFactor, err := tx.TwoFactor.GetByUserID(userID)
+		if err != nil || thisTwoFactor == nil || thisTwoFactor.Enabled {
+			return err
+		}
+		step, ok := utils.VerifyTOTP(thisTwoFactor.Secret, code, twoFactor.now())
+		if !ok {
+			return nil
+		}
+		now := twoFactor.now()
+		err = tx.TwoFactor.Update(thisTwoFactor.ID, map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": &now,
+			"last_step":  step,
+		})
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx.TwoFactor, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+/*
+Accept a current TOTP code once, or burn one recovery code
+*/
+func (twoFactor *twoFactorService) Verify(userID uint, code string) (bool, error) {
+	thisTwoFactor, err := twoFactor.twoFactorDao.GetByUserID(userID)
+	if err != nil || thisTwoFactor == nil || !thisTwoFactor.Enabled {
+		return false, err
+	}
+	if step, ok := utils.VerifyTOTP(thisTwoFactor.Secret, code, twoFactor.now()); ok {
+		return twoFactor.twoFactorDao.UseStep(thisTwoFactor.ID, step)
+	}
+	recoveryCode, err := twoFactor.twoFactorDao.GetRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil || recoveryCode == nil {
+		return false, err
+	}
+	return twoFactor.twoFactorDao.UseRecoveryCode(recoveryCode.ID)
+}
+
+func (twoFactor *twoFactorService) Disable(userID uint) error {
+	return twoFactor.uow.Transaction(func(tx *dao.Daos) error {
+		if err := tx.TwoFactor.DeleteRecoveryCodes(userID); err != nil {
+			return err
+		}
+		return tx.TwoFactor.DeleteByUserID(userID)
+	})
+}
+
+func (twoFactor *twoFactorService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	var codes []string
+	err := twoFactor.uow.Transaction(func(tx *dao.Daos) error {
+		thisTwoFactor, err := tx.TwoFactor.GetByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if thisTwoFactor == nil || !thisTwoFactor.Enabled {
+			return errors.New("two-factor authentication is not enabled")
+		}
+		codes, err = replaceRecoveryCodes(tx.TwoFactor, userID)
+		return err
+	})
+	return codes, err
+}
+
+func (twoFactor *twoFactorService) SetEntityRequired(entityID uint, required bool) error {
+	return twoFactor.entityDao.Update(entityID, map[string]interface{}{
+		"require_two_factor": required,
+	})
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/model"
+	"asset-management/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+	now := time.Now()
+	twoFactorService := &twoFactorService{
+		twoFactorDao: daos.TwoFactor,
+		entityDao:    daos.Entity,
+		uow:          daos,
+		now:          func() time.Time { return now },
+	}
+
+	err := daos.Entity.Create(model.Entity{Name: "two_factor"})
+	assert.Equal(t, nil, err, "service error")
+	entity, err := daos.Entity.GetEntityByName("two_factor")
+	assert.Equal(t, nil, err, "service error")
+	err = daos.User.Create(model.User{UserName: "manager", Password: "x", EntityID: entity.ID, EntitySuper: true})
+	assert.Equal(t, nil, err, "service error")
+	manager, err := daos.User.GetUserByName("manager")
+	assert.Equal(t, nil, err, "service error")
+
+	required, err := twoFactorService.Required(manager)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, required, "service error")
+	err = twoFactorService.SetEntityRequired(entity.ID, true)
+	assert.Equal(t, nil, err, "service error")
+	required, err = twoFactorService.Required(manager)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, required, "service error")
+
+	setup, err := twoFactorService.Setup(manager)
+	assert.Equal(t, nil, err, "service error")
+	codes, err := twoFactorService.Enable(manager.ID, "000000")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, []string(nil), codes, "service error")
+	code, err := utils.TOTPCode(setup.Secret, utils.TOTPStep(now))
+	assert.Equal(t, nil, err, "service error")
+	codes, err = twoFactorService.Enable(manager.ID, code)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, RECOVERY_CODE_NUMBER, len(codes), "service error")
+
+	setup, err = twoFactorService.Setup(manager)
+	assert.Equal(t, nil, err, "service error")
+	assert.Nil(t, setup, "service error")
+
+	// the enrollment code cannot be replayed, the next step's code works once
+	ok, err := twoFactorService.Verify(manager.ID, code)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, ok, "service error")
+	now = now.Add(utils.TOTPPeriod * time.Second)
+	status, err := twoFactorService.Status(manager)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, status.Enabled, "service error")
+	secret, err := daos.TwoFactor.GetByUserID(manager.ID)
+	assert.Equal(t, nil, err, "service error")
+	code, err = utils.TOTPCode(secret.Secret, utils.TOTPStep(now))
+	assert.Equal(t, nil, err, "service error")
+	ok, err = twoFactorService.Verify(manager.ID, code)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, ok, "service error")
+	ok, err = twoFactorService.Verify(manager.ID, code)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, ok, "service error")
+
+	// recovery codes are single use
+	ok, err = twoFactorService.Verify(manager.ID, codes[0])
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, ok, "service error")
+	ok, err = twoFactorService.Verify(manager.ID, codes[0])
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, ok, "service error")
+	status, err = twoFactorService.Status(manager)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, int64(RECOVERY_CODE_NUMBER-1), status.RecoveryCodesLeft, "service error")
+
+	newCodes, err := twoFactorService.RegenerateRecoveryCodes(manager.ID)
+	assert.Equal(t, nil, err, "service error")
+	ok, err = twoFactorService.Verify(manager.ID, codes[1])
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, ok, "service error")
+	ok, err = twoFactorService.Verify(manager.ID, newCodes[1])
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, true, ok, "service error")
+
+	err = twoFactorService.Disable(manager.ID)
+	assert.Equal(t, nil, err, "service error")
+	enabled, err := twoFactorService.Enabled(manager.ID)
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, enabled, "service error")
+}
@@ -6,21 +6,24 @@ import (
 	"asset-management/app/model"
 )
 
-type urlService struct {
+type UrlServiceInterface interface {
+	CreateUrl(req define.CreateUrlReq, entity_id uint) error
+	ModifyUrlInfo(req define.ModifyUrlReq, entity_id uint) error
+	DeleteUrl(name string, entity_id uint) error
+	GetUrlsByEntity(entity_id uint, DepartmentSuper bool, EntitySuper bool, SystemSuper bool) ([]*model.Url, error)
+	CheckIfUrlExists(name string, entity_id uint) (exists bool, err error)
 }
 
-var UrlService *urlService
-
-func newUrlService() *urlService {
-	return &urlService{}
+type urlService struct {
+	urlDao dao.UrlDaoInterface
 }
 
-func init() {
-	UrlService = newUrlService()
+func NewUrlService(urlDao dao.UrlDaoInterface) UrlServiceInterface {
+	return &urlService{urlDao: urlDao}
 }
 
 func (url *urlService) CreateUrl(req define.CreateUrlReq, entity_id uint) error {
-	err := dao.UrlDao.Create(model.Url{
+	err := url.urlDao.Create(model.Url{
 		Name:            req.Name,
 		Url:             req.Url,
 		EntityID:        entity_id,
@@ -32,7 +35,7 @@ func (url *urlService) CreateUrl(req define.CreateUrlReq, entity_id uint) error
 }
 
 func (url *urlService) ModifyUrlInfo(req define.ModifyUrlReq, entity_id uint) error {
-	err := dao.UrlDao.Update(req.OldName, entity_id, map[string]interface{}{
+	err := url.urlDao.Update(req.OldName, entity_id, map[string]interface{}{
 		"name":             req.Name,
 		"url":              req.Url,
 		"department_super": req.DepartmentSuper,
@@ -43,16 +46,16 @@ func (url *urlService) ModifyUrlInfo(req define.ModifyUrlReq, entity_id uint) er
 }
 
 func (url *urlService) DeleteUrl(name string, entity_id uint) error {
-	err := dao.UrlDao.Delete([]string{name}, entity_id)
+	err := url.urlDao.Delete([]string{name}, entity_id)
 	return err
 }
 
 func (url *urlService) GetUrlsByEntity(entity_id uint, DepartmentSuper bool, EntitySuper bool, SystemSuper bool) ([]*model.Url, error) {
-	return dao.UrlDao.GetUrlsByEntity(entity_id, DepartmentSuper, EntitySuper, SystemSuper)
+	return url.urlDao.GetUrlsByEntity(entity_id, DepartmentSuper, EntitySuper, SystemSuper)
 }
 
 func (url *urlService) CheckIfUrlExists(name string, entity_id uint) (exists bool, err error) {
-	target_url, err := dao.UrlDao.GetUrlByName(name, entity_id)
+	target_url, err := url.urlDao.GetUrlByName(name, entity_id)
 	if target_url == nil {
 		return false, err
 	}
@@ -8,21 +8,39 @@ import (
 	"errors"
 )
 
-type userService struct{}
-
-var UserService *userService
+type UserServiceInterface interface {
+	CreateUser(username, password string) error
+	VerifyPasswordAndGetUser(username, password string) (*model.User, error)
+	GetUserByID(id uint) (*model.User, error)
+	GetUserByName(name string) (*model.User, error)
+	ExistsUser(username string) (bool, error)
+	ExistsUserByID(userID uint) (bool, error)
+	UserName(ctx *utils.Context) (string, error)
+	ModifyUserIdentity(username string, identity int) error
+	ModifyUserPassword(username string, password string) error
+	ModifyUserBanstate(username string, ban bool) error
+	DeleteUser(userID uint) error
+	GetAllUsers(page_size uint, page_num uint) ([]*model.User, int64, error)
+	ModifyUserEntity(userID uint, entityID uint) error
+	ModifyUserDepartment(userID uint, departmentID uint) error
+	ModifyUserIdentityUpdate(userID uint, req *define.ModifyUserIdentityReq) error
+	ModifyUserEmail(userID uint, email string) error
+}
 
-func newUserService() *userService {
-	return &userService{}
+type userService struct {
+	userDao dao.UserDaoInterface
 }
 
-func init() {
-	UserService = newUserService()
+func NewUserService(userDao dao.UserDaoInterface) UserServiceInterface {
+	return &userService{userDao: userDao}
 }
 
 func (user *userService) CreateUser(username, password string) error {
-	password = utils.CreateMD5(password)
-	return dao.UserDao.Create(model.User{
+	password, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return user.userDao.Create(model.User{
 		UserName: username,
 		Password: password,
 		Ban:      false,
@@ -31,33 +49,40 @@ func (user *userService) CreateUser(username, password string) error {
 	})
 }
 
-func (user *userService) VerifyPasswordAndGetUser(username, password string) (string, *model.User, error) {
-	password = utils.CreateMD5(password)
-	thisUser, err := dao.UserDao.GetUserByName(username)
+/*
+Legacy md5 hashes and hashes with an outdated cost are replaced
+by a fresh bcrypt hash once the password is known to be right
+*/
+func (user *userService) VerifyPasswordAndGetUser(username, password string) (*model.User, error) {
+	thisUser, err := user.userDao.GetUserByName(username)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	if thisUser == nil || thisUser.Password != password {
-		return "", nil, nil
+	if thisUser == nil {
+		return nil, nil
 	}
-	userInfo := define.UserBasicInfo{
-		UserID:          thisUser.ID,
-		UserName:        thisUser.UserName,
-		EntitySuper:     thisUser.EntitySuper,
-		DepartmentSuper: thisUser.DepartmentSuper,
-		SystemSuper:     thisUser.SystemSuper,
-		EntityID:        thisUser.EntityID,
-		DepartmentID:    thisUser.DepartmentID,
+	ok, rehash := utils.CheckPassword(thisUser.Password, password)
+	if !ok {
+		return nil, nil
 	}
-	token, err := utils.CreateToken(userInfo)
-	if err != nil {
-		return "", nil, err
+	if rehash {
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		err = user.userDao.Update(thisUser.ID, map[string]interface{}{
+			"password": hash,
+		})
+		if err != nil {
+			return nil, err
+		}
+		thisUser.Password = hash
 	}
-	return token, thisUser, nil
+	return thisUser, nil
 }
 
 func (user *userService) GetUserByID(id uint) (*model.User, error) {
-	thisUser, err := dao.UserDao.GetUserByID(id)
+	thisUser, err := user.userDao.GetUserByID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +90,7 @@ func (user *userService) GetUserByID(id uint) (*model.User, error) {
 }
 
 func (user *userService) GetUserByName(name string) (*model.User, error) {
-	thisUser, err := dao.UserDao.GetUserByName(name)
+	thisUser, err := user.userDao.GetUserByName(name)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +98,7 @@ func (user *userService) GetUserByName(name string) (*model.User, error) {
 }
 
 func (user *userService) ExistsUser(username string) (bool, error) {
-	thisUser, err := dao.UserDao.GetUserByName(username)
+	thisUser, err := user.userDao.GetUserByName(username)
 	if err != nil || thisUser == nil {
 		return false, err
 	}
@@ -81,49 +106,13 @@ func (user *userService) ExistsUser(username string) (bool, error) {
 }
 
 func (user *userService) ExistsUserByID(userID uint) (bool, error) {
-	thisUser, err := dao.UserDao.GetUserByID(userID)
+	thisUser, err := user.userDao.GetUserByID(userID)
 	if err != nil || thisUser == nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func (user *userService) SystemSuper(ctx *utils.Context) bool {
-	userInfo, exists := ctx.Get("user")
-	if exists {
-		if userInfo, ok := userInfo.(define.UserBasicInfo); ok {
-			if userInfo.SystemSuper {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (user *userService) EntitySuper(ctx *utils.Context) bool {
-	userInfo, exists := ctx.Get("user")
-	if exists {
-		if userInfo, ok := userInfo.(define.UserBasicInfo); ok {
-			if userInfo.EntitySuper {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (user *userService) DepartmentSuper(ctx *utils.Context) bool {
-	userInfo, exists := ctx.Get("user")
-	if exists {
-		if userInfo, ok := userInfo.(define.UserBasicInfo); ok {
-			if userInfo.DepartmentSuper {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func (user *userService) UserName(ctx *utils.Context) (string, error) {
 	userInfo, exists := ctx.Get("user")
 	if exists {
@@ -135,40 +124,49 @@ func (user *userService) UserName(ctx *utils.Context) (string, error) {
 }
 
 func (user *userService) ModifyUserIdentity(username string, identity int) error {
-	return dao.UserDao.ModifyUserIdentity(username, identity)
+	return user.userDao.ModifyUserIdentity(username, identity)
 }
 
 func (user *userService) ModifyUserPassword(username string, password string) error {
-	password = utils.CreateMD5(password)
-	return dao.UserDao.ModifyUserPassword(username, password)
+	password, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return user.userDao.ModifyUserPassword(username, password)
 }
 
 func (user *userService) ModifyUserBanstate(username string, ban bool) error {
-	return dao.UserDao.ModifyUserBanstate(username, ban)
+	return user.userDao.ModifyUserBanstate(username, ban)
 }
 
 func (user *userService) DeleteUser(userID uint) error {
-	return dao.UserDao.Delete([]uint{userID})
+	return user.userDao.Delete([]uint{userID})
 }
 
 func (user *userService) GetAllUsers(page_size uint, page_num uint) ([]*model.User, int64, error) {
 	offset := page_size * page_num
 	limit := page_size
-	return dao.UserDao.AllUser(int(offset), int(limit))
+	return user.userDao.AllUser(int(offset), int(limit))
 }
 
 func (user *userService) ModifyUserEntity(userID uint, entityID uint) error {
-	return dao.UserDao.ModifyUserEntityByID(userID, entityID)
+	return user.userDao.ModifyUserEntityByID(userID, entityID)
 }
 
 func (user *userService) ModifyUserDepartment(userID uint, departmentID uint) error {
-	return dao.UserDao.ModifyUserDepartmentByID(userID, departmentID)
+	return user.userDao.ModifyUserDepartmentByID(userID, departmentID)
 }
 
 func (user *userService) ModifyUserIdentityUpdate(userID uint, req *define.ModifyUserIdentityReq) error {
-	return dao.UserDao.Update(userID, map[string]interface{}{
+	return user.userDao.Update(userID, map[string]interface{}{
 		"system_super":     req.SystemSuper,
 		"entity_super":     req.EntitySuper,
 		"department_super": req.DepartmentSuper,
 	})
 }
+
+func (user *userService) ModifyUserEmail(userID uint, email string) error {
+	return user.userDao.Update(userID, map[string]interface{}{
+		"email": email,
+	})
+}
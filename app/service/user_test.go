@@ -4,6 +4,7 @@ import (
 	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/app/model"
+	"asset-management/config"
 	"asset-management/utils"
 	"log"
 	"net/http/httptest"
@@ -13,8 +14,33 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var (
+	AssetService      AssetServiceInterface
+	AssetClassService AssetClassServiceInterface
+	DepartmentService DepartmentServiceInterface
+	EntityService     EntityServiceInterface
+	IdentityService   IdentityServiceInterface
+	LogService        LogServiceInterface
+	TaskService       TaskServiceInterface
+	TokenService      TokenServiceInterface
+	UrlService        UrlServiceInterface
+	UserService       UserServiceInterface
+)
+
 func InitForTest() {
-	dao.InitForTest()
+	conf := config.ForTest()
+	utils.Initial(conf.Security)
+	services := NewServices(conf, dao.NewDaos(dao.InitForTest()))
+	AssetService = services.Asset
+	AssetClassService = services.AssetClass
+	DepartmentService = services.Department
+	EntityService = services.Entity
+	IdentityService = services.Identity
+	LogService = services.Log
+	TaskService = services.Task
+	TokenService = services.Token
+	UrlService = services.Url
+	UserService = services.User
 }
 
 func TestUser(t *testing.T) {
@@ -37,39 +63,33 @@ func TestUser(t *testing.T) {
 	}
 	assert.Equal(t, false, exist, "service error")
 
-	var (
-		token    string
-		userInfo *model.User
-	)
+	var userInfo *model.User
 
-	token, userInfo, err = UserService.VerifyPasswordAndGetUser("test", "123456")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("test", "123456")
 	assert.Equal(t, nil, err, "service error")
 	assert.Equal(t, (*model.User)(nil), userInfo, "service error")
-	assert.Equal(t, "", token, "servicve error")
-	token, userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
 	assert.Equal(t, nil, err, "service error")
 	assert.Equal(t, (*model.User)(nil), userInfo, "service error")
-	assert.Equal(t, "", token, "servicve error")
-	token, userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "admin")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "admin")
 	assert.Equal(t, nil, err, "service error")
 	assert.Equal(t, uint(1), userInfo.ID, "service error")
-	assert.Equal(t, false, token == "", "servicve error")
 
 	err = UserService.ModifyUserIdentity("admin", 1)
 	assert.Equal(t, nil, err, "service error")
-	_, userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "admin")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "admin")
 	assert.Equal(t, nil, err, "service error")
 	assert.Equal(t, true, userInfo.DepartmentSuper, "service error")
 
 	err = UserService.ModifyUserPassword("admin", "123456")
 	assert.Equal(t, nil, err, "service error")
-	_, userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
 	assert.Equal(t, nil, err, "service error")
-	assert.Equal(t, utils.CreateMD5("123456"), userInfo.Password, "service error")
+	assert.Equal(t, false, utils.IsLegacyPasswordHash(userInfo.Password), "service error")
 
 	err = UserService.ModifyUserBanstate("admin", true)
 	assert.Equal(t, nil, err, "service error")
-	_, userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
+	userInfo, err = UserService.VerifyPasswordAndGetUser("admin", "123456")
 	assert.Equal(t, nil, err, "service error")
 	assert.Equal(t, true, userInfo.Ban, "service error")
 
@@ -77,12 +97,6 @@ func TestUser(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(res)
 	context := utils.Context{Context: ctx}
 
-	isSuper := UserService.SystemSuper(&context)
-	assert.Equal(t, false, isSuper, "service error")
-	isSuper = UserService.EntitySuper(&context)
-	assert.Equal(t, false, isSuper, "service error")
-	isSuper = UserService.DepartmentSuper(&context)
-	assert.Equal(t, false, isSuper, "service error")
 	username, err := UserService.UserName(&context)
 	assert.Equal(t, "", username, "service error")
 	assert.Equal(t, "no user vertification info", err.Error(), "service error")
@@ -94,14 +108,34 @@ func TestUser(t *testing.T) {
 		DepartmentSuper: true,
 		SystemSuper:     true,
 	})
-	isSuper = UserService.SystemSuper(&context)
-	assert.Equal(t, true, isSuper, "service error")
-	isSuper = UserService.EntitySuper(&context)
-	assert.Equal(t, true, isSuper, "service error")
-	isSuper = UserService.DepartmentSuper(&context)
-	assert.Equal(t, true, isSuper, "service error")
 	username, err = UserService.UserName(&context)
 	assert.Equal(t, "admin", username, "service error")
 	assert.Equal(t, nil, err, "service error")
 
 }
+
+func TestLegacyPasswordRehash(t *testing.T) {
+	userDao := dao.NewUserDao(dao.InitForTest())
+	userService := NewUserService(userDao)
+
+	err := userDao.Create(model.User{
+		UserName: "legacy",
+		Password: utils.CreateMD5("123456"),
+	})
+	assert.Equal(t, nil, err, "service error")
+
+	userInfo, err := userService.VerifyPasswordAndGetUser("legacy", "654321")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, (*model.User)(nil), userInfo, "service error")
+
+	userInfo, err = userService.VerifyPasswordAndGetUser("legacy", "123456")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, false, utils.IsLegacyPasswordHash(userInfo.Password), "service error")
+
+	stored, err := userDao.GetUserByName("legacy")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, userInfo.Password, stored.Password, "service error")
+	userInfo, err = userService.VerifyPasswordAndGetUser("legacy", "123456")
+	assert.Equal(t, nil, err, "service error")
+	assert.Equal(t, stored.ID, userInfo.ID, "service error")
+}
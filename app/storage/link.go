@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type linkConfig struct {
+	publicURL string
+	secret    []byte
+	expire    int64
+}
+
+func linkSignature(bucket string, key string, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(linkConf.secret, bucket+"\n"+key+"\n"+strconv.FormatInt(expires, 10)))
+}
+
+/*
+Backend download link for an object, valid for link_expire_seconds
+*/
+func SignedLink(bucket string, key string) string {
+	expires := time.Now().Unix() + linkConf.expire
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", linkSignature(bucket, key, expires))
+	return strings.TrimRight(linkConf.publicURL, "/") + "/storage/" + bucket + "/" + s3EscapePath(key) + "?" + query.Encode()
+}
+
+/*
+Check signature and expiry of a download link
+*/
+func VerifyLink(bucket string, key string, expires string, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(linkSignature(bucket, key, expiresAt)), []byte(signature))
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+/*
+Objects stored as plain files under root/bucket/key
+*/
+type localBucket struct {
+	dir string
+}
+
+func newLocalBucket(root string, name string) (*localBucket, error) {
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &localBucket{dir: dir}, nil
+}
+
+/*
+Keys are cleaned as absolute paths first so ".." cannot escape the bucket directory
+*/
+func (b *localBucket) path(key string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (b *localBucket) Put(key string, reader io.Reader) error {
+	path := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (b *localBucket) Get(key string) (io.ReadCloser, error) {
+	file, err := os.Open(b.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return file, err
+}
+
+func (b *localBucket) Delete(key string) error {
+	err := os.Remove(b.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
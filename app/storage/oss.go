@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"asset-management/config"
+	"errors"
+	"io"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+type ossBucket struct {
+	bucket *oss.Bucket
+}
+
+func newOSSBucket(conf config.OSSConfig, name string) (*ossBucket, error) {
+	client, err := oss.New(conf.Endpoint, conf.AccessKeyID, conf.AccessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := client.Bucket(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ossBucket{bucket: bucket}, nil
+}
+
+func (b *ossBucket) Put(key string, reader io.Reader) error {
+	return b.bucket.PutObject(key, reader)
+}
+
+func (b *ossBucket) Get(key string) (io.ReadCloser, error) {
+	reader, err := b.bucket.GetObject(key)
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.StatusCode == 404 {
+		return nil, ErrObjectNotFound
+	}
+	return reader, err
+}
+
+func (b *ossBucket) Delete(key string) error {
+	return b.bucket.DeleteObject(key)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"asset-management/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+)
+
+/*
+Minimal S3 client (MinIO and friends), path-style requests signed with AWS Signature V4
+*/
+type s3Bucket struct {
+	client          *http.Client
+	endpoint        *url.URL
+	region          string
+	accessKeyID     string
+	accessKeySecret string
+	name            string
+}
+
+func newS3Bucket(conf config.S3Config, name string) (*s3Bucket, error) {
+	endpoint, err := url.Parse(strings.TrimRight(conf.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3 endpoint %q must contain scheme and host", conf.Endpoint)
+	}
+	return &s3Bucket{
+		client:          &http.Client{Timeout: time.Minute},
+		endpoint:        endpoint,
+		region:          conf.Region,
+		accessKeyID:     conf.AccessKeyID,
+		accessKeySecret: conf.AccessKeySecret,
+		name:            name,
+	}, nil
+}
+
+/*
+URI-encode every byte except unreserved characters and '/', as the signature spec requires
+*/
+func s3EscapePath(path string) string {
+	var builder strings.Builder
+	for _, c := range []byte(path) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			builder.WriteByte(c)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func (b *s3Bucket) sign(req *http.Request, canonicalURI string, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalURI, "", canonicalHeaders, s3SignedHeaders, payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+b.accessKeySecret), date)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, b.accessKeyID, scope, s3SignedHeaders, signature))
+}
+
+func (b *s3Bucket) do(method string, key string, body []byte) (*http.Response, error) {
+	canonicalURI := s3EscapePath(b.endpoint.Path + "/" + b.name + "/" + strings.TrimLeft(key, "/"))
+	req, err := http.NewRequest(method, b.endpoint.Scheme+"://"+b.endpoint.Host+canonicalURI, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	b.sign(req, canonicalURI, sha256Hex(body), time.Now().UTC())
+	return b.client.Do(req)
+}
+
+func s3ResponseError(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3: %s %s: %s", resp.Request.Method, resp.Status, message)
+}
+
+func (b *s3Bucket) Put(key string, reader io.Reader) error {
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp)
+	}
+	return nil
+}
+
+func (b *s3Bucket) Get(key string) (io.ReadCloser, error) {
+	resp, err := b.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	defer resp.Body.Close()
+	return nil, s3ResponseError(resp)
+}
+
+func (b *s3Bucket) Delete(key string) error {
+	resp, err := b.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3ResponseError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"asset-management/config"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+Names of the buckets used by async tasks, also the first path segment of download links
+*/
+const (
+	ImportBucketName = "import"
+	ExportBucketName = "export"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+/*
+A flat key-value object store, implemented by Aliyun OSS, S3-compatible services and local disk
+*/
+type Bucket interface {
+	Put(key string, reader io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var (
+	ImportBucket Bucket
+	ExportBucket Bucket
+)
+
+var linkConf linkConfig
+
+func Initial(conf *config.Config) error {
+	var err error
+	switch conf.Storage.Backend {
+	case "local":
+		ImportBucket, err = newLocalBucket(conf.Storage.LocalDir, ImportBucketName)
+		if err != nil {
+			return err
+		}
+		ExportBucket, err = newLocalBucket(conf.Storage.LocalDir, ExportBucketName)
+	case "s3":
+		ImportBucket, err = newS3Bucket(conf.Storage.S3, conf.Storage.S3.ImportBucket)
+		if err != nil {
+			return err
+		}
+		ExportBucket, err = newS3Bucket(conf.Storage.S3, conf.Storage.S3.ExportBucket)
+	case "oss":
+		ImportBucket, err = newOSSBucket(conf.OSS, conf.OSS.ImportBucket)
+		if err != nil {
+			return err
+		}
+		ExportBucket, err = newOSSBucket(conf.OSS, conf.OSS.ExportBucket)
+	default:
+		err = fmt.Errorf("unknown storage backend %q", conf.Storage.Backend)
+	}
+	if err != nil {
+		return err
+	}
+
+	linkConf = linkConfig{
+		publicURL: conf.Server.PublicURL,
+		secret:    []byte(conf.Storage.LinkSecret),
+		expire:    conf.Storage.LinkExpireSeconds,
+	}
+	return nil
+}
+
+func GetBucket(name string) Bucket {
+	switch name {
+	case ImportBucketName:
+		return ImportBucket
+	case ExportBucketName:
+		return ExportBucket
+	}
+	return nil
+}
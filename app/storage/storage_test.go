@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"asset-management/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, bucket Bucket, key string) string {
+	reader, err := bucket.Get(key)
+	assert.Equal(t, nil, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	assert.Equal(t, nil, err)
+	return string(data)
+}
+
+func TestLocalBucket(t *testing.T) {
+	conf := config.ForTest()
+	conf.Storage.Backend = "local"
+	conf.Storage.LocalDir = t.TempDir()
+	assert.Equal(t, nil, Initial(conf))
+
+	assert.Equal(t, nil, ExportBucket.Put("logs/a.xlsx", strings.NewReader("hello")))
+	assert.Equal(t, "hello", readAll(t, ExportBucket, "logs/a.xlsx"))
+
+	// cannot escape the bucket directory
+	assert.Equal(t, nil, ExportBucket.Put("../../b.xlsx", strings.NewReader("world")))
+	assert.Equal(t, "world", readAll(t, ExportBucket, "b.xlsx"))
+	_, err := ImportBucket.Get("../export/b.xlsx")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	assert.Equal(t, nil, ExportBucket.Delete("logs/a.xlsx"))
+	_, err = ExportBucket.Get("logs/a.xlsx")
+	assert.Equal(t, ErrObjectNotFound, err)
+}
+
+func TestSignedLink(t *testing.T) {
+	conf := config.ForTest()
+	conf.Storage.Backend = "local"
+	conf.Storage.LocalDir = t.TempDir()
+	assert.Equal(t, nil, Initial(conf))
+
+	link, err := url.Parse(SignedLink(ExportBucketName, "logs/log 1.xlsx"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "/storage/export/logs/log%201.xlsx", link.EscapedPath())
+
+	expires := link.Query().Get("expires")
+	signature := link.Query().Get("signature")
+	assert.Equal(t, true, VerifyLink(ExportBucketName, "logs/log 1.xlsx", expires, signature))
+	assert.Equal(t, false, VerifyLink(ImportBucketName, "logs/log 1.xlsx", expires, signature))
+	assert.Equal(t, false, VerifyLink(ExportBucketName, "logs/log 2.xlsx", expires, signature))
+	assert.Equal(t, false, VerifyLink(ExportBucketName, "logs/log 1.xlsx", "1", signature))
+	assert.Equal(t, false, VerifyLink(ExportBucketName, "logs/log 1.xlsx", "abc", signature))
+}
+
+func TestS3Bucket(t *testing.T) {
+	var mutex sync.Mutex
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), s3Algorithm+" Credential=id/") ||
+			r.Header.Get("x-amz-date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if sha256Hex(body) != r.Header.Get("x-amz-content-sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.EscapedPath()] = string(body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(data))
+		case http.MethodDelete:
+			delete(objects, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	conf := config.ForTest()
+	conf.Storage.Backend = "s3"
+	conf.Storage.S3.Endpoint = server.URL
+	conf.Storage.S3.AccessKeyID = "id"
+	conf.Storage.S3.AccessKeySecret = "secret"
+	assert.Equal(t, nil, Initial(conf))
+
+	assert.Equal(t, nil, ImportBucket.Put("imports/1/a b.xlsx", strings.NewReader("data")))
+	assert.Equal(t, "data", objects["/import-bucket/imports/1/a%20b.xlsx"])
+	assert.Equal(t, "data", readAll(t, ImportBucket, "imports/1/a b.xlsx"))
+
+	assert.Equal(t, nil, ImportBucket.Delete("imports/1/a b.xlsx"))
+	_, err := ImportBucket.Get("imports/1/a b.xlsx")
+	assert.Equal(t, ErrObjectNotFound, err)
+}
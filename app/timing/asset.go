@@ -2,11 +2,12 @@ package timing
 
 import (
 	"asset-management/app/dao"
+	"asset-management/app/define"
 	"asset-management/app/model"
+	"asset-management/app/service"
 	"log"
 	"time"
 
-	"github.com/shopspring/decimal"
 	"github.com/thoas/go-funk"
 	"gorm.io/gorm"
 )
@@ -16,53 +17,45 @@ const (
 )
 
 type AssetDepreciate struct {
+	assetDao dao.AssetDaoInterface
+	uow      dao.UnitOfWork
+}
+
+func NewAssetDepreciate(assetDao dao.AssetDaoInterface, uow dao.UnitOfWork) *AssetDepreciate {
+	return &AssetDepreciate{
+		assetDao: assetDao,
+		uow:      uow,
+	}
 }
 
 func (depreciate *AssetDepreciate) Run() {
-	count, err := dao.AssetDao.AssetCount()
+	count, err := depreciate.assetDao.AssetCount()
 	log.Println(count)
-	// assetList, err := dao.AssetDao.GetAllAssets()
+	// assetList, err := depreciate.assetDao.GetAllAssets()
 
 	if err == nil {
 		var i int64
 		for i = 0; i < count; i += SIZE_PER_BATCH {
-			assetList, err := dao.AssetDao.GetAllAssets(int(i), SIZE_PER_BATCH)
+			assetList, err := depreciate.assetDao.GetAllAssets(int(i), SIZE_PER_BATCH)
 			if err == nil {
 				for _, asset := range assetList {
-					if asset.State < 3 && asset.Expire != 0 {
+					if asset.State < define.ASSET_RETIRED && asset.Expire != 0 {
 						// log.Println(asset)
 						interval := getDiffDays(time.Time(*asset.CreatedAt), time.Now())
 						if interval >= int(asset.Expire) {
-							err = dao.AssetDao.Update(asset.ID, map[string]interface{}{
-								"net_worth": decimal.Zero,
-								"state":     3,
-								"parent_id": gorm.Expr("NULL"),
+							err = depreciate.uow.Transaction(func(tx *dao.Daos) error {
+								return expireAsset(tx, asset)
 							})
 
 							if err != nil {
 								continue
 							}
-
-							subAssets, _, err := dao.AssetDao.GetSubAsset(asset.ID, -1, -1)
-							if err == nil {
-								subAssetIDs := funk.Map(subAssets, func(thisAsset *model.Asset) uint {
-									return thisAsset.ID
-								}).([]uint)
-
-								err := dao.AssetDao.AllUpdate(subAssetIDs, map[string]interface{}{
-									"parent_id": gorm.Expr("NULL"),
-								})
-
-								if err != nil {
-									continue
-								}
-							}
 						} else {
-							rate := 1.0 - float64(interval)/float64(asset.Expire)
-							asset.NetWorth = asset.Price.Mul(decimal.NewFromFloat(rate))
+							method, residualRate := service.AssetDepreciation(asset)
+							asset.NetWorth = service.DepreciatedNetWorth(asset, method, residualRate, interval)
 							asset.Warn = (int(asset.Expire) - interval) < int(asset.Threshold)
 
-							err = dao.AssetDao.Update(asset.ID, map[string]interface{}{
+							err = depreciate.assetDao.Update(asset.ID, map[string]interface{}{
 								"net_worth": asset.NetWorth,
 								"warn":      asset.Warn,
 							})
@@ -86,11 +79,51 @@ func (depreciate *AssetDepreciate) Run() {
 	}
 }
 
+/*
+Retire the asset at its residual value and detach its sub assets, all or nothing.
+Needs model.Asset.Class loaded
+*/
+func expireAsset(tx *dao.Daos, asset *model.Asset) error {
+	assetID := asset.ID
+	err := service.ApplyAssetTransition(tx, []uint{assetID}, service.AssetStateChange{
+		Event:  define.ASSET_RETIRE,
+		Reason: define.ASSET_EXPIRED_REASON,
+	})
+	if err != nil {
+		return err
+	}
+	method, residualRate := service.AssetDepreciation(asset)
+	err = tx.Asset.Update(assetID, map[string]interface{}{
+		"parent_id": gorm.Expr("NULL"),
+		"net_worth": service.DepreciatedNetWorth(asset, method, residualRate, int(asset.Expire)),
+	})
+	if err != nil {
+		return err
+	}
+
+	subAssets, _, err := tx.Asset.GetSubAsset(assetID, -1, -1)
+	if err != nil {
+		return err
+	}
+	subAssetIDs := funk.Map(subAssets, func(thisAsset *model.Asset) uint {
+		return thisAsset.ID
+	}).([]uint)
+
+	return tx.Asset.AllUpdate(subAssetIDs, map[string]interface{}{
+		"parent_id": gorm.Expr("NULL"),
+	})
+}
+
 type AssetStat struct {
+	statDao dao.StatDaoInterface
 }
 
-func (stat *AssetStat) Run() {
-	stats, err := dao.StatDao.GetAllAssetStat()
+func NewAssetStat(statDao dao.StatDaoInterface) *AssetStat {
+	return &AssetStat{statDao: statDao}
+}
+
+func (job *AssetStat) Run() {
+	stats, err := job.statDao.GetAllAssetStat()
 	if err != nil {
 		log.Println("AssetStat Failed")
 		return
@@ -102,7 +135,7 @@ func (stat *AssetStat) Run() {
 		stat.Time = model.ModelTime(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, timezone))
 	}
 
-	err = dao.StatDao.CreateAssetStats(stats)
+	err = job.statDao.CreateAssetStats(stats)
 	if err != nil {
 		log.Println("AssetStat Failed")
 		return
@@ -111,6 +144,26 @@ func (stat *AssetStat) Run() {
 	log.Println("AssetStat Succeed")
 }
 
+/*
+Archive and remove assets whose time in the recycle bin is up
+*/
+type AssetPurge struct {
+	assetRecycleService service.AssetRecycleServiceInterface
+}
+
+func NewAssetPurge(assetRecycleService service.AssetRecycleServiceInterface) *AssetPurge {
+	return &AssetPurge{assetRecycleService: assetRecycleService}
+}
+
+func (job *AssetPurge) Run() {
+	purged, err := job.assetRecycleService.PurgeExpired()
+	if err != nil {
+		log.Println("AssetPurge Failed:", err)
+		return
+	}
+	log.Println("AssetPurge Succeed:", purged, "purged")
+}
+
 func getDiffDays(t1, t2 time.Time) int {
 	timezone, _ := time.LoadLocation("Asia/Shanghai")
 	timeDay1 := time.Date(t1.Year(), t1.Month(), t1.Day(), 0, 0, 0, 0, timezone)
@@ -2,16 +2,16 @@ package timing
 
 import (
 	"asset-management/app/dao"
+	"asset-management/app/define"
 	"asset-management/app/model"
 	"asset-management/app/service"
+	"asset-management/app/storage"
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"time"
 
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/shopspring/decimal"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/datatypes"
@@ -21,7 +21,7 @@ const (
 	GET_ASYNC_TASK_FAILED                  = "something wrong happened when get async task in pending"
 	ASYNC_TASK_USER_NOT_FOUND              = "async task's launcher isn't exist"
 	ASYNC_TASK_LAUNCHER_PERMISSION_DENIED  = "async task's launcher has no right to execute the task"
-	ACCESS_TO_OSS_FAILED                   = "get access to oss failed"
+	ACCESS_TO_STORAGE_FAILED               = "get access to storage failed"
 	GET_IMPORT_FILE_FAILED                 = "cannot download import file"
 	FILE_MUST_BE_XLSX                      = "import file must be a valid excel file"
 	READ_FILE_FAILED                       = "failed to read file, please check if file is valid or retry later"
@@ -40,22 +40,15 @@ const (
 	IMPORT_ASSET_SUCCESS                   = "Successfully import assets!"
 	GET_LOG_FAILED                         = "failed to get logs"
 	CREATE_LOG_EXCEL_FILE_ERROR            = "fail to create log excel file"
-	UPLOAD_TO_OSS_FAILED                   = "fail to upload log file to oss"
+	UPLOAD_TO_STORAGE_FAILED               = "fail to upload log file to storage"
 	EXPORT_ASSET_SUCCESS                   = "Successfully export logs!"
 	ASYNC_TASK_SUCCESS                     = "Successfully finish async task!"
 )
 
 const (
-	endpoint             = "https://oss-cn-beijing.aliyuncs.com"
-	downloadLinkPrefix   = "https://export-bucket-1.oss-cn-beijing.aliyuncs.com/"
-	myAccessKeyId        = "LTAI5tCpT5SSksUNe355TY8V"
-	myAccessKeySecret    = "WgkwIjagXCfiu0ykLmrZu1bcXQswV5"
-	importBucketName     = "import-bucket"
-	exportBucketName     = "export-bucket-1"
-	exportSheetName      = "log"
-	TEMP_LOG_FILE_FORMAT = "/var/tmp/export_log_%d.xlsx"
-	OSS_LOG_FILE_FORMAT  = "logs/log_%d_%s.xlsx"
-	TIME_FORMAT          = "2006-01-02_15-04-05"
+	exportSheetName     = "log"
+	OSS_LOG_FILE_FORMAT = "logs/log_%d_%s.xlsx"
+	TIME_FORMAT         = "2006-01-02_15-04-05"
 )
 
 var (
@@ -64,33 +57,35 @@ var (
 	cellIndexFormatList = []string{"A%d", "B%d", "C%d", "D%d", "E%d", "F%d", "G%d", "H%d", "I%d"}
 )
 
-func getClient() (*oss.Client, error) {
-	return oss.New(endpoint, myAccessKeyId, myAccessKeySecret)
+type GetPendingAsyncTask struct {
+	assetDao          dao.AssetDaoInterface
+	asyncDao          dao.AsyncDaoInterface
+	logDao            dao.LogDaoInterface
+	userDao           dao.UserDaoInterface
+	assetClassService service.AssetClassServiceInterface
+	permissionService service.PermissionServiceInterface
 }
 
-func getImportBucket() (*oss.Bucket, error) {
-	client, err := getClient()
-	if err != nil {
-		return nil, err
+func NewGetPendingAsyncTask(
+	assetDao dao.AssetDaoInterface,
+	asyncDao dao.AsyncDaoInterface,
+	logDao dao.LogDaoInterface,
+	userDao dao.UserDaoInterface,
+	assetClassService service.AssetClassServiceInterface,
+	permissionService service.PermissionServiceInterface,
+) *GetPendingAsyncTask {
+	return &GetPendingAsyncTask{
+		assetDao:          assetDao,
+		asyncDao:          asyncDao,
+		logDao:            logDao,
+		userDao:           userDao,
+		assetClassService: assetClassService,
+		permissionService: permissionService,
 	}
-
-	return client.Bucket(importBucketName)
 }
 
-func getExportBucket() (*oss.Bucket, error) {
-	client, err := getClient()
-	if err != nil {
-		return nil, err
-	}
-
-	return client.Bucket(exportBucketName)
-}
-
-type GetPendingAsyncTask struct {
-}
-
-func (task *GetPendingAsyncTask) Run() {
-	asyncTask, err := dao.AsyncDao.GetPendingTask()
+func (job *GetPendingAsyncTask) Run() {
+	asyncTask, err := job.asyncDao.GetPendingTask()
 	if err != nil {
 		log.Println(GET_ASYNC_TASK_FAILED)
 		return
@@ -98,12 +93,12 @@ func (task *GetPendingAsyncTask) Run() {
 		return
 	}
 
-	thisUser, err := dao.UserDao.GetUserByID(asyncTask.UserID)
+	thisUser, err := job.userDao.GetUserByID(asyncTask.UserID)
 	if err != nil {
 		log.Println(err.Error())
 		return
 	} else if thisUser == nil {
-		err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+		err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 			"state":   3,
 			"message": ASYNC_TASK_USER_NOT_FOUND,
 		})
@@ -113,7 +108,7 @@ func (task *GetPendingAsyncTask) Run() {
 		return
 	}
 
-	err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+	err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 		"state": 1,
 	})
 	if err != nil {
@@ -121,9 +116,27 @@ func (task *GetPendingAsyncTask) Run() {
 		return
 	}
 
+	// the launcher may have lost the permission since the task was created
+	var allowed bool
 	if asyncTask.Type == 0 {
-		if !thisUser.DepartmentSuper || thisUser.DepartmentID != asyncTask.DepartmentID {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+		allowed, err = job.permissionService.Can(service.UserBasicInfo(thisUser), define.PERM_ASSET_IMPORT, asyncTask.EntityID, asyncTask.DepartmentID)
+	} else {
+		allowed, err = job.permissionService.Can(service.UserBasicInfo(thisUser), define.PERM_LOG_EXPORT, asyncTask.EntityID, 0)
+	}
+	if err != nil {
+		err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+			"state":   3,
+			"message": err.Error(),
+		})
+		if err != nil {
+			log.Println(err.Error())
+		}
+		return
+	}
+
+	if asyncTask.Type == 0 {
+		if !allowed {
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 				"state":   3,
 				"message": ASYNC_TASK_LAUNCHER_PERMISSION_DENIED,
 			})
@@ -133,9 +146,9 @@ func (task *GetPendingAsyncTask) Run() {
 			return
 		}
 
-		err = ImportAssets(asyncTask)
+		err = job.ImportAssets(asyncTask)
 		if err != nil {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 				"state":   3,
 				"message": err.Error(),
 			})
@@ -144,7 +157,7 @@ func (task *GetPendingAsyncTask) Run() {
 				return
 			}
 		} else {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 				"state":   2,
 				"message": IMPORT_ASSET_SUCCESS,
 			})
@@ -154,8 +167,8 @@ func (task *GetPendingAsyncTask) Run() {
 			}
 		}
 	} else {
-		if !thisUser.EntitySuper || thisUser.EntityID != asyncTask.EntityID {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+		if !allowed {
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 				"state":   3,
 				"message": ASYNC_TASK_LAUNCHER_PERMISSION_DENIED,
 			})
@@ -165,9 +178,9 @@ func (task *GetPendingAsyncTask) Run() {
 			return
 		}
 
-		err = ExportLogs(asyncTask)
+		err = job.ExportLogs(asyncTask)
 		if err != nil {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
 				"state":   3,
 				"message": err.Error(),
 			})
@@ -176,10 +189,10 @@ func (task *GetPendingAsyncTask) Run() {
 				return
 			}
 		} else {
-			err = dao.AsyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
-				"state":         2,
-				"message":       EXPORT_ASSET_SUCCESS,
-				"download_link": asyncTask.DownloadLink,
+			err = job.asyncDao.ModifyAsyncTaskInfo(asyncTask.ID, map[string]interface{}{
+				"state":      2,
+				"message":    EXPORT_ASSET_SUCCESS,
+				"object_key": asyncTask.ObjectKey,
 			})
 			if err != nil {
 				log.Println(err.Error())
@@ -190,16 +203,16 @@ func (task *GetPendingAsyncTask) Run() {
 	log.Println(ASYNC_TASK_SUCCESS)
 }
 
-func ExportLogs(task *model.AsyncTask) error {
+func (job *GetPendingAsyncTask) ExportLogs(task *model.AsyncTask) error {
 	var logList []*model.Log
 	var err error
 	if task.Type == 1 {
-		logList, err = dao.LogDao.GetLoginLogsForExport(task.EntityID, task.FromTime, task.LogType)
+		logList, err = job.logDao.GetLoginLogsForExport(task.EntityID, task.FromTime, task.LogType)
 		if err != nil {
 			return errors.New(GET_LOG_FAILED)
 		}
 	} else {
-		logList, err = dao.LogDao.GetDataLogsForExport(task.EntityID, task.FromTime, task.LogType)
+		logList, err = job.logDao.GetDataLogsForExport(task.EntityID, task.FromTime, task.LogType)
 		if err != nil {
 			return errors.New(GET_LOG_FAILED)
 		}
@@ -249,51 +262,31 @@ func ExportLogs(task *model.AsyncTask) error {
 				int(thisLog.UserID), thisLog.Username, thisLog.Time.String()})
 	}
 
-	tempLogFilePath := fmt.Sprintf(TEMP_LOG_FILE_FORMAT, task.ID)
-	err = exportFile.SaveAs(tempLogFilePath)
-	defer func() {
-		isExist := false
-		_, err := os.Stat(tempLogFilePath)
-		if err == nil {
-			isExist = true
-		} else {
-			isExist = !os.IsNotExist(err)
-		}
-
-		if isExist {
-			err := os.Remove(tempLogFilePath)
-			if err != nil {
-				log.Println(err.Error())
-				return
-			}
-		}
-	}()
+	buffer, err := exportFile.WriteToBuffer()
 	if err != nil {
 		return errors.New(CREATE_LOG_EXCEL_FILE_ERROR)
 	}
 
-	exportBucket, err := getExportBucket()
-	if err != nil {
-		return errors.New(ACCESS_TO_OSS_FAILED)
+	if storage.ExportBucket == nil {
+		return errors.New(ACCESS_TO_STORAGE_FAILED)
 	}
 
 	objectKey := fmt.Sprintf(OSS_LOG_FILE_FORMAT, task.ID, time.Now().Format(TIME_FORMAT))
-	err = exportBucket.PutObjectFromFile(objectKey, tempLogFilePath)
+	err = storage.ExportBucket.Put(objectKey, buffer)
 	if err != nil {
-		return errors.New(UPLOAD_TO_OSS_FAILED)
+		return errors.New(UPLOAD_TO_STORAGE_FAILED)
 	}
 
-	task.DownloadLink = downloadLinkPrefix + objectKey
+	task.ObjectKey = objectKey
 	return nil
 }
 
-func ImportAssets(task *model.AsyncTask) error {
-	importBucket, err := getImportBucket()
-	if err != nil {
-		return errors.New(ACCESS_TO_OSS_FAILED)
+func (job *GetPendingAsyncTask) ImportAssets(task *model.AsyncTask) error {
+	if storage.ImportBucket == nil {
+		return errors.New(ACCESS_TO_STORAGE_FAILED)
 	}
 
-	importFileReader, err := importBucket.GetObject(task.ObjectKey)
+	importFileReader, err := storage.ImportBucket.Get(task.ObjectKey)
 	if err != nil {
 		return errors.New(GET_IMPORT_FILE_FAILED)
 	}
@@ -340,7 +333,7 @@ func ImportAssets(task *model.AsyncTask) error {
 		return errors.New(READ_FILE_FAILED)
 	}
 
-	err = checkColsValueValid(cols, task.DepartmentID)
+	err = job.checkColsValueValid(cols, task.DepartmentID)
 	if err != nil {
 		return err
 	}
@@ -356,7 +349,7 @@ func ImportAssets(task *model.AsyncTask) error {
 		if err != nil {
 			return fmt.Errorf(PARSE_ASSET_FAILED_FORMAT, lineIndex)
 		}
-		err = dao.AssetDao.Create(*thisAsset)
+		err = job.assetDao.Create(*thisAsset)
 		if err != nil {
 			return fmt.Errorf(INSERT_ASSET_FAILED_FORMAT, lineIndex)
 		}
@@ -375,8 +368,8 @@ func parseRowGetAssetInfo(row []string, departmentID uint, userID uint) (*model.
 	assetName := row[0]
 	assetPrice, _ := decimal.NewFromString(row[1])
 	assetClassID, _ := strconv.ParseUint(row[2], 10, 64)
-	assetType := 1
-	assetCount := 1
+	assetType, _ := strconv.Atoi(row[3])
+	assetCount, _ := strconv.Atoi(row[4])
 	assetExpire, _ := strconv.ParseUint(row[5], 10, 64)
 	assetThreshold, _ := strconv.ParseUint(row[6], 10, 64)
 	assetDescription := ""
@@ -404,7 +397,7 @@ func parseRowGetAssetInfo(row []string, departmentID uint, userID uint) (*model.
 	}, nil
 }
 
-func checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
+func (job *GetPendingAsyncTask) checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
 	// Check field Name: cannot be empty
 	if !cols.Next() {
 		return errors.New(READ_FILE_FAILED)
@@ -464,7 +457,7 @@ func checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
 			return fmt.Errorf(FIELD_CLASSID_ERROR_FORMAT, k+2)
 		}
 
-		thisClass, err := service.AssetClassService.GetAssetClassByID(uint(classID))
+		thisClass, err := job.assetClassService.GetAssetClassByID(uint(classID))
 		if err != nil {
 			return fmt.Errorf(FIELD_CLASSID_ERROR_FORMAT, k+2)
 		} else if thisClass == nil {
@@ -474,7 +467,7 @@ func checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
 		}
 	}
 
-	// Check field Type, Count: int, can only value 1 so far
+	// Check field Type, Count: int, an item-type asset counts 1, a quantity-type one any positive count
 	if !cols.Next() {
 		return errors.New(READ_FILE_FAILED)
 	}
@@ -483,8 +476,9 @@ func checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
 		return errors.New(READ_FILE_FAILED)
 	}
 
+	typeCol := col
 	for k, assetTypeStr := range col[1:] {
-		if assetTypeStr != "1" {
+		if assetTypeStr != "1" && assetTypeStr != "2" {
 			return fmt.Errorf(FIELD_TYPE_ERROR_FORMAT, k+2)
 		}
 	}
@@ -498,7 +492,11 @@ func checkColsValueValid(cols *excelize.Cols, departmentID uint) error {
 	}
 
 	for k, assetCountStr := range col[1:] {
-		if assetCountStr != "1" {
+		assetCount, err := strconv.ParseUint(assetCountStr, 10, 31)
+		if err != nil || assetCount == 0 {
+			return fmt.Errorf(FIELD_COUNT_ERROR_FORMAT, k+2)
+		}
+		if k+1 < len(typeCol) && typeCol[k+1] == "1" && assetCount != 1 {
 			return fmt.Errorf(FIELD_COUNT_ERROR_FORMAT, k+2)
 		}
 	}
@@ -0,0 +1,47 @@
+package timing
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/model"
+	"asset-management/app/service"
+	"asset-management/app/storage"
+	"asset-management/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportLogsToLocalStorage(t *testing.T) {
+	conf := config.ForTest()
+	daos := dao.NewDaos(dao.InitForTest())
+	services := service.NewServices(conf, daos)
+	conf.Storage.Backend = "local"
+	conf.Storage.LocalDir = t.TempDir()
+	assert.Equal(t, nil, storage.Initial(conf))
+
+	assert.Equal(t, nil, daos.Entity.Create(model.Entity{Name: "async_entity"}))
+	entity, err := daos.Entity.GetEntityByName("async_entity")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, daos.User.Create(model.User{UserName: "async_user", Password: "x", EntityID: entity.ID, EntitySuper: true}))
+	user, err := daos.User.GetUserByName("async_user")
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, nil, daos.Async.CreateAsyncTask(model.AsyncTask{Type: 1, UserID: user.ID, EntityID: entity.ID}))
+	NewGetPendingAsyncTask(daos.Asset, daos.Async, daos.Log, daos.User, services.AssetClass, services.Permission).Run()
+
+	tasks, err := daos.Async.GetAsyncTaskListByUserID(user.ID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, uint(2), tasks[0].State)
+	assert.NotEqual(t, "", tasks[0].ObjectKey)
+
+	reader, err := storage.ExportBucket.Get(tasks[0].ObjectKey)
+	assert.Equal(t, nil, err)
+	defer reader.Close()
+	file, err := excelize.OpenReader(reader)
+	assert.Equal(t, nil, err)
+	header, err := file.GetCellValue(exportSheetName, "A1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, exportFileFiledList[0], header)
+}
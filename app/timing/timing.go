@@ -1,6 +1,9 @@
 package timing
 
 import (
+	"asset-management/app/dao"
+	"asset-management/app/service"
+	"asset-management/config"
 	"log"
 	"time"
 
@@ -10,10 +13,33 @@ import (
 var timezone *time.Location
 
 /*
-This package is for timing task: asset depreciate and statistics
+Job names accepted by run-job, same jobs the scheduler runs
 */
-func Init() *cron.Cron {
-	timezone, _ = time.LoadLocation("Asia/Shanghai")
+const (
+	JOB_DEPRECIATE = "depreciate"
+	JOB_STAT       = "stat"
+	JOB_ASYNC      = "async"
+	JOB_PURGE      = "purge"
+)
+
+/*
+Build every timing job by name, so the scheduler and the command line share them
+*/
+func NewJobs(conf *config.Config, daos *dao.Daos, services *service.Services) map[string]cron.Job {
+	timezone, _ = time.LoadLocation(conf.Server.Timezone)
+	return map[string]cron.Job{
+		JOB_DEPRECIATE: NewAssetDepreciate(daos.Asset, daos),
+		JOB_STAT:       NewAssetStat(daos.Stat),
+		JOB_ASYNC:      NewGetPendingAsyncTask(daos.Asset, daos.Async, daos.Log, daos.User, services.AssetClass, services.Permission),
+		JOB_PURGE:      NewAssetPurge(services.AssetRecycle),
+	}
+}
+
+/*
+This package is for timing task: asset depreciate, statistics and recycle bin purge
+*/
+func Init(conf *config.Config, daos *dao.Daos, services *service.Services) *cron.Cron {
+	jobs := NewJobs(conf, daos, services)
 	c := cron.New(cron.WithLocation(timezone))
 
 	// _, _ = c.AddFunc("@every 1s", func() {
@@ -22,7 +48,7 @@ func Init() *cron.Cron {
 
 	_, err := c.AddJob(
 		"0 3 * * *",
-		cron.NewChain(cron.Recover(cron.DefaultLogger)).Then(&AssetDepreciate{}),
+		cron.NewChain(cron.Recover(cron.DefaultLogger)).Then(jobs[JOB_DEPRECIATE]),
 	)
 
 	if err != nil {
@@ -31,7 +57,16 @@ func Init() *cron.Cron {
 
 	_, err = c.AddJob(
 		"0 4 * * *",
-		cron.NewChain(cron.Recover(cron.DefaultLogger)).Then(&AssetStat{}),
+		cron.NewChain(cron.Recover(cron.DefaultLogger)).Then(jobs[JOB_STAT]),
+	)
+
+	if err != nil {
+		log.Println("Something error when register daily job")
+	}
+
+	_, err = c.AddJob(
+		"0 5 * * *",
+		cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger), cron.Recover(cron.DefaultLogger)).Then(jobs[JOB_PURGE]),
 	)
 
 	if err != nil {
@@ -40,7 +75,7 @@ func Init() *cron.Cron {
 
 	_, err = c.AddJob(
 		"@every 10s",
-		cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger), cron.Recover(cron.DefaultLogger)).Then(&GetPendingAsyncTask{}),
+		cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger), cron.Recover(cron.DefaultLogger)).Then(jobs[JOB_ASYNC]),
 	)
 
 	if err != nil {
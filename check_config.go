@@ -0,0 +1,27 @@
+package main
+
+import (
+	"asset-management/config"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+check-config reports every problem at once and exits non-zero if there is any
+*/
+func runCheckConfig(conf *config.Config, err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config is invalid:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "  %s\n", line)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("config is valid")
+	fmt.Printf("  profile   %s\n", conf.Server.Profile)
+	fmt.Printf("  addr      %s\n", conf.Server.Addr)
+	fmt.Printf("  database  %s\n", conf.Database.Driver)
+	fmt.Printf("  storage   %s\n", conf.Storage.Backend)
+}
@@ -0,0 +1,399 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+Deployment profiles, chosen by the RELEASE / DEBUG environment variables
+exactly as the hard-coded settings used to be
+*/
+const (
+	ProfileLocal   = "local"
+	ProfileDev     = "dev"
+	ProfileRelease = "release"
+)
+
+const DefaultConfigFile = "config.json"
+
+type ServerConfig struct {
+	Profile   string `json:"profile"`
+	Mode      string `json:"mode"`
+	Addr      string `json:"addr"`
+	Timezone  string `json:"timezone"`
+	PublicURL string `json:"public_url"` // base url of this backend, used in links we hand out
+}
+
+type DatabaseConfig struct {
+	Driver      string `json:"driver"`
+	DSN         string `json:"dsn"`
+	AutoMigrate bool   `json:"auto_migrate"` // dev only, sync tables with models instead of running migrations
+}
+
+/*
+PasswordSalt is only used to check legacy md5 hashes, they are rejected
+from LegacyPasswordCutoff on (a 2006-01-02 date in server.timezone, empty means never).
+After LoginMaxFailures failed logins a username or client ip is locked, the lock
+starts at LoginLockoutSeconds and doubles on every further lockout up to LoginLockoutMaxSeconds.
+PasswordMinLength applies wherever an entity has no password policy of its own.
+A password reset token lasts PasswordResetMinutes, a user and a client ip may ask
+for at most PasswordResetHourlyLimit of them an hour
+*/
+type SecurityConfig struct {
+	JWTSecret                string `json:"jwt_secret"`
+	PasswordSalt             string `json:"password_salt"`
+	LegacyPasswordCutoff     string `json:"legacy_password_cutoff"`
+	LoginMaxFailures         int64  `json:"login_max_failures"`
+	LoginLockoutSeconds      int64  `json:"login_lockout_seconds"`
+	LoginLockoutMaxSeconds   int64  `json:"login_lockout_max_seconds"`
+	PasswordMinLength        int64  `json:"password_min_length"`
+	PasswordResetMinutes     int64  `json:"password_reset_minutes"`
+	PasswordResetHourlyLimit int64  `json:"password_reset_hourly_limit"`
+}
+
+const cutoffLayout = "2006-01-02"
+
+/*
+Zero time when no cutoff is configured
+*/
+func (security SecurityConfig) LegacyPasswordCutoffTime(loc *time.Location) (time.Time, error) {
+	if security.LegacyPasswordCutoff == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(cutoffLayout, security.LegacyPasswordCutoff, loc)
+}
+
+type OSSConfig struct {
+	Endpoint        string `json:"endpoint"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	ImportBucket    string `json:"import_bucket"`
+	ExportBucket    string `json:"export_bucket"`
+}
+
+type S3Config struct {
+	Endpoint        string `json:"endpoint"` // scheme and host, e.g. http://minio:9000, path-style requests
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	ImportBucket    string `json:"import_bucket"`
+	ExportBucket    string `json:"export_bucket"`
+}
+
+/*
+Backend is one of oss, s3, local; downloads always go through signed backend links
+*/
+type StorageConfig struct {
+	Backend           string   `json:"backend"`
+	LocalDir          string   `json:"local_dir"`
+	S3                S3Config `json:"s3"`
+	LinkSecret        string   `json:"link_secret"`
+	LinkExpireSeconds int64    `json:"link_expire_seconds"`
+}
+
+type STSConfig struct {
+	Endpoint        string `json:"endpoint"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	RoleArn         string `json:"role_arn"`
+	RoleSessionName string `json:"role_session_name"`
+	DurationSeconds int64  `json:"duration_seconds"`
+}
+
+type FeishuConfig struct {
+	AppID         string `json:"app_id"`
+	AppSecret     string `json:"app_secret"`
+	CallbackURL   string `json:"callback_url"`
+	CallbackToken string `json:"callback_token"`
+	FrontendURL   string `json:"frontend_url"`
+}
+
+/*
+Outgoing SMTP mail, an empty host disables mail
+*/
+type MailConfig struct {
+	Host     string `json:"host"`
+	Port     int64  `json:"port"`
+	Username string `json:"username"` // empty skips authentication, e.g. for a local catcher
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+/*
+A deleted asset stays in its department's recycle bin for RecycleRetentionDays,
+then the purge job archives it and removes it for good
+*/
+type AssetConfig struct {
+	RecycleRetentionDays int64 `json:"recycle_retention_days"`
+}
+
+type Config struct {
+	Server   ServerConfig   `json:"server"`
+	Database DatabaseConfig `json:"database"`
+	Security SecurityConfig `json:"security"`
+	OSS      OSSConfig      `json:"oss"`
+	Storage  StorageConfig  `json:"storage"`
+	STS      STSConfig      `json:"sts"`
+	Feishu   FeishuConfig   `json:"feishu"`
+	Mail     MailConfig     `json:"mail"`
+	Asset    AssetConfig    `json:"asset"`
+}
+
+/*
+Detect profile from environment, RELEASE wins over DEBUG
+*/
+func ProfileFromEnv() string {
+	if profile := os.Getenv("PROFILE"); profile != "" {
+		return profile
+	}
+	if os.Getenv("RELEASE") != "" {
+		return ProfileRelease
+	}
+	if os.Getenv("DEBUG") != "" {
+		return ProfileDev
+	}
+	return ProfileLocal
+}
+
+/*
+Load config: profile defaults, then the json file at path (skipped if path is empty
+or the default file does not exist), then environment overrides, then validate
+*/
+func Load(path string) (*Config, error) {
+	conf := Default(ProfileFromEnv())
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultConfigFile
+	}
+	if err := conf.loadFile(path, explicit); err != nil {
+		return nil, err
+	}
+
+	if err := conf.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (conf *Config) loadFile(path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read config file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+/*
+Environment overrides, e.g. AM_DATABASE_DSN, AM_OSS_ACCESS_KEY_SECRET
+*/
+func (conf *Config) envStrings() map[string]*string {
+	return map[string]*string{
+		"AM_SERVER_MODE":                     &conf.Server.Mode,
+		"AM_SERVER_ADDR":                     &conf.Server.Addr,
+		"AM_SERVER_TIMEZONE":                 &conf.Server.Timezone,
+		"AM_SERVER_PUBLIC_URL":               &conf.Server.PublicURL,
+		"AM_DATABASE_DRIVER":                 &conf.Database.Driver,
+		"AM_DATABASE_DSN":                    &conf.Database.DSN,
+		"AM_SECURITY_JWT_SECRET":             &conf.Security.JWTSecret,
+		"AM_SECURITY_PASSWORD_SALT":          &conf.Security.PasswordSalt,
+		"AM_SECURITY_LEGACY_PASSWORD_CUTOFF": &conf.Security.LegacyPasswordCutoff,
+		"AM_OSS_ENDPOINT":                    &conf.OSS.Endpoint,
+		"AM_OSS_ACCESS_KEY_ID":               &conf.OSS.AccessKeyID,
+		"AM_OSS_ACCESS_KEY_SECRET":           &conf.OSS.AccessKeySecret,
+		"AM_OSS_IMPORT_BUCKET":               &conf.OSS.ImportBucket,
+		"AM_OSS_EXPORT_BUCKET":               &conf.OSS.ExportBucket,
+		"AM_STORAGE_BACKEND":                 &conf.Storage.Backend,
+		"AM_STORAGE_LOCAL_DIR":               &conf.Storage.LocalDir,
+		"AM_STORAGE_LINK_SECRET":             &conf.Storage.LinkSecret,
+		"AM_S3_ENDPOINT":                     &conf.Storage.S3.Endpoint,
+		"AM_S3_REGION":                       &conf.Storage.S3.Region,
+		"AM_S3_ACCESS_KEY_ID":                &conf.Storage.S3.AccessKeyID,
+		"AM_S3_ACCESS_KEY_SECRET":            &conf.Storage.S3.AccessKeySecret,
+		"AM_S3_IMPORT_BUCKET":                &conf.Storage.S3.ImportBucket,
+		"AM_S3_EXPORT_BUCKET":                &conf.Storage.S3.ExportBucket,
+		"AM_STS_ENDPOINT":                    &conf.STS.Endpoint,
+		"AM_STS_ACCESS_KEY_ID":               &conf.STS.AccessKeyID,
+		"AM_STS_ACCESS_KEY_SECRET":           &conf.STS.AccessKeySecret,
+		"AM_STS_ROLE_ARN":                    &conf.STS.RoleArn,
+		"AM_STS_ROLE_SESSION_NAME":           &conf.STS.RoleSessionName,
+		"AM_FEISHU_APP_ID":                   &conf.Feishu.AppID,
+		"AM_FEISHU_APP_SECRET":               &conf.Feishu.AppSecret,
+		"AM_FEISHU_CALLBACK_URL":             &conf.Feishu.CallbackURL,
+		"AM_FEISHU_CALLBACK_TOKEN":           &conf.Feishu.CallbackToken,
+		"AM_FEISHU_FRONTEND_URL":             &conf.Feishu.FrontendURL,
+		"AM_MAIL_HOST":                       &conf.Mail.Host,
+		"AM_MAIL_USERNAME":                   &conf.Mail.Username,
+		"AM_MAIL_PASSWORD":                   &conf.Mail.Password,
+		"AM_MAIL_FROM":                       &conf.Mail.From,
+	}
+}
+
+func (conf *Config) envInts() map[string]*int64 {
+	return map[string]*int64{
+		"AM_STS_DURATION_SECONDS":                 &conf.STS.DurationSeconds,
+		"AM_SECURITY_LOGIN_MAX_FAILURES":          &conf.Security.LoginMaxFailures,
+		"AM_SECURITY_LOGIN_LOCKOUT_SECONDS":       &conf.Security.LoginLockoutSeconds,
+		"AM_SECURITY_LOGIN_LOCKOUT_MAX_SECONDS":   &conf.Security.LoginLockoutMaxSeconds,
+		"AM_SECURITY_PASSWORD_MIN_LENGTH":         &conf.Security.PasswordMinLength,
+		"AM_SECURITY_PASSWORD_RESET_MINUTES":      &conf.Security.PasswordResetMinutes,
+		"AM_SECURITY_PASSWORD_RESET_HOURLY_LIMIT": &conf.Security.PasswordResetHourlyLimit,
+		"AM_MAIL_PORT":                            &conf.Mail.Port,
+		"AM_STORAGE_LINK_EXPIRE_SECONDS":          &conf.Storage.LinkExpireSeconds,
+		"AM_ASSET_RECYCLE_RETENTION_DAYS":         &conf.Asset.RecycleRetentionDays,
+	}
+}
+
+func (conf *Config) envBools() map[string]*bool {
+	return map[string]*bool{
+		"AM_DATABASE_AUTO_MIGRATE": &conf.Database.AutoMigrate,
+	}
+}
+
+func (conf *Config) applyEnv() error {
+	for key, field := range conf.envStrings() {
+		if value, ok := os.LookupEnv(key); ok {
+			*field = value
+		}
+	}
+	for key, field := range conf.envInts() {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("env %s: %w", key, err)
+			}
+			*field = parsed
+		}
+	}
+	for key, field := range conf.envBools() {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("env %s: %w", key, err)
+			}
+			*field = parsed
+		}
+	}
+	return nil
+}
+
+/*
+Check the config is complete and consistent, all problems are reported at once
+*/
+func (conf *Config) Validate() error {
+	var errs []error
+	require := func(name string, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	switch conf.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("server.mode %q must be one of debug, release, test", conf.Server.Mode))
+	}
+	require("server.addr", conf.Server.Addr)
+	require("server.public_url", conf.Server.PublicURL)
+	if _, err := time.LoadLocation(conf.Server.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("server.timezone: %w", err))
+	}
+
+	switch conf.Database.Driver {
+	case "mysql", "sqlite":
+	default:
+		errs = append(errs, fmt.Errorf("database.driver %q must be mysql or sqlite", conf.Database.Driver))
+	}
+	require("database.dsn", conf.Database.DSN)
+
+	require("security.jwt_secret", conf.Security.JWTSecret)
+	require("security.password_salt", conf.Security.PasswordSalt)
+	if _, err := conf.Security.LegacyPasswordCutoffTime(time.UTC); err != nil {
+		errs = append(errs, fmt.Errorf("security.legacy_password_cutoff: %w", err))
+	}
+	if conf.Security.LoginMaxFailures <= 0 {
+		errs = append(errs, errors.New("security.login_max_failures must be positive"))
+	}
+	if conf.Security.LoginLockoutSeconds <= 0 {
+		errs = append(errs, errors.New("security.login_lockout_seconds must be positive"))
+	}
+	if conf.Security.LoginLockoutMaxSeconds < conf.Security.LoginLockoutSeconds {
+		errs = append(errs, errors.New("security.login_lockout_max_seconds must not be less than security.login_lockout_seconds"))
+	}
+	if conf.Security.PasswordMinLength <= 0 {
+		errs = append(errs, errors.New("security.password_min_length must be positive"))
+	}
+	if conf.Security.PasswordResetMinutes <= 0 {
+		errs = append(errs, errors.New("security.password_reset_minutes must be positive"))
+	}
+	if conf.Security.PasswordResetHourlyLimit <= 0 {
+		errs = append(errs, errors.New("security.password_reset_hourly_limit must be positive"))
+	}
+
+	switch conf.Storage.Backend {
+	case "oss":
+		require("oss.endpoint", conf.OSS.Endpoint)
+		require("oss.access_key_id", conf.OSS.AccessKeyID)
+		require("oss.access_key_secret", conf.OSS.AccessKeySecret)
+		require("oss.import_bucket", conf.OSS.ImportBucket)
+		require("oss.export_bucket", conf.OSS.ExportBucket)
+	case "local":
+		require("storage.local_dir", conf.Storage.LocalDir)
+	case "s3":
+		require("storage.s3.endpoint", conf.Storage.S3.Endpoint)
+		require("storage.s3.region", conf.Storage.S3.Region)
+		require("storage.s3.access_key_id", conf.Storage.S3.AccessKeyID)
+		require("storage.s3.access_key_secret", conf.Storage.S3.AccessKeySecret)
+		require("storage.s3.import_bucket", conf.Storage.S3.ImportBucket)
+		require("storage.s3.export_bucket", conf.Storage.S3.ExportBucket)
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend %q must be one of oss, s3, local", conf.Storage.Backend))
+	}
+	require("storage.link_secret", conf.Storage.LinkSecret)
+	if conf.Storage.LinkExpireSeconds <= 0 {
+		errs = append(errs, errors.New("storage.link_expire_seconds must be positive"))
+	}
+
+	require("sts.endpoint", conf.STS.Endpoint)
+	require("sts.access_key_id", conf.STS.AccessKeyID)
+	require("sts.access_key_secret", conf.STS.AccessKeySecret)
+	require("sts.role_arn", conf.STS.RoleArn)
+	require("sts.role_session_name", conf.STS.RoleSessionName)
+	if conf.STS.DurationSeconds < 900 {
+		errs = append(errs, errors.New("sts.duration_seconds must be at least 900"))
+	}
+
+	require("feishu.app_id", conf.Feishu.AppID)
+	require("feishu.app_secret", conf.Feishu.AppSecret)
+	require("feishu.callback_url", conf.Feishu.CallbackURL)
+	require("feishu.callback_token", conf.Feishu.CallbackToken)
+	require("feishu.frontend_url", conf.Feishu.FrontendURL)
+
+	if conf.Mail.Host != "" {
+		if conf.Mail.Port <= 0 || conf.Mail.Port > 65535 {
+			errs = append(errs, errors.New("mail.port must be a valid port"))
+		}
+		require("mail.from", conf.Mail.From)
+	}
+
+	if conf.Asset.RecycleRetentionDays <= 0 {
+		errs = append(errs, errors.New("asset.recycle_retention_days must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	// no profile ships credentials, they have to be configured
+	for _, profile := range []string{ProfileLocal, ProfileDev, ProfileRelease} {
+		err := Default(profile).Validate()
+		assert.NotEqual(t, nil, err, profile)
+		for _, name := range []string{"database.dsn", "security.jwt_secret", "security.password_salt", "oss.access_key_secret",
+			"storage.link_secret", "sts.access_key_secret", "feishu.app_secret", "feishu.callback_token"} {
+			assert.Contains(t, err.Error(), name, profile)
+		}
+	}
+	assert.Equal(t, nil, ForTest().Validate())
+	assert.Equal(t, "release", Default(ProfileRelease).Server.Mode)
+	assert.Equal(t, "0.0.0.0:8080", Default(ProfileLocal).Server.Addr)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"database": {"driver": "sqlite", "dsn": "file::memory:"},
+		"server": {"addr": ":9000"},
+		"security": {"jwt_secret": "file-secret", "password_salt": "file-salt"},
+		"storage": {"backend": "local", "link_secret": "file-link-secret"},
+		"sts": {"access_key_id": "id", "access_key_secret": "secret", "role_arn": "arn", "role_session_name": "session"},
+		"feishu": {"app_id": "app", "app_secret": "secret"}
+	}`), 0644)
+	assert.Equal(t, nil, err)
+
+	// a secret missing from both the file and the environment fails the load
+	_, err = Load(path)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "feishu.callback_token")
+
+	t.Setenv("AM_FEISHU_CALLBACK_TOKEN", "env-token")
+	t.Setenv("AM_SECURITY_JWT_SECRET", "env-secret")
+	t.Setenv("AM_SERVER_ADDR", ":9001")
+	t.Setenv("AM_STS_DURATION_SECONDS", "1200")
+
+	conf, err := Load(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "sqlite", conf.Database.Driver)
+	assert.Equal(t, "file::memory:", conf.Database.DSN)
+	assert.Equal(t, ":9001", conf.Server.Addr)
+	assert.Equal(t, int64(1200), conf.STS.DurationSeconds)
+	assert.Equal(t, "env-secret", conf.Security.JWTSecret)
+	assert.Equal(t, "file-salt", conf.Security.PasswordSalt)
+	assert.Equal(t, "env-token", conf.Feishu.CallbackToken)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotEqual(t, nil, err)
+
+	t.Setenv("AM_STS_DURATION_SECONDS", "abc")
+	_, err = Load(path)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestValidate(t *testing.T) {
+	conf := ForTest()
+	conf.Server.Mode = "prod"
+	conf.Database.Driver = "postgres"
+	conf.Security.JWTSecret = ""
+	conf.Security.LegacyPasswordCutoff = "2023-13-01"
+	conf.Mail.Host = "localhost"
+	conf.Asset.RecycleRetentionDays = 0
+	err := conf.Validate()
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "server.mode")
+	assert.Contains(t, err.Error(), "database.driver")
+	assert.Contains(t, err.Error(), "security.jwt_secret")
+	assert.Contains(t, err.Error(), "security.legacy_password_cutoff")
+	assert.Contains(t, err.Error(), "mail.from")
+	assert.Contains(t, err.Error(), "asset.recycle_retention_days")
+}
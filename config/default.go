@@ -0,0 +1,87 @@
+package config
+
+/*
+Built-in defaults for everything but credentials, the database dsn, secrets and
+keys only ever come from the config file or the environment
+*/
+func Default(profile string) *Config {
+	conf := &Config{
+		Server: ServerConfig{
+			Profile:   profile,
+			Mode:      "debug",
+			Addr:      "0.0.0.0:8080",
+			Timezone:  "Asia/Shanghai",
+			PublicURL: "http://127.0.0.1:8080",
+		},
+		Database: DatabaseConfig{
+			Driver: "mysql",
+		},
+		Security: SecurityConfig{
+			LoginMaxFailures:         5,
+			LoginLockoutSeconds:      60,
+			LoginLockoutMaxSeconds:   3600,
+			PasswordMinLength:        6,
+			PasswordResetMinutes:     30,
+			PasswordResetHourlyLimit: 3,
+		},
+		OSS: OSSConfig{
+			Endpoint:     "https://oss-cn-beijing.aliyuncs.com",
+			ImportBucket: "import-bucket",
+			ExportBucket: "export-bucket-1",
+		},
+		Storage: StorageConfig{
+			Backend:           "oss",
+			LocalDir:          "storage",
+			S3:                S3Config{Region: "us-east-1", ImportBucket: "import-bucket", ExportBucket: "export-bucket"},
+			LinkExpireSeconds: 3600,
+		},
+		STS: STSConfig{
+			Endpoint:        "sts.cn-beijing.aliyuncs.com",
+			DurationSeconds: 900,
+		},
+		Feishu: FeishuConfig{
+			CallbackURL: "http://AssetManagement-Backend-dev-BinaryAbstract.app.secoder.net/user/feishu/callback",
+			FrontendURL: "http://assetmanagement-frontend-binaryabstract.app.secoder.net",
+		},
+		Mail: MailConfig{
+			Port: 25,
+		},
+		Asset: AssetConfig{
+			RecycleRetentionDays: 30,
+		},
+	}
+
+	switch profile {
+	case ProfileDev:
+		conf.Server.Addr = "0.0.0.0:80"
+		conf.Server.PublicURL = "http://AssetManagement-Backend-dev-BinaryAbstract.app.secoder.net"
+	case ProfileRelease:
+		conf.Server.Mode = "release"
+		conf.Server.Addr = "0.0.0.0:80"
+		conf.Server.PublicURL = "http://AssetManagement-Backend-BinaryAbstract.app.secoder.net"
+		conf.Feishu.CallbackURL = "http://AssetManagement-Backend-BinaryAbstract.app.secoder.net/user/feishu/callback"
+	}
+
+	return conf
+}
+
+/*
+Local defaults with throwaway secrets, an in-memory database and local storage, for tests only
+*/
+func ForTest() *Config {
+	conf := Default(ProfileLocal)
+	conf.Database.Driver = "sqlite"
+	conf.Database.DSN = "file::memory:"
+	conf.Security.JWTSecret = "test-jwt-secret"
+	conf.Security.PasswordSalt = "test-password-salt"
+	conf.Storage.Backend = "local"
+	conf.Storage.LinkSecret = "test-link-secret"
+	conf.STS.AccessKeyID = "test-sts-key"
+	conf.STS.AccessKeySecret = "test-sts-secret"
+	conf.STS.RoleArn = "acs:ram::0:role/test"
+	conf.STS.RoleSessionName = "test"
+	conf.Feishu.AppID = "test-app"
+	conf.Feishu.AppSecret = "test-app-secret"
+	conf.Feishu.CallbackToken = "test-callback-token"
+	return conf
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"asset-management/app/timing"
+	"asset-management/config"
+	"log"
+)
+
+/*
+run-job depreciate|stat|async|purge
+*/
+func runJob(conf *config.Config, args []string) {
+	if len(args) != 1 {
+		log.Fatalf("run-job needs exactly one job name: %s, %s, %s or %s", timing.JOB_DEPRECIATE, timing.JOB_STAT, timing.JOB_ASYNC, timing.JOB_PURGE)
+	}
+
+	daos, services := newContainer(conf)
+	job, ok := timing.NewJobs(conf, daos, services)[args[0]]
+	if !ok {
+		log.Fatalf("unknown job %q, expected %s, %s, %s or %s", args[0], timing.JOB_DEPRECIATE, timing.JOB_STAT, timing.JOB_ASYNC, timing.JOB_PURGE)
+	}
+	job.Run()
+}
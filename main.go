@@ -1,44 +1,81 @@
 package main
 
 import (
-	"asset-management/app/api"
-	"asset-management/app/timing"
-	"asset-management/middleware"
-	"asset-management/routers"
+	"asset-management/app/dao"
+	"asset-management/app/service"
+	"asset-management/app/storage"
+	"asset-management/config"
+	"asset-management/utils"
+	"fmt"
+	"log"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const usage = `usage: asset-management [command] [args]
+
+commands:
+  serve                               start the http server and timing jobs (default)
+  migrate [up | down [steps] | status]
+  create-superuser -username NAME [-password PASSWORD]
+  run-job depreciate|stat|async|purge run one timing job now
+  seed                                create a demo entity, departments and assets
+  check-config                        validate the configuration and exit
+`
+
 func main() {
-	timezone, _ := time.LoadLocation("Asia/Shanghai")
+	command := "serve"
+	var args []string
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	conf, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if command == "check-config" {
+		runCheckConfig(conf, err)
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	timezone, _ := time.LoadLocation(conf.Server.Timezone)
 	time.Local = timezone
-	c := timing.Init()
-	c.Start()
+	gin.SetMode(conf.Server.Mode)
+	utils.Initial(conf.Security)
 
-	api.Initial()
-	if os.Getenv("RELEASE") != "" {
-		gin.SetMode(gin.ReleaseMode)
+	switch command {
+	case "serve":
+		runServe(conf)
+	case "migrate":
+		runMigrate(conf, args)
+	case "create-superuser":
+		runCreateSuperuser(conf, args)
+	case "run-job":
+		runJob(conf, args)
+	case "seed":
+		runSeed(conf)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-	r := gin.Default()
-
-	r.Use(middleware.Cors())
-
-	routers.Router.Init(r)
-
-	r.GET("/ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"message": "pong",
-		})
-	})
-	if gin.Mode() == gin.DebugMode {
-		if os.Getenv("DEBUG") == "" {
-			r.Run("0.0.0.0:8080")
-		} else {
-			r.Run("0.0.0.0:80")
-		}
-	} else {
-		r.Run("0.0.0.0:80")
+}
+
+/*
+Open the database and storage, then wire every layer explicitly,
+each one only sees the interfaces it is handed
+*/
+func newContainer(conf *config.Config) (*dao.Daos, *service.Services) {
+	db := dao.Initial(conf.Database)
+	if err := storage.Initial(conf); err != nil {
+		log.Fatal(err)
 	}
+
+	daos := dao.NewDaos(db)
+	services := service.NewServices(conf, daos)
+	return daos, services
 }
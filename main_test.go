@@ -0,0 +1,52 @@
+package main
+
+import (
+	"asset-management/app/dao"
+	"asset-management/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSuperuser(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+
+	err := createSuperuser(daos, "root", "")
+	assert.NotEqual(t, nil, err)
+
+	err = createSuperuser(daos, "root", "secret")
+	assert.Equal(t, nil, err)
+	root, err := daos.User.GetUserByName("root")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, root.SystemSuper)
+	ok, _ := utils.CheckPassword(root.Password, clientPassword("secret"))
+	assert.Equal(t, true, ok)
+
+	assert.Equal(t, nil, daos.User.ModifyUserBanstate("root", true))
+	err = createSuperuser(daos, "root", "")
+	assert.Equal(t, nil, err)
+	root, err = daos.User.GetUserByName("root")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, root.Ban)
+	ok, _ = utils.CheckPassword(root.Password, clientPassword("secret"))
+	assert.Equal(t, true, ok)
+}
+
+func TestSeed(t *testing.T) {
+	daos := dao.NewDaos(dao.InitForTest())
+
+	seeded, err := seedDemo(daos)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, seeded)
+
+	manager, err := daos.User.GetUserByName(DEMO_MANAGER)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, manager.EntitySuper)
+	assets, err := daos.Asset.GetDepartmentAssetBasicList(manager.DepartmentID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, len(assets))
+
+	seeded, err = seedDemo(daos)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, seeded)
+}
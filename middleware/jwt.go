@@ -4,9 +4,69 @@ import (
 	"asset-management/app/define"
 	"asset-management/myerror"
 	"asset-management/utils"
+	"net/http"
+	"strings"
 )
 
-func JWTMiddleware() utils.HandlerFunc {
+/*
+Looks up whether the login grant behind a token is still live, whether the identity
+it carries is still current, and what identity a personal token or entity key stands for
+*/
+type TokenChecker interface {
+	CheckToken(claims *define.UserClaims) (bool, error)
+	CheckSecurityVersion(userID uint, version uint) (bool, error)
+	ResolveApiToken(token string) (*define.ApiTokenIdentity, error)
+}
+
+/*
+Searches are POSTs only because of their body, a read-only token may still run them
+*/
+var readOnlyPostSuffixes = []string{"/asset/search", "/asset/search/spare"}
+
+func isReadRequest(ctx *utils.Context) bool {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		for _, suffix := range readOnlyPostSuffixes {
+			if strings.HasSuffix(ctx.FullPath(), suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func apiTokenAuth(ctx *utils.Context, checker TokenChecker, token string) {
+	identity, err := checker.ResolveApiToken(token)
+	if err != nil {
+		ctx.InternalError(err.Error())
+		ctx.Abort()
+		return
+	}
+	if identity == nil {
+		ctx.Unauthorized(myerror.API_TOKEN_INVALID, myerror.API_TOKEN_INVALID_INFO)
+		ctx.Abort()
+		return
+	}
+	if identity.ReadOnly && !isReadRequest(ctx) {
+		ctx.Forbidden(myerror.API_TOKEN_READ_ONLY, myerror.API_TOKEN_READ_ONLY_INFO)
+		ctx.Abort()
+		return
+	}
+	if identity.PasswordChangeRequired && ctx.FullPath() != define.PASSWORD_CHANGE_PATH {
+		ctx.Forbidden(myerror.PASSWORD_CHANGE_REQUIRED, myerror.PASSWORD_CHANGE_REQUIRED_INFO)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("user", identity.UserBasicInfo)
+	ctx.Set("token", token)
+	ctx.Set("api_token_id", identity.TokenID)
+	ctx.Next()
+}
+
+func JWTMiddleware(checker TokenChecker) utils.HandlerFunc {
 	return func(ctx *utils.Context) {
 		token := ctx.GetHeader("Authorization")
 		if token == "" {
@@ -14,6 +74,10 @@ func JWTMiddleware() utils.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if define.IsApiToken(token) {
+			apiTokenAuth(ctx, checker, token)
+			return
+		}
 
 		claims, err := utils.ParseToken(token)
 		if utils.IsTokenExpiredError(err) {
@@ -26,19 +90,65 @@ func JWTMiddleware() utils.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if err != nil {
+			ctx.InternalError(err.Error())
+			ctx.Abort()
+			return
+		}
+
+		valid, err := checker.CheckToken(claims)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			ctx.Abort()
+			return
+		}
+		if !valid {
+			ctx.Unauthorized(myerror.TOKEN_REVOKED, myerror.TOKEN_REVOKED_INFO)
+			ctx.Abort()
+			return
+		}
+		current, err := checker.CheckSecurityVersion(claims.UserID, claims.SecurityVersion)
+		if err != nil {
+			ctx.InternalError(err.Error())
+			ctx.Abort()
+			return
+		}
+		if current && claims.Impersonator != nil {
+			// the admin losing their role ends the impersonation as well
+			current, err = checker.CheckSecurityVersion(claims.Impersonator.UserID, claims.Impersonator.SecurityVersion)
+			if err != nil {
+				ctx.InternalError(err.Error())
+				ctx.Abort()
+				return
+			}
+		}
+		if !current {
+			ctx.Unauthorized(myerror.TOKEN_OUTDATED, myerror.TOKEN_OUTDATED_INFO)
+			ctx.Abort()
+			return
+		}
 
 		userInfo := define.UserBasicInfo{
-			UserID:          claims.UserID,
-			UserName:        claims.UserName,
-			EntitySuper:     claims.EntitySuper,
-			DepartmentSuper: claims.DepartmentSuper,
-			SystemSuper:     claims.SystemSuper,
-			EntityID:        claims.EntityID,
-			DepartmentID:    claims.DepartmentID,
+			UserID:                 claims.UserID,
+			UserName:               claims.UserName,
+			EntitySuper:            claims.EntitySuper,
+			DepartmentSuper:        claims.DepartmentSuper,
+			SystemSuper:            claims.SystemSuper,
+			EntityID:               claims.EntityID,
+			DepartmentID:           claims.DepartmentID,
+			SecurityVersion:        claims.SecurityVersion,
+			Impersonator:           claims.Impersonator,
+			PasswordChangeRequired: claims.PasswordChangeRequired,
+		}
+		if claims.PasswordChangeRequired && ctx.FullPath() != define.PASSWORD_CHANGE_PATH {
+			ctx.Forbidden(myerror.PASSWORD_CHANGE_REQUIRED, myerror.PASSWORD_CHANGE_REQUIRED_INFO)
+			ctx.Abort()
+			return
 		}
 
 		ctx.Set("user", userInfo)
 		ctx.Set("token", token)
+		ctx.Set("token_id", claims.Id)
 		ctx.Next()
 	}
 }
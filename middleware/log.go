@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"asset-management/app/dao"
 	"asset-management/app/define"
 	"asset-management/utils"
 	"bytes"
@@ -10,16 +9,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
 )
 
-var customLog *logrus.Logger
-
-func init() {
+/*
+Operation logger, entries are persisted by hook (normally the mysql log hook)
+*/
+func NewLogger(hook logrus.Hook) *logrus.Logger {
 	customFormatter := new(logrus.JSONFormatter)
 	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
-	customLog = logrus.New()
+	customLog := logrus.New()
 	customLog.SetFormatter(customFormatter)
-	customLog.AddHook(dao.MysqlHook)
+	customLog.AddHook(hook)
+	return customLog
 }
 
 func getOperatorInfo(ctx *utils.Context) *define.UserBasicInfo {
@@ -32,6 +34,23 @@ func getOperatorInfo(ctx *utils.Context) *define.UserBasicInfo {
 	return nil
 }
 
+func getLoginLockout(ctx *utils.Context) (define.LoginLockoutEvent, bool) {
+	event, exists := ctx.Get("login_lockout")
+	if exists {
+		if event, ok := event.(define.LoginLockoutEvent); ok {
+			return event, true
+		}
+	}
+	return define.LoginLockoutEvent{}, false
+}
+
+/*
+Everything done through an impersonation token is logged, reads included
+*/
+func isImpersonated(userInfo *define.UserBasicInfo) bool {
+	return userInfo != nil && userInfo.Impersonator != nil
+}
+
 type CustomResponseWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
@@ -47,16 +66,19 @@ func (w CustomResponseWriter) WriteString(s string) (int, error) {
 	return w.ResponseWriter.WriteString(s)
 }
 
-func LogMiddleware() utils.HandlerFunc {
+func LogMiddleware(customLog *logrus.Logger) utils.HandlerFunc {
 	return func(ctx *utils.Context) {
-		if ctx.Request.URL.Path == "/user/login" {
+		if funk.ContainsString(define.LOGIN_URLS, ctx.Request.URL.Path) {
 			blw := &CustomResponseWriter{body: bytes.NewBufferString(""), ResponseWriter: ctx.Writer}
 			ctx.Writer = blw
 			ctx.Next()
 			resData := utils.ResponseData{}
 			_ = json.Unmarshal(blw.body.Bytes(), &resData)
-			if ctx.Writer.Status() == 200 {
-				loginRes := resData.Data.(map[string]interface{})
+			loginRes, _ := resData.Data.(map[string]interface{})
+			if ctx.Writer.Status() == 200 && loginRes["two_factor_required"] == true {
+				// password accepted, the login is logged once the second factor passes
+				return
+			} else if ctx.Writer.Status() == 200 {
 				userInfo := loginRes["user"].(map[string]interface{})
 				customLog.WithFields(logrus.Fields{
 					"method":        ctx.Request.Method,
@@ -69,6 +91,18 @@ func LogMiddleware() utils.HandlerFunc {
 					"entity_id":     uint(userInfo["entity_id"].(float64)),
 					"department_id": uint(userInfo["department_id"].(float64)),
 				}).Info("Successfully login")
+			} else if lockout, ok := getLoginLockout(ctx); ok {
+				customLog.WithFields(logrus.Fields{
+					"method":        ctx.Request.Method,
+					"url":           ctx.Request.URL.Path,
+					"status":        ctx.Writer.Status(),
+					"error_code":    resData.Error.Code,
+					"error_message": resData.Error.Message,
+					"user_id":       lockout.UserID,
+					"username":      lockout.UserName,
+					"entity_id":     lockout.EntityID,
+					"department_id": lockout.DepartmentID,
+				}).Info("Login locked by " + lockout.Kind + " until " + lockout.LockedUntil.Format("2006-01-02 15:04:05"))
 			} else {
 				customLog.WithFields(logrus.Fields{
 					"method":        ctx.Request.Method,
@@ -82,37 +116,32 @@ func LogMiddleware() utils.HandlerFunc {
 					"department_id": uint(0),
 				}).Info("Login failed")
 			}
-		} else if ctx.Request.Method != "GET" && !strings.HasSuffix(ctx.Request.URL.Path, "/asset/search") {
-			userInfo := getOperatorInfo(ctx)
+		} else if userInfo := getOperatorInfo(ctx); userInfo != nil && (isImpersonated(userInfo) ||
+			ctx.Request.Method != "GET" && !strings.HasSuffix(ctx.Request.URL.Path, "/asset/search")) {
 			blw := &CustomResponseWriter{body: bytes.NewBufferString(""), ResponseWriter: ctx.Writer}
 			ctx.Writer = blw
 			ctx.Next()
 			resData := utils.ResponseData{}
 			_ = json.Unmarshal(blw.body.Bytes(), &resData)
+			fields := logrus.Fields{
+				"method":        ctx.Request.Method,
+				"url":           ctx.Request.URL.Path,
+				"status":        ctx.Writer.Status(),
+				"error_code":    resData.Error.Code,
+				"error_message": resData.Error.Message,
+				"user_id":       userInfo.UserID,
+				"username":      userInfo.UserName,
+				"entity_id":     userInfo.EntityID,
+				"department_id": userInfo.DepartmentID,
+			}
+			if isImpersonated(userInfo) {
+				fields["impersonator_id"] = userInfo.Impersonator.UserID
+				fields["impersonator_name"] = userInfo.Impersonator.UserName
+			}
 			if ctx.Writer.Status() == 200 {
-				customLog.WithFields(logrus.Fields{
-					"method":        ctx.Request.Method,
-					"url":           ctx.Request.URL.Path,
-					"status":        ctx.Writer.Status(),
-					"error_code":    resData.Error.Code,
-					"error_message": resData.Error.Message,
-					"user_id":       userInfo.UserID,
-					"username":      userInfo.UserName,
-					"entity_id":     userInfo.EntityID,
-					"department_id": userInfo.DepartmentID,
-				}).Info("Operation succeed")
+				customLog.WithFields(fields).Info("Operation succeed")
 			} else {
-				customLog.WithFields(logrus.Fields{
-					"method":        ctx.Request.Method,
-					"url":           ctx.Request.URL.Path,
-					"status":        ctx.Writer.Status(),
-					"error_code":    resData.Error.Code,
-					"error_message": resData.Error.Message,
-					"user_id":       userInfo.UserID,
-					"username":      userInfo.UserName,
-					"entity_id":     userInfo.EntityID,
-					"department_id": userInfo.DepartmentID,
-				}).Info("Operation fail")
+				customLog.WithFields(fields).Info("Operation fail")
 			}
 		}
 	}
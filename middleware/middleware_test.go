@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"asset-management/app/define"
+	"asset-management/config"
 	"asset-management/utils"
 	"log"
 	"net/http"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,11 +48,31 @@ func TestCors(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.Result().StatusCode, "cors error")
 }
 
+type fakeTokenChecker struct {
+	revoked   map[string]bool
+	versions  map[uint]uint
+	apiTokens map[string]*define.ApiTokenIdentity
+}
+
+func (fake *fakeTokenChecker) CheckToken(claims *define.UserClaims) (bool, error) {
+	return !fake.revoked[claims.Id], nil
+}
+
+func (fake *fakeTokenChecker) CheckSecurityVersion(userID uint, version uint) (bool, error) {
+	return fake.versions[userID] == version, nil
+}
+
+func (fake *fakeTokenChecker) ResolveApiToken(token string) (*define.ApiTokenIdentity, error) {
+	return fake.apiTokens[token], nil
+}
+
 func TestJwt(t *testing.T) {
+	utils.Initial(config.ForTest().Security)
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
 
-	r.Use(utils.Handler(JWTMiddleware()))
+	checker := &fakeTokenChecker{revoked: map[string]bool{"2": true}, versions: map[uint]uint{3: 2}}
+	r.Use(utils.Handler(JWTMiddleware(checker)))
 	r.GET("/hello", utils.Handler(HelloFunc))
 
 	{
@@ -83,7 +106,7 @@ func TestJwt(t *testing.T) {
 			ExpiresAt: expiredTime.Unix(),
 		}
 		tokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, stdClaims)
-		token, err := tokenObj.SignedString([]byte("AssetManagement-BinaryAbstract"))
+		token, err := tokenObj.SignedString([]byte(config.ForTest().Security.JWTSecret))
 		assert.Equal(t, nil, err, "jwt error")
 
 		req, err := http.NewRequest(http.MethodGet, "/hello", nil)
@@ -102,7 +125,7 @@ func TestJwt(t *testing.T) {
 			EntitySuper:     true,
 			DepartmentSuper: true,
 			SystemSuper:     true,
-		})
+		}, "1")
 		assert.Equal(t, nil, err, "jwt create error")
 
 		req, err := http.NewRequest(http.MethodGet, "/hello", nil)
@@ -114,4 +137,197 @@ func TestJwt(t *testing.T) {
 		r.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Result().StatusCode, "jwt middleware error")
 	}
+	{
+		token, err := utils.CreateToken(define.UserBasicInfo{
+			UserID:   1,
+			UserName: "admin",
+		}, "2")
+		assert.Equal(t, nil, err, "jwt create error")
+
+		req, err := http.NewRequest(http.MethodGet, "/hello", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "jwt middleware error")
+	}
+	// the user's identity changed after the token was issued
+	{
+		token, err := utils.CreateToken(define.UserBasicInfo{
+			UserID:          3,
+			UserName:        "outdated",
+			SecurityVersion: 1,
+		}, "3")
+		assert.Equal(t, nil, err, "jwt create error")
+
+		req, err := http.NewRequest(http.MethodGet, "/hello", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode, "jwt middleware error")
+	}
+	// the impersonating admin's identity is checked as well
+	for version, status := range map[uint]int{1: http.StatusUnauthorized, 2: http.StatusOK} {
+		token, err := utils.CreateImpersonationToken(define.UserBasicInfo{
+			UserID:   4,
+			UserName: "impersonated",
+			Impersonator: &define.Impersonator{
+				UserID:          3,
+				UserName:        "outdated",
+				SecurityVersion: version,
+			},
+		}, "4")
+		assert.Equal(t, nil, err, "jwt create error")
+
+		req, err := http.NewRequest(http.MethodGet, "/hello", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, status, res.Result().StatusCode, "jwt middleware error")
+	}
+}
+
+func TestImpersonationLog(t *testing.T) {
+	hook := test.NewLocal(logrus.New())
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	r.GET("/hello", utils.Handler(func(ctx *utils.Context) {
+		ctx.Set("user", define.UserBasicInfo{
+			UserID:       4,
+			UserName:     "impersonated",
+			EntityID:     3,
+			Impersonator: &define.Impersonator{UserID: 1, UserName: "admin"},
+		})
+	}), utils.Handler(LogMiddleware(NewLogger(hook))), utils.Handler(HelloFunc))
+
+	req, err := http.NewRequest(http.MethodGet, "/hello", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Result().StatusCode)
+	entry := hook.LastEntry()
+	assert.NotEqual(t, (*logrus.Entry)(nil), entry)
+	assert.Equal(t, uint(4), entry.Data["user_id"])
+	assert.Equal(t, uint(1), entry.Data["impersonator_id"])
+	assert.Equal(t, "admin", entry.Data["impersonator_name"])
+}
+
+func TestLoginLockoutLog(t *testing.T) {
+	hook := test.NewLocal(logrus.New())
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	r.POST("/user/login", utils.Handler(LogMiddleware(NewLogger(hook))), utils.Handler(func(ctx *utils.Context) {
+		ctx.Set("login_lockout", define.LoginLockoutEvent{
+			UserID:      2,
+			UserName:    "locked",
+			EntityID:    3,
+			Kind:        "username",
+			LockedUntil: time.Now().Add(time.Minute),
+		})
+		ctx.Forbidden(63, "locked")
+	}))
+
+	req, err := http.NewRequest(http.MethodPost, "/user/login", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+	entry := hook.LastEntry()
+	assert.NotEqual(t, (*logrus.Entry)(nil), entry)
+	assert.Equal(t, uint(3), entry.Data["entity_id"])
+	assert.Equal(t, "locked", entry.Data["username"])
+	assert.Contains(t, entry.Message, "Login locked by username")
+}
+
+func TestJwtApiToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+
+	checker := &fakeTokenChecker{apiTokens: map[string]*define.ApiTokenIdentity{
+		"amp_read": {
+			UserBasicInfo: define.UserBasicInfo{UserID: 1, UserName: "admin"},
+			TokenID:       1,
+			ReadOnly:      true,
+		},
+		"amk_write": {
+			UserBasicInfo: define.UserBasicInfo{UserID: 1, UserName: "apikey:sync", EntitySuper: true, EntityID: 1},
+			TokenID:       2,
+		},
+		"amp_stale": {
+			UserBasicInfo: define.UserBasicInfo{UserID: 2, UserName: "stale", PasswordChangeRequired: true},
+			TokenID:       3,
+		},
+	}}
+	r.Use(utils.Handler(JWTMiddleware(checker)))
+	r.GET("/hello", utils.Handler(HelloFunc))
+	r.POST("/hello", utils.Handler(HelloFunc))
+	r.POST("/department/:department_id/asset/search", utils.Handler(HelloFunc))
+
+	cases := []struct {
+		method string
+		url    string
+		token  string
+		status int
+	}{
+		{http.MethodGet, "/hello", "amp_unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/hello", "amp_read", http.StatusOK},
+		{http.MethodPost, "/hello", "amp_read", http.StatusForbidden},
+		{http.MethodPost, "/department/1/asset/search", "amp_read", http.StatusOK},
+		{http.MethodPost, "/hello", "amk_write", http.StatusOK},
+		{http.MethodGet, "/hello", "amp_stale", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, c.url, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Authorization", c.token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, c.status, res.Result().StatusCode, "jwt middleware error")
+	}
+}
+
+func TestJwtPasswordChangeRequired(t *testing.T) {
+	utils.Initial(config.ForTest().Security)
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+
+	checker := &fakeTokenChecker{}
+	r.Use(utils.Handler(JWTMiddleware(checker)))
+	r.GET("/hello", utils.Handler(HelloFunc))
+	r.POST(define.PASSWORD_CHANGE_PATH, utils.Handler(HelloFunc))
+
+	token, err := utils.CreateToken(define.UserBasicInfo{UserID: 1, UserName: "admin", PasswordChangeRequired: true}, "1")
+	assert.Equal(t, nil, err, "jwt error")
+	cases := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{http.MethodGet, "/hello", http.StatusForbidden},
+		{http.MethodPost, "/user/info/1/password", http.StatusOK},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, c.url, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, c.status, res.Result().StatusCode, "jwt middleware error")
+	}
 }
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"asset-management/app/define"
+	"asset-management/utils"
+	"net/http"
+	"strings"
+)
+
+/*
+A provisioning client authenticates with an entity-wide API token as a bearer token,
+the entity it provisions is the one the token belongs to
+*/
+func ScimMiddleware(checker TokenChecker) utils.HandlerFunc {
+	return func(ctx *utils.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer "))
+		if token == "" || !define.IsApiToken(token) {
+			ctx.ScimError(http.StatusUnauthorized, "", "A bearer API token is required")
+			ctx.Abort()
+			return
+		}
+
+		identity, err := checker.ResolveApiToken(token)
+		if err != nil {
+			ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+			ctx.Abort()
+			return
+		}
+		if identity == nil {
+			ctx.ScimError(http.StatusUnauthorized, "", "API token is invalid, expired or revoked")
+			ctx.Abort()
+			return
+		}
+		// a department restriction takes entity super away, and with it provisioning
+		if !identity.EntitySuper || identity.EntityID == 0 {
+			ctx.ScimError(http.StatusForbidden, "", "Provisioning needs an entity-wide token of an entity super")
+			ctx.Abort()
+			return
+		}
+		if identity.ReadOnly && !isReadRequest(ctx) {
+			ctx.ScimError(http.StatusForbidden, "", "This API token is read-only")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("user", identity.UserBasicInfo)
+		ctx.Set("token", token)
+		ctx.Set("api_token_id", identity.TokenID)
+		ctx.Next()
+	}
+}
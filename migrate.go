@@ -0,0 +1,65 @@
+package main
+
+import (
+	"asset-management/app/dao"
+	"asset-management/config"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+/*
+migrate [up | down [steps] | status]
+*/
+func runMigrate(conf *config.Config, args []string) {
+	db := dao.Open(conf.Database)
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		versions, err := dao.MigrateUp(db)
+		for _, version := range versions {
+			log.Printf("applied migration %d", version)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(versions) == 0 {
+			log.Println("database schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid steps %q", args[1])
+			}
+		}
+		versions, err := dao.MigrateDown(db, steps)
+		for _, version := range versions {
+			log.Printf("rolled back migration %d", version)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		states, err := dao.MigrationStatus(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, state := range states {
+			if state.AppliedAt == nil {
+				fmt.Printf("%4d  %-40s  pending\n", state.Version, state.Name)
+			} else {
+				fmt.Printf("%4d  %-40s  applied at %s\n", state.Version, state.Name, state.AppliedAt.Format("2006-01-02 15:04:05"))
+			}
+		}
+	default:
+		log.Fatalf("unknown migrate action %q, expected up, down or status", action)
+	}
+}
@@ -60,4 +60,47 @@ const (
 	USER_HAS_ASSETS                 = 56
 	USER_HAS_TASKS                  = 57
 	CANNOT_MODIFY_SELF_IDENTITY     = 58
+	DOWNLOAD_LINK_INVALID           = 59
+	OBJECT_NOT_FOUND                = 60
+	TOKEN_REVOKED                   = 61
+	REFRESH_TOKEN_INVALID           = 62
+	LOGIN_LOCKED                    = 63
+	LOGIN_LOCK_NOT_FOUND            = 64
+	TWO_FACTOR_CODE_INVALID         = 65
+	TWO_FACTOR_TOKEN_INVALID        = 66
+	TWO_FACTOR_ALREADY_ENABLED      = 67
+	TWO_FACTOR_NOT_ENABLED          = 68
+	TWO_FACTOR_REQUIRED             = 69
+	API_TOKEN_INVALID               = 70
+	API_TOKEN_READ_ONLY             = 71
+	API_TOKEN_NOT_FOUND             = 72
+	API_TOKEN_SCOPE_INVALID         = 73
+	API_TOKEN_NOT_ALLOWED           = 74
+	ROLE_NOT_FOUND                  = 75
+	ROLE_BUILT_IN                   = 76
+	ROLE_HAS_EXIST                  = 77
+	PERMISSION_UNKNOWN              = 78
+	ROLE_ASSIGNMENT_NOT_FOUND       = 79
+	TOKEN_OUTDATED                  = 80
+	SESSION_NOT_FOUND               = 81
+	IDENTITY_PROVIDER_NOT_FOUND     = 82
+	IDENTITY_PROVIDER_HAS_EXIST     = 83
+	IDENTITY_PROVIDER_INVALID       = 84
+	AUTHORIZATION_STATE_INVALID     = 85
+	IDENTITY_CODE_INVALID           = 86
+	IDENTITY_NOT_BIND               = 87
+	IDENTITY_DUPLICATE_BIND         = 88
+	IMPERSONATION_NOT_ALLOWED       = 89
+	IMPERSONATE_SELF                = 90
+	IMPERSONATE_BANNED              = 91
+	INVITE_NOT_FOUND                = 92
+	INVITE_INVALID                  = 93
+	PASSWORD_POLICY_VIOLATED        = 94
+	PASSWORD_REUSED                 = 95
+	PASSWORD_CHANGE_REQUIRED        = 96
+	PASSWORD_RESET_INVALID          = 97
+	ASSET_STATE_INVALID             = 98
+	ASSET_VERSION_NOT_FOUND         = 99
+	ASSET_QUANTITY_INVALID          = 100
+	DEPRECIATION_INVALID            = 101
 )
@@ -59,4 +59,47 @@ const (
 	USER_HAS_ASSETS_INFO                 = "This user has assets, cannot modify their entity or department"
 	USER_HAS_TASKS_INFO                  = "This user has uncompleted tasks, cannot modify their entity or department"
 	CANNOT_MODIFY_SELF_IDENTITY_INFO     = "Cannot modify yourself's identity"
+	DOWNLOAD_LINK_INVALID_INFO           = "Download link is invalid or expired"
+	OBJECT_NOT_FOUND_INFO                = "File not found"
+	TOKEN_REVOKED_INFO                   = "Token has been revoked, please log in again"
+	REFRESH_TOKEN_INVALID_INFO           = "Refresh token is invalid, expired or revoked"
+	LOGIN_LOCKED_INFO                    = "Too many failed login attempts, please try again later"
+	LOGIN_LOCK_NOT_FOUND_INFO            = "Login lock not found"
+	TWO_FACTOR_CODE_INVALID_INFO         = "Invalid two-factor code"
+	TWO_FACTOR_TOKEN_INVALID_INFO        = "Two-factor login token is invalid or expired"
+	TWO_FACTOR_ALREADY_ENABLED_INFO      = "Two-factor authentication is already enabled"
+	TWO_FACTOR_NOT_ENABLED_INFO          = "Two-factor authentication is not enabled"
+	TWO_FACTOR_REQUIRED_INFO             = "Two-factor authentication is required by your entity"
+	API_TOKEN_INVALID_INFO               = "API token is invalid, expired or revoked"
+	API_TOKEN_READ_ONLY_INFO             = "This API token is read-only"
+	API_TOKEN_NOT_FOUND_INFO             = "API token not found"
+	API_TOKEN_SCOPE_INVALID_INFO         = "The department is out of your scope"
+	API_TOKEN_NOT_ALLOWED_INFO           = "Not allowed with an API token, log in instead"
+	ROLE_NOT_FOUND_INFO                  = "Role not found"
+	ROLE_BUILT_IN_INFO                   = "Built-in roles cannot be modified"
+	ROLE_HAS_EXIST_INFO                  = "Role name already exists"
+	PERMISSION_UNKNOWN_INFO              = "Unknown permission"
+	ROLE_ASSIGNMENT_NOT_FOUND_INFO       = "Role assignment not found"
+	TOKEN_OUTDATED_INFO                  = "Your identity has changed, please refresh the token"
+	SESSION_NOT_FOUND_INFO               = "Session not found"
+	IDENTITY_PROVIDER_NOT_FOUND_INFO     = "Identity provider not found"
+	IDENTITY_PROVIDER_HAS_EXIST_INFO     = "Identity provider name already exists"
+	IDENTITY_PROVIDER_INVALID_INFO       = "OIDC providers need an issuer and a client id"
+	AUTHORIZATION_STATE_INVALID_INFO     = "Authorization state is invalid or expired"
+	IDENTITY_CODE_INVALID_INFO           = "Authorization code is invalid"
+	IDENTITY_NOT_BIND_INFO               = "This identity is not bound to any user"
+	IDENTITY_DUPLICATE_BIND_INFO         = "This identity is bound to another user"
+	IMPERSONATION_NOT_ALLOWED_INFO       = "Not allowed while impersonating a user"
+	IMPERSONATE_SELF_INFO                = "You cannot impersonate yourself"
+	IMPERSONATE_BANNED_INFO              = "A banned user cannot be impersonated"
+	INVITE_NOT_FOUND_INFO                = "Invite not found"
+	INVITE_INVALID_INFO                  = "Invite is invalid, expired or used up"
+	PASSWORD_POLICY_VIOLATED_INFO        = "Password does not meet the password policy"
+	PASSWORD_REUSED_INFO                 = "Password has been used recently"
+	PASSWORD_CHANGE_REQUIRED_INFO        = "Password must be changed before continuing"
+	PASSWORD_RESET_INVALID_INFO          = "Password reset token is invalid, expired or used"
+	ASSET_STATE_INVALID_INFO             = "Asset state does not allow this operation"
+	ASSET_VERSION_NOT_FOUND_INFO         = "Asset version not found"
+	ASSET_QUANTITY_INVALID_INFO          = "Asset quantity is invalid or not available"
+	DEPRECIATION_INVALID_INFO            = "Depreciation method or residual rate is invalid"
 )
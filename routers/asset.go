@@ -2,48 +2,57 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type assetRouter struct{}
-
-var AssetRouter *assetRouter
-
-func newAssetRouter() *assetRouter {
-	return &assetRouter{}
+type assetRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	AssetRouter = newAssetRouter()
+func newAssetRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *assetRouter {
+	return &assetRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (asset *assetRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
+	group.Use(utils.Handler(asset.jwtMiddleware), utils.Handler(asset.logMiddleware))
 	asset.routerCheckAtHandler(group)
 }
 
 func (asset *assetRouter) routerCheckAtHandler(group *gin.RouterGroup) {
-	group.GET("/:department_id/asset/list", utils.Handler(api.AssetApi.GetAssetList))
-	group.PATCH("/:department_id/asset/:asset_id", utils.Handler(api.AssetApi.ModifyAssetInfo))
-	group.POST("/:department_id/asset", utils.Handler(api.AssetApi.CreateAssets))
-	group.PATCH("/:department_id/asset/expire", utils.Handler(api.AssetApi.ExpireAsset))
-	group.POST("/:department_id/asset/transfer", utils.Handler(api.AssetApi.TransferAssets))
-	group.POST("/:department_id/asset/:asset_id/property", utils.Handler(api.AssetApi.CreateAssetProperty))
-	group.PATCH("/:department_id/asset/:asset_id/property", utils.Handler(api.AssetApi.ModifyAssetProperty))
-	group.DELETE("/:department_id/asset/:asset_id/property", utils.Handler(api.AssetApi.DeleteAssetProperty))
-	group.GET("/:department_id/asset/:asset_id/history", utils.Handler(api.AssetApi.GetAssetHistory))
-	group.POST("/:department_id/asset/search", utils.Handler(api.AssetApi.SearchAssets))
-	group.POST("/:department_id/asset/search/spare", utils.Handler(api.AssetApi.SearchSpareAssets))
-	group.GET("/:department_id/asset/stat/total", utils.Handler(api.StatApi.GetDepartmentStatTotal))
-	group.GET("/:department_id/asset/stat/distribution", utils.Handler(api.StatApi.GetDepartmentStatDistribution))
-	group.GET("/:department_id/asset/stat/sub", utils.Handler(api.StatApi.GetSubDepartmentsAssetDistribution))
-	group.GET("/:department_id/asset/:asset_id", utils.Handler(api.AssetApi.GetAssetInfo))
-	group.POST("/:department_id/template", utils.Handler(api.DepartmentApi.DefineDepartmentAssetTemplate))
-	group.GET("/:department_id/template", utils.Handler(api.DepartmentApi.GetDepartmentTemplate))
-	group.POST("/:department_id/warn", utils.Handler(api.DepartmentApi.SetDepartmentWarnStrategy))
-	group.GET("/:department_id/warn", utils.Handler(api.DepartmentApi.GetDepartmentAssetWarnInfo))
-	group.GET("/:department_id/asset/list/basic", utils.Handler(api.AssetApi.GetDepartmentAssetBasicList))
+	group.GET("/:department_id/asset/list", utils.Handler(asset.apis.Asset.GetAssetList))
+	group.PATCH("/:department_id/asset/:asset_id", utils.Handler(asset.apis.Asset.ModifyAssetInfo))
+	group.POST("/:department_id/asset", utils.Handler(asset.apis.Asset.CreateAssets))
+	group.PATCH("/:department_id/asset/expire", utils.Handler(asset.apis.Asset.ExpireAsset))
+	group.POST("/:department_id/asset/transfer", utils.Handler(asset.apis.Asset.TransferAssets))
+	group.DELETE("/:department_id/asset", utils.Handler(asset.apis.Asset.DeleteAssets))
+	group.GET("/:department_id/asset/recycle", utils.Handler(asset.apis.Asset.GetRecycleBin))
+	group.POST("/:department_id/asset/recycle/restore", utils.Handler(asset.apis.Asset.RestoreAssets))
+	group.POST("/:department_id/asset/:asset_id/property", utils.Handler(asset.apis.Asset.CreateAssetProperty))
+	group.PATCH("/:department_id/asset/:asset_id/property", utils.Handler(asset.apis.Asset.ModifyAssetProperty))
+	group.DELETE("/:department_id/asset/:asset_id/property", utils.Handler(asset.apis.Asset.DeleteAssetProperty))
+	group.GET("/:department_id/asset/:asset_id/history", utils.Handler(asset.apis.Asset.GetAssetHistory))
+	group.GET("/:department_id/asset/:asset_id/states", utils.Handler(asset.apis.Asset.GetAssetStateHistory))
+	group.GET("/:department_id/asset/:asset_id/versions", utils.Handler(asset.apis.Asset.GetAssetVersions))
+	group.POST("/:department_id/asset/:asset_id/versions/:version/revert", utils.Handler(asset.apis.Asset.RevertAsset))
+	group.GET("/:department_id/asset/:asset_id/depreciation", utils.Handler(asset.apis.Asset.PreviewDepreciation))
+	group.PUT("/:department_id/asset/:asset_id/depreciation", utils.Handler(asset.apis.Asset.ModifyAssetDepreciation))
+	group.POST("/:department_id/asset/search", utils.Handler(asset.apis.Asset.SearchAssets))
+	group.POST("/:department_id/asset/search/spare", utils.Handler(asset.apis.Asset.SearchSpareAssets))
+	group.GET("/:department_id/asset/stat/total", utils.Handler(asset.apis.Stat.GetDepartmentStatTotal))
+	group.GET("/:department_id/asset/stat/distribution", utils.Handler(asset.apis.Stat.GetDepartmentStatDistribution))
+	group.GET("/:department_id/asset/stat/sub", utils.Handler(asset.apis.Stat.GetSubDepartmentsAssetDistribution))
+	group.GET("/:department_id/asset/:asset_id", utils.Handler(asset.apis.Asset.GetAssetInfo))
+	group.POST("/:department_id/template", utils.Handler(asset.apis.Department.DefineDepartmentAssetTemplate))
+	group.GET("/:department_id/template", utils.Handler(asset.apis.Department.GetDepartmentTemplate))
+	group.POST("/:department_id/warn", utils.Handler(asset.apis.Department.SetDepartmentWarnStrategy))
+	group.GET("/:department_id/warn", utils.Handler(asset.apis.Department.GetDepartmentAssetWarnInfo))
+	group.GET("/:department_id/asset/list/basic", utils.Handler(asset.apis.Asset.GetDepartmentAssetBasicList))
 }
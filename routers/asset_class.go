@@ -2,33 +2,34 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type assetClassRouter struct{}
-
-var AssetClassRouter *assetClassRouter
-
-func newAssetClassRouter() *assetClassRouter {
-	return &assetClassRouter{}
+type assetClassRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	AssetClassRouter = newAssetClassRouter()
+func newAssetClassRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *assetClassRouter {
+	return &assetClassRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (assetClass *assetClassRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
+	group.Use(utils.Handler(assetClass.jwtMiddleware), utils.Handler(assetClass.logMiddleware))
 	assetClass.routerCheckAtHandler(group)
 }
 
 func (assetClass *assetClassRouter) routerCheckAtHandler(group *gin.RouterGroup) {
-	group.GET("/:department_id/asset_class/tree", utils.Handler(api.AssetClassApi.GetAssetClassTree))
-	group.POST("/:department_id/asset_class", utils.Handler(api.AssetClassApi.CreateAssetClass))
-	group.DELETE("/:department_id/asset_class/:class_id", utils.Handler(api.AssetClassApi.DeleteAssetClass))
-	group.PATCH("/:department_id/asset_class/:class_id", utils.Handler(api.AssetClassApi.ModifyAssetClassInfo))
-	group.GET("/:department_id/asset_class/:class_id", utils.Handler(api.AssetClassApi.GetSubAssetClass))
+	group.GET("/:department_id/asset_class/tree", utils.Handler(assetClass.apis.AssetClass.GetAssetClassTree))
+	group.POST("/:department_id/asset_class", utils.Handler(assetClass.apis.AssetClass.CreateAssetClass))
+	group.DELETE("/:department_id/asset_class/:class_id", utils.Handler(assetClass.apis.AssetClass.DeleteAssetClass))
+	group.PATCH("/:department_id/asset_class/:class_id", utils.Handler(assetClass.apis.AssetClass.ModifyAssetClassInfo))
+	group.GET("/:department_id/asset_class/:class_id", utils.Handler(assetClass.apis.AssetClass.GetSubAssetClass))
 }
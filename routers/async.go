@@ -2,34 +2,34 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type asyncRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-var AsyncRouter *asyncRouter
-
-func newAsyncRouter() *asyncRouter {
-	return &asyncRouter{}
-}
-
-func init() {
-	AsyncRouter = newAsyncRouter()
+func newAsyncRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *asyncRouter {
+	return &asyncRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (asy *asyncRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
-	group.GET("/users/:user_id/async/list", utils.Handler(api.AsyncApi.GetUserAsyncTasks))
-	group.POST("/users/:user_id/async", utils.Handler(api.AsyncApi.CreateAsyncTask))
-	group.PATCH("/users/:user_id/async/:task_id", utils.Handler(api.AsyncApi.ModifyAsyncState))
+	group.Use(utils.Handler(asy.jwtMiddleware), utils.Handler(asy.logMiddleware))
+	group.GET("/users/:user_id/async/list", utils.Handler(asy.apis.Async.GetUserAsyncTasks))
+	group.POST("/users/:user_id/async", utils.Handler(asy.apis.Async.CreateAsyncTask))
+	group.PATCH("/users/:user_id/async/:task_id", utils.Handler(asy.apis.Async.ModifyAsyncState))
 }
 
 // func (asy *asyncRouter) routerCheckAtHandler(group *gin.RouterGroup) {
-// 	group.GET("/user/:user_id/async/list", utils.Handler(api.AsyncApi.GetUserAsyncTasks))
-// 	group.POST("/user/:user_id/async", utils.Handler(api.AsyncApi.CreateAsyncTask))
-// 	group.PATCH("/user/:user_id/async/:task_id", utils.Handler(api.AsyncApi.ModifyAsyncState))
+// 	group.GET("/user/:user_id/async/list", utils.Handler(asy.apis.Async.GetUserAsyncTasks))
+// 	group.POST("/user/:user_id/async", utils.Handler(asy.apis.Async.CreateAsyncTask))
+// 	group.PATCH("/user/:user_id/async/:task_id", utils.Handler(asy.apis.Async.ModifyAsyncState))
 // }
@@ -2,26 +2,28 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
+	"asset-management/app/define"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type entityRouter struct{}
-
-var EntityRouter *entityRouter
-
-func newEntityRouter() *entityRouter {
-	return &entityRouter{}
+type entityRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	EntityRouter = newEntityRouter()
+func newEntityRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *entityRouter {
+	return &entityRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (entity *entityRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
+	group.Use(utils.Handler(entity.jwtMiddleware), utils.Handler(entity.logMiddleware))
 	{
 		entity.UrlrouterCheckAtHandler(group)
 	}
@@ -30,41 +32,63 @@ func (entity *entityRouter) Init(group *gin.RouterGroup) {
 }
 
 func (entity *entityRouter) routerNeedSystemSuper(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.CheckSystemSuper()))
+	group.Use(utils.Handler(entity.apis.Permission.Require(define.PERM_SYSTEM_MANAGE)))
 	{
-		group.POST("/", utils.Handler(api.EntityApi.CreateEntity))
-		group.DELETE("/:entity_id", utils.Handler(api.EntityApi.DeleteEntity))
-		group.GET("/list", utils.Handler(api.EntityApi.GetEntityList))
-		group.GET("/:entity_id", utils.Handler(api.EntityApi.GetEntityByID))
-		group.POST("/:entity_id/manager", utils.Handler(api.EntityApi.SetManager))
-		group.DELETE("/:entity_id/manager/:user_id", utils.Handler(api.EntityApi.DeleteManager))
+		group.POST("/", utils.Handler(entity.apis.Entity.CreateEntity))
+		group.DELETE("/:entity_id", utils.Handler(entity.apis.Entity.DeleteEntity))
+		group.GET("/list", utils.Handler(entity.apis.Entity.GetEntityList))
+		group.GET("/:entity_id", utils.Handler(entity.apis.Entity.GetEntityByID))
+		group.POST("/:entity_id/manager", utils.Handler(entity.apis.Entity.SetManager))
+		group.DELETE("/:entity_id/manager/:user_id", utils.Handler(entity.apis.Entity.DeleteManager))
 	}
 }
 
 func (entity *entityRouter) routerCheckAtHandler(group *gin.RouterGroup) {
-	group.GET("/:entity_id/user/list", utils.Handler(api.EntityApi.UsersInEntity))
-	group.GET("/:entity_id/department/list", utils.Handler(api.EntityApi.DepartmentsInEntity)) // change later
-	group.PATCH("/:entity_id", utils.Handler(api.EntityApi.ModifyEntityInfo))
-	group.GET("/:entity_id/department/sub", utils.Handler(api.EntityApi.GetEntitySubDepartments))
+	group.GET("/:entity_id/user/list", utils.Handler(entity.apis.Entity.UsersInEntity))
+	group.GET("/:entity_id/department/list", utils.Handler(entity.apis.Entity.DepartmentsInEntity)) // change later
+	group.PATCH("/:entity_id", utils.Handler(entity.apis.Entity.ModifyEntityInfo))
+	group.GET("/:entity_id/department/sub", utils.Handler(entity.apis.Entity.GetEntitySubDepartments))
+	group.GET("/:entity_id/login-locks", utils.Handler(entity.apis.Entity.GetLoginLocks))
+	group.DELETE("/:entity_id/login-locks/:lock_id", utils.Handler(entity.apis.Entity.ClearLoginLock))
+	group.PUT("/:entity_id/two-factor", utils.Handler(entity.apis.Entity.SetTwoFactorPolicy))
+	group.GET("/:entity_id/password-policy", utils.Handler(entity.apis.Entity.GetPasswordPolicy))
+	group.PUT("/:entity_id/password-policy", utils.Handler(entity.apis.Entity.SetPasswordPolicy))
+	group.GET("/:entity_id/api-keys", utils.Handler(entity.apis.ApiToken.GetEntityKeys))
+	group.POST("/:entity_id/api-keys", utils.Handler(entity.apis.ApiToken.CreateEntityKey))
+	group.DELETE("/:entity_id/api-keys/:key_id", utils.Handler(entity.apis.ApiToken.RevokeEntityKey))
+	group.GET("/:entity_id/identity-providers", utils.Handler(entity.apis.Identity.GetProviders))
+	group.POST("/:entity_id/identity-providers", utils.Handler(entity.apis.Identity.CreateProvider))
+	group.PUT("/:entity_id/identity-providers/:provider_id", utils.Handler(entity.apis.Identity.ModifyProvider))
+	group.DELETE("/:entity_id/identity-providers/:provider_id", utils.Handler(entity.apis.Identity.DeleteProvider))
+	group.GET("/:entity_id/roles", utils.Handler(entity.apis.Permission.GetRoles))
+	group.POST("/:entity_id/roles", utils.Handler(entity.apis.Permission.CreateRole))
+	group.PUT("/:entity_id/roles/:role_id", utils.Handler(entity.apis.Permission.ModifyRole))
+	group.DELETE("/:entity_id/roles/:role_id", utils.Handler(entity.apis.Permission.DeleteRole))
+	group.GET("/:entity_id/role-assignments", utils.Handler(entity.apis.Permission.GetAssignments))
+	group.POST("/:entity_id/role-assignments", utils.Handler(entity.apis.Permission.Assign))
+	group.DELETE("/:entity_id/role-assignments/:assignment_id", utils.Handler(entity.apis.Permission.Unassign))
 
-	group.POST("/:entity_id/department", utils.Handler(api.DepartmentApi.CreateDepartment))
-	group.POST("/:entity_id/department/:department_id/department", utils.Handler(api.DepartmentApi.CreateDepartment))
-	group.DELETE("/:entity_id/department/:department_id", utils.Handler(api.DepartmentApi.DeleteDepartment))
-	group.GET("/:entity_id/department/:department_id", utils.Handler(api.DepartmentApi.GetDepartmentByID))
-	group.GET("/:entity_id/department/:department_id/department/list", utils.Handler(api.DepartmentApi.GetSubDepartments))
-	group.GET("/:entity_id/department/:department_id/user/list", utils.Handler(api.DepartmentApi.GetAllUsersUnderDepartment))
-	group.POST("/:entity_id/department/:department_id/user", utils.Handler(api.DepartmentApi.CreateUserInDepartment))
-	group.POST("/:entity_id/department/:department_id/manager", utils.Handler(api.DepartmentApi.SetManager))
-	group.DELETE("/:entity_id/department/:department_id/manager/:user_id", utils.Handler(api.DepartmentApi.DeleteDepartmentManager))
-	group.GET("/:entity_id/department/:department_id/manager", utils.Handler(api.DepartmentApi.GetDepartmentManager))
-	group.GET("/:entity_id/department/tree", utils.Handler(api.DepartmentApi.GetDepartmentTree))
-	group.GET("/:entity_id/department/:department_id/user/sub", utils.Handler(api.DepartmentApi.GetDepartmentSubUsers))
+	group.POST("/:entity_id/department", utils.Handler(entity.apis.Department.CreateDepartment))
+	group.POST("/:entity_id/department/:department_id/department", utils.Handler(entity.apis.Department.CreateDepartment))
+	group.DELETE("/:entity_id/department/:department_id", utils.Handler(entity.apis.Department.DeleteDepartment))
+	group.GET("/:entity_id/department/:department_id", utils.Handler(entity.apis.Department.GetDepartmentByID))
+	group.GET("/:entity_id/department/:department_id/department/list", utils.Handler(entity.apis.Department.GetSubDepartments))
+	group.GET("/:entity_id/department/:department_id/user/list", utils.Handler(entity.apis.Department.GetAllUsersUnderDepartment))
+	group.POST("/:entity_id/department/:department_id/user", utils.Handler(entity.apis.Department.CreateUserInDepartment))
+	group.POST("/:entity_id/department/:department_id/manager", utils.Handler(entity.apis.Department.SetManager))
+	group.DELETE("/:entity_id/department/:department_id/manager/:user_id", utils.Handler(entity.apis.Department.DeleteDepartmentManager))
+	group.GET("/:entity_id/department/:department_id/manager", utils.Handler(entity.apis.Department.GetDepartmentManager))
+	group.GET("/:entity_id/department/tree", utils.Handler(entity.apis.Department.GetDepartmentTree))
+	group.GET("/:entity_id/department/:department_id/user/sub", utils.Handler(entity.apis.Department.GetDepartmentSubUsers))
+	group.GET("/:entity_id/department/:department_id/invites", utils.Handler(entity.apis.Invite.GetInvites))
+	group.POST("/:entity_id/department/:department_id/invites", utils.Handler(entity.apis.Invite.CreateInvite))
+	group.DELETE("/:entity_id/department/:department_id/invites/:invite_id", utils.Handler(entity.apis.Invite.RevokeInvite))
 }
 
 func (entity *entityRouter) UrlrouterCheckAtHandler(group *gin.RouterGroup) {
-	group.GET("/:entity_id/url", utils.Handler(api.UrlApi.GetUrlsByEntity))
-	group.POST("/:entity_id/url", utils.Handler(api.UrlApi.CreateUrl))
-	group.PATCH("/:entity_id/url", utils.Handler(api.UrlApi.ModifyUrl))
-	group.DELETE("/:entity_id/url", utils.Handler(api.UrlApi.DeleteUrl))
-	group.GET("/:entity_id/url/list", utils.Handler(api.UrlApi.GetUrlList))
+	group.GET("/:entity_id/url", utils.Handler(entity.apis.Url.GetUrlsByEntity))
+	group.POST("/:entity_id/url", utils.Handler(entity.apis.Url.CreateUrl))
+	group.PATCH("/:entity_id/url", utils.Handler(entity.apis.Url.ModifyUrl))
+	group.DELETE("/:entity_id/url", utils.Handler(entity.apis.Url.DeleteUrl))
+	group.GET("/:entity_id/url/list", utils.Handler(entity.apis.Url.GetUrlList))
 }
@@ -2,26 +2,27 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type logRouter struct{}
-
-var LogRouter *logRouter
-
-func newLogRouter() *logRouter {
-	return &logRouter{}
+type logRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	LogRouter = newLogRouter()
+func newLogRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *logRouter {
+	return &logRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (mylog *logRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()))
-	group.GET("/:entity_id/login-logs", utils.Handler(api.LogApi.GetLoginLog))
-	group.GET("/:entity_id/data-logs", utils.Handler(api.LogApi.GetDataLog))
+	group.Use(utils.Handler(mylog.jwtMiddleware))
+	group.GET("/:entity_id/login-logs", utils.Handler(mylog.apis.Log.GetLoginLog))
+	group.GET("/:entity_id/data-logs", utils.Handler(mylog.apis.Log.GetDataLog))
 }
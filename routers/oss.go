@@ -2,26 +2,26 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ossRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-var OssRouter *ossRouter
-
-func newOssRouter() *ossRouter {
-	return &ossRouter{}
-}
-
-func init() {
-	OssRouter = newOssRouter()
+func newOssRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *ossRouter {
+	return &ossRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (oss *ossRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()))
-	group.GET("/oss/key", utils.Handler(api.OssApi.GetTempKey))
+	group.Use(utils.Handler(oss.jwtMiddleware))
+	group.GET("/oss/key", utils.Handler(oss.apis.Oss.GetTempKey))
 }
@@ -2,37 +2,47 @@ package routers
 
 import (
 	"asset-management/app/api"
+	"asset-management/app/define"
+	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-type router struct{}
-
-var Router *router
-
-func newRouter() *router {
-	return &router{}
+type Router struct {
+	apis         *api.Apis
+	logger       *logrus.Logger
+	tokenChecker middleware.TokenChecker
 }
 
-func init() {
-	Router = newRouter()
+func NewRouter(apis *api.Apis, logger *logrus.Logger, tokenChecker middleware.TokenChecker) *Router {
+	return &Router{
+		apis:         apis,
+		logger:       logger,
+		tokenChecker: tokenChecker,
+	}
 }
 
-func (router *router) Init(r *gin.Engine) *gin.Engine {
+func (router *Router) Init(r *gin.Engine) *gin.Engine {
 	r.NoRoute(utils.Handler(RouteNotFound))
 	r.NoMethod(utils.Handler(MethodNotFound))
 
-	UserRouter.Init(r.Group("/user"))
-	UsersRouter.Init(r.Group("/users"))
-	EntityRouter.Init(r.Group("/entity"))
-	AssetClassRouter.Init(r.Group("/department"))
-	AssetRouter.Init(r.Group("/department"))
-	TaskRouter.Init(r.Group(""))
-	LogRouter.Init(r.Group("/entity"))
-	OssRouter.Init(r.Group(""))
-	AsyncRouter.Init(r.Group(""))
-	r.GET("/asset/:asset_id/info", utils.Handler(api.AssetApi.GetAssetInfoByScan))
+	jwtMiddleware := middleware.JWTMiddleware(router.tokenChecker)
+	logMiddleware := middleware.LogMiddleware(router.logger)
+	scimMiddleware := middleware.ScimMiddleware(router.tokenChecker)
+	newUserRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/user"))
+	newUsersRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/users"))
+	newEntityRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/entity"))
+	newAssetClassRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/department"))
+	newAssetRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/department"))
+	newTaskRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group(""))
+	newLogRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/entity"))
+	newOssRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group(""))
+	newAsyncRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group(""))
+	newStorageRouter(router.apis, jwtMiddleware, logMiddleware).Init(r.Group("/storage"))
+	newScimRouter(router.apis, scimMiddleware, logMiddleware).Init(r.Group(define.SCIM_BASE_PATH))
+	r.GET("/asset/:asset_id/info", utils.Handler(router.apis.Asset.GetAssetInfoByScan))
 	return r
 }
 
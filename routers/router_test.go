@@ -1,12 +1,20 @@
 package routers
 
 import (
+	"asset-management/app/api"
+	"asset-management/app/dao"
+	"asset-management/app/service"
+	"asset-management/config"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 func TestRouter(t *testing.T) {
+	conf := config.ForTest()
+	services := service.NewServices(conf, dao.NewDaos(nil))
+	apis := api.NewApis(conf, services)
 	r := gin.Default()
-	_ = Router.Init(r)
+	_ = NewRouter(apis, logrus.New(), services.Token).Init(r)
 }
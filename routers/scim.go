@@ -0,0 +1,43 @@
+package routers
+
+import (
+	"asset-management/app/api"
+	"asset-management/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scimRouter struct {
+	apis           *api.Apis
+	scimMiddleware utils.HandlerFunc
+	logMiddleware  utils.HandlerFunc
+}
+
+func newScimRouter(apis *api.Apis, scimMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *scimRouter {
+	return &scimRouter{
+		apis:           apis,
+		scimMiddleware: scimMiddleware,
+		logMiddleware:  logMiddleware,
+	}
+}
+
+func (scim *scimRouter) Init(group *gin.RouterGroup) {
+	group.Use(utils.Handler(scim.scimMiddleware), utils.Handler(scim.logMiddleware))
+	scim.routerCheckAtHandler(group)
+}
+
+func (scim *scimRouter) routerCheckAtHandler(group *gin.RouterGroup) {
+	group.GET("/ServiceProviderConfig", utils.Handler(scim.apis.Scim.GetServiceProviderConfig))
+	group.GET("/Users", utils.Handler(scim.apis.Scim.GetUsers))
+	group.POST("/Users", utils.Handler(scim.apis.Scim.CreateUser))
+	group.GET("/Users/:user_id", utils.Handler(scim.apis.Scim.GetUser))
+	group.PUT("/Users/:user_id", utils.Handler(scim.apis.Scim.ReplaceUser))
+	group.PATCH("/Users/:user_id", utils.Handler(scim.apis.Scim.PatchUser))
+	group.DELETE("/Users/:user_id", utils.Handler(scim.apis.Scim.DeleteUser))
+	group.GET("/Groups", utils.Handler(scim.apis.Scim.GetGroups))
+	group.POST("/Groups", utils.Handler(scim.apis.Scim.CreateGroup))
+	group.GET("/Groups/:group_id", utils.Handler(scim.apis.Scim.GetGroup))
+	group.PUT("/Groups/:group_id", utils.Handler(scim.apis.Scim.ReplaceGroup))
+	group.PATCH("/Groups/:group_id", utils.Handler(scim.apis.Scim.PatchGroup))
+	group.DELETE("/Groups/:group_id", utils.Handler(scim.apis.Scim.DeleteGroup))
+}
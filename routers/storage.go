@@ -0,0 +1,29 @@
+package routers
+
+import (
+	"asset-management/app/api"
+	"asset-management/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type storageRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
+}
+
+func newStorageRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *storageRouter {
+	return &storageRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
+}
+
+func (st *storageRouter) Init(group *gin.RouterGroup) {
+	// signed links carry their own authorization
+	group.GET("/:bucket/*key", utils.Handler(st.apis.Storage.Download))
+
+	group.POST("/import", utils.Handler(st.jwtMiddleware), utils.Handler(st.logMiddleware), utils.Handler(st.apis.Storage.UploadImportFile))
+}
@@ -2,22 +2,23 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type taskRouter struct{}
-
-var TaskRouter *taskRouter
-
-func newTaskRouter() *taskRouter {
-	return &taskRouter{}
+type taskRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	TaskRouter = newTaskRouter()
+func newTaskRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *taskRouter {
+	return &taskRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (task *taskRouter) Init(group *gin.RouterGroup) {
@@ -26,19 +27,19 @@ func (task *taskRouter) Init(group *gin.RouterGroup) {
 }
 
 func (task *taskRouter) routerUserTask(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
-	group.POST("/:user_id/assets/task", utils.Handler(api.TaskApi.CreateNewTask))
-	group.GET("/:user_id/assets/tasks", utils.Handler(api.TaskApi.GetUserTaskList))
-	group.GET("/:user_id/assets/tasks/:task_id", utils.Handler(api.TaskApi.GetUserTaskInfo))
-	group.DELETE("/:user_id/assets/tasks/:task_id", utils.Handler(api.TaskApi.CancelTasks))
-	group.GET("/:user_id/assets/maintain", utils.Handler(api.AssetApi.GetUserMaintainAssets))
-	group.POST("/:user_id/assets/:asset_id/maintain", utils.Handler(api.AssetApi.FinishMaintenance))
+	group.Use(utils.Handler(task.jwtMiddleware), utils.Handler(task.logMiddleware))
+	group.POST("/:user_id/assets/task", utils.Handler(task.apis.Task.CreateNewTask))
+	group.GET("/:user_id/assets/tasks", utils.Handler(task.apis.Task.GetUserTaskList))
+	group.GET("/:user_id/assets/tasks/:task_id", utils.Handler(task.apis.Task.GetUserTaskInfo))
+	group.DELETE("/:user_id/assets/tasks/:task_id", utils.Handler(task.apis.Task.CancelTasks))
+	group.GET("/:user_id/assets/maintain", utils.Handler(task.apis.Asset.GetUserMaintainAssets))
+	group.POST("/:user_id/assets/:asset_id/maintain", utils.Handler(task.apis.Asset.FinishMaintenance))
 }
 
 func (task *taskRouter) routerDepartmentTask(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
-	group.GET("/:department_id/assets/tasks", utils.Handler(api.TaskApi.GetDepartmentTaskList))
-	group.GET("/:department_id/assets/tasks/:task_id", utils.Handler(api.TaskApi.GetDepartmentTaskInfo))
-	group.POST("/:department_id/assets/tasks/:task_id", utils.Handler(api.TaskApi.ApproveTask))
-	group.DELETE("/:department_id/assets/tasks/:task_id", utils.Handler(api.TaskApi.RejectTask))
+	group.Use(utils.Handler(task.jwtMiddleware), utils.Handler(task.logMiddleware))
+	group.GET("/:department_id/assets/tasks", utils.Handler(task.apis.Task.GetDepartmentTaskList))
+	group.GET("/:department_id/assets/tasks/:task_id", utils.Handler(task.apis.Task.GetDepartmentTaskInfo))
+	group.POST("/:department_id/assets/tasks/:task_id", utils.Handler(task.apis.Task.ApproveTask))
+	group.DELETE("/:department_id/assets/tasks/:task_id", utils.Handler(task.apis.Task.RejectTask))
 }
@@ -2,22 +2,24 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
+	"asset-management/app/define"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type userRouter struct{}
-
-var UserRouter *userRouter
-
-func newUserRouter() *userRouter {
-	return &userRouter{}
+type userRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	UserRouter = newUserRouter()
+func newUserRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *userRouter {
+	return &userRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (user *userRouter) Init(group *gin.RouterGroup) {
@@ -26,29 +28,59 @@ func (user *userRouter) Init(group *gin.RouterGroup) {
 }
 
 func (user *userRouter) routerNotNeedLogin(group *gin.RouterGroup) {
-	group.POST("/feishu/callback", utils.Handler(api.FeishuApi.FeishuCallBack))
-	group.POST("/feishu/login", utils.Handler(api.FeishuApi.FeishuLogin))
-	group.Use(utils.Handler(middleware.LogMiddleware()))
-	group.POST("/register", utils.Handler(api.UserApi.UserRegister))
-	group.POST("/login", utils.Handler(api.UserApi.UserLogin))
+	group.POST("/feishu/callback", utils.Handler(user.apis.Feishu.FeishuCallBack))
+	group.POST("/feishu/login", utils.Handler(user.apis.Feishu.FeishuLogin))
+	group.POST("/token/refresh", utils.Handler(user.apis.User.RefreshToken))
+	group.POST("/login/two-factor/setup", utils.Handler(user.apis.TwoFactor.LoginSetup))
+	group.GET("/oauth/providers", utils.Handler(user.apis.Identity.GetLoginProviders))
+	group.POST("/oauth/:provider_id/authorize", utils.Handler(user.apis.Identity.Authorize))
+	group.POST("/oauth/:provider_id/login", utils.Handler(user.apis.Identity.Login))
+	group.Use(utils.Handler(user.logMiddleware))
+	group.POST("/register", utils.Handler(user.apis.User.UserRegister))
+	group.POST("/login", utils.Handler(user.apis.User.UserLogin))
+	group.POST("/login/two-factor", utils.Handler(user.apis.TwoFactor.Login))
+	group.POST("/password/forgot", utils.Handler(user.apis.User.ForgotPassword))
+	group.POST("/password/reset", utils.Handler(user.apis.User.ResetPassword))
 }
 
 func (user *userRouter) routerNeedLogin(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
-	group.POST("/logout", utils.Handler(api.UserApi.UserLogout))
-	group.POST("", utils.Handler(api.UserApi.UserCreate))
-	group.PATCH("/:username", utils.Handler((api.UserApi.ResetContent)))
-	group.GET("/:username/lock", utils.Handler(api.UserApi.LockUser))
-	group.GET("/:username/unlock", utils.Handler(api.UserApi.UnlockUser))
-	group.GET("/info/:user_id", utils.Handler(api.UserApi.GetUserInfoByID))
-	group.GET("/list", utils.Handler(api.UserApi.GetAllUsers))
-	group.DELETE("/:user_id", utils.Handler(api.UserApi.DeleteUser))
-	group.POST("/info/:user_id/password", utils.Handler(api.UserApi.ChangePassword))
-	group.PATCH("/info/:user_id/identity", utils.Handler(api.UserApi.ModifyUserIdentity))
-	group.DELETE("/info/:user_id/entity", utils.Handler(middleware.CheckSystemSuper()), utils.Handler(api.UserApi.ChangeUserEntity))
-	group.DELETE("/info/:user_id/department", utils.Handler(api.UserApi.ChangeUserDepartment))
-	group.POST("/info/:user_id/entity", utils.Handler(api.UserApi.ChangeUserEntity))
-	group.POST("/info/:user_id/department", utils.Handler(api.UserApi.ChangeUserDepartment))
-	group.POST("/feishu/bind", utils.Handler(api.FeishuApi.FeishuBind))
-	group.DELETE("/feishu/bind", utils.Handler(api.FeishuApi.FeishuUnBind))
+	group.Use(utils.Handler(user.jwtMiddleware), utils.Handler(user.logMiddleware))
+	group.POST("/logout", utils.Handler(user.apis.User.UserLogout))
+	group.POST("", utils.Handler(user.apis.User.UserCreate))
+	group.PATCH("/:username", utils.Handler((user.apis.User.ResetContent)))
+	group.GET("/:username/lock", utils.Handler(user.apis.User.LockUser))
+	group.GET("/:username/unlock", utils.Handler(user.apis.User.UnlockUser))
+	group.GET("/login-locks", utils.Handler(user.apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(user.apis.User.GetIPLoginLocks))
+	group.DELETE("/login-locks/:lock_id", utils.Handler(user.apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(user.apis.User.ClearIPLoginLock))
+	group.GET("/info/:user_id", utils.Handler(user.apis.User.GetUserInfoByID))
+	group.GET("/list", utils.Handler(user.apis.User.GetAllUsers))
+	group.DELETE("/:user_id", utils.Handler(user.apis.User.DeleteUser))
+	group.POST("/info/:user_id/password", utils.Handler(user.apis.User.ChangePassword))
+	group.PUT("/info/:user_id/email", utils.Handler(user.apis.User.ChangeEmail))
+	group.PATCH("/info/:user_id/identity", utils.Handler(user.apis.User.ModifyUserIdentity))
+	group.DELETE("/info/:user_id/entity", utils.Handler(user.apis.Permission.Require(define.PERM_SYSTEM_MANAGE)), utils.Handler(user.apis.User.ChangeUserEntity))
+	group.DELETE("/info/:user_id/department", utils.Handler(user.apis.User.ChangeUserDepartment))
+	group.POST("/info/:user_id/entity", utils.Handler(user.apis.User.ChangeUserEntity))
+	group.POST("/info/:user_id/department", utils.Handler(user.apis.User.ChangeUserDepartment))
+	group.POST("/feishu/bind", utils.Handler(user.apis.Feishu.FeishuBind))
+	group.DELETE("/feishu/bind", utils.Handler(user.apis.Feishu.FeishuUnBind))
+	group.GET("/two-factor", utils.Handler(user.apis.TwoFactor.GetStatus))
+	group.POST("/two-factor/setup", utils.Handler(user.apis.TwoFactor.Setup))
+	group.POST("/two-factor/enable", utils.Handler(user.apis.TwoFactor.Enable))
+	group.POST("/two-factor/disable", utils.Handler(user.apis.TwoFactor.Disable))
+	group.POST("/two-factor/recovery-codes", utils.Handler(user.apis.TwoFactor.RegenerateRecoveryCodes))
+	group.GET("/tokens", utils.Handler(user.apis.ApiToken.GetPersonalTokens))
+	group.POST("/tokens", utils.Handler(user.apis.ApiToken.CreatePersonalToken))
+	group.DELETE("/tokens/:token_id", utils.Handler(user.apis.ApiToken.RevokePersonalToken))
+	group.GET("/permissions", utils.Handler(user.apis.Permission.GetMyPermissions))
+	group.GET("/sessions", utils.Handler(user.apis.Session.GetMySessions))
+	group.DELETE("/sessions", utils.Handler(user.apis.Session.RevokeMySessions))
+	group.DELETE("/sessions/:session_id", utils.Handler(user.apis.Session.RevokeMySession))
+	group.GET("/info/:user_id/sessions", utils.Handler(user.apis.Session.GetUserSessions))
+	group.DELETE("/info/:user_id/sessions", utils.Handler(user.apis.Session.ForceLogout))
+	group.POST("/info/:user_id/impersonate", utils.Handler(user.apis.Session.Impersonate))
+	group.GET("/oauth/bindings", utils.Handler(user.apis.Identity.GetMyBindings))
+	group.POST("/oauth/:provider_id/bind/authorize", utils.Handler(user.apis.Identity.BindAuthorize))
+	group.POST("/oauth/:provider_id/bind", utils.Handler(user.apis.Identity.Bind))
+	group.DELETE("/oauth/:provider_id/bind", utils.Handler(user.apis.Identity.Unbind))
 }
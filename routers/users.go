@@ -2,29 +2,30 @@ package routers
 
 import (
 	"asset-management/app/api"
-	"asset-management/middleware"
 	"asset-management/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type usersRouter struct{}
-
-var UsersRouter *usersRouter
-
-func newUsersRouter() *usersRouter {
-	return &usersRouter{}
+type usersRouter struct {
+	apis          *api.Apis
+	jwtMiddleware utils.HandlerFunc
+	logMiddleware utils.HandlerFunc
 }
 
-func init() {
-	UsersRouter = newUsersRouter()
+func newUsersRouter(apis *api.Apis, jwtMiddleware utils.HandlerFunc, logMiddleware utils.HandlerFunc) *usersRouter {
+	return &usersRouter{
+		apis:          apis,
+		jwtMiddleware: jwtMiddleware,
+		logMiddleware: logMiddleware,
+	}
 }
 
 func (users *usersRouter) Init(group *gin.RouterGroup) {
-	group.Use(utils.Handler(middleware.JWTMiddleware()), utils.Handler(middleware.LogMiddleware()))
+	group.Use(utils.Handler(users.jwtMiddleware), utils.Handler(users.logMiddleware))
 	users.routerCheckAtHandler(group)
 }
 
 func (users *usersRouter) routerCheckAtHandler(group *gin.RouterGroup) {
-	group.GET("/:user_id/assets", utils.Handler(api.UserApi.GetUserUsedAssets))
+	group.GET("/:user_id/assets", utils.Handler(users.apis.User.GetUserUsedAssets))
 }
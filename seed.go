@@ -0,0 +1,149 @@
+package main
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/define"
+	"asset-management/app/service"
+	"asset-management/config"
+	"errors"
+	"log"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DEMO_ENTITY       = "Demo Entity"
+	DEMO_HEADQUARTERS = "Demo Headquarters"
+	DEMO_DEPARTMENT   = "Demo R&D"
+	DEMO_CLASS        = "Computers"
+	DEMO_MANAGER      = "demo_manager"
+	DEMO_USER         = "demo_user"
+	DEMO_PASSWORD     = "123456"
+)
+
+func runSeed(conf *config.Config) {
+	daos, _ := newContainer(conf)
+	seeded, err := seedDemo(daos)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !seeded {
+		log.Printf("%s already exists, nothing to seed", DEMO_ENTITY)
+		return
+	}
+	log.Printf("seeded %s, log in as %s or %s with password %s", DEMO_ENTITY, DEMO_MANAGER, DEMO_USER, DEMO_PASSWORD)
+}
+
+/*
+Demo entity with two departments, a manager, a user, an asset class and a few assets,
+skipped if the demo entity already exists
+*/
+func seedDemo(uow dao.UnitOfWork) (bool, error) {
+	seeded := false
+	err := uow.Transaction(func(tx *dao.Daos) error {
+		userService := service.NewUserService(tx.User)
+		entityService := service.NewEntityService(tx.Department, tx.Entity, tx.User)
+		departmentService := service.NewDepartmentService(tx.Department, tx.Entity, tx.User, entityService, userService)
+		assetClassService := service.NewAssetClassService(tx.AssetClass, tx.Asset)
+		assetService := service.NewAssetService(tx.Asset, tx.AssetState, tx.AssetVersion, tx)
+
+		exists, err := entityService.ExistsEntityByName(DEMO_ENTITY)
+		if err != nil || exists {
+			return err
+		}
+
+		if err := entityService.CreateEntity(DEMO_ENTITY); err != nil {
+			return err
+		}
+		entity, err := tx.Entity.GetEntityByName(DEMO_ENTITY)
+		if err != nil {
+			return err
+		}
+
+		if err := departmentService.CreateDepartment(DEMO_HEADQUARTERS, entity.ID, 0); err != nil {
+			return err
+		}
+		headquarters, err := tx.Department.GetDepartmentSub(DEMO_HEADQUARTERS, entity.ID, 0)
+		if err != nil {
+			return err
+		}
+		if err := departmentService.CreateDepartment(DEMO_DEPARTMENT, entity.ID, headquarters.ID); err != nil {
+			return err
+		}
+		department, err := tx.Department.GetDepartmentSub(DEMO_DEPARTMENT, entity.ID, headquarters.ID)
+		if err != nil {
+			return err
+		}
+
+		err = departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+			UserName:        DEMO_MANAGER,
+			Password:        clientPassword(DEMO_PASSWORD),
+			DepartmentSuper: true,
+		}, entity.ID, department.ID)
+		if err != nil {
+			return err
+		}
+		if err := userService.ModifyUserIdentity(DEMO_MANAGER, 2); err != nil {
+			return err
+		}
+		err = departmentService.CreateDepartmentUser(define.CreateDepartmentUserReq{
+			UserName: DEMO_USER,
+			Password: clientPassword(DEMO_PASSWORD),
+		}, entity.ID, department.ID)
+		if err != nil {
+			return err
+		}
+		manager, err := userService.GetUserByName(DEMO_MANAGER)
+		if err != nil {
+			return err
+		}
+
+		err = assetClassService.CreateAssetClass(define.CreateAssetClassReq{ClassName: DEMO_CLASS, Type: 1}, department.ID)
+		if err != nil {
+			return err
+		}
+		classes, err := tx.AssetClass.GetDepartmentDirectClass(department.ID)
+		if err != nil {
+			return err
+		}
+		if len(classes) == 0 {
+			return errors.New("demo asset class was not created")
+		}
+		classID := classes[0].ID
+
+		demoAssets := []*define.CreateAssetReq{
+			{
+				AssetName:   "Workstation",
+				Price:       decimal.NewFromInt(12000),
+				Description: "Developer workstation",
+				Position:    "Room 101",
+				ClassID:     classID,
+				Type:        1,
+				Expire:      1095,
+				Threshold:   30,
+				Children: []*define.CreateAssetReq{
+					{AssetName: "Monitor", Price: decimal.NewFromInt(1500), ClassID: classID, Type: 1, Expire: 1095},
+					{AssetName: "Keyboard", Price: decimal.NewFromInt(300), ClassID: classID, Type: 1, Expire: 730},
+				},
+			},
+			{
+				AssetName: "Laptop",
+				Price:     decimal.NewFromInt(8000),
+				Position:  "Room 102",
+				ClassID:   classID,
+				Type:      1,
+				Expire:    1095,
+				Threshold: 30,
+			},
+		}
+		for _, demoAsset := range demoAssets {
+			if err := assetService.CreateAsset(demoAsset, department.ID, 0, manager.ID); err != nil {
+				return err
+			}
+		}
+
+		seeded = true
+		return nil
+	})
+	return seeded, err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"asset-management/app/api"
+	"asset-management/app/timing"
+	"asset-management/config"
+	"asset-management/middleware"
+	"asset-management/routers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func runServe(conf *config.Config) {
+	daos, services := newContainer(conf)
+	apis := api.NewApis(conf, services)
+
+	c := timing.Init(conf, daos, services)
+	c.Start()
+
+	r := gin.Default()
+
+	r.Use(middleware.Cors())
+
+	routers.NewRouter(apis, middleware.NewLogger(daos.LogHook), services.Token).Init(r)
+
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "pong",
+		})
+	})
+	r.Run(conf.Server.Addr)
+}
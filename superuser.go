@@ -0,0 +1,71 @@
+package main
+
+import (
+	"asset-management/app/dao"
+	"asset-management/app/service"
+	"asset-management/config"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"log"
+	"os"
+)
+
+/*
+The frontend sends md5(password), the server salts and hashes that again
+*/
+func clientPassword(plain string) string {
+	sum := md5.Sum([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+create-superuser -username NAME [-password PASSWORD], the password may also come from AM_SUPERUSER_PASSWORD
+*/
+func runCreateSuperuser(conf *config.Config, args []string) {
+	flags := flag.NewFlagSet("create-superuser", flag.ExitOnError)
+	username := flags.String("username", "", "user to create or promote")
+	password := flags.String("password", os.Getenv("AM_SUPERUSER_PASSWORD"), "plain password, required if the user does not exist yet")
+	_ = flags.Parse(args)
+	if *username == "" {
+		log.Fatal("create-superuser needs -username")
+	}
+
+	daos, _ := newContainer(conf)
+	if err := createSuperuser(daos, *username, *password); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s is now a system super", *username)
+}
+
+/*
+Create the user if needed, reset the password if one is given, then grant system super and lift any ban
+*/
+func createSuperuser(uow dao.UnitOfWork, username string, password string) error {
+	return uow.Transaction(func(tx *dao.Daos) error {
+		userService := service.NewUserService(tx.User)
+
+		exists, err := userService.ExistsUser(username)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if password == "" {
+				return errors.New("a password is required to create a new user")
+			}
+			err = userService.CreateUser(username, clientPassword(password))
+		} else if password != "" {
+			err = userService.ModifyUserPassword(username, clientPassword(password))
+		}
+		if err != nil {
+			return err
+		}
+
+		err = userService.ModifyUserBanstate(username, false)
+		if err != nil {
+			return err
+		}
+		return userService.ModifyUserIdentity(username, 3)
+	})
+}
@@ -0,0 +1,43 @@
+package utils
+
+import "strings"
+
+/*
+Checked in order, phones and tablets before the desktop systems their agents also mention
+*/
+var devicePatterns = []struct {
+	keyword string
+	device  string
+}{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Macintosh", "macOS"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+/*
+At most size characters of s
+*/
+func Truncate(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return s
+	}
+	return string(runes[:size])
+}
+
+/*
+A readable device name for a session, guessed from the user agent
+*/
+func DeviceName(userAgent string) string {
+	for _, pattern := range devicePatterns {
+		if strings.Contains(userAgent, pattern.keyword) {
+			return pattern.device
+		}
+	}
+	return "Unknown"
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceName(t *testing.T) {
+	assert.Equal(t, "iPhone", DeviceName("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15"), "device error")
+	assert.Equal(t, "Android", DeviceName("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36"), "device error")
+	assert.Equal(t, "Windows", DeviceName("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"), "device error")
+	assert.Equal(t, "macOS", DeviceName("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36"), "device error")
+	assert.Equal(t, "Unknown", DeviceName("curl/8.4.0"), "device error")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "curl", Truncate("curl", 4), "truncate error")
+	assert.Equal(t, "cur", Truncate("curl", 3), "truncate error")
+	assert.Equal(t, "设备", Truncate("设备名称", 2), "truncate error")
+}
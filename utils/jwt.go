@@ -9,27 +9,44 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-const secretTokenSalt = "AssetManagement-BinaryAbstract"
+/*
+Set from security.jwt_secret by Initial, tokens are refused until then
+*/
+var secretTokenSalt string
 
 var (
 	ErrTokenInvalid = errors.New("token invalid")
 	ErrTokenExpire  = errors.New("token expire")
+	ErrTokenSecret  = errors.New("token secret is not configured")
 )
 
 const (
-	ATokenExpiredDuration = time.Hour
-	/*
-		TODO: Add a refresh token
-	*/
+	ATokenExpiredDuration         = time.Hour
+	RTokenExpiredDuration         = 7 * 24 * time.Hour
+	TwoFactorTokenExpiredDuration = 5 * time.Minute
+	ImpersonationExpiredDuration  = 15 * time.Minute
 )
 
 /*
-Create a token from the given user info, expired at an hour later
+Create a token from the given user info, expired at an hour later,
+tokenID names the login grant the token was issued from so it can be revoked
+*/
+func CreateToken(userInfo define.UserBasicInfo, tokenID string) (token string, err error) {
+	return createToken(userInfo, tokenID, ATokenExpiredDuration)
+}
+
+/*
+Create a token for an admin acting as another user, userInfo carries both identities
 */
-func CreateToken(userInfo define.UserBasicInfo) (token string, err error) {
+func CreateImpersonationToken(userInfo define.UserBasicInfo, tokenID string) (token string, err error) {
+	return createToken(userInfo, tokenID, ImpersonationExpiredDuration)
+}
+
+func createToken(userInfo define.UserBasicInfo, tokenID string, duration time.Duration) (token string, err error) {
 	nowTime := time.Now()
-	expiredTime := nowTime.Add(ATokenExpiredDuration)
+	expiredTime := nowTime.Add(duration)
 	stdClaims := jwt.StandardClaims{
+		Id:        tokenID,
 		IssuedAt:  nowTime.Unix(),
 		NotBefore: nowTime.Unix(),
 		ExpiresAt: expiredTime.Unix(),
@@ -38,12 +55,18 @@ func CreateToken(userInfo define.UserBasicInfo) (token string, err error) {
 		UserBasicInfo:  userInfo,
 		StandardClaims: stdClaims,
 	}
+	if secretTokenSalt == "" {
+		return "", ErrTokenSecret
+	}
 	tokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	token, err = tokenObj.SignedString([]byte(secretTokenSalt))
 	return
 }
 
 func ParseToken(token string) (claims *define.UserClaims, err error) {
+	if secretTokenSalt == "" {
+		return nil, ErrTokenSecret
+	}
 	tokenObj, err := jwt.ParseWithClaims(token, &define.UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -71,6 +94,50 @@ func ParseToken(token string) (claims *define.UserClaims, err error) {
 	return
 }
 
+/*
+Two-factor tokens are signed with their own key so they can never pass as an access token
+*/
+func twoFactorKey() []byte {
+	return []byte(secretTokenSalt + "-two-factor")
+}
+
+func CreateTwoFactorToken(userID uint, setup bool) (string, error) {
+	if secretTokenSalt == "" {
+		return "", ErrTokenSecret
+	}
+	nowTime := time.Now()
+	claims := &define.TwoFactorClaims{
+		UserID: userID,
+		Setup:  setup,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  nowTime.Unix(),
+			NotBefore: nowTime.Unix(),
+			ExpiresAt: nowTime.Add(TwoFactorTokenExpiredDuration).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(twoFactorKey())
+}
+
+func ParseTwoFactorToken(token string) (*define.TwoFactorClaims, error) {
+	if secretTokenSalt == "" {
+		return nil, ErrTokenSecret
+	}
+	tokenObj, err := jwt.ParseWithClaims(token, &define.TwoFactorClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return twoFactorKey(), nil
+	})
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	claims, ok := tokenObj.Claims.(*define.TwoFactorClaims)
+	if !ok {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
 func IsTokenInvalidError(err error) bool {
 	return err == ErrTokenInvalid
 }
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"asset-management/app/define"
+	"asset-management/config"
 	"testing"
 	"time"
 
@@ -14,6 +15,7 @@ var claims *define.UserClaims
 var token string
 
 func TestCreateToken(t *testing.T) {
+	Initial(config.ForTest().Security)
 	userInfo = define.UserBasicInfo{
 		UserID:          10000,
 		UserName:        "test",
@@ -24,7 +26,7 @@ func TestCreateToken(t *testing.T) {
 
 	var err error
 
-	token, err = CreateToken(userInfo)
+	token, err = CreateToken(userInfo, "1")
 	if err != nil {
 		t.Fatalf("Token generation error.")
 	}
@@ -51,6 +53,7 @@ func TestParseToken(t *testing.T) {
 	assert.Equal(t, false, claims.EntitySuper)
 	assert.Equal(t, false, claims.DepartmentSuper)
 	assert.Equal(t, false, claims.DepartmentSuper)
+	assert.Equal(t, "1", claims.Id)
 
 	nowTime := time.Now()
 	expiredTime := nowTime.Add(time.Hour)
@@ -98,3 +101,32 @@ func TestParseToken(t *testing.T) {
 	claims, err = ParseToken(token)
 	assert.Equal(t, ErrTokenInvalid, err, "jwt error")
 }
+
+func TestTwoFactorToken(t *testing.T) {
+	token, err := CreateTwoFactorToken(10000, true)
+	assert.Equal(t, nil, err, "token error")
+
+	claims, err := ParseTwoFactorToken(token)
+	assert.Equal(t, nil, err, "token error")
+	assert.Equal(t, uint(10000), claims.UserID, "token error")
+	assert.Equal(t, true, claims.Setup, "token error")
+
+	_, err = ParseToken(token)
+	assert.Equal(t, ErrTokenInvalid, err, "token error")
+	accessToken, err := CreateToken(userInfo, "1")
+	assert.Equal(t, nil, err, "token error")
+	_, err = ParseTwoFactorToken(accessToken)
+	assert.Equal(t, ErrTokenInvalid, err, "token error")
+}
+
+func TestTokenSecretMissing(t *testing.T) {
+	Initial(config.SecurityConfig{})
+	defer Initial(config.ForTest().Security)
+
+	_, err := CreateToken(userInfo, "1")
+	assert.Equal(t, ErrTokenSecret, err, "token error")
+	_, err = ParseToken(token)
+	assert.Equal(t, ErrTokenSecret, err, "token error")
+	_, err = CreateTwoFactorToken(10000, true)
+	assert.Equal(t, ErrTokenSecret, err, "token error")
+}
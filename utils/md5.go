@@ -5,7 +5,10 @@ import (
 	"encoding/hex"
 )
 
-const salt = "se-asset-BinaryAbstract-2023"
+/*
+Set from security.password_salt by Initial
+*/
+var salt string
 
 func CreateMD5(str string) string {
 	h := md5.New()
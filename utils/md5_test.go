@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"asset-management/config"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMD5(t *testing.T) {
+	Initial(config.ForTest().Security)
 	result := CreateMD5("123456")
-	assert.Equal(t, "dc171ced6d63ab8021656851788801c7", result, "md5 error")
+	assert.Equal(t, "1ae1a30e0f49e2a69c9a75ae78565a84", result, "md5 error")
 }
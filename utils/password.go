@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+Hashes written before bcrypt are salted md5 hex strings, they stay valid
+until legacyPasswordCutoff (zero means no cutoff) and get rehashed on login
+*/
+var legacyPasswordCutoff time.Time
+
+var passwordCost = bcrypt.DefaultCost
+
+/*
+Hash a password with bcrypt, the per-user salt is stored inside the hash
+*/
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func IsLegacyPasswordHash(hash string) bool {
+	return !strings.HasPrefix(hash, "$2")
+}
+
+func LegacyPasswordAllowed(now time.Time) bool {
+	return legacyPasswordCutoff.IsZero() || now.Before(legacyPasswordCutoff)
+}
+
+/*
+Check password against a stored hash, rehash is true when the hash
+should be replaced by a fresh one from HashPassword
+*/
+func CheckPassword(hash, password string) (ok bool, rehash bool) {
+	if IsLegacyPasswordHash(hash) {
+		if !LegacyPasswordAllowed(time.Now()) {
+			return false, false
+		}
+		return hash == CreateMD5(password), true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err == nil && cost != passwordCost
+}
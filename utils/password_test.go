@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("123456")
+	assert.Equal(t, nil, err, "hash error")
+	assert.Equal(t, false, IsLegacyPasswordHash(hash), "hash error")
+
+	other, err := HashPassword("123456")
+	assert.Equal(t, nil, err, "hash error")
+	assert.NotEqual(t, hash, other, "salt error")
+
+	ok, rehash := CheckPassword(hash, "123456")
+	assert.Equal(t, true, ok, "check error")
+	assert.Equal(t, false, rehash, "check error")
+	ok, _ = CheckPassword(hash, "654321")
+	assert.Equal(t, false, ok, "check error")
+}
+
+func TestLegacyPassword(t *testing.T) {
+	legacy := CreateMD5("123456")
+	defer func() { legacyPasswordCutoff = time.Time{} }()
+
+	ok, rehash := CheckPassword(legacy, "123456")
+	assert.Equal(t, true, ok, "check error")
+	assert.Equal(t, true, rehash, "check error")
+	ok, _ = CheckPassword(legacy, "654321")
+	assert.Equal(t, false, ok, "check error")
+
+	legacyPasswordCutoff = time.Now().Add(time.Hour)
+	ok, _ = CheckPassword(legacy, "123456")
+	assert.Equal(t, true, ok, "check error")
+
+	legacyPasswordCutoff = time.Now().Add(-time.Hour)
+	ok, rehash = CheckPassword(legacy, "123456")
+	assert.Equal(t, false, ok, "check error")
+	assert.Equal(t, false, rehash, "check error")
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const (
+	SCIM_CONTENT_TYPE = "application/scim+json"
+	SCIM_ERROR_SCHEMA = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+/*
+SCIM clients expect the error body of RFC 7644 instead of ResponseData
+*/
+type ScimErrorData struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func (ctx *Context) Scim(status int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		ctx.ScimError(http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	ctx.Data(status, SCIM_CONTENT_TYPE, body)
+}
+
+func (ctx *Context) ScimError(status int, scimType string, detail string) {
+	body, _ := json.Marshal(ScimErrorData{
+		Schemas:  []string{SCIM_ERROR_SCHEMA},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+	ctx.Data(status, SCIM_CONTENT_TYPE, body)
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"asset-management/config"
+	"time"
+)
+
+/*
+Replace the built-in token secret and password salt with configured ones,
+the config has been validated so the cutoff date parses
+*/
+func Initial(conf config.SecurityConfig) {
+	secretTokenSalt = conf.JWTSecret
+	salt = conf.PasswordSalt
+	legacyPasswordCutoff, _ = conf.LegacyPasswordCutoffTime(time.Local)
+}
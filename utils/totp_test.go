@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B, sha1 test vectors truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		assert.Equal(t, nil, err, "totp error")
+		assert.Equal(t, expected, code, "totp error")
+	}
+
+	now := time.Unix(1111111109, 0)
+	step, ok := VerifyTOTP(secret, "081804", now.Add(TOTPPeriod*time.Second))
+	assert.Equal(t, true, ok, "totp error")
+	assert.Equal(t, TOTPStep(now), step, "totp error")
+	_, ok = VerifyTOTP(secret, "081804", now.Add(3*TOTPPeriod*time.Second))
+	assert.Equal(t, false, ok, "totp error")
+	_, ok = VerifyTOTP(secret, "81804", now)
+	assert.Equal(t, false, ok, "totp error")
+
+	newSecret, err := NewTOTPSecret()
+	assert.Equal(t, nil, err, "totp error")
+	assert.Contains(t, TOTPURL("AssetManagement", "admin", newSecret), "secret="+newSecret)
+}